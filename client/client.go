@@ -0,0 +1,177 @@
+// Package client is a Go client for the ledger API. List methods return iterators that
+// follow continuation tokens, requests are retried with backoff when rate limited, and
+// non-2xx responses are returned as *APIError, which matches the Err* sentinels with
+// errors.Is.
+//
+//	c := client.New("https://ledger.example.com", apiKey)
+//	for tx, err := range c.Transactions(ctx, client.ListOptions{}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// APIError is a non-2xx response. Code is the error code of the response, e.g.
+// INSUFFICIENT_FUNDS; Message is its message, or the response body if it has none.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ledger api: %d %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code to the matching sentinel error.
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return target == ErrValidation
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return false
+}
+
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	// MaxRetries of a rate-limited (429) or unavailable (503) request
+	MaxRetries int
+	// MaxBackoff caps the wait between retries, including server-requested ones
+	MaxBackoff time.Duration
+}
+
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		MaxRetries: 5,
+		MaxBackoff: time.Minute,
+	}
+}
+
+// do sends a request and decodes a JSON response into out, retrying rate-limited
+// requests after the server's Retry-After or an exponential backoff.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if retryable && attempt < c.MaxRetries {
+			if err := sleep(ctx, c.backoff(attempt, resp.Header.Get("Retry-After"))); err != nil {
+				return err
+			}
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return newAPIError(resp.StatusCode, data)
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(data, out)
+	}
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	var envelope struct {
+		Error struct {
+			Code    string   `json:"code"`
+			Message string   `json:"message"`
+			Details []string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error.Message == "" {
+		return &APIError{StatusCode: status, Message: strings.TrimSpace(string(body))}
+	}
+	return &APIError{StatusCode: status, Code: envelope.Error.Code, Message: envelope.Error.Message, Details: envelope.Error.Details}
+}
+
+// backoff is the server's Retry-After in seconds, else 500ms doubled per attempt.
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	d := 500 * time.Millisecond << attempt
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		d = time.Duration(seconds) * time.Second
+	}
+	if c.MaxBackoff > 0 && d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransactionsFollowsContinuationTokens(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// First request is rate limited once
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		switch r.URL.Query().Get("continuation_token") {
+		case "":
+			fmt.Fprint(w, `{"transactions":[{"id":"t1"},{"id":"t2"}],"pagination":{"has_more":true,"continuation_token":"next"}}`)
+		case "next":
+			fmt.Fprint(w, `{"transactions":[{"id":"t3"}],"pagination":{"has_more":false}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var ids []string
+	for tx, err := range New(srv.URL, "key").Transactions(context.Background(), ListOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, tx.ID)
+	}
+	if fmt.Sprint(ids) != "[t1 t2 t3]" || calls != 3 {
+		t.Fatalf("unexpected ids %v after %d calls", ids, calls)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key")
+	c.MaxRetries = 1
+	c.MaxBackoff = time.Millisecond
+	for _, err := range c.Events(context.Background(), ListOptions{}) {
+		var apiErr *APIError
+		if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.Message != "slow down" {
+			t.Fatalf("expected a rate limited APIError, got %v", err)
+		}
+	}
+
+	// Backoff waits honor the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.MaxBackoff = time.Hour
+	for _, err := range c.Events(ctx, ListOptions{}) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context cancellation, got %v", err)
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"error":{"code":"INSUFFICIENT_FUNDS","message":"account cash would be overdrawn"}}`)
+	}))
+	defer srv.Close()
+
+	for _, err := range New(srv.URL, "key").Events(context.Background(), ListOptions{}) {
+		var apiErr *APIError
+		if !errors.Is(err, ErrValidation) || !errors.As(err, &apiErr) || apiErr.Code != "INSUFFICIENT_FUNDS" ||
+			apiErr.Message != "account cash would be overdrawn" {
+			t.Fatalf("expected the envelope's code and message, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+type Pagination struct {
+	HasMore           bool   `json:"has_more"`
+	ContinuationToken string `json:"continuation_token,omitempty"`
+	Count             int    `json:"count"`
+}
+
+type Posting struct {
+	ID          string `json:"id"`
+	AccountCode string `json:"account_code"`
+	AccountName string `json:"account_name"`
+	Direction   string `json:"direction"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	TaxCode     string `json:"tax_code,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Transaction struct {
+	ID          string         `json:"id"`
+	ExternalID  string         `json:"external_id"`
+	Description string         `json:"description,omitempty"`
+	Amount      string         `json:"amount"`
+	Currency    string         `json:"currency"`
+	OccurredAt  string         `json:"occurred_at"`
+	ValueDate   string         `json:"value_date"`
+	CreatedAt   string         `json:"created_at"`
+	Entity      string         `json:"entity,omitempty"`
+	Metadata    map[string]any `json:"metadata"`
+	Postings    []Posting      `json:"postings"`
+}
+
+// JournalPosting is a posting listed on its own, with its transaction's references.
+type JournalPosting struct {
+	Posting
+	TransactionID string `json:"transaction_id"`
+	ExternalID    string `json:"external_id"`
+	OccurredAt    string `json:"occurred_at"`
+	ValueDate     string `json:"value_date"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type Event struct {
+	ID            string         `json:"id"`
+	Sequence      int64          `json:"sequence"`
+	AggregateType string         `json:"aggregate_type"`
+	AggregateID   string         `json:"aggregate_id"`
+	EventType     string         `json:"event_type"`
+	Payload       map[string]any `json:"payload"`
+	OccurredAt    string         `json:"occurred_at"`
+	CreatedAt     string         `json:"created_at"`
+}
+
+// ListOptions are the query parameters of a listing, e.g. start_time or event_type.
+// PageSize is the page limit; the iterators fetch further pages as they are consumed.
+type ListOptions struct {
+	PageSize int
+	Filters  url.Values
+}
+
+// Transactions iterates over the ledger's transactions, newest first.
+func (c *Client) Transactions(ctx context.Context, opts ListOptions) iter.Seq2[Transaction, error] {
+	return paginate(ctx, c, "/v1/transactions", opts, func(page *struct {
+		Transactions []Transaction `json:"transactions"`
+		Pagination   Pagination    `json:"pagination"`
+	}) ([]Transaction, Pagination) {
+		return page.Transactions, page.Pagination
+	})
+}
+
+// Postings iterates over the ledger's postings across transactions, newest first, e.g.
+// filtered by account, direction, min_amount or start_time.
+func (c *Client) Postings(ctx context.Context, opts ListOptions) iter.Seq2[JournalPosting, error] {
+	return paginate(ctx, c, "/v1/postings", opts, func(page *struct {
+		Postings   []JournalPosting `json:"postings"`
+		Pagination Pagination       `json:"pagination"`
+	}) ([]JournalPosting, Pagination) {
+		return page.Postings, page.Pagination
+	})
+}
+
+// Events iterates over the ledger's events, newest first.
+func (c *Client) Events(ctx context.Context, opts ListOptions) iter.Seq2[Event, error] {
+	return paginate(ctx, c, "/v1/events", opts, func(page *struct {
+		Events     []Event    `json:"events"`
+		Pagination Pagination `json:"pagination"`
+	}) ([]Event, Pagination) {
+		return page.Events, page.Pagination
+	})
+}
+
+// paginate yields the items of each page and follows continuation tokens until the
+// last page, the consumer stops, or a request fails (yielded once as the error).
+func paginate[T, P any](ctx context.Context, c *Client, path string, opts ListOptions, items func(*P) ([]T, Pagination)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		query := url.Values{}
+		for k, v := range opts.Filters {
+			query[k] = v
+		}
+		if opts.PageSize > 0 {
+			query.Set("limit", strconv.Itoa(opts.PageSize))
+		}
+
+		for {
+			var page P
+			if err := c.do(ctx, "GET", path, query, nil, &page); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			list, pagination := items(&page)
+			for _, item := range list {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !pagination.HasMore || pagination.ContinuationToken == "" {
+				return
+			}
+			query.Set("continuation_token", pagination.ContinuationToken)
+		}
+	}
+}
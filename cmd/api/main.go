@@ -1,13 +1,25 @@
 package main
 
 import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/archive"
 	"Go_FormanceLegder/internal/auth"
 	"Go_FormanceLegder/internal/config"
 	"Go_FormanceLegder/internal/dashboard"
 	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/hooks"
 	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/mail"
+	"Go_FormanceLegder/internal/offboarding"
+	"Go_FormanceLegder/internal/openapi"
+	"Go_FormanceLegder/internal/privacy"
+	"Go_FormanceLegder/internal/ratelimit"
 	"Go_FormanceLegder/internal/webhook"
+	"Go_FormanceLegder/internal/widget"
+	"Go_FormanceLegder/internal/workflow"
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -22,15 +34,26 @@ func main() {
 	ctx := context.Background()
 
 	cfg := config.Load()
+	// Test sends and replays are delivered from the API
+	webhook.LegacySignature = cfg.WebhookLegacySignature
+	webhook.DefaultRetryPolicy = webhook.RetryPolicy{MaxAttempts: cfg.WebhookMaxAttempts, Backoff: cfg.WebhookBackoff,
+		Base: cfg.WebhookBackoffBase, MaxDelay: cfg.WebhookBackoffMax}
+	if err := webhook.DefaultRetryPolicy.Validate(); err != nil {
+		log.Fatalf("invalid webhook retry policy: %v", err)
+	}
 
-	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	// The main database, with read failover to its standby when one is configured
+	control, err := db.NewCluster(ctx, db.ControlRegion, cfg.DatabaseURL, cfg.DatabaseStandbyURL)
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
+	pool := control.Pool
 	defer pool.Close()
 
 	workers := river.NewWorkers()
 	river.AddWorker(workers, &webhook.Worker{DB: pool})
+	// Registered so workflow jobs can be inserted; they are worked by cmd/worker
+	river.AddWorker(workers, &workflow.Worker{})
 
 	riverClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{
 		Workers: workers,
@@ -39,18 +62,38 @@ func main() {
 		log.Fatalf("failed to create river client: %v", err)
 	}
 
-	// Create ledger service with River client
-	ledgerService := &ledger.Service{
-		DB:          pool,
-		RiverClient: riverClient,
+	// Regional ledger databases (data residency); the default region uses the main pool
+	router, err := db.NewRouter(ctx, pool, cfg.DatabaseRegions, cfg.DatabaseStandbyRegions, cfg.DefaultRegion)
+	if err != nil {
+		log.Fatalf("failed to connect to regional databases: %v", err)
 	}
+	defer router.Close()
+	go control.Watch(ctx, cfg.FailoverCheckInterval)
+	router.Watch(ctx, cfg.FailoverCheckInterval)
 
-	ledgerHandler := &ledger.Handler{Service: ledgerService}
-
-	authHandler := &dashboard.AuthHandler{DB: pool, Config: cfg}
-	dashboardLedgerHandler := &dashboard.LedgerHandler{DB: pool}
+	authHandler := &dashboard.AuthHandler{DB: pool, Config: cfg, Router: router}
+	dashboardLedgerHandler := &dashboard.LedgerHandler{DB: pool, Router: router}
 	apiKeyHandler := &dashboard.APIKeyHandler{DB: pool, APIKeySecret: cfg.APIKeySecret}
-	webhookHandler := &dashboard.WebhookHandler{DB: pool}
+	noteHandler := &dashboard.NoteHandler{DB: pool, Router: router}
+	maskingHandler := &dashboard.MaskingHandler{DB: pool}
+	var archiveStore *archive.S3
+	if cfg.S3Bucket != "" {
+		archiveStore = archive.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey)
+	}
+	mailer := mail.New(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	offboardingHandler := &dashboard.OffboardingHandler{DB: pool, Offboarding: &offboarding.Service{
+		Control: pool,
+		Router:  router,
+		Store:   archiveStore,
+		Mailer:  mailer,
+		Grace:   cfg.OrgDeletionGrace,
+	}}
+	supportHandler := &dashboard.SupportHandler{DB: pool, Config: cfg, Mailer: mailer}
+	accountHandler := &dashboard.AccountHandler{DB: pool, Config: cfg, Privacy: &privacy.Service{
+		Control: pool,
+		Router:  router,
+		Mailer:  mailer,
+	}}
 
 	apiKeyAuth := &auth.Middleware{DB: pool, APIKeySecret: cfg.APIKeySecret}
 
@@ -62,10 +105,32 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	// Failover state of the databases, for operators following the promote procedure
+	mux.HandleFunc("/health/databases", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"databases": append([]db.ClusterStatus{control.Status()}, router.Clusters()...),
+		})
+	})
+
+	// The v1 API's OpenAPI document and a Swagger UI over it, for generating clients
+	mux.Handle("/openapi.json", openapi.Handler(openapi.V1()))
+	mux.Handle("/docs", openapi.SwaggerUI("/openapi.json"))
+
 	// Dashboard Auth APIs (no auth required)
 	mux.HandleFunc("/api/auth/register", authHandler.Register)
 	mux.HandleFunc("/api/auth/login", authHandler.Login)
-	mux.HandleFunc("/api/auth/me", authHandler.GetCurrentUser)
+	mux.HandleFunc("/api/auth/me", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			authHandler.GetCurrentUser(w, r)
+		case http.MethodDelete:
+			accountHandler.DeleteAccount(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/auth/me/export", accountHandler.ExportPersonalData)
 
 	// Dashboard Ledger Management APIs (JWT auth)
 	mux.HandleFunc("/api/ledgers", func(w http.ResponseWriter, r *http.Request) {
@@ -79,10 +144,74 @@ func main() {
 		case http.MethodPost:
 			dashboardLedgerHandler.CreateLedger(w, r)
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
+	mux.HandleFunc("/api/ledgers/stats", dashboardLedgerHandler.GetLedgerStats)
+	mux.HandleFunc("/api/ledgers/rate-limit", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			dashboardLedgerHandler.GetRateLimit(w, r)
+		case http.MethodPut:
+			dashboardLedgerHandler.SetRateLimit(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/ledgers/notes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			noteHandler.ListNotes(w, r)
+		case http.MethodPost:
+			noteHandler.CreateNote(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/masking-rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			maskingHandler.ListMaskingRules(w, r)
+		case http.MethodPut:
+			maskingHandler.SetMaskingRule(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Support access: customer consents and impersonation (JWT auth)
+	mux.HandleFunc("/api/support/consents", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			supportHandler.ListConsents(w, r)
+		case http.MethodPost:
+			supportHandler.GrantConsent(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/support/consents/revoke", supportHandler.RevokeConsent)
+	mux.HandleFunc("/api/support/audit", supportHandler.GetAudit)
+	mux.HandleFunc("/api/support/impersonate", supportHandler.Impersonate)
+	mux.HandleFunc("/api/support/impersonate/end", supportHandler.EndImpersonation)
+
+	// Organization deletion and export (JWT auth)
+	mux.HandleFunc("/api/organization/offboarding", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			offboardingHandler.GetOffboarding(w, r)
+		case http.MethodPost:
+			offboardingHandler.ScheduleDeletion(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/organization/offboarding/cancel", offboardingHandler.CancelDeletion)
+	mux.HandleFunc("/api/organization/export", offboardingHandler.ExportOrganization)
+	mux.HandleFunc("/api/organization/transfer-ownership", accountHandler.TransferOwnership)
+
 	// Dashboard API Key Management APIs (JWT auth)
 	mux.HandleFunc("/api/ledgers/api-keys", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -91,18 +220,246 @@ func main() {
 		case http.MethodPost:
 			apiKeyHandler.CreateAPIKey(w, r)
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 	mux.HandleFunc("/api/api-keys/revoke", apiKeyHandler.RevokeAPIKey)
+	mux.HandleFunc("/api/api-keys/approve", apiKeyHandler.ApproveAPIKey)
+	mux.HandleFunc("/api/api-keys/reject", apiKeyHandler.RejectAPIKey)
+	mux.HandleFunc("/api/api-keys/history", apiKeyHandler.GetAPIKeyHistory)
+	mux.HandleFunc("/api/api-keys/approval-policy", apiKeyHandler.SetApprovalPolicy)
 
-	// Ledger APIs (API key auth)
+	// Ledger APIs (API key auth, then the ledger's rate limit)
+	limiter := ratelimit.NewLimiter(pool, ratelimit.Limit{PerSecond: cfg.RateLimitPerSecond, Burst: cfg.RateLimitBurst})
 	authWrap := func(handler http.HandlerFunc) http.Handler {
-		return apiKeyAuth.AuthMiddleware(handler)
+		return apiKeyAuth.AuthMiddleware(limiter.Middleware(handler))
+	}
+
+	// Posting hooks shared by every region's ledger service
+	postingHooks := &hooks.Registry{}
+	if err := postingHooks.DialPlugins(cfg.Plugins, cfg.PluginTimeout, cfg.PluginFailOpen); err != nil {
+		log.Fatalf("failed to set up posting hooks: %v", err)
+	}
+
+	// Each region gets its own ledger service; requests are routed by the
+	// authenticated organization's region
+	regionalMuxes := map[string]http.Handler{}
+	regionalHandlers := map[string]*ledger.Handler{}
+	regionalTiers := map[string]*archive.Tier{}
+	for _, region := range router.Regions() {
+		regionPool, _ := router.Pool(region)
+		regionRiver := riverClient
+		if regionPool != pool {
+			regionRiver, err = river.NewClient(riverpgxv5.New(regionPool), &river.Config{Workers: workers})
+			if err != nil {
+				log.Fatalf("failed to create river client for region %s: %v", region, err)
+			}
+		}
+		regionalHandlers[region] = &ledger.Handler{Service: &ledger.Service{
+			DB:                  regionPool,
+			RiverClient:         regionRiver,
+			FXConversionAccount: cfg.FXConversionAccount,
+			FXRoundingAccount:   cfg.FXRoundingAccount,
+			Hooks:               postingHooks,
+		}, WidgetSecret: cfg.WidgetSecret, Events: &ledger.EventHub{DB: regionPool}}
+		go regionalHandlers[region].Events.Run(ctx)
+		regionalMuxes[region] = newLedgerMux(regionalHandlers[region], &dashboard.WebhookHandler{DB: regionPool, RiverClient: regionRiver})
+		// Archived ledgers are rehydrated before any request reaches their data
+		if archiveStore != nil {
+			regionalHandlers[region].Archiver = archive.NewArchiver(regionPool, archiveStore, cfg.EventArchiveAfter)
+			regionalTiers[region] = archive.NewTier(regionalHandlers[region].Archiver, 0)
+			regionalMuxes[region] = regionalTiers[region].Middleware(regionalMuxes[region])
+		}
+	}
+
+	// PSP connector webhooks (signature auth); routed by the ledger's region
+	mux.HandleFunc("/hooks/connectors", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		region, _, err := router.ForLedger(r.Context(), r.URL.Query().Get("ledger"))
+		if err != nil {
+			api.Error(w, "connector not found", http.StatusNotFound)
+			return
+		}
+		handler, ok := regionalHandlers[region]
+		if !ok {
+			api.Error(w, "ledger region unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ReceiveConnectorWebhook(w, r)
+	})
+
+	// Public balance and statement widgets (signed token auth); routed by the ledger's region
+	mux.HandleFunc("/public/widgets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		claims, err := widget.Verify(cfg.WidgetSecret, r.URL.Query().Get("token"), time.Now())
+		if err != nil {
+			api.WriteError(w, err, http.StatusUnauthorized)
+			return
+		}
+		region, _, err := router.ForLedger(r.Context(), claims.LedgerID)
+		if err != nil {
+			api.Error(w, "ledger not found", http.StatusNotFound)
+			return
+		}
+		handler, ok := regionalHandlers[region]
+		if !ok {
+			api.Error(w, "ledger region unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeWidget(w, r, claims)
+	})
+
+	// Dashboard ledger read APIs (JWT auth); served by the same handlers as the API
+	ledgerAccess := &dashboard.LedgerAccess{DB: pool, JWTSecret: cfg.JWTSecret}
+	dashboardLedger := func(serve func(h *ledger.Handler, w http.ResponseWriter, r *http.Request)) http.Handler {
+		return ledgerAccess.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.FromContext(r.Context())
+			region := principal.Region
+			if region == "" {
+				region = router.Default
+			}
+			handler, ok := regionalHandlers[region]
+			if !ok {
+				api.Error(w, "organization region unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if tier, ok := regionalTiers[region]; ok {
+				tier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					serve(handler, w, r)
+				})).ServeHTTP(w, r)
+				return
+			}
+			serve(handler, w, r)
+		}))
+	}
+	dashboardRead := func(read func(h *ledger.Handler, w http.ResponseWriter, r *http.Request)) http.Handler {
+		return dashboardLedger(func(h *ledger.Handler, w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			read(h, w, r)
+		})
+	}
+	transactionsRead := dashboardRead(func(h *ledger.Handler, w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") != "" {
+			h.GetTransaction(w, r)
+		} else {
+			h.ListTransactions(w, r)
+		}
+	})
+	accountsRead := dashboardRead(func(h *ledger.Handler, w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("code") != "" {
+			h.GetAccount(w, r)
+		} else {
+			h.ListAccounts(w, r)
+		}
+	})
+	balanceSummaryRead := dashboardRead((*ledger.Handler).GetBalanceSummary)
+	mux.Handle("/api/ledgers/{id}/transactions", transactionsRead)
+	mux.Handle("/api/ledgers/{id}/accounts", accountsRead)
+	mux.Handle("/api/ledgers/{id}/balance-summary", balanceSummaryRead)
+
+	// Saved views; a run goes through the view's read, so the caller's masking applies
+	viewReads := map[string]http.Handler{
+		"transactions":    transactionsRead,
+		"accounts":        accountsRead,
+		"balance_summary": balanceSummaryRead,
+	}
+	mux.Handle("/api/ledgers/{id}/views", dashboardLedger(func(h *ledger.Handler, w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.ListViews(w, r)
+		case http.MethodPost:
+			h.SaveView(w, r)
+		case http.MethodDelete:
+			h.DeleteView(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	mux.Handle("/api/ledgers/{id}/views/run", dashboardRead(func(h *ledger.Handler, w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.FromContext(r.Context())
+		view, err := h.LoadView(r.Context(), principal.LedgerID, r.URL.Query().Get("name"))
+		if errors.Is(err, ledger.ErrViewNotFound) {
+			api.Error(w, "view not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			api.Error(w, "failed to load view", http.StatusInternalServerError)
+			return
+		}
+		run := r.Clone(r.Context())
+		run.URL.RawQuery = view.Query().Encode()
+		viewReads[view.Kind].ServeHTTP(w, run)
+	}))
+
+	// Dashboard GraphQL reads across the organization's ledgers (JWT auth)
+	mux.Handle("/api/graphql", &dashboard.GraphQLHandler{
+		DB:        pool,
+		Router:    router,
+		JWTSecret: cfg.JWTSecret,
+		Rehydrate: func(ctx context.Context, region, ledgerID string) error {
+			if tier, ok := regionalTiers[region]; ok {
+				return tier.Rehydrate(ctx, ledgerID)
+			}
+			return nil
+		},
+	})
+
+	mux.Handle("/v1/", authWrap(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.FromContext(r.Context())
+		region := principal.Region
+		if region == "" {
+			region = router.Default
+		}
+		regionalMux, ok := regionalMuxes[region]
+		if !ok {
+			api.Error(w, "organization region unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		regionalMux.ServeHTTP(w, r)
+	}))
+
+	server := &http.Server{
+		Addr:    ":" + cfg.ServerPort,
+		Handler: api.Routes(mux),
 	}
 
+	go func() {
+		log.Printf("Server starting on port %s", cfg.ServerPort)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+
+	log.Println("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("server shutdown error: %v", err)
+	}
+
+	log.Println("Server stopped")
+}
+
+// newLedgerMux registers the API-key authenticated ledger routes for one region.
+func newLedgerMux(ledgerHandler *ledger.Handler, webhookHandler *dashboard.WebhookHandler) http.Handler {
+	mux := http.NewServeMux()
+
 	// Transaction APIs
-	mux.Handle("/v1/transactions", authWrap(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v1/transactions", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
 			ledgerHandler.PostTransaction(w, r)
@@ -113,12 +470,319 @@ func main() {
 				ledgerHandler.ListTransactions(w, r)
 			}
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
-	}))
+	})
+	mux.HandleFunc("/v1/postings", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.ListPostings(w, r)
+	})
+
+	// Cross-ledger transfer APIs
+	mux.HandleFunc("/v1/transfers", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetTransfers(w, r)
+		case http.MethodPost:
+			ledgerHandler.PostTransfer(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Embeddable widget APIs
+	mux.HandleFunc("/v1/widgets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.CreateWidget(w, r)
+	})
+
+	// Conversion APIs
+	mux.HandleFunc("/v1/conversions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.PostConversion(w, r)
+	})
+
+	// Currency APIs
+	mux.HandleFunc("/v1/currencies", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListCurrencies(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveCurrency(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Exchange rate APIs
+	mux.HandleFunc("/v1/exchange-rates", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListExchangeRates(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveExchangeRate(w, r)
+		case http.MethodDelete:
+			ledgerHandler.DeleteExchangeRate(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/exchange-rates/convert", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.ConvertAmount(w, r)
+	})
+
+	// Schedule APIs
+	mux.HandleFunc("/v1/schedules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("id") != "" {
+				ledgerHandler.GetSchedule(w, r)
+			} else {
+				ledgerHandler.ListSchedules(w, r)
+			}
+		case http.MethodPost:
+			ledgerHandler.CreateSchedule(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/schedules/cancel", ledgerHandler.CancelSchedule)
+	mux.HandleFunc("/v1/schedules/preview", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.PreviewSchedule(w, r)
+	})
+	mux.HandleFunc("/v1/calendar", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetCalendar(w, r)
+		case http.MethodPut:
+			ledgerHandler.UpdateCalendar(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/holidays", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListHolidays(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveHoliday(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/holidays/{date}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.DeleteHoliday(w, r)
+	})
+
+	// Settlement APIs
+	mux.HandleFunc("/v1/settlements", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("id") != "" {
+				ledgerHandler.GetSettlement(w, r)
+			} else {
+				ledgerHandler.ListSettlements(w, r)
+			}
+		case http.MethodPost:
+			ledgerHandler.CreateSettlement(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/settlements/post", ledgerHandler.RetrySettlement)
+	mux.HandleFunc("/v1/settlements/export", ledgerHandler.ExportSettlement)
+
+	// Interest accrual by value date
+	mux.HandleFunc("/v1/interest/accruals", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.AccrueInterest(w, r)
+	})
+
+	// Hold APIs
+	mux.HandleFunc("/v1/holds", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("id") != "" {
+				ledgerHandler.GetHold(w, r)
+			} else {
+				ledgerHandler.ListHolds(w, r)
+			}
+		case http.MethodPost:
+			ledgerHandler.CreateHold(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/holds/capture", ledgerHandler.CaptureHold)
+	mux.HandleFunc("/v1/holds/void", ledgerHandler.VoidHold)
+
+	// Saga APIs
+	mux.HandleFunc("/v1/sagas", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("id") != "" {
+				ledgerHandler.GetSaga(w, r)
+			} else {
+				ledgerHandler.ListSagas(w, r)
+			}
+		case http.MethodPost:
+			ledgerHandler.CreateSaga(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/sagas/resume", ledgerHandler.ResumeSaga)
+
+	// Workflow APIs
+	workflowHandler := &workflow.Handler{Service: ledgerHandler.Service}
+	mux.HandleFunc("/v1/workflows/definitions", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			workflowHandler.ListDefinitions(w, r)
+		case http.MethodPost:
+			workflowHandler.SaveDefinition(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/workflows", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("id") != "" {
+				workflowHandler.GetWorkflow(w, r)
+			} else {
+				workflowHandler.ListWorkflows(w, r)
+			}
+		case http.MethodPost:
+			workflowHandler.StartWorkflow(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/workflows/signal", workflowHandler.Signal)
+
+	// PSP connector APIs
+	mux.HandleFunc("/v1/connectors", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListConnectors(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveConnector(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/connectors/events", ledgerHandler.ListConnectorEvents)
+
+	// Open banking feed APIs
+	mux.HandleFunc("/v1/bank-feeds", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListBankFeeds(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveBankFeed(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/bank-feeds/sync", ledgerHandler.SyncBankFeed)
+	mux.HandleFunc("/v1/bank-feeds/post", ledgerHandler.PostBankLines)
+	mux.HandleFunc("/v1/bank-lines", ledgerHandler.ListBankLines)
+
+	// Payout file APIs
+	mux.HandleFunc("/v1/payout-files", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListPayoutFiles(w, r)
+		case http.MethodPost:
+			ledgerHandler.CreatePayoutFile(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/payout-files/download", ledgerHandler.DownloadPayoutFile)
+
+	// Clearing ingestion APIs
+	mux.HandleFunc("/v1/clearing/mappings", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListClearingMappings(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveClearingMapping(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/clearing/imports", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListClearingImports(w, r)
+		case http.MethodPost:
+			ledgerHandler.CreateClearingImport(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/clearing/discrepancies", ledgerHandler.GetClearingDiscrepancies)
+
+	// Tax APIs
+	mux.HandleFunc("/v1/tax-codes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListTaxCodes(w, r)
+		case http.MethodPost:
+			ledgerHandler.SaveTaxCode(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/tax/report", ledgerHandler.GetTaxReport)
+
+	// Journal export APIs
+	mux.HandleFunc("/v1/journal-exports", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.ExportJournal(w, r)
+	})
+	mux.HandleFunc("/v1/journal-exports/settings", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetJournalExportSettings(w, r)
+		case http.MethodPut:
+			ledgerHandler.SaveJournalExportSettings(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 
 	// Account APIs
-	mux.Handle("/v1/accounts", authWrap(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v1/accounts", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			if r.URL.Query().Get("code") != "" {
@@ -128,15 +792,136 @@ func main() {
 			}
 		case http.MethodPost:
 			ledgerHandler.CreateAccount(w, r)
+		case http.MethodPatch:
+			ledgerHandler.UpdateAccountMetadata(w, r)
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
-	}))
+	})
+
+	mux.HandleFunc("/v1/account-code-rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetAccountCodeRules(w, r)
+		case http.MethodPut:
+			ledgerHandler.SetAccountCodeRules(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/accounts/batch", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.CreateAccounts(w, r)
+	})
+	mux.HandleFunc("/v1/accounts/disable", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.DisableAccount(w, r)
+	})
+	mux.HandleFunc("/v1/accounts/enable", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.EnableAccount(w, r)
+	})
+	mux.HandleFunc("/v1/accounts/constraints", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.SetAccountConstraints(w, r)
+	})
+	mux.HandleFunc("/v1/accounts/entity", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.LinkAccountEntity(w, r)
+	})
+
+	// Entity APIs
+	mux.HandleFunc("/v1/entities", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("code") != "" {
+				ledgerHandler.GetEntity(w, r)
+			} else {
+				ledgerHandler.ListEntities(w, r)
+			}
+		case http.MethodPost:
+			ledgerHandler.SaveEntity(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/entities/kyc", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.SetEntityKYCStatus(w, r)
+	})
+	mux.HandleFunc("/v1/kyc-policy", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetKYCPolicy(w, r)
+		case http.MethodPut:
+			ledgerHandler.SetKYCPolicy(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Validation rules: the ledger's own CEL checks on every transaction
+	mux.HandleFunc("/v1/validation-rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetValidationRules(w, r)
+		case http.MethodPut:
+			ledgerHandler.SetValidationRules(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Screening APIs
+	mux.HandleFunc("/v1/screening/rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.GetScreeningRules(w, r)
+		case http.MethodPut:
+			ledgerHandler.SetScreeningRules(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v1/screening/reviews", ledgerHandler.ListScreeningReviews)
+	mux.HandleFunc("/v1/screening/reviews/approve", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.ApproveScreeningReview(w, r)
+	})
+	mux.HandleFunc("/v1/screening/reviews/reject", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.RejectScreeningReview(w, r)
+	})
 
 	// Event APIs
-	mux.Handle("/v1/events", authWrap(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v1/events", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		if r.URL.Query().Get("id") != "" {
@@ -144,48 +929,107 @@ func main() {
 		} else {
 			ledgerHandler.ListEvents(w, r)
 		}
-	}))
+	})
+
+	// Event archives in object storage
+	mux.HandleFunc("/v1/event-archives", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.ListEventArchives(w, r)
+	})
+	mux.HandleFunc("/v1/event-archives/restore", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ledgerHandler.RestoreEventArchive(w, r)
+	})
+
+	// Event push over WebSocket
+	mux.HandleFunc("/v1/ws", ledgerHandler.Subscribe)
 
 	// Balance APIs
-	mux.Handle("/v1/balance/summary", authWrap(ledgerHandler.GetBalanceSummary))
-	mux.Handle("/v1/accounts/balance-history", authWrap(ledgerHandler.GetAccountBalanceHistory))
+	mux.HandleFunc("/v1/balance/summary", ledgerHandler.GetBalanceSummary)
+	mux.HandleFunc("/v1/balance/rollup", ledgerHandler.GetBalanceRollup)
+	mux.HandleFunc("/v1/accounts/balance-history", ledgerHandler.GetAccountBalanceHistory)
+	mux.HandleFunc("/v1/balance/diff", ledgerHandler.GetBalanceDiff)
+	mux.HandleFunc("/v1/balance/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ledgerHandler.ListBalanceSnapshots(w, r)
+		case http.MethodPost:
+			ledgerHandler.CreateBalanceSnapshot(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 
 	// Webhook APIs (API key auth)
-	mux.Handle("/v1/webhook-endpoints", authWrap(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v1/webhook-endpoints", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			webhookHandler.ListWebhookEndpoints(w, r)
 		case http.MethodPost:
 			webhookHandler.CreateWebhookEndpoint(w, r)
 		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
-	}))
-	mux.Handle("/v1/webhook-deliveries", authWrap(webhookHandler.ListWebhookDeliveries))
-
-	server := &http.Server{
-		Addr:    ":" + cfg.ServerPort,
-		Handler: mux,
-	}
-
-	go func() {
-		log.Printf("Server starting on port %s", cfg.ServerPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+	})
+	mux.HandleFunc("/v1/webhook-endpoints/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			webhookHandler.UpdateWebhookEndpoint(w, r)
+		case http.MethodDelete:
+			webhookHandler.DeleteWebhookEndpoint(w, r)
+		default:
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
-
-	log.Println("Shutting down server...")
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("server shutdown error: %v", err)
-	}
+	})
+	mux.HandleFunc("/v1/webhook-endpoints/{id}/test", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.TestWebhookEndpoint(w, r)
+	})
+	mux.HandleFunc("/v1/webhook-endpoints/{id}/rotate-secret", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.RotateWebhookSecret(w, r)
+	})
+	mux.HandleFunc("/v1/webhook-deliveries", webhookHandler.ListWebhookDeliveries)
+	mux.HandleFunc("/v1/webhook-deliveries/retry", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.RetryWebhookDeliveries(w, r)
+	})
+	mux.HandleFunc("/v1/webhook-deliveries/{id}/retry", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.RetryWebhookDelivery(w, r)
+	})
+	mux.HandleFunc("/v1/events/{id}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.ListEventDeliveries(w, r)
+	})
+	mux.HandleFunc("/v1/webhook-replays", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhookHandler.ReplayWebhookEvents(w, r)
+	})
 
-	log.Println("Server stopped")
+	return api.Routes(mux)
 }
@@ -0,0 +1,51 @@
+// Command conformance runs the ledger API conformance suite against a deployment and
+// exits non-zero if any check fails.
+package main
+
+import (
+	"Go_FormanceLegder/conformance"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	baseURL := flag.String("url", os.Getenv("LEDGER_URL"), "API base URL")
+	apiKey := flag.String("api-key", os.Getenv("LEDGER_API_KEY"), "API key of a ledger set aside for testing")
+	webhookURL := flag.String("webhook-url", "", "URL at which the deployment reaches the webhook receiver; the webhook check is skipped without it")
+	webhookListen := flag.String("webhook-listen", ":9099", "listen address of the webhook receiver")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each check")
+	flag.Parse()
+
+	if *baseURL == "" || *apiKey == "" {
+		fmt.Fprintln(os.Stderr, "conformance: -url and -api-key are required")
+		os.Exit(2)
+	}
+
+	s := conformance.New(conformance.Config{
+		BaseURL:       *baseURL,
+		APIKey:        *apiKey,
+		WebhookURL:    *webhookURL,
+		WebhookListen: *webhookListen,
+		Timeout:       *timeout,
+	})
+	fmt.Printf("run %s against %s\n", s.RunID, *baseURL)
+
+	failed := 0
+	for _, r := range s.Run(context.Background()) {
+		switch {
+		case r.Passed:
+			fmt.Printf("PASS  %s\n", r.Name)
+		case r.Skipped:
+			fmt.Printf("SKIP  %s\n", r.Name)
+		default:
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", r.Name, r.Err)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
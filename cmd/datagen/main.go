@@ -0,0 +1,285 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/ledger"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+)
+
+type options struct {
+	orgName      string
+	ledgers      int
+	customers    int
+	merchants    int
+	transactions int
+	zipf         float64
+	rate         float64
+	workers      int
+	days         int
+	seed         int64
+	region       string
+	currency     string
+}
+
+// datagen creates synthetic ledgers through the service layer, for load tests, demos and
+// query-plan validation:
+//
+//	datagen -customers 5000 -merchants 200 -transactions 1000000 -rate 500
+//
+// Customers and merchants are picked with a Zipf distribution, so a few hot accounts
+// receive most of the traffic, as in production. Transactions are spread evenly over
+// the last -days days and carry order metadata. Webhook jobs are enqueued like any other
+// post; run the worker to deliver them or truncate river_job afterwards.
+func main() {
+	var opts options
+	flag.StringVar(&opts.orgName, "org", "Datagen", "name of the organization to create")
+	flag.IntVar(&opts.ledgers, "ledgers", 1, "number of ledgers to create")
+	flag.IntVar(&opts.customers, "customers", 1000, "customer accounts per ledger")
+	flag.IntVar(&opts.merchants, "merchants", 50, "merchant accounts per ledger")
+	flag.IntVar(&opts.transactions, "transactions", 10000, "transactions per ledger")
+	flag.Float64Var(&opts.zipf, "zipf", 1.1, "Zipf exponent for picking accounts (> 1; higher means hotter hot accounts)")
+	flag.Float64Var(&opts.rate, "rate", 0, "target transactions per second across workers (0 = unlimited)")
+	flag.IntVar(&opts.workers, "workers", 4, "concurrent posting workers")
+	flag.IntVar(&opts.days, "days", 90, "spread occurred_at over this many past days")
+	flag.Int64Var(&opts.seed, "seed", time.Now().UnixNano(), "random seed")
+	flag.StringVar(&opts.region, "region", "", "data residency region of the organization (default region if empty)")
+	flag.StringVar(&opts.currency, "currency", "USD", "ledger currency")
+	flag.Parse()
+
+	if opts.zipf <= 1 {
+		log.Fatalf("-zipf must be greater than 1")
+	}
+	if opts.customers < 1 || opts.merchants < 1 || opts.workers < 1 {
+		log.Fatalf("-customers, -merchants and -workers must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt)
+		<-quit
+		log.Println("Stopping generator...")
+		cancel()
+	}()
+
+	cfg := config.Load()
+
+	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	router, err := db.NewRouter(ctx, pool, cfg.DatabaseRegions, nil, cfg.DefaultRegion)
+	if err != nil {
+		log.Fatalf("failed to connect to regional databases: %v", err)
+	}
+	defer router.Close()
+
+	regionPool, err := router.Pool(opts.region)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// Insert-only client: jobs are enqueued for the worker, never processed here
+	riverClient, err := river.NewClient(riverpgxv5.New(regionPool), &river.Config{})
+	if err != nil {
+		log.Fatalf("failed to create River client: %v", err)
+	}
+	service := ledger.NewService(regionPool, riverClient)
+
+	var orgID, projectID string
+	err = pool.QueryRow(ctx, `INSERT INTO organizations (name, region) VALUES ($1, NULLIF($2, '')) RETURNING id`,
+		opts.orgName, opts.region).Scan(&orgID)
+	if err != nil {
+		log.Fatalf("failed to create organization: %v", err)
+	}
+	err = pool.QueryRow(ctx, `INSERT INTO projects (organization_id, name, code) VALUES ($1, 'Datagen', 'datagen') RETURNING id`,
+		orgID).Scan(&projectID)
+	if err != nil {
+		log.Fatalf("failed to create project: %v", err)
+	}
+
+	for i := 0; i < opts.ledgers; i++ {
+		var ledgerID string
+		code := fmt.Sprintf("datagen-%d", i+1)
+		err := pool.QueryRow(ctx, `INSERT INTO ledgers (project_id, name, code, currency) VALUES ($1, $2, $2, $3) RETURNING id`,
+			projectID, code, opts.currency).Scan(&ledgerID)
+		if err != nil {
+			log.Fatalf("failed to create ledger: %v", err)
+		}
+		if err := router.EnsureLedger(ctx, ledgerID); err != nil {
+			log.Fatalf("failed to create ledger in region: %v", err)
+		}
+
+		if err := createAccounts(ctx, regionPool, ledgerID, opts); err != nil {
+			log.Fatalf("failed to create accounts: %v", err)
+		}
+		log.Printf("Ledger %s (%s): %d customers, %d merchants", code, ledgerID, opts.customers, opts.merchants)
+
+		start := time.Now()
+		posted := generate(ctx, service, ledgerID, i, opts)
+		elapsed := time.Since(start)
+		log.Printf("Ledger %s: posted %d transactions in %s (%.0f tx/s)",
+			code, posted, elapsed.Round(time.Millisecond), float64(posted)/elapsed.Seconds())
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func createAccounts(ctx context.Context, pool *pgxpool.Pool, ledgerID string, opts options) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO accounts (ledger_id, code, name, type, balance) VALUES
+			($1, 'bank', 'Operating bank account', 'asset', 0),
+			($1, 'fees', 'Fee revenue', 'revenue', 0)
+	`, ledgerID)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO accounts (ledger_id, code, name, type, balance)
+		SELECT $1, 'customer:' || lpad(n::text, 6, '0'), 'Customer ' || n, 'liability', 0
+		FROM generate_series(1, $2) n
+		UNION ALL
+		SELECT $1, 'merchant:' || lpad(n::text, 6, '0'), 'Merchant ' || n, 'liability', 0
+		FROM generate_series(1, $3) n
+	`, ledgerID, opts.customers, opts.merchants)
+	return err
+}
+
+// generate posts opts.transactions transactions with opts.workers concurrent workers and
+// returns how many were posted.
+func generate(ctx context.Context, service *ledger.Service, ledgerID string, ledgerIndex int, opts options) int64 {
+	var next, posted atomic.Int64
+	span := time.Duration(opts.days) * 24 * time.Hour
+	start := time.Now().Add(-span)
+
+	var limiter <-chan time.Time
+	if opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// rand.Rand is not safe for concurrent use
+			rng := rand.New(rand.NewSource(opts.seed + int64(ledgerIndex*opts.workers+w)))
+			customers := rand.NewZipf(rng, opts.zipf, 1, uint64(opts.customers-1))
+			merchants := rand.NewZipf(rng, opts.zipf, 1, uint64(opts.merchants-1))
+
+			for {
+				n := next.Add(1)
+				if n > int64(opts.transactions) || ctx.Err() != nil {
+					return
+				}
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				cmd := randomTransaction(rng, customers, merchants)
+				cmd.LedgerID = ledgerID
+				cmd.Currency = opts.currency
+				cmd.ExternalID = fmt.Sprintf("order-%d-%08d", ledgerIndex+1, n)
+				cmd.IdempotencyKey = fmt.Sprintf("datagen:%s:%d", ledgerID, n)
+				cmd.OccurredAt = start.Add(time.Duration(float64(span) * float64(n) / float64(opts.transactions)))
+
+				if _, err := service.PostTransaction(ctx, cmd); err != nil {
+					if ctx.Err() == nil {
+						log.Printf("transaction %d failed: %v", n, err)
+					}
+					continue
+				}
+				if p := posted.Add(1); p%1000 == 0 {
+					log.Printf("  %d/%d transactions", p, opts.transactions)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	return posted.Load()
+}
+
+// randomTransaction returns a payment (customer to merchant, with a 2% fee), deposit,
+// withdrawal or merchant payout, weighted roughly like a wallet product's traffic.
+func randomTransaction(rng *rand.Rand, customers, merchants *rand.Zipf) ledger.PostTransactionCommand {
+	customer := fmt.Sprintf("customer:%06d", customers.Uint64()+1)
+	merchant := fmt.Sprintf("merchant:%06d", merchants.Uint64()+1)
+	// Log-normal amounts: median around 33.00, with a long tail
+	cents := int64(math.Exp(rng.NormFloat64()*1.2+3.5) * 100)
+	if cents < 1 {
+		cents = 1
+	}
+
+	var postings []ledger.PostingInput
+	metadata := map[string]any{"source": "datagen"}
+
+	switch roll := rng.Float64(); {
+	case roll < 0.50:
+		fee := cents * 2 / 100
+		postings = []ledger.PostingInput{
+			{AccountCode: customer, Direction: "debit", Amount: formatCents(cents)},
+			{AccountCode: merchant, Direction: "credit", Amount: formatCents(cents - fee)},
+		}
+		if fee > 0 {
+			postings = append(postings, ledger.PostingInput{AccountCode: "fees", Direction: "credit", Amount: formatCents(fee)})
+		}
+		metadata["type"] = "payment"
+		metadata["customer"] = customer
+		metadata["merchant"] = merchant
+	case roll < 0.80:
+		postings = []ledger.PostingInput{
+			{AccountCode: "bank", Direction: "debit", Amount: formatCents(cents)},
+			{AccountCode: customer, Direction: "credit", Amount: formatCents(cents)},
+		}
+		metadata["type"] = "deposit"
+		metadata["customer"] = customer
+	case roll < 0.95:
+		postings = []ledger.PostingInput{
+			{AccountCode: customer, Direction: "debit", Amount: formatCents(cents)},
+			{AccountCode: "bank", Direction: "credit", Amount: formatCents(cents)},
+		}
+		metadata["type"] = "withdrawal"
+		metadata["customer"] = customer
+	default:
+		cents *= 20
+		postings = []ledger.PostingInput{
+			{AccountCode: merchant, Direction: "debit", Amount: formatCents(cents)},
+			{AccountCode: "bank", Direction: "credit", Amount: formatCents(cents)},
+		}
+		metadata["type"] = "payout"
+		metadata["merchant"] = merchant
+	}
+
+	return ledger.PostTransactionCommand{Postings: postings, Metadata: metadata}
+}
+
+func formatCents(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
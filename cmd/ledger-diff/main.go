@@ -0,0 +1,86 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/ledgerdiff"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+)
+
+// ledger-diff compares two ledgers, e.g. production and its replay in a test environment,
+// or a ledger before and after a migration, and prints the differences as JSON:
+//
+//	ledger-diff -left-ledger <id> -right-db postgres://test/ledger -right-ledger <id>
+//	ledger-diff -left-ledger <id> -right-ledger <id> -from 2024-01-01T00:00:00Z
+//
+// Each database defaults to DATABASE_URL. It exits 1 unless the ledgers match.
+func main() {
+	leftDB := flag.String("left-db", "", "database URL of the left ledger (default DATABASE_URL)")
+	leftLedger := flag.String("left-ledger", "", "id of the left ledger")
+	rightDB := flag.String("right-db", "", "database URL of the right ledger (default DATABASE_URL)")
+	rightLedger := flag.String("right-ledger", "", "id of the right ledger")
+	from := flag.String("from", "", "only compare transactions that occurred at or after this RFC3339 time")
+	to := flag.String("to", "", "only compare transactions that occurred before this RFC3339 time")
+	limit := flag.Int("limit", ledgerdiff.DefaultLimit, "differences listed per kind")
+	flag.Parse()
+
+	if *leftLedger == "" || *rightLedger == "" {
+		log.Fatal("-left-ledger and -right-ledger are required")
+	}
+	opts := ledgerdiff.Options{Limit: *limit}
+	for _, bound := range []struct {
+		value string
+		t     *time.Time
+	}{{*from, &opts.From}, {*to, &opts.To}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			log.Fatalf("invalid time %q: %v", bound.value, err)
+		}
+		*bound.t = t
+	}
+
+	ctx := context.Background()
+	cfg := config.Load()
+	if *leftDB == "" {
+		*leftDB = cfg.DatabaseURL
+	}
+	if *rightDB == "" {
+		*rightDB = cfg.DatabaseURL
+	}
+
+	left, err := db.NewPool(ctx, *leftDB)
+	if err != nil {
+		log.Fatalf("failed to connect to the left database: %v", err)
+	}
+	defer left.Close()
+	right := left
+	if *rightDB != *leftDB {
+		right, err = db.NewPool(ctx, *rightDB)
+		if err != nil {
+			log.Fatalf("failed to connect to the right database: %v", err)
+		}
+		defer right.Close()
+	}
+
+	report, err := ledgerdiff.Compare(ctx,
+		ledgerdiff.Side{DB: left, LedgerID: *leftLedger},
+		ledgerdiff.Side{DB: right, LedgerID: *rightLedger},
+		opts)
+	if err != nil {
+		log.Fatalf("failed to compare ledgers: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.Matches() {
+		os.Exit(1)
+	}
+}
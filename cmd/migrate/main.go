@@ -26,20 +26,31 @@ func main() {
 	}
 	defer pool.Close()
 
-	// Run SQL migrations first
-	if err := runSQLMigrations(ctx, pool); err != nil {
-		log.Fatalf("failed to run SQL migrations: %v", err)
-	}
-
-	// Then run River migrations
-	migrator, err := rivermigrate.New(riverpgxv5.New(pool), nil)
+	// Every regional database carries the full schema
+	router, err := db.NewRouter(ctx, pool, cfg.DatabaseRegions, nil, cfg.DefaultRegion)
 	if err != nil {
-		log.Fatalf("failed to create River migrator: %v", err)
+		log.Fatalf("failed to connect to regional databases: %v", err)
 	}
+	defer router.Close()
 
-	_, err = migrator.Migrate(ctx, rivermigrate.DirectionUp, nil)
-	if err != nil {
-		log.Fatalf("failed to run River migrations: %v", err)
+	for region, regionPool := range router.Pools() {
+		log.Printf("Migrating database for region %s", region)
+
+		// Run SQL migrations first
+		if err := runSQLMigrations(ctx, regionPool); err != nil {
+			log.Fatalf("failed to run SQL migrations: %v", err)
+		}
+
+		// Then run River migrations
+		migrator, err := rivermigrate.New(riverpgxv5.New(regionPool), nil)
+		if err != nil {
+			log.Fatalf("failed to create River migrator: %v", err)
+		}
+
+		_, err = migrator.Migrate(ctx, rivermigrate.DirectionUp, nil)
+		if err != nil {
+			log.Fatalf("failed to run River migrations: %v", err)
+		}
 	}
 
 	// Create completion flag for healthcheck
@@ -0,0 +1,228 @@
+// Command mockserver serves the core public API (accounts, transactions, events and
+// the balance summary) from memory with deterministic data, for integrators' contract
+// tests in CI without Postgres or real credentials.
+//
+// Any bearer token is accepted and gets its own ledger, seeded with the same accounts
+// and transactions; POST /mock/reset restores the caller's ledger to that state. IDs and
+// timestamps come from a mock clock, so the same calls always return the same data.
+package main
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/ledger"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type server struct {
+	seed bool
+
+	mu     sync.Mutex
+	stores map[string]*store // by bearer token
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	seed := flag.Bool("seed", true, "seed each ledger with sample accounts and transactions")
+	flag.Parse()
+
+	s := &server{seed: *seed, stores: map[string]*store{}}
+	log.Printf("Mock server listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s.routes()))
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/mock/reset", s.with(func(st *store, w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		*st = *newStore(st.token, s.seed)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("/v1/accounts", s.with(s.accounts))
+	mux.HandleFunc("/v1/transactions", s.with(s.transactions))
+	mux.HandleFunc("/v1/events", s.with(s.events))
+	mux.HandleFunc("/v1/balance/summary", s.with(func(st *store, w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, st.balanceSummary())
+	}))
+	return mux
+}
+
+// with authenticates like the API (any bearer token) and serializes access to the
+// token's ledger.
+func (s *server) with(serve func(st *store, w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(token) == "" {
+			http.Error(w, "missing authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		st, ok := s.stores[token]
+		if !ok {
+			st = newStore(token, s.seed)
+			s.stores[token] = st
+		}
+		serve(st, w, r)
+	}
+}
+
+func (s *server) accounts(st *store, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if code := r.URL.Query().Get("code"); code != "" {
+			a, ok := st.accounts[code]
+			if !ok {
+				http.Error(w, "account not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, st.accountResponse(a))
+			return
+		}
+		writeJSON(w, http.StatusOK, st.listAccounts())
+	case http.MethodPost:
+		var req struct {
+			Code     string         `json:"code"`
+			Name     string         `json:"name"`
+			Type     string         `json:"type"`
+			Metadata map[string]any `json:"metadata,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		account, err := st.createAccount(req.Code, req.Name, req.Type, req.Metadata)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, account)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) transactions(st *store, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			tx, err := st.transaction(id)
+			if err != nil {
+				http.Error(w, "transaction not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, tx)
+			return
+		}
+		page, pagination, err := paginate(r, st.transactions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, ledger.ListTransactionsResponse{Transactions: page, Pagination: pagination})
+	case http.MethodPost:
+		var req ledger.PostTransactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		id, err := st.postTransaction(req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ledger.PostTransactionResponse{TransactionID: id, Status: "accepted"})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) events(st *store, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if id := r.URL.Query().Get("id"); id != "" {
+		for _, evt := range st.events {
+			if evt.ID == id {
+				writeJSON(w, http.StatusOK, evt)
+				return
+			}
+		}
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+
+	events := st.events
+	if eventType := r.URL.Query().Get("event_type"); eventType != "" {
+		events = nil
+		for _, evt := range st.events {
+			if evt.EventType == eventType {
+				events = append(events, evt)
+			}
+		}
+	}
+	page, pagination, err := paginate(r, events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, ledger.ListEventsResponse{Events: page, Pagination: pagination})
+}
+
+// paginate returns a page of items newest first, like the API's listings. The
+// continuation token carries the position of the next item.
+func paginate[T any](r *http.Request, items []T) ([]T, api.PaginationResponse, error) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit = api.ValidateLimit(limit)
+	cursor, err := api.DecodeCursor(r.URL.Query().Get("continuation_token"))
+	if err != nil {
+		return nil, api.PaginationResponse{}, err
+	}
+
+	end := len(items)
+	if cursor.Sequence > 0 && int(cursor.Sequence) <= len(items) {
+		end = int(cursor.Sequence)
+	}
+	page := []T{}
+	for i := end - 1; i >= 0 && len(page) < limit; i-- {
+		page = append(page, items[i])
+	}
+
+	pagination := api.PaginationResponse{Count: len(page)}
+	if next := end - len(page); next > 0 {
+		pagination.HasMore = true
+		pagination.ContinuationToken, _ = api.EncodeCursor(api.Cursor{Sequence: int64(next)})
+	}
+	return page, pagination, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// epoch is the mock clock's start; every write advances it by a second, so the same
+// calls always produce the same IDs and timestamps.
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var idNamespace = uuid.MustParse("0b9f6f7e-2f0c-4d55-9a3e-6f1e9c2d4b10")
+
+var (
+	errNotFound  = errors.New("not found")
+	errConflict  = errors.New("conflict")
+	errInvalid   = errors.New("invalid")
+	accountTypes = map[string]bool{"asset": true, "liability": true, "equity": true, "revenue": true, "expense": true}
+)
+
+type account struct {
+	ledger.AccountResponse
+	balance *big.Rat
+}
+
+// store is one API key's ledger.
+type store struct {
+	token        string
+	clock        time.Time
+	ids          int
+	accounts     map[string]*account // by code
+	transactions []ledger.TransactionResponse
+	events       []ledger.EventResponse
+	idempotency  map[string]string // key to transaction ID
+}
+
+func newStore(token string, seed bool) *store {
+	s := &store{token: token, clock: epoch, accounts: map[string]*account{}, idempotency: map[string]string{}}
+	if seed {
+		s.seed()
+	}
+	return s
+}
+
+func (s *store) seed() {
+	for _, a := range []struct{ code, name, typ string }{
+		{"cash:main", "Main cash", "asset"},
+		{"customer:alice", "Alice", "liability"},
+		{"customer:bob", "Bob", "liability"},
+		{"revenue:fees", "Fees", "revenue"},
+		{"equity:capital", "Capital", "equity"},
+	} {
+		s.createAccount(a.code, a.name, a.typ, nil)
+	}
+	for i, t := range []struct{ debit, credit, amount string }{
+		{"cash:main", "equity:capital", "10000.00"},
+		{"cash:main", "customer:alice", "250.00"},
+		{"cash:main", "customer:bob", "120.50"},
+		{"customer:alice", "revenue:fees", "2.50"},
+	} {
+		s.postTransaction(ledger.PostTransactionRequest{
+			IdempotencyKey: fmt.Sprintf("seed-%d", i+1),
+			ExternalID:     fmt.Sprintf("seed-%d", i+1),
+			Currency:       "USD",
+			Postings: []ledger.PostingInput{
+				{AccountCode: t.debit, Direction: "debit", Amount: t.amount},
+				{AccountCode: t.credit, Direction: "credit", Amount: t.amount},
+			},
+		})
+	}
+}
+
+// next advances the clock and returns a deterministic ID for kind.
+func (s *store) next(kind string) (string, time.Time) {
+	s.ids++
+	s.clock = s.clock.Add(time.Second)
+	return uuid.NewSHA1(idNamespace, fmt.Appendf(nil, "%s/%s/%d", s.token, kind, s.ids)).String(), s.clock
+}
+
+func (s *store) createAccount(code, name, typ string, metadata map[string]any) (ledger.AccountResponse, error) {
+	if code == "" || !accountTypes[typ] {
+		return ledger.AccountResponse{}, fmt.Errorf("%w: code and a valid type required", errInvalid)
+	}
+	if _, ok := s.accounts[code]; ok {
+		return ledger.AccountResponse{}, fmt.Errorf("%w: account %s exists", errConflict, code)
+	}
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	id, at := s.next("account")
+	a := &account{balance: new(big.Rat), AccountResponse: ledger.AccountResponse{
+		ID: id, Code: code, Name: name, Type: typ, Status: "active", AllowNegativeBalance: true,
+		Metadata: metadata, CreatedAt: at.Format(time.RFC3339),
+	}}
+	s.accounts[code] = a
+	s.appendEvent("account", id, "AccountCreated", map[string]any{"code": code, "type": typ}, at)
+	return s.accountResponse(a), nil
+}
+
+func (s *store) accountResponse(a *account) ledger.AccountResponse {
+	resp := a.AccountResponse
+	resp.Balance = a.balance.FloatString(10)
+	resp.HeldBalance = "0.0000000000"
+	resp.AvailableBalance = resp.Balance
+	return resp
+}
+
+func (s *store) listAccounts() []ledger.AccountResponse {
+	accounts := []ledger.AccountResponse{}
+	for _, a := range s.accounts {
+		accounts = append(accounts, s.accountResponse(a))
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Code < accounts[j].Code })
+	return accounts
+}
+
+// postTransaction checks the postings balance per currency like the ledger does and
+// applies them (credits add to the balance, debits subtract).
+func (s *store) postTransaction(req ledger.PostTransactionRequest) (string, error) {
+	if req.IdempotencyKey == "" || req.Currency == "" {
+		return "", fmt.Errorf("%w: idempotency_key and currency required", errInvalid)
+	}
+	if id, ok := s.idempotency[req.IdempotencyKey]; ok {
+		return id, nil
+	}
+	if len(req.Postings) < 2 {
+		return "", fmt.Errorf("%w: transaction must have at least 2 postings", errInvalid)
+	}
+
+	net := map[string]*big.Rat{}
+	amounts := make([]*big.Rat, len(req.Postings))
+	for i, p := range req.Postings {
+		if _, ok := s.accounts[p.AccountCode]; !ok {
+			return "", fmt.Errorf("%w: account %s not found", errInvalid, p.AccountCode)
+		}
+		amount, ok := new(big.Rat).SetString(p.Amount)
+		if !ok || amount.Sign() <= 0 {
+			return "", fmt.Errorf("%w: invalid amount: %s", errInvalid, p.Amount)
+		}
+		if p.Direction != "debit" && p.Direction != "credit" {
+			return "", fmt.Errorf("%w: invalid direction: %s", errInvalid, p.Direction)
+		}
+		currency := p.Currency
+		if currency == "" {
+			currency = req.Currency
+		}
+		if net[currency] == nil {
+			net[currency] = new(big.Rat)
+		}
+		if p.Direction == "credit" {
+			net[currency].Add(net[currency], amount)
+		} else {
+			net[currency].Sub(net[currency], amount)
+		}
+		amounts[i] = amount
+	}
+	for currency, total := range net {
+		if total.Sign() != 0 {
+			return "", fmt.Errorf("%w: debits and credits do not balance for %s", errInvalid, currency)
+		}
+	}
+
+	valueDate := req.ValueDate
+	if _, err := time.Parse("2006-01-02", valueDate); valueDate != "" && err != nil {
+		return "", fmt.Errorf("%w: value_date must be YYYY-MM-DD", errInvalid)
+	}
+
+	id, at := s.next("transaction")
+	occurredAt := req.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = at
+	}
+	if valueDate == "" {
+		valueDate = occurredAt.UTC().Format("2006-01-02")
+	}
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	tx := ledger.TransactionResponse{
+		ID: id, ExternalID: req.ExternalID, Description: req.Description, Currency: req.Currency, Metadata: metadata,
+		OccurredAt: occurredAt.Format(time.RFC3339), ValueDate: valueDate, CreatedAt: at.Format(time.RFC3339),
+	}
+	debited := new(big.Rat)
+	for i, p := range req.Postings {
+		a := s.accounts[p.AccountCode]
+		if p.Direction == "credit" {
+			a.balance.Add(a.balance, amounts[i])
+		} else {
+			a.balance.Sub(a.balance, amounts[i])
+			if p.Currency == "" || p.Currency == req.Currency {
+				debited.Add(debited, amounts[i])
+			}
+		}
+		postingID, _ := s.next("posting")
+		currency := p.Currency
+		if currency == "" {
+			currency = req.Currency
+		}
+		tx.Postings = append(tx.Postings, ledger.PostingDetail{
+			ID: postingID, AccountCode: p.AccountCode, AccountName: a.Name, Direction: p.Direction,
+			Amount: amounts[i].FloatString(10), Currency: currency, Description: p.Description,
+		})
+	}
+	tx.Amount = debited.FloatString(10)
+
+	s.transactions = append(s.transactions, tx)
+	s.idempotency[req.IdempotencyKey] = id
+	s.appendEvent("ledger", id, "TransactionPosted", map[string]any{
+		"transaction_id": id, "external_id": req.ExternalID, "description": req.Description, "currency": req.Currency,
+		"occurred_at": tx.OccurredAt, "value_date": valueDate, "postings": req.Postings,
+	}, at)
+	return id, nil
+}
+
+func (s *store) transaction(id string) (ledger.TransactionResponse, error) {
+	for _, tx := range s.transactions {
+		if tx.ID == id {
+			return tx, nil
+		}
+	}
+	return ledger.TransactionResponse{}, errNotFound
+}
+
+func (s *store) appendEvent(aggregateType, aggregateID, eventType string, payload map[string]any, at time.Time) {
+	id, _ := s.next("event")
+	s.events = append(s.events, ledger.EventResponse{
+		ID: id, Sequence: int64(len(s.events) + 1), AggregateType: aggregateType, AggregateID: aggregateID,
+		EventType: eventType, Payload: payload, OccurredAt: at.Format(time.RFC3339), CreatedAt: at.Format(time.RFC3339),
+	})
+}
+
+func (s *store) balanceSummary() ledger.BalanceSummaryResponse {
+	totals := map[string]*big.Rat{}
+	for t := range accountTypes {
+		totals[t] = new(big.Rat)
+	}
+	for _, a := range s.accounts {
+		totals[a.Type].Add(totals[a.Type], a.balance)
+	}
+	summary := ledger.BalanceSummaryResponse{ByType: map[string]string{}}
+	for t, total := range totals {
+		summary.ByType[t] = total.FloatString(10)
+	}
+	summary.TotalAssets = summary.ByType["asset"]
+	summary.TotalLiabilities = summary.ByType["liability"]
+	summary.TotalEquity = summary.ByType["equity"]
+	summary.TotalRevenue = summary.ByType["revenue"]
+	summary.TotalExpenses = summary.ByType["expense"]
+	return summary
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"errors"
+	"testing"
+)
+
+func TestStoreIsDeterministic(t *testing.T) {
+	a, b := newStore("key", true), newStore("key", true)
+	if a.transactions[0].ID != b.transactions[0].ID || a.events[len(a.events)-1].ID != b.events[len(b.events)-1].ID {
+		t.Fatal("expected the same token to seed the same IDs")
+	}
+	if newStore("other", true).transactions[0].ID == a.transactions[0].ID {
+		t.Fatal("expected tokens to get distinct IDs")
+	}
+	if got := a.accountResponse(a.accounts["cash:main"]).Balance; got != "-10370.5000000000" {
+		t.Fatalf("unexpected seeded cash balance %s", got)
+	}
+}
+
+func TestStorePostTransaction(t *testing.T) {
+	s := newStore("key", true)
+	req := ledger.PostTransactionRequest{
+		IdempotencyKey: "k1",
+		Currency:       "USD",
+		Postings: []ledger.PostingInput{
+			{AccountCode: "customer:alice", Direction: "debit", Amount: "10"},
+			{AccountCode: "customer:bob", Direction: "credit", Amount: "10"},
+		},
+	}
+	id, err := s.postTransaction(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.postTransaction(req); again != id {
+		t.Fatal("expected the idempotency key to return the first transaction")
+	}
+
+	req.IdempotencyKey = "k2"
+	req.Postings[1].Amount = "9"
+	if _, err := s.postTransaction(req); !errors.Is(err, errInvalid) {
+		t.Fatalf("expected unbalanced postings to be rejected, got %v", err)
+	}
+	if _, err := s.createAccount("cash:main", "", "asset", nil); !errors.Is(err, errConflict) {
+		t.Fatalf("expected duplicate code conflict, got %v", err)
+	}
+}
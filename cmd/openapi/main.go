@@ -0,0 +1,31 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/openapi"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+)
+
+// openapi writes the v1 API's OpenAPI document, the one the API serves at /openapi.json:
+//
+//	openapi -o api/openapi.json
+func main() {
+	out := flag.String("o", "", "file to write the document to (stdout if empty)")
+	flag.Parse()
+
+	body, err := json.MarshalIndent(openapi.V1(), "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode the document: %v", err)
+	}
+	body = append(body, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(body)
+		return
+	}
+	if err := os.WriteFile(*out, body, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+}
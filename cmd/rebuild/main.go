@@ -0,0 +1,47 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/projector"
+	"context"
+	"flag"
+	"log"
+)
+
+// rebuild recomputes read-model columns that older projector versions did not fill,
+// without replaying the event stream:
+//
+//	rebuild -region eu   # backfill transaction amounts in the eu database
+func main() {
+	region := flag.String("region", "", "region whose database to rebuild (default region if empty)")
+	batchSize := flag.Int("batch", 1000, "transactions updated per statement")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cfg := config.Load()
+
+	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	router, err := db.NewRouter(ctx, pool, cfg.DatabaseRegions, nil, cfg.DefaultRegion)
+	if err != nil {
+		log.Fatalf("failed to connect to regional databases: %v", err)
+	}
+	defer router.Close()
+
+	regionPool, err := router.Pool(*region)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	updated, err := projector.BackfillTransactionAmounts(ctx, regionPool, *batchSize)
+	if err != nil {
+		log.Fatalf("failed to backfill transaction amounts (%d updated): %v", updated, err)
+	}
+	log.Printf("Backfilled amounts of %d transactions", updated)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/openapi"
+	"Go_FormanceLegder/internal/sdkgen"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// sdkgen writes the generated sources of the TypeScript and Python clients from an
+// OpenAPI document; with -check it writes nothing and fails if they are out of date:
+//
+//	sdkgen -spec api/openapi.json -o sdks [-check]
+func main() {
+	spec := flag.String("spec", "api/openapi.json", "OpenAPI document to generate from")
+	out := flag.String("o", "sdks", "directory of the clients")
+	check := flag.Bool("check", false, "fail if the generated sources differ instead of writing them")
+	flag.Parse()
+
+	body, err := os.ReadFile(*spec)
+	if err != nil {
+		log.Fatalf("failed to read the document: %v", err)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(body, &doc); err != nil {
+		log.Fatalf("failed to decode %s: %v", *spec, err)
+	}
+	files, err := sdkgen.Generate(&doc)
+	if err != nil {
+		log.Fatalf("failed to generate the clients: %v", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	stale := false
+	for _, path := range paths {
+		target := filepath.Join(*out, path)
+		if *check {
+			if current, err := os.ReadFile(target); err != nil || !bytes.Equal(current, files[path]) {
+				log.Printf("%s is out of date with %s", target, *spec)
+				stale = true
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			log.Fatalf("failed to create %s: %v", filepath.Dir(target), err)
+		}
+		if err := os.WriteFile(target, files[path], 0o644); err != nil {
+			log.Fatalf("failed to write %s: %v", target, err)
+		}
+	}
+	if stale {
+		log.Fatal("run scripts/generate-sdks.sh to regenerate the clients")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/projector"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// shadow-projector runs a build of the projector against the live event stream into a
+// staging schema, and compares the result with the live read model before cutover:
+//
+//	shadow-projector -schema shadow -reset   # rebuild the staging schema and replay all events
+//	shadow-projector -schema shadow -compare # print the diff; exits 1 unless identical
+func main() {
+	schema := flag.String("schema", "shadow", "staging schema for the shadow read model")
+	region := flag.String("region", "", "region whose database to project (default region if empty)")
+	reset := flag.Bool("reset", false, "recreate the staging schema and replay from the first event")
+	compare := flag.Bool("compare", false, "compare the shadow read model with the live one and exit")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.Load()
+
+	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	router, err := db.NewRouter(ctx, pool, cfg.DatabaseRegions, nil, cfg.DefaultRegion)
+	if err != nil {
+		log.Fatalf("failed to connect to regional databases: %v", err)
+	}
+	defer router.Close()
+
+	regionPool, err := router.Pool(*region)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *compare {
+		report, err := projector.CompareShadow(ctx, regionPool, *schema)
+		if err != nil {
+			log.Fatalf("failed to compare shadow projection: %v", err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		if !report.Matches() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *reset {
+		if err := projector.PrepareShadowSchema(ctx, regionPool, *schema); err != nil {
+			log.Fatalf("failed to prepare shadow schema: %v", err)
+		}
+		log.Printf("Shadow schema %s prepared", *schema)
+	}
+
+	proj := projector.NewShadowProjector(regionPool, *schema)
+	go func() {
+		log.Printf("Shadow projector starting (schema %s)...", *schema)
+		if err := proj.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("shadow projector error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+
+	log.Println("Shutting down shadow projector...")
+	cancel()
+}
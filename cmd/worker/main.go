@@ -1,15 +1,31 @@
 package main
 
 import (
+	"Go_FormanceLegder/internal/alerts"
+	"Go_FormanceLegder/internal/archive"
+	"Go_FormanceLegder/internal/backup"
+	"Go_FormanceLegder/internal/bankfeed"
 	"Go_FormanceLegder/internal/config"
 	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/hooks"
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/mail"
+	"Go_FormanceLegder/internal/offboarding"
+	"Go_FormanceLegder/internal/outbox"
 	"Go_FormanceLegder/internal/projector"
+	"Go_FormanceLegder/internal/schedule"
 	"Go_FormanceLegder/internal/webhook"
+	"Go_FormanceLegder/internal/workflow"
 	"context"
+	"encoding/json"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/riverqueue/river"
 	"github.com/riverqueue/river/riverdriver/riverpgxv5"
 )
@@ -20,48 +36,276 @@ func main() {
 
 	cfg := config.Load()
 
-	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	// The main database, with read failover to its standby when one is configured
+	control, err := db.NewCluster(ctx, db.ControlRegion, cfg.DatabaseURL, cfg.DatabaseStandbyURL)
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
+	pool := control.Pool
 	defer pool.Close()
 
+	// Regional ledger databases (data residency); each gets its own queue and projector
+	router, err := db.NewRouter(ctx, pool, cfg.DatabaseRegions, cfg.DatabaseStandbyRegions, cfg.DefaultRegion)
+	if err != nil {
+		log.Fatalf("failed to connect to regional databases: %v", err)
+	}
+	defer router.Close()
+	go control.Watch(ctx, cfg.FailoverCheckInterval)
+	router.Watch(ctx, cfg.FailoverCheckInterval)
+
+	// Shared by all regions so a host's cap holds for the whole process
+	limiter := webhook.NewHostLimiter(cfg.WebhookHostConcurrency, cfg.WebhookHostDelay)
+	endpointLimiter := webhook.NewEndpointLimiter()
+	webhook.DefaultEndpointLimit = webhook.EndpointLimit{MaxConcurrent: cfg.WebhookEndpointConcurrency,
+		PerSecond: cfg.WebhookEndpointRate}
+	if err := webhook.DefaultEndpointLimit.Validate(); err != nil {
+		log.Fatalf("invalid webhook endpoint limit: %v", err)
+	}
+	webhook.LegacySignature = cfg.WebhookLegacySignature
+	webhook.DefaultRetryPolicy = webhook.RetryPolicy{MaxAttempts: cfg.WebhookMaxAttempts, Backoff: cfg.WebhookBackoff,
+		Base: cfg.WebhookBackoffBase, MaxDelay: cfg.WebhookBackoffMax}
+	if err := webhook.DefaultRetryPolicy.Validate(); err != nil {
+		log.Fatalf("invalid webhook retry policy: %v", err)
+	}
+
+	// Optional outbox publishers, each relayed from every region's events with its own offset
+	publishers := map[string]outbox.Publisher{}
+	if len(cfg.KafkaBrokers) > 0 {
+		kafka := outbox.NewKafka(cfg.KafkaBrokers, cfg.KafkaTopic)
+		defer kafka.Close()
+		publishers["kafka"] = kafka
+	}
+	if cfg.NATSURL != "" {
+		nats, err := outbox.NewNATS(ctx, cfg.NATSURL, cfg.NATSStream, cfg.NATSSubject)
+		if err != nil {
+			log.Fatalf("failed to connect to NATS: %v", err)
+		}
+		defer nats.Close()
+		publishers["nats"] = nats
+	}
+
+	// Optional event and ledger archival to S3-compatible storage
+	var archiveStore *archive.S3
+	if cfg.S3Bucket != "" {
+		archiveStore = archive.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey)
+	}
+
+	monitor := &projector.Monitor{}
+
+	// Posting hooks shared by every region's ledger service
+	postingHooks := &hooks.Registry{}
+	if err := postingHooks.DialPlugins(cfg.Plugins, cfg.PluginTimeout, cfg.PluginFailOpen); err != nil {
+		log.Fatalf("failed to set up posting hooks: %v", err)
+	}
+
+	var riverClients []*river.Client[pgx.Tx]
+	for region, regionPool := range router.Pools() {
+		riverClient := startRegion(ctx, region, regionPool, limiter, endpointLimiter, cfg.WebhookDisableAfterFailures, publishers, monitor, cfg.ProjectorShards, cfg.ProjectorLagAlert, postingHooks)
+		riverClients = append(riverClients, riverClient)
+
+		if archiveStore != nil && cfg.EventArchiveAfter > 0 {
+			archiver := archive.NewArchiver(regionPool, archiveStore, cfg.EventArchiveAfter)
+			go func() {
+				log.Printf("Event archiver starting (region %s)...", region)
+				if err := archiver.Run(ctx); err != nil {
+					log.Printf("archiver error (region %s): %v", region, err)
+				}
+			}()
+		}
+		if archiveStore != nil && cfg.LedgerArchiveAfterMonths > 0 {
+			tier := archive.NewTier(archive.NewArchiver(regionPool, archiveStore, cfg.EventArchiveAfter), cfg.LedgerArchiveAfterMonths)
+			go func() {
+				log.Printf("Ledger archival starting (region %s)...", region)
+				if err := tier.Run(ctx); err != nil {
+					log.Printf("ledger archival error (region %s): %v", region, err)
+				}
+			}()
+		}
+	}
+
+	// Organization deletions past their grace period, across regions
+	offboarder := &offboarding.Service{
+		Control: pool,
+		Router:  router,
+		Store:   archiveStore,
+		Mailer:  mail.New(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom),
+		Grace:   cfg.OrgDeletionGrace,
+	}
+	go func() {
+		if err := offboarder.Run(ctx); err != nil {
+			log.Printf("offboarding error: %v", err)
+		}
+	}()
+
+	// Restores of the latest backups into a scratch database, checked for consistency
+	var backups *backup.Verifier
+	if len(cfg.BackupDirs) > 0 && cfg.BackupScratchURL != "" {
+		// Restoring replaces the scratch database's contents
+		live := map[string]bool{cfg.DatabaseURL: true}
+		for _, url := range cfg.DatabaseRegions {
+			live[url] = true
+		}
+		if live[cfg.BackupScratchURL] {
+			log.Fatalf("BACKUP_SCRATCH_URL must not be a live database")
+		}
+		backups = &backup.Verifier{
+			Dirs:       cfg.BackupDirs,
+			ScratchURL: cfg.BackupScratchURL,
+			Interval:   cfg.BackupVerifyInterval,
+			MaxAge:     cfg.BackupMaxAge,
+		}
+		go func() {
+			log.Println("Backup verification starting...")
+			if err := backups.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("backup verification error: %v", err)
+			}
+		}()
+	}
+
+	// Internal status endpoint for projector lag and backup alerts
+	metrics := http.NewServeMux()
+	metrics.Handle("GET /projectors", monitor.Handler())
+	metrics.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		projector.WriteMetrics(w, monitor.Statuses(r.Context()))
+		if backups != nil {
+			backups.WriteMetrics(w)
+		}
+	})
+	metrics.HandleFunc("GET /backups", func(w http.ResponseWriter, r *http.Request) {
+		results := []backup.Result{}
+		if backups != nil {
+			results = backups.Results()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"backups": results})
+	})
+	metricsServer := &http.Server{Addr: ":" + cfg.MetricsPort, Handler: metrics}
+	go func() {
+		log.Printf("Projector metrics listening on :%s", cfg.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("metrics server error: %v", err)
+		}
+	}()
+
+	log.Println("Worker processes started")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+
+	log.Println("Shutting down workers...")
+	cancel()
+	metricsServer.Close()
+	for _, riverClient := range riverClients {
+		riverClient.Stop(ctx)
+	}
+	log.Println("Workers stopped")
+}
+
+// startRegion starts the River workers and the projector for one database.
+func startRegion(ctx context.Context, region string, pool *pgxpool.Pool, limiter *webhook.HostLimiter, endpointLimiter *webhook.EndpointLimiter, disableAfter int, publishers map[string]outbox.Publisher, monitor *projector.Monitor, shards int, lagAlert time.Duration, postingHooks *hooks.Registry) *river.Client[pgx.Tx] {
 	// Setup River workers
 	workers := river.NewWorkers()
-	river.AddWorker(workers, &webhook.Worker{DB: pool})
+	webhookWorker := &webhook.Worker{DB: pool, Limiter: limiter, Endpoints: endpointLimiter, DisableAfter: disableAfter}
+	river.AddWorker(workers, webhookWorker)
+	river.AddWorker(workers, &webhook.BatchWorker{Deliveries: webhookWorker})
+	scheduleWorker := &schedule.Worker{DB: pool}
+	river.AddWorker(workers, scheduleWorker)
+	workflowWorker := &workflow.Worker{DB: pool}
+	river.AddWorker(workers, workflowWorker)
+	bankFeedWorker := &bankfeed.Worker{DB: pool}
+	river.AddWorker(workers, bankFeedWorker)
+	viewWorker := &schedule.ViewWorker{}
+	river.AddWorker(workers, viewWorker)
+	alertWorker := &alerts.Worker{DB: pool, LagThreshold: lagAlert}
+	river.AddWorker(workers, alertWorker)
 
 	riverClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{
 		Queues: map[string]river.QueueConfig{
 			river.QueueDefault: {MaxWorkers: 100},
 		},
 		Workers: workers,
+		PeriodicJobs: []*river.PeriodicJob{
+			river.NewPeriodicJob(
+				river.PeriodicInterval(time.Minute),
+				func() (river.JobArgs, *river.InsertOpts) {
+					return schedule.InstallmentArgs{}, nil
+				},
+				&river.PeriodicJobOpts{RunOnStart: true},
+			),
+			river.NewPeriodicJob(
+				river.PeriodicInterval(15*time.Minute),
+				func() (river.JobArgs, *river.InsertOpts) {
+					return bankfeed.SyncArgs{}, nil
+				},
+				nil,
+			),
+			river.NewPeriodicJob(
+				river.PeriodicInterval(5*time.Minute),
+				func() (river.JobArgs, *river.InsertOpts) {
+					return schedule.ViewArgs{}, nil
+				},
+				nil,
+			),
+			river.NewPeriodicJob(
+				river.PeriodicInterval(time.Minute),
+				func() (river.JobArgs, *river.InsertOpts) {
+					return alerts.CheckArgs{}, nil
+				},
+				nil,
+			),
+		},
 	})
 	if err != nil {
-		log.Fatalf("failed to create river client: %v", err)
+		log.Fatalf("failed to create river client for region %s: %v", region, err)
+	}
+
+	// Installment postings go through the ledger service like any other transaction,
+	// hooks included
+	scheduleWorker.Service = &ledger.Service{DB: pool, RiverClient: riverClient, Hooks: postingHooks}
+	workflowWorker.Service = scheduleWorker.Service
+	bankFeedWorker.Syncer = scheduleWorker.Service
+	viewWorker.Handler = &ledger.Handler{Service: scheduleWorker.Service}
+
+	// The projectors of every registered projection, each shard with its own offset
+	var projectors []*projector.Projector
+	for _, projection := range projector.Registered() {
+		projectors = append(projectors, projector.NewShardedProjectors(pool, projection, shards)...)
 	}
+	alertWorker.Projectors = projectors
 
 	// Start River
 	if err := riverClient.Start(ctx); err != nil {
-		log.Fatalf("failed to start river: %v", err)
+		log.Fatalf("failed to start river for region %s: %v", region, err)
+	}
+
+	for _, proj := range projectors {
+		monitor.Add(region, proj)
+		go func() {
+			log.Printf("Projector %s starting (region %s)...", proj.OffsetName(), region)
+			if err := proj.Run(ctx); err != nil {
+				log.Printf("projector %s error (region %s): %v", proj.OffsetName(), region, err)
+			}
+		}()
 	}
 
-	// Start projector
-	proj := projector.NewProjector(pool)
+	// Create the coming months' events partitions
 	go func() {
-		log.Println("Projector worker starting...")
-		if err := proj.Run(ctx); err != nil {
-			log.Printf("projector error: %v", err)
+		if err := db.MaintainEventPartitions(ctx, pool); err != nil {
+			log.Printf("event partitions error (region %s): %v", region, err)
 		}
 	}()
 
-	log.Println("Worker processes started")
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	for name, publisher := range publishers {
+		relay := outbox.NewRelay(pool, name, publisher)
+		go func() {
+			log.Printf("Outbox relay %s starting (region %s)...", name, region)
+			if err := relay.Run(ctx); err != nil {
+				log.Printf("outbox %s error (region %s): %v", name, region, err)
+			}
+		}()
+	}
 
-	log.Println("Shutting down workers...")
-	cancel()
-	riverClient.Stop(ctx)
-	log.Println("Workers stopped")
+	return riverClient
 }
@@ -0,0 +1,336 @@
+package conformance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type account struct {
+	Code    string `json:"code"`
+	Balance string `json:"balance"`
+}
+
+type posting struct {
+	AccountCode string `json:"account_code"`
+	Direction   string `json:"direction"`
+	Amount      string `json:"amount"`
+}
+
+type transactionRequest struct {
+	IdempotencyKey string    `json:"idempotency_key"`
+	ExternalID     string    `json:"external_id,omitempty"`
+	Currency       string    `json:"currency"`
+	Postings       []posting `json:"postings"`
+}
+
+type transaction struct {
+	ID       string    `json:"id"`
+	Currency string    `json:"currency"`
+	Postings []posting `json:"postings"`
+}
+
+type pagination struct {
+	HasMore           bool   `json:"has_more"`
+	ContinuationToken string `json:"continuation_token,omitempty"`
+	Count             int    `json:"count"`
+}
+
+// createAccounts creates an asset and a liability account for a check.
+func (s *Suite) createAccounts(ctx context.Context, check string) (asset, liability string, err error) {
+	asset, liability = s.RunID+":"+check+":asset", s.RunID+":"+check+":liability"
+	for code, typ := range map[string]string{asset: "asset", liability: "liability"} {
+		body := map[string]any{"code": code, "name": code, "type": typ}
+		if err := s.call(ctx, http.MethodPost, "/v1/accounts", body, nil); err != nil {
+			return "", "", err
+		}
+	}
+	return asset, liability, nil
+}
+
+func (s *Suite) post(ctx context.Context, req transactionRequest) (string, error) {
+	var resp struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	if err := s.call(ctx, http.MethodPost, "/v1/transactions", req, &resp); err != nil {
+		return "", err
+	}
+	if resp.TransactionID == "" {
+		return "", errors.New("POST /v1/transactions: no transaction_id in response")
+	}
+	return resp.TransactionID, nil
+}
+
+// waitForBalance polls until the account's balance equals want. Balances are projected
+// from events, so they may lag the transaction's acceptance.
+func (s *Suite) waitForBalance(ctx context.Context, code, want string) error {
+	wantRat, _ := new(big.Rat).SetString(want)
+	var last string
+	for {
+		var acc account
+		if err := s.call(ctx, http.MethodGet, "/v1/accounts?code="+url.QueryEscape(code), nil, &acc); err != nil {
+			return err
+		}
+		last = acc.Balance
+		if got, ok := new(big.Rat).SetString(acc.Balance); ok && got.Cmp(wantRat) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("balance of %s is %s, want %s", code, last, want)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// checkPostings posts a balanced transaction and checks it reads back unchanged, moves
+// both balances (credits increase a balance, debits decrease it) and that unbalanced
+// transactions are rejected.
+func checkPostings(ctx context.Context, s *Suite) error {
+	asset, liability, err := s.createAccounts(ctx, "postings")
+	if err != nil {
+		return err
+	}
+
+	id, err := s.post(ctx, transactionRequest{
+		IdempotencyKey: s.RunID + ":postings",
+		ExternalID:     s.RunID + ":postings",
+		Currency:       "USD",
+		Postings: []posting{
+			{AccountCode: asset, Direction: "debit", Amount: "12.34"},
+			{AccountCode: liability, Direction: "credit", Amount: "12.34"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var tx transaction
+	if err := s.call(ctx, http.MethodGet, "/v1/transactions?id="+url.QueryEscape(id), nil, &tx); err != nil {
+		return err
+	}
+	if tx.ID != id || tx.Currency != "USD" || len(tx.Postings) != 2 {
+		return fmt.Errorf("transaction %s read back as %+v", id, tx)
+	}
+	for _, p := range tx.Postings {
+		amount, ok := new(big.Rat).SetString(p.Amount)
+		if !ok || amount.Cmp(big.NewRat(1234, 100)) != 0 {
+			return fmt.Errorf("posting on %s has amount %s, want 12.34", p.AccountCode, p.Amount)
+		}
+	}
+
+	if err := s.waitForBalance(ctx, asset, "-12.34"); err != nil {
+		return err
+	}
+	if err := s.waitForBalance(ctx, liability, "12.34"); err != nil {
+		return err
+	}
+
+	_, err = s.post(ctx, transactionRequest{
+		IdempotencyKey: s.RunID + ":unbalanced",
+		Currency:       "USD",
+		Postings: []posting{
+			{AccountCode: asset, Direction: "debit", Amount: "10"},
+			{AccountCode: liability, Direction: "credit", Amount: "9"},
+		},
+	})
+	var status *statusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("unbalanced transaction: want 400, got %v", err)
+	}
+	var envelope struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(status.Body), &envelope) != nil || envelope.Error.Code != "VALIDATION_FAILED" {
+		return fmt.Errorf("unbalanced transaction: want a VALIDATION_FAILED error, got %s", status.Body)
+	}
+	return nil
+}
+
+// checkIdempotency retries a transaction with the same idempotency key and checks it
+// returns the first transaction and moves the balances once.
+func checkIdempotency(ctx context.Context, s *Suite) error {
+	asset, liability, err := s.createAccounts(ctx, "idempotency")
+	if err != nil {
+		return err
+	}
+
+	req := transactionRequest{
+		IdempotencyKey: s.RunID + ":idempotency",
+		Currency:       "USD",
+		Postings: []posting{
+			{AccountCode: asset, Direction: "debit", Amount: "5"},
+			{AccountCode: liability, Direction: "credit", Amount: "5"},
+		},
+	}
+	first, err := s.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	for range 2 {
+		again, err := s.post(ctx, req)
+		if err != nil {
+			return fmt.Errorf("retry with the same idempotency key: %w", err)
+		}
+		if again != first {
+			return fmt.Errorf("retry returned transaction %s, want %s", again, first)
+		}
+	}
+
+	if err := s.waitForBalance(ctx, liability, "5"); err != nil {
+		return err
+	}
+	// Give a duplicate time to be projected before checking it was not
+	time.Sleep(time.Second)
+	return s.waitForBalance(ctx, asset, "-5")
+}
+
+// checkPagination pages through the events two at a time and checks the pages are
+// newest first with no duplicates or gaps in between.
+func checkPagination(ctx context.Context, s *Suite) error {
+	var sequences []int64
+	token := ""
+	for page := 0; page < 5; page++ {
+		path := "/v1/events?limit=2"
+		if token != "" {
+			path += "&continuation_token=" + url.QueryEscape(token)
+		}
+		var resp struct {
+			Events []struct {
+				ID       string `json:"id"`
+				Sequence int64  `json:"sequence"`
+			} `json:"events"`
+			Pagination pagination `json:"pagination"`
+		}
+		if err := s.call(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return err
+		}
+		if len(resp.Events) > 2 || resp.Pagination.Count != len(resp.Events) {
+			return fmt.Errorf("page %d: %d events with count %d and limit 2", page+1, len(resp.Events), resp.Pagination.Count)
+		}
+		for _, e := range resp.Events {
+			if n := len(sequences); n > 0 && e.Sequence >= sequences[n-1] {
+				return fmt.Errorf("page %d: sequence %d after %d, want descending", page+1, e.Sequence, sequences[n-1])
+			}
+			sequences = append(sequences, e.Sequence)
+		}
+		if !resp.Pagination.HasMore {
+			break
+		}
+		if resp.Pagination.ContinuationToken == "" {
+			return fmt.Errorf("page %d: has_more without a continuation_token", page+1)
+		}
+		token = resp.Pagination.ContinuationToken
+	}
+	if len(sequences) < 2 {
+		return fmt.Errorf("need at least 2 events to check pagination, got %d", len(sequences))
+	}
+
+	err := s.call(ctx, http.MethodGet, "/v1/events?continuation_token=not-a-token", nil, nil)
+	var status *statusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("malformed continuation_token: want 400, got %v", err)
+	}
+	return nil
+}
+
+// checkWebhookSignatures replays the latest event to the suite's receiver and checks
+// its X-Ledger-Webhook-Signature is "t=<unix>,v1=<hex HMAC-SHA256 of t.body>" under the
+// replay secret, with a timestamp close to now.
+func checkWebhookSignatures(ctx context.Context, s *Suite) error {
+	if s.WebhookURL == "" {
+		return ErrSkipped
+	}
+
+	var events struct {
+		Events []struct {
+			Sequence int64 `json:"sequence"`
+		} `json:"events"`
+	}
+	if err := s.call(ctx, http.MethodGet, "/v1/events?limit=1", nil, &events); err != nil {
+		return err
+	}
+	if len(events.Events) == 0 {
+		return errors.New("no events to replay")
+	}
+
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	received := make(chan delivery, 1)
+	ln, err := net.Listen("tcp", s.WebhookListen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		select {
+		case received <- delivery{body, r.Header.Get("X-Ledger-Webhook-Signature")}:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	secret := "whsec_" + s.RunID
+	var replay struct {
+		Deliveries []struct {
+			Status     string `json:"status"`
+			HTTPStatus int    `json:"http_status"`
+		} `json:"deliveries"`
+	}
+	err = s.call(ctx, http.MethodPost, "/v1/webhook-replays", map[string]any{
+		"url":           s.WebhookURL,
+		"secret":        secret,
+		"from_sequence": events.Events[0].Sequence,
+		"limit":         1,
+	}, &replay)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case d := <-received:
+		var timestamp, signature string
+		for _, part := range strings.Split(d.signature, ",") {
+			key, value, _ := strings.Cut(part, "=")
+			switch key {
+			case "t":
+				timestamp = value
+			case "v1":
+				signature = value
+			}
+		}
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("X-Ledger-Webhook-Signature %q has no timestamp", d.signature)
+		}
+		if skew := time.Since(time.Unix(unix, 0)); skew > 5*time.Minute || skew < -5*time.Minute {
+			return fmt.Errorf("X-Ledger-Webhook-Signature timestamp is %v off", skew)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(d.body)
+		if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+			return fmt.Errorf("X-Ledger-Webhook-Signature %q does not match the body", d.signature)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("no webhook received at %s (replay: %+v)", s.WebhookURL, replay.Deliveries)
+	}
+}
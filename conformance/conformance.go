@@ -0,0 +1,156 @@
+// Package conformance checks that a ledger deployment behaves like the API contract:
+// postings move balances, idempotency keys deduplicate, pagination is stable and
+// webhooks are signed. It only talks HTTP, so it runs against any URL and API key, e.g.
+// from cmd/conformance or with
+//
+//	LEDGER_URL=https://ledger.example.com LEDGER_API_KEY=... go test ./conformance
+//
+// Checks create accounts and transactions prefixed with a run ID, so point it at a
+// ledger set aside for testing.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	BaseURL string
+	APIKey  string
+	// WebhookURL is where the deployment can reach WebhookListen, the address of the
+	// suite's webhook receiver. The webhook check is skipped without it.
+	WebhookURL    string
+	WebhookListen string
+	// Timeout bounds each check, including waiting for balances to be projected
+	Timeout    time.Duration
+	HTTPClient *http.Client
+}
+
+// Check is one named conformance check. Run returns errSkipped when the deployment or
+// config does not support it.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, s *Suite) error
+}
+
+type Result struct {
+	Name    string
+	Passed  bool
+	Skipped bool
+	Err     error
+}
+
+// ErrSkipped is returned by checks that could not run against this config.
+var ErrSkipped = errors.New("skipped")
+
+var Checks = []Check{
+	{"posting semantics", checkPostings},
+	{"idempotency", checkIdempotency},
+	{"pagination", checkPagination},
+	{"webhook signatures", checkWebhookSignatures},
+}
+
+// Suite holds one run's config and the run ID prefixing the data it creates.
+type Suite struct {
+	Config
+	RunID string
+}
+
+func New(cfg Config) *Suite {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.WebhookListen == "" {
+		cfg.WebhookListen = ":9099"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &Suite{Config: cfg, RunID: "conformance-" + uuid.NewString()[:8]}
+}
+
+// Run runs every check and returns their results in order.
+func (s *Suite) Run(ctx context.Context) []Result {
+	results := make([]Result, 0, len(Checks))
+	for _, c := range Checks {
+		results = append(results, s.RunCheck(ctx, c))
+	}
+	return results
+}
+
+func (s *Suite) RunCheck(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	err := c.Run(ctx, s)
+	switch {
+	case err == nil:
+		return Result{Name: c.Name, Passed: true}
+	case errors.Is(err, ErrSkipped):
+		return Result{Name: c.Name, Skipped: true}
+	default:
+		return Result{Name: c.Name, Err: err}
+	}
+}
+
+// statusError is an unexpected response status.
+type statusError struct {
+	Method, Path string
+	StatusCode   int
+	Body         string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// call sends a request and decodes a 2xx JSON response into out. Other statuses are
+// returned as *statusError.
+func (s *Suite) call(ctx context.Context, method, path string, body, out any) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.BaseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{Method: method, Path: path, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: decoding response: %w", method, path, err)
+	}
+	return nil
+}
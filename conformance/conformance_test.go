@@ -0,0 +1,34 @@
+package conformance
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// TestConformance runs the suite against LEDGER_URL with LEDGER_API_KEY. Set
+// LEDGER_WEBHOOK_URL (and LEDGER_WEBHOOK_LISTEN) to include the webhook check.
+func TestConformance(t *testing.T) {
+	baseURL, apiKey := os.Getenv("LEDGER_URL"), os.Getenv("LEDGER_API_KEY")
+	if baseURL == "" || apiKey == "" {
+		t.Skip("LEDGER_URL and LEDGER_API_KEY not set")
+	}
+
+	s := New(Config{
+		BaseURL:       baseURL,
+		APIKey:        apiKey,
+		WebhookURL:    os.Getenv("LEDGER_WEBHOOK_URL"),
+		WebhookListen: os.Getenv("LEDGER_WEBHOOK_LISTEN"),
+	})
+	for _, c := range Checks {
+		t.Run(c.Name, func(t *testing.T) {
+			r := s.RunCheck(context.Background(), c)
+			if r.Skipped {
+				t.Skip("not configured")
+			}
+			if !r.Passed {
+				t.Fatal(r.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package alerts tells ledgers about operational problems affecting them, as events
+// delivered like any other: each incident is recorded once when it starts and once
+// when it ends.
+package alerts
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/projector"
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+)
+
+// CheckArgs is the periodic job that raises and resolves the ledgers' alerts.
+type CheckArgs struct{}
+
+func (CheckArgs) Kind() string {
+	return "ledger_alerts"
+}
+
+const kindProjectorLag = "projector_lag"
+
+type Worker struct {
+	river.WorkerDefaults[CheckArgs]
+	DB *pgxpool.Pool
+
+	// Projectors whose lag is watched, those of the worker's database
+	Projectors []*projector.Projector
+	// LagThreshold is how long an event may wait for a projector; 0 watches nothing
+	LagThreshold time.Duration
+}
+
+// Work raises a projector lag alert for each ledger a projector trails by more than
+// LagThreshold, and resolves those whose ledger it has caught up on. Failing to check one
+// projector leaves its alerts as they are until the next run.
+func (w *Worker) Work(ctx context.Context, job *river.Job[CheckArgs]) error {
+	if w.LagThreshold <= 0 {
+		return nil
+	}
+	var failed int
+	for _, p := range w.Projectors {
+		if err := w.checkProjector(ctx, p); err != nil {
+			log.Printf("projector %s lag alerts: %v", p.OffsetName(), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to check %d projectors for lag", failed)
+	}
+	return nil
+}
+
+func (w *Worker) checkProjector(ctx context.Context, p *projector.Projector) error {
+	lagging, err := p.LaggingLedgers(ctx, w.LagThreshold)
+	if err != nil {
+		return err
+	}
+	laggingLedgers := make([]string, len(lagging))
+	for i, l := range lagging {
+		laggingLedgers[i] = l.LedgerID
+	}
+
+	for _, l := range lagging {
+		if err := w.raiseLag(ctx, p.OffsetName(), l); err != nil {
+			return err
+		}
+	}
+
+	rows, err := w.DB.Query(ctx, `
+		SELECT ledger_id FROM ledger_alerts
+		WHERE kind = $1 AND subject = $2 AND NOT (ledger_id::text = ANY($3))
+	`, kindProjectorLag, p.OffsetName(), laggingLedgers)
+	if err != nil {
+		return err
+	}
+	recovered, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, ledgerID := range recovered {
+		if err := w.resolveLag(ctx, p.OffsetName(), ledgerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// raiseLag records ProjectorLagExceeded for the ledger unless its alert is already raised.
+func (w *Worker) raiseLag(ctx context.Context, name string, l projector.LedgerLag) error {
+	tx, err := w.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO ledger_alerts (ledger_id, kind, subject) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, l.LedgerID, kindProjectorLag, name)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	err = appendEvent(ctx, tx, l.LedgerID, "ProjectorLagExceeded", &events.ProjectorLagExceeded{
+		Projector:        name,
+		PendingEvents:    l.PendingEvents,
+		OldestPendingAt:  l.OldestPendingAt.UTC(),
+		LagSeconds:       time.Since(l.OldestPendingAt).Seconds(),
+		ThresholdSeconds: w.LagThreshold.Seconds(),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// resolveLag records ProjectorLagRecovered for the ledger and clears its alert.
+func (w *Worker) resolveLag(ctx context.Context, name, ledgerID string) error {
+	tx, err := w.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var raisedAt time.Time
+	err = tx.QueryRow(ctx, `
+		DELETE FROM ledger_alerts WHERE ledger_id = $1 AND kind = $2 AND subject = $3
+		RETURNING raised_at
+	`, ledgerID, kindProjectorLag, name).Scan(&raisedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = appendEvent(ctx, tx, ledgerID, "ProjectorLagRecovered", &events.ProjectorLagRecovered{
+		Projector:   name,
+		AlertedAt:   raisedAt.UTC(),
+		RecoveredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// appendEvent records an alert event on the ledger and queues its webhook delivery in tx.
+func appendEvent(ctx context.Context, tx pgx.Tx, ledgerID, eventType string, payload events.Payload) error {
+	payloadJSON, err := events.Marshal(eventType, payload)
+	if err != nil {
+		return err
+	}
+	eventID := uuid.NewString()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO events (id, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at)
+		VALUES ($1, $2, 'ledger', $2, $3, $4, NOW())
+	`, eventID, ledgerID, eventType, payloadJSON)
+	if err != nil {
+		return err
+	}
+	client, err := river.ClientFromContextSafely[pgx.Tx](ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.InsertTx(ctx, tx, webhook.WebhookArgs{EventID: eventID, LedgerID: ledgerID}, nil)
+	return err
+}
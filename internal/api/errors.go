@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Error codes tell clients apart failures that share a status. Codes other than these
+// generic ones are defined next to the errors they describe.
+const (
+	CodeValidationFailed   = "VALIDATION_FAILED"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
+	CodeConflict           = "CONFLICT"
+	CodeGone               = "GONE"
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodeUnprocessable      = "UNPROCESSABLE"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeInternal           = "INTERNAL_ERROR"
+	CodeBadGateway         = "BAD_GATEWAY"
+	CodeUnavailable        = "SERVICE_UNAVAILABLE"
+)
+
+// ErrorResponse is the body of every error reply:
+//
+//	{"error":{"code":"VALIDATION_FAILED","message":"...","details":["..."]}}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// StatusCode returns the generic code of an HTTP status.
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeValidationFailed
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeValidationFailed
+}
+
+// Error replies with message and the generic code of status; it replaces http.Error.
+func Error(w http.ResponseWriter, message string, status int) {
+	Fail(w, status, StatusCode(status), message)
+}
+
+// WriteError replies with err and the generic code of status. The errors of an
+// errors.Join, e.g. those collected by a Validate method, are listed in details.
+func WriteError(w http.ResponseWriter, err error, status int) {
+	WriteErrorCode(w, err, status, StatusCode(status))
+}
+
+// WriteErrorCode is WriteError with a specific code.
+func WriteErrorCode(w http.ResponseWriter, err error, status int, code string) {
+	var details []string
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			details = append(details, e.Error())
+		}
+	}
+	message := err.Error()
+	if len(details) > 1 {
+		message = strings.Join(details, "; ")
+	} else {
+		details = nil
+	}
+	Fail(w, status, code, message, details...)
+}
+
+// Fail replies with an error envelope.
+func Fail(w http.ResponseWriter, status int, code, message string, details ...string) {
+	// As http.Error does, drop headers set for the response this one replaces
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Del("Content-Disposition")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message, Details: details}})
+}
+
+// Routes serves mux, replying with the error envelope instead of the mux's plain text
+// when no route matches a request or its method.
+func Routes(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		// The mux's own 404 or 405 handler: keep its status and Allow header
+		rec := &statusRecorder{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		if allow := rec.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		Error(w, strings.ToLower(http.StatusText(rec.status)), rec.status)
+	})
+}
+
+// statusRecorder keeps the status of a response and discards its body.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (r *statusRecorder) Header() http.Header         { return r.header }
+func (r *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (r *statusRecorder) WriteHeader(status int)      { r.status = status }
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const NDJSONContentType = "application/x-ndjson"
+
+// ndjsonFlushEvery is how many rows are buffered before flushing to the client.
+const ndjsonFlushEvery = 100
+
+// WantsNDJSON reports whether the request accepts newline-delimited JSON, which
+// listings stream without pagination.
+func WantsNDJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == NDJSONContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// NDJSONWriter streams one JSON value per line. Writes block while the client is slow
+// to read, so a streamed listing holds one row at a time, whatever its size.
+type NDJSONWriter struct {
+	enc   *json.Encoder
+	rc    *http.ResponseController
+	count int
+}
+
+func NewNDJSONWriter(w http.ResponseWriter) *NDJSONWriter {
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	return &NDJSONWriter{enc: json.NewEncoder(w), rc: http.NewResponseController(w)}
+}
+
+// Write encodes v as a line; an error means the client went away.
+func (s *NDJSONWriter) Write(v any) error {
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.count++
+	if s.count%ndjsonFlushEvery == 0 {
+		return s.rc.Flush()
+	}
+	return nil
+}
+
+// Fail ends a stream that broke after the 200 status was sent with an error line,
+// {"error": msg}, so clients can tell a failed export from a complete one.
+func (s *NDJSONWriter) Fail(msg string) {
+	s.enc.Encode(map[string]string{"error": msg})
+	s.rc.Flush()
+}
+
+func (s *NDJSONWriter) Close() error {
+	return s.rc.Flush()
+}
@@ -21,6 +21,7 @@ type PaginationResponse struct {
 type Cursor struct {
 	Timestamp time.Time `json:"timestamp"`
 	ID        string    `json:"id"`
+	Sequence  int64     `json:"sequence,omitempty"` // event position, for event listings
 }
 
 func EncodeCursor(cursor Cursor) (string, error) {
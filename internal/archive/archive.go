@@ -0,0 +1,308 @@
+// Package archive moves old events out of Postgres into S3-compatible object storage
+// as gzipped NDJSON, one object per batch of a ledger's events, and restores them on
+// demand.
+//
+// Only events every consumer is done with are archived: the projector and all outbox
+// relays must have passed them, and hold and API key events, whose current state is
+// folded from the event stream, are kept. Idempotency keys of archived events can be
+// reused, and shadow projections (cmd/shadow-projector) only see the events still in
+// Postgres. Tier builds on this to move whole inactive ledgers out of Postgres.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrNotFound        = errors.New("archive not found")
+	ErrAlreadyRestored = errors.New("archive already restored")
+)
+
+// Event is one archived row of the events table.
+type Event struct {
+	ID             string          `json:"id"`
+	Sequence       int64           `json:"sequence"`
+	LedgerID       string          `json:"ledger_id"`
+	AggregateType  string          `json:"aggregate_type"`
+	AggregateID    string          `json:"aggregate_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	IdempotencyKey *string         `json:"idempotency_key,omitempty"`
+}
+
+type Archiver struct {
+	DB    *pgxpool.Pool
+	Store *S3
+
+	// MaxAge is how long events stay in Postgres. It should exceed the webhook retry
+	// horizon, since deliveries load their event.
+	MaxAge    time.Duration
+	BatchSize int
+}
+
+func NewArchiver(db *pgxpool.Pool, store *S3, maxAge time.Duration) *Archiver {
+	return &Archiver{DB: db, Store: store, MaxAge: maxAge, BatchSize: 10000}
+}
+
+// Run archives eligible events hourly until ctx is done.
+func (a *Archiver) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := a.ArchiveAll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("event archival: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveAll archives every ledger's eligible events.
+func (a *Archiver) ArchiveAll(ctx context.Context) error {
+	rows, err := a.DB.Query(ctx, `
+		SELECT DISTINCT ledger_id FROM events WHERE created_at < $1 AND sequence <= (`+safeSequence+`)
+	`, now().Add(-a.MaxAge))
+	if err != nil {
+		return err
+	}
+	ledgerIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for _, ledgerID := range ledgerIDs {
+		for {
+			n, err := a.archiveBatch(ctx, ledgerID)
+			if err != nil {
+				return fmt.Errorf("ledger %s: %w", ledgerID, err)
+			}
+			if n < a.BatchSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// safeSequence is the highest sequence every live projector and outbox relay has
+// processed.
+const safeSequence = `
+	SELECT LEAST(
+		COALESCE((SELECT MIN(last_processed_sequence) FROM projector_offsets WHERE projector_name NOT LIKE 'shadow:%'), 0),
+		COALESCE((SELECT MIN(last_sequence) FROM outbox_offsets), 9223372036854775807)
+	)
+`
+
+// archiveBatch uploads one batch of a ledger's eligible events and deletes them in the
+// same transaction as recording the archive. The object key is derived from the
+// sequence range, so a retry after a failed commit overwrites the orphaned object.
+func (a *Archiver) archiveBatch(ctx context.Context, ledgerID string) (int, error) {
+	tx, err := a.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+eventColumns+`
+		FROM events e
+		WHERE e.ledger_id = $1
+		  AND e.created_at < $2
+		  AND e.sequence <= (`+safeSequence+`)
+		  AND e.aggregate_type NOT IN ('hold', 'api_key')
+		  AND NOT EXISTS (
+			SELECT 1 FROM event_archives a
+			WHERE a.ledger_id = e.ledger_id AND a.status = 'restored'
+			  AND e.sequence BETWEEN a.from_sequence AND a.to_sequence
+		  )
+		ORDER BY e.sequence
+		LIMIT $3
+		FOR UPDATE OF e
+	`, ledgerID, now().Add(-a.MaxAge), a.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	events, err := collectEvents(rows)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	key := fmt.Sprintf("events/%s/%020d-%020d.ndjson.gz", ledgerID, events[0].Sequence, events[len(events)-1].Sequence)
+	if err := a.archiveEvents(ctx, tx, ledgerID, key, "age", events); err != nil {
+		return 0, err
+	}
+	return len(events), tx.Commit(ctx)
+}
+
+// eventColumns are the events columns collectEvents scans, for an events table aliased e.
+const eventColumns = `e.id, e.sequence, e.ledger_id, e.aggregate_type, e.aggregate_id, e.event_type, e.payload,
+	e.occurred_at, e.created_at, e.idempotency_key`
+
+func collectEvents(rows pgx.Rows) ([]Event, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+		var e Event
+		err := row.Scan(&e.ID, &e.Sequence, &e.LedgerID, &e.AggregateType, &e.AggregateID, &e.EventType,
+			&e.Payload, &e.OccurredAt, &e.CreatedAt, &e.IdempotencyKey)
+		return e, err
+	})
+}
+
+// archiveEvents uploads events under key and, in tx, records the archive and deletes
+// them.
+func (a *Archiver) archiveEvents(ctx context.Context, tx pgx.Tx, ledgerID, key, reason string, events []Event) error {
+	body, err := Encode(events)
+	if err != nil {
+		return err
+	}
+	if err := a.Store.Put(ctx, key, body, "application/gzip"); err != nil {
+		return err
+	}
+
+	first, last := events[0], events[len(events)-1]
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO event_archives (ledger_id, object_key, reason, from_sequence, to_sequence, event_count, size_bytes,
+			oldest_event_at, newest_event_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, ledgerID, key, reason, first.Sequence, last.Sequence, len(events), len(body), first.CreatedAt, last.CreatedAt)
+	if err != nil {
+		return err
+	}
+	// Deliveries reference their event without a foreign key since events are partitioned
+	if _, err := tx.Exec(ctx, `DELETE FROM webhook_deliveries WHERE event_id = ANY($1::uuid[])`, ids); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM events WHERE id = ANY($1::uuid[])`, ids)
+	return err
+}
+
+// Restore puts an archive's events back with their original IDs and sequences, so
+// consumers that already processed them don't see them again. Events whose ID or
+// idempotency key was taken since are skipped. It returns how many were restored.
+func (a *Archiver) Restore(ctx context.Context, ledgerID, archiveID string) (int, error) {
+	tx, err := a.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var key, status string
+	err = tx.QueryRow(ctx, `
+		SELECT object_key, status FROM event_archives WHERE id::text = $1 AND ledger_id = $2 FOR UPDATE
+	`, archiveID, ledgerID).Scan(&key, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status == "restored" {
+		return 0, ErrAlreadyRestored
+	}
+
+	body, err := a.Store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	events, err := Decode(body)
+	if err != nil {
+		return 0, err
+	}
+
+	restored, err := restoreEvents(ctx, tx, ledgerID, key, events)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE event_archives SET status = 'restored', restored_at = NOW() WHERE id::text = $1
+	`, archiveID)
+	if err != nil {
+		return 0, err
+	}
+	return restored, tx.Commit(ctx)
+}
+
+// restoreEvents inserts archived events in tx with their original IDs and sequences,
+// skipping those whose ID or idempotency key was taken since.
+func restoreEvents(ctx context.Context, tx pgx.Tx, ledgerID, key string, events []Event) (int, error) {
+	if _, err := tx.Exec(ctx, `SELECT set_config('ledger.restoring_events', 'on', true)`); err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, e := range events {
+		if e.LedgerID != ledgerID {
+			return 0, fmt.Errorf("archive %s holds an event of ledger %s", key, e.LedgerID)
+		}
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO events (id, sequence, ledger_id, aggregate_type, aggregate_id, event_type, payload,
+				occurred_at, created_at, idempotency_key)
+			SELECT $1::uuid, $2::bigint, $3::uuid, $4::text, $5::uuid, $6::text, $7::jsonb, $8::timestamptz,
+				$9::timestamptz, $10::text
+			WHERE NOT EXISTS (
+				SELECT 1 FROM event_idempotency_keys WHERE ledger_id = $3::uuid AND idempotency_key = $10::text
+			)
+			ON CONFLICT DO NOTHING
+		`, e.ID, e.Sequence, e.LedgerID, e.AggregateType, e.AggregateID, e.EventType, e.Payload,
+			e.OccurredAt, e.CreatedAt, e.IdempotencyKey)
+		if err != nil {
+			return 0, err
+		}
+		restored += int(tag.RowsAffected())
+	}
+	return restored, nil
+}
+
+// Encode writes events as gzipped NDJSON, one event per line.
+func Encode(events []Event) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func Decode(body []byte) ([]Event, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var events []Event
+	dec := json.NewDecoder(zr)
+	for dec.More() {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	key := "idem-1"
+	events := []Event{
+		{ID: "e1", Sequence: 7, LedgerID: "l1", AggregateType: "ledger", EventType: "TransactionPosted",
+			Payload: json.RawMessage(`{"schema_version":2}`), OccurredAt: time.Unix(100, 0).UTC(), IdempotencyKey: &key},
+		{ID: "e2", Sequence: 9, LedgerID: "l1", AggregateType: "account", EventType: "AccountCreated",
+			Payload: json.RawMessage(`{}`), OccurredAt: time.Unix(200, 0).UTC()},
+	}
+	body, err := Encode(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(zr)
+	if lines := strings.Split(strings.TrimSpace(string(raw)), "\n"); len(lines) != 2 {
+		t.Fatalf("expected one line per event, got %q", raw)
+	}
+
+	decoded, err := Decode(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].Sequence != 7 || *decoded[0].IdempotencyKey != key || decoded[1].IdempotencyKey != nil {
+		t.Fatalf("unexpected round trip: %+v", decoded)
+	}
+	if string(decoded[0].Payload) != `{"schema_version":2}` {
+		t.Fatalf("unexpected payload %s", decoded[0].Payload)
+	}
+}
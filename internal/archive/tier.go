@@ -0,0 +1,278 @@
+package archive
+
+import (
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/projector"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tier moves ledgers nobody has written to for months into the archival tier: their
+// events go to object storage as "ledger" archives and their transactions and postings
+// are dropped, leaving accounts with a zero balance. The first API request for an
+// archived ledger rehydrates it (see Middleware) by restoring those events and
+// replaying every posting, including those of archives made by age.
+//
+// Hold and API key events stay in Postgres, as do holds and accounts, which are
+// small. Ledgers with settlement batches stay hot, since their members reference
+// transactions.
+type Tier struct {
+	*Archiver
+	Projector *projector.Projector
+
+	// InactiveMonths is how long a ledger goes without new events, accounts or a
+	// rehydration before it is archived; 0 only rehydrates.
+	InactiveMonths int
+}
+
+func NewTier(archiver *Archiver, inactiveMonths int) *Tier {
+	return &Tier{Archiver: archiver, Projector: projector.NewProjector(archiver.DB, projector.Ledger), InactiveMonths: inactiveMonths}
+}
+
+// Run archives inactive ledgers daily until ctx is done.
+func (t *Tier) Run(ctx context.Context) error {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := t.ArchiveInactive(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("ledger archival: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveInactive moves every inactive hot ledger to the archival tier.
+func (t *Tier) ArchiveInactive(ctx context.Context) error {
+	if t.InactiveMonths <= 0 {
+		return nil
+	}
+	rows, err := t.DB.Query(ctx, `
+		SELECT l.id::text
+		FROM ledgers l
+		WHERE l.storage_tier = 'hot'
+		  AND COALESCE(l.rehydrated_at, l.created_at) < $1
+		  AND NOT EXISTS (SELECT 1 FROM events e WHERE e.ledger_id = l.id AND e.created_at >= $1)
+		  AND NOT EXISTS (SELECT 1 FROM accounts a WHERE a.ledger_id = l.id AND a.created_at >= $1)
+		  AND NOT EXISTS (SELECT 1 FROM settlement_batches s WHERE s.ledger_id = l.id)
+	`, now().AddDate(0, -t.InactiveMonths, 0))
+	if err != nil {
+		return err
+	}
+	ledgerIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	for _, ledgerID := range ledgerIDs {
+		if err := t.archiveLedger(ctx, ledgerID); err != nil {
+			return fmt.Errorf("ledger %s: %w", ledgerID, err)
+		}
+	}
+	return nil
+}
+
+// archiveLedger moves a ledger's events to object storage and drops its read model in
+// one transaction. Ledgers with events the projector or a relay hasn't passed yet are
+// left for the next run.
+func (t *Tier) archiveLedger(ctx context.Context, ledgerID string) error {
+	tx, err := t.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var pending bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM events WHERE ledger_id = l.id AND sequence > (`+safeSequence+`))
+		FROM ledgers l
+		WHERE l.id = $1 AND l.storage_tier = 'hot'
+		FOR UPDATE OF l
+	`, ledgerID).Scan(&pending)
+	if errors.Is(err, pgx.ErrNoRows) || pending {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.Query(ctx, `
+			SELECT `+eventColumns+`
+			FROM events e
+			WHERE e.ledger_id = $1 AND e.aggregate_type NOT IN ('hold', 'api_key')
+			ORDER BY e.sequence
+			LIMIT $2
+		`, ledgerID, t.BatchSize)
+		if err != nil {
+			return err
+		}
+		events, err := collectEvents(rows)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+		// A separate prefix, since these ranges may overlap restored "age" archives
+		key := fmt.Sprintf("ledgers/%s/%020d-%020d.ndjson.gz", ledgerID, events[0].Sequence, events[len(events)-1].Sequence)
+		if err := t.archiveEvents(ctx, tx, ledgerID, key, "ledger", events); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM postings WHERE ledger_id = $1`,
+		`DELETE FROM transactions WHERE ledger_id = $1`,
+		`UPDATE accounts SET balance = 0 WHERE ledger_id = $1`,
+		`UPDATE ledgers SET storage_tier = 'archived', archived_at = NOW() WHERE id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, stmt, ledgerID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Rehydrate brings an archived ledger back to the hot tier: "ledger" archives are
+// restored into events and dropped, and the postings of every archive and of the events
+// in Postgres are replayed into the read model. Hot ledgers cost a single lookup.
+func (t *Tier) Rehydrate(ctx context.Context, ledgerID string) error {
+	var tier string
+	err := t.DB.QueryRow(ctx, `SELECT storage_tier FROM ledgers WHERE id = $1`, ledgerID).Scan(&tier)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && tier == "hot") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	tx, err := t.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent requests wait here, then find the ledger hot
+	err = tx.QueryRow(ctx, `SELECT storage_tier FROM ledgers WHERE id = $1 FOR UPDATE`, ledgerID).Scan(&tier)
+	if err != nil || tier == "hot" {
+		return err
+	}
+
+	type archived struct{ ID, Key, Reason string }
+	rows, err := tx.Query(ctx, `
+		SELECT id::text, object_key, reason FROM event_archives
+		WHERE ledger_id = $1 AND status = 'archived'
+		ORDER BY from_sequence
+	`, ledgerID)
+	if err != nil {
+		return err
+	}
+	archives, err := pgx.CollectRows(rows, pgx.RowToStructByPos[archived])
+	if err != nil {
+		return err
+	}
+
+	for _, a := range archives {
+		body, err := t.Store.Get(ctx, a.Key)
+		if err != nil {
+			return err
+		}
+		events, err := Decode(body)
+		if err != nil {
+			return err
+		}
+
+		// Restored events are replayed with the ones already in Postgres below
+		if a.Reason == "ledger" {
+			if _, err := restoreEvents(ctx, tx, ledgerID, a.Key, events); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM event_archives WHERE id::text = $1`, a.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, e := range events {
+			if err := t.replay(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+eventColumns+`
+		FROM events e
+		WHERE e.ledger_id = $1 AND e.event_type = 'TransactionPosted'
+		ORDER BY e.sequence
+	`, ledgerID)
+	if err != nil {
+		return err
+	}
+	events, err := collectEvents(rows)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := t.replay(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE ledgers SET storage_tier = 'hot', archived_at = NULL, rehydrated_at = NOW() WHERE id = $1
+	`, ledgerID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("rehydrated ledger %s from %d archives in %s", ledgerID, len(archives), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// replay projects an archived posting; the other event types' read models were kept.
+func (t *Tier) replay(ctx context.Context, tx pgx.Tx, e Event) error {
+	if e.EventType != "TransactionPosted" {
+		return nil
+	}
+	payload, err := events.Decode(e.EventType, e.Payload)
+	if err == nil {
+		err = t.Projector.Apply(ctx, tx, projector.Event{ID: e.ID, Sequence: e.Sequence, LedgerID: e.LedgerID,
+			Type: e.EventType, OccurredAt: e.OccurredAt, Payload: payload})
+	}
+	if err != nil {
+		return fmt.Errorf("replay event %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// Middleware rehydrates the authenticated ledger before serving the request, so reads
+// and posts against an archived ledger see its full history.
+func (t *Tier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.FromContext(r.Context())
+		if err == nil {
+			if err := t.Rehydrate(r.Context(), principal.LedgerID); err != nil {
+				log.Printf("rehydrate ledger %s: %v", principal.LedgerID, err)
+				w.Header().Set("Retry-After", "5")
+				http.Error(w, "ledger is being restored from the archival tier, retry shortly", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,14 +1,24 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrConsentRevoked is returned for an impersonated session whose consent was revoked.
+var ErrConsentRevoked = errors.New("support consent revoked")
+
 type Claims struct {
 	UserID string `json:"sub"`
 	OrgID  string `json:"org_id"`
+
+	// ImpersonatorID is the support user acting as UserID, set on impersonated sessions
+	ImpersonatorID string `json:"imp,omitempty"`
+	ConsentID      string `json:"consent,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -41,3 +51,43 @@ func ValidateJWT(tokenString string, secret []byte) (*Claims, error) {
 
 	return nil, jwt.ErrSignatureInvalid
 }
+
+// ValidateSession validates a dashboard session like ValidateJWT. An impersonated session
+// is also checked against its consent, which the customer can revoke to end the session
+// before it expires.
+func ValidateSession(ctx context.Context, db *pgxpool.Pool, tokenString string, secret []byte) (*Claims, error) {
+	claims, err := ValidateJWT(tokenString, secret)
+	if err != nil || claims.ConsentID == "" {
+		return claims, err
+	}
+	var revoked bool
+	err = db.QueryRow(ctx, `
+		SELECT revoked_at IS NOT NULL FROM support_consents WHERE id::text = $1
+	`, claims.ConsentID).Scan(&revoked)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrConsentRevoked
+	}
+	return claims, nil
+}
+
+// GenerateImpersonationJWT issues a session as userID for a support user, under the
+// consent the user granted.
+func GenerateImpersonationJWT(userID, orgID, impersonatorID, consentID string, ttl time.Duration, secret []byte) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:         userID,
+		OrgID:          orgID,
+		ImpersonatorID: impersonatorID,
+		ConsentID:      consentID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImpersonationJWT(t *testing.T) {
+	secret := []byte("test-secret")
+
+	token, err := GenerateImpersonationJWT("user-1", "org-1", "support-1", "consent-1", time.Hour, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateJWT(token, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "user-1" || claims.OrgID != "org-1" || claims.ImpersonatorID != "support-1" || claims.ConsentID != "consent-1" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	token, err = GenerateJWT("user-1", "org-1", time.Hour, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := ValidateJWT(token, secret); err != nil || claims.ImpersonatorID != "" {
+		t.Fatalf("regular session should not be impersonated: %+v, %v", claims, err)
+	}
+}
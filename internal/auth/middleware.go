@@ -17,6 +17,8 @@ type Principal struct {
 	OrganizationID string
 	ProjectID      string
 	LedgerID       string
+	Region         string // data residency region of the organization ("" = default)
+	UserEmail      string // dashboard user, for requests made with a session instead of an API key
 }
 
 type contextKey string
@@ -56,7 +58,7 @@ func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 
 		ctx := r.Context()
 		row := m.DB.QueryRow(ctx, `
-			SELECT k.id, l.id, p.id, o.id
+			SELECT k.id, l.id, p.id, o.id, COALESCE(o.region, '')
 			FROM api_keys k
 			JOIN ledgers l ON l.id = k.ledger_id
 			JOIN projects p ON p.id = l.project_id
@@ -67,7 +69,7 @@ func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 		`, keyHash)
 
 		var principal Principal
-		err = row.Scan(&principal.APIKeyID, &principal.LedgerID, &principal.ProjectID, &principal.OrganizationID)
+		err = row.Scan(&principal.APIKeyID, &principal.LedgerID, &principal.ProjectID, &principal.OrganizationID, &principal.Region)
 		if err != nil {
 			http.Error(w, "invalid api key", http.StatusUnauthorized)
 			return
@@ -78,6 +80,12 @@ func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// WithPrincipal returns ctx carrying p, for callers authenticated by other means than an
+// API key (e.g. a dashboard session) that reuse the API-key handlers.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
 func FromContext(ctx context.Context) (Principal, error) {
 	p, ok := ctx.Value(principalKey).(Principal)
 	if !ok {
@@ -0,0 +1,315 @@
+// Package backup checks that database backups can actually be restored. Periodically,
+// the newest pg_dump archive of each database is restored into a scratch database and
+// the restored ledger data goes through consistency checks; the outcome is logged and
+// exposed as Prometheus metrics, so a backup that stopped being taken, no longer
+// restores or restores inconsistent data raises an alert before it is needed.
+//
+// Backups are custom-format archives (pg_dump -Fc) with a .dump extension, one
+// directory per database; how they get there is up to the operator.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNoBackup = errors.New("no backup found")
+
+// Verifier restores and checks the backups of Dirs, keyed by database (region) name.
+type Verifier struct {
+	Dirs       map[string]string
+	ScratchURL string // restored into; its contents are replaced on every run
+	PgRestore  string // pg_restore binary; "pg_restore" when empty
+	Interval   time.Duration
+	MaxAge     time.Duration // a newer backup than this must exist
+
+	mu      sync.Mutex
+	results map[string]*stats
+}
+
+// Result is the outcome of verifying one database's newest backup.
+type Result struct {
+	Database   string        `json:"database"`
+	Backup     string        `json:"backup,omitempty"`
+	BackupAge  time.Duration `json:"-"`
+	Duration   time.Duration `json:"-"`
+	Checks     []Check       `json:"checks"`
+	Error      string        `json:"error,omitempty"`
+	VerifiedAt time.Time     `json:"verified_at"`
+}
+
+func (r Result) OK() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, c := range r.Checks {
+		if c.Violations > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check is a consistency check and the rows of the restored data violating it.
+type Check struct {
+	Name       string `json:"name"`
+	Violations int64  `json:"violations"`
+}
+
+type stats struct {
+	last        Result
+	successes   int64
+	failures    int64
+	lastSuccess time.Time
+}
+
+// checks are run against the restored database; each counts the rows violating one
+// invariant of the ledger.
+var checks = []struct{ name, sql string }{
+	{"unbalanced_transactions", `
+		SELECT COUNT(*) FROM (
+			SELECT transaction_id, currency FROM postings
+			GROUP BY transaction_id, currency
+			HAVING SUM(CASE direction WHEN 'credit' THEN amount ELSE -amount END) <> 0
+		) unbalanced`},
+	{"account_balance_mismatches", `
+		SELECT COUNT(*) FROM accounts a
+		WHERE a.balance <> COALESCE((
+			SELECT SUM(CASE p.direction WHEN 'credit' THEN p.amount ELSE -p.amount END)
+			FROM postings p WHERE p.account_id = a.id
+		), 0)`},
+	{"orphaned_postings", `
+		SELECT COUNT(*) FROM postings p
+		WHERE NOT EXISTS (SELECT 1 FROM transactions t WHERE t.id = p.transaction_id AND t.ledger_id = p.ledger_id)`},
+	{"offsets_past_events", `
+		SELECT COUNT(*) FROM projector_offsets
+		WHERE last_processed_sequence > COALESCE((SELECT MAX(sequence) FROM events), 0)
+		  AND EXISTS (SELECT 1 FROM events)`},
+}
+
+// Run verifies every database's backup each Interval until ctx is done.
+func (v *Verifier) Run(ctx context.Context) error {
+	ticker := time.NewTicker(v.Interval)
+	defer ticker.Stop()
+
+	for {
+		v.VerifyAll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// VerifyAll verifies the databases one after the other, since they share the scratch
+// database.
+func (v *Verifier) VerifyAll(ctx context.Context) []Result {
+	names := make([]string, 0, len(v.Dirs))
+	for name := range v.Dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []Result
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		r := v.Verify(ctx, name)
+		if r.OK() {
+			log.Printf("backup %s of %s restored and checked in %s", r.Backup, name, r.Duration.Round(time.Second))
+		} else {
+			log.Printf("backup verification of %s failed: %s", name, r.summary())
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Verify restores the newest backup of a database into the scratch database and runs
+// the consistency checks on it.
+func (v *Verifier) Verify(ctx context.Context, database string) Result {
+	start := time.Now()
+	r := Result{Database: database}
+	err := v.verify(ctx, &r)
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.Duration = time.Since(start)
+	r.VerifiedAt = time.Now()
+	v.record(r)
+	return r
+}
+
+func (v *Verifier) verify(ctx context.Context, r *Result) error {
+	path, modTime, err := latestBackup(v.Dirs[r.Database])
+	if err != nil {
+		return err
+	}
+	r.Backup = filepath.Base(path)
+	r.BackupAge = time.Since(modTime)
+	if v.MaxAge > 0 && r.BackupAge > v.MaxAge {
+		return fmt.Errorf("newest backup %s is %s old", r.Backup, r.BackupAge.Round(time.Minute))
+	}
+
+	pgRestore := v.PgRestore
+	if pgRestore == "" {
+		pgRestore = "pg_restore"
+	}
+	cmd := exec.CommandContext(ctx, pgRestore, "--clean", "--if-exists", "--no-owner", "--no-privileges",
+		"--exit-on-error", "--dbname", v.ScratchURL, path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("restore %s: %w: %s", r.Backup, err, strings.TrimSpace(string(out)))
+	}
+
+	conn, err := pgx.Connect(ctx, v.ScratchURL)
+	if err != nil {
+		return fmt.Errorf("connect to scratch database: %w", err)
+	}
+	defer conn.Close(ctx)
+	for _, c := range checks {
+		check := Check{Name: c.name}
+		if err := conn.QueryRow(ctx, c.sql).Scan(&check.Violations); err != nil {
+			return fmt.Errorf("check %s: %w", c.name, err)
+		}
+		r.Checks = append(r.Checks, check)
+	}
+	return nil
+}
+
+// latestBackup returns the most recently modified .dump file in dir.
+func latestBackup(dir string) (string, time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var newest string
+	var newestTime time.Time
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".dump" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest, newestTime = filepath.Join(dir, e.Name()), info.ModTime()
+		}
+	}
+	if newest == "" {
+		return "", time.Time{}, fmt.Errorf("%w in %s", ErrNoBackup, dir)
+	}
+	return newest, newestTime, nil
+}
+
+func (r Result) summary() string {
+	if r.Error != "" {
+		return r.Error
+	}
+	var failed []string
+	for _, c := range r.Checks {
+		if c.Violations > 0 {
+			failed = append(failed, fmt.Sprintf("%s: %d", c.Name, c.Violations))
+		}
+	}
+	return fmt.Sprintf("backup %s is inconsistent (%s)", r.Backup, strings.Join(failed, ", "))
+}
+
+func (v *Verifier) record(r Result) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.results == nil {
+		v.results = map[string]*stats{}
+	}
+	s, ok := v.results[r.Database]
+	if !ok {
+		s = &stats{}
+		v.results[r.Database] = s
+	}
+	s.last = r
+	if r.OK() {
+		s.successes++
+		s.lastSuccess = r.VerifiedAt
+	} else {
+		s.failures++
+	}
+}
+
+// Results returns the last verification of each database verified so far.
+func (v *Verifier) Results() []Result {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	results := make([]Result, 0, len(v.results))
+	for _, s := range v.results {
+		results = append(results, s.last)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Database < results[j].Database })
+	return results
+}
+
+// WriteMetrics writes the verification outcomes in the Prometheus text exposition
+// format, e.g. to alert when ledger_backup_last_success_timestamp_seconds is over a day
+// old.
+func (v *Verifier) WriteMetrics(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	names := make([]string, 0, len(v.results))
+	for name := range v.results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	labels := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	fmt.Fprint(w, "# HELP ledger_backup_verifications_total Backup verifications since the worker started.\n"+
+		"# TYPE ledger_backup_verifications_total counter\n")
+	for _, name := range names {
+		s := v.results[name]
+		fmt.Fprintf(w, "ledger_backup_verifications_total{database=\"%s\",result=\"success\"} %d\n", labels.Replace(name), s.successes)
+		fmt.Fprintf(w, "ledger_backup_verifications_total{database=\"%s\",result=\"failure\"} %d\n", labels.Replace(name), s.failures)
+	}
+
+	gauges := []struct {
+		name, help string
+		value      func(*stats) string
+	}{
+		{"ledger_backup_last_verification_ok", "Whether the last verification restored a consistent backup (1) or not (0).",
+			func(s *stats) string {
+				if s.last.OK() {
+					return "1"
+				}
+				return "0"
+			}},
+		{"ledger_backup_last_success_timestamp_seconds", "Unix time of the last successful verification.",
+			func(s *stats) string {
+				if s.lastSuccess.IsZero() {
+					return "0"
+				}
+				return fmt.Sprint(s.lastSuccess.Unix())
+			}},
+		{"ledger_backup_age_seconds", "Age of the backup checked by the last verification.",
+			func(s *stats) string { return fmt.Sprintf("%.0f", s.last.BackupAge.Seconds()) }},
+		{"ledger_backup_restore_duration_seconds", "Duration of the last verification.",
+			func(s *stats) string { return fmt.Sprintf("%.3f", s.last.Duration.Seconds()) }},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{database=\"%s\"} %s\n", g.name, labels.Replace(name), g.value(v.results[name]))
+		}
+	}
+}
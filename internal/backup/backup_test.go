@@ -0,0 +1,65 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatestBackup(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := latestBackup(dir); !errors.Is(err, ErrNoBackup) {
+		t.Fatalf("expected ErrNoBackup for an empty directory, got %v", err)
+	}
+
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"ledger-1.dump": 48 * time.Hour,
+		"ledger-2.dump": 24 * time.Hour,
+		"ledger-3.sql":  time.Hour, // not a custom-format archive
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, modTime, err := latestBackup(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "ledger-2.dump" {
+		t.Errorf("got %s, want ledger-2.dump", filepath.Base(path))
+	}
+	if age := now.Sub(modTime); age < 23*time.Hour || age > 25*time.Hour {
+		t.Errorf("unexpected backup age %s", age)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	v := &Verifier{}
+	v.record(Result{Database: "eu", Backup: "a.dump", Checks: []Check{{Name: "orphaned_postings"}}, VerifiedAt: time.Unix(1700000000, 0)})
+	v.record(Result{Database: "eu", Backup: "b.dump", Checks: []Check{{Name: "orphaned_postings", Violations: 2}}})
+	v.record(Result{Database: "us", Error: "no backup found"})
+
+	var b strings.Builder
+	v.WriteMetrics(&b)
+	out := b.String()
+	for _, want := range []string{
+		`ledger_backup_verifications_total{database="eu",result="success"} 1`,
+		`ledger_backup_verifications_total{database="eu",result="failure"} 1`,
+		`ledger_backup_last_verification_ok{database="eu"} 0`,
+		`ledger_backup_last_success_timestamp_seconds{database="eu"} 1700000000`,
+		`ledger_backup_last_success_timestamp_seconds{database="us"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package bankfeed
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlaidFetchPagesAndSkipsPending(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Cursor string `json:"cursor"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Cursor == "" {
+			w.Write([]byte(`{"added":[
+				{"transaction_id":"t1","account_id":"acc","amount":12.5,"iso_currency_code":"USD","date":"2026-03-01","name":"Coffee"},
+				{"transaction_id":"t2","account_id":"acc","amount":3,"iso_currency_code":"USD","date":"2026-03-01","name":"Bus","pending":true}
+			],"next_cursor":"c1","has_more":true}`))
+			return
+		}
+		w.Write([]byte(`{"added":[
+			{"transaction_id":"t3","account_id":"acc","amount":-1000,"iso_currency_code":"USD","date":"2026-03-02","name":"Payroll"},
+			{"transaction_id":"t4","account_id":"other","amount":5,"iso_currency_code":"USD","date":"2026-03-02","name":"Other account"}
+		],"next_cursor":"c2","has_more":false}`))
+	}))
+	defer srv.Close()
+
+	lines, cursor, err := Plaid{}.Fetch(context.Background(), Credentials{AccessToken: "tok", AccountID: "acc", BaseURL: srv.URL}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != "c2" || len(lines) != 2 {
+		t.Fatalf("unexpected cursor %q or lines %+v", cursor, lines)
+	}
+	// Plaid's outflows are positive; lines are signed from the holder's view
+	if lines[0].Amount.FloatString(2) != "-12.50" || lines[1].Amount.FloatString(2) != "1000.00" {
+		t.Fatalf("unexpected amounts %s %s", lines[0].Amount.FloatString(2), lines[1].Amount.FloatString(2))
+	}
+}
+
+func TestTrueLayerAndNordigenCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/data/v1/accounts/acc/transactions":
+			w.Write([]byte(`{"results":[
+				{"transaction_id":"tl1","timestamp":"2026-03-04T00:00:00+00:00","description":"RENT","amount":-950.00,"currency":"GBP"}
+			]}`))
+		case "/api/v2/accounts/acc/transactions/":
+			w.Write([]byte(`{"transactions":{"booked":[
+				{"internalTransactionId":"n1","bookingDate":"2026-03-05","transactionAmount":{"amount":"250.10","currency":"EUR"},
+				 "debtorName":"ACME","remittanceInformationUnstructured":"Invoice 7"}
+			],"pending":[]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	creds := Credentials{AccessToken: "tok", AccountID: "acc", BaseURL: srv.URL}
+
+	lines, cursor, err := TrueLayer{}.Fetch(context.Background(), creds, "2026-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != "2026-03-04" || len(lines) != 1 || lines[0].Amount.Sign() >= 0 {
+		t.Fatalf("unexpected truelayer result %q %+v", cursor, lines)
+	}
+
+	lines, cursor, err = Nordigen{}.Fetch(context.Background(), creds, "2026-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != "2026-03-05" || len(lines) != 1 || lines[0].ExternalID != "n1" || lines[0].Counterparty != "ACME" {
+		t.Fatalf("unexpected nordigen result %q %+v", cursor, lines)
+	}
+
+	if _, _, err := (TrueLayer{}).Fetch(context.Background(), Credentials{AccessToken: "bad", AccountID: "acc", BaseURL: srv.URL}, ""); err == nil {
+		t.Fatal("expected an error for a rejected token")
+	}
+}
+
+func TestConfigRuleFor(t *testing.T) {
+	c := Config{
+		BankAccount: "bank:main",
+		AutoPost:    true,
+		Rules: []Rule{
+			{Match: "stripe", Direction: "in", Account: "psp:stripe"},
+			{Direction: "out", Account: "expenses:unsorted"},
+		},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	in := Line{Amount: big.NewRat(100, 1), Description: "STRIPE PAYOUT 123"}
+	if r, ok := c.RuleFor(in); !ok || r.Account != "psp:stripe" {
+		t.Fatalf("unexpected rule for inflow: %+v", r)
+	}
+	out := Line{Amount: big.NewRat(-5, 1), Description: "Stripe fee"}
+	if r, ok := c.RuleFor(out); !ok || r.Account != "expenses:unsorted" {
+		t.Fatalf("unexpected rule for outflow: %+v", r)
+	}
+	if _, ok := c.RuleFor(Line{Amount: big.NewRat(7, 1), Description: "Refund"}); ok {
+		t.Fatal("expected no rule for an unmatched inflow")
+	}
+
+	if err := (Config{AutoPost: true}).Validate(); err == nil {
+		t.Fatal("expected bank_account and rules to be required")
+	}
+}
@@ -0,0 +1,84 @@
+package bankfeed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Nordigen reads GoCardless Bank Account Data (formerly Nordigen) booked
+// transactions. Like TrueLayer, the cursor is the last booking date seen.
+type Nordigen struct{}
+
+func (Nordigen) Fetch(ctx context.Context, creds Credentials, cursor string) ([]Line, string, error) {
+	from := dateCursor(cursor)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		baseURL(creds, "https://bankaccountdata.gocardless.com")+"/api/v2/accounts/"+url.PathEscape(creds.AccountID)+
+			"/transactions/?date_from="+from, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+creds.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var page struct {
+		Transactions struct {
+			Booked []struct {
+				TransactionID         string `json:"transactionId"`
+				InternalTransactionID string `json:"internalTransactionId"`
+				BookingDate           string `json:"bookingDate"`
+				TransactionAmount     struct {
+					Amount   string `json:"amount"`
+					Currency string `json:"currency"`
+				} `json:"transactionAmount"`
+				CreditorName   string `json:"creditorName"`
+				DebtorName     string `json:"debtorName"`
+				RemittanceInfo string `json:"remittanceInformationUnstructured"`
+				AdditionalInfo string `json:"additionalInformation"`
+			} `json:"booked"`
+		} `json:"transactions"`
+	}
+	if err := doJSON(req, "nordigen", &page); err != nil {
+		return nil, "", err
+	}
+
+	var lines []Line
+	next := from
+	for _, t := range page.Transactions.Booked {
+		id := t.TransactionID
+		if id == "" {
+			// Not every bank provides a transaction id
+			id = t.InternalTransactionID
+		}
+		bookedAt, err := time.Parse("2006-01-02", t.BookingDate)
+		if err != nil {
+			return nil, "", fmt.Errorf("nordigen: transaction %s: invalid booking date %q", id, t.BookingDate)
+		}
+		amount, err := parseAmount(t.TransactionAmount.Amount)
+		if err != nil {
+			return nil, "", fmt.Errorf("nordigen: transaction %s: %w", id, err)
+		}
+		counterparty := t.DebtorName
+		if amount.Sign() < 0 {
+			counterparty = t.CreditorName
+		}
+		description := t.RemittanceInfo
+		if description == "" {
+			description = t.AdditionalInfo
+		}
+		lines = append(lines, Line{
+			ExternalID:   id,
+			Amount:       amount,
+			Currency:     t.TransactionAmount.Currency,
+			BookedAt:     bookedAt,
+			Description:  description,
+			Counterparty: counterparty,
+		})
+		if t.BookingDate > next {
+			next = t.BookingDate
+		}
+	}
+	return lines, next, nil
+}
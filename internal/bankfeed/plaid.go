@@ -0,0 +1,99 @@
+package bankfeed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Plaid reads /transactions/sync. Its cursor is Plaid's own sync cursor; pending
+// transactions are skipped until Plaid reports them booked. Plaid amounts are positive
+// for money out, so they are negated.
+type Plaid struct{}
+
+func (Plaid) Fetch(ctx context.Context, creds Credentials, cursor string) ([]Line, string, error) {
+	var lines []Line
+	for {
+		reqBody, err := json.Marshal(map[string]any{
+			"client_id":    creds.ClientID,
+			"secret":       creds.Secret,
+			"access_token": creds.AccessToken,
+			"cursor":       cursor,
+			"count":        500,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+			baseURL(creds, "https://production.plaid.com")+"/transactions/sync", bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		var page struct {
+			Added []struct {
+				TransactionID   string      `json:"transaction_id"`
+				AccountID       string      `json:"account_id"`
+				Amount          json.Number `json:"amount"`
+				IsoCurrencyCode string      `json:"iso_currency_code"`
+				Date            string      `json:"date"`
+				Name            string      `json:"name"`
+				MerchantName    string      `json:"merchant_name"`
+				Pending         bool        `json:"pending"`
+			} `json:"added"`
+			NextCursor string `json:"next_cursor"`
+			HasMore    bool   `json:"has_more"`
+		}
+		if err := doJSON(req, "plaid", &page); err != nil {
+			return nil, "", err
+		}
+
+		for _, t := range page.Added {
+			if t.Pending || (creds.AccountID != "" && t.AccountID != creds.AccountID) {
+				continue
+			}
+			bookedAt, err := time.Parse("2006-01-02", t.Date)
+			if err != nil {
+				return nil, "", fmt.Errorf("plaid: transaction %s: invalid date %q", t.TransactionID, t.Date)
+			}
+			amount, err := parseAmount(t.Amount.String())
+			if err != nil {
+				return nil, "", fmt.Errorf("plaid: transaction %s: %w", t.TransactionID, err)
+			}
+			amount.Neg(amount)
+			lines = append(lines, Line{
+				ExternalID:   t.TransactionID,
+				Amount:       amount,
+				Currency:     t.IsoCurrencyCode,
+				BookedAt:     bookedAt,
+				Description:  t.Name,
+				Counterparty: t.MerchantName,
+			})
+		}
+
+		cursor = page.NextCursor
+		if !page.HasMore {
+			return lines, cursor, nil
+		}
+	}
+}
+
+// doJSON performs req and decodes a 2xx JSON response into dst.
+func doJSON(req *http.Request, provider string, dst any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, provider); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", provider, err)
+	}
+	return nil
+}
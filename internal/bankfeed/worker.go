@@ -0,0 +1,59 @@
+package bankfeed
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+)
+
+// SyncArgs is the periodic job that syncs every active bank feed.
+type SyncArgs struct{}
+
+func (SyncArgs) Kind() string {
+	return "bank_feed_sync"
+}
+
+// Syncer fetches, stages and (for auto-post feeds) posts one feed's new lines. It is
+// implemented by ledger.Service; the interface keeps this package free of the ledger.
+type Syncer interface {
+	SyncBankFeed(ctx context.Context, ledgerID, feedID string) error
+}
+
+type Worker struct {
+	river.WorkerDefaults[SyncArgs]
+	DB     *pgxpool.Pool
+	Syncer Syncer
+}
+
+// Work syncs the active feeds one by one. A failing feed records its error and does
+// not hold back the others; it is retried on the next run.
+func (w *Worker) Work(ctx context.Context, job *river.Job[SyncArgs]) error {
+	rows, err := w.DB.Query(ctx, `
+		SELECT id, ledger_id FROM bank_feeds WHERE status = 'active' ORDER BY last_synced_at NULLS FIRST
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to load bank feeds: %w", err)
+	}
+
+	type feed struct{ ID, LedgerID string }
+	var feeds []feed
+	for rows.Next() {
+		var f feed
+		if err := rows.Scan(&f.ID, &f.LedgerID); err != nil {
+			rows.Close()
+			return err
+		}
+		feeds = append(feeds, f)
+	}
+	rows.Close()
+
+	for _, f := range feeds {
+		if err := w.Syncer.SyncBankFeed(ctx, f.LedgerID, f.ID); err != nil {
+			log.Printf("bank feed %s: sync failed: %v", f.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package calendar holds business-day calendars and the recurrence rules schedules
+// are planned with. Dates are civil dates, represented as midnight UTC.
+package calendar
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Calendar tells business days apart from weekends and holidays.
+type Calendar struct {
+	Weekend  []time.Weekday
+	Holidays map[string]string // name by YYYY-MM-DD
+}
+
+// Default is a calendar with Saturday and Sunday weekends and no holidays.
+func Default() Calendar {
+	return Calendar{Weekend: []time.Weekday{time.Saturday, time.Sunday}, Holidays: map[string]string{}}
+}
+
+// Date truncates t to its civil date.
+func Date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseDate reads a YYYY-MM-DD date.
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(dateLayout, s)
+}
+
+// FormatDate writes d as YYYY-MM-DD.
+func FormatDate(d time.Time) string {
+	return d.Format(dateLayout)
+}
+
+// ParseWeekday reads an English day name, e.g. "saturday" or "Sat".
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(s)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+// IsBusinessDay reports whether d is neither a weekend day nor a holiday.
+func (c Calendar) IsBusinessDay(d time.Time) bool {
+	for _, w := range c.Weekend {
+		if d.Weekday() == w {
+			return false
+		}
+	}
+	_, holiday := c.Holidays[FormatDate(d)]
+	return !holiday
+}
+
+// Policy says what happens to a date that is not a business day.
+type Policy string
+
+const (
+	PolicyNone      Policy = "none"      // keep the date
+	PolicyFollowing Policy = "following" // move to the next business day
+	PolicyPreceding Policy = "preceding" // move to the previous business day
+	// Next business day, unless that is in the following month: then the previous one
+	PolicyModifiedFollowing Policy = "modified_following"
+	PolicySkip              Policy = "skip" // drop the date
+)
+
+// ParsePolicy reads a policy; empty is PolicyNone.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case "":
+		return PolicyNone, nil
+	case PolicyNone, PolicyFollowing, PolicyPreceding, PolicyModifiedFollowing, PolicySkip:
+		return p, nil
+	}
+	return "", fmt.Errorf("holiday policy must be none, following, preceding, modified_following or skip")
+}
+
+// A calendar without a business day in this many days is treated as broken rather
+// than searched forever.
+const maxNonBusinessDays = 366
+
+// Dates looks at most this many dates of a sequence.
+const maxCandidates = 100000
+
+// Adjust applies policy to d. It returns false when the date is dropped.
+func (c Calendar) Adjust(d time.Time, policy Policy) (time.Time, bool) {
+	if policy == PolicyNone || c.IsBusinessDay(d) {
+		return d, true
+	}
+	switch policy {
+	case PolicyFollowing:
+		return c.step(d, 1)
+	case PolicyPreceding:
+		return c.step(d, -1)
+	case PolicyModifiedFollowing:
+		next, ok := c.step(d, 1)
+		if ok && next.Month() == d.Month() {
+			return next, true
+		}
+		return c.step(d, -1)
+	}
+	return time.Time{}, false
+}
+
+// AddBusinessDays moves d by n business days, forward or back; 0 returns d.
+func (c Calendar) AddBusinessDays(d time.Time, n int) (time.Time, bool) {
+	dir := 1
+	if n < 0 {
+		dir, n = -1, -n
+	}
+	for ; n > 0; n-- {
+		next, ok := c.step(d, dir)
+		if !ok {
+			return time.Time{}, false
+		}
+		d = next
+	}
+	return d, true
+}
+
+// step returns the nearest business day after (dir 1) or before (dir -1) d.
+func (c Calendar) step(d time.Time, dir int) (time.Time, bool) {
+	for range maxNonBusinessDays {
+		d = d.AddDate(0, 0, dir)
+		if c.IsBusinessDay(d) {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Dates returns up to count dates of seq adjusted by policy, strictly increasing: a
+// date adjusted onto or before the previous one is dropped rather than repeated. It
+// gives up after maxCandidates dates of seq, so a sequence that never yields stops.
+func (c Calendar) Dates(seq iter.Seq[time.Time], count int, policy Policy) []time.Time {
+	var dates []time.Time
+	candidates := 0
+	for d := range seq {
+		if len(dates) == count || candidates == maxCandidates {
+			break
+		}
+		candidates++
+		adjusted, ok := c.Adjust(d, policy)
+		if !ok || (len(dates) > 0 && !adjusted.After(dates[len(dates)-1])) {
+			continue
+		}
+		dates = append(dates, adjusted)
+	}
+	return dates
+}
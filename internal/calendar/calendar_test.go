@@ -0,0 +1,168 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func dates(t *testing.T, values ...string) []time.Time {
+	t.Helper()
+	var out []time.Time
+	for _, v := range values {
+		d, err := ParseDate(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func format(ds []time.Time) string {
+	var out []string
+	for _, d := range ds {
+		out = append(out, FormatDate(d))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestAdjust(t *testing.T) {
+	cal := Default()
+	cal.Holidays["2026-12-25"] = "Christmas"
+	cal.Holidays["2026-01-30"] = "Closed"
+
+	tests := []struct {
+		date   string
+		policy Policy
+		want   string // empty when dropped
+	}{
+		{"2026-12-24", PolicyFollowing, "2026-12-24"},         // business day, kept
+		{"2026-12-25", PolicyNone, "2026-12-25"},              // holiday, kept
+		{"2026-12-25", PolicyFollowing, "2026-12-28"},         // Friday holiday, past the weekend
+		{"2026-12-25", PolicyPreceding, "2026-12-24"},         // to Thursday
+		{"2026-01-31", PolicyFollowing, "2026-02-02"},         // Saturday, into February
+		{"2026-01-31", PolicyModifiedFollowing, "2026-01-29"}, // stays in January, skipping the 30th
+		{"2026-12-25", PolicySkip, ""},
+	}
+	for _, tt := range tests {
+		got, ok := cal.Adjust(dates(t, tt.date)[0], tt.policy)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("%s %s: expected the date dropped, got %s", tt.date, tt.policy, FormatDate(got))
+			}
+			continue
+		}
+		if !ok || FormatDate(got) != tt.want {
+			t.Errorf("%s %s: got %s (%v), want %s", tt.date, tt.policy, FormatDate(got), ok, tt.want)
+		}
+	}
+}
+
+func TestAddBusinessDays(t *testing.T) {
+	cal := Default()
+	cal.Holidays["2026-12-28"] = "Boxing Day observed"
+	d := dates(t, "2026-12-24")[0]
+	if got, _ := cal.AddBusinessDays(d, 2); FormatDate(got) != "2026-12-29" {
+		t.Errorf("expected 2026-12-29, got %s", FormatDate(got))
+	}
+	if got, _ := cal.AddBusinessDays(dates(t, "2026-12-29")[0], -1); FormatDate(got) != "2026-12-25" {
+		t.Errorf("expected 2026-12-25, got %s", FormatDate(got))
+	}
+
+	closed := Calendar{Weekend: []time.Weekday{0, 1, 2, 3, 4, 5, 6}}
+	if _, ok := closed.AddBusinessDays(d, 1); ok {
+		t.Error("expected a calendar without business days to fail")
+	}
+}
+
+func TestRuleAll(t *testing.T) {
+	tests := []struct {
+		rule  string
+		start string
+		count int
+		want  string
+	}{
+		{"FREQ=DAILY;INTERVAL=2", "2026-03-01", 3, "2026-03-01,2026-03-03,2026-03-05"},
+		{"FREQ=DAILY;BYDAY=MO,FR", "2026-03-01", 3, "2026-03-02,2026-03-06,2026-03-09"},
+		{"FREQ=WEEKLY", "2026-03-04", 3, "2026-03-04,2026-03-11,2026-03-18"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", "2026-03-04", 4, "2026-03-05,2026-03-17,2026-03-19,2026-03-31"},
+		{"FREQ=MONTHLY", "2026-01-31", 3, "2026-01-31,2026-03-31,2026-05-31"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-15", 3, "2026-01-31,2026-02-28,2026-03-31"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "2026-01-01", 2, "2026-01-30,2026-02-27"},
+		{"FREQ=MONTHLY;BYDAY=2MO", "2026-01-01", 2, "2026-01-12,2026-02-09"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2026-01-01", 3, "2026-01-30,2026-02-27,2026-03-31"},
+		{"FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR", "2026-01-01", 2, "2026-02-13,2026-03-13"},
+	}
+	for _, tt := range tests {
+		r, err := ParseRule(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.rule, err)
+		}
+		got := Calendar{}.Dates(r.All(dates(t, tt.start)[0]), tt.count, PolicyNone)
+		if format(got) != tt.want {
+			t.Errorf("%s from %s: got %s, want %s", tt.rule, tt.start, format(got), tt.want)
+		}
+	}
+}
+
+func TestLastBusinessDayWithHolidays(t *testing.T) {
+	cal := Default()
+	cal.Holidays["2026-03-31"] = "Closed"
+	r, err := ParseRule("FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cal.Dates(r.All(dates(t, "2026-02-01")[0]), 2, PolicyPreceding)
+	if format(got) != "2026-02-27,2026-03-30" {
+		t.Fatalf("got %s", format(got))
+	}
+}
+
+func TestDatesDropsCollisions(t *testing.T) {
+	cal := Default()
+	r, _ := ParseRule("FREQ=DAILY")
+	got := cal.Dates(r.All(dates(t, "2026-03-06")[0]), 3, PolicyFollowing)
+	// Saturday and Sunday both move to Monday, which is kept once
+	if format(got) != "2026-03-06,2026-03-09,2026-03-10" {
+		t.Fatalf("got %s", format(got))
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"", "BYDAY=MO", "FREQ=YEARLY", "FREQ=DAILY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO", "FREQ=DAILY;BYMONTHDAY=1", "FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYSETPOS=1", "FREQ=MONTHLY;COUNT=3", "FREQ",
+	} {
+		if _, err := ParseRule(rule); err == nil {
+			t.Errorf("expected %q to be rejected", rule)
+		}
+	}
+	if _, err := ParsePolicy("nearest"); err == nil {
+		t.Error("expected an unknown policy to be rejected")
+	}
+	if p, _ := ParsePolicy(""); p != PolicyNone {
+		t.Error("expected an empty policy to be none")
+	}
+}
+
+func TestFridaySaturdayWeekend(t *testing.T) {
+	fri, err := ParseWeekday("Friday")
+	if err != nil || fri != time.Friday {
+		t.Fatalf("got %v, %v", fri, err)
+	}
+	if sat, _ := ParseWeekday("sat"); sat != time.Saturday {
+		t.Fatalf("got %v", sat)
+	}
+	if _, err := ParseWeekday("weekend"); err == nil {
+		t.Fatal("expected an unknown day to be rejected")
+	}
+
+	c := Calendar{Weekend: []time.Weekday{time.Friday, time.Saturday}}
+	// Thursday 2024-03-07 plus one business day is Sunday
+	got, _ := c.AddBusinessDays(dates(t, "2024-03-07")[0], 1)
+	if FormatDate(got) != "2024-03-10" {
+		t.Fatalf("got %s, want 2024-03-10", FormatDate(got))
+	}
+}
@@ -0,0 +1,239 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is a recurrence in a subset of RFC 5545 RRULE syntax:
+//
+//	FREQ=DAILY|WEEKLY|MONTHLY  required
+//	INTERVAL=n                 every n days, weeks or months; default 1
+//	BYDAY=MO,WE,FR             those weekdays; monthly also 1MO (first Monday), -1FR (last Friday)
+//	BYMONTHDAY=15,-1           monthly, those days of the month; negative counts from the end
+//	BYSETPOS=-1                of each period's dates, only those positions
+//
+// Months lacking a requested day are skipped, as in RRULE. Monthly on the last business
+// day is FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1 with the preceding holiday policy.
+type Rule struct {
+	Freq       string // daily, weekly or monthly
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	BySetPos   []int
+}
+
+// WeekdayNum is a BYDAY entry: the N-th Day of the month, counting from the end when
+// negative, or every Day when N is 0.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+const (
+	maxInterval = 1000
+	// All stops after this many periods, e.g. about 27 years of days
+	maxPeriods = 10000
+)
+
+// ParseRule reads a rule, with or without an "RRULE:" prefix.
+func ParseRule(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, errors.New("recurrence is empty")
+	}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("recurrence part %q must be KEY=VALUE", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToLower(value)
+			if r.Freq != "daily" && r.Freq != "weekly" && r.Freq != "monthly" {
+				return r, errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 || r.Interval > maxInterval {
+				return r, fmt.Errorf("INTERVAL must be between 1 and %d", maxInterval)
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(strings.ToUpper(value), ",") {
+				if len(item) < 2 {
+					return r, fmt.Errorf("invalid BYDAY %q", item)
+				}
+				day, ok := weekdays[item[len(item)-2:]]
+				if !ok {
+					return r, fmt.Errorf("invalid BYDAY %q", item)
+				}
+				wd := WeekdayNum{Day: day}
+				if prefix := item[:len(item)-2]; prefix != "" {
+					if wd.N, err = strconv.Atoi(prefix); err != nil || wd.N == 0 || wd.N < -5 || wd.N > 5 {
+						return r, fmt.Errorf("invalid BYDAY %q", item)
+					}
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = parseInts(value, 31); err != nil {
+				return r, fmt.Errorf("BYMONTHDAY: %w", err)
+			}
+		case "BYSETPOS":
+			if r.BySetPos, err = parseInts(value, 366); err != nil {
+				return r, fmt.Errorf("BYSETPOS: %w", err)
+			}
+		default:
+			return r, fmt.Errorf("unsupported recurrence part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, errors.New("FREQ is required")
+	}
+	if r.Freq != "monthly" {
+		if len(r.ByMonthDay) > 0 {
+			return r, errors.New("BYMONTHDAY needs FREQ=MONTHLY")
+		}
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return r, errors.New("numbered BYDAY needs FREQ=MONTHLY")
+			}
+		}
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+		return r, errors.New("BYSETPOS needs BYDAY or BYMONTHDAY")
+	}
+	return r, nil
+}
+
+// parseInts reads a comma-separated list of non-zero integers within ±limit.
+func parseInts(value string, limit int) ([]int, error) {
+	var ints []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < -limit || n > limit {
+			return nil, fmt.Errorf("%q must be a non-zero number between -%d and %d", item, limit, limit)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// All yields the rule's dates on or after start, in order, starting with the period
+// (day, Monday-based week or month) that holds start.
+func (r Rule) All(start time.Time) iter.Seq[time.Time] {
+	start = Date(start)
+	return func(yield func(time.Time) bool) {
+		for k := range maxPeriods {
+			for _, d := range r.setPos(r.period(start, k*r.Interval)) {
+				if !d.Before(start) && !yield(d) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// period returns the sorted candidate dates of the n-th period after start's.
+func (r Rule) period(start time.Time, n int) []time.Time {
+	switch r.Freq {
+	case "daily":
+		d := start.AddDate(0, 0, n)
+		if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(wd WeekdayNum) bool { return wd.Day == d.Weekday() }) {
+			return nil
+		}
+		return []time.Time{d}
+
+	case "weekly":
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*n)
+		if len(r.ByDay) == 0 {
+			return []time.Time{monday.AddDate(0, 0, (int(start.Weekday())+6)%7)}
+		}
+		var dates []time.Time
+		for _, wd := range r.ByDay {
+			dates = append(dates, monday.AddDate(0, 0, (int(wd.Day)+6)%7))
+		}
+		return sortedUnique(dates)
+	}
+
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	days := first.AddDate(0, 1, -1).Day()
+	var byMonthDay, byDay []time.Time
+	for _, md := range r.ByMonthDay {
+		if md < 0 {
+			md = days + 1 + md
+		}
+		if md >= 1 && md <= days {
+			byMonthDay = append(byMonthDay, first.AddDate(0, 0, md-1))
+		}
+	}
+	for _, wd := range r.ByDay {
+		var matches []time.Time
+		for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+			if d.Weekday() == wd.Day {
+				matches = append(matches, d)
+			}
+		}
+		switch {
+		case wd.N == 0:
+			byDay = append(byDay, matches...)
+		case wd.N > 0 && wd.N <= len(matches):
+			byDay = append(byDay, matches[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(matches):
+			byDay = append(byDay, matches[len(matches)+wd.N])
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0 && len(r.ByDay) > 0:
+		var both []time.Time
+		for _, d := range byMonthDay {
+			if slices.ContainsFunc(byDay, d.Equal) {
+				both = append(both, d)
+			}
+		}
+		return sortedUnique(both)
+	case len(r.ByMonthDay) > 0:
+		return sortedUnique(byMonthDay)
+	case len(r.ByDay) > 0:
+		return sortedUnique(byDay)
+	}
+	if start.Day() > days {
+		return nil
+	}
+	return []time.Time{first.AddDate(0, 0, start.Day()-1)}
+}
+
+// setPos keeps the BYSETPOS positions of a period's dates, if the rule has any.
+func (r Rule) setPos(dates []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return dates
+	}
+	var kept []time.Time
+	for _, pos := range r.BySetPos {
+		if pos > 0 && pos <= len(dates) {
+			kept = append(kept, dates[pos-1])
+		} else if pos < 0 && -pos <= len(dates) {
+			kept = append(kept, dates[len(dates)+pos])
+		}
+	}
+	return sortedUnique(kept)
+}
+
+func sortedUnique(dates []time.Time) []time.Time {
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(dates, time.Time.Equal)
+}
@@ -0,0 +1,82 @@
+package clearing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Mapping describes how to read one clearing file layout (one per card network
+// or processor) and which ledger accounts each record type is posted to.
+type Mapping struct {
+	Delimiter  string  `json:"delimiter,omitempty"`   // defaults to ","
+	TimeFormat string  `json:"time_format,omitempty"` // Go layout, defaults to RFC3339
+	MinorUnits int     `json:"minor_units,omitempty"` // amount column is in minor units (e.g. 2 for cents)
+	Currency   string  `json:"currency,omitempty"`    // used when the file has no currency column
+	Columns    Columns `json:"columns"`
+
+	// ExternalIDPrefix is prepended to the record reference to form the ledger external_id
+	ExternalIDPrefix string `json:"external_id_prefix,omitempty"`
+	Rules            []Rule `json:"rules"`
+}
+
+// Columns names the header columns holding each field.
+type Columns struct {
+	Reference  string `json:"reference"`
+	Amount     string `json:"amount"`
+	Currency   string `json:"currency,omitempty"`
+	OccurredAt string `json:"occurred_at"`
+	Type       string `json:"type,omitempty"` // optional; without it the "*" rule applies to every record
+}
+
+// Rule posts records of a given type from the debit account to the credit account.
+// A negative amount (e.g. a refund or chargeback) reverses the direction.
+type Rule struct {
+	Type          string `json:"type"` // record type value, or "*" for any
+	DebitAccount  string `json:"debit_account"`
+	CreditAccount string `json:"credit_account"`
+}
+
+func (m Mapping) Validate() error {
+	var errs []error
+	if m.Columns.Reference == "" || m.Columns.Amount == "" || m.Columns.OccurredAt == "" {
+		errs = append(errs, errors.New("columns.reference, columns.amount and columns.occurred_at required"))
+	}
+	if m.Columns.Currency == "" && m.Currency == "" {
+		errs = append(errs, errors.New("columns.currency or a default currency required"))
+	}
+	if len(m.Delimiter) > 1 {
+		errs = append(errs, errors.New("delimiter must be a single character"))
+	}
+	if m.MinorUnits < 0 || m.MinorUnits > 10 {
+		errs = append(errs, errors.New("minor_units must be between 0 and 10"))
+	}
+	if len(m.Rules) == 0 {
+		errs = append(errs, errors.New("at least one rule required"))
+	}
+	for i, r := range m.Rules {
+		if r.Type == "" || r.DebitAccount == "" || r.CreditAccount == "" {
+			errs = append(errs, fmt.Errorf("rule %d: type, debit_account and credit_account required", i+1))
+		}
+		if r.DebitAccount != "" && r.DebitAccount == r.CreditAccount {
+			errs = append(errs, fmt.Errorf("rule %d: debit and credit accounts must differ", i+1))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RuleFor returns the rule matching the record type, preferring an exact match over "*".
+func (m Mapping) RuleFor(recordType string) (Rule, bool) {
+	var wildcard *Rule
+	for i, r := range m.Rules {
+		if r.Type == recordType {
+			return r, true
+		}
+		if r.Type == "*" && wildcard == nil {
+			wildcard = &m.Rules[i]
+		}
+	}
+	if wildcard != nil {
+		return *wildcard, true
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,133 @@
+package clearing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Record is one parsed clearing line. Err is set when the line could not be parsed;
+// such records are reported as invalid rather than aborting the whole file.
+type Record struct {
+	Line       int
+	Reference  string
+	Type       string
+	Amount     *big.Rat
+	Currency   string
+	OccurredAt time.Time
+	Err        error
+}
+
+// Parse reads a clearing CSV with a header row using the mapping's column names.
+func Parse(r io.Reader, m Mapping) ([]Record, error) {
+	cr := csv.NewReader(r)
+	if m.Delimiter != "" {
+		cr.Comma = rune(m.Delimiter[0])
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	col := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := index[name]
+		if !ok {
+			return 0, fmt.Errorf("column %q not found in header", name)
+		}
+		return i, nil
+	}
+
+	refCol, err := col(m.Columns.Reference)
+	if err != nil {
+		return nil, err
+	}
+	amountCol, err := col(m.Columns.Amount)
+	if err != nil {
+		return nil, err
+	}
+	currencyCol, err := col(m.Columns.Currency)
+	if err != nil {
+		return nil, err
+	}
+	occurredCol, err := col(m.Columns.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+	typeCol, err := col(m.Columns.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	timeFormat := m.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(m.MinorUnits)), nil))
+
+	var records []Record
+	line := 1
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			records = append(records, Record{Line: line, Err: err})
+			continue
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[i])
+		}
+
+		rec := Record{
+			Line:      line,
+			Reference: field(refCol),
+			Type:      field(typeCol),
+			Currency:  strings.ToUpper(field(currencyCol)),
+		}
+		if rec.Currency == "" {
+			rec.Currency = m.Currency
+		}
+
+		switch {
+		case rec.Reference == "":
+			rec.Err = fmt.Errorf("missing reference")
+		default:
+			amount, ok := new(big.Rat).SetString(field(amountCol))
+			if !ok {
+				rec.Err = fmt.Errorf("invalid amount %q", field(amountCol))
+				break
+			}
+			rec.Amount = amount.Quo(amount, scale)
+
+			occurredAt, err := time.Parse(timeFormat, field(occurredCol))
+			if err != nil {
+				rec.Err = fmt.Errorf("invalid occurred_at %q", field(occurredCol))
+				break
+			}
+			rec.OccurredAt = occurredAt
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
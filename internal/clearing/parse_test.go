@@ -0,0 +1,55 @@
+package clearing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	m := Mapping{
+		Delimiter:  ";",
+		TimeFormat: "2006-01-02",
+		MinorUnits: 2,
+		Currency:   "EUR",
+		Columns:    Columns{Reference: "arn", Amount: "amt", OccurredAt: "date", Type: "kind"},
+		Rules:      []Rule{{Type: "*", DebitAccount: "scheme_receivable", CreditAccount: "merchant_payable"}},
+	}
+
+	input := "arn;amt;date;kind\n" +
+		"A1;1250;2026-03-01;PURCHASE\n" +
+		"A2;-300;2026-03-01;REFUND\n" +
+		"A3;abc;2026-03-01;PURCHASE\n" +
+		";100;2026-03-01;PURCHASE\n"
+
+	records, err := Parse(strings.NewReader(input), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d", len(records))
+	}
+
+	if got := records[0].Amount.FloatString(2); got != "12.50" {
+		t.Errorf("amount = %s", got)
+	}
+	if records[0].Currency != "EUR" || records[0].Line != 2 {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+	if records[1].Amount.Sign() >= 0 {
+		t.Error("expected negative refund amount")
+	}
+	if records[2].Err == nil || records[3].Err == nil {
+		t.Error("expected invalid records to carry errors")
+	}
+
+	if _, ok := m.RuleFor("CHARGEBACK"); !ok {
+		t.Error("expected wildcard rule to match")
+	}
+}
+
+func TestParseMissingColumn(t *testing.T) {
+	m := Mapping{Columns: Columns{Reference: "ref", Amount: "amount", OccurredAt: "ts"}}
+	if _, err := Parse(strings.NewReader("ref,amount\n1,2\n"), m); err == nil {
+		t.Error("expected missing column error")
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,7 +12,98 @@ type Config struct {
 	ServerPort     string
 	JWTSecret      []byte
 	APIKeySecret   []byte
+	WidgetSecret   []byte // signs public balance and statement widget URLs
 	SessionTimeout time.Duration
+
+	// Data residency: regional ledger databases keyed by region name. Organizations
+	// without an assigned region use DefaultRegion, which falls back to DatabaseURL.
+	DatabaseRegions map[string]string
+	DefaultRegion   string
+
+	// Failover: hot standbys of the main and regional databases, and how often the
+	// primaries are health-checked (see db.Cluster)
+	DatabaseStandbyURL     string
+	DatabaseStandbyRegions map[string]string
+	FailoverCheckInterval  time.Duration
+
+	// Default accounts used by POST /v1/conversions
+	FXConversionAccount string
+	FXRoundingAccount   string
+
+	// Default API rate limit per ledger; ledgers can override it and add burst windows
+	RateLimitPerSecond float64
+	RateLimitBurst     int
+
+	// Per destination host: concurrent webhook requests and the gap between their starts
+	WebhookHostConcurrency int
+	WebhookHostDelay       time.Duration
+	// Default per endpoint: concurrent webhook requests and their average starts per
+	// second; endpoints can override both
+	WebhookEndpointConcurrency int
+	WebhookEndpointRate        int
+	// Failed deliveries in a row after which an endpoint is disabled; 0 never disables
+	WebhookDisableAfterFailures int
+	// Also sign deliveries with the deprecated X-Ledger-Signature header
+	WebhookLegacySignature bool
+	// Default retry policy of webhook deliveries; endpoints can override all but the cap
+	WebhookMaxAttempts int
+	WebhookBackoff     string // exponential, linear or fixed
+	WebhookBackoffBase time.Duration
+	WebhookBackoffMax  time.Duration
+
+	// Kafka outbox; disabled unless brokers are set
+	KafkaBrokers []string
+	KafkaTopic   string
+
+	// NATS JetStream outbox; disabled unless a URL is set
+	NATSURL     string
+	NATSStream  string
+	NATSSubject string
+
+	// Event archival to S3-compatible storage; disabled unless the bucket and age are set.
+	// Ledgers without activity for LedgerArchiveAfterMonths move to the archival tier
+	// and are rehydrated by the API, which needs the bucket for that.
+	EventArchiveAfter        time.Duration
+	LedgerArchiveAfterMonths int
+	S3Endpoint               string
+	S3Region                 string
+	S3Bucket                 string
+	S3AccessKeyID            string
+	S3SecretAccessKey        string
+
+	// Notification emails; logged instead of sent unless an SMTP server is set
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// How long a scheduled organization deletion can be cancelled
+	OrgDeletionGrace time.Duration
+
+	// Worker port serving projector status and Prometheus metrics; keep it internal
+	MetricsPort string
+
+	// Projectors per projection, each projecting a fixed subset of ledgers
+	ProjectorShards int
+	// Ledgers with an event left unprojected this long are sent ProjectorLagExceeded;
+	// 0 disables the alerts
+	ProjectorLagAlert time.Duration
+
+	// Dashboard users who may log in as a customer with the customer's consent token
+	SupportEmails []string
+
+	// Backup verification; disabled unless backup directories and a scratch database
+	// are set (see package backup)
+	BackupDirs           map[string]string
+	BackupScratchURL     string
+	BackupVerifyInterval time.Duration
+	BackupMaxAge         time.Duration
+
+	// Posting hook plugins served over gRPC, as name=host:port, run in this order (see
+	// package hooks); PluginFailOpen lets transactions through a plugin that fails
+	Plugins        []string
+	PluginTimeout  time.Duration
+	PluginFailOpen bool
 }
 
 func Load() *Config {
@@ -19,7 +112,69 @@ func Load() *Config {
 		ServerPort:     getEnv("SERVER_PORT", "8080"),
 		JWTSecret:      []byte(getEnv("JWT_SECRET", "change-me-in-production")),
 		APIKeySecret:   []byte(getEnv("API_KEY_SECRET", "change-me-in-production")),
+		WidgetSecret:   []byte(getEnv("WIDGET_SECRET", "change-me-in-production")),
 		SessionTimeout: time.Hour * 24,
+
+		DatabaseRegions: parseRegions(getEnv("DATABASE_REGIONS", "")),
+		DefaultRegion:   getEnv("DEFAULT_REGION", "default"),
+
+		DatabaseStandbyURL:     getEnv("DATABASE_STANDBY_URL", ""),
+		DatabaseStandbyRegions: parseRegions(getEnv("DATABASE_STANDBY_REGIONS", "")),
+		FailoverCheckInterval:  getEnvDuration("FAILOVER_CHECK_INTERVAL", 5*time.Second),
+
+		FXConversionAccount: getEnv("FX_CONVERSION_ACCOUNT", "fx_conversion"),
+		FXRoundingAccount:   getEnv("FX_ROUNDING_ACCOUNT", "fx_rounding"),
+
+		RateLimitPerSecond: getEnvFloat("RATE_LIMIT_PER_SECOND", 100),
+		RateLimitBurst:     getEnvInt("RATE_LIMIT_BURST", 200),
+
+		WebhookHostConcurrency:      getEnvInt("WEBHOOK_HOST_CONCURRENCY", 4),
+		WebhookHostDelay:            getEnvDuration("WEBHOOK_HOST_DELAY", 50*time.Millisecond),
+		WebhookEndpointConcurrency:  getEnvInt("WEBHOOK_ENDPOINT_CONCURRENCY", 2),
+		WebhookEndpointRate:         getEnvInt("WEBHOOK_ENDPOINT_RATE", 10),
+		WebhookDisableAfterFailures: getEnvInt("WEBHOOK_DISABLE_AFTER_FAILURES", 50),
+		WebhookLegacySignature:      getEnv("WEBHOOK_LEGACY_SIGNATURE", "true") == "true",
+		WebhookMaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 25),
+		WebhookBackoff:              getEnv("WEBHOOK_BACKOFF", "exponential"),
+		WebhookBackoffBase:          getEnvDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookBackoffMax:           getEnvDuration("WEBHOOK_BACKOFF_MAX", 12*time.Hour),
+
+		KafkaBrokers: parseList(getEnv("KAFKA_BROKERS", "")),
+		KafkaTopic:   getEnv("KAFKA_TOPIC", "ledger.events"),
+
+		NATSURL:     getEnv("NATS_URL", ""),
+		NATSStream:  getEnv("NATS_STREAM", "LEDGER_EVENTS"),
+		NATSSubject: getEnv("NATS_SUBJECT", "ledger.events"),
+
+		EventArchiveAfter:        getEnvDuration("EVENT_ARCHIVE_AFTER", 0),
+		LedgerArchiveAfterMonths: getEnvInt("LEDGER_ARCHIVE_AFTER_MONTHS", 0),
+		S3Endpoint:               getEnv("S3_ENDPOINT", ""),
+		S3Region:                 getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:                 getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:            getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:        getEnv("S3_SECRET_ACCESS_KEY", ""),
+
+		SMTPAddr:     getEnv("SMTP_ADDR", ""),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "ledger@localhost"),
+
+		OrgDeletionGrace: getEnvDuration("ORG_DELETION_GRACE", 30*24*time.Hour),
+
+		MetricsPort:       getEnv("METRICS_PORT", "9090"),
+		ProjectorShards:   getEnvInt("PROJECTOR_SHARDS", 1),
+		ProjectorLagAlert: getEnvDuration("PROJECTOR_LAG_ALERT", 5*time.Minute),
+
+		SupportEmails: parseList(getEnv("SUPPORT_EMAILS", "")),
+
+		BackupDirs:           parseRegions(getEnv("BACKUP_DIRS", "")),
+		BackupScratchURL:     getEnv("BACKUP_SCRATCH_URL", ""),
+		BackupVerifyInterval: getEnvDuration("BACKUP_VERIFY_INTERVAL", 24*time.Hour),
+		BackupMaxAge:         getEnvDuration("BACKUP_MAX_AGE", 26*time.Hour),
+
+		Plugins:        parseList(getEnv("PLUGINS", "")),
+		PluginTimeout:  getEnvDuration("PLUGIN_TIMEOUT", 5*time.Second),
+		PluginFailOpen: getEnv("PLUGIN_FAIL_OPEN", "false") == "true",
 	}
 }
 
@@ -29,3 +184,48 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// parseList parses a comma-separated list, dropping empty entries.
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRegions parses "eu=postgres://...;us=postgres://..." into a region map.
+func parseRegions(value string) map[string]string {
+	regions := map[string]string{}
+	for _, entry := range strings.Split(value, ";") {
+		name, url, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || url == "" {
+			continue
+		}
+		regions[strings.TrimSpace(name)] = strings.TrimSpace(url)
+	}
+	return regions
+}
@@ -0,0 +1,115 @@
+package connectors
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Adyen reads Adyen standard notification webhooks. Unsuccessful notifications are
+// reported with a ":failed" suffix on the event code (e.g. "AUTHORISATION:failed"), so
+// rules only match them when asked to.
+type Adyen struct{}
+
+type adyenItem struct {
+	PSPReference        string `json:"pspReference"`
+	OriginalReference   string `json:"originalReference"`
+	MerchantAccountCode string `json:"merchantAccountCode"`
+	MerchantReference   string `json:"merchantReference"`
+	EventCode           string `json:"eventCode"`
+	EventDate           string `json:"eventDate"`
+	Success             string `json:"success"`
+	Amount              struct {
+		Value    int64  `json:"value"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	AdditionalData map[string]string `json:"additionalData"`
+}
+
+type adyenNotification struct {
+	NotificationItems []struct {
+		Item adyenItem `json:"NotificationRequestItem"`
+	} `json:"notificationItems"`
+}
+
+// Verify checks the HMAC signature carried by every notification item
+// (additionalData.hmacSignature); the secret is the hex HMAC key from the Adyen
+// customer area.
+func (Adyen) Verify(header http.Header, body []byte, secret string) error {
+	key, err := hex.DecodeString(secret)
+	if err != nil {
+		return fmt.Errorf("%w: hmac key must be hex", ErrInvalidSignature)
+	}
+	var n adyenNotification
+	if err := json.Unmarshal(body, &n); err != nil || len(n.NotificationItems) == 0 {
+		return ErrInvalidSignature
+	}
+
+	for _, wrapper := range n.NotificationItems {
+		item := wrapper.Item
+		signed := strings.Join([]string{
+			item.PSPReference, item.OriginalReference, item.MerchantAccountCode, item.MerchantReference,
+			strconv.FormatInt(item.Amount.Value, 10), item.Amount.Currency, item.EventCode, item.Success,
+		}, ":")
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(item.AdditionalData["hmacSignature"])) {
+			return ErrInvalidSignature
+		}
+	}
+	return nil
+}
+
+func (Adyen) Parse(body []byte) ([]Event, error) {
+	var n adyenNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("invalid adyen notification: %w", err)
+	}
+
+	var events []Event
+	for _, wrapper := range n.NotificationItems {
+		item := wrapper.Item
+		if item.PSPReference == "" || item.EventCode == "" {
+			return nil, fmt.Errorf("invalid adyen notification: pspReference and eventCode required")
+		}
+
+		eventType := item.EventCode
+		if item.Success != "true" {
+			eventType += ":failed"
+		}
+		occurredAt, err := time.Parse(time.RFC3339, item.EventDate)
+		if err != nil {
+			occurredAt = time.Now().UTC()
+		}
+		metadata := map[string]string{"merchant_reference": item.MerchantReference}
+		for k, v := range item.AdditionalData {
+			if strings.HasPrefix(k, "metadata.") {
+				metadata[strings.TrimPrefix(k, "metadata.")] = v
+			}
+		}
+
+		events = append(events, Event{
+			// A payment's notifications share its pspReference
+			ID:         item.PSPReference + ":" + item.EventCode,
+			Type:       eventType,
+			Reference:  item.PSPReference,
+			Amount:     fromMinor(item.Amount.Value, item.Amount.Currency),
+			Currency:   item.Amount.Currency,
+			OccurredAt: occurredAt,
+			Metadata:   metadata,
+		})
+	}
+	return events, nil
+}
+
+func (Adyen) Ack() string {
+	return "[accepted]"
+}
@@ -0,0 +1,111 @@
+package connectors
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStripe(t *testing.T) {
+	now = func() time.Time { return time.Unix(1767225600, 0) }
+	defer func() { now = time.Now }()
+
+	body := []byte(`{"id":"evt_1","type":"charge.succeeded","created":1767225500,
+		"data":{"object":{"id":"ch_1","amount":1250,"currency":"usd","metadata":{"customer_id":"42"}}}}`)
+
+	sign := func(ts int64, secret string) http.Header {
+		mac := hmac.New(sha256.New, []byte(secret))
+		fmt.Fprintf(mac, "%d.%s", ts, body)
+		h := http.Header{}
+		h.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil))))
+		return h
+	}
+
+	p := Stripe{}
+	if err := p.Verify(sign(1767225590, "whsec"), body, "whsec"); err != nil {
+		t.Fatalf("expected valid signature: %v", err)
+	}
+	if err := p.Verify(sign(1767225590, "other"), body, "whsec"); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+	if err := p.Verify(sign(1767225000, "whsec"), body, "whsec"); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected stale signature to be rejected, got %v", err)
+	}
+
+	events, err := p.Parse(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Amount.FloatString(2) != "12.50" || events[0].Currency != "USD" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	rule := Rule{EventType: "charge.succeeded", DebitAccount: "stripe:balance", CreditAccount: "customer:${metadata.customer_id}"}
+	debit, credit, err := rule.Accounts(events[0])
+	if err != nil || debit != "stripe:balance" || credit != "customer:42" {
+		t.Fatalf("unexpected accounts %s %s %v", debit, credit, err)
+	}
+	if _, _, err := (Rule{DebitAccount: "${metadata.missing}"}).Accounts(events[0]); err == nil {
+		t.Fatal("expected missing metadata error")
+	}
+}
+
+func TestAdyen(t *testing.T) {
+	key := "44782def547aaa06c910c43932b1eb0c71fc68d9d0c057550c48ec2acf6ba056"
+	keyBytes, _ := hex.DecodeString(key)
+	mac := hmac.New(sha256.New, keyBytes)
+	mac.Write([]byte("8535296650153317::Merchant:order-7:1000:JPY:AUTHORISATION:true"))
+	sig := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	body := []byte(`{"live":"false","notificationItems":[{"NotificationRequestItem":{
+		"pspReference":"8535296650153317","merchantAccountCode":"Merchant","merchantReference":"order-7",
+		"amount":{"value":1000,"currency":"JPY"},"eventCode":"AUTHORISATION","success":"true",
+		"eventDate":"2026-01-01T10:00:00+01:00","additionalData":{"hmacSignature":"` + sig + `"}}}]}`)
+
+	p := Adyen{}
+	if err := p.Verify(nil, body, key); err != nil {
+		t.Fatalf("expected valid signature: %v", err)
+	}
+	tampered := []byte(strings.Replace(string(body), `"value":1000`, `"value":9000`, 1))
+	if err := p.Verify(nil, tampered, key); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+
+	events, err := p.Parse(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// JPY has no minor unit
+	if len(events) != 1 || events[0].Amount.FloatString(0) != "1000" || events[0].Type != "AUTHORISATION" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if events[0].Metadata["merchant_reference"] != "order-7" {
+		t.Fatalf("unexpected metadata: %v", events[0].Metadata)
+	}
+}
+
+func TestMappingRuleFor(t *testing.T) {
+	m := Mapping{Rules: []Rule{
+		{EventType: "*", DebitAccount: "psp:clearing", CreditAccount: "revenue"},
+		{EventType: "charge.refunded", DebitAccount: "revenue", CreditAccount: "psp:clearing"},
+	}}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := m.RuleFor("charge.refunded"); r.DebitAccount != "revenue" {
+		t.Fatalf("expected exact match to win, got %+v", r)
+	}
+	if r, ok := m.RuleFor("charge.succeeded"); !ok || r.EventType != "*" {
+		t.Fatalf("expected wildcard match, got %+v", r)
+	}
+	if err := (Mapping{}).Validate(); err == nil {
+		t.Fatal("expected empty mapping to be rejected")
+	}
+}
@@ -0,0 +1,133 @@
+// Package connectors translates payment service provider webhooks (Stripe, Adyen) into
+// normalized payment events, and maps those events to ledger postings with per-ledger
+// rules. It has no database access; the ledger service stores connectors and posts
+// the mapped events.
+package connectors
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Provider is one payment service provider's webhook format.
+type Provider interface {
+	// Verify checks the request signature against the connector's signing secret.
+	Verify(header http.Header, body []byte, secret string) error
+	// Parse translates a webhook body into events.
+	Parse(body []byte) ([]Event, error)
+	// Ack is the response body the provider expects once a webhook is accepted.
+	Ack() string
+}
+
+var providers = map[string]Provider{
+	"stripe": Stripe{},
+	"adyen":  Adyen{},
+}
+
+// Lookup returns the provider registered under name.
+func Lookup(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Event is a provider event normalized to major currency units.
+type Event struct {
+	ID         string // unique per provider event; used for deduplication
+	Type       string // provider event type, e.g. charge.succeeded or AUTHORISATION
+	Reference  string // provider object reference, e.g. a charge id or pspReference
+	Amount     *big.Rat
+	Currency   string
+	OccurredAt time.Time
+	Metadata   map[string]string
+}
+
+// Mapping decides which ledger accounts each event type is posted to.
+type Mapping struct {
+	// ExternalIDPrefix is prepended to the event id to form the transaction external_id
+	ExternalIDPrefix string `json:"external_id_prefix,omitempty"`
+	Rules            []Rule `json:"rules"`
+}
+
+// Rule posts events of a type from the debit account to the credit account. Account
+// codes may reference event metadata, e.g. "customer:${metadata.customer_id}".
+type Rule struct {
+	EventType     string `json:"event_type"` // provider event type, or "*" for any
+	DebitAccount  string `json:"debit_account"`
+	CreditAccount string `json:"credit_account"`
+}
+
+func (m Mapping) Validate() error {
+	var errs []error
+	if len(m.Rules) == 0 {
+		errs = append(errs, errors.New("at least one rule required"))
+	}
+	for i, r := range m.Rules {
+		if r.EventType == "" || r.DebitAccount == "" || r.CreditAccount == "" {
+			errs = append(errs, fmt.Errorf("rule %d: event_type, debit_account and credit_account required", i+1))
+		}
+		if r.DebitAccount != "" && r.DebitAccount == r.CreditAccount {
+			errs = append(errs, fmt.Errorf("rule %d: debit and credit accounts must differ", i+1))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RuleFor returns the rule matching the event type, preferring an exact match over "*".
+func (m Mapping) RuleFor(eventType string) (Rule, bool) {
+	var wildcard *Rule
+	for i, r := range m.Rules {
+		if r.EventType == eventType {
+			return r, true
+		}
+		if r.EventType == "*" && wildcard == nil {
+			wildcard = &m.Rules[i]
+		}
+	}
+	if wildcard != nil {
+		return *wildcard, true
+	}
+	return Rule{}, false
+}
+
+var metadataRef = regexp.MustCompile(`\$\{metadata\.([^}]+)\}`)
+
+// Accounts resolves the rule's account codes for an event.
+func (r Rule) Accounts(e Event) (debit, credit string, err error) {
+	resolve := func(code string) string {
+		return metadataRef.ReplaceAllStringFunc(code, func(match string) string {
+			key := metadataRef.FindStringSubmatch(match)[1]
+			value, ok := e.Metadata[key]
+			if !ok || value == "" {
+				if err == nil {
+					err = fmt.Errorf("event has no metadata %q", key)
+				}
+				return match
+			}
+			return value
+		})
+	}
+	debit, credit = resolve(r.DebitAccount), resolve(r.CreditAccount)
+	return debit, credit, err
+}
+
+// Exponents of currencies whose minor unit is not the cent
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "MGA": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "JOD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+// fromMinor converts an amount in minor units of the currency to major units.
+func fromMinor(value int64, currency string) *big.Rat {
+	exponent, ok := exponents[currency]
+	if !ok {
+		exponent = 2
+	}
+	return new(big.Rat).SetFrac(big.NewInt(value), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+}
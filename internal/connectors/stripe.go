@@ -0,0 +1,99 @@
+package connectors
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stripeTolerance bounds the age of a signed Stripe webhook, against replays.
+const stripeTolerance = 5 * time.Minute
+
+// now is replaced in tests.
+var now = time.Now
+
+// Stripe reads Stripe event webhooks (charge.*, payment_intent.*, refund.*, payout.*).
+// The event amount is the object's amount.
+type Stripe struct{}
+
+// Verify checks the Stripe-Signature header: "t=<unix>,v1=<hex hmac>" where the HMAC
+// covers "<t>.<body>".
+func (Stripe) Verify(header http.Header, body []byte, secret string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now().Sub(time.Unix(unix, 0)); age > stripeTolerance || age < -stripeTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(expected), []byte(sig)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func (Stripe) Parse(body []byte) ([]Event, error) {
+	var payload struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		Data    struct {
+			Object struct {
+				ID       string            `json:"id"`
+				Amount   *int64            `json:"amount"`
+				Currency string            `json:"currency"`
+				Metadata map[string]string `json:"metadata"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid stripe event: %w", err)
+	}
+	object := payload.Data.Object
+	if payload.ID == "" || payload.Type == "" {
+		return nil, fmt.Errorf("invalid stripe event: id and type required")
+	}
+	if object.Amount == nil || object.Currency == "" {
+		// Events without money movement (customer.created, ...) are acknowledged and ignored
+		return nil, nil
+	}
+
+	currency := strings.ToUpper(object.Currency)
+	return []Event{{
+		ID:         payload.ID,
+		Type:       payload.Type,
+		Reference:  object.ID,
+		Amount:     fromMinor(*object.Amount, currency),
+		Currency:   currency,
+		OccurredAt: time.Unix(payload.Created, 0).UTC(),
+		Metadata:   object.Metadata,
+	}}, nil
+}
+
+func (Stripe) Ack() string {
+	return `{"received":true}`
+}
@@ -0,0 +1,188 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/privacy"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AccountHandler serves a user's data subject requests on their own account.
+type AccountHandler struct {
+	DB      *pgxpool.Pool
+	Config  *config.Config
+	Privacy *privacy.Service
+}
+
+// DeleteAccountRequest must repeat the user's password to confirm.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type TransferOwnershipRequest struct {
+	Email string `json:"email"`
+}
+
+// GET /api/auth/me/export - Download the personal data held about the user
+func (h *AccountHandler) ExportPersonalData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return
+	}
+
+	export, err := h.Privacy.Export(r.Context(), claims.UserID)
+	if errors.Is(err, privacy.ErrUserNotFound) {
+		api.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to export personal data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%s.json"`,
+		time.Now().UTC().Format("20060102")))
+	json.NewEncoder(w).Encode(export)
+}
+
+// DELETE /api/auth/me - Delete the user's account
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var passwordHash string
+	err := h.DB.QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1`, claims.UserID).Scan(&passwordHash)
+	if err != nil {
+		api.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err := auth.CheckPassword(passwordHash, req.Password); err != nil {
+		api.Error(w, "invalid password", http.StatusUnauthorized)
+		return
+	}
+
+	var ownership *privacy.OwnershipError
+	err = h.Privacy.Delete(ctx, claims.UserID)
+	if errors.As(err, &ownership) {
+		api.Error(w, ownership.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/organization/transfer-ownership - Make another member the owner (owners only)
+//
+// The caller becomes a developer, so an owner can hand the organization over before
+// deleting their account.
+func (h *AccountHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return
+	}
+
+	var req TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		api.Error(w, "email required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var role string
+	err = tx.QueryRow(ctx, `
+		SELECT role FROM org_users WHERE user_id = $1 AND organization_id = $2 FOR UPDATE
+	`, claims.UserID, claims.OrgID).Scan(&role)
+	if err != nil || role != "owner" {
+		api.Error(w, "only owners can transfer ownership", http.StatusForbidden)
+		return
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE org_users ou SET role = 'owner'
+		FROM users u
+		WHERE u.id = ou.user_id AND u.email = $1 AND ou.organization_id = $2 AND ou.user_id <> $3
+	`, req.Email, claims.OrgID, claims.UserID)
+	if err != nil {
+		api.Error(w, "failed to transfer ownership", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "not a member of the organization", http.StatusNotFound)
+		return
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE org_users SET role = 'developer' WHERE user_id = $1 AND organization_id = $2
+	`, claims.UserID, claims.OrgID); err != nil {
+		api.Error(w, "failed to transfer ownership", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AccountHandler) session(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, h.Config.JWTSecret)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
+}
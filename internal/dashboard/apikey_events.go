@@ -0,0 +1,105 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// API key lifecycle events. The api_keys table is a read model projected from these.
+const (
+	eventAPIKeyRequested = "APIKeyRequested"
+	eventAPIKeyCreated   = "APIKeyCreated"
+	eventAPIKeyApproved  = "APIKeyApproved"
+	eventAPIKeyRejected  = "APIKeyRejected"
+	eventAPIKeyRevoked   = "APIKeyRevoked"
+)
+
+const (
+	apiKeyStatusPending  = "pending_approval"
+	apiKeyStatusActive   = "active"
+	apiKeyStatusRejected = "rejected"
+	apiKeyStatusRevoked  = "revoked"
+)
+
+var apiKeyStatusByEvent = map[string]string{
+	eventAPIKeyRequested: apiKeyStatusPending,
+	eventAPIKeyCreated:   apiKeyStatusActive,
+	eventAPIKeyApproved:  apiKeyStatusActive,
+	eventAPIKeyRejected:  apiKeyStatusRejected,
+	eventAPIKeyRevoked:   apiKeyStatusRevoked,
+}
+
+func appendAPIKeyEvent(ctx context.Context, tx pgx.Tx, ledgerID, keyID, eventType string, payload *events.APIKeyChanged) error {
+	payloadJSON, err := events.Marshal(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO events (
+			id,
+			ledger_id,
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			occurred_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, uuid.NewString(), ledgerID, "api_key", keyID, eventType, payloadJSON, time.Now().UTC())
+	return err
+}
+
+// recordAPIKeyEvent appends a state transition event for an existing key.
+func (h *APIKeyHandler) recordAPIKeyEvent(ctx context.Context, ledgerID, keyID, eventType, userID string) error {
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = appendAPIKeyEvent(ctx, tx, ledgerID, keyID, eventType, &events.APIKeyChanged{
+		APIKeyID: keyID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// apiKeyState returns the key's ledger, owning organization and current status.
+// The status is folded from the key's latest event so decisions don't depend on
+// projector lag; keys created before lifecycle events existed fall back to the read model.
+func (h *APIKeyHandler) apiKeyState(ctx context.Context, keyID string) (ledgerID, orgID, status string, err error) {
+	var eventType string
+	err = h.DB.QueryRow(ctx, `
+		SELECT e.ledger_id, p.organization_id, e.event_type
+		FROM events e
+		JOIN ledgers l ON l.id = e.ledger_id
+		JOIN projects p ON p.id = l.project_id
+		WHERE e.aggregate_type = 'api_key' AND e.aggregate_id = $1
+		ORDER BY e.sequence DESC
+		LIMIT 1
+	`, keyID).Scan(&ledgerID, &orgID, &eventType)
+	if err == nil {
+		return ledgerID, orgID, apiKeyStatusByEvent[eventType], nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", "", "", err
+	}
+
+	err = h.DB.QueryRow(ctx, `
+		SELECT k.ledger_id, p.organization_id, k.status
+		FROM api_keys k
+		JOIN ledgers l ON l.id = k.ledger_id
+		JOIN projects p ON p.id = l.project_id
+		WHERE k.id = $1
+	`, keyID).Scan(&ledgerID, &orgID, &status)
+	return ledgerID, orgID, status, err
+}
@@ -1,13 +1,18 @@
 package dashboard
 
 import (
+	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/events"
 	"encoding/base32"
 	"encoding/json"
 	"math/rand"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -20,6 +25,7 @@ type APIKeyResponse struct {
 	ID          string `json:"id"`
 	Prefix      string `json:"prefix"`
 	Description string `json:"description"`
+	Status      string `json:"status"`
 	IsActive    bool   `json:"is_active"`
 	CreatedAt   string `json:"created_at"`
 	RevokedAt   string `json:"revoked_at,omitempty"`
@@ -34,6 +40,18 @@ type CreateAPIKeyResponse struct {
 	RawKey      string `json:"raw_key"`
 	Prefix      string `json:"prefix"`
 	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+type APIKeyEventResponse struct {
+	ID         string                 `json:"id"`
+	EventType  string                 `json:"event_type"`
+	Payload    map[string]interface{} `json:"payload"`
+	OccurredAt string                 `json:"occurred_at"`
+}
+
+type ApprovalPolicyRequest struct {
+	RequireApproval bool `json:"require_approval"`
 }
 
 // GET /api/ledgers/:ledgerId/api-keys
@@ -42,19 +60,19 @@ func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
 
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, []byte("jwt-secret"))
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	ledgerID := r.URL.Query().Get("ledger_id")
 	if ledgerID == "" {
-		http.Error(w, "ledger_id required", http.StatusBadRequest)
+		api.Error(w, "ledger_id required", http.StatusBadRequest)
 		return
 	}
 
@@ -67,18 +85,18 @@ func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
 		WHERE l.id = $1
 	`, ledgerID).Scan(&projectOrgID)
 	if err != nil || projectOrgID != claims.OrgID {
-		http.Error(w, "ledger not found", http.StatusNotFound)
+		api.Error(w, "ledger not found", http.StatusNotFound)
 		return
 	}
 
 	rows, err := h.DB.Query(ctx, `
-		SELECT id, prefix, description, is_active, created_at, revoked_at
+		SELECT id, prefix, description, status, is_active, created_at, revoked_at
 		FROM api_keys
 		WHERE ledger_id = $1
 		ORDER BY created_at DESC
 	`, ledgerID)
 	if err != nil {
-		http.Error(w, "failed to query api keys", http.StatusInternalServerError)
+		api.Error(w, "failed to query api keys", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -87,9 +105,9 @@ func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var key APIKeyResponse
 		var revokedAt *string
-		err = rows.Scan(&key.ID, &key.Prefix, &key.Description, &key.IsActive, &key.CreatedAt, &revokedAt)
+		err = rows.Scan(&key.ID, &key.Prefix, &key.Description, &key.Status, &key.IsActive, &key.CreatedAt, &revokedAt)
 		if err != nil {
-			http.Error(w, "failed to scan api key", http.StatusInternalServerError)
+			api.Error(w, "failed to scan api key", http.StatusInternalServerError)
 			return
 		}
 		if revokedAt != nil {
@@ -108,19 +126,22 @@ func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, []byte("jwt-secret"))
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !notImpersonated(w, claims) {
 		return
 	}
 
 	ledgerID := r.URL.Query().Get("ledger_id")
 	if ledgerID == "" {
-		http.Error(w, "ledger_id required", http.StatusBadRequest)
+		api.Error(w, "ledger_id required", http.StatusBadRequest)
 		return
 	}
 
@@ -133,42 +154,80 @@ func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		WHERE l.id = $1
 	`, ledgerID).Scan(&projectOrgID)
 	if err != nil || projectOrgID != claims.OrgID {
-		http.Error(w, "ledger not found", http.StatusNotFound)
+		api.Error(w, "ledger not found", http.StatusNotFound)
 		return
 	}
 
 	var req CreateAPIKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
 	// Generate raw API key
 	rawKey, err := generateAPIKey()
 	if err != nil {
-		http.Error(w, "failed to generate api key", http.StatusInternalServerError)
+		api.Error(w, "failed to generate api key", http.StatusInternalServerError)
 		return
 	}
 
 	// Compute hash
 	keyHash, err := auth.ComputeKeyHash(h.APIKeySecret, rawKey)
 	if err != nil {
-		http.Error(w, "failed to hash api key", http.StatusInternalServerError)
+		api.Error(w, "failed to hash api key", http.StatusInternalServerError)
 		return
 	}
 
 	// Extract prefix (first 10 characters)
 	prefix := rawKey[:10]
 
-	// Store in database
-	var keyID string
-	err = h.DB.QueryRow(ctx, `
-		INSERT INTO api_keys (ledger_id, key_hash, prefix, description, is_active)
-		VALUES ($1, $2, $3, $4, true)
-		RETURNING id
-	`, ledgerID, keyHash, prefix, req.Description).Scan(&keyID)
+	// Developers need owner approval when the organization requires it
+	role, requireApproval, err := h.memberRole(r, claims)
+	if err != nil {
+		api.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	eventType := eventAPIKeyCreated
+	status := apiKeyStatusActive
+	if role == "developer" && requireApproval {
+		eventType = eventAPIKeyRequested
+		status = apiKeyStatusPending
+	}
+
+	// Write the key with its hash, which stays out of the lifecycle event; the projector
+	// materializes its later transitions
+	keyID := uuid.NewString()
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO api_keys (id, ledger_id, key_hash, prefix, description, is_active, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid)
+	`, keyID, ledgerID, keyHash, prefix, req.Description, status == apiKeyStatusActive, status, claims.UserID)
 	if err != nil {
-		http.Error(w, "failed to create api key", http.StatusInternalServerError)
+		api.Error(w, "failed to create api key", http.StatusInternalServerError)
+		return
+	}
+
+	err = appendAPIKeyEvent(ctx, tx, ledgerID, keyID, eventType, &events.APIKeyChanged{
+		APIKeyID:    keyID,
+		Prefix:      prefix,
+		Description: req.Description,
+		Status:      status,
+		UserID:      claims.UserID,
+	})
+	if err != nil {
+		api.Error(w, "failed to create api key", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to create api key", http.StatusInternalServerError)
 		return
 	}
 
@@ -177,6 +236,7 @@ func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		RawKey:      rawKey,
 		Prefix:      prefix,
 		Description: req.Description,
+		Status:      status,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -190,50 +250,218 @@ func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
 
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, []byte("jwt-secret"))
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	keyID := r.URL.Query().Get("id")
 	if keyID == "" {
-		http.Error(w, "key id required", http.StatusBadRequest)
+		api.Error(w, "key id required", http.StatusBadRequest)
 		return
 	}
 
 	// Verify key belongs to user's organization
-	var projectOrgID string
-	err = h.DB.QueryRow(ctx, `
-		SELECT p.organization_id
-		FROM api_keys k
-		JOIN ledgers l ON l.id = k.ledger_id
-		JOIN projects p ON p.id = l.project_id
-		WHERE k.id = $1
-	`, keyID).Scan(&projectOrgID)
-	if err != nil || projectOrgID != claims.OrgID {
-		http.Error(w, "api key not found", http.StatusNotFound)
+	ledgerID, orgID, status, err := h.apiKeyState(ctx, keyID)
+	if err != nil || orgID != claims.OrgID {
+		api.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+	if status == apiKeyStatusRevoked || status == apiKeyStatusRejected {
+		api.Error(w, "api key already "+status, http.StatusConflict)
 		return
 	}
 
 	// Revoke key
-	_, err = h.DB.Exec(ctx, `
-		UPDATE api_keys
-		SET is_active = false, revoked_at = NOW()
-		WHERE id = $1
+	if err := h.recordAPIKeyEvent(ctx, ledgerID, keyID, eventAPIKeyRevoked, claims.UserID); err != nil {
+		api.Error(w, "failed to revoke api key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/api-keys/:id/approve - Owner approves a pending key request
+func (h *APIKeyHandler) ApproveAPIKey(w http.ResponseWriter, r *http.Request) {
+	h.decideAPIKey(w, r, eventAPIKeyApproved)
+}
+
+// POST /api/api-keys/:id/reject - Owner rejects a pending key request
+func (h *APIKeyHandler) RejectAPIKey(w http.ResponseWriter, r *http.Request) {
+	h.decideAPIKey(w, r, eventAPIKeyRejected)
+}
+
+func (h *APIKeyHandler) decideAPIKey(w http.ResponseWriter, r *http.Request, eventType string) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !notImpersonated(w, claims) {
+		return
+	}
+
+	keyID := r.URL.Query().Get("id")
+	if keyID == "" {
+		api.Error(w, "key id required", http.StatusBadRequest)
+		return
+	}
+
+	role, _, err := h.memberRole(r, claims)
+	if err != nil || role != "owner" {
+		api.Error(w, "only organization owners can approve api keys", http.StatusForbidden)
+		return
+	}
+
+	ledgerID, orgID, status, err := h.apiKeyState(ctx, keyID)
+	if err != nil || orgID != claims.OrgID {
+		api.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+	if status != apiKeyStatusPending {
+		api.Error(w, "api key is not pending approval", http.StatusConflict)
+		return
+	}
+
+	if err := h.recordAPIKeyEvent(ctx, ledgerID, keyID, eventType, claims.UserID); err != nil {
+		api.Error(w, "failed to record decision", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/api-keys/:id/history - Lifecycle events of a key (audit trail)
+func (h *APIKeyHandler) GetAPIKeyHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyID := r.URL.Query().Get("id")
+	if keyID == "" {
+		api.Error(w, "key id required", http.StatusBadRequest)
+		return
+	}
+
+	_, orgID, _, err := h.apiKeyState(ctx, keyID)
+	if err != nil || orgID != claims.OrgID {
+		api.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		SELECT id, event_type, payload, occurred_at
+		FROM events
+		WHERE aggregate_type = 'api_key' AND aggregate_id = $1
+		ORDER BY sequence
 	`, keyID)
 	if err != nil {
-		http.Error(w, "failed to revoke api key", http.StatusInternalServerError)
+		api.Error(w, "failed to query api key history", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []APIKeyEventResponse{}
+	for rows.Next() {
+		var evt APIKeyEventResponse
+		var payloadJSON []byte
+		var occurredAt time.Time
+		if err := rows.Scan(&evt.ID, &evt.EventType, &payloadJSON, &occurredAt); err != nil {
+			api.Error(w, "failed to scan api key event", http.StatusInternalServerError)
+			return
+		}
+		if err := json.Unmarshal(payloadJSON, &evt.Payload); err != nil {
+			api.Error(w, "failed to parse event payload", http.StatusInternalServerError)
+			return
+		}
+		evt.OccurredAt = occurredAt.Format(time.RFC3339)
+		history = append(history, evt)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// POST /api/api-keys/approval-policy - Toggle owner approval for developer keys
+func (h *APIKeyHandler) SetApprovalPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !notImpersonated(w, claims) {
+		return
+	}
+
+	role, _, err := h.memberRole(r, claims)
+	if err != nil || role != "owner" {
+		api.Error(w, "only organization owners can change the approval policy", http.StatusForbidden)
+		return
+	}
+
+	var req ApprovalPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.DB.Exec(ctx, `
+		UPDATE organizations
+		SET require_api_key_approval = $1
+		WHERE id = $2
+	`, req.RequireApproval, claims.OrgID)
+	if err != nil {
+		api.Error(w, "failed to update approval policy", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// memberRole returns the caller's role and the organization's approval policy.
+func (h *APIKeyHandler) memberRole(r *http.Request, claims *auth.Claims) (string, bool, error) {
+	var role string
+	var requireApproval bool
+	err := h.DB.QueryRow(r.Context(), `
+		SELECT ou.role, o.require_api_key_approval
+		FROM org_users ou
+		JOIN organizations o ON o.id = ou.organization_id
+		WHERE ou.user_id = $1 AND ou.organization_id = $2
+	`, claims.UserID, claims.OrgID).Scan(&role, &requireApproval)
+	return role, requireApproval, err
+}
+
 func generateAPIKey() (string, error) {
 	// Generate 32 random bytes
 	bytes := make([]byte, 32)
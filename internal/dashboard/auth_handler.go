@@ -1,8 +1,10 @@
 package dashboard
 
 import (
+	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
 	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/db"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -14,6 +16,7 @@ import (
 type AuthHandler struct {
 	DB     *pgxpool.Pool
 	Config *config.Config
+	Router *db.Router
 }
 
 type LoginRequest struct {
@@ -24,6 +27,7 @@ type LoginRequest struct {
 type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Region   string `json:"region,omitempty"` // data residency region, defaults to the default region
 }
 
 type UserResponse struct {
@@ -31,6 +35,7 @@ type UserResponse struct {
 	Email          string `json:"email"`
 	OrganizationID string `json:"organization_id"`
 	Role           string `json:"role"`
+	ImpersonatedBy string `json:"impersonated_by,omitempty"` // support user's email, for a banner
 }
 
 // POST /api/auth/register
@@ -39,21 +44,26 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Region != "" && (h.Router == nil || !h.Router.HasRegion(req.Region)) {
+		api.Error(w, "unknown region", http.StatusBadRequest)
 		return
 	}
 
 	// Hash password
 	passwordHash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "failed to hash password", http.StatusInternalServerError)
+		api.Error(w, "failed to hash password", http.StatusInternalServerError)
 		return
 	}
 
 	// Begin transaction
 	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		http.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
 		return
 	}
 	defer tx.Rollback(ctx)
@@ -66,7 +76,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		RETURNING id
 	`, req.Email, passwordHash).Scan(&userID)
 	if err != nil {
-		http.Error(w, "email already exists", http.StatusConflict)
+		api.Error(w, "email already exists", http.StatusConflict)
 		return
 	}
 
@@ -77,12 +87,12 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		orgName = req.Email[:atIndex] + "'s Organization"
 	}
 	err = tx.QueryRow(ctx, `
-		INSERT INTO organizations (name)
-		VALUES ($1)
+		INSERT INTO organizations (name, region)
+		VALUES ($1, NULLIF($2, ''))
 		RETURNING id
-	`, orgName).Scan(&orgID)
+	`, orgName, req.Region).Scan(&orgID)
 	if err != nil {
-		http.Error(w, "failed to create organization", http.StatusInternalServerError)
+		api.Error(w, "failed to create organization", http.StatusInternalServerError)
 		return
 	}
 
@@ -92,19 +102,19 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		VALUES ($1, $2, 'owner')
 	`, orgID, userID)
 	if err != nil {
-		http.Error(w, "failed to link user to organization", http.StatusInternalServerError)
+		api.Error(w, "failed to link user to organization", http.StatusInternalServerError)
 		return
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		http.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		api.Error(w, "failed to commit transaction", http.StatusInternalServerError)
 		return
 	}
 
 	// Generate JWT
 	token, err := auth.GenerateJWT(userID, orgID, h.Config.SessionTimeout, h.Config.JWTSecret)
 	if err != nil {
-		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		api.Error(w, "failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
@@ -132,7 +142,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
@@ -146,19 +156,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		LIMIT 1
 	`, req.Email).Scan(&userID, &passwordHash, &orgID)
 	if err != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		api.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	if err := auth.CheckPassword(passwordHash, req.Password); err != nil {
-		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		api.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	// Generate JWT
 	token, err := auth.GenerateJWT(userID, orgID, h.Config.SessionTimeout, h.Config.JWTSecret)
 	if err != nil {
-		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		api.Error(w, "failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
@@ -183,13 +193,13 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// Extract JWT from cookie
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, h.Config.JWTSecret)
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, h.Config.JWTSecret)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -201,9 +211,15 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		WHERE u.id = $1 AND ou.organization_id = $2
 	`, claims.UserID, claims.OrgID).Scan(&user.ID, &user.Email, &user.OrganizationID, &user.Role)
 	if err != nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		api.Error(w, "user not found", http.StatusNotFound)
 		return
 	}
+	if claims.ImpersonatorID != "" {
+		err = h.DB.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, claims.ImpersonatorID).Scan(&user.ImpersonatedBy)
+		if err != nil {
+			user.ImpersonatedBy = "support"
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
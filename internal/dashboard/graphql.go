@@ -0,0 +1,594 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/graphql"
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GraphQLHandler serves the dashboard's GraphQL API: the session's organization's
+// ledgers, with their accounts, transactions, postings and webhook deliveries, nested as
+// deeply as a page needs in a single request. Reads go to the organization's regional
+// database and are masked by the masking rule of the user's role, like those of
+// LedgerAccess.
+//
+// Children are loaded for all their siblings at once: the postings of a page of
+// transactions, for instance, take one query, not one per transaction.
+type GraphQLHandler struct {
+	DB        *pgxpool.Pool // control plane
+	Router    *db.Router
+	JWTSecret []byte
+
+	// Rehydrate, when set, brings an archived ledger back before its data is read
+	Rehydrate func(ctx context.Context, region, ledgerID string) error
+}
+
+// POST /graphql - Run a GraphQL query against the organization's ledgers
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, h.JWTSecret)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s := &graphSession{handler: h, orgID: claims.OrgID, rehydrated: map[string]error{}}
+	err = h.DB.QueryRow(ctx, `
+		SELECT COALESCE(o.region, ''), COALESCE(m.hide_amounts, FALSE), COALESCE(m.hidden_metadata, '{}')
+		FROM org_users ou
+		JOIN organizations o ON o.id = ou.organization_id
+		LEFT JOIN masking_rules m ON m.organization_id = ou.organization_id AND m.role = ou.role
+		WHERE ou.user_id = $1 AND ou.organization_id = $2
+	`, claims.UserID, claims.OrgID).Scan(&s.region, &s.rule.HideAmounts, &s.rule.HiddenMetadata)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.pool, err = h.Router.Pool(s.region); err != nil {
+		api.Error(w, "organization region unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	graphql.Handler(dashboardSchema()).ServeHTTP(w, r.WithContext(context.WithValue(ctx, graphSessionKey{}, s)))
+}
+
+// graphSession is who a GraphQL request reads for.
+type graphSession struct {
+	handler    *GraphQLHandler
+	orgID      string
+	region     string
+	pool       *pgxpool.Pool // of the organization's region
+	rule       MaskingRule
+	rehydrated map[string]error // by ledger
+}
+
+type graphSessionKey struct{}
+
+func sessionOf(ctx context.Context) *graphSession {
+	return ctx.Value(graphSessionKey{}).(*graphSession)
+}
+
+// ledgerPool returns the database holding a ledger of the organization, restoring the
+// ledger first if it was archived.
+func (s *graphSession) ledgerPool(ctx context.Context, ledgerID string) (*pgxpool.Pool, error) {
+	if s.handler.Rehydrate == nil {
+		return s.pool, nil
+	}
+	err, done := s.rehydrated[ledgerID]
+	if !done {
+		err = s.handler.Rehydrate(ctx, s.region, ledgerID)
+		if err != nil {
+			log.Printf("rehydrate ledger %s: %v", ledgerID, err)
+			err = errors.New("ledger is being restored from the archival tier, retry shortly")
+		}
+		s.rehydrated[ledgerID] = err
+	}
+	return s.pool, err
+}
+
+// batch loads the values of a set of sibling objects' keys in one query, the first time
+// one of them is asked for.
+type batch[V any] struct {
+	keys   []string
+	load   func(keys []string) (map[string]V, error)
+	once   sync.Once
+	values map[string]V
+	err    error
+}
+
+func newBatch[V any](load func(keys []string) (map[string]V, error)) *batch[V] {
+	return &batch[V]{load: load}
+}
+
+func (b *batch[V]) add(key string) {
+	b.keys = append(b.keys, key)
+}
+
+func (b *batch[V]) get(key string) (V, error) {
+	b.once.Do(func() {
+		b.values, b.err = b.load(b.keys)
+	})
+	return b.values[key], b.err
+}
+
+type graphAccount struct {
+	ledger.AccountResponse
+	postings func(limit int) *batch[[]*graphPosting] // of the account and its siblings
+}
+
+type graphTransaction struct {
+	ledger.TransactionResponse
+	createdAt time.Time // for pagination
+	postings  *batch[[]*graphPosting]
+}
+
+type graphPosting struct {
+	ledger.PostingDetail
+	TransactionID string `json:"transaction_id"`
+	account       *batch[*graphAccount]
+	transaction   *batch[*graphTransaction]
+}
+
+type graphTransactionPage struct {
+	Transactions []*graphTransaction    `json:"transactions"`
+	Pagination   api.PaginationResponse `json:"pagination"`
+}
+
+var dashboardSchema = sync.OnceValue(func() *graphql.Schema {
+	pagination := &graphql.Object{Name: "Pagination", Fields: graphql.Fields{
+		"has_more":           {Type: graphql.Boolean},
+		"continuation_token": {Type: graphql.String},
+		"count":              {Type: graphql.Int},
+	}}
+	delivery := &graphql.Object{Name: "WebhookDelivery", Fields: graphql.Fields{
+		"id":                  {Type: graphql.ID},
+		"delivery_id":         {Type: graphql.ID},
+		"event_id":            {Type: graphql.ID},
+		"webhook_endpoint_id": {Type: graphql.ID},
+		"endpoint_url":        {Type: graphql.String},
+		"status":              {Type: graphql.String},
+		"attempt":             {Type: graphql.Int},
+		"last_attempt_at":     {Type: graphql.String},
+		"next_attempt_at":     {Type: graphql.String},
+		"http_status":         {Type: graphql.Int},
+		"error_message":       {Type: graphql.String},
+	}}
+	account := &graphql.Object{Name: "Account"}
+	transaction := &graphql.Object{Name: "Transaction"}
+	posting := &graphql.Object{Name: "Posting", Fields: graphql.Fields{
+		"id":             {Type: graphql.ID},
+		"transaction_id": {Type: graphql.ID},
+		"account_code":   {Type: graphql.String},
+		"account_name":   {Type: graphql.String},
+		"direction":      {Type: graphql.String},
+		"amount":         {Type: graphql.String},
+		"currency":       {Type: graphql.String},
+		"tax_code":       {Type: graphql.String},
+		"description":    {Type: graphql.String},
+		"account": {Type: account, Resolve: func(p graphql.Params) (any, error) {
+			posting := p.Source.(*graphPosting)
+			return posting.account.get(posting.AccountCode)
+		}},
+		"transaction": {Type: transaction, Resolve: func(p graphql.Params) (any, error) {
+			posting := p.Source.(*graphPosting)
+			return posting.transaction.get(posting.TransactionID)
+		}},
+	}}
+	account.Fields = graphql.Fields{
+		"id":                     {Type: graphql.ID},
+		"code":                   {Type: graphql.String},
+		"name":                   {Type: graphql.String},
+		"type":                   {Type: graphql.String},
+		"balance":                {Type: graphql.String},
+		"held_balance":           {Type: graphql.String},
+		"available_balance":      {Type: graphql.String},
+		"tax_code":               {Type: graphql.String},
+		"entity":                 {Type: graphql.String},
+		"status":                 {Type: graphql.String},
+		"allow_negative_balance": {Type: graphql.Boolean},
+		"min_balance":            {Type: graphql.String},
+		"metadata":               {Type: graphql.JSON},
+		"created_at":             {Type: graphql.String},
+		"postings": {
+			Type: graphql.ListOf(posting),
+			Args: graphql.Args{"limit": {Type: graphql.Int, Default: 20}},
+			Size: func(args map[string]any) int { return min(max(args["limit"].(int), 1), maxNestedPostings) },
+			Resolve: func(p graphql.Params) (any, error) {
+				account := p.Source.(*graphAccount)
+				return account.postings(min(max(p.Args["limit"].(int), 1), maxNestedPostings)).get(account.ID)
+			},
+		},
+	}
+	transaction.Fields = graphql.Fields{
+		"id":          {Type: graphql.ID},
+		"external_id": {Type: graphql.String},
+		"description": {Type: graphql.String},
+		"amount":      {Type: graphql.String},
+		"currency":    {Type: graphql.String},
+		"occurred_at": {Type: graphql.String},
+		"value_date":  {Type: graphql.String},
+		"created_at":  {Type: graphql.String},
+		"entity":      {Type: graphql.String},
+		"metadata":    {Type: graphql.JSON},
+		"postings": {Type: graphql.ListOf(posting), Resolve: func(p graphql.Params) (any, error) {
+			transaction := p.Source.(*graphTransaction)
+			return transaction.postings.get(transaction.ID)
+		}},
+	}
+	ledgerType := &graphql.Object{Name: "Ledger", Fields: graphql.Fields{
+		"id":         {Type: graphql.ID},
+		"project_id": {Type: graphql.ID},
+		"name":       {Type: graphql.String},
+		"code":       {Type: graphql.String},
+		"currency":   {Type: graphql.String},
+		"created_at": {Type: graphql.String},
+		"accounts": {
+			Type: graphql.ListOf(account),
+			Args: graphql.Args{"status": {Type: graphql.String, Default: "active"}, "limit": {Type: graphql.Int, Default: 100}},
+			Size: limitSize,
+			Resolve: func(p graphql.Params) (any, error) {
+				switch p.Args["status"] {
+				case "active", "disabled", "all":
+				default:
+					return nil, errors.New("status must be active, disabled or all")
+				}
+				return sessionOf(p.Context).accounts(p.Context, p.Source.(*LedgerResponse).ID,
+					`AND ($2 = 'all' OR status = $2) ORDER BY code LIMIT $3`, p.Args["status"], api.ValidateLimit(p.Args["limit"].(int)))
+			},
+		},
+		"account": {
+			Type: account,
+			Args: graphql.Args{"code": {Type: graphql.String, Required: true}},
+			Resolve: func(p graphql.Params) (any, error) {
+				return first(sessionOf(p.Context).accounts(p.Context, p.Source.(*LedgerResponse).ID, `AND code = $2`, p.Args["code"]))
+			},
+		},
+		"transactions": {
+			Type: &graphql.Object{Name: "TransactionPage", Fields: graphql.Fields{
+				"transactions": {Type: graphql.ListOf(transaction)},
+				"pagination":   {Type: pagination},
+			}},
+			Args: graphql.Args{"limit": {Type: graphql.Int, Default: 20}, "continuation_token": {Type: graphql.String}},
+			Size: limitSize,
+			Resolve: func(p graphql.Params) (any, error) {
+				token, _ := p.Args["continuation_token"].(string)
+				return sessionOf(p.Context).transactionPage(p.Context, p.Source.(*LedgerResponse).ID,
+					api.ValidateLimit(p.Args["limit"].(int)), token)
+			},
+		},
+		"transaction": {
+			Type: transaction,
+			Args: graphql.Args{"id": {Type: graphql.ID, Required: true}},
+			Resolve: func(p graphql.Params) (any, error) {
+				return first(sessionOf(p.Context).transactions(p.Context, p.Source.(*LedgerResponse).ID, `AND t.id::text = $2`, p.Args["id"]))
+			},
+		},
+		"webhook_deliveries": {
+			Type: graphql.ListOf(delivery),
+			Args: graphql.Args{"limit": {Type: graphql.Int, Default: 100}, "status": {Type: graphql.String}},
+			Size: limitSize,
+			Resolve: func(p graphql.Params) (any, error) {
+				status, _ := p.Args["status"].(string)
+				return sessionOf(p.Context).webhookDeliveries(p.Context, p.Source.(*LedgerResponse).ID,
+					api.ValidateLimit(p.Args["limit"].(int)), status)
+			},
+		},
+	}}
+
+	return &graphql.Schema{Query: &graphql.Object{Name: "Query", Fields: graphql.Fields{
+		"ledgers": {Type: graphql.ListOf(ledgerType), Resolve: func(p graphql.Params) (any, error) {
+			return sessionOf(p.Context).ledgers(p.Context, "")
+		}},
+		"ledger": {
+			Type: ledgerType,
+			Args: graphql.Args{"id": {Type: graphql.ID, Required: true}},
+			Resolve: func(p graphql.Params) (any, error) {
+				return first(sessionOf(p.Context).ledgers(p.Context, p.Args["id"].(string)))
+			},
+		},
+	}}}
+})
+
+// Most postings Account.postings returns per account.
+const maxNestedPostings = 100
+
+// limitSize is the Size of fields limited by api.ValidateLimit.
+func limitSize(args map[string]any) int {
+	return api.ValidateLimit(args["limit"].(int))
+}
+
+// first returns the first of values, or nil if there are none.
+func first[V any](values []*V, err error) (*V, error) {
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// ledgers returns the organization's ledgers, or the one with the given id.
+func (s *graphSession) ledgers(ctx context.Context, id string) ([]*LedgerResponse, error) {
+	rows, err := s.handler.DB.Query(ctx, `
+		SELECT l.id, l.project_id, l.name, l.code, l.currency, l.created_at
+		FROM ledgers l
+		JOIN projects p ON p.id = l.project_id
+		WHERE p.organization_id = $1 AND ($2 = '' OR l.id::text = $2)
+		ORDER BY l.created_at DESC
+	`, s.orgID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledgers: %w", err)
+	}
+	defer rows.Close()
+
+	ledgers := []*LedgerResponse{}
+	for rows.Next() {
+		var l LedgerResponse
+		var createdAt time.Time
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Name, &l.Code, &l.Currency, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger: %w", err)
+		}
+		l.CreatedAt = createdAt.Format(time.RFC3339)
+		ledgers = append(ledgers, &l)
+	}
+	return ledgers, rows.Err()
+}
+
+// accounts returns the ledger's accounts matching where, which follows WHERE ledger_id =
+// $1 and uses args from $2 on.
+func (s *graphSession) accounts(ctx context.Context, ledgerID, where string, args ...any) ([]*graphAccount, error) {
+	pool, err := s.ledgerPool(ctx, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT id, code, name, type, balance, held_balance, balance - held_balance, COALESCE(tax_code, ''), COALESCE(entity_code, ''), status,
+			allow_negative_balance, COALESCE(min_balance::text, ''), metadata, created_at
+		FROM accounts
+		WHERE ledger_id = $1 `+where, append([]any{ledgerID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query accounts: %w", err)
+	}
+	defer rows.Close()
+
+	// Siblings load their postings together, once per limit asked for
+	postings := map[int]*batch[[]*graphPosting]{}
+	var ids []string
+	postingsOf := func(limit int) *batch[[]*graphPosting] {
+		if b, ok := postings[limit]; ok {
+			return b
+		}
+		b := newBatch(func(ids []string) (map[string][]*graphPosting, error) {
+			return s.postings(ctx, ledgerID, `
+				FROM accounts a
+				CROSS JOIN LATERAL (
+					SELECT * FROM postings
+					WHERE ledger_id = $1 AND account_id = a.id
+					ORDER BY created_at DESC, id DESC
+					LIMIT $3
+				) p
+				JOIN transactions t ON t.id = p.transaction_id
+				WHERE a.ledger_id = $1 AND a.id::text = ANY($2)
+				ORDER BY p.created_at DESC, p.id DESC
+			`, func(p *graphPosting, accountID string) string { return accountID }, ids, limit)
+		})
+		b.keys = ids
+		postings[limit] = b
+		return b
+	}
+
+	accounts := []*graphAccount{}
+	for rows.Next() {
+		a := &graphAccount{postings: postingsOf}
+		var createdAt time.Time
+		err := rows.Scan(&a.ID, &a.Code, &a.Name, &a.Type, &a.Balance, &a.HeldBalance, &a.AvailableBalance, &a.TaxCode, &a.Entity,
+			&a.Status, &a.AllowNegativeBalance, &a.MinBalance, &a.Metadata, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		a.CreatedAt = createdAt.Format(time.RFC3339)
+		if s.rule.HideAmounts {
+			a.Balance, a.HeldBalance, a.AvailableBalance = maskedValue, maskedValue, maskedValue
+			if a.MinBalance != "" {
+				a.MinBalance = maskedValue
+			}
+		}
+		s.rule.maskMetadata(a.Metadata)
+		accounts = append(accounts, a)
+		ids = append(ids, a.ID)
+	}
+	return accounts, rows.Err()
+}
+
+// transactionPage returns a page of the ledger's transactions, newest first, as
+// GET /v1/transactions does.
+func (s *graphSession) transactionPage(ctx context.Context, ledgerID string, limit int, token string) (*graphTransactionPage, error) {
+	cursor, err := api.DecodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	var transactions []*graphTransaction
+	if cursor.Timestamp.IsZero() {
+		transactions, err = s.transactions(ctx, ledgerID, `ORDER BY t.created_at DESC, t.id DESC LIMIT $2`, limit+1)
+	} else {
+		transactions, err = s.transactions(ctx, ledgerID, `AND (t.created_at, t.id) < ($2, $3)
+			ORDER BY t.created_at DESC, t.id DESC LIMIT $4`, cursor.Timestamp, cursor.ID, limit+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &graphTransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.Pagination.HasMore = true
+		page.Pagination.ContinuationToken, _ = api.EncodeCursor(api.Cursor{Timestamp: last.createdAt, ID: last.ID})
+	}
+	page.Pagination.Count = len(page.Transactions)
+	return page, nil
+}
+
+// transactions returns the ledger's transactions matching where, which follows WHERE
+// t.ledger_id = $1 and uses args from $2 on.
+func (s *graphSession) transactions(ctx context.Context, ledgerID, where string, args ...any) ([]*graphTransaction, error) {
+	pool, err := s.ledgerPool(ctx, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT t.id, COALESCE(t.external_id, ''), COALESCE(t.description, ''), t.amount, t.currency, t.occurred_at, t.value_date::text,
+			t.created_at, COALESCE(t.entity_code, ''), t.metadata
+		FROM transactions t
+		WHERE t.ledger_id = $1 `+where, append([]any{ledgerID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	postings := newBatch(func(ids []string) (map[string][]*graphPosting, error) {
+		return s.postings(ctx, ledgerID, `
+			FROM postings p
+			JOIN transactions t ON t.id = p.transaction_id
+			JOIN accounts a ON a.id = p.account_id
+			WHERE p.ledger_id = $1 AND p.transaction_id::text = ANY($2)
+			ORDER BY p.created_at, p.id
+		`, func(p *graphPosting, _ string) string { return p.TransactionID }, ids)
+	})
+	transactions := []*graphTransaction{}
+	for rows.Next() {
+		t := &graphTransaction{postings: postings}
+		var occurredAt time.Time
+		err := rows.Scan(&t.ID, &t.ExternalID, &t.Description, &t.Amount, &t.Currency, &occurredAt, &t.ValueDate, &t.createdAt,
+			&t.Entity, &t.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		t.OccurredAt = occurredAt.Format(time.RFC3339)
+		t.CreatedAt = t.createdAt.Format(time.RFC3339)
+		if s.rule.HideAmounts {
+			t.Amount = maskedValue
+		}
+		s.rule.maskMetadata(t.Metadata)
+		transactions = append(transactions, t)
+		postings.add(t.ID)
+	}
+	return transactions, rows.Err()
+}
+
+// postings loads the ledger's postings of keys, by the key keyOf gives each. from is the
+// query's FROM clause onwards, with postings as p, their accounts as a and transactions as
+// t, and keys as $2; args follow from $3 on.
+func (s *graphSession) postings(ctx context.Context, ledgerID, from string, keyOf func(p *graphPosting, accountID string) string, keys []string, args ...any) (map[string][]*graphPosting, error) {
+	pool, err := s.ledgerPool(ctx, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT p.id, p.transaction_id, a.id, a.code, a.name, p.direction, p.amount, COALESCE(p.currency, t.currency),
+			COALESCE(p.tax_code, ''), COALESCE(p.description, '')
+		`+from, append([]any{ledgerID, keys}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query postings: %w", err)
+	}
+	defer rows.Close()
+
+	// The postings' accounts and transactions are loaded together in turn
+	accounts := newBatch(func(codes []string) (map[string]*graphAccount, error) {
+		return byKey(s.accounts(ctx, ledgerID, `AND code = ANY($2)`, codes))(func(a *graphAccount) string { return a.Code })
+	})
+	transactions := newBatch(func(ids []string) (map[string]*graphTransaction, error) {
+		return byKey(s.transactions(ctx, ledgerID, `AND t.id::text = ANY($2)`, ids))(func(t *graphTransaction) string { return t.ID })
+	})
+
+	postings := map[string][]*graphPosting{}
+	for rows.Next() {
+		p := &graphPosting{account: accounts, transaction: transactions}
+		var accountID string
+		err := rows.Scan(&p.ID, &p.TransactionID, &accountID, &p.AccountCode, &p.AccountName, &p.Direction, &p.Amount, &p.Currency,
+			&p.TaxCode, &p.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan posting: %w", err)
+		}
+		if s.rule.HideAmounts {
+			p.Amount = maskedValue
+		}
+		key := keyOf(p, accountID)
+		postings[key] = append(postings[key], p)
+		accounts.add(p.AccountCode)
+		transactions.add(p.TransactionID)
+	}
+	return postings, rows.Err()
+}
+
+// byKey indexes values by the key keyOf gives each.
+func byKey[V any](values []V, err error) func(keyOf func(V) string) (map[string]V, error) {
+	return func(keyOf func(V) string) (map[string]V, error) {
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]V, len(values))
+		for _, v := range values {
+			m[keyOf(v)] = v
+		}
+		return m, nil
+	}
+}
+
+// webhookDeliveries returns the ledger's latest webhook delivery attempts, as
+// GET /v1/webhook-deliveries does.
+func (s *graphSession) webhookDeliveries(ctx context.Context, ledgerID string, limit int, status string) ([]WebhookDeliveryResponse, error) {
+	pool, err := s.ledgerPool(ctx, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pool.Query(ctx, `
+		SELECT wd.id, wd.event_id, wd.webhook_endpoint_id, we.url, wd.status, wd.attempt, wd.last_attempt_at,
+			wd.next_attempt_at, COALESCE(wd.http_status, 0), COALESCE(wd.error_message, '')
+		FROM webhook_deliveries wd
+		JOIN webhook_endpoints we ON we.id = wd.webhook_endpoint_id
+		WHERE we.ledger_id = $1 AND ($2 = '' OR wd.status = $2)
+		ORDER BY wd.last_attempt_at DESC
+		LIMIT $3
+	`, ledgerID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDeliveryResponse{}
+	for rows.Next() {
+		var d WebhookDeliveryResponse
+		var lastAttemptAt, nextAttemptAt *time.Time
+		err := rows.Scan(&d.ID, &d.EventID, &d.WebhookEndpointID, &d.EndpointURL, &d.Status, &d.Attempt, &lastAttemptAt,
+			&nextAttemptAt, &d.HTTPStatus, &d.ErrorMessage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if lastAttemptAt != nil {
+			d.LastAttemptAt = lastAttemptAt.Format(time.RFC3339)
+		}
+		if nextAttemptAt != nil {
+			d.NextAttemptAt = nextAttemptAt.Format(time.RFC3339)
+		}
+		d.DeliveryID = webhook.DeliveryID(d.EventID, d.WebhookEndpointID)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,76 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LedgerAccess lets dashboard sessions use the read-only ledger APIs. It authorizes the
+// session for the ledger in the {id} path segment and passes the request on with that
+// ledger's principal, as if it had been made with one of the ledger's API keys. The
+// responses are masked by the masking rule of the user's role, if any.
+type LedgerAccess struct {
+	DB        *pgxpool.Pool
+	JWTSecret []byte
+}
+
+func (a *LedgerAccess) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			api.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := auth.ValidateSession(r.Context(), a.DB, cookie.Value, a.JWTSecret)
+		if err != nil {
+			api.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Ledgers of other organizations are reported as missing, not forbidden
+		var principal auth.Principal
+		err = a.DB.QueryRow(ctx, `
+			SELECT l.id, p.id, o.id, COALESCE(o.region, '')
+			FROM ledgers l
+			JOIN projects p ON p.id = l.project_id
+			JOIN organizations o ON o.id = p.organization_id
+			WHERE l.id::text = $1 AND o.id = $2
+		`, r.PathValue("id"), claims.OrgID).Scan(&principal.LedgerID, &principal.ProjectID, &principal.OrganizationID, &principal.Region)
+		if err != nil {
+			api.Error(w, "ledger not found", http.StatusNotFound)
+			return
+		}
+
+		var rule MaskingRule
+		err = a.DB.QueryRow(ctx, `
+			SELECT u.email, COALESCE(m.hide_amounts, FALSE), COALESCE(m.hidden_metadata, '{}')
+			FROM org_users ou
+			JOIN users u ON u.id = ou.user_id
+			LEFT JOIN masking_rules m ON m.organization_id = ou.organization_id AND m.role = ou.role
+			WHERE ou.user_id = $1 AND ou.organization_id = $2
+		`, claims.UserID, claims.OrgID).Scan(&principal.UserEmail, &rule.HideAmounts, &rule.HiddenMetadata)
+		if err != nil {
+			api.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r = r.WithContext(auth.WithPrincipal(ctx, principal))
+		if !rule.active() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !rule.allowsQuery(r.URL.Query()) {
+			api.Error(w, "filtering on hidden metadata is not permitted", http.StatusForbidden)
+			return
+		}
+		mw := &maskingWriter{ResponseWriter: w, rule: rule}
+		next.ServeHTTP(mw, r)
+		mw.flush()
+	})
+}
@@ -1,15 +1,19 @@
 package dashboard
 
 import (
+	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/db"
 	"encoding/json"
 	"net/http"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type LedgerHandler struct {
-	DB *pgxpool.Pool
+	DB     *pgxpool.Pool
+	Router *db.Router
 }
 
 type LedgerResponse struct {
@@ -35,13 +39,13 @@ func (h *LedgerHandler) ListLedgers(w http.ResponseWriter, r *http.Request) {
 	// Extract JWT claims
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, []byte("jwt-secret")) // TODO: use config
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret")) // TODO: use config
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -53,7 +57,7 @@ func (h *LedgerHandler) ListLedgers(w http.ResponseWriter, r *http.Request) {
 		ORDER BY l.created_at DESC
 	`, claims.OrgID)
 	if err != nil {
-		http.Error(w, "failed to query ledgers", http.StatusInternalServerError)
+		api.Error(w, "failed to query ledgers", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -63,7 +67,7 @@ func (h *LedgerHandler) ListLedgers(w http.ResponseWriter, r *http.Request) {
 		var ledger LedgerResponse
 		err = rows.Scan(&ledger.ID, &ledger.ProjectID, &ledger.Name, &ledger.Code, &ledger.Currency, &ledger.CreatedAt)
 		if err != nil {
-			http.Error(w, "failed to scan ledger", http.StatusInternalServerError)
+			api.Error(w, "failed to scan ledger", http.StatusInternalServerError)
 			return
 		}
 		ledgers = append(ledgers, ledger)
@@ -79,19 +83,19 @@ func (h *LedgerHandler) GetLedger(w http.ResponseWriter, r *http.Request) {
 
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, []byte("jwt-secret"))
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	ledgerID := r.URL.Query().Get("id")
 	if ledgerID == "" {
-		http.Error(w, "ledger id required", http.StatusBadRequest)
+		api.Error(w, "ledger id required", http.StatusBadRequest)
 		return
 	}
 
@@ -103,7 +107,7 @@ func (h *LedgerHandler) GetLedger(w http.ResponseWriter, r *http.Request) {
 		WHERE l.id = $1 AND p.organization_id = $2
 	`, ledgerID, claims.OrgID).Scan(&ledger.ID, &ledger.ProjectID, &ledger.Name, &ledger.Code, &ledger.Currency, &ledger.CreatedAt)
 	if err != nil {
-		http.Error(w, "ledger not found", http.StatusNotFound)
+		api.Error(w, "ledger not found", http.StatusNotFound)
 		return
 	}
 
@@ -117,19 +121,19 @@ func (h *LedgerHandler) CreateLedger(w http.ResponseWriter, r *http.Request) {
 
 	cookie, err := r.Cookie("session")
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	claims, err := auth.ValidateJWT(cookie.Value, []byte("jwt-secret"))
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req CreateLedgerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
@@ -139,19 +143,39 @@ func (h *LedgerHandler) CreateLedger(w http.ResponseWriter, r *http.Request) {
 		SELECT organization_id FROM projects WHERE id = $1
 	`, req.ProjectID).Scan(&projectOrgID)
 	if err != nil || projectOrgID != claims.OrgID {
-		http.Error(w, "project not found", http.StatusNotFound)
+		api.Error(w, "project not found", http.StatusNotFound)
 		return
 	}
 
 	// Create ledger
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	var ledgerID string
-	err = h.DB.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		INSERT INTO ledgers (project_id, name, code, currency)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id
 	`, req.ProjectID, req.Name, req.Code, req.Currency).Scan(&ledgerID)
 	if err != nil {
-		http.Error(w, "failed to create ledger", http.StatusInternalServerError)
+		api.Error(w, "failed to create ledger", http.StatusInternalServerError)
+		return
+	}
+
+	// Make the ledger referenceable from the organization's regional database before it
+	// exists in the control plane, so a failed copy leaves nothing behind to retry
+	if h.Router != nil {
+		if err := h.Router.EnsureLedgerTx(ctx, tx, ledgerID); err != nil {
+			api.Error(w, "failed to provision ledger in its region", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to create ledger", http.StatusInternalServerError)
 		return
 	}
 
@@ -167,3 +191,82 @@ func (h *LedgerHandler) CreateLedger(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
 }
+
+type LedgerStatsResponse struct {
+	LedgerID         string `json:"ledger_id"`
+	Region           string `json:"region"`
+	AccountCount     int    `json:"account_count"`
+	TransactionCount int    `json:"transaction_count"`
+	EventCount       int    `json:"event_count"`
+}
+
+// GET /api/ledgers/stats - Per-ledger activity counts for the organization
+//
+// Ledger data lives in the organization's region, so the ledger list comes from the
+// control plane and the counts from the regional database. Only aggregates leave the region.
+func (h *LedgerHandler) GetLedgerStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	region, pool := "", h.DB
+	if h.Router != nil {
+		region, pool, err = h.Router.ForOrganization(ctx, claims.OrgID)
+		if err != nil {
+			api.Error(w, "failed to resolve organization region", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		SELECT l.id
+		FROM ledgers l
+		JOIN projects p ON p.id = l.project_id
+		WHERE p.organization_id = $1
+		ORDER BY l.created_at DESC
+	`, claims.OrgID)
+	if err != nil {
+		api.Error(w, "failed to query ledgers", http.StatusInternalServerError)
+		return
+	}
+	var ledgerIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			api.Error(w, "failed to scan ledger", http.StatusInternalServerError)
+			return
+		}
+		ledgerIDs = append(ledgerIDs, id)
+	}
+	rows.Close()
+
+	stats := []LedgerStatsResponse{}
+	for _, id := range ledgerIDs {
+		s := LedgerStatsResponse{LedgerID: id, Region: region}
+		err := pool.QueryRow(ctx, `
+			SELECT
+				(SELECT COUNT(*) FROM accounts WHERE ledger_id = $1),
+				(SELECT COUNT(*) FROM transactions WHERE ledger_id = $1),
+				(SELECT COUNT(*) FROM events WHERE ledger_id = $1)
+		`, id).Scan(&s.AccountCount, &s.TransactionCount, &s.EventCount)
+		if err != nil {
+			api.Error(w, "failed to query ledger stats", http.StatusInternalServerError)
+			return
+		}
+		stats = append(stats, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
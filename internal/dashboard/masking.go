@@ -0,0 +1,138 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const maskedValue = "***"
+
+// MaskingRule hides sensitive values from a role's dashboard ledger reads, so e.g.
+// support staff can triage without seeing them.
+type MaskingRule struct {
+	HideAmounts    bool     `json:"hide_amounts"`
+	HiddenMetadata []string `json:"hidden_metadata"` // metadata keys, or "*" for all
+}
+
+// amountFields are the response fields carrying monetary values.
+var amountFields = map[string]bool{
+	"amount":            true,
+	"balance":           true,
+	"held_balance":      true,
+	"available_balance": true,
+	"min_balance":       true,
+	"total_assets":      true,
+	"total_liabilities": true,
+	"total_equity":      true,
+	"total_revenue":     true,
+	"total_expenses":    true,
+}
+
+func (m MaskingRule) active() bool {
+	return m.HideAmounts || len(m.HiddenMetadata) > 0
+}
+
+func (m MaskingRule) hidesMetadata(key string) bool {
+	return slices.Contains(m.HiddenMetadata, "*") || slices.Contains(m.HiddenMetadata, key)
+}
+
+// allowsQuery rejects metadata filters on hidden keys, which would reveal their values.
+func (m MaskingRule) allowsQuery(q url.Values) bool {
+	for param := range q {
+		key, ok := strings.CutPrefix(param, "metadata[")
+		if ok && m.hidesMetadata(strings.TrimSuffix(key, "]")) {
+			return false
+		}
+	}
+	return true
+}
+
+// mask replaces hidden values in a decoded JSON document. Masked fields keep their key
+// so readers can tell a value exists.
+func (m MaskingRule) mask(v any) {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			m.mask(item)
+		}
+	case map[string]any:
+		for key, value := range v {
+			switch {
+			case key == "metadata":
+				if fields, ok := value.(map[string]any); ok {
+					m.maskMetadata(fields)
+				}
+			case m.HideAmounts && amountFields[key] && value != nil:
+				v[key] = maskedValue
+			case m.HideAmounts && key == "by_type":
+				if totals, ok := value.(map[string]any); ok {
+					for k := range totals {
+						totals[k] = maskedValue
+					}
+				}
+			default:
+				m.mask(value)
+			}
+		}
+	}
+}
+
+// maskMetadata replaces the hidden values of metadata.
+func (m MaskingRule) maskMetadata(metadata map[string]any) {
+	for k := range metadata {
+		if m.hidesMetadata(k) {
+			metadata[k] = maskedValue
+		}
+	}
+}
+
+// maskingWriter buffers a JSON response so it can be masked before it is sent.
+type maskingWriter struct {
+	http.ResponseWriter
+	rule   MaskingRule
+	status int
+	body   bytes.Buffer
+}
+
+func (w *maskingWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *maskingWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// flush masks a successful JSON response and sends it. Errors are sent unchanged.
+func (w *maskingWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	body := w.body.Bytes()
+
+	if w.status < 300 && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		var doc any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			api.Error(w.ResponseWriter, "failed to mask response", http.StatusInternalServerError)
+			return
+		}
+		w.rule.mask(doc)
+		masked, err := json.Marshal(doc)
+		if err != nil {
+			api.Error(w.ResponseWriter, "failed to mask response", http.StatusInternalServerError)
+			return
+		}
+		body = append(masked, '\n')
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,147 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MaskingHandler struct {
+	DB *pgxpool.Pool
+}
+
+type MaskingRuleRequest struct {
+	Role string `json:"role"` // developer or support
+	MaskingRule
+}
+
+type MaskingRuleResponse struct {
+	Role string `json:"role"`
+	MaskingRule
+	UpdatedAt string `json:"updated_at"`
+}
+
+// GET /api/masking-rules - List the organization's masking rules
+func (h *MaskingHandler) ListMaskingRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		SELECT role, hide_amounts, hidden_metadata, updated_at
+		FROM masking_rules
+		WHERE organization_id = $1
+		ORDER BY role
+	`, claims.OrgID)
+	if err != nil {
+		api.Error(w, "failed to query masking rules", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rules := []MaskingRuleResponse{}
+	for rows.Next() {
+		var rule MaskingRuleResponse
+		var updatedAt time.Time
+		if err := rows.Scan(&rule.Role, &rule.HideAmounts, &rule.HiddenMetadata, &updatedAt); err != nil {
+			api.Error(w, "failed to scan masking rule", http.StatusInternalServerError)
+			return
+		}
+		rule.UpdatedAt = updatedAt.Format(time.RFC3339)
+		rules = append(rules, rule)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// PUT /api/masking-rules - Set a role's masking rule (owners only); an empty rule unmasks the role
+func (h *MaskingHandler) SetMaskingRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var role string
+	err = h.DB.QueryRow(ctx, `
+		SELECT role FROM org_users WHERE user_id = $1 AND organization_id = $2
+	`, claims.UserID, claims.OrgID).Scan(&role)
+	if err != nil || role != "owner" {
+		api.Error(w, "only owners can change masking rules", http.StatusForbidden)
+		return
+	}
+
+	var req MaskingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Role != "developer" && req.Role != "support" {
+		api.Error(w, "role must be developer or support", http.StatusBadRequest)
+		return
+	}
+	if req.HiddenMetadata == nil {
+		req.HiddenMetadata = []string{}
+	}
+	for _, key := range req.HiddenMetadata {
+		if key == "" {
+			api.Error(w, "hidden_metadata keys must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !req.active() {
+		_, err := h.DB.Exec(ctx, `
+			DELETE FROM masking_rules WHERE organization_id = $1 AND role = $2
+		`, claims.OrgID, req.Role)
+		if err != nil {
+			api.Error(w, "failed to update masking rule", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var updatedAt time.Time
+	err = h.DB.QueryRow(ctx, `
+		INSERT INTO masking_rules (organization_id, role, hide_amounts, hidden_metadata)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, role) DO UPDATE
+			SET hide_amounts = EXCLUDED.hide_amounts, hidden_metadata = EXCLUDED.hidden_metadata, updated_at = NOW()
+		RETURNING updated_at
+	`, claims.OrgID, req.Role, req.HideAmounts, req.HiddenMetadata).Scan(&updatedAt)
+	if err != nil {
+		api.Error(w, "failed to update masking rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MaskingRuleResponse{
+		Role:        req.Role,
+		MaskingRule: req.MaskingRule,
+		UpdatedAt:   updatedAt.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,63 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMaskingRuleMasksResponse(t *testing.T) {
+	rule := MaskingRule{HideAmounts: true, HiddenMetadata: []string{"iban"}}
+
+	rec := httptest.NewRecorder()
+	mw := &maskingWriter{ResponseWriter: rec, rule: rule}
+	mw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(mw).Encode(map[string]any{
+		"transactions": []any{map[string]any{
+			"id":       "t1",
+			"amount":   "10.00",
+			"metadata": map[string]any{"iban": "DE89", "order": "42"},
+			"postings": []any{map[string]any{"account_code": "a", "amount": "10.00"}},
+		}},
+		"by_type": map[string]any{"asset": "10.00"},
+	})
+	mw.flush()
+
+	body := rec.Body.String()
+	for _, hidden := range []string{"10.00", "DE89"} {
+		if strings.Contains(body, hidden) {
+			t.Fatalf("expected %s to be masked: %s", hidden, body)
+		}
+	}
+	for _, kept := range []string{`"t1"`, `"42"`, `"account_code":"a"`} {
+		if !strings.Contains(body, kept) {
+			t.Fatalf("expected %s to be kept: %s", kept, body)
+		}
+	}
+
+	// Errors are passed through as they are
+	rec = httptest.NewRecorder()
+	mw = &maskingWriter{ResponseWriter: rec, rule: rule}
+	api.Error(mw, "account not found", http.StatusNotFound)
+	mw.flush()
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "account not found") {
+		t.Fatalf("unexpected error response %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMaskingRuleAllowsQuery(t *testing.T) {
+	rule := MaskingRule{HiddenMetadata: []string{"iban"}}
+	if rule.allowsQuery(url.Values{"metadata[iban]": {"DE89"}}) {
+		t.Fatal("expected filter on hidden metadata to be rejected")
+	}
+	if !rule.allowsQuery(url.Values{"metadata[order]": {"42"}}) {
+		t.Fatal("expected filter on visible metadata to be allowed")
+	}
+	if (MaskingRule{HiddenMetadata: []string{"*"}}).allowsQuery(url.Values{"metadata[order]": {"42"}}) {
+		t.Fatal("expected wildcard to hide every metadata key")
+	}
+}
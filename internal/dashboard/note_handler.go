@@ -0,0 +1,221 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const maxNoteLength = 10000
+
+type NoteHandler struct {
+	DB     *pgxpool.Pool
+	Router *db.Router
+}
+
+type CreateNoteRequest struct {
+	TargetType string `json:"target_type"` // transaction or account
+	TargetID   string `json:"target_id"`   // transaction id or account code
+	Body       string `json:"body"`
+}
+
+type NoteResponse struct {
+	ID          string `json:"id"`
+	TargetType  string `json:"target_type"`
+	TargetID    string `json:"target_id"`
+	AuthorID    string `json:"author_id"`
+	AuthorEmail string `json:"author_email"`
+	Body        string `json:"body"`
+	CreatedAt   string `json:"created_at"`
+}
+
+var errLedgerNotFound = errors.New("ledger not found")
+
+// POST /api/ledgers/notes?ledger= - Add a note to a transaction or account
+func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.TargetID == "" || req.Body == "" {
+		api.Error(w, "target_id and body required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Body) > maxNoteLength {
+		api.Error(w, "body too long", http.StatusBadRequest)
+		return
+	}
+
+	ledgerID := r.URL.Query().Get("ledger")
+	pool, err := h.ledgerPool(ctx, claims.OrgID, ledgerID)
+	if errors.Is(err, errLedgerNotFound) {
+		api.Error(w, "ledger not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to resolve ledger region", http.StatusInternalServerError)
+		return
+	}
+
+	var targetQuery string
+	switch req.TargetType {
+	case "transaction":
+		targetQuery = `SELECT EXISTS (SELECT 1 FROM transactions WHERE ledger_id = $1 AND id::text = $2)`
+	case "account":
+		targetQuery = `SELECT EXISTS (SELECT 1 FROM accounts WHERE ledger_id = $1 AND code = $2)`
+	default:
+		api.Error(w, "target_type must be transaction or account", http.StatusBadRequest)
+		return
+	}
+	var exists bool
+	if err := pool.QueryRow(ctx, targetQuery, ledgerID, req.TargetID).Scan(&exists); err != nil || !exists {
+		api.Error(w, req.TargetType+" not found", http.StatusNotFound)
+		return
+	}
+
+	var authorEmail string
+	err = h.DB.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, claims.UserID).Scan(&authorEmail)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	note := NoteResponse{
+		TargetType:  req.TargetType,
+		TargetID:    req.TargetID,
+		AuthorID:    claims.UserID,
+		AuthorEmail: authorEmail,
+		Body:        req.Body,
+	}
+	var createdAt time.Time
+	err = pool.QueryRow(ctx, `
+		INSERT INTO notes (ledger_id, target_type, target_id, author_id, author_email, body)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, ledgerID, req.TargetType, req.TargetID, claims.UserID, authorEmail, req.Body).Scan(&note.ID, &createdAt)
+	if err != nil {
+		api.Error(w, "failed to create note", http.StatusInternalServerError)
+		return
+	}
+	note.CreatedAt = createdAt.Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// GET /api/ledgers/notes?ledger=&target_type=&target_id= - List a ledger's notes, oldest first
+func (h *NoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ledgerID := r.URL.Query().Get("ledger")
+	pool, err := h.ledgerPool(ctx, claims.OrgID, ledgerID)
+	if errors.Is(err, errLedgerNotFound) {
+		api.Error(w, "ledger not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to resolve ledger region", http.StatusInternalServerError)
+		return
+	}
+
+	query := `
+		SELECT id, target_type, target_id, author_id, author_email, body, created_at
+		FROM notes
+		WHERE ledger_id = $1
+	`
+	args := []interface{}{ledgerID}
+	if targetType := r.URL.Query().Get("target_type"); targetType != "" {
+		args = append(args, targetType)
+		query += ` AND target_type = $2`
+		if targetID := r.URL.Query().Get("target_id"); targetID != "" {
+			args = append(args, targetID)
+			query += ` AND target_id = $3`
+		}
+	}
+	query += ` ORDER BY created_at LIMIT 1000`
+
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query notes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	notes := []NoteResponse{}
+	for rows.Next() {
+		var n NoteResponse
+		var createdAt time.Time
+		err := rows.Scan(&n.ID, &n.TargetType, &n.TargetID, &n.AuthorID, &n.AuthorEmail, &n.Body, &createdAt)
+		if err != nil {
+			api.Error(w, "failed to scan note", http.StatusInternalServerError)
+			return
+		}
+		n.CreatedAt = createdAt.Format(time.RFC3339)
+		notes = append(notes, n)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// ledgerPool checks the ledger belongs to the organization and returns the pool of the
+// organization's region, where the ledger's data lives.
+func (h *NoteHandler) ledgerPool(ctx context.Context, orgID, ledgerID string) (*pgxpool.Pool, error) {
+	var exists bool
+	err := h.DB.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM ledgers l
+			JOIN projects p ON p.id = l.project_id
+			WHERE l.id::text = $1 AND p.organization_id = $2
+		)
+	`, ledgerID, orgID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errLedgerNotFound
+	}
+
+	if h.Router == nil {
+		return h.DB, nil
+	}
+	_, pool, err := h.Router.ForOrganization(ctx, orgID)
+	return pool, err
+}
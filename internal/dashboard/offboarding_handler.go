@@ -0,0 +1,166 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/offboarding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OffboardingHandler struct {
+	DB          *pgxpool.Pool
+	Offboarding *offboarding.Service
+}
+
+// ScheduleDeletionRequest must repeat the organization's name to confirm.
+type ScheduleDeletionRequest struct {
+	Confirm string `json:"confirm"`
+}
+
+// GET /api/organization/offboarding - The organization's latest deletion request
+func (h *OffboardingHandler) GetOffboarding(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := h.Offboarding.Latest(r.Context(), claims.OrgID)
+	if errors.Is(err, offboarding.ErrNotScheduled) {
+		api.Error(w, "no deletion requested", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load deletion request", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(o)
+}
+
+// POST /api/organization/offboarding - Schedule the organization's deletion (owners only)
+func (h *OffboardingHandler) ScheduleDeletion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, email, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	var req ScheduleDeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var name string
+	if err := h.DB.QueryRow(ctx, `SELECT name FROM organizations WHERE id = $1`, claims.OrgID).Scan(&name); err != nil {
+		api.Error(w, "organization not found", http.StatusNotFound)
+		return
+	}
+	if req.Confirm != name {
+		api.Error(w, "confirm must be the organization's name", http.StatusBadRequest)
+		return
+	}
+
+	o, err := h.Offboarding.Schedule(ctx, claims.OrgID, email)
+	if errors.Is(err, offboarding.ErrAlreadyScheduled) {
+		api.Error(w, "deletion already scheduled", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to schedule deletion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(o)
+}
+
+// POST /api/organization/offboarding/cancel - Cancel a scheduled deletion (owners only)
+func (h *OffboardingHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, email, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := h.Offboarding.Cancel(r.Context(), claims.OrgID, email)
+	if errors.Is(err, offboarding.ErrNotScheduled) {
+		api.Error(w, "no deletion scheduled", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to cancel deletion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(o)
+}
+
+// GET /api/organization/export - Download the organization's export bundle (owners only)
+func (h *OffboardingHandler) ExportOrganization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, _, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="organization-export-%s.zip"`,
+		time.Now().UTC().Format("20060102")))
+	// The bundle is streamed, so a failure part-way leaves a truncated zip
+	if err := h.Offboarding.Export(r.Context(), claims.OrgID, w); err != nil {
+		log.Printf("export organization %s: %v", claims.OrgID, err)
+	}
+}
+
+func (h *OffboardingHandler) session(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
+}
+
+// owner authenticates the session of an organization owner and returns their email.
+func (h *OffboardingHandler) owner(w http.ResponseWriter, r *http.Request) (*auth.Claims, string, bool) {
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return nil, "", false
+	}
+
+	var email, role string
+	err := h.DB.QueryRow(r.Context(), `
+		SELECT u.email, ou.role
+		FROM org_users ou JOIN users u ON u.id = ou.user_id
+		WHERE ou.user_id = $1 AND ou.organization_id = $2
+	`, claims.UserID, claims.OrgID).Scan(&email, &role)
+	if err != nil || role != "owner" {
+		api.Error(w, "only owners can delete or export the organization", http.StatusForbidden)
+		return nil, "", false
+	}
+	return claims, email, true
+}
@@ -0,0 +1,204 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/ratelimit"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RateLimitRequest sets a ledger's API rate limit; null fields use the server default.
+// Windows replace the ledger's existing windows.
+type RateLimitRequest struct {
+	PerSecond *float64          `json:"requests_per_second"`
+	Burst     *int              `json:"burst"`
+	Windows   []RateLimitWindow `json:"windows"`
+}
+
+type RateLimitWindow struct {
+	Name       string  `json:"name"`
+	Start      string  `json:"start"` // HH:MM
+	End        string  `json:"end"`   // HH:MM, before start for windows past midnight
+	Timezone   string  `json:"timezone,omitempty"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+type RateLimitResponse struct {
+	LedgerID  string            `json:"ledger_id"`
+	PerSecond *float64          `json:"requests_per_second"`
+	Burst     *int              `json:"burst"`
+	Windows   []RateLimitWindow `json:"windows"`
+}
+
+func (req RateLimitRequest) validate() error {
+	if req.PerSecond != nil && *req.PerSecond < 0 {
+		return fmt.Errorf("requests_per_second must not be negative")
+	}
+	if req.Burst != nil && *req.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1")
+	}
+	names := map[string]bool{}
+	for i, w := range req.Windows {
+		if w.Name == "" || names[w.Name] {
+			return fmt.Errorf("window %d: a unique name is required", i+1)
+		}
+		names[w.Name] = true
+		start, err := ratelimit.ParseClock(w.Start)
+		if err != nil {
+			return fmt.Errorf("window %s: start: %w", w.Name, err)
+		}
+		end, err := ratelimit.ParseClock(w.End)
+		if err != nil {
+			return fmt.Errorf("window %s: end: %w", w.Name, err)
+		}
+		if start == end {
+			return fmt.Errorf("window %s: start and end must differ", w.Name)
+		}
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("window %s: unknown timezone %q", w.Name, w.Timezone)
+		}
+		if w.Multiplier < 1 {
+			return fmt.Errorf("window %s: multiplier must be at least 1", w.Name)
+		}
+	}
+	return nil
+}
+
+// GET /api/ledgers/rate-limit?id= - A ledger's API rate limit and burst windows
+func (h *LedgerHandler) GetRateLimit(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.sessionClaims(r)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.writeRateLimit(w, r, r.URL.Query().Get("id"), claims.OrgID)
+}
+
+// PUT /api/ledgers/rate-limit?id= - Set a ledger's API rate limit and burst windows (owners only)
+func (h *LedgerHandler) SetRateLimit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, err := h.sessionClaims(r)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var role string
+	err = h.DB.QueryRow(ctx, `
+		SELECT role FROM org_users WHERE user_id = $1 AND organization_id = $2
+	`, claims.UserID, claims.OrgID).Scan(&role)
+	if err != nil || role != "owner" {
+		api.Error(w, "only owners can change rate limits", http.StatusForbidden)
+		return
+	}
+
+	var req RateLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	for i := range req.Windows {
+		if req.Windows[i].Timezone == "" {
+			req.Windows[i].Timezone = "UTC"
+		}
+	}
+	if err := req.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ledgerID := r.URL.Query().Get("id")
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		api.Error(w, "failed to update rate limit", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE ledgers l
+		SET rate_limit_per_second = $3, rate_limit_burst = $4
+		FROM projects p
+		WHERE p.id = l.project_id AND l.id::text = $1 AND p.organization_id = $2
+	`, ledgerID, claims.OrgID, req.PerSecond, req.Burst)
+	if err != nil {
+		api.Error(w, "failed to update rate limit", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "ledger not found", http.StatusNotFound)
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM rate_limit_windows WHERE ledger_id::text = $1`, ledgerID); err != nil {
+		api.Error(w, "failed to update rate limit", http.StatusInternalServerError)
+		return
+	}
+	for _, win := range req.Windows {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO rate_limit_windows (ledger_id, name, start_time, end_time, timezone, multiplier)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, ledgerID, win.Name, win.Start, win.End, win.Timezone, win.Multiplier)
+		if err != nil {
+			api.Error(w, "failed to update rate limit", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to update rate limit", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRateLimit(w, r, ledgerID, claims.OrgID)
+}
+
+func (h *LedgerHandler) sessionClaims(r *http.Request) (*auth.Claims, error) {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		return nil, err
+	}
+	return auth.ValidateSession(r.Context(), h.DB, cookie.Value, []byte("jwt-secret"))
+}
+
+func (h *LedgerHandler) writeRateLimit(w http.ResponseWriter, r *http.Request, ledgerID, orgID string) {
+	ctx := r.Context()
+
+	resp := RateLimitResponse{Windows: []RateLimitWindow{}}
+	err := h.DB.QueryRow(ctx, `
+		SELECT l.id, l.rate_limit_per_second::float8, l.rate_limit_burst
+		FROM ledgers l
+		JOIN projects p ON p.id = l.project_id
+		WHERE l.id::text = $1 AND p.organization_id = $2
+	`, ledgerID, orgID).Scan(&resp.LedgerID, &resp.PerSecond, &resp.Burst)
+	if err != nil {
+		api.Error(w, "ledger not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		SELECT name, start_time, end_time, timezone, multiplier::float8
+		FROM rate_limit_windows
+		WHERE ledger_id = $1
+		ORDER BY start_time, name
+	`, resp.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query rate limit windows", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var win RateLimitWindow
+		if err := rows.Scan(&win.Name, &win.Start, &win.End, &win.Timezone, &win.Multiplier); err != nil {
+			api.Error(w, "failed to scan rate limit window", http.StatusInternalServerError)
+			return
+		}
+		resp.Windows = append(resp.Windows, win)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,480 @@
+package dashboard
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/config"
+	"Go_FormanceLegder/internal/mail"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultConsentHours = 24
+	maxConsentHours     = 72
+
+	// An impersonated session never outlives this, whatever the consent's expiry
+	supportSessionTTL = time.Hour
+)
+
+// SupportHandler lets support staff log in as a customer to reproduce a dashboard
+// issue. The customer grants consent by issuing a single-use token and handing it to
+// support; every grant, revocation and login is written to the organization's support
+// audit trail, and the customer and the organization's owners are emailed when support
+// logs in. Revoking the consent ends the support session. Impersonated sessions cannot
+// change credentials, membership or the organization's existence.
+type SupportHandler struct {
+	DB     *pgxpool.Pool
+	Config *config.Config
+	Mailer *mail.Mailer
+}
+
+type GrantConsentRequest struct {
+	Reason         string `json:"reason"`
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"` // defaults to 24, at most 72
+}
+
+type ConsentResponse struct {
+	ID        string `json:"id"`
+	UserEmail string `json:"user_email"`
+	Reason    string `json:"reason"`
+	Token     string `json:"token,omitempty"` // only when granted
+	ExpiresAt string `json:"expires_at"`
+	UsedBy    string `json:"used_by,omitempty"`
+	UsedAt    string `json:"used_at,omitempty"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type SupportAuditEntry struct {
+	ConsentID    string `json:"consent_id"`
+	EventType    string `json:"event_type"`
+	ActorEmail   string `json:"actor_email"`
+	SubjectEmail string `json:"subject_email"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type ImpersonateRequest struct {
+	Token string `json:"token"`
+}
+
+// POST /api/support/consents - Issue a consent token for support to log in as the user
+func (h *SupportHandler) GrantConsent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return
+	}
+
+	var req GrantConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresInHours == 0 {
+		req.ExpiresInHours = defaultConsentHours
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxConsentHours {
+		api.Error(w, fmt.Sprintf("expires_in_hours must be between 1 and %d", maxConsentHours), http.StatusBadRequest)
+		return
+	}
+
+	token, err := generateConsentToken()
+	if err != nil {
+		api.Error(w, "failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	tokenHash, err := auth.ComputeKeyHash(h.Config.APIKeySecret, token)
+	if err != nil {
+		api.Error(w, "failed to hash token", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	consent := ConsentResponse{Reason: req.Reason, Token: token}
+	var expiresAt, createdAt time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO support_consents (organization_id, user_id, token_hash, reason, expires_at)
+		SELECT ou.organization_id, ou.user_id, $3, $4, $5
+		FROM org_users ou
+		WHERE ou.user_id = $1 AND ou.organization_id = $2
+		RETURNING id, expires_at, created_at,
+			(SELECT email FROM users WHERE id = $1)
+	`, claims.UserID, claims.OrgID, tokenHash, req.Reason, time.Now().Add(time.Duration(req.ExpiresInHours)*time.Hour)).
+		Scan(&consent.ID, &expiresAt, &createdAt, &consent.UserEmail)
+	if err != nil {
+		api.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	consent.ExpiresAt = expiresAt.Format(time.RFC3339)
+	consent.CreatedAt = createdAt.Format(time.RFC3339)
+
+	if err := recordSupportAudit(ctx, tx, claims.OrgID, consent.ID, "consent_granted", consent.UserEmail, consent.UserEmail); err != nil {
+		api.Error(w, "failed to record audit event", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(consent)
+}
+
+// GET /api/support/consents - The organization's consents, newest first
+func (h *SupportHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.DB.Query(r.Context(), `
+		SELECT c.id, u.email, c.reason, c.expires_at, COALESCE(c.used_by, ''), c.used_at, c.revoked_at, c.created_at
+		FROM support_consents c JOIN users u ON u.id = c.user_id
+		WHERE c.organization_id = $1
+		ORDER BY c.created_at DESC
+		LIMIT 100
+	`, claims.OrgID)
+	if err != nil {
+		api.Error(w, "failed to list consents", http.StatusInternalServerError)
+		return
+	}
+	consents, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ConsentResponse, error) {
+		var c ConsentResponse
+		var expiresAt, createdAt time.Time
+		var usedAt, revokedAt *time.Time
+		err := row.Scan(&c.ID, &c.UserEmail, &c.Reason, &expiresAt, &c.UsedBy, &usedAt, &revokedAt, &createdAt)
+		c.ExpiresAt = expiresAt.Format(time.RFC3339)
+		c.CreatedAt = createdAt.Format(time.RFC3339)
+		if usedAt != nil {
+			c.UsedAt = usedAt.Format(time.RFC3339)
+		}
+		if revokedAt != nil {
+			c.RevokedAt = revokedAt.Format(time.RFC3339)
+		}
+		return c, err
+	})
+	if err != nil {
+		api.Error(w, "failed to list consents", http.StatusInternalServerError)
+		return
+	}
+	if consents == nil {
+		consents = []ConsentResponse{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"consents": consents})
+}
+
+// POST /api/support/consents/revoke?id= - Revoke a consent (its grantor or an owner); revoking a
+// used consent ends the support session opened with it
+func (h *SupportHandler) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var actorEmail, subjectEmail string
+	err = tx.QueryRow(ctx, `
+		UPDATE support_consents c SET revoked_at = NOW()
+		FROM org_users me JOIN users actor ON actor.id = me.user_id, users subject
+		WHERE c.id::text = $1 AND c.organization_id = $2 AND c.revoked_at IS NULL
+		  AND me.user_id = $3 AND me.organization_id = c.organization_id
+		  AND (c.user_id = $3 OR me.role = 'owner')
+		  AND subject.id = c.user_id
+		RETURNING actor.email, subject.email
+	`, r.URL.Query().Get("id"), claims.OrgID, claims.UserID).Scan(&actorEmail, &subjectEmail)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "consent not found or already revoked", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to revoke consent", http.StatusInternalServerError)
+		return
+	}
+
+	if err := recordSupportAudit(ctx, tx, claims.OrgID, r.URL.Query().Get("id"), "consent_revoked", actorEmail, subjectEmail); err != nil {
+		api.Error(w, "failed to record audit event", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/support/audit - The organization's support access trail, newest first
+func (h *SupportHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.DB.Query(r.Context(), `
+		SELECT consent_id, event_type, actor_email, subject_email, created_at
+		FROM support_audit
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+		LIMIT 200
+	`, claims.OrgID)
+	if err != nil {
+		api.Error(w, "failed to load audit trail", http.StatusInternalServerError)
+		return
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SupportAuditEntry, error) {
+		var e SupportAuditEntry
+		var createdAt time.Time
+		err := row.Scan(&e.ConsentID, &e.EventType, &e.ActorEmail, &e.SubjectEmail, &createdAt)
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		return e, err
+	})
+	if err != nil {
+		api.Error(w, "failed to load audit trail", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []SupportAuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+}
+
+// POST /api/support/impersonate - Redeem a consent token and log in as its grantor (support staff only)
+func (h *SupportHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.session(w, r)
+	if !ok || !notImpersonated(w, claims) {
+		return
+	}
+	var supportEmail string
+	err := h.DB.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, claims.UserID).Scan(&supportEmail)
+	if err != nil || !slices.Contains(h.Config.SupportEmails, supportEmail) {
+		api.Error(w, "only support staff can impersonate users", http.StatusForbidden)
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		api.Error(w, "token required", http.StatusBadRequest)
+		return
+	}
+	tokenHash, err := auth.ComputeKeyHash(h.Config.APIKeySecret, req.Token)
+	if err != nil {
+		api.Error(w, "failed to hash token", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Redeeming marks the consent used, so a token logs in once
+	var consentID, userID, orgID, userEmail string
+	var expiresAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE support_consents c SET used_by = $2, used_at = NOW()
+		FROM users u
+		WHERE c.token_hash = $1 AND u.id = c.user_id
+		  AND c.used_at IS NULL AND c.revoked_at IS NULL AND c.expires_at > NOW()
+		RETURNING c.id, c.user_id, c.organization_id, u.email, c.expires_at
+	`, tokenHash, supportEmail).Scan(&consentID, &userID, &orgID, &userEmail, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "invalid, used, revoked or expired consent token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to redeem consent", http.StatusInternalServerError)
+		return
+	}
+
+	if err := recordSupportAudit(ctx, tx, orgID, consentID, "impersonation_started", supportEmail, userEmail); err != nil {
+		api.Error(w, "failed to record audit event", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("support: %s logged in as %s (organization %s, consent %s)", supportEmail, userEmail, orgID, consentID)
+
+	token, err := auth.GenerateImpersonationJWT(userID, orgID, claims.UserID, consentID, supportSessionTTL, h.Config.JWTSecret)
+	if err != nil {
+		api.Error(w, "failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	h.setSession(w, token, supportSessionTTL)
+
+	h.notifyImpersonation(ctx, orgID, userEmail, supportEmail)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"user_id":         userID,
+		"organization_id": orgID,
+		"expires_at":      time.Now().Add(supportSessionTTL).UTC().Format(time.RFC3339),
+	})
+}
+
+// POST /api/support/impersonate/end - End an impersonated session
+func (h *SupportHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		api.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	if claims.ImpersonatorID == "" {
+		api.Error(w, "not an impersonated session", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.DB.Exec(ctx, `
+		INSERT INTO support_audit (organization_id, consent_id, event_type, actor_email, subject_email)
+		SELECT $1, $2, 'impersonation_ended',
+			COALESCE((SELECT email FROM users WHERE id = $3), $3::text),
+			COALESCE((SELECT email FROM users WHERE id = $4), $4::text)
+	`, claims.OrgID, claims.ConsentID, claims.ImpersonatorID, claims.UserID)
+	if err != nil {
+		api.Error(w, "failed to record audit event", http.StatusInternalServerError)
+		return
+	}
+
+	h.setSession(w, "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notifyImpersonation emails the impersonated user and the organization's owners.
+func (h *SupportHandler) notifyImpersonation(ctx context.Context, orgID, userEmail, supportEmail string) {
+	rows, err := h.DB.Query(ctx, `
+		SELECT u.email FROM org_users ou JOIN users u ON u.id = ou.user_id
+		WHERE ou.organization_id = $1 AND ou.role = 'owner'
+	`, orgID)
+	var recipients []string
+	if err == nil {
+		recipients, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if !slices.Contains(recipients, userEmail) {
+		recipients = append(recipients, userEmail)
+	}
+	if err == nil {
+		err = h.Mailer.Send(recipients, "Support logged in as "+userEmail, fmt.Sprintf(
+			"%s from support logged in to the dashboard as %s, with the consent %s granted. The session lasts "+
+				"at most %s, and ends when the consent is revoked; the organization's support audit trail lists "+
+				"every such login.",
+			supportEmail, userEmail, userEmail, supportSessionTTL))
+	}
+	if err != nil {
+		log.Printf("support: notify %s: %v", orgID, err)
+	}
+}
+
+func (h *SupportHandler) session(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	claims, err := auth.ValidateSession(r.Context(), h.DB, cookie.Value, h.Config.JWTSecret)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
+}
+
+func (h *SupportHandler) setSession(w http.ResponseWriter, token string, ttl time.Duration) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	})
+}
+
+func recordSupportAudit(ctx context.Context, tx pgx.Tx, orgID, consentID, eventType, actorEmail, subjectEmail string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO support_audit (organization_id, consent_id, event_type, actor_email, subject_email)
+		VALUES ($1, $2, $3, $4, $5)
+	`, orgID, consentID, eventType, actorEmail, subjectEmail)
+	return err
+}
+
+// notImpersonated rejects actions support may not take on a customer's behalf.
+func notImpersonated(w http.ResponseWriter, claims *auth.Claims) bool {
+	if claims.ImpersonatorID != "" {
+		api.Error(w, "not permitted while impersonating a user", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func generateConsentToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	encoded := strings.TrimRight(base32.StdEncoding.EncodeToString(bytes), "=")
+	return "sup_" + strings.ToLower(encoded), nil
+}
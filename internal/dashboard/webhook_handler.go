@@ -1,29 +1,176 @@
 package dashboard
 
 import (
+	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"slices"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
 )
 
 type WebhookHandler struct {
-	DB *pgxpool.Pool
+	DB          *pgxpool.Pool
+	RiverClient *river.Client[pgx.Tx] // enqueues manual delivery retries
 }
 
 type WebhookEndpointResponse struct {
-	ID        string `json:"id"`
-	URL       string `json:"url"`
-	IsActive  bool   `json:"is_active"`
-	CreatedAt string `json:"created_at"`
+	ID                  string             `json:"id"`
+	URL                 string             `json:"url"`
+	IsActive            bool               `json:"is_active"`
+	ConsecutiveFailures int                `json:"consecutive_failures"`
+	DisabledAt          string             `json:"disabled_at,omitempty"` // set when the worker disabled it
+	DisabledReason      string             `json:"disabled_reason,omitempty"`
+	RetryPolicy         WebhookRetryPolicy `json:"retry_policy"`
+	RateLimit           WebhookRateLimit   `json:"rate_limit"`
+	Batch               WebhookBatch       `json:"batch"`
+	Headers             []string           `json:"headers"` // names only; values are write-only
+	ClientCertificate   *CertificateInfo   `json:"client_certificate,omitempty"`
+	Filter              string             `json:"filter,omitempty"`
+	CreatedAt           string             `json:"created_at"`
+}
+
+// CertificateInfo describes an endpoint's client certificate; its key is never returned.
+type CertificateInfo struct {
+	Subject  string `json:"subject"`
+	NotAfter string `json:"not_after"`
+}
+
+// WebhookClientCertificate is a PEM certificate chain and its private key, presented to
+// the endpoint for mutual TLS. An empty one removes the endpoint's certificate.
+type WebhookClientCertificate struct {
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"private_key"`
+}
+
+func (c *WebhookClientCertificate) validate() error {
+	if c.Certificate == "" && c.PrivateKey == "" {
+		return nil
+	}
+	_, err := webhook.ParseClientCertificate(c.Certificate, c.PrivateKey)
+	return err
+}
+
+// endpointTransport fills in the headers and certificate of an endpoint's response.
+func (e *WebhookEndpointResponse) endpointTransport(headers map[string]string, certificate string) {
+	e.Headers = slices.Sorted(maps.Keys(headers))
+	if certificate == "" {
+		return
+	}
+	e.ClientCertificate = &CertificateInfo{}
+	if block, _ := pem.Decode([]byte(certificate)); block != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			e.ClientCertificate.Subject = cert.Subject.String()
+			e.ClientCertificate.NotAfter = cert.NotAfter.UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+// WebhookRetryPolicy overrides the worker's retry policy for one endpoint. Fields left
+// out keep the worker's default.
+type WebhookRetryPolicy struct {
+	MaxAttempts *int    `json:"max_attempts,omitempty"`
+	Backoff     *string `json:"backoff,omitempty"` // exponential, linear or fixed
+	BaseSeconds *int    `json:"base_seconds,omitempty"`
+}
+
+const (
+	maxRetryAttempts    = 100
+	maxRetryBaseSeconds = 24 * 60 * 60
+)
+
+func (p *WebhookRetryPolicy) validate() error {
+	if p.MaxAttempts != nil && (*p.MaxAttempts < 1 || *p.MaxAttempts > maxRetryAttempts) {
+		return fmt.Errorf("retry_policy.max_attempts must be between 1 and %d", maxRetryAttempts)
+	}
+	if p.Backoff != nil && !slices.Contains(webhook.BackoffStrategies, *p.Backoff) {
+		return fmt.Errorf("retry_policy.backoff must be one of %v", webhook.BackoffStrategies)
+	}
+	if p.BaseSeconds != nil && (*p.BaseSeconds < 1 || *p.BaseSeconds > maxRetryBaseSeconds) {
+		return fmt.Errorf("retry_policy.base_seconds must be between 1 and %d", maxRetryBaseSeconds)
+	}
+	return nil
+}
+
+// WebhookRateLimit overrides the worker's delivery limits for one endpoint: how many
+// deliveries may be in flight at once and how many start per second on average. Fields
+// left out keep the worker's default.
+type WebhookRateLimit struct {
+	MaxConcurrency *int `json:"max_concurrency,omitempty"`
+	PerSecond      *int `json:"per_second,omitempty"`
+}
+
+const (
+	maxEndpointConcurrency = 50
+	maxEndpointPerSecond   = 1000
+)
+
+func (l *WebhookRateLimit) validate() error {
+	if l.MaxConcurrency != nil && (*l.MaxConcurrency < 1 || *l.MaxConcurrency > maxEndpointConcurrency) {
+		return fmt.Errorf("rate_limit.max_concurrency must be between 1 and %d", maxEndpointConcurrency)
+	}
+	if l.PerSecond != nil && (*l.PerSecond < 1 || *l.PerSecond > maxEndpointPerSecond) {
+		return fmt.Errorf("rate_limit.per_second must be between 1 and %d", maxEndpointPerSecond)
+	}
+	return nil
+}
+
+// WebhookBatch puts an endpoint in batch mode: the events arriving within WindowSeconds
+// of the first are delivered together, as a JSON array of envelopes under one signature,
+// up to MaxEvents (100 by default) per request. Without a window each event is delivered
+// on its own.
+type WebhookBatch struct {
+	WindowSeconds *int `json:"window_seconds,omitempty"`
+	MaxEvents     *int `json:"max_events,omitempty"`
+}
+
+const (
+	maxBatchWindowSeconds = 300
+	maxBatchEvents        = 1000
+)
+
+func (b *WebhookBatch) validate() error {
+	if b.WindowSeconds != nil && (*b.WindowSeconds < 1 || *b.WindowSeconds > maxBatchWindowSeconds) {
+		return fmt.Errorf("batch.window_seconds must be between 1 and %d", maxBatchWindowSeconds)
+	}
+	if b.MaxEvents != nil && (*b.MaxEvents < 1 || *b.MaxEvents > maxBatchEvents) {
+		return fmt.Errorf("batch.max_events must be between 1 and %d", maxBatchEvents)
+	}
+	if b.MaxEvents != nil && b.WindowSeconds == nil {
+		return errors.New("batch.max_events needs batch.window_seconds")
+	}
+	return nil
 }
 
 type CreateWebhookEndpointRequest struct {
-	URL string `json:"url"`
+	URL         string              `json:"url"`
+	RetryPolicy *WebhookRetryPolicy `json:"retry_policy,omitempty"`
+	RateLimit   *WebhookRateLimit   `json:"rate_limit,omitempty"`
+	Batch       *WebhookBatch       `json:"batch,omitempty"`
+
+	Headers           map[string]string         `json:"headers,omitempty"`
+	ClientCertificate *WebhookClientCertificate `json:"client_certificate,omitempty"`
+
+	// Filter is a CEL expression over the event payload, e.g. amount > 1000 &&
+	// currency == "USD"; only events satisfying it are delivered
+	Filter string `json:"filter,omitempty"`
 }
 
 type CreateWebhookEndpointResponse struct {
@@ -32,14 +179,55 @@ type CreateWebhookEndpointResponse struct {
 	Secret string `json:"secret"`
 }
 
+// UpdateWebhookEndpointRequest changes the fields that are set. A retry policy, rate
+// limit, batch mode or set of headers replaces the endpoint's as a whole, so {} restores
+// the defaults, and an empty client certificate or filter removes it.
+type UpdateWebhookEndpointRequest struct {
+	URL         *string             `json:"url,omitempty"`
+	IsActive    *bool               `json:"is_active,omitempty"`
+	RetryPolicy *WebhookRetryPolicy `json:"retry_policy,omitempty"`
+	RateLimit   *WebhookRateLimit   `json:"rate_limit,omitempty"`
+	Batch       *WebhookBatch       `json:"batch,omitempty"`
+
+	Headers           map[string]string         `json:"headers,omitempty"`
+	ClientCertificate *WebhookClientCertificate `json:"client_certificate,omitempty"`
+	Filter            *string                   `json:"filter,omitempty"`
+}
+
+type RotateWebhookSecretRequest struct {
+	GracePeriodHours int `json:"grace_period_hours,omitempty"` // defaults to 24, at most 168
+}
+
+type RotateWebhookSecretResponse struct {
+	ID                      string `json:"id"`
+	URL                     string `json:"url"`
+	Secret                  string `json:"secret"`
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at"`
+}
+
+const (
+	defaultSecretGraceHours = 24
+	maxSecretGraceHours     = 7 * 24
+)
+
+type TestWebhookEndpointResponse struct {
+	EventID      string `json:"event_id"` // of the synthetic event, which is not stored
+	Status       string `json:"status"`
+	HTTPStatus   int    `json:"http_status"`
+	LatencyMS    int64  `json:"latency_ms"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
 type WebhookDeliveryResponse struct {
 	ID                string `json:"id"`
+	DeliveryID        string `json:"delivery_id"` // same for every attempt of the event to the endpoint
 	EventID           string `json:"event_id"`
 	WebhookEndpointID string `json:"webhook_endpoint_id"`
 	EndpointURL       string `json:"endpoint_url"`
 	Status            string `json:"status"`
 	Attempt           int    `json:"attempt"`
 	LastAttemptAt     string `json:"last_attempt_at"`
+	NextAttemptAt     string `json:"next_attempt_at,omitempty"` // when a failed attempt is retried
 	HTTPStatus        int    `json:"http_status"`
 	ErrorMessage      string `json:"error_message,omitempty"`
 }
@@ -50,18 +238,21 @@ func (h *WebhookHandler) ListWebhookEndpoints(w http.ResponseWriter, r *http.Req
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	rows, err := h.DB.Query(ctx, `
-		SELECT id, url, is_active, created_at
+		SELECT id, url, is_active, consecutive_failures, disabled_at, COALESCE(disabled_reason, ''),
+			retry_max_attempts, retry_backoff, retry_backoff_base_seconds,
+			rate_limit_max_concurrency, rate_limit_per_second, batch_window_seconds, batch_max_events,
+			custom_headers, COALESCE(client_certificate, ''), COALESCE(filter, ''), created_at
 		FROM webhook_endpoints
 		WHERE ledger_id = $1
 		ORDER BY created_at DESC
 	`, principal.LedgerID)
 	if err != nil {
-		http.Error(w, "failed to query webhook endpoints", http.StatusInternalServerError)
+		api.Error(w, "failed to query webhook endpoints", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -69,11 +260,21 @@ func (h *WebhookHandler) ListWebhookEndpoints(w http.ResponseWriter, r *http.Req
 	endpoints := []WebhookEndpointResponse{}
 	for rows.Next() {
 		var endpoint WebhookEndpointResponse
-		err = rows.Scan(&endpoint.ID, &endpoint.URL, &endpoint.IsActive, &endpoint.CreatedAt)
+		var disabledAt *time.Time
+		var headers map[string]string
+		var certificate string
+		err = rows.Scan(&endpoint.ID, &endpoint.URL, &endpoint.IsActive, &endpoint.ConsecutiveFailures, &disabledAt,
+			&endpoint.DisabledReason, &endpoint.RetryPolicy.MaxAttempts, &endpoint.RetryPolicy.Backoff,
+			&endpoint.RetryPolicy.BaseSeconds, &endpoint.RateLimit.MaxConcurrency, &endpoint.RateLimit.PerSecond,
+			&endpoint.Batch.WindowSeconds, &endpoint.Batch.MaxEvents, &headers, &certificate, &endpoint.Filter, &endpoint.CreatedAt)
 		if err != nil {
-			http.Error(w, "failed to scan webhook endpoint", http.StatusInternalServerError)
+			api.Error(w, "failed to scan webhook endpoint", http.StatusInternalServerError)
 			return
 		}
+		endpoint.endpointTransport(headers, certificate)
+		if disabledAt != nil {
+			endpoint.DisabledAt = disabledAt.Format(time.RFC3339)
+		}
 		endpoints = append(endpoints, endpoint)
 	}
 
@@ -87,32 +288,77 @@ func (h *WebhookHandler) CreateWebhookEndpoint(w http.ResponseWriter, r *http.Re
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req CreateWebhookEndpointRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.RetryPolicy == nil {
+		req.RetryPolicy = &WebhookRetryPolicy{}
+	}
+	if err := req.RetryPolicy.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.RateLimit == nil {
+		req.RateLimit = &WebhookRateLimit{}
+	}
+	if err := req.RateLimit.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Batch == nil {
+		req.Batch = &WebhookBatch{}
+	}
+	if err := req.Batch.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Headers == nil {
+		req.Headers = map[string]string{}
+	}
+	if err := webhook.ValidateHeaders(req.Headers); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.ClientCertificate == nil {
+		req.ClientCertificate = &WebhookClientCertificate{}
+	}
+	if err := req.ClientCertificate.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := webhook.ValidateFilter(req.Filter); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
 		return
 	}
 
 	// Generate webhook secret
 	secret, err := generateWebhookSecret()
 	if err != nil {
-		http.Error(w, "failed to generate secret", http.StatusInternalServerError)
+		api.Error(w, "failed to generate secret", http.StatusInternalServerError)
 		return
 	}
 
 	// Create endpoint
 	var endpointID string
 	err = h.DB.QueryRow(ctx, `
-		INSERT INTO webhook_endpoints (ledger_id, url, secret, is_active)
-		VALUES ($1, $2, $3, true)
+		INSERT INTO webhook_endpoints (ledger_id, url, secret, is_active,
+			retry_max_attempts, retry_backoff, retry_backoff_base_seconds,
+			rate_limit_max_concurrency, rate_limit_per_second, custom_headers, client_certificate, client_key,
+			batch_window_seconds, batch_max_events, filter)
+		VALUES ($1, $2, $3, true, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12, $13, NULLIF($14, ''))
 		RETURNING id
-	`, principal.LedgerID, req.URL, secret).Scan(&endpointID)
+	`, principal.LedgerID, req.URL, secret, req.RetryPolicy.MaxAttempts, req.RetryPolicy.Backoff,
+		req.RetryPolicy.BaseSeconds, req.RateLimit.MaxConcurrency, req.RateLimit.PerSecond, req.Headers,
+		req.ClientCertificate.Certificate, req.ClientCertificate.PrivateKey, req.Batch.WindowSeconds,
+		req.Batch.MaxEvents, req.Filter).Scan(&endpointID)
 	if err != nil {
-		http.Error(w, "failed to create webhook endpoint", http.StatusInternalServerError)
+		api.Error(w, "failed to create webhook endpoint", http.StatusInternalServerError)
 		return
 	}
 
@@ -127,13 +373,281 @@ func (h *WebhookHandler) CreateWebhookEndpoint(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(resp)
 }
 
+// PATCH /v1/webhook-endpoints/{id} - Change an endpoint's URL, retry policy, rate limit, batch mode,
+// headers, client certificate or filter, or pause and resume it
+//
+// Deliveries already queued go to the new URL; those due while the endpoint is
+// inactive are skipped. Setting is_active, e.g. to re-enable an endpoint the worker
+// disabled after repeated failures, starts its failure count over.
+func (h *WebhookHandler) UpdateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateWebhookEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.URL != nil && !validWebhookURL(*req.URL) {
+		api.Error(w, "url must be an absolute http(s) URL", http.StatusBadRequest)
+		return
+	}
+	retry := req.RetryPolicy
+	if retry == nil {
+		retry = &WebhookRetryPolicy{}
+	} else if err := retry.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	limit := req.RateLimit
+	if limit == nil {
+		limit = &WebhookRateLimit{}
+	} else if err := limit.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	batch := req.Batch
+	if batch == nil {
+		batch = &WebhookBatch{}
+	} else if err := batch.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := webhook.ValidateHeaders(req.Headers); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	certificate := req.ClientCertificate
+	if certificate == nil {
+		certificate = &WebhookClientCertificate{}
+	} else if err := certificate.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Filter != nil {
+		if err := webhook.ValidateFilter(*req.Filter); err != nil {
+			api.WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var endpoint WebhookEndpointResponse
+	var createdAt time.Time
+	var disabledAt *time.Time
+	var headers map[string]string
+	var clientCertificate string
+	err = h.DB.QueryRow(ctx, `
+		UPDATE webhook_endpoints
+		SET url = COALESCE($3, url), is_active = COALESCE($4, is_active),
+			consecutive_failures = CASE WHEN $4::bool IS NULL THEN consecutive_failures ELSE 0 END,
+			disabled_at = CASE WHEN $4::bool IS NULL THEN disabled_at END,
+			disabled_reason = CASE WHEN $4::bool IS NULL THEN disabled_reason END,
+			retry_max_attempts = CASE WHEN $5::bool THEN $6::int ELSE retry_max_attempts END,
+			retry_backoff = CASE WHEN $5::bool THEN $7::text ELSE retry_backoff END,
+			retry_backoff_base_seconds = CASE WHEN $5::bool THEN $8::int ELSE retry_backoff_base_seconds END,
+			rate_limit_max_concurrency = CASE WHEN $9::bool THEN $10::int ELSE rate_limit_max_concurrency END,
+			rate_limit_per_second = CASE WHEN $9::bool THEN $11::int ELSE rate_limit_per_second END,
+			custom_headers = COALESCE($12::jsonb, custom_headers),
+			client_certificate = CASE WHEN $13::bool THEN NULLIF($14::text, '') ELSE client_certificate END,
+			client_key = CASE WHEN $13::bool THEN NULLIF($15::text, '') ELSE client_key END,
+			batch_window_seconds = CASE WHEN $16::bool THEN $17::int ELSE batch_window_seconds END,
+			batch_max_events = CASE WHEN $16::bool THEN $18::int ELSE batch_max_events END,
+			filter = CASE WHEN $19::text IS NULL THEN filter ELSE NULLIF($19, '') END
+		WHERE id::text = $1 AND ledger_id = $2
+		RETURNING id, url, is_active, consecutive_failures, disabled_at, COALESCE(disabled_reason, ''),
+			retry_max_attempts, retry_backoff, retry_backoff_base_seconds,
+			rate_limit_max_concurrency, rate_limit_per_second, batch_window_seconds, batch_max_events,
+			custom_headers, COALESCE(client_certificate, ''), COALESCE(filter, ''), created_at
+	`, r.PathValue("id"), principal.LedgerID, req.URL, req.IsActive, req.RetryPolicy != nil,
+		retry.MaxAttempts, retry.Backoff, retry.BaseSeconds, req.RateLimit != nil, limit.MaxConcurrency,
+		limit.PerSecond, req.Headers, req.ClientCertificate != nil, certificate.Certificate,
+		certificate.PrivateKey, req.Batch != nil, batch.WindowSeconds, batch.MaxEvents, req.Filter).Scan(&endpoint.ID,
+		&endpoint.URL, &endpoint.IsActive, &endpoint.ConsecutiveFailures, &disabledAt, &endpoint.DisabledReason,
+		&endpoint.RetryPolicy.MaxAttempts, &endpoint.RetryPolicy.Backoff, &endpoint.RetryPolicy.BaseSeconds,
+		&endpoint.RateLimit.MaxConcurrency, &endpoint.RateLimit.PerSecond, &endpoint.Batch.WindowSeconds,
+		&endpoint.Batch.MaxEvents, &headers, &clientCertificate, &endpoint.Filter, &createdAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "webhook endpoint not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to update webhook endpoint", http.StatusInternalServerError)
+		return
+	}
+	endpoint.CreatedAt = createdAt.Format(time.RFC3339)
+	endpoint.endpointTransport(headers, clientCertificate)
+	if disabledAt != nil {
+		endpoint.DisabledAt = disabledAt.Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoint)
+}
+
+// DELETE /v1/webhook-endpoints/{id} - Delete an endpoint along with its delivery history
+//
+// Deliveries already queued for it are dropped.
+func (h *WebhookHandler) DeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tag, err := h.DB.Exec(ctx, `
+		DELETE FROM webhook_endpoints WHERE id::text = $1 AND ledger_id = $2
+	`, r.PathValue("id"), principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to delete webhook endpoint", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "webhook endpoint not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /v1/webhook-endpoints/{id}/rotate-secret - Issue a new signing secret
+//
+// The old secret stays valid for the grace period: until it ends, deliveries are signed
+// under both, the new secret first and the old one second, so the receiver can switch
+// secrets at any point in the window. Rotating again during a
+// window ends it, keeping only the secret being replaced.
+func (h *WebhookHandler) RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RotateWebhookSecretRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.GracePeriodHours == 0 {
+		req.GracePeriodHours = defaultSecretGraceHours
+	}
+	if req.GracePeriodHours < 0 || req.GracePeriodHours > maxSecretGraceHours {
+		api.Error(w, fmt.Sprintf("grace_period_hours must be between 1 and %d", maxSecretGraceHours), http.StatusBadRequest)
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		api.Error(w, "failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+
+	resp := RotateWebhookSecretResponse{Secret: secret}
+	var expiresAt time.Time
+	err = h.DB.QueryRow(ctx, `
+		UPDATE webhook_endpoints
+		SET previous_secret = secret, previous_secret_expires_at = $4, secret = $3
+		WHERE id::text = $1 AND ledger_id = $2
+		RETURNING id, url, previous_secret_expires_at
+	`, r.PathValue("id"), principal.LedgerID, secret, time.Now().Add(time.Duration(req.GracePeriodHours)*time.Hour)).
+		Scan(&resp.ID, &resp.URL, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "webhook endpoint not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to rotate webhook secret", http.StatusInternalServerError)
+		return
+	}
+	resp.PreviousSecretExpiresAt = expiresAt.Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// POST /v1/webhook-endpoints/{id}/test - Send a signed WebhookTest event to an endpoint
+//
+// The request is signed and sent like a delivery, inactive endpoints included, but is
+// neither recorded nor retried; the receiver's response code and latency are returned.
+// The X-Ledger-Test header marks it.
+func (h *WebhookHandler) TestWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var ep webhook.WebhookEndpoint
+	err = h.DB.QueryRow(ctx, `
+		SELECT id, url, secret,
+			CASE WHEN previous_secret_expires_at > NOW() THEN COALESCE(previous_secret, '') ELSE '' END,
+			custom_headers, COALESCE(client_certificate, ''), COALESCE(client_key, '')
+		FROM webhook_endpoints
+		WHERE id::text = $1 AND ledger_id = $2
+	`, r.PathValue("id"), principal.LedgerID).Scan(&ep.ID, &ep.URL, &ep.Secret, &ep.PreviousSecret, &ep.Headers,
+		&ep.ClientCertificate, &ep.ClientKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "webhook endpoint not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to query webhook endpoint", http.StatusInternalServerError)
+		return
+	}
+
+	sentAt := time.Now().UTC()
+	payload, err := events.Marshal("WebhookTest", &events.WebhookTest{EndpointID: ep.ID, Test: true, SentAt: sentAt})
+	if err != nil {
+		api.Error(w, "failed to build test event", http.StatusInternalServerError)
+		return
+	}
+	resp := TestWebhookEndpointResponse{EventID: uuid.NewString()}
+	deliveryID := webhook.DeliveryID(resp.EventID, ep.ID)
+	body, err := webhook.Wrap(deliveryID, resp.EventID, "WebhookTest", principal.LedgerID, sentAt, payload)
+	if err != nil {
+		api.Error(w, "failed to build test event", http.StatusInternalServerError)
+		return
+	}
+
+	client, err := webhook.ClientFor(nil, ep)
+	if err != nil {
+		api.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	header := ep.Header()
+	header.Set("X-Ledger-Event-Id", resp.EventID)
+	header.Set("X-Ledger-Delivery-Id", deliveryID)
+	header.Set("X-Ledger-Event-Fingerprint", webhook.Fingerprint(payload))
+	header.Set("X-Ledger-Test", "true")
+	start := time.Now()
+	outcome := webhook.Deliver(ctx, client, ep.URL, ep.Secrets(), body, header)
+	resp.LatencyMS = time.Since(start).Milliseconds()
+	resp.Status, resp.HTTPStatus, resp.ErrorMessage = outcome.Status, outcome.HTTPStatus, outcome.Error
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // GET /v1/webhook-deliveries
 func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -155,6 +669,7 @@ func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Re
 			wd.status, 
 			wd.attempt, 
 			wd.last_attempt_at, 
+			wd.next_attempt_at,
 			wd.http_status, 
 			wd.error_message
 		FROM webhook_deliveries wd
@@ -164,7 +679,7 @@ func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Re
 		LIMIT $2
 	`, principal.LedgerID, limit)
 	if err != nil {
-		http.Error(w, "failed to query webhook deliveries", http.StatusInternalServerError)
+		api.Error(w, "failed to query webhook deliveries", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -173,6 +688,7 @@ func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Re
 	for rows.Next() {
 		var delivery WebhookDeliveryResponse
 		var errorMessage *string
+		var nextAttemptAt *time.Time
 		err = rows.Scan(
 			&delivery.ID,
 			&delivery.EventID,
@@ -181,16 +697,21 @@ func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Re
 			&delivery.Status,
 			&delivery.Attempt,
 			&delivery.LastAttemptAt,
+			&nextAttemptAt,
 			&delivery.HTTPStatus,
 			&errorMessage,
 		)
 		if err != nil {
-			http.Error(w, "failed to scan webhook delivery", http.StatusInternalServerError)
+			api.Error(w, "failed to scan webhook delivery", http.StatusInternalServerError)
 			return
 		}
 		if errorMessage != nil {
 			delivery.ErrorMessage = *errorMessage
 		}
+		if nextAttemptAt != nil {
+			delivery.NextAttemptAt = nextAttemptAt.Format(time.RFC3339)
+		}
+		delivery.DeliveryID = webhook.DeliveryID(delivery.EventID, delivery.WebhookEndpointID)
 		deliveries = append(deliveries, delivery)
 	}
 
@@ -198,6 +719,443 @@ func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(deliveries)
 }
 
+type RetryWebhookDeliveriesRequest struct {
+	EventIDs   []string `json:"event_ids,omitempty"`
+	From       string   `json:"from,omitempty"` // RFC 3339, over the failed attempt's time
+	To         string   `json:"to,omitempty"`
+	EndpointID string   `json:"endpoint_id,omitempty"`
+}
+
+type RetriedDelivery struct {
+	EventID           string `json:"event_id"`
+	WebhookEndpointID string `json:"webhook_endpoint_id"`
+	DeliveryID        string `json:"delivery_id"`
+	// Duplicate is set when a retry of the delivery was already queued
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+type RetryWebhookDeliveriesResponse struct {
+	Retries []RetriedDelivery `json:"retries"`
+	// More is set when further failed deliveries match; call again to queue them
+	More bool `json:"more,omitempty"`
+}
+
+// A bulk retry queues at most this many deliveries per call.
+const maxDeliveryRetries = 1000
+
+var unfinishedJobStates = []rivertype.JobState{
+	rivertype.JobStateAvailable, rivertype.JobStatePending, rivertype.JobStateRetryable,
+	rivertype.JobStateRunning, rivertype.JobStateScheduled,
+}
+
+// failedDeliverySQL selects the (event, endpoint) pairs of ledger $1 whose deliveries
+// failed and never succeeded since, to active endpoints. wd is the failed attempt.
+const failedDeliverySQL = `
+	SELECT DISTINCT wd.event_id::text, wd.webhook_endpoint_id::text
+	FROM webhook_deliveries wd
+	JOIN webhook_endpoints we ON we.id = wd.webhook_endpoint_id
+	WHERE we.ledger_id = $1 AND we.is_active AND wd.status <> 'success'
+	  AND NOT EXISTS (
+		SELECT 1 FROM webhook_deliveries s
+		WHERE s.event_id = wd.event_id AND s.webhook_endpoint_id = wd.webhook_endpoint_id AND s.status = 'success'
+	  )
+	  AND EXISTS (SELECT 1 FROM events e WHERE e.id = wd.event_id AND e.ledger_id = $1)`
+
+// POST /v1/webhook-deliveries/{id}/retry - Deliver a failed delivery's event again
+//
+// A fresh job sends the event to the delivery's endpoint only, with the usual retries,
+// so a delivery given up on after a 4xx can be sent once the receiver is fixed.
+func (h *WebhookHandler) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var status string
+	var active bool
+	var delivery RetriedDelivery
+	err = h.DB.QueryRow(ctx, `
+		SELECT wd.event_id::text, wd.webhook_endpoint_id::text, wd.status, we.is_active
+		FROM webhook_deliveries wd
+		JOIN webhook_endpoints we ON we.id = wd.webhook_endpoint_id
+		WHERE wd.id::text = $1 AND we.ledger_id = $2
+	`, r.PathValue("id"), principal.LedgerID).Scan(&delivery.EventID, &delivery.WebhookEndpointID, &status, &active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "webhook delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to query webhook delivery", http.StatusInternalServerError)
+		return
+	}
+	if status == "success" {
+		api.Error(w, "webhook delivery succeeded", http.StatusConflict)
+		return
+	}
+	if !active {
+		api.Error(w, "webhook endpoint is inactive", http.StatusConflict)
+		return
+	}
+
+	var failed bool
+	err = h.DB.QueryRow(ctx, `SELECT EXISTS (`+failedDeliverySQL+` AND wd.event_id::text = $2 AND wd.webhook_endpoint_id::text = $3)`,
+		principal.LedgerID, delivery.EventID, delivery.WebhookEndpointID).Scan(&failed)
+	if err != nil {
+		api.Error(w, "failed to query webhook delivery", http.StatusInternalServerError)
+		return
+	}
+	if !failed {
+		api.Error(w, "event was delivered to the endpoint since, or is archived", http.StatusConflict)
+		return
+	}
+
+	retries, err := h.enqueueRetries(ctx, principal.LedgerID, []RetriedDelivery{delivery})
+	if err != nil {
+		api.Error(w, "failed to enqueue retry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(retries[0])
+}
+
+// POST /v1/webhook-deliveries/retry - Deliver failed events again in bulk
+//
+// Retries every failed delivery matching the given event IDs and/or time range (and
+// endpoint, if given) whose event has not reached the endpoint since.
+func (h *WebhookHandler) RetryWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RetryWebhookDeliveriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if len(req.EventIDs) == 0 && req.From == "" {
+		api.Error(w, "event_ids or from is required", http.StatusBadRequest)
+		return
+	}
+
+	query := failedDeliverySQL
+	args := []interface{}{principal.LedgerID}
+	if len(req.EventIDs) > 0 {
+		args = append(args, req.EventIDs)
+		query += fmt.Sprintf(" AND wd.event_id::text = ANY($%d)", len(args))
+	}
+	for _, bound := range []struct{ value, op, name string }{{req.From, ">=", "from"}, {req.To, "<", "to"}} {
+		if bound.value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			api.Error(w, bound.name+" must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		args = append(args, at)
+		query += fmt.Sprintf(" AND wd.last_attempt_at %s $%d", bound.op, len(args))
+	}
+	if req.EndpointID != "" {
+		args = append(args, req.EndpointID)
+		query += fmt.Sprintf(" AND wd.webhook_endpoint_id::text = $%d", len(args))
+	}
+	args = append(args, maxDeliveryRetries+1)
+	query += fmt.Sprintf(" ORDER BY 1, 2 LIMIT $%d", len(args))
+
+	rows, err := h.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	var pending []RetriedDelivery
+	for rows.Next() {
+		var d RetriedDelivery
+		if err := rows.Scan(&d.EventID, &d.WebhookEndpointID); err != nil {
+			rows.Close()
+			api.Error(w, "failed to scan webhook delivery", http.StatusInternalServerError)
+			return
+		}
+		pending = append(pending, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		api.Error(w, "failed to query webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	resp := RetryWebhookDeliveriesResponse{Retries: []RetriedDelivery{}}
+	if len(pending) > maxDeliveryRetries {
+		pending, resp.More = pending[:maxDeliveryRetries], true
+	}
+	if len(pending) > 0 {
+		if resp.Retries, err = h.enqueueRetries(ctx, principal.LedgerID, pending); err != nil {
+			api.Error(w, "failed to enqueue retries", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// enqueueRetries queues a delivery job per (event, endpoint). Jobs are unique by their
+// args while unfinished, so retrying a delivery whose retry is still queued adds nothing.
+func (h *WebhookHandler) enqueueRetries(ctx context.Context, ledgerID string, retries []RetriedDelivery) ([]RetriedDelivery, error) {
+	params := make([]river.InsertManyParams, len(retries))
+	for i, d := range retries {
+		params[i] = river.InsertManyParams{
+			Args:       webhook.WebhookArgs{EventID: d.EventID, LedgerID: ledgerID, EndpointIDs: []string{d.WebhookEndpointID}, Manual: true},
+			InsertOpts: &river.InsertOpts{UniqueOpts: river.UniqueOpts{ByArgs: true, ByState: unfinishedJobStates}},
+		}
+	}
+	results, err := h.RiverClient.InsertMany(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range retries {
+		retries[i].DeliveryID = webhook.DeliveryID(retries[i].EventID, retries[i].WebhookEndpointID)
+		retries[i].Duplicate = results[i].UniqueSkippedAsDuplicate
+	}
+	return retries, nil
+}
+
+type EventDeliveriesResponse struct {
+	EventID          string                    `json:"event_id"`
+	EventFingerprint string                    `json:"event_fingerprint"`
+	Deliveries       []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+// GET /v1/events/{id}/deliveries - Delivery attempts of one event, oldest first
+func (h *WebhookHandler) ListEventDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp := EventDeliveriesResponse{EventID: r.PathValue("id"), Deliveries: []WebhookDeliveryResponse{}}
+	var payload []byte
+	err = h.DB.QueryRow(ctx, `
+		SELECT payload FROM events WHERE ledger_id = $1 AND id::text = $2
+	`, principal.LedgerID, resp.EventID).Scan(&payload)
+	if err != nil {
+		api.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	resp.EventFingerprint = webhook.Fingerprint(payload)
+
+	rows, err := h.DB.Query(ctx, `
+		SELECT wd.id, wd.event_id, wd.webhook_endpoint_id, we.url, wd.status, wd.attempt,
+			wd.last_attempt_at, wd.next_attempt_at, wd.http_status, wd.error_message
+		FROM webhook_deliveries wd
+		JOIN webhook_endpoints we ON we.id = wd.webhook_endpoint_id
+		WHERE we.ledger_id = $1 AND wd.event_id::text = $2
+		ORDER BY wd.created_at
+	`, principal.LedgerID, resp.EventID)
+	if err != nil {
+		api.Error(w, "failed to query webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var delivery WebhookDeliveryResponse
+		var errorMessage *string
+		var lastAttemptAt, nextAttemptAt *time.Time
+		var httpStatus *int
+		err = rows.Scan(&delivery.ID, &delivery.EventID, &delivery.WebhookEndpointID, &delivery.EndpointURL,
+			&delivery.Status, &delivery.Attempt, &lastAttemptAt, &nextAttemptAt, &httpStatus, &errorMessage)
+		if err != nil {
+			api.Error(w, "failed to scan webhook delivery", http.StatusInternalServerError)
+			return
+		}
+		if lastAttemptAt != nil {
+			delivery.LastAttemptAt = lastAttemptAt.Format(time.RFC3339)
+		}
+		if nextAttemptAt != nil {
+			delivery.NextAttemptAt = nextAttemptAt.Format(time.RFC3339)
+		}
+		if httpStatus != nil {
+			delivery.HTTPStatus = *httpStatus
+		}
+		if errorMessage != nil {
+			delivery.ErrorMessage = *errorMessage
+		}
+		delivery.DeliveryID = webhook.DeliveryID(delivery.EventID, delivery.WebhookEndpointID)
+		resp.Deliveries = append(resp.Deliveries, delivery)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type ReplayWebhookEventsRequest struct {
+	URL          string `json:"url"`
+	Secret       string `json:"secret,omitempty"` // generated and returned when empty
+	FromSequence int64  `json:"from_sequence"`
+	ToSequence   int64  `json:"to_sequence,omitempty"` // inclusive; 0 replays up to the latest event
+	EventType    string `json:"event_type,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+}
+
+type ReplayedDelivery struct {
+	EventID      string `json:"event_id"`
+	Sequence     int64  `json:"sequence"`
+	EventType    string `json:"event_type"`
+	Status       string `json:"status"`
+	HTTPStatus   int    `json:"http_status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+type ReplayWebhookEventsResponse struct {
+	Secret     string             `json:"secret"`
+	Deliveries []ReplayedDelivery `json:"deliveries"`
+	// NextFromSequence continues the replay; empty once the range is exhausted
+	NextFromSequence int64 `json:"next_from_sequence,omitempty"`
+}
+
+// Replays send at most this many events per call, since they are delivered inline.
+const maxReplayEvents = 100
+
+// POST /v1/webhook-replays - Send a range of historical events to a staging URL
+//
+// Events are signed and sent like production deliveries but nothing is recorded, so
+// retries, delivery history and the production endpoints are unaffected. The replay
+// stops at the first unreachable URL and returns where to resume.
+func (h *WebhookHandler) ReplayWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReplayWebhookEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !validWebhookURL(req.URL) {
+		api.Error(w, "url must be an absolute http(s) URL", http.StatusBadRequest)
+		return
+	}
+	if req.ToSequence != 0 && req.ToSequence < req.FromSequence {
+		api.Error(w, "to_sequence must not be before from_sequence", http.StatusBadRequest)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > maxReplayEvents {
+		req.Limit = maxReplayEvents
+	}
+	if req.Secret == "" {
+		if req.Secret, err = generateWebhookSecret(); err != nil {
+			api.Error(w, "failed to generate secret", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	query := `
+		SELECT id, sequence, event_type, payload, occurred_at
+		FROM events
+		WHERE ledger_id = $1 AND sequence >= $2
+	`
+	args := []interface{}{principal.LedgerID, req.FromSequence}
+	if req.ToSequence != 0 {
+		args = append(args, req.ToSequence)
+		query += fmt.Sprintf(" AND sequence <= $%d", len(args))
+	}
+	if req.EventType != "" {
+		args = append(args, req.EventType)
+		query += fmt.Sprintf(" AND event_type = $%d", len(args))
+	}
+	args = append(args, req.Limit+1)
+	query += fmt.Sprintf(" ORDER BY sequence LIMIT $%d", len(args))
+
+	type replayEvent struct {
+		ID, Type   string
+		Sequence   int64
+		Payload    []byte
+		OccurredAt time.Time
+	}
+	rows, err := h.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query events", http.StatusInternalServerError)
+		return
+	}
+	var replayed []replayEvent
+	for rows.Next() {
+		var e replayEvent
+		if err := rows.Scan(&e.ID, &e.Sequence, &e.Type, &e.Payload, &e.OccurredAt); err != nil {
+			rows.Close()
+			api.Error(w, "failed to scan event", http.StatusInternalServerError)
+			return
+		}
+		replayed = append(replayed, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		api.Error(w, "failed to query events", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ReplayWebhookEventsResponse{Secret: req.Secret, Deliveries: []ReplayedDelivery{}}
+	if len(replayed) > req.Limit {
+		resp.NextFromSequence = replayed[req.Limit].Sequence
+		replayed = replayed[:req.Limit]
+	}
+
+	header := http.Header{}
+	header.Set("X-Ledger-Replay", "true")
+	for _, e := range replayed {
+		payload, err := events.Current(e.Type, e.Payload)
+		// A replay is a new delivery of the event, not a retry of an endpoint's
+		deliveryID := uuid.NewString()
+		if err == nil {
+			payload, err = webhook.Wrap(deliveryID, e.ID, e.Type, principal.LedgerID, e.OccurredAt, payload)
+		}
+		if err != nil {
+			api.Error(w, fmt.Sprintf("event %s: %v", e.ID, err), http.StatusInternalServerError)
+			return
+		}
+		header.Set("X-Ledger-Event-Id", e.ID)
+		header.Set("X-Ledger-Delivery-Id", deliveryID)
+		outcome := webhook.Deliver(ctx, nil, req.URL, []string{req.Secret}, payload, header)
+		resp.Deliveries = append(resp.Deliveries, ReplayedDelivery{
+			EventID:      e.ID,
+			Sequence:     e.Sequence,
+			EventType:    e.Type,
+			Status:       outcome.Status,
+			HTTPStatus:   outcome.HTTPStatus,
+			ErrorMessage: outcome.Error,
+		})
+		if outcome.HTTPStatus == 0 && outcome.Retryable() {
+			// Unreachable; resume from this event
+			resp.NextFromSequence = e.Sequence
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 func generateWebhookSecret() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
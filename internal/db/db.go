@@ -1,6 +1,7 @@
 package db
 
 import (
+	"Go_FormanceLegder/internal/faults"
 	"context"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -11,10 +12,16 @@ func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newPoolWithConfig(ctx, config)
+}
 
+func newPoolWithConfig(ctx context.Context, config *pgxpool.Config) (*pgxpool.Pool, error) {
 	config.MaxConns = 20
 	config.MinConns = 5
 
+	// No-op unless built with the "faults" tag
+	faults.ConfigurePool(config)
+
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, err
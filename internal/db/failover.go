@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Cluster is a database with an optional hot standby, for active/passive failover.
+//
+// The pool connects to the primary and falls back to the standby when the primary is
+// unreachable, so reads keep working through an outage. Writes are fenced by the
+// standby itself, which refuses them while in recovery; IsWriteFenced recognizes the
+// error. Once the standby is promoted, Watch notices and moves every connection to it,
+// and the old primary stays fenced until the process restarts, even if it comes back
+// accepting writes.
+type Cluster struct {
+	Name string
+	Pool *pgxpool.Pool
+
+	standby *pgconn.Config
+
+	// What Watch connects to, and how it tells a server accepts writes
+	primaryCheck, standbyCheck *pgx.ConnConfig
+	writable                   func(ctx context.Context, config *pgx.ConnConfig) bool
+
+	primaryDown atomic.Bool
+	promoted    atomic.Bool
+}
+
+// ClusterStatus is what the last health check saw.
+type ClusterStatus struct {
+	Name         string `json:"name"`
+	Standby      bool   `json:"standby_configured"`
+	PrimaryDown  bool   `json:"primary_down"`
+	Promoted     bool   `json:"standby_promoted"`
+	WritesFenced bool   `json:"writes_fenced"`
+}
+
+// NewCluster connects to a primary database and, when standbyURL is set, configures
+// its standby as the fallback. The standby is a physical replica, so it is reached
+// with the primary's database, user and password; only its host, port and TLS
+// settings are taken from standbyURL.
+func NewCluster(ctx context.Context, name, primaryURL, standbyURL string) (*Cluster, error) {
+	if standbyURL == "" {
+		pool, err := NewPool(ctx, primaryURL)
+		return &Cluster{Name: name, Pool: pool}, err
+	}
+
+	c, config, err := newCluster(name, primaryURL, standbyURL)
+	if err != nil {
+		return nil, err
+	}
+	c.Pool, err = newPoolWithConfig(ctx, config)
+	return c, err
+}
+
+// newCluster sets up a cluster with a standby and returns the config of its pool.
+func newCluster(name, primaryURL, standbyURL string) (*Cluster, *pgxpool.Config, error) {
+	c := &Cluster{Name: name, writable: writable}
+	config, err := pgxpool.ParseConfig(primaryURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.standby, err = pgconn.ParseConfig(standbyURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("standby: %w", err)
+	}
+
+	// Health checks reach each server alone, with the primary's credentials
+	c.primaryCheck = config.ConnConfig.Copy()
+	c.standbyCheck = config.ConnConfig.Copy()
+	c.onStandby(c.standbyCheck)
+
+	config.ConnConfig.Fallbacks = append(config.ConnConfig.Fallbacks, c.standbyHosts()...)
+
+	// After a promotion, new connections skip the old primary
+	config.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+		if c.promoted.Load() {
+			c.onStandby(cc)
+		}
+		return nil
+	}
+	return c, config, nil
+}
+
+// onStandby points cc at the standby, keeping its database and credentials.
+func (c *Cluster) onStandby(cc *pgx.ConnConfig) {
+	hosts := c.standbyHosts()
+	cc.Host, cc.Port, cc.TLSConfig = hosts[0].Host, hosts[0].Port, hosts[0].TLSConfig
+	cc.Fallbacks = hosts[1:]
+}
+
+func (c *Cluster) standbyHosts() []*pgconn.FallbackConfig {
+	hosts := []*pgconn.FallbackConfig{{Host: c.standby.Host, Port: c.standby.Port, TLSConfig: c.standby.TLSConfig}}
+	return append(hosts, c.standby.Fallbacks...)
+}
+
+// Status reports the cluster's failover state.
+func (c *Cluster) Status() ClusterStatus {
+	return ClusterStatus{
+		Name:         c.Name,
+		Standby:      c.standby != nil,
+		PrimaryDown:  c.primaryDown.Load(),
+		Promoted:     c.promoted.Load(),
+		WritesFenced: c.primaryDown.Load() && !c.promoted.Load(),
+	}
+}
+
+// Watch checks the primary and the standby every interval until ctx is done. When the
+// primary comes back, connections made to the standby in the meantime are dropped so
+// reads return to the primary; when the standby is found promoted, every connection is
+// dropped so writes move to it. A cluster without a standby is not watched.
+func (c *Cluster) Watch(ctx context.Context, interval time.Duration) error {
+	if c.standby == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			c.check(ctx)
+		}
+	}
+}
+
+func (c *Cluster) check(ctx context.Context) {
+	primaryUp := c.writable(ctx, c.primaryCheck)
+
+	if !c.promoted.Load() && c.writable(ctx, c.standbyCheck) {
+		c.promoted.Store(true)
+		c.primaryDown.Store(true)
+		c.Pool.Reset()
+		log.Printf("database %s: standby promoted; connections moved to it and the old primary is fenced", c.Name)
+		return
+	}
+	if c.promoted.Load() {
+		if primaryUp {
+			log.Printf("database %s: old primary accepts writes after the promotion; it stays fenced, rebuild it as a standby", c.Name)
+		}
+		return
+	}
+
+	if wasDown := c.primaryDown.Swap(!primaryUp); wasDown && primaryUp {
+		c.Pool.Reset()
+		log.Printf("database %s: primary is back; reads and writes returned to it", c.Name)
+	} else if !wasDown && !primaryUp {
+		log.Printf("database %s: primary unreachable; reads served by the standby, writes fenced until it returns or the standby is promoted", c.Name)
+	}
+}
+
+// writable reports whether the server config reaches is out of recovery.
+func writable(ctx context.Context, config *pgx.ConnConfig) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return false
+	}
+	defer conn.Close(ctx)
+	var inRecovery bool
+	if err := conn.QueryRow(ctx, `SELECT pg_is_in_recovery()`).Scan(&inRecovery); err != nil {
+		return false
+	}
+	return !inRecovery
+}
+
+// IsWriteFenced reports whether err is a write refused by a standby, i.e. while the
+// primary is down and the standby not yet promoted.
+func IsWriteFenced(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "25006" // read_only_sql_transaction
+}
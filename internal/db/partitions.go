@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// eventPartitionsAhead is how many months of events partitions exist beyond the current one.
+const eventPartitionsAhead = 3
+
+// EnsureEventPartitions creates the monthly events partitions from this month to
+// eventPartitionsAhead months out (see ensure_event_partitions in the migrations).
+func EnsureEventPartitions(ctx context.Context, pool *pgxpool.Pool) error {
+	now := time.Now().UTC()
+	_, err := pool.Exec(ctx, `SELECT ensure_event_partitions($1::date, $2::date)`,
+		now, now.AddDate(0, eventPartitionsAhead, 0))
+	return err
+}
+
+// MaintainEventPartitions runs EnsureEventPartitions daily until ctx is done. Inserts
+// beyond the last partition land in the default one, so a missed day is harmless.
+func MaintainEventPartitions(ctx context.Context, pool *pgxpool.Pool) error {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := EnsureEventPartitions(ctx, pool); err != nil && ctx.Err() == nil {
+			log.Printf("event partitions: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ControlRegion names the control-plane database in Router.Pools when no region uses it.
+const ControlRegion = "control"
+
+// Router resolves which database cluster holds an organization's ledger data.
+//
+// Identity data (users, organizations, projects, ledgers, API keys) lives in the
+// control-plane database. Ledger data (events, accounts, transactions, webhooks, ...)
+// lives in the organization's region. Every regional database carries the full schema
+// and a copy of the organization/project/ledger rows its ledgers reference.
+type Router struct {
+	Control *pgxpool.Pool
+	Default string
+
+	pools    map[string]*pgxpool.Pool
+	clusters []*Cluster
+}
+
+// NewRouter connects to every regional database, with the region's hot standby from
+// standbyURLs as its fallback when set (see Cluster). The default region uses the
+// control database unless it has its own URL, so a single-database setup needs no
+// configuration.
+func NewRouter(ctx context.Context, control *pgxpool.Pool, regionURLs, standbyURLs map[string]string, defaultRegion string) (*Router, error) {
+	r := &Router{
+		Control: control,
+		Default: defaultRegion,
+		pools:   map[string]*pgxpool.Pool{},
+	}
+
+	for region, url := range regionURLs {
+		cluster, err := NewCluster(ctx, region, url, standbyURLs[region])
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		r.pools[region] = cluster.Pool
+		r.clusters = append(r.clusters, cluster)
+	}
+	if _, ok := r.pools[defaultRegion]; !ok {
+		r.pools[defaultRegion] = control
+	}
+
+	return r, nil
+}
+
+// Watch health-checks every regional database with a standby until ctx is done (see
+// Cluster.Watch).
+func (r *Router) Watch(ctx context.Context, interval time.Duration) {
+	for _, c := range r.clusters {
+		go c.Watch(ctx, interval)
+	}
+}
+
+// Clusters returns the failover state of the regional databases.
+func (r *Router) Clusters() []ClusterStatus {
+	statuses := make([]ClusterStatus, len(r.clusters))
+	for i, c := range r.clusters {
+		statuses[i] = c.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Pool returns the database of a region; an empty region means the default one.
+func (r *Router) Pool(region string) (*pgxpool.Pool, error) {
+	if region == "" {
+		region = r.Default
+	}
+	pool, ok := r.pools[region]
+	if !ok {
+		return nil, fmt.Errorf("unknown region %q", region)
+	}
+	return pool, nil
+}
+
+// HasRegion reports whether the region is configured.
+func (r *Router) HasRegion(region string) bool {
+	_, ok := r.pools[region]
+	return ok
+}
+
+// Regions returns the configured region names in sorted order.
+func (r *Router) Regions() []string {
+	regions := make([]string, 0, len(r.pools))
+	for region := range r.pools {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// Pools returns every distinct database keyed by region. The control database is
+// included under ControlRegion when it is not also a region's database, since it
+// still holds control-plane events (e.g. API key lifecycle) that need projecting.
+func (r *Router) Pools() map[string]*pgxpool.Pool {
+	pools := map[string]*pgxpool.Pool{}
+	controlUsed := false
+	for region, pool := range r.pools {
+		pools[region] = pool
+		if pool == r.Control {
+			controlUsed = true
+		}
+	}
+	if !controlUsed {
+		pools[ControlRegion] = r.Control
+	}
+	return pools
+}
+
+// ForOrganization resolves the region and database assigned to an organization.
+func (r *Router) ForOrganization(ctx context.Context, orgID string) (string, *pgxpool.Pool, error) {
+	var region string
+	err := r.Control.QueryRow(ctx, `
+		SELECT COALESCE(region, '') FROM organizations WHERE id = $1
+	`, orgID).Scan(&region)
+	if err != nil {
+		return "", nil, err
+	}
+	if region == "" {
+		region = r.Default
+	}
+	pool, err := r.Pool(region)
+	return region, pool, err
+}
+
+// ForLedger resolves the region and database holding a ledger's data, for requests that
+// identify a ledger without an API key (e.g. provider webhooks).
+func (r *Router) ForLedger(ctx context.Context, ledgerID string) (string, *pgxpool.Pool, error) {
+	var orgID string
+	err := r.Control.QueryRow(ctx, `
+		SELECT p.organization_id
+		FROM ledgers l
+		JOIN projects p ON p.id = l.project_id
+		WHERE l.id::text = $1
+	`, ledgerID).Scan(&orgID)
+	if err != nil {
+		return "", nil, err
+	}
+	return r.ForOrganization(ctx, orgID)
+}
+
+// EnsureLedger copies a ledger and its owning organization and project from the control
+// database into the organization's regional database so ledger data can reference it.
+// Only identifiers and names are copied; users and memberships stay in the control plane.
+// Copying again is a no-op, so a failed copy can be retried.
+func (r *Router) EnsureLedger(ctx context.Context, ledgerID string) error {
+	return r.EnsureLedgerTx(ctx, r.Control, ledgerID)
+}
+
+// EnsureLedgerTx is EnsureLedger reading the ledger through control, e.g. the control
+// transaction creating it: committing it only once the copy succeeded leaves no ledger
+// without its regional row.
+func (r *Router) EnsureLedgerTx(ctx context.Context, control Querier, ledgerID string) error {
+	var orgID, orgName, projectID, projectName, projectCode, ledgerName, ledgerCode, currency string
+	err := control.QueryRow(ctx, `
+		SELECT o.id, o.name, p.id, p.name, p.code, l.name, l.code, l.currency
+		FROM ledgers l
+		JOIN projects p ON p.id = l.project_id
+		JOIN organizations o ON o.id = p.organization_id
+		WHERE l.id = $1
+	`, ledgerID).Scan(&orgID, &orgName, &projectID, &projectName, &projectCode, &ledgerName, &ledgerCode, &currency)
+	if err != nil {
+		return err
+	}
+
+	region, pool, err := r.ForOrganization(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if pool == r.Control {
+		return nil
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO organizations (id, name, region) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO NOTHING
+	`, orgID, orgName, region)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO projects (id, organization_id, name, code) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING
+	`, projectID, orgID, projectName, projectCode)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO ledgers (id, project_id, name, code, currency) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO NOTHING
+	`, ledgerID, projectID, ledgerName, ledgerCode, currency)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Querier is what EnsureLedgerTx reads through: a pool or a transaction.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Close closes the regional pools; the control pool is owned by the caller.
+func (r *Router) Close() {
+	for _, pool := range r.pools {
+		if pool != r.Control {
+			pool.Close()
+		}
+	}
+}
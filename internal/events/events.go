@@ -0,0 +1,99 @@
+// Package events defines the payload of each event type. Writers fill a payload struct
+// and Marshal it, which stamps the schema version; readers Decode a stored payload,
+// which upcasts older versions first, so producers and consumers share one checked
+// shape instead of indexing into maps.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Payload is the payload of an event type; every payload struct embeds Header.
+type Payload interface {
+	header() *Header
+}
+
+// Header holds what every payload carries.
+type Header struct {
+	SchemaVersion int `json:"schema_version"`
+}
+
+func (h *Header) header() *Header { return h }
+
+// payloads returns an empty payload of each event type.
+var payloads = map[string]func() Payload{
+	"TransactionPosted":         func() Payload { return &TransactionPosted{} },
+	"HoldCreated":               func() Payload { return &HoldCreated{} },
+	"HoldCaptured":              func() Payload { return &HoldCaptured{} },
+	"HoldVoided":                func() Payload { return &HoldVoided{} },
+	"AccountMetadataUpdated":    func() Payload { return &AccountMetadataUpdated{} },
+	"AccountDisabled":           func() Payload { return &AccountStatusChanged{} },
+	"AccountEnabled":            func() Payload { return &AccountStatusChanged{} },
+	"EntityKYCStatusChanged":    func() Payload { return &EntityKYCStatusChanged{} },
+	"TransactionHeldForReview":  func() Payload { return &TransactionHeldForReview{} },
+	"TransactionReviewRejected": func() Payload { return &TransactionReviewRejected{} },
+	"APIKeyRequested":           func() Payload { return &APIKeyChanged{} },
+	"APIKeyCreated":             func() Payload { return &APIKeyChanged{} },
+	"APIKeyApproved":            func() Payload { return &APIKeyChanged{} },
+	"APIKeyRejected":            func() Payload { return &APIKeyChanged{} },
+	"APIKeyRevoked":             func() Payload { return &APIKeyChanged{} },
+	"WebhookTest":               func() Payload { return &WebhookTest{} },
+	"WebhookEndpointDisabled":   func() Payload { return &WebhookEndpointDisabled{} },
+	"ProjectorLagExceeded":      func() Payload { return &ProjectorLagExceeded{} },
+	"ProjectorLagRecovered":     func() Payload { return &ProjectorLagRecovered{} },
+	"ImportCompleted":           func() Payload { return &ImportCompleted{} },
+}
+
+// Marshal encodes the payload of a new event of eventType at its current schema version.
+func Marshal(eventType string, p Payload) ([]byte, error) {
+	newPayload, ok := payloads[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %s", eventType)
+	}
+	if want := reflect.TypeOf(newPayload()); reflect.TypeOf(p) != want {
+		return nil, fmt.Errorf("%s payload must be a %s, not %T", eventType, want, p)
+	}
+	p.header().SchemaVersion = SchemaVersion(eventType)
+	return json.Marshal(p)
+}
+
+// Decode parses a stored payload of eventType into its payload struct, upcasting it to
+// the current schema version first.
+func Decode(eventType string, raw []byte) (Payload, error) {
+	newPayload, ok := payloads[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %s", eventType)
+	}
+	current, err := Current(eventType, raw)
+	if err != nil {
+		return nil, err
+	}
+	p := newPayload()
+	if err := json.Unmarshal(current, p); err != nil {
+		return nil, fmt.Errorf("bad %s payload: %w", eventType, err)
+	}
+	return p, nil
+}
+
+// Current returns a stored payload at the current schema version of eventType: raw
+// itself when it already is, or else its upcast re-encoded.
+func Current(eventType string, raw []byte) ([]byte, error) {
+	var h Header
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return nil, fmt.Errorf("bad payload: %w", err)
+	}
+	if h.SchemaVersion == SchemaVersion(eventType) {
+		return raw, nil
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("bad payload: %w", err)
+	}
+	if err := Upcast(eventType, payload); err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarshalDecode(t *testing.T) {
+	posted := &TransactionPosted{
+		TransactionID: "t1",
+		Currency:      "USD",
+		OccurredAt:    time.Date(2024, 5, 1, 12, 0, 0, 123, time.UTC),
+		Postings: []Posting{
+			{AccountCode: "cash", Direction: "debit", Amount: "10", Currency: "USD"},
+			{AccountCode: "revenue", Direction: "credit", Amount: "10", Currency: "USD"},
+		},
+	}
+	raw, err := Marshal("TransactionPosted", posted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode("TransactionPosted", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := decoded.(*TransactionPosted)
+	if !ok {
+		t.Fatalf("expected a *TransactionPosted, got %T", decoded)
+	}
+	if got.SchemaVersion != 2 || got.TransactionID != "t1" || !got.OccurredAt.Equal(posted.OccurredAt) || len(got.Postings) != 2 {
+		t.Fatalf("unexpected round trip: %+v", got)
+	}
+
+	// Version 1 payloads are upcast before decoding
+	v1 := `{"transaction_id":"t1","currency":"EUR","occurred_at":"2024-05-01T12:00:00Z",
+		"postings":[{"account_code":"cash","direction":"debit","amount":"10"}]}`
+	decoded, err = Decode("TransactionPosted", []byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := decoded.(*TransactionPosted).Postings[0]; p.Currency != "EUR" {
+		t.Fatalf("expected the upcast posting currency, got %q", p.Currency)
+	}
+
+	if _, err := Decode("Unknown", []byte(`{}`)); err == nil {
+		t.Fatal("expected an unknown event type to be rejected")
+	}
+	if _, err := Marshal("HoldCaptured", &HoldVoided{HoldID: "h1"}); err == nil {
+		t.Fatal("expected another event type's payload to be rejected")
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	current := []byte(`{"schema_version":2,"transaction_id":"t1"}`)
+	if got, err := Current("TransactionPosted", current); err != nil || string(got) != string(current) {
+		t.Fatalf("expected a current payload unchanged, got %s (%v)", got, err)
+	}
+	got, err := Current("HoldVoided", []byte(`{"hold_id":"h1"}`))
+	if err != nil || string(got) != `{"hold_id":"h1","schema_version":1}` {
+		t.Fatalf("expected an unversioned payload stamped, got %s (%v)", got, err)
+	}
+}
@@ -0,0 +1,155 @@
+package events
+
+import "time"
+
+// TransactionPosted records a balanced transaction. Version 2: every posting carries
+// its currency.
+type TransactionPosted struct {
+	Header
+	TransactionID string         `json:"transaction_id"`
+	ExternalID    string         `json:"external_id"`
+	Description   string         `json:"description,omitempty"`
+	Currency      string         `json:"currency"`
+	OccurredAt    time.Time      `json:"occurred_at"`
+	ValueDate     string         `json:"value_date,omitempty"` // YYYY-MM-DD; absent is occurred_at's date
+	Postings      []Posting      `json:"postings"`
+	Metadata      map[string]any `json:"metadata,omitempty"`
+	Script        string         `json:"script,omitempty"`
+	EntityCode    string         `json:"entity_code,omitempty"`
+}
+
+type Posting struct {
+	AccountCode string `json:"account_code"`
+	Direction   string `json:"direction"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	TaxCode     string `json:"tax_code,omitempty"` // defaults to the account's tax code
+	Description string `json:"description,omitempty"`
+}
+
+// HoldCreated reserves an amount of an account until the hold is captured or voided.
+type HoldCreated struct {
+	Header
+	HoldID          string         `json:"hold_id"`
+	AccountCode     string         `json:"account_code"`
+	DestinationCode string         `json:"destination_code"`
+	Amount          string         `json:"amount"`
+	Currency        string         `json:"currency"`
+	Description     string         `json:"description"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+}
+
+// HoldCaptured releases a hold for the transaction that settled it.
+type HoldCaptured struct {
+	Header
+	HoldID        string `json:"hold_id"`
+	Amount        string `json:"amount"`
+	TransactionID string `json:"transaction_id"`
+}
+
+type HoldVoided struct {
+	Header
+	HoldID string `json:"hold_id"`
+}
+
+// AccountMetadataUpdated is a JSON merge patch of an account's metadata: null values
+// remove keys.
+type AccountMetadataUpdated struct {
+	Header
+	AccountID string         `json:"account_id"`
+	Code      string         `json:"code"`
+	Metadata  map[string]any `json:"metadata"`
+}
+
+// AccountStatusChanged is the payload of AccountDisabled and AccountEnabled.
+type AccountStatusChanged struct {
+	Header
+	AccountID string `json:"account_id"`
+	Code      string `json:"code"`
+}
+
+type EntityKYCStatusChanged struct {
+	Header
+	EntityCode     string `json:"entity_code"`
+	PreviousStatus string `json:"previous_status"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+type TransactionHeldForReview struct {
+	Header
+	ReviewID       string `json:"review_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	ExternalID     string `json:"external_id"`
+	Reason         string `json:"reason"`
+}
+
+type TransactionReviewRejected struct {
+	Header
+	ReviewID string `json:"review_id"`
+	Note     string `json:"note"`
+}
+
+// APIKeyChanged is the payload of the API key lifecycle events. Only APIKeyRequested and
+// APIKeyCreated describe the key; the others name it and the acting user. The key's
+// hash is never recorded in events, which are readable through the API and published;
+// it is written to api_keys with the first event.
+type APIKeyChanged struct {
+	Header
+	APIKeyID    string `json:"api_key_id"`
+	UserID      string `json:"user_id"`
+	Prefix      string `json:"prefix,omitempty"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status,omitempty"`
+}
+
+// WebhookTest is sent to an endpoint on request to check the receiver and its signature
+// verification. It is never stored, so no real event has its ID.
+type WebhookTest struct {
+	Header
+	EndpointID string    `json:"endpoint_id"`
+	Test       bool      `json:"test"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// WebhookEndpointDisabled records the webhook worker disabling an endpoint after too
+// many failed deliveries in a row.
+type WebhookEndpointDisabled struct {
+	Header
+	EndpointID          string `json:"endpoint_id"`
+	URL                 string `json:"url"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error"`
+}
+
+// ProjectorLagExceeded records that a projector has left one of the ledger's events
+// unapplied for longer than the alert threshold, so reads of the ledger are stale.
+// ProjectorLagRecovered follows once it has caught up.
+type ProjectorLagExceeded struct {
+	Header
+	Projector        string    `json:"projector"`
+	PendingEvents    int64     `json:"pending_events"`
+	OldestPendingAt  time.Time `json:"oldest_pending_at"`
+	LagSeconds       float64   `json:"lag_seconds"`
+	ThresholdSeconds float64   `json:"threshold_seconds"`
+}
+
+type ProjectorLagRecovered struct {
+	Header
+	Projector   string    `json:"projector"`
+	AlertedAt   time.Time `json:"alerted_at"` // when ProjectorLagExceeded was recorded
+	RecoveredAt time.Time `json:"recovered_at"`
+}
+
+// ImportCompleted records the end of an import into the ledger: a clearing file, or a
+// bank feed sync. Discrepancies are records left for review rather than posted or matched.
+type ImportCompleted struct {
+	Header
+	Kind          string `json:"kind"`      // clearing or bank_feed
+	ImportID      string `json:"import_id"` // the clearing import, or the bank feed
+	Filename      string `json:"filename,omitempty"`
+	Records       int    `json:"records"`
+	Posted        int    `json:"posted"`
+	Matched       int    `json:"matched"`
+	Discrepancies int    `json:"discrepancies"`
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"fmt"
+)
+
+// Event payloads carry a "schema_version"; payloads written before versioning are
+// version 1. When a payload shape changes, change its struct, bump its version here and
+// register an upcaster from the previous version, so readers only ever decode the
+// current shape while old events stay projectable (e.g. on rebuilds and shadow
+// projections).
+var schemaVersions = map[string]int{
+	// 2: every posting carries its currency
+	"TransactionPosted": 2,
+}
+
+// upcasters[eventType][v] rewrites a version v payload into version v+1 in place.
+var upcasters = map[string]map[int]func(payload map[string]any) error{
+	"TransactionPosted": {
+		1: upcastTransactionPostedV1,
+	},
+}
+
+// SchemaVersion is the version writers stamp on new payloads of eventType.
+func SchemaVersion(eventType string) int {
+	if v, ok := schemaVersions[eventType]; ok {
+		return v
+	}
+	return 1
+}
+
+// Upcast upgrades payload to the current schema version of eventType.
+func Upcast(eventType string, payload map[string]any) error {
+	version := 1
+	if v, ok := payload["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	current := SchemaVersion(eventType)
+	if version > current {
+		return fmt.Errorf("%s schema version %d is newer than supported version %d", eventType, version, current)
+	}
+	for ; version < current; version++ {
+		upcast, ok := upcasters[eventType][version]
+		if !ok {
+			return fmt.Errorf("no upcaster for %s schema version %d", eventType, version)
+		}
+		if err := upcast(payload); err != nil {
+			return fmt.Errorf("upcast %s from version %d: %w", eventType, version, err)
+		}
+	}
+	payload["schema_version"] = float64(current)
+	return nil
+}
+
+// Version 1 postings omitted the currency when it was the transaction's.
+func upcastTransactionPostedV1(payload map[string]any) error {
+	currency, _ := payload["currency"].(string)
+	postings, ok := payload["postings"].([]any)
+	if !ok {
+		return fmt.Errorf("invalid postings payload")
+	}
+	for _, raw := range postings {
+		pMap, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid posting payload")
+		}
+		if c, _ := pMap["currency"].(string); c == "" {
+			pMap["currency"] = currency
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpcastTransactionPosted(t *testing.T) {
+	var payload map[string]any
+	v1 := `{"transaction_id":"t1","currency":"USD","postings":[
+		{"account_code":"a","direction":"debit","amount":"10"},
+		{"account_code":"b","direction":"credit","amount":"9","currency":"EUR"}]}`
+	if err := json.Unmarshal([]byte(v1), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := Upcast("TransactionPosted", payload); err != nil {
+		t.Fatal(err)
+	}
+	postings := payload["postings"].([]any)
+	if postings[0].(map[string]any)["currency"] != "USD" || postings[1].(map[string]any)["currency"] != "EUR" {
+		t.Fatalf("unexpected postings after upcast: %v", postings)
+	}
+	if payload["schema_version"] != float64(2) {
+		t.Fatalf("unexpected schema version %v", payload["schema_version"])
+	}
+
+	// Current payloads and unversioned event types pass through
+	if err := Upcast("TransactionPosted", payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := Upcast("HoldCreated", map[string]any{"hold_id": "h1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Upcast("TransactionPosted", map[string]any{"schema_version": float64(3)}); err == nil {
+		t.Fatal("expected a payload newer than the projector to be rejected")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !faults
+
+package faults
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Enabled reports whether fault injection is compiled in.
+func Enabled() bool { return false }
+
+func Set(Config) {}
+
+func Reset() {}
+
+func ConfigurePool(*pgxpool.Config) {}
+
+func ProjectorBatch() error { return nil }
+
+func WebhookDelay(context.Context) error { return nil }
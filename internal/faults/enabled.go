@@ -0,0 +1,73 @@
+//go:build faults
+
+package faults
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var current atomic.Pointer[Config]
+
+func init() {
+	c, err := ParseConfig(os.Getenv("FAULTS"))
+	if err != nil {
+		log.Fatalf("invalid FAULTS: %v", err)
+	}
+	current.Store(&c)
+	log.Printf("fault injection enabled: %+v", c)
+}
+
+// Enabled reports whether fault injection is compiled in.
+func Enabled() bool { return true }
+
+// Set replaces the active fault configuration.
+func Set(c Config) { current.Store(&c) }
+
+// Reset disables every fault.
+func Reset() { current.Store(&Config{}) }
+
+// ConfigurePool makes the pool drop connections at the configured rate. A dropped
+// connection is destroyed and the query that acquired it fails with ErrInjected.
+func ConfigurePool(config *pgxpool.Config) {
+	config.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		if hit(current.Load().DBDropRate) {
+			return false, fmt.Errorf("database connection dropped: %w", ErrInjected)
+		}
+		return true, nil
+	}
+}
+
+// ProjectorBatch fails the current projector batch at the configured rate.
+func ProjectorBatch() error {
+	if hit(current.Load().ProjectorFailRate) {
+		return fmt.Errorf("projector batch failed: %w", ErrInjected)
+	}
+	return nil
+}
+
+// WebhookDelay waits for the configured delay before a webhook request is sent.
+func WebhookDelay(ctx context.Context) error {
+	delay := current.Load().WebhookDelay
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
@@ -0,0 +1,65 @@
+// Package faults injects failures (dropped database connections, slow webhook
+// endpoints, failing projector batches) for resilience testing.
+//
+// Injection is only compiled in with the "faults" build tag; in regular builds every
+// hook is a no-op. With the tag, faults are configured from the FAULTS environment
+// variable or programmatically with Set, e.g.
+//
+//	FAULTS="db_drop=0.05,webhook_delay=2s,projector_fail=0.2"
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInjected is returned (wrapped) by every injected failure.
+var ErrInjected = errors.New("injected fault")
+
+type Config struct {
+	DBDropRate        float64       // probability a connection is dropped when acquired from the pool
+	WebhookDelay      time.Duration // added before each webhook request is sent
+	ProjectorFailRate float64       // probability a projector batch fails before committing
+}
+
+// ParseConfig parses a comma-separated list of key=value fault settings.
+func ParseConfig(spec string) (Config, error) {
+	var c Config
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid fault %q", entry)
+		}
+
+		var err error
+		switch key {
+		case "db_drop":
+			c.DBDropRate, err = parseRate(value)
+		case "webhook_delay":
+			c.WebhookDelay, err = time.ParseDuration(value)
+		case "projector_fail":
+			c.ProjectorFailRate, err = parseRate(value)
+		default:
+			err = fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("fault rate must be between 0 and 1, got %q", value)
+	}
+	return rate, nil
+}
@@ -0,0 +1,610 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMaxDepth = 10
+	// Most fields a query may select, counting those of each spread of a fragment, so
+	// fragments spreading each other can't blow up validation
+	maxSelections = 10000
+)
+
+// operation returns the operation to run: the named one, or the only one.
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operationName required for a document with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+// validator checks an operation against the schema before anything is resolved.
+type validator struct {
+	doc      *document
+	vars     map[string]bool
+	maxDepth int
+	fields   int
+	errors   []*Error
+}
+
+func (s *Schema) validate(doc *document, op *operation) []*Error {
+	v := &validator{doc: doc, vars: map[string]bool{}, maxDepth: s.MaxDepth}
+	if v.maxDepth <= 0 {
+		v.maxDepth = defaultMaxDepth
+	}
+	for _, def := range op.vars {
+		if v.vars[def.name] {
+			v.fail(nil, "variable $%s declared twice", def.name)
+		}
+		v.vars[def.name] = true
+		t := def.typ
+		for t.elem != nil {
+			t = *t.elem
+		}
+		if !knownScalar(Scalar(t.name)) {
+			v.fail(nil, "variable $%s: unknown type %s", def.name, t.name)
+		}
+	}
+	v.directives(nil, op.directives)
+	v.selections(s.Query, op.selections, 1, nil)
+	return v.errors
+}
+
+func (v *validator) fail(f *field, format string, args ...any) {
+	err := &Error{Message: fmt.Sprintf(format, args...)}
+	if f != nil {
+		err.Locations = []Location{{f.line, f.col}}
+	}
+	v.errors = append(v.errors, err)
+}
+
+// selections checks selections on obj, at depth, within the fragments being spread.
+func (v *validator) selections(obj *Object, selections []selection, depth int, spreading []string) {
+	if depth > v.maxDepth {
+		v.fail(nil, "query is nested more than %d levels deep", v.maxDepth)
+		return
+	}
+	for _, sel := range selections {
+		if v.fields++; v.fields > maxSelections {
+			v.fail(nil, "query selects more than %d fields", maxSelections)
+			return
+		}
+		switch sel := sel.(type) {
+		case *field:
+			v.directives(sel, sel.directives)
+			v.field(obj, sel, depth, spreading)
+		case *fragmentSpread:
+			v.directives(nil, sel.directives)
+			f, ok := v.doc.fragments[sel.name]
+			if !ok {
+				v.fail(nil, "unknown fragment %s", sel.name)
+				continue
+			}
+			for _, name := range spreading {
+				if name == sel.name {
+					v.fail(nil, "fragment %s spreads itself", sel.name)
+					return
+				}
+			}
+			if f.on != obj.Name {
+				v.fail(nil, "fragment %s on %s cannot be spread on %s", f.name, f.on, obj.Name)
+				continue
+			}
+			v.selections(obj, f.selections, depth, append(spreading, sel.name))
+		case *inlineFragment:
+			v.directives(nil, sel.directives)
+			if sel.on != "" && sel.on != obj.Name {
+				v.fail(nil, "fragment on %s cannot be spread on %s", sel.on, obj.Name)
+				continue
+			}
+			v.selections(obj, sel.selections, depth, spreading)
+		}
+	}
+}
+
+func (v *validator) field(obj *Object, f *field, depth int, spreading []string) {
+	if f.name == "__typename" {
+		if len(f.args) > 0 || f.selections != nil {
+			v.fail(f, "__typename takes no arguments or selections")
+		}
+		return
+	}
+	def, ok := obj.Fields[f.name]
+	if !ok {
+		v.fail(f, "cannot query field %s on type %s", f.name, obj.Name)
+		return
+	}
+
+	given := map[string]bool{}
+	for _, a := range f.args {
+		given[a.name] = a.value != nil
+		arg, ok := def.Args[a.name]
+		if !ok {
+			v.fail(f, "unknown argument %s on field %s.%s", a.name, obj.Name, f.name)
+			continue
+		}
+		v.value(f, a.value)
+		if _, isVar := a.value.(variable); !isVar {
+			if _, err := coerceArg(arg.Type, a.value); err != nil {
+				v.fail(f, "argument %s of %s.%s: %v", a.name, obj.Name, f.name, err)
+			}
+		}
+	}
+	for name, arg := range def.Args {
+		if arg.Required && !given[name] {
+			v.fail(f, "field %s.%s requires argument %s", obj.Name, f.name, name)
+		}
+	}
+
+	t := def.Type
+	for {
+		l, ok := t.(List)
+		if !ok {
+			break
+		}
+		t = l.Of
+	}
+	switch t := t.(type) {
+	case *Object:
+		if f.selections == nil {
+			v.fail(f, "field %s.%s of type %s needs a selection", obj.Name, f.name, t.Name)
+			return
+		}
+		v.selections(t, f.selections, depth+1, spreading)
+	default:
+		if f.selections != nil {
+			v.fail(f, "field %s.%s of type %s has no fields to select", obj.Name, f.name, t.typeName())
+		}
+	}
+}
+
+func (v *validator) directives(f *field, directives []directive) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			v.fail(f, "unknown directive @%s", d.name)
+			continue
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			v.fail(f, "@%s takes a single if argument", d.name)
+			continue
+		}
+		v.value(f, d.args[0].value)
+	}
+}
+
+// value checks that the variables a value uses are declared.
+func (v *validator) value(f *field, value any) {
+	switch value := value.(type) {
+	case variable:
+		if !v.vars[string(value)] {
+			v.fail(f, "variable $%s is not declared", value)
+		}
+	case []any:
+		for _, item := range value {
+			v.value(f, item)
+		}
+	case map[string]any:
+		for _, item := range value {
+			v.value(f, item)
+		}
+	}
+}
+
+func knownScalar(s Scalar) bool {
+	switch s {
+	case String, Int, Float, Boolean, ID, JSON:
+		return true
+	}
+	return false
+}
+
+// coerceVariables returns the operation's variables, from the request's values or their
+// defaults, coerced to their declared types.
+func coerceVariables(op *operation, values map[string]any) (map[string]any, error) {
+	vars := map[string]any{}
+	for _, def := range op.vars {
+		value, given := values[def.name]
+		if !given {
+			value = def.def
+		}
+		coerced, err := coerceVariable(def.typ, value)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s of type %s: %v", def.name, def.typ, err)
+		}
+		vars[def.name] = coerced
+	}
+	return vars, nil
+}
+
+func coerceVariable(t typeRef, value any) (any, error) {
+	if value == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("value required")
+		}
+		return nil, nil
+	}
+	if t.elem == nil {
+		return coerceArg(Scalar(t.name), value)
+	}
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	list := make([]any, len(items))
+	for i, item := range items {
+		var err error
+		if list[i], err = coerceVariable(*t.elem, item); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// coerceArg converts a literal or variable value to scalar t.
+func coerceArg(t Scalar, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch t {
+	case Int:
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+				return int(n), nil
+			}
+		}
+	case Float:
+		switch n := value.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case String:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case ID:
+		switch id := value.(type) {
+		case string:
+			return id, nil
+		case int:
+			return strconv.Itoa(id), nil
+		}
+	case Boolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case JSON:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", value, t)
+}
+
+type executor struct {
+	ctx    context.Context
+	doc    *document
+	vars   map[string]any
+	errors []*Error
+}
+
+// selectionSet resolves the selections on obj, of which source is the value.
+func (e *executor) selectionSet(obj *Object, source any, selections []selection, path []any) *orderedMap {
+	keys, fields := e.collect(obj, selections, nil, map[string]bool{})
+	result := &orderedMap{values: make(map[string]any, len(keys))}
+	for _, key := range keys {
+		result.keys = append(result.keys, key)
+		f := fields[key][0]
+		fieldPath := append(path[:len(path):len(path)], key)
+		if f.name == "__typename" {
+			result.values[key] = obj.Name
+			continue
+		}
+		if conflict := conflicting(fields[key]); conflict != nil {
+			e.errors = append(e.errors, &Error{Message: fmt.Sprintf("%s selects both %s and %s", key, f.name, conflict.name),
+				Locations: []Location{{conflict.line, conflict.col}}, Path: fieldPath})
+			result.values[key] = nil
+			continue
+		}
+		def := obj.Fields[f.name]
+		value, err := e.resolve(def, source, f)
+		if err != nil {
+			e.errors = append(e.errors, &Error{Message: err.Error(), Locations: []Location{{f.line, f.col}}, Path: fieldPath})
+			result.values[key] = nil
+			continue
+		}
+		result.values[key] = e.complete(def.Type, value, fields[key], fieldPath)
+	}
+	return result
+}
+
+// conflicting returns a field of fields that selects another field than the first under
+// the same response key.
+func conflicting(fields []*field) *field {
+	for _, f := range fields[1:] {
+		if f.name != fields[0].name {
+			return f
+		}
+	}
+	return nil
+}
+
+// collect groups the fields selected on obj by response key, in order, expanding
+// fragments and dropping what @skip and @include leave out.
+func (e *executor) collect(obj *Object, selections []selection, keys []string, visited map[string]bool) ([]string, map[string][]*field) {
+	fields := map[string][]*field{}
+	var walk func(selections []selection)
+	walk = func(selections []selection) {
+		for _, sel := range selections {
+			switch sel := sel.(type) {
+			case *field:
+				if !e.included(sel.directives) {
+					continue
+				}
+				key := sel.key()
+				if _, seen := fields[key]; !seen {
+					keys = append(keys, key)
+				}
+				fields[key] = append(fields[key], sel)
+			case *fragmentSpread:
+				if visited[sel.name] || !e.included(sel.directives) {
+					continue
+				}
+				visited[sel.name] = true
+				walk(e.doc.fragments[sel.name].selections)
+			case *inlineFragment:
+				if e.included(sel.directives) {
+					walk(sel.selections)
+				}
+			}
+		}
+	}
+	walk(selections)
+	return keys, fields
+}
+
+func (e *executor) included(directives []directive) bool {
+	for _, d := range directives {
+		cond, _ := e.substitute(d.args[0].value).(bool)
+		if d.name == "skip" && cond || d.name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+// substitute replaces the variables in value with their values.
+func (e *executor) substitute(value any) any {
+	switch value := value.(type) {
+	case variable:
+		return e.vars[string(value)]
+	case enum:
+		return string(value)
+	case []any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			list[i] = e.substitute(item)
+		}
+		return list
+	case map[string]any:
+		object := make(map[string]any, len(value))
+		for k, item := range value {
+			object[k] = e.substitute(item)
+		}
+		return object
+	}
+	return value
+}
+
+func (e *executor) resolve(def *Field, source any, f *field) (any, error) {
+	args, err := e.args(def, f)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if def.Resolve != nil {
+		return def.Resolve(Params{Context: e.ctx, Source: source, Args: args})
+	}
+	return fieldOf(source, f.name), nil
+}
+
+// args returns the arguments of f, coerced to the types def declares and defaulted.
+func (e *executor) args(def *Field, f *field) (map[string]any, error) {
+	args := make(map[string]any, len(def.Args))
+	for name, arg := range def.Args {
+		if arg.Default != nil {
+			args[name] = arg.Default
+		}
+	}
+	for _, a := range f.args {
+		value, err := coerceArg(def.Args[a.name].Type, e.substitute(a.value))
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %v", a.name, err)
+		}
+		if value != nil {
+			args[a.name] = value
+		} else if def.Args[a.name].Required {
+			return nil, fmt.Errorf("argument %s required", a.name)
+		}
+	}
+	return args, nil
+}
+
+// defaultListSize is how many items a list without Size counts as in a query's cost.
+const defaultListSize = 10
+
+// cost estimates how many objects the selections on obj resolve for each of n sources,
+// multiplying by the size of every list on the way; lists without Size count as
+// listSize items. It stops counting once the total exceeds limit.
+func (e *executor) cost(obj *Object, selections []selection, n, listSize, limit int) int {
+	keys, fields := e.collect(obj, selections, nil, map[string]bool{})
+	total := 0
+	for _, key := range keys {
+		f := fields[key][0]
+		def := obj.Fields[f.name]
+		if def == nil {
+			continue // __typename
+		}
+		size := -1
+		if def.Size != nil {
+			// Arguments that fail to coerce fail the field when it resolves
+			if args, err := e.args(def, f); err == nil {
+				size = max(def.Size(args), 0)
+			}
+		}
+		count, t, inner := n, def.Type, defaultListSize
+		if list, ok := t.(List); ok {
+			for ; ok; list, ok = t.(List) {
+				itemSize := listSize
+				if size >= 0 && t == def.Type {
+					itemSize = size
+				}
+				if itemSize > 0 && count > limit/itemSize {
+					return limit + 1
+				}
+				count, t = count*itemSize, list.Of
+			}
+		} else if size >= 0 {
+			inner = size
+		}
+		child, ok := t.(*Object)
+		if !ok {
+			continue
+		}
+		var sub []selection
+		for _, f := range fields[key] {
+			sub = append(sub, f.selections...)
+		}
+		if total += count; total <= limit {
+			total += e.cost(child, sub, count, inner, limit-total)
+		}
+		if total > limit {
+			return total
+		}
+	}
+	return total
+}
+
+// complete turns a resolved value of type t into its response value.
+func (e *executor) complete(t Type, value any, fields []*field, path []any) any {
+	if isNil(value) {
+		return nil
+	}
+	switch t := t.(type) {
+	case List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			e.errors = append(e.errors, &Error{Message: fmt.Sprintf("expected a list, got %T", value), Path: path})
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = e.complete(t.Of, v.Index(i).Interface(), fields, append(path[:len(path):len(path)], i))
+		}
+		return list
+	case *Object:
+		var selections []selection
+		for _, f := range fields {
+			selections = append(selections, f.selections...)
+		}
+		return e.selectionSet(t, value, selections, path)
+	}
+	return value
+}
+
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// fieldOf is the default resolver: the entry name of a map, or the field of a struct
+// whose JSON name is name.
+func fieldOf(source any, name string) any {
+	if m, ok := source.(map[string]any); ok {
+		return m[name]
+	}
+	v := reflect.ValueOf(source)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return structField(v, name)
+}
+
+func structField(v reflect.Value, name string) any {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("json") == "" {
+			if value := structField(v.Field(i), name); value != nil {
+				return value
+			}
+			continue
+		}
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if tag == name || tag == "" && sf.Name == name {
+			return v.Field(i).Interface()
+		}
+	}
+	return nil
+}
+
+// orderedMap is an object of the response, its fields in the order they were selected.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
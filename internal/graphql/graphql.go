@@ -0,0 +1,216 @@
+// Package graphql serves read-only GraphQL (https://spec.graphql.org) queries against a
+// schema built in code, so a client can fetch nested data in one request instead of one
+// per object.
+//
+// It implements what a dashboard needs rather than the whole specification: queries
+// with variables, aliases, arguments, fragments and the @include and @skip directives.
+// Mutations, subscriptions, interfaces, unions, input objects and introspection (other
+// than __typename) are not supported. Every field is nullable: a field whose resolver
+// fails is null in the response, with the error under "errors".
+//
+// Before running a query, its cost is estimated as the number of objects it may resolve,
+// each list counting as many items as its Size allows, and queries above the schema's
+// MaxCost are refused: nesting lists multiplies their limits.
+package graphql
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Type is a field's type: a Scalar, an *Object or a List.
+type Type interface {
+	typeName() string
+}
+
+// Scalar is a leaf type; its values are encoded in the response as JSON.
+type Scalar string
+
+const (
+	String  Scalar = "String"
+	Int     Scalar = "Int"
+	Float   Scalar = "Float"
+	Boolean Scalar = "Boolean"
+	ID      Scalar = "ID"
+	JSON    Scalar = "JSON" // any JSON value, e.g. metadata
+)
+
+func (s Scalar) typeName() string { return string(s) }
+
+// List is a list of Of.
+type List struct {
+	Of Type
+}
+
+func (l List) typeName() string { return "[" + l.Of.typeName() + "]" }
+
+// ListOf returns the list type of t.
+func ListOf(t Type) List {
+	return List{Of: t}
+}
+
+// Object is a type with fields, which queries select.
+type Object struct {
+	Name   string
+	Fields Fields
+}
+
+func (o *Object) typeName() string { return o.Name }
+
+type Fields map[string]*Field
+
+// Field is a field of an Object. Without Resolve, its value is the field of the source
+// with the same JSON name, or the entry of a map source.
+//
+// Size bounds how many items a list field returns given its arguments, e.g. its limit.
+// On an object field, such as a page of results, it bounds the lists of the object
+// instead. Lists bounded by neither count as defaultListSize items in a query's cost.
+type Field struct {
+	Type    Type
+	Args    Args
+	Resolve func(p Params) (any, error)
+	Size    func(args map[string]any) int
+}
+
+type Args map[string]Arg
+
+// Arg is an argument of a field. Arguments are scalars; Default applies when it is
+// omitted.
+type Arg struct {
+	Type     Scalar
+	Required bool
+	Default  any
+}
+
+// Params are what a resolver gets: the value of the parent object and the field's
+// arguments, coerced to their types (int, float64, string or bool) and defaulted.
+type Params struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+}
+
+// Schema is the query root of a GraphQL API.
+type Schema struct {
+	Query *Object
+
+	// MaxDepth bounds how deeply queries nest fields; 0 means 10
+	MaxDepth int
+
+	// MaxCost bounds how many objects a query may resolve; 0 means 10000
+	MaxCost int
+}
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request failed before
+// execution, e.g. on a syntax error.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"` // of the field that failed, keys and list indexes
+}
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Execute runs the request's operation against the schema.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: "syntax error: " + err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*Error{{Message: op.kind + " operations are not supported"}}}
+	}
+	if errs := s.validate(doc, op); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{ctx: ctx, doc: doc, vars: vars}
+	maxCost := cmp.Or(s.MaxCost, 10000)
+	if e.cost(s.Query, op.selections, 1, defaultListSize, maxCost) > maxCost {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("query may resolve more than %d objects; lower its limits or nesting", maxCost)}}}
+	}
+	data := e.selectionSet(s.Query, nil, op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// maxBody bounds the size of a request.
+const maxBody = 1 << 20
+
+// Handler serves the schema over HTTP: queries are POSTed as a JSON Request, or sent by
+// GET in the query, operationName and variables (JSON) parameters. Responses are 200
+// whenever the request could be parsed, errors included, as usual for GraphQL.
+func Handler(schema *Schema) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+			if v := q.Get("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "variables must be a JSON object"}}})
+					return
+				}
+			}
+		case http.MethodPost:
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+			if err != nil {
+				writeResponse(w, http.StatusRequestEntityTooLarge, &Response{Errors: []*Error{{Message: fmt.Sprintf("request must be at most %d bytes", maxBody)}}})
+				return
+			}
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+				req.Query = string(body)
+			} else if err := json.Unmarshal(body, &req); err != nil {
+				writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "request must be a JSON object with a query"}}})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Query == "" {
+			writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "query required"}}})
+			return
+		}
+		writeResponse(w, http.StatusOK, schema.Execute(r.Context(), req))
+	})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,259 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type book struct {
+	ID       string         `json:"id"`
+	Title    string         `json:"title"`
+	Pages    int            `json:"pages"`
+	Metadata map[string]any `json:"metadata"`
+	author   string
+}
+
+type author struct {
+	Name string `json:"name"`
+}
+
+func testSchema() *Schema {
+	books := []*book{
+		{ID: "1", Title: "Double Entry", Pages: 120, Metadata: map[string]any{"shelf": "a"}, author: "Pacioli"},
+		{ID: "2", Title: "Ledgers", Pages: 300, author: "Nobody"},
+	}
+	authorType := &Object{Name: "Author", Fields: Fields{"name": {Type: String}}}
+	bookType := &Object{Name: "Book", Fields: Fields{
+		"id":       {Type: ID},
+		"title":    {Type: String},
+		"pages":    {Type: Int},
+		"metadata": {Type: JSON},
+		"author": {Type: authorType, Resolve: func(p Params) (any, error) {
+			if name := p.Source.(*book).author; name != "Nobody" {
+				return &author{Name: name}, nil
+			}
+			return nil, errors.New("author unknown")
+		}},
+	}}
+	return &Schema{MaxDepth: 3, Query: &Object{Name: "Query", Fields: Fields{
+		"books": {
+			Type: ListOf(bookType),
+			Args: Args{"limit": {Type: Int, Default: 10}},
+			Resolve: func(p Params) (any, error) {
+				return books[:min(p.Args["limit"].(int), len(books))], nil
+			},
+		},
+		"book": {
+			Type: bookType,
+			Args: Args{"id": {Type: ID, Required: true}},
+			Resolve: func(p Params) (any, error) {
+				for _, b := range books {
+					if b.ID == p.Args["id"] {
+						return b, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+	}}}
+}
+
+func execute(t *testing.T, req Request) string {
+	t.Helper()
+	body, err := json.Marshal(testSchema().Execute(context.Background(), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestExecute(t *testing.T) {
+	cases := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "nested fields in selection order",
+			req:  Request{Query: `{ books { title id author { name } } }`},
+			want: `{"data":{"books":[{"title":"Double Entry","id":"1","author":{"name":"Pacioli"}},{"title":"Ledgers","id":"2","author":null}]},` +
+				`"errors":[{"message":"author unknown","locations":[{"line":1,"column":20}],"path":["books",1,"author"]}]}`,
+		},
+		{
+			name: "aliases, arguments and variables",
+			req: Request{
+				Query:     `query Two($id: ID!, $n: Int = 1) { first: books(limit: $n) { id } second: book(id: $id) { title, __typename } }`,
+				Variables: map[string]any{"id": "2"},
+			},
+			want: `{"data":{"first":[{"id":"1"}],"second":{"title":"Ledgers","__typename":"Book"}}}`,
+		},
+		{
+			name: "fragments and directives",
+			req: Request{
+				Query: `query ($withPages: Boolean!) {
+					book(id: 1) { ...Summary ... on Book @include(if: $withPages) { pages } metadata @skip(if: true) }
+				}
+				fragment Summary on Book { id title }`,
+				Variables: map[string]any{"withPages": true},
+			},
+			want: `{"data":{"book":{"id":"1","title":"Double Entry","pages":120}}}`,
+		},
+		{
+			name: "operation by name",
+			req:  Request{Query: `query A { book(id: "1") { id } } query B { book(id: "2") { id } }`, OperationName: "B"},
+			want: `{"data":{"book":{"id":"2"}}}`,
+		},
+		{
+			name: "block string and JSON scalar",
+			req:  Request{Query: `{ book(id: """1""") { metadata } }`},
+			want: `{"data":{"book":{"metadata":{"shelf":"a"}}}}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := execute(t, c.req); got != c.want {
+				t.Errorf("got  %s\nwant %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRefusesInvalidQueries(t *testing.T) {
+	cases := map[string]string{
+		`{ books { title `:                                     "syntax error",
+		`{ books { isbn } }`:                                   "cannot query field isbn on type Book",
+		`{ books }`:                                            "needs a selection",
+		`{ books { title { x } } }`:                            "has no fields to select",
+		`{ book { id } }`:                                      "requires argument id",
+		`{ books(limit: "ten") { id } }`:                       "is not a valid Int",
+		`{ books(order: ASC) { id } }`:                         "unknown argument order",
+		`{ book(id: $id) { id } }`:                             "variable $id is not declared",
+		`{ books { ...F } } fragment F on Book { ...F }`:       "fragment F spreads itself",
+		`{ books { ...F } }`:                                   "unknown fragment F",
+		`{ books(limit: 1) { author { name } } }`:              "",
+		`{ books { author { name } author2: author { x } } }`:  "cannot query field x",
+		`{ book(id: 1) { author { name } } } { books { id } }`: "operationName required",
+		`mutation { books { id } }`:                            "mutation operations are not supported",
+		`{ books { id @defer } }`:                              "unknown directive @defer",
+		`{ a: book(id: 1) { id } a: books { id } }`:            "a selects both book and books",
+	}
+	for query, want := range cases {
+		got := execute(t, Request{Query: query})
+		if want == "" {
+			if strings.Contains(got, `"errors"`) {
+				t.Errorf("%s: unexpected errors %s", query, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, want) {
+			t.Errorf("%s: got %s, want an error containing %q", query, got, want)
+		}
+	}
+}
+
+func TestExecuteLimitsDepth(t *testing.T) {
+	schema := testSchema()
+	self := &Object{Name: "Node"}
+	self.Fields = Fields{"next": {Type: self, Resolve: func(Params) (any, error) { return map[string]any{}, nil }}, "id": {Type: ID}}
+	schema.Query.Fields["node"] = &Field{Type: self, Resolve: func(Params) (any, error) { return map[string]any{"id": "n"}, nil }}
+
+	resp := schema.Execute(context.Background(), Request{Query: `{ node { next { id } } }`})
+	if len(resp.Errors) != 0 {
+		t.Fatalf("unexpected errors %v", resp.Errors[0])
+	}
+	resp = schema.Execute(context.Background(), Request{Query: `{ node { next { next { id } } } }`})
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "nested more than 3 levels") || resp.Data != nil {
+		t.Fatalf("expected the query to be refused for its depth, got %+v", resp)
+	}
+}
+
+func TestExecuteLimitsCost(t *testing.T) {
+	schema := testSchema()
+	schema.MaxDepth, schema.MaxCost = 4, 100
+	limit := func(args map[string]any) int { return args["limit"].(int) }
+	books := schema.Query.Fields["books"]
+	books.Size = limit
+	bookType := books.Type.(List).Of.(*Object)
+	bookType.Fields["similar"] = &Field{Type: ListOf(bookType), Args: Args{"limit": {Type: Int, Default: 10}}, Size: limit}
+	schema.Query.Fields["page"] = &Field{Type: &Object{Name: "Page", Fields: Fields{"books": {Type: ListOf(bookType)}}},
+		Args: Args{"limit": {Type: Int, Default: 10}}, Size: limit}
+
+	cases := map[string]bool{
+		`{ books(limit: 5) { similar { id } } }`:                                         true,  // 5 + 5×10
+		`{ books { similar { id } } }`:                                                   false, // 10 + 10×10
+		`{ books { similar @skip(if: true) { id } } }`:                                   true,
+		`{ books(limit: 1) { ...S } } fragment S on Book { similar(limit: 500) { id } }`: false,
+		`{ a: books(limit: 5) { id } b: books(limit: 5) { similar(limit: 19) { id } } }`: false, // 5 + 5 + 5×19
+		`{ page(limit: 40) { books { similar(limit: 1) { id } } } }`:                     true,  // 1 + 40 + 40
+		`{ page(limit: 50) { books { similar(limit: 1) { id } } } }`:                     false, // 1 + 50 + 50
+	}
+	for query, allowed := range cases {
+		resp := schema.Execute(context.Background(), Request{Query: query})
+		refused := len(resp.Errors) > 0 && strings.Contains(resp.Errors[0].Message, "more than 100 objects")
+		if refused == allowed || refused && resp.Data != nil {
+			t.Errorf("%s: got %+v", query, resp)
+		}
+	}
+	resp := schema.Execute(context.Background(), Request{Query: `query ($n: Int) { books(limit: $n) { similar { id } } }`,
+		Variables: map[string]any{"n": float64(20)}})
+	if len(resp.Errors) == 0 || resp.Data != nil {
+		t.Errorf("expected the variable limit to count, got %+v", resp)
+	}
+}
+
+func TestVariablesAreCoerced(t *testing.T) {
+	// JSON numbers decode as float64
+	got := execute(t, Request{Query: `query ($n: Int) { books(limit: $n) { id } }`, Variables: map[string]any{"n": float64(1)}})
+	if got != `{"data":{"books":[{"id":"1"}]}}` {
+		t.Errorf("got %s", got)
+	}
+	got = execute(t, Request{Query: `query ($n: Int) { books(limit: $n) { id } }`, Variables: map[string]any{"n": 1.5}})
+	if !strings.Contains(got, "1.5 is not a valid Int") || strings.Contains(got, `"data"`) {
+		t.Errorf("got %s", got)
+	}
+	got = execute(t, Request{Query: `query ($id: ID!) { book(id: $id) { id } }`})
+	if !strings.Contains(got, "variable $id of type ID!: value required") {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(testSchema())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query($id: ID!) { book(id: $id) { title } }","variables":{"id":"1"}}`)))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"data":{"book":{"title":"Double Entry"}}}` {
+		t.Errorf("POST: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql?query=%7Bbooks(limit:1)%7Bid%7D%7D", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"data":{"books":[{"id":"1"}]}}` {
+		t.Errorf("GET: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{ books(limit: 1) { id } }`))
+	req.Header.Set("Content-Type", "application/graphql")
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"data":{"books":[{"id":"1"}]}}` {
+		t.Errorf("application/graphql: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("empty query: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/graphql", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: %d", rec.Code)
+	}
+}
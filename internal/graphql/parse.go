@@ -0,0 +1,652 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed query document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind, name string // kind is query, mutation or subscription
+	vars       []varDef
+	directives []directive
+	selections []selection
+}
+
+type varDef struct {
+	name string
+	typ  typeRef
+	def  any // nil without a default
+}
+
+// typeRef is a variable's declared type, e.g. [String!]!.
+type typeRef struct {
+	name    string
+	elem    *typeRef // set for list types
+	nonNull bool
+}
+
+func (t typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name, on   string
+	selections []selection
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias, name string
+	args        []argument
+	directives  []directive
+	selections  []selection
+	line, col   int
+}
+
+// key is the field's name in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []directive
+}
+
+type inlineFragment struct {
+	on         string // empty without a type condition
+	directives []directive
+	selections []selection
+}
+
+type argument struct {
+	name  string
+	value any
+}
+
+type directive struct {
+	name string
+	args []argument
+}
+
+// Values are parsed into int, float64, string, bool, nil, enum, variable, []any and
+// map[string]any.
+type (
+	enum     string
+	variable string
+)
+
+// maxTokens bounds the documents parse accepts, against crafted queries.
+const maxTokens = 10000
+
+type token struct {
+	kind      tokenKind
+	value     string
+	line, col int
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+func lex(src string) ([]token, error) {
+	src = strings.TrimPrefix(src, "\ufeff")
+	var tokens []token
+	line, lineStart := 1, 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		col := i - lineStart + 1
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+			continue
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		if len(tokens) >= maxTokens {
+			return nil, fmt.Errorf("query has more than %d tokens", maxTokens)
+		}
+
+		switch {
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{tokenPunct, "...", line, col})
+			i += 3
+		case strings.ContainsRune("!$&():=@[]{}|", rune(c)):
+			tokens = append(tokens, token{tokenPunct, string(c), line, col})
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenName, src[start:i], line, col})
+		case c == '-' || isDigit(c):
+			start, kind := i, tokenInt
+			if c == '-' {
+				i++
+			}
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				kind = tokenFloat
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = tokenFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind, src[start:i], line, col})
+		case strings.HasPrefix(src[i:], `"""`):
+			end := i + 3
+			for end < len(src) && !strings.HasPrefix(src[end:], `"""`) {
+				if strings.HasPrefix(src[end:], `\"""`) {
+					end += 3
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("%d:%d: unterminated block string", line, col)
+			}
+			raw := src[i+3 : end]
+			tokens = append(tokens, token{tokenString, blockString(raw), line, col})
+			line += strings.Count(raw, "\n")
+			if n := strings.LastIndexByte(raw, '\n'); n >= 0 {
+				lineStart = i + 3 + n + 1
+			}
+			i = end + 3
+		case c == '"':
+			s, n, err := quotedString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%d:%d: %w", line, col, err)
+			}
+			tokens = append(tokens, token{tokenString, s, line, col})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("%d:%d: unexpected character %q", line, col, r)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, line: line, col: len(src) - lineStart + 1}), nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// quotedString decodes the string literal src starts with and returns its length.
+func quotedString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '\\':
+			if i+1 >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch e := src[i+1]; e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+6 > len(src) {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(src[i+2:i+6], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape %q", src[i:i+6])
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", 0, fmt.Errorf("invalid escape \\%c", e)
+			}
+			i += 2
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// blockString is the value of a """block string""": escaped quotes restored, common
+// indentation and blank first and last lines removed.
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, `\"""`, `"""`), "\n")
+	indent := -1
+	for _, l := range lines[1:] {
+		trimmed := strings.TrimLeft(l, " \t")
+		if trimmed != "" && (indent < 0 || len(l)-len(trimmed) < indent) {
+			indent = len(l) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses an executable document: operations and fragments.
+func parse(src string) (*document, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.peek().kind != tokenEOF {
+		switch t := p.peek(); {
+		case t.kind == tokenPunct && t.value == "{":
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sel})
+		case t.kind == tokenName && (t.value == "query" || t.value == "mutation" || t.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == tokenName && t.value == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[f.name]; dup {
+				return nil, fmt.Errorf("fragment %s defined twice", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("%d:%d: unexpected end of query", t.line, t.col)
+	}
+	return fmt.Errorf("%d:%d: unexpected %q", t.line, t.col, t.value)
+}
+
+// skip consumes the punctuator punct if it comes next.
+func (p *parser) skip(punct string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.value == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.skip(punct) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	if p.peek().kind != tokenName {
+		return "", p.unexpected()
+	}
+	return p.next().value, nil
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.next().value}
+	if p.peek().kind == tokenName {
+		op.name = p.next().value
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			typ, err := p.typeRef()
+			if err != nil {
+				return nil, err
+			}
+			v := varDef{name: name, typ: typ}
+			if p.skip("=") {
+				if v.def, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	var err error
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) typeRef() (typeRef, error) {
+	var t typeRef
+	if p.skip("[") {
+		elem, err := p.typeRef()
+		if err != nil {
+			return t, err
+		}
+		if err := p.expect("]"); err != nil {
+			return t, err
+		}
+		t.elem = &elem
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return t, err
+		}
+		t.name = name
+	}
+	t.nonNull = p.skip("!")
+	return t, nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	p.next()
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("fragment cannot be named on")
+	}
+	if t := p.peek(); t.kind != tokenName || t.value != "on" {
+		return nil, p.unexpected()
+	}
+	p.next()
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, on: on, selections: sel}, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.skip("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return selections, nil
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.skip("...") {
+		if t := p.peek(); t.kind == tokenName && t.value != "on" {
+			spread := &fragmentSpread{name: p.next().value}
+			var err error
+			spread.directives, err = p.directives()
+			return spread, err
+		}
+		inline := &inlineFragment{}
+		if t := p.peek(); t.kind == tokenName && t.value == "on" {
+			p.next()
+			on, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			inline.on = on
+		}
+		var err error
+		if inline.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		if inline.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+		return inline, nil
+	}
+
+	t := p.peek()
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name, line: t.line, col: t.col}
+	if p.skip(":") {
+		f.alias = name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenPunct && t.value == "{" {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments(constant bool) ([]argument, error) {
+	if !p.skip("(") {
+		return nil, nil
+	}
+	var args []argument
+	for !p.skip(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range args {
+			if a.name == name {
+				return nil, fmt.Errorf("argument %s given twice", name)
+			}
+		}
+		args = append(args, argument{name, value})
+	}
+	return args, nil
+}
+
+func (p *parser) directives() ([]directive, error) {
+	var directives []directive
+	for p.skip("@") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive{name, args})
+	}
+	return directives, nil
+}
+
+// value parses a value; constant ones, e.g. variable defaults, can't use variables.
+func (p *parser) value(constant bool) (any, error) {
+	start := p.pos
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		n, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, fmt.Errorf("%d:%d: invalid int %s", t.line, t.col, t.value)
+		}
+		return n, nil
+	case tokenFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%d:%d: invalid float %s", t.line, t.col, t.value)
+		}
+		return f, nil
+	case tokenString:
+		return t.value, nil
+	case tokenName:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enum(t.value), nil
+	case tokenPunct:
+		switch t.value {
+		case "$":
+			if constant {
+				break
+			}
+			name, err := p.name()
+			return variable(name), err
+		case "[":
+			list := []any{}
+			for !p.skip("]") {
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, nil
+		case "{":
+			object := map[string]any{}
+			for !p.skip("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if object[name], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+			return object, nil
+		}
+	}
+	p.pos = start
+	return nil, p.unexpected()
+}
@@ -0,0 +1,204 @@
+// Package hooks lets deployments attach their own logic to the posting pipeline without
+// changing the ledger service. A transaction goes through three stages:
+//
+//   - pre-validate, before the ledger's checks: hooks may reject the transaction or
+//     enrich it, e.g. with metadata or fee postings, which the checks then cover. Dry
+//     runs call them too, so they must not have side effects.
+//   - pre-commit, after the checks and screening, before the transaction is appended:
+//     hooks may still reject it.
+//   - post-commit, once the transaction is durable: hooks are told about it, e.g. to
+//     notify another system, and can no longer refuse it.
+//
+// Hooks are registered in code, or served out of process by plugins over gRPC (see
+// Plugin).
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type Stage string
+
+const (
+	StagePreValidate Stage = "pre-validate"
+	StagePreCommit   Stage = "pre-commit"
+	StagePostCommit  Stage = "post-commit"
+)
+
+// Transaction is a transaction as hooks see it, with its script already compiled into
+// postings.
+type Transaction struct {
+	LedgerID       string         `json:"ledger_id"`
+	TransactionID  string         `json:"transaction_id,omitempty"` // set post-commit
+	IdempotencyKey string         `json:"idempotency_key"`
+	ExternalID     string         `json:"external_id"`
+	Description    string         `json:"description,omitempty"`
+	Currency       string         `json:"currency"`
+	OccurredAt     time.Time      `json:"occurred_at"`
+	ValueDate      string         `json:"value_date"` // YYYY-MM-DD
+	EntityCode     string         `json:"entity_code,omitempty"`
+	Postings       []Posting      `json:"postings"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+}
+
+type Posting struct {
+	Account     string `json:"account"`
+	Direction   string `json:"direction"` // debit or credit
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency,omitempty"`
+	TaxCode     string `json:"tax_code,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PreValidateHook may change the transaction's description, entity, postings and
+// metadata; changes to other fields are ignored.
+type PreValidateHook interface {
+	PreValidate(ctx context.Context, t *Transaction) error
+}
+
+type PreCommitHook interface {
+	PreCommit(ctx context.Context, t Transaction) error
+}
+
+type PostCommitHook interface {
+	PostCommit(ctx context.Context, t Transaction) error
+}
+
+// PreValidateFunc adapts a function to PreValidateHook.
+type PreValidateFunc func(ctx context.Context, t *Transaction) error
+
+func (f PreValidateFunc) PreValidate(ctx context.Context, t *Transaction) error {
+	return f(ctx, t)
+}
+
+// PreCommitFunc adapts a function to PreCommitHook.
+type PreCommitFunc func(ctx context.Context, t Transaction) error
+
+func (f PreCommitFunc) PreCommit(ctx context.Context, t Transaction) error {
+	return f(ctx, t)
+}
+
+// PostCommitFunc adapts a function to PostCommitHook.
+type PostCommitFunc func(ctx context.Context, t Transaction) error
+
+func (f PostCommitFunc) PostCommit(ctx context.Context, t Transaction) error {
+	return f(ctx, t)
+}
+
+// RejectionError refuses a transaction. Hooks return it through Reject; any other error
+// is a hook failing to decide (see FailureError).
+type RejectionError struct {
+	Hook   string
+	Stage  Stage
+	Reason string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("rejected by hook %s at %s: %s", e.Hook, e.Stage, e.Reason)
+}
+
+// Reject returns the error a hook refuses a transaction with.
+func Reject(reason string) error {
+	return &RejectionError{Reason: reason}
+}
+
+// FailureError is a hook that could not decide, e.g. a plugin that can't be reached. The
+// transaction is refused all the same.
+type FailureError struct {
+	Hook  string
+	Stage Stage
+	Err   error
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("hook %s failed at %s: %v", e.Hook, e.Stage, e.Err)
+}
+
+func (e *FailureError) Unwrap() error {
+	return e.Err
+}
+
+type named[H any] struct {
+	name string
+	hook H
+}
+
+// Registry runs hooks stage by stage, in the order they were registered. Register them
+// all before the service posts; its methods do nothing on a nil Registry.
+type Registry struct {
+	preValidate []named[PreValidateHook]
+	preCommit   []named[PreCommitHook]
+	postCommit  []named[PostCommitHook]
+}
+
+// Register adds hook to every stage whose interface it implements.
+func (r *Registry) Register(name string, hook any) error {
+	registered := false
+	if h, ok := hook.(PreValidateHook); ok {
+		r.preValidate = append(r.preValidate, named[PreValidateHook]{name, h})
+		registered = true
+	}
+	if h, ok := hook.(PreCommitHook); ok {
+		r.preCommit = append(r.preCommit, named[PreCommitHook]{name, h})
+		registered = true
+	}
+	if h, ok := hook.(PostCommitHook); ok {
+		r.postCommit = append(r.postCommit, named[PostCommitHook]{name, h})
+		registered = true
+	}
+	if !registered {
+		return fmt.Errorf("hook %s implements no stage", name)
+	}
+	return nil
+}
+
+// PreValidate runs the pre-validate hooks until one refuses the transaction.
+func (r *Registry) PreValidate(ctx context.Context, t *Transaction) error {
+	if r == nil {
+		return nil
+	}
+	for _, h := range r.preValidate {
+		if err := h.hook.PreValidate(ctx, t); err != nil {
+			return stageError(h.name, StagePreValidate, err)
+		}
+	}
+	return nil
+}
+
+// PreCommit runs the pre-commit hooks until one refuses the transaction.
+func (r *Registry) PreCommit(ctx context.Context, t Transaction) error {
+	if r == nil {
+		return nil
+	}
+	for _, h := range r.preCommit {
+		if err := h.hook.PreCommit(ctx, t); err != nil {
+			return stageError(h.name, StagePreCommit, err)
+		}
+	}
+	return nil
+}
+
+// PostCommit runs every post-commit hook. The transaction is committed already, so
+// failures are only logged.
+func (r *Registry) PostCommit(ctx context.Context, t Transaction) {
+	if r == nil {
+		return
+	}
+	for _, h := range r.postCommit {
+		if err := h.hook.PostCommit(ctx, t); err != nil {
+			log.Printf("hook %s: post-commit of transaction %s: %v", h.name, t.TransactionID, err)
+		}
+	}
+}
+
+func stageError(hook string, stage Stage, err error) error {
+	var rejection *RejectionError
+	if errors.As(err, &rejection) {
+		return &RejectionError{Hook: hook, Stage: stage, Reason: rejection.Reason}
+	}
+	return &FailureError{Hook: hook, Stage: stage, Err: err}
+}
@@ -0,0 +1,73 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// both is a pre-validate and post-commit hook
+type both struct {
+	posted []string
+}
+
+func (b *both) PreValidate(ctx context.Context, t *Transaction) error {
+	if t.Metadata == nil {
+		t.Metadata = map[string]any{}
+	}
+	t.Metadata["enriched"] = true
+	return nil
+}
+
+func (b *both) PostCommit(ctx context.Context, t Transaction) error {
+	b.posted = append(b.posted, t.TransactionID)
+	return errors.New("ignored")
+}
+
+func TestRegistry(t *testing.T) {
+	var none *Registry
+	if err := none.PreValidate(context.Background(), &Transaction{}); err != nil {
+		t.Fatalf("nil registry: %v", err)
+	}
+
+	r := &Registry{}
+	if err := r.Register("nothing", struct{}{}); err == nil {
+		t.Fatal("expected a hook of no stage to be refused")
+	}
+	b := &both{}
+	if err := r.Register("enrich", b); err != nil {
+		t.Fatal(err)
+	}
+	r.Register("limit", PreCommitFunc(func(ctx context.Context, t Transaction) error {
+		if len(t.Postings) > 2 {
+			return Reject("too many postings")
+		}
+		return nil
+	}))
+	r.Register("broken", PreCommitFunc(func(ctx context.Context, t Transaction) error {
+		return errors.New("unreachable")
+	}))
+
+	tx := Transaction{Postings: make([]Posting, 3)}
+	if err := r.PreValidate(context.Background(), &tx); err != nil || tx.Metadata["enriched"] != true {
+		t.Fatalf("expected enrichment, got %v %v", tx.Metadata, err)
+	}
+
+	var rejection *RejectionError
+	err := r.PreCommit(context.Background(), tx)
+	if !errors.As(err, &rejection) || rejection.Hook != "limit" || rejection.Stage != StagePreCommit || rejection.Reason != "too many postings" {
+		t.Fatalf("expected limit to reject, got %v", err)
+	}
+
+	var failure *FailureError
+	tx.Postings = tx.Postings[:2]
+	if err := r.PreCommit(context.Background(), tx); !errors.As(err, &failure) || failure.Hook != "broken" {
+		t.Fatalf("expected broken to fail, got %v", err)
+	}
+
+	tx.TransactionID = "t1"
+	r.PostCommit(context.Background(), tx)
+	if len(b.posted) != 1 || b.posted[0] != "t1" {
+		t.Fatalf("expected post-commit to run, got %v", b.posted)
+	}
+}
@@ -0,0 +1,137 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The gRPC service plugins serve; see api/hooks.proto.
+const service = "/ledger.hooks.v1.Hooks/"
+
+// Plugin is a hook served out of process over gRPC. Requests and responses are
+// google.protobuf.Struct messages holding a Transaction as JSON, so plugins need only the
+// well-known types, in any language. A plugin serves the stages it wants and answers
+// UNIMPLEMENTED for the others, which lets transactions through.
+//
+// A plugin refuses a transaction by answering FAILED_PRECONDITION, INVALID_ARGUMENT or
+// PERMISSION_DENIED with the reason as the status message.
+type Plugin struct {
+	Name    string
+	Timeout time.Duration // of each call
+
+	// FailOpen lets transactions through when the plugin fails to answer, instead of
+	// refusing them
+	FailOpen bool
+
+	conn *grpc.ClientConn
+}
+
+// Dial connects to the plugin at target, without TLS unless opts set credentials: run
+// plugins next to the ledger, e.g. as sidecars.
+func Dial(name, target string, opts ...grpc.DialOption) (*Plugin, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Plugin{Name: name, Timeout: 5 * time.Second, conn: conn}, nil
+}
+
+// DialPlugins registers a Plugin for each name=target of targets, in order.
+func (r *Registry) DialPlugins(targets []string, timeout time.Duration, failOpen bool) error {
+	for _, entry := range targets {
+		name, target, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || target == "" {
+			return fmt.Errorf("plugin %q must be name=target", entry)
+		}
+		p, err := Dial(name, target)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", name, err)
+		}
+		p.Timeout, p.FailOpen = timeout, failOpen
+		if err := r.Register(name, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) Close() error {
+	return p.conn.Close()
+}
+
+// PreValidate replaces t with the transaction the plugin answers, unless it answers an
+// empty struct.
+func (p *Plugin) PreValidate(ctx context.Context, t *Transaction) error {
+	resp, err := p.call(ctx, "PreValidate", *t)
+	if err != nil || len(resp.GetFields()) == 0 {
+		return err
+	}
+	body, err := json.Marshal(resp.AsMap())
+	if err != nil {
+		return err
+	}
+	var changed Transaction
+	if err := json.Unmarshal(body, &changed); err != nil {
+		return err
+	}
+	*t = changed
+	return nil
+}
+
+func (p *Plugin) PreCommit(ctx context.Context, t Transaction) error {
+	_, err := p.call(ctx, "PreCommit", t)
+	return err
+}
+
+func (p *Plugin) PostCommit(ctx context.Context, t Transaction) error {
+	_, err := p.call(ctx, "PostCommit", t)
+	return err
+}
+
+// call invokes method with t, turning the plugin's refusals into a RejectionError.
+func (p *Plugin) call(ctx context.Context, method string, t Transaction) (*structpb.Struct, error) {
+	body, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	req, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	resp := &structpb.Struct{}
+	err = p.conn.Invoke(ctx, service+method, req, resp)
+	switch status.Code(err) {
+	case codes.OK:
+		return resp, nil
+	case codes.Unimplemented:
+		return nil, nil
+	case codes.FailedPrecondition, codes.InvalidArgument, codes.PermissionDenied:
+		return nil, Reject(status.Convert(err).Message())
+	}
+	if p.FailOpen {
+		log.Printf("hook %s: %s failed, letting the transaction through: %v", p.Name, method, err)
+		return nil, nil
+	}
+	return nil, err
+}
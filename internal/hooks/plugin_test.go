@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// servePlugin serves handlers as the Hooks service of api/hooks.proto, in memory.
+func servePlugin(t *testing.T, handlers map[string]func(*structpb.Struct) (*structpb.Struct, error)) *Plugin {
+	desc := grpc.ServiceDesc{ServiceName: "ledger.hooks.v1.Hooks", HandlerType: (*any)(nil)}
+	for name, handle := range handlers {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &structpb.Struct{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return handle(req)
+			},
+		})
+	}
+	server := grpc.NewServer()
+	server.RegisterService(&desc, struct{}{})
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	p, err := Dial("test", "passthrough:///bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestPlugin(t *testing.T) {
+	p := servePlugin(t, map[string]func(*structpb.Struct) (*structpb.Struct, error){
+		"PreValidate": func(req *structpb.Struct) (*structpb.Struct, error) {
+			req.Fields["description"] = structpb.NewStringValue("enriched")
+			return req, nil
+		},
+		"PreCommit": func(req *structpb.Struct) (*structpb.Struct, error) {
+			if req.Fields["currency"].GetStringValue() != "USD" {
+				return nil, status.Error(codes.FailedPrecondition, "USD only")
+			}
+			return &structpb.Struct{}, nil
+		},
+	})
+	ctx := context.Background()
+
+	tx := Transaction{LedgerID: "l1", Currency: "EUR", Postings: []Posting{{Account: "cash", Direction: "debit", Amount: "10"}}}
+	if err := p.PreValidate(ctx, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Description != "enriched" || tx.LedgerID != "l1" || len(tx.Postings) != 1 || tx.Postings[0].Amount != "10" {
+		t.Fatalf("unexpected transaction after pre-validate: %+v", tx)
+	}
+
+	var rejection *RejectionError
+	if err := p.PreCommit(ctx, tx); !errors.As(err, &rejection) || rejection.Reason != "USD only" {
+		t.Fatalf("expected a rejection, got %v", err)
+	}
+	tx.Currency = "USD"
+	if err := p.PreCommit(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stages the plugin doesn't serve let the transaction through
+	if err := p.PostCommit(ctx, tx); err != nil {
+		t.Fatalf("expected an unimplemented stage to pass, got %v", err)
+	}
+}
+
+func TestPluginFailOpen(t *testing.T) {
+	p := servePlugin(t, map[string]func(*structpb.Struct) (*structpb.Struct, error){
+		"PreCommit": func(*structpb.Struct) (*structpb.Struct, error) {
+			return nil, status.Error(codes.Internal, "database down")
+		},
+	})
+	if err := p.PreCommit(context.Background(), Transaction{}); err == nil {
+		t.Fatal("expected a failing plugin to refuse")
+	}
+	p.FailOpen = true
+	if err := p.PreCommit(context.Background(), Transaction{}); err != nil {
+		t.Fatalf("expected fail-open to let through, got %v", err)
+	}
+}
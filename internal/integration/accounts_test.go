@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/testutil"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCreateAccountsBatch(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	cash := f.Account(l.ID, "cash", "asset")
+
+	var accounts []ledger.CreateAccountRequest
+	for i := range 500 {
+		accounts = append(accounts, ledger.CreateAccountRequest{Code: fmt.Sprintf("wallets:%d", i), Name: "wallet", Type: "liability"})
+	}
+	accounts = append(accounts, ledger.CreateAccountRequest{Code: "cash", Type: "asset"})
+
+	resp, err := f.Service.CreateAccounts(ctx, l.ID, accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Created != 500 {
+		t.Fatalf("expected 500 accounts created, got %d", resp.Created)
+	}
+	if last := resp.Results[500]; last.Status != "exists" || last.ID != cash.ID {
+		t.Fatalf("expected the existing account to be reported, got %+v", last)
+	}
+
+	// One invalid item keeps the whole batch out
+	resp, err = f.Service.CreateAccounts(ctx, l.ID, []ledger.CreateAccountRequest{
+		{Code: "fees", Type: "revenue"},
+		{Code: "fees", Type: "revenue"},
+		{Code: "tax", Type: "liability", TaxCode: "missing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := []string{resp.Results[0].Status, resp.Results[1].Status, resp.Results[2].Status}
+	if resp.Created != 0 || statuses[0] != "skipped" || statuses[1] != "invalid" || statuses[2] != "invalid" {
+		t.Fatalf("expected the batch to be rejected, got %d created and %v", resp.Created, statuses)
+	}
+	var n int
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM accounts WHERE ledger_id = $1 AND code = 'fees'`, l.ID).Scan(&n)
+	if n != 0 {
+		t.Fatal("expected no account from a rejected batch")
+	}
+}
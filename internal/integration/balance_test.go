@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/projector"
+	"Go_FormanceLegder/internal/testutil"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestBalanceChecksCountUnprojectedEvents checks that minimum balances and holds are
+// enforced against the events already committed, not only the projected balance: the
+// projector is not running until the end, so every check reads a stale accounts row.
+func TestBalanceChecksCountUnprojectedEvents(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "world", "equity")
+	f.Account(l.ID, "wallet", "liability")
+	if _, err := pool.Exec(ctx, `UPDATE accounts SET allow_negative_balance = FALSE WHERE ledger_id = $1 AND code = 'wallet'`, l.ID); err != nil {
+		t.Fatal(err)
+	}
+	f.Transfer(l.ID, "world", "wallet", "100")
+
+	spend := func(key, amount string) error {
+		_, err := f.Service.PostTransaction(ctx, ledger.PostTransactionCommand{
+			LedgerID: l.ID, IdempotencyKey: key, Currency: l.Currency, OccurredAt: time.Now(),
+			Postings: []ledger.PostingInput{
+				{AccountCode: "wallet", Direction: "debit", Amount: amount},
+				{AccountCode: "world", Direction: "credit", Amount: amount},
+			},
+		})
+		return err
+	}
+
+	// Concurrent debits of 30 out of 100: only three fit
+	var wg sync.WaitGroup
+	var posted, refused atomic.Int32
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var insufficient *ledger.InsufficientFundsError
+			switch err := spend(fmt.Sprintf("spend-%d", i), "30"); {
+			case err == nil:
+				posted.Add(1)
+			case errors.As(err, &insufficient):
+				refused.Add(1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if posted.Load() != 3 || refused.Load() != 3 {
+		t.Fatalf("expected 3 debits posted and 3 refused, got %d and %d", posted.Load(), refused.Load())
+	}
+
+	// 10 left: a hold reserves it, so it can neither be spent nor held again
+	hold := func(amount string) (string, error) {
+		return f.Service.CreateHold(ctx, ledger.HoldCommand{LedgerID: l.ID, AccountCode: "wallet",
+			DestinationCode: "world", Amount: amount, Currency: l.Currency})
+	}
+	if _, err := hold("10.01"); err == nil {
+		t.Fatal("expected a hold above the available balance to be refused")
+	}
+	holdID, err := hold("10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := spend("after-hold", "1"); err == nil {
+		t.Fatal("expected held funds not to be spendable")
+	}
+	if _, err := hold("1"); err == nil {
+		t.Fatal("expected held funds not to be held twice")
+	}
+
+	// Capturing the hold spends what it reserved
+	if _, err := f.Service.CaptureHold(ctx, l.ID, holdID, "4"); err != nil {
+		t.Fatalf("expected the capture to pass: %v", err)
+	}
+	if err := spend("after-capture", "6"); err != nil {
+		t.Fatalf("expected the rest of the released hold to be spendable: %v", err)
+	}
+	if err := spend("overdraw", "0.01"); err == nil {
+		t.Fatal("expected the wallet to be empty")
+	}
+
+	// Assertions read the same balances
+	_, err = f.Service.PostTransaction(ctx, ledger.PostTransactionCommand{
+		LedgerID: l.ID, IdempotencyKey: "asserted", Currency: l.Currency, OccurredAt: time.Now(),
+		Postings: []ledger.PostingInput{
+			{AccountCode: "world", Direction: "debit", Amount: "1"},
+			{AccountCode: "wallet", Direction: "credit", Amount: "1"},
+		},
+		Assertions: []ledger.BalanceAssertion{{Account: "wallet", BalanceEQ: "1"}},
+	})
+	if err != nil {
+		t.Fatalf("expected the assertion to see the unprojected debits: %v", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	go projector.NewProjector(pool, projector.Ledger).Run(runCtx)
+	for {
+		var balance, held string
+		pool.QueryRow(ctx, `SELECT balance::text, held_balance::text FROM accounts WHERE ledger_id = $1 AND code = 'wallet'`,
+			l.ID).Scan(&balance, &held)
+		if balance == "1.0000000000" && held == "0.0000000000" {
+			break
+		}
+		if runCtx.Err() != nil {
+			t.Fatalf("projector did not catch up: balance %s, held %s", balance, held)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := spend("projected", "1.01"); err == nil {
+		t.Fatal("expected the projected balance to be enforced")
+	}
+	if err := spend("projected-2", "1"); err != nil {
+		t.Fatalf("expected the projected balance to be spendable: %v", err)
+	}
+}
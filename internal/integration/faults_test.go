@@ -0,0 +1,217 @@
+//go:build faults
+
+package integration
+
+import (
+	"Go_FormanceLegder/internal/faults"
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/projector"
+	"Go_FormanceLegder/internal/testutil"
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+)
+
+// Run with: go test -tags faults ./internal/integration -run Fault
+
+func TestFaultIdempotencyUnderDroppedConnections(t *testing.T) {
+	ctx := context.Background()
+	pool, service, faultLedgerID := setupFaultEnv(t)
+
+	faults.Set(faults.Config{DBDropRate: 0.3})
+	defer faults.Reset()
+
+	cmd := ledger.PostTransactionCommand{
+		LedgerID:       faultLedgerID,
+		ExternalID:     "fault-order-1",
+		IdempotencyKey: "fault-idempotency-1",
+		Currency:       "USD",
+		OccurredAt:     time.Now(),
+		Postings: []ledger.PostingInput{
+			{AccountCode: "cash", Direction: "debit", Amount: "100.00"},
+			{AccountCode: "revenue", Direction: "credit", Amount: "100.00"},
+		},
+	}
+
+	// A client retrying until it gets an answer, then retrying again anyway
+	var ids []string
+	for attempt := 0; attempt < 50 && len(ids) < 5; attempt++ {
+		id, err := service.PostTransaction(ctx, cmd)
+		if err != nil {
+			if !errors.Is(err, faults.ErrInjected) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) < 5 {
+		t.Fatalf("too few successful posts under faults: %d", len(ids))
+	}
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("retries returned different transaction ids: %v", ids)
+		}
+	}
+
+	faults.Reset()
+
+	var events, jobs int
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM events WHERE idempotency_key = $1`, cmd.IdempotencyKey).Scan(&events)
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM river_job WHERE kind = 'webhook_delivery'`).Scan(&jobs)
+	if events != 1 || jobs != 1 {
+		t.Fatalf("expected exactly 1 event and 1 webhook job, got %d and %d", events, jobs)
+	}
+}
+
+func TestFaultProjectorRetriesFailedBatches(t *testing.T) {
+	ctx := context.Background()
+	pool, service, faultLedgerID := setupFaultEnv(t)
+
+	for i := 0; i < 5; i++ {
+		_, err := service.PostTransaction(ctx, ledger.PostTransactionCommand{
+			LedgerID:       faultLedgerID,
+			ExternalID:     fmt.Sprintf("fault-proj-%d", i),
+			IdempotencyKey: fmt.Sprintf("fault-proj-%d", i),
+			Currency:       "USD",
+			OccurredAt:     time.Now(),
+			Postings: []ledger.PostingInput{
+				{AccountCode: "cash", Direction: "debit", Amount: "100.00"},
+				{AccountCode: "revenue", Direction: "credit", Amount: "100.00"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to post transaction: %v", err)
+		}
+	}
+
+	faults.Set(faults.Config{ProjectorFailRate: 0.7})
+	defer faults.Reset()
+
+	runCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	go projector.NewProjector(pool, projector.Ledger).Run(runCtx)
+
+	for {
+		var projected int
+		pool.QueryRow(ctx, `SELECT COUNT(*) FROM transactions WHERE ledger_id = $1`, faultLedgerID).Scan(&projected)
+		if projected == 5 {
+			break
+		}
+		if runCtx.Err() != nil {
+			t.Fatalf("projector did not catch up under faults: %d/5 transactions", projected)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	// Failed batches are rolled back entirely, so nothing is applied twice
+	var postings int
+	var cash, revenue string
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM postings WHERE ledger_id = $1`, faultLedgerID).Scan(&postings)
+	pool.QueryRow(ctx, `SELECT balance::text FROM accounts WHERE ledger_id = $1 AND code = 'cash'`, faultLedgerID).Scan(&cash)
+	pool.QueryRow(ctx, `SELECT balance::text FROM accounts WHERE ledger_id = $1 AND code = 'revenue'`, faultLedgerID).Scan(&revenue)
+	if postings != 10 {
+		t.Fatalf("expected 10 postings, got %d", postings)
+	}
+	if cash != "-500.0000000000" || revenue != "500.0000000000" {
+		t.Fatalf("unexpected balances cash=%s revenue=%s", cash, revenue)
+	}
+}
+
+func TestFaultWebhookRetryWithSlowEndpoint(t *testing.T) {
+	ctx := context.Background()
+	pool, service, faultLedgerID := setupFaultEnv(t)
+
+	receiver := testutil.NewWebhookReceiver(t, "secret", http.StatusServiceUnavailable)
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO webhook_endpoints (ledger_id, url, secret) VALUES ($1, $2, 'secret')
+	`, faultLedgerID, receiver.URL)
+	if err != nil {
+		t.Fatalf("failed to seed webhook endpoint: %v", err)
+	}
+
+	_, err = service.PostTransaction(ctx, ledger.PostTransactionCommand{
+		LedgerID:       faultLedgerID,
+		ExternalID:     "fault-webhook-1",
+		IdempotencyKey: "fault-webhook-1",
+		Currency:       "USD",
+		OccurredAt:     time.Now(),
+		Postings: []ledger.PostingInput{
+			{AccountCode: "cash", Direction: "debit", Amount: "10.00"},
+			{AccountCode: "revenue", Direction: "credit", Amount: "10.00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to post transaction: %v", err)
+	}
+
+	var eventID string
+	pool.QueryRow(ctx, `SELECT id FROM events WHERE idempotency_key = 'fault-webhook-1'`).Scan(&eventID)
+
+	faults.Set(faults.Config{WebhookDelay: 300 * time.Millisecond})
+	defer faults.Reset()
+
+	immediateRetries(t)
+	worker := webhook.NewWorker(pool)
+	work := func(attempt int) error {
+		return worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+			JobRow: &rivertype.JobRow{Attempt: attempt},
+			Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: faultLedgerID},
+		})
+	}
+
+	start := time.Now()
+	if err := work(1); err == nil {
+		t.Fatal("expected first attempt to fail with a retryable error")
+	}
+	if time.Since(start) < 300*time.Millisecond {
+		t.Fatal("expected the injected webhook delay to apply")
+	}
+	if err := work(2); err != nil {
+		t.Fatalf("expected retry to succeed: %v", err)
+	}
+	// A duplicate run of an already delivered job must not call the endpoint again
+	if err := work(3); err != nil {
+		t.Fatalf("expected duplicate run to be a no-op: %v", err)
+	}
+	if receiver.Count() != 2 {
+		t.Fatalf("expected 2 endpoint calls, got %d", receiver.Count())
+	}
+
+	var statuses []string
+	rows, _ := pool.Query(ctx, `SELECT status FROM webhook_deliveries WHERE event_id = $1 ORDER BY attempt`, eventID)
+	for rows.Next() {
+		var s string
+		rows.Scan(&s)
+		statuses = append(statuses, s)
+	}
+	rows.Close()
+	if strings.Join(statuses, ",") != "retryable_error,success" {
+		t.Fatalf("unexpected delivery log: %v", statuses)
+	}
+}
+
+// setupFaultEnv returns a fresh database with a ledger holding cash and revenue accounts.
+func setupFaultEnv(t *testing.T) (*pgxpool.Pool, *ledger.Service, string) {
+	t.Helper()
+
+	// testutil.NewDB connects through db.NewPool, which installs the connection-drop hook
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	return pool, f.Service, l.ID
+}
@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/projector"
+	"Go_FormanceLegder/internal/testutil"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestTenantIsolation checks that a ledger's API key cannot read, or learn the existence
+// of, another ledger's data: IDs and codes of the other ledger answer exactly like IDs
+// and codes that exist nowhere, and reused idempotency keys are independent per ledger.
+func TestTenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	p := f.Project("")
+	a := f.LedgerIn(p, "USD")
+	b := f.LedgerIn(p, "USD")
+	c := f.LedgerIn(p, "USD")
+	for _, l := range []testutil.Ledger{a, b, c} {
+		f.Account(l.ID, "cash", "asset")
+		f.Account(l.ID, "revenue", "revenue")
+		f.Account(l.ID, "due_to", "liability")
+		f.Account(l.ID, "due_from", "asset")
+	}
+	f.Account(a.ID, "only-in-a", "asset")
+
+	post := func(ledgerID, key string) string {
+		return f.Transaction(ledger.PostTransactionCommand{
+			LedgerID:       ledgerID,
+			ExternalID:     "order-1",
+			IdempotencyKey: key,
+			Postings: []ledger.PostingInput{
+				{AccountCode: "cash", Direction: "debit", Amount: "10.00"},
+				{AccountCode: "revenue", Direction: "credit", Amount: "10.00"},
+			},
+		})
+	}
+	txA := post(a.ID, "key-1")
+	txB := post(b.ID, "key-1")
+	if txA == txB {
+		t.Fatal("an idempotency key used by another ledger returned that ledger's transaction")
+	}
+	if again := post(b.ID, "key-1"); again != txB {
+		t.Fatalf("retry in the same ledger: got %s, want %s", again, txB)
+	}
+
+	transfer := func(from, to testutil.Ledger, key string) ledger.Transfer {
+		tr, err := f.Service.PostTransfer(ctx, ledger.TransferCommand{
+			ProjectID:                  p.ID,
+			IdempotencyKey:             key,
+			Amount:                     "5.00",
+			Currency:                   "USD",
+			SourceLedgerID:             from.ID,
+			SourceAccount:              "cash",
+			SourceClearingAccount:      "due_to",
+			DestinationLedgerID:        to.ID,
+			DestinationAccount:         "revenue",
+			DestinationClearingAccount: "due_from",
+		})
+		if err != nil {
+			t.Fatalf("failed to post transfer: %v", err)
+		}
+		return tr
+	}
+	if ab, ba := transfer(a, b, "transfer-1"), transfer(b, a, "transfer-1"); ab.ID == ba.ID {
+		t.Fatal("a transfer idempotency key used by another ledger returned that ledger's transfer")
+	}
+	transferAC := transfer(a, c, "transfer-2")
+
+	var eventA string
+	if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txA).Scan(&eventA); err != nil {
+		t.Fatalf("failed to load event: %v", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	go projector.NewProjector(pool, projector.Ledger).Run(runCtx)
+	for {
+		var projected int
+		pool.QueryRow(ctx, `SELECT COUNT(*) FROM transactions WHERE id IN ($1, $2)`, txA, txB).Scan(&projected)
+		if projected == 2 {
+			break
+		}
+		if runCtx.Err() != nil {
+			t.Fatalf("projector did not catch up: %d/2 transactions", projected)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	h := &ledger.Handler{Service: f.Service}
+	mw := &auth.Middleware{DB: pool, APIKeySecret: testutil.APIKeySecret}
+	keyB := f.APIKey(b.ID)
+	get := func(handler http.HandlerFunc, target string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+keyB.Key)
+		rec := httptest.NewRecorder()
+		mw.AuthMiddleware(handler).ServeHTTP(rec, req)
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+	// indistinguishable asserts that target answers like missing, which names nothing
+	indistinguishable := func(handler http.HandlerFunc, target, missing string) {
+		t.Helper()
+		code, body := get(handler, target)
+		wantCode, wantBody := get(handler, missing)
+		if code != http.StatusNotFound || code != wantCode || body != wantBody {
+			t.Errorf("GET %s: got %d %q, want %d %q as for a missing resource", target, code, body, wantCode, wantBody)
+		}
+	}
+
+	unknown := uuid.NewString()
+	indistinguishable(h.GetTransaction, "/v1/transactions?id="+txA, "/v1/transactions?id="+unknown)
+	indistinguishable(h.GetEvent, "/v1/events?id="+eventA, "/v1/events?id="+unknown)
+	indistinguishable(h.GetAccount, "/v1/accounts?code=only-in-a", "/v1/accounts?code=nowhere")
+	indistinguishable(h.GetTransfers, "/v1/transfers?id="+transferAC.ID, "/v1/transfers?id="+unknown)
+
+	// Listings only return the ledger's own records
+	for _, list := range []struct {
+		target  string
+		handler http.HandlerFunc
+	}{
+		{"/v1/transactions", h.ListTransactions},
+		{"/v1/events", h.ListEvents},
+		{"/v1/accounts", h.ListAccounts},
+		{"/v1/transfers", h.GetTransfers},
+		{"/v1/postings", h.ListPostings},
+	} {
+		code, body := get(list.handler, list.target)
+		if code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", list.target, code, body)
+		}
+		if strings.Contains(body, txA) || strings.Contains(body, eventA) || strings.Contains(body, transferAC.ID) || strings.Contains(body, "only-in-a") {
+			t.Errorf("GET %s returned ledger A's data: %s", list.target, body)
+		}
+	}
+	if _, body := get(h.ListTransactions, "/v1/transactions"); !strings.Contains(body, txB) {
+		t.Errorf("GET /v1/transactions is missing the ledger's own transaction: %s", body)
+	}
+	if _, body := get(h.ListPostings, "/v1/postings?account=cash&direction=debit&min_amount=10"); !strings.Contains(body, txB) {
+		t.Errorf("GET /v1/postings is missing the ledger's own posting: %s", body)
+	}
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"Go_FormanceLegder/internal/calendar"
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/projector"
+	"Go_FormanceLegder/internal/testutil"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestInterestAccruesByValueDate(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "loan", "asset")
+	f.Account(l.ID, "cash", "asset")
+
+	date := func(s string) time.Time {
+		d, err := calendar.ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	lend := func(key, amount string, occurredAt, valueDate time.Time) string {
+		return f.Transaction(ledger.PostTransactionCommand{
+			LedgerID: l.ID, ExternalID: key, IdempotencyKey: key, OccurredAt: occurredAt, ValueDate: valueDate,
+			Postings: []ledger.PostingInput{
+				{AccountCode: "loan", Direction: "debit", Amount: amount},
+				{AccountCode: "cash", Direction: "credit", Amount: amount},
+			},
+		})
+	}
+	// Booked mid-month but valued from the 1st, and booked early but valued on the 20th
+	backValued := lend("loan-1", "365.00", date("2024-03-15").Add(10*time.Hour), date("2024-03-01"))
+	forwardValued := lend("loan-2", "730.00", date("2024-03-05").Add(10*time.Hour), date("2024-03-20"))
+
+	runCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	go projector.NewProjector(pool, projector.Ledger).Run(runCtx)
+	for {
+		var projected int
+		pool.QueryRow(ctx, `SELECT COUNT(*) FROM transactions WHERE id IN ($1, $2)`, backValued, forwardValued).Scan(&projected)
+		if projected == 2 {
+			break
+		}
+		if runCtx.Err() != nil {
+			t.Fatalf("projector did not catch up: %d/2 transactions", projected)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var valueDate string
+	pool.QueryRow(ctx, `SELECT value_date::text FROM transactions WHERE id = $1`, backValued).Scan(&valueDate)
+	if valueDate != "2024-03-01" {
+		t.Fatalf("expected the projected value date 2024-03-01, got %q", valueDate)
+	}
+
+	// 10% a year on 365.00 for 19 days, then on 1095.00 for 12 days
+	accrual, err := f.Service.AccrueInterest(ctx, ledger.InterestCommand{
+		LedgerID: l.ID, AccountCode: "loan", Currency: l.Currency, AnnualRate: big.NewRat(1, 10),
+		From: date("2024-03-01"), To: date("2024-03-31"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accrual.Interest != "5.50" || len(accrual.Days) != 31 {
+		t.Fatalf("expected 5.50 over 31 days, got %s over %d", accrual.Interest, len(accrual.Days))
+	}
+	if day := accrual.Days[19]; day.Date != "2024-03-20" || day.Balance != "1095.0000000000" {
+		t.Fatalf("unexpected day %+v", day)
+	}
+}
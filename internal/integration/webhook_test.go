@@ -0,0 +1,347 @@
+package integration
+
+import (
+	"Go_FormanceLegder/internal/testutil"
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertest"
+	"github.com/riverqueue/river/rivertype"
+)
+
+func TestWebhookRetriesUntilDelivered(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	receiver := testutil.NewWebhookReceiver(t, "whsec", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	f.WebhookEndpoint(l.ID, receiver.URL, "whsec")
+
+	txID := f.Transfer(l.ID, "cash", "revenue", "25.00")
+
+	var eventID string
+	if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txID).Scan(&eventID); err != nil {
+		t.Fatalf("failed to load event: %v", err)
+	}
+
+	immediateRetries(t)
+	worker := webhook.NewWorker(pool)
+	for attempt := 1; attempt <= 3; attempt++ {
+		err := worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+			JobRow: &rivertype.JobRow{Attempt: attempt},
+			Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID},
+		})
+		if attempt < 3 && err == nil {
+			t.Fatalf("attempt %d: expected a retryable error", attempt)
+		}
+		if attempt == 3 && err != nil {
+			t.Fatalf("attempt 3: expected delivery to succeed: %v", err)
+		}
+	}
+
+	requests := receiver.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	for i, req := range requests {
+		if !req.SignatureValid {
+			t.Errorf("request %d: invalid signature", i+1)
+		}
+		if string(req.Body) != string(requests[0].Body) {
+			t.Errorf("request %d: payload changed between retries", i+1)
+		}
+	}
+	var envelope webhook.Envelope
+	if err := json.Unmarshal(requests[0].Body, &envelope); err != nil {
+		t.Fatalf("failed to decode envelope: %v", err)
+	}
+	if envelope.EventID != eventID || envelope.EventType != "TransactionPosted" || envelope.LedgerID != l.ID ||
+		envelope.DeliveryID != requests[0].Header.Get("X-Ledger-Delivery-Id") || len(envelope.Data) == 0 {
+		t.Errorf("unexpected envelope %s", requests[0].Body)
+	}
+
+	var statuses []string
+	rows, err := pool.Query(ctx, `SELECT status FROM webhook_deliveries WHERE event_id = $1 ORDER BY attempt`, eventID)
+	if err != nil {
+		t.Fatalf("failed to load deliveries: %v", err)
+	}
+	for rows.Next() {
+		var s string
+		rows.Scan(&s)
+		statuses = append(statuses, s)
+	}
+	rows.Close()
+	if strings.Join(statuses, ",") != "retryable_error,retryable_error,success" {
+		t.Fatalf("unexpected delivery log: %v", statuses)
+	}
+}
+
+func TestWebhookRetryPolicyPerEndpoint(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	immediateRetries(t)
+	short := testutil.NewWebhookReceiver(t, "whsec", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	slow := testutil.NewWebhookReceiver(t, "whsec", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	shortID := f.WebhookEndpoint(l.ID, short.URL, "whsec")
+	slowID := f.WebhookEndpoint(l.ID, slow.URL, "whsec")
+	if _, err := pool.Exec(ctx, `UPDATE webhook_endpoints SET retry_max_attempts = 2 WHERE id = $1`, shortID); err != nil {
+		t.Fatalf("failed to override retry policy: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE webhook_endpoints SET retry_backoff = 'fixed', retry_backoff_base_seconds = 3600 WHERE id = $1`, slowID); err != nil {
+		t.Fatalf("failed to override retry policy: %v", err)
+	}
+
+	txID := f.Transfer(l.ID, "cash", "revenue", "25.00")
+	var eventID string
+	if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txID).Scan(&eventID); err != nil {
+		t.Fatalf("failed to load event: %v", err)
+	}
+
+	worker := webhook.NewWorker(pool)
+	for attempt := 1; attempt <= 3; attempt++ {
+		err := worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+			JobRow: &rivertype.JobRow{Attempt: attempt},
+			Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID},
+		})
+		if attempt < 3 && err == nil {
+			t.Fatalf("attempt %d: expected the job to be snoozed for a retry", attempt)
+		}
+	}
+
+	// The short endpoint gave up after its two attempts; the slow one waits an hour
+	if short.Count() != 2 || slow.Count() != 1 {
+		t.Fatalf("expected 2 and 1 requests, got %d and %d", short.Count(), slow.Count())
+	}
+	var nextAttempt *time.Time
+	pool.QueryRow(ctx, `SELECT next_attempt_at FROM webhook_deliveries WHERE webhook_endpoint_id = $1 ORDER BY attempt DESC LIMIT 1`, shortID).Scan(&nextAttempt)
+	if nextAttempt != nil {
+		t.Fatalf("expected the last attempt not to be retried, got next attempt at %v", nextAttempt)
+	}
+	pool.QueryRow(ctx, `SELECT next_attempt_at FROM webhook_deliveries WHERE webhook_endpoint_id = $1`, slowID).Scan(&nextAttempt)
+	if nextAttempt == nil || time.Until(*nextAttempt) < 59*time.Minute {
+		t.Fatalf("expected a retry in an hour, got %v", nextAttempt)
+	}
+}
+
+func TestWebhookManualRetryTargetsOneEndpoint(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	broken := testutil.NewWebhookReceiver(t, "whsec", http.StatusUnauthorized, http.StatusOK)
+	healthy := testutil.NewWebhookReceiver(t, "whsec", http.StatusOK)
+	brokenID := f.WebhookEndpoint(l.ID, broken.URL, "whsec")
+	f.WebhookEndpoint(l.ID, healthy.URL, "whsec")
+
+	txID := f.Transfer(l.ID, "cash", "revenue", "25.00")
+	var eventID string
+	if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txID).Scan(&eventID); err != nil {
+		t.Fatalf("failed to load event: %v", err)
+	}
+
+	// A 4xx is not retried, so the job completes with the delivery lost
+	worker := webhook.NewWorker(pool)
+	err := worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+		JobRow: &rivertype.JobRow{Attempt: 1},
+		Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID},
+	})
+	if err != nil {
+		t.Fatalf("expected the job to give up on the 4xx: %v", err)
+	}
+
+	err = worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+		JobRow: &rivertype.JobRow{Attempt: 1},
+		Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID, EndpointIDs: []string{brokenID}},
+	})
+	if err != nil {
+		t.Fatalf("expected the retry to deliver: %v", err)
+	}
+	if broken.Count() != 2 || healthy.Count() != 1 {
+		t.Fatalf("expected the retry to reach only the failed endpoint, got %d and %d requests", broken.Count(), healthy.Count())
+	}
+}
+
+func TestWebhookFilterSelectsEvents(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	large := testutil.NewWebhookReceiver(t, "whsec", http.StatusOK)
+	posted := testutil.NewWebhookReceiver(t, "whsec", http.StatusOK)
+	largeID := f.WebhookEndpoint(l.ID, large.URL, "whsec")
+	postedID := f.WebhookEndpoint(l.ID, posted.URL, "whsec")
+	if _, err := pool.Exec(ctx, `UPDATE webhook_endpoints SET filter = 'postings.exists(p, p.amount >= 1000)' WHERE id = $1`, largeID); err != nil {
+		t.Fatalf("failed to set filter: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE webhook_endpoints SET filter = 'event_type == "TransactionPosted"' WHERE id = $1`, postedID); err != nil {
+		t.Fatalf("failed to set filter: %v", err)
+	}
+
+	worker := webhook.NewWorker(pool)
+	for _, amount := range []string{"25.00", "1500.00"} {
+		txID := f.Transfer(l.ID, "cash", "revenue", amount)
+		var eventID string
+		if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txID).Scan(&eventID); err != nil {
+			t.Fatalf("failed to load event: %v", err)
+		}
+		err := worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+			JobRow: &rivertype.JobRow{Attempt: 1},
+			Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID},
+		})
+		if err != nil {
+			t.Fatalf("transfer of %s: %v", amount, err)
+		}
+	}
+
+	if large.Count() != 1 || posted.Count() != 2 {
+		t.Fatalf("expected 1 and 2 requests, got %d and %d", large.Count(), posted.Count())
+	}
+	var logged int
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_endpoint_id = $1`, largeID).Scan(&logged)
+	if logged != 1 {
+		t.Fatalf("expected filtered out events not to be logged as deliveries, got %d", logged)
+	}
+}
+
+func TestWebhookBatchDelivery(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	receiver := testutil.NewWebhookReceiver(t, "whsec", http.StatusInternalServerError, http.StatusOK)
+	endpointID := f.WebhookEndpoint(l.ID, receiver.URL, "whsec")
+	if _, err := pool.Exec(ctx, `UPDATE webhook_endpoints SET batch_window_seconds = 5 WHERE id = $1`, endpointID); err != nil {
+		t.Fatalf("failed to enable batching: %v", err)
+	}
+
+	// Each event's job queues it for the endpoint's batch instead of sending it
+	immediateRetries(t)
+	worker := webhook.NewWorker(pool)
+	workCtx := rivertest.WorkContext(ctx, f.Service.RiverClient)
+	var eventIDs []string
+	for _, amount := range []string{"1.00", "2.00", "3.00"} {
+		txID := f.Transfer(l.ID, "cash", "revenue", amount)
+		var eventID string
+		if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txID).Scan(&eventID); err != nil {
+			t.Fatalf("failed to load event: %v", err)
+		}
+		eventIDs = append(eventIDs, eventID)
+		err := worker.Work(workCtx, &river.Job[webhook.WebhookArgs]{
+			JobRow: &rivertype.JobRow{Attempt: 1},
+			Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID},
+		})
+		if err != nil {
+			t.Fatalf("failed to queue event: %v", err)
+		}
+	}
+	var batchJobs int
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM river_job WHERE kind = 'webhook_batch'`).Scan(&batchJobs)
+	if receiver.Count() != 0 || batchJobs != 1 {
+		t.Fatalf("expected one pending batch and no request yet, got %d jobs and %d requests", batchJobs, receiver.Count())
+	}
+
+	// The failed batch is retried whole, then the queue is empty and the job completes
+	batches := &webhook.BatchWorker{Deliveries: worker}
+	job := &river.Job[webhook.BatchArgs]{JobRow: &rivertype.JobRow{Attempt: 1}, Args: webhook.BatchArgs{EndpointID: endpointID, LedgerID: l.ID}}
+	var snooze *river.JobSnoozeError
+	if err := batches.Work(workCtx, job); !errors.As(err, &snooze) {
+		t.Fatalf("expected the failed batch to snooze, got %v", err)
+	}
+	if err := batches.Work(workCtx, job); err != nil {
+		t.Fatalf("expected the batch to be delivered: %v", err)
+	}
+
+	requests := receiver.Requests()
+	if len(requests) != 2 || !requests[1].SignatureValid || requests[1].Header.Get("X-Ledger-Batch-Size") != "3" {
+		t.Fatalf("expected two signed batch requests, got %+v", requests)
+	}
+	var envelopes []webhook.Envelope
+	if err := json.Unmarshal(requests[1].Body, &envelopes); err != nil {
+		t.Fatalf("failed to decode batch: %v", err)
+	}
+	for i, envelope := range envelopes {
+		if envelope.EventID != eventIDs[i] || envelope.DeliveryID != webhook.DeliveryID(eventIDs[i], endpointID) {
+			t.Errorf("envelope %d: unexpected %+v", i, envelope)
+		}
+	}
+
+	var delivered, queued int
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_endpoint_id = $1 AND status = 'success' AND attempt = 2`, endpointID).Scan(&delivered)
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_batch_items`).Scan(&queued)
+	if delivered != 3 || queued != 0 {
+		t.Fatalf("expected 3 events delivered on the second attempt and none queued, got %d and %d", delivered, queued)
+	}
+}
+
+func TestWebhookEndpointDisabledAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	pool := testutil.NewDB(t)
+	f := testutil.NewFactory(t, pool)
+
+	l := f.Ledger()
+	f.Account(l.ID, "cash", "asset")
+	f.Account(l.ID, "revenue", "revenue")
+
+	receiver := testutil.NewWebhookReceiver(t, "whsec", http.StatusGone, http.StatusGone, http.StatusGone)
+	endpointID := f.WebhookEndpoint(l.ID, receiver.URL, "whsec")
+
+	worker := &webhook.Worker{DB: pool, DisableAfter: 2}
+	for range 3 {
+		txID := f.Transfer(l.ID, "cash", "revenue", "1.00")
+		var eventID string
+		if err := pool.QueryRow(ctx, `SELECT id FROM events WHERE aggregate_id = $1`, txID).Scan(&eventID); err != nil {
+			t.Fatalf("failed to load event: %v", err)
+		}
+		worker.Work(ctx, &river.Job[webhook.WebhookArgs]{
+			JobRow: &rivertype.JobRow{Attempt: 1},
+			Args:   webhook.WebhookArgs{EventID: eventID, LedgerID: l.ID},
+		})
+	}
+
+	if receiver.Count() != 2 {
+		t.Fatalf("expected no delivery after the endpoint was disabled, got %d requests", receiver.Count())
+	}
+	var active bool
+	var events int
+	pool.QueryRow(ctx, `SELECT is_active FROM webhook_endpoints WHERE id = $1`, endpointID).Scan(&active)
+	pool.QueryRow(ctx, `SELECT COUNT(*) FROM events WHERE event_type = 'WebhookEndpointDisabled' AND aggregate_id = $1`, endpointID).Scan(&events)
+	if active || events != 1 {
+		t.Fatalf("expected the endpoint disabled once, got active=%v and %d events", active, events)
+	}
+}
+
+// immediateRetries makes failed deliveries due again at once for the rest of the test.
+func immediateRetries(t *testing.T) {
+	policy := webhook.DefaultRetryPolicy
+	webhook.DefaultRetryPolicy.Base = 0
+	t.Cleanup(func() { webhook.DefaultRetryPolicy = policy })
+}
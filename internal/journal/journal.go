@@ -0,0 +1,254 @@
+// Package journal writes a ledger's transactions as journal entries in the import
+// formats of accounting systems.
+package journal
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Formats are the supported export formats: a generic general ledger CSV, a Xero manual
+// journal CSV and a QuickBooks Desktop IIF general journal.
+var Formats = []string{"gl", "xero", "iif"}
+
+// Mapping is a ledger's export settings: how its accounts and tax codes are named in the
+// accounting system the journal is imported into.
+type Mapping struct {
+	// Accounts maps ledger account codes to the accounting system's accounts
+	Accounts map[string]string `json:"accounts,omitempty"`
+	// RequireMapped refuses to export accounts missing from Accounts; otherwise they
+	// keep their ledger code
+	RequireMapped bool `json:"require_mapped,omitempty"`
+	// TaxRates maps ledger tax codes to Xero tax rates; other lines are "Tax Exempt"
+	TaxRates map[string]string `json:"tax_rates,omitempty"`
+	// DateFormat is a Go layout; defaults to 2006-01-02, or 01/02/2006 for IIF
+	DateFormat string `json:"date_format,omitempty"`
+}
+
+func (m Mapping) Validate() error {
+	var errs []error
+	for code, account := range m.Accounts {
+		if code == "" || strings.TrimSpace(account) == "" {
+			errs = append(errs, fmt.Errorf("account %q must map to a non-empty account", code))
+		}
+		if strings.ContainsAny(account, "\t\r\n") {
+			errs = append(errs, fmt.Errorf("account %q must be on a single line without tabs", code))
+		}
+	}
+	if m.DateFormat != "" && time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC).Format(m.DateFormat) == m.DateFormat {
+		errs = append(errs, errors.New("date_format must be a Go time layout, e.g. 02/01/2006"))
+	}
+	return errors.Join(errs...)
+}
+
+// Account returns the accounting system's name for a ledger account.
+func (m Mapping) Account(code string) (string, error) {
+	if account, ok := m.Accounts[code]; ok {
+		return account, nil
+	}
+	if m.RequireMapped {
+		return "", fmt.Errorf("account %s is not mapped", code)
+	}
+	return code, nil
+}
+
+// Unmapped returns those of codes that Account refuses.
+func (m Mapping) Unmapped(codes []string) []string {
+	var unmapped []string
+	for _, code := range codes {
+		if _, err := m.Account(code); err != nil {
+			unmapped = append(unmapped, code)
+		}
+	}
+	return unmapped
+}
+
+// Entry is one balanced transaction.
+type Entry struct {
+	TransactionID string
+	ExternalID    string
+	Description   string
+	Date          time.Time
+	Lines         []Line
+}
+
+type Line struct {
+	Account     string // ledger code
+	AccountName string
+	Direction   string // debit or credit
+	Amount      string // positive decimal
+	Currency    string
+	TaxCode     string
+	Description string
+}
+
+// Writer writes entries in one of Formats.
+type Writer struct {
+	format  string
+	mapping Mapping
+	csv     *csv.Writer
+	started bool
+}
+
+func NewWriter(w io.Writer, format string, mapping Mapping) (*Writer, error) {
+	jw := &Writer{format: format, mapping: mapping}
+	switch format {
+	case "gl", "xero":
+		jw.csv = csv.NewWriter(w)
+	case "iif":
+		jw.csv = csv.NewWriter(w)
+		jw.csv.Comma = '\t'
+	default:
+		return nil, fmt.Errorf("format must be one of %v", Formats)
+	}
+	if jw.mapping.DateFormat == "" {
+		jw.mapping.DateFormat = "2006-01-02"
+		if format == "iif" {
+			jw.mapping.DateFormat = "01/02/2006"
+		}
+	}
+	return jw, nil
+}
+
+// ContentType and Extension describe the written file.
+func (jw *Writer) ContentType() string {
+	if jw.format == "iif" {
+		return "text/plain"
+	}
+	return "text/csv"
+}
+
+func (jw *Writer) Extension() string {
+	if jw.format == "iif" {
+		return "iif"
+	}
+	return "csv"
+}
+
+// Write writes e, preceded by the format's header on the first call.
+func (jw *Writer) Write(e Entry) error {
+	if !jw.started {
+		jw.started = true
+		if err := jw.header(); err != nil {
+			return err
+		}
+	}
+	date := e.Date.Format(jw.mapping.DateFormat)
+	narration := e.Description
+	if narration == "" {
+		narration = e.ExternalID
+	}
+
+	for i, l := range e.Lines {
+		account, err := jw.mapping.Account(l.Account)
+		if err != nil {
+			return err
+		}
+		debit, credit := "", ""
+		if l.Direction == "debit" {
+			debit = formatAmount(l.Amount, false)
+		} else {
+			credit = formatAmount(l.Amount, false)
+		}
+		signed := formatAmount(l.Amount, l.Direction == "credit")
+		memo := l.Description
+		if memo == "" {
+			memo = narration
+		}
+
+		var record []string
+		switch jw.format {
+		case "gl":
+			record = []string{e.TransactionID, date, e.ExternalID, e.Description, account, l.AccountName, debit, credit,
+				l.Currency, l.TaxCode, l.Description}
+		case "xero":
+			taxRate := "Tax Exempt"
+			if rate, ok := jw.mapping.TaxRates[l.TaxCode]; ok && l.TaxCode != "" {
+				taxRate = rate
+			}
+			record = []string{narration, date, memo, account, taxRate, signed}
+		case "iif":
+			kind := "SPL"
+			if i == 0 {
+				kind = "TRNS"
+			}
+			record = []string{kind, "", "GENERAL JOURNAL", date, iifText(account), signed, iifText(e.ExternalID), iifText(memo)}
+		}
+		if err := jw.csv.Write(record); err != nil {
+			return err
+		}
+	}
+	if jw.format == "iif" {
+		if err := jw.csv.Write([]string{"ENDTRNS"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (jw *Writer) header() error {
+	switch jw.format {
+	case "gl":
+		return jw.csv.Write([]string{"journal_id", "date", "reference", "description", "account", "account_name",
+			"debit", "credit", "currency", "tax_code", "memo"})
+	case "xero":
+		return jw.csv.Write([]string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount"})
+	}
+	for _, record := range [][]string{
+		{"!TRNS", "TRNSID", "TRNSTYPE", "DATE", "ACCNT", "AMOUNT", "DOCNUM", "MEMO"},
+		{"!SPL", "SPLID", "TRNSTYPE", "DATE", "ACCNT", "AMOUNT", "DOCNUM", "MEMO"},
+		{"!ENDTRNS"},
+	} {
+		if err := jw.csv.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the header of an empty journal, then any buffered data.
+func (jw *Writer) Flush() error {
+	if !jw.started {
+		jw.started = true
+		if err := jw.header(); err != nil {
+			return err
+		}
+	}
+	jw.csv.Flush()
+	return jw.csv.Error()
+}
+
+// formatAmount drops an amount's trailing zeros, keeping two decimals, and negates it.
+func formatAmount(amount string, negate bool) string {
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return amount
+	}
+	if negate {
+		r.Neg(r)
+	}
+	s := strings.TrimRight(r.FloatString(10), "0")
+	if dot := strings.IndexByte(s, '.'); len(s)-dot-1 < 2 {
+		s += strings.Repeat("0", 2-(len(s)-dot-1))
+	}
+	return s
+}
+
+// iifText keeps a memo on one IIF field: tabs and line breaks become spaces, quotes are
+// dropped since QuickBooks doesn't unquote them.
+func iifText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\t', '\r', '\n':
+			return ' '
+		case '"':
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,105 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var sale = Entry{
+	TransactionID: "tx-1",
+	ExternalID:    "INV-7",
+	Description:   "Sale",
+	Date:          time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+	Lines: []Line{
+		{Account: "1000", AccountName: "Cash", Direction: "debit", Amount: "120.5000", Currency: "EUR"},
+		{Account: "4000", AccountName: "Revenue", Direction: "credit", Amount: "100", Currency: "EUR", TaxCode: "VAT20"},
+		{Account: "2200", AccountName: "VAT", Direction: "credit", Amount: "20.5", Currency: "EUR", Description: "VAT\t20%"},
+	},
+}
+
+func export(t *testing.T, format string, m Mapping, entries ...Entry) string {
+	t.Helper()
+	var b strings.Builder
+	jw, err := NewWriter(&b, format, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := jw.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestWriteFormats(t *testing.T) {
+	m := Mapping{Accounts: map[string]string{"1000": "Bank", "4000": "Sales"}, TaxRates: map[string]string{"VAT20": "20% (VAT on Income)"}}
+
+	gl := export(t, "gl", m, sale)
+	if want := "tx-1,2024-03-05,INV-7,Sale,Bank,Cash,120.50,,EUR,,\n"; !strings.Contains(gl, want) {
+		t.Errorf("gl: missing %q in\n%s", want, gl)
+	}
+	if want := "tx-1,2024-03-05,INV-7,Sale,Sales,Revenue,,100.00,EUR,VAT20,\n"; !strings.Contains(gl, want) {
+		t.Errorf("gl: missing %q in\n%s", want, gl)
+	}
+
+	xero := export(t, "xero", m, sale)
+	for _, want := range []string{
+		"*Narration,*Date,Description,*AccountCode,*TaxRate,*Amount\n",
+		"Sale,2024-03-05,Sale,Bank,Tax Exempt,120.50\n",
+		"Sale,2024-03-05,Sale,Sales,20% (VAT on Income),-100.00\n",
+		"Sale,2024-03-05,VAT\t20%,2200,Tax Exempt,-20.50\n",
+	} {
+		if !strings.Contains(xero, want) {
+			t.Errorf("xero: missing %q in\n%s", want, xero)
+		}
+	}
+
+	iif := export(t, "iif", m, sale, sale)
+	lines := strings.Split(strings.TrimSuffix(iif, "\n"), "\n")
+	if len(lines) != 3+2*4 {
+		t.Fatalf("iif: expected 11 lines, got %d:\n%s", len(lines), iif)
+	}
+	if lines[3] != "TRNS\t\tGENERAL JOURNAL\t03/05/2024\tBank\t120.50\tINV-7\tSale" {
+		t.Errorf("iif: unexpected TRNS line %q", lines[3])
+	}
+	if lines[5] != "SPL\t\tGENERAL JOURNAL\t03/05/2024\t2200\t-20.50\tINV-7\tVAT 20%" {
+		t.Errorf("iif: unexpected SPL line %q", lines[5])
+	}
+	if lines[6] != "ENDTRNS" {
+		t.Errorf("iif: expected ENDTRNS, got %q", lines[6])
+	}
+}
+
+func TestWriteRequireMapped(t *testing.T) {
+	m := Mapping{Accounts: map[string]string{"1000": "Bank"}, RequireMapped: true}
+	if unmapped := m.Unmapped([]string{"1000", "2200", "4000"}); strings.Join(unmapped, ",") != "2200,4000" {
+		t.Fatalf("unexpected unmapped accounts %v", unmapped)
+	}
+	jw, _ := NewWriter(&strings.Builder{}, "gl", m)
+	if err := jw.Write(sale); err == nil {
+		t.Fatal("expected an unmapped account to be refused")
+	}
+}
+
+func TestEmptyExportAndValidation(t *testing.T) {
+	if out := export(t, "xero", Mapping{}); out != "*Narration,*Date,Description,*AccountCode,*TaxRate,*Amount\n" {
+		t.Errorf("expected only the header, got %q", out)
+	}
+	if _, err := NewWriter(&strings.Builder{}, "qbo", Mapping{}); err == nil {
+		t.Error("expected an unknown format to be refused")
+	}
+	if err := (Mapping{DateFormat: "dd/mm/yyyy"}).Validate(); err == nil {
+		t.Error("expected a date format without a layout to be refused")
+	}
+	if err := (Mapping{Accounts: map[string]string{"1000": " "}}).Validate(); err == nil {
+		t.Error("expected an empty account to be refused")
+	}
+	if got := formatAmount("0.123400", true); got != "-0.1234" {
+		t.Errorf("formatAmount: got %s", got)
+	}
+}
@@ -0,0 +1,248 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// A batch creates at most this many accounts.
+const maxAccountBatch = 1000
+
+type CreateAccountsRequest struct {
+	Accounts []CreateAccountRequest `json:"accounts"`
+}
+
+// AccountBatchResult is the outcome of one item of a batch, in request order. Status is
+// created, exists (an account with the code was already there; it is left as is),
+// invalid, or skipped (valid, but not created because another item is invalid).
+type AccountBatchResult struct {
+	Index  int    `json:"index"`
+	Code   string `json:"code"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type CreateAccountsResponse struct {
+	Created int                  `json:"created"`
+	Results []AccountBatchResult `json:"results"`
+}
+
+// POST /v1/accounts/batch - Create many accounts in one transaction
+//
+// Either every new account is created or, when any item is invalid, none is and the
+// response is a 422 whose results name the invalid items. Codes that already exist
+// are reported with their account's ID rather than failing the batch, so a batch cut
+// short can simply be sent again.
+func (h *Handler) CreateAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateAccountsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Accounts) == 0 || len(req.Accounts) > maxAccountBatch {
+		api.Error(w, fmt.Sprintf("accounts must hold between 1 and %d items", maxAccountBatch), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.Service.CreateAccounts(ctx, principal.LedgerID, req.Accounts)
+	if err != nil {
+		api.Error(w, "failed to create accounts", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	for _, result := range resp.Results {
+		if result.Status == "invalid" {
+			status = http.StatusUnprocessableEntity
+			break
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CreateAccounts validates accounts as a whole, then creates those whose code is new
+// with one statement. Nothing is written if any account is invalid.
+func (s *Service) CreateAccounts(ctx context.Context, ledgerID string, accounts []CreateAccountRequest) (CreateAccountsResponse, error) {
+	resp := CreateAccountsResponse{Results: make([]AccountBatchResult, len(accounts))}
+	rules, err := s.accountCodeRules(ctx, ledgerID)
+	if err != nil {
+		return resp, err
+	}
+
+	seen := map[string]int{}
+	var taxCodes, entities []string
+	for i := range accounts {
+		a := &accounts[i]
+		err := a.validate(rules)
+		resp.Results[i] = AccountBatchResult{Index: i, Code: a.Code}
+		if err != nil {
+			resp.Results[i].Status, resp.Results[i].Error = "invalid", err.Error()
+			continue
+		}
+		if first, ok := seen[a.Code]; ok {
+			resp.Results[i].Status, resp.Results[i].Error = "invalid", fmt.Sprintf("duplicate of item %d", first)
+			continue
+		}
+		seen[a.Code] = i
+		if a.TaxCode != "" {
+			taxCodes = append(taxCodes, a.TaxCode)
+		}
+		if a.Entity != "" {
+			entities = append(entities, a.Entity)
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return resp, err
+	}
+	defer tx.Rollback(ctx)
+
+	knownTaxCodes, err := existingCodes(ctx, tx, `SELECT code FROM tax_codes WHERE ledger_id = $1 AND code = ANY($2)`, ledgerID, taxCodes)
+	if err != nil {
+		return resp, err
+	}
+	knownEntities, err := existingCodes(ctx, tx, `SELECT code FROM entities WHERE ledger_id = $1 AND code = ANY($2)`, ledgerID, entities)
+	if err != nil {
+		return resp, err
+	}
+	invalid := false
+	for i, a := range accounts {
+		result := &resp.Results[i]
+		switch {
+		case result.Status == "invalid":
+		case a.TaxCode != "" && !knownTaxCodes[a.TaxCode]:
+			result.Status, result.Error = "invalid", "tax code not found"
+		case a.Entity != "" && !knownEntities[a.Entity]:
+			result.Status, result.Error = "invalid", "entity not found"
+		}
+		invalid = invalid || result.Status == "invalid"
+	}
+	if invalid {
+		for i := range resp.Results {
+			if resp.Results[i].Status == "" {
+				resp.Results[i].Status = "skipped"
+			}
+		}
+		return resp, nil
+	}
+
+	var codes, names, types, taxes, entityCodes, metadata, minBalances []string
+	var allowNegative []bool
+	for _, a := range accounts {
+		encoded, err := json.Marshal(a.Metadata)
+		if err != nil {
+			return resp, err
+		}
+		codes = append(codes, a.Code)
+		names = append(names, a.Name)
+		types = append(types, a.Type)
+		taxes = append(taxes, a.TaxCode)
+		entityCodes = append(entityCodes, a.Entity)
+		metadata = append(metadata, string(encoded))
+		minBalances = append(minBalances, a.MinBalance)
+		allowNegative = append(allowNegative, a.allowNegative())
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO accounts (ledger_id, code, name, type, balance, tax_code, entity_code, metadata,
+			allow_negative_balance, min_balance)
+		SELECT $1, a.code, a.name, a.type, 0, NULLIF(a.tax_code, ''), NULLIF(a.entity_code, ''), a.metadata::jsonb,
+			a.allow_negative, NULLIF(a.min_balance, '')::numeric
+		FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::bool[], $9::text[])
+			AS a (code, name, type, tax_code, entity_code, metadata, allow_negative, min_balance)
+		ON CONFLICT (ledger_id, code) DO NOTHING
+		RETURNING code, id
+	`, ledgerID, codes, names, types, taxes, entityCodes, metadata, allowNegative, minBalances)
+	if err != nil {
+		return resp, err
+	}
+	created, err := collectAccountIDs(rows)
+	if err != nil {
+		return resp, err
+	}
+
+	var kept []string
+	for _, code := range codes {
+		if _, ok := created[code]; !ok {
+			kept = append(kept, code)
+		}
+	}
+	existing := map[string]string{}
+	if len(kept) > 0 {
+		rows, err := tx.Query(ctx, `SELECT code, id FROM accounts WHERE ledger_id = $1 AND code = ANY($2)`, ledgerID, kept)
+		if err != nil {
+			return resp, err
+		}
+		if existing, err = collectAccountIDs(rows); err != nil {
+			return resp, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return resp, err
+	}
+
+	for i := range resp.Results {
+		result := &resp.Results[i]
+		if id, ok := created[result.Code]; ok {
+			result.Status, result.ID = "created", id
+			resp.Created++
+		} else {
+			result.Status, result.ID = "exists", existing[result.Code]
+		}
+	}
+	return resp, nil
+}
+
+// existingCodes returns which of codes query ($1 the ledger, $2 the codes) finds.
+func existingCodes(ctx context.Context, tx pgx.Tx, query, ledgerID string, codes []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(codes) == 0 {
+		return found, nil
+	}
+	rows, err := tx.Query(ctx, query, ledgerID, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		found[code] = true
+	}
+	return found, rows.Err()
+}
+
+// collectAccountIDs reads (code, id) rows into a map by code.
+func collectAccountIDs(rows pgx.Rows) (map[string]string, error) {
+	defer rows.Close()
+	ids := map[string]string{}
+	for rows.Next() {
+		var code, id string
+		if err := rows.Scan(&code, &id); err != nil {
+			return nil, err
+		}
+		ids[code] = id
+	}
+	return ids, rows.Err()
+}
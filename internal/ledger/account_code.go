@@ -0,0 +1,132 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AccountCodeSeparator splits account codes into segments, from the most general to the
+// most specific (assets:cash:eur). Every prefix of segments is a parent whose balance
+// rolls up its descendants.
+const AccountCodeSeparator = ":"
+
+const (
+	// Codes are never longer than this; a ledger's rules may lower it
+	maxAccountCodeLength = 255
+
+	// ReservedAccountPrefix is the first segment of the ledger's own accounts, which
+	// clients cannot create (in any case)
+	ReservedAccountPrefix = "system"
+)
+
+// validateAccountCode checks the rules every ledger shares: non-empty segments of
+// printable ASCII, so codes hold no whitespace and no look-alike Unicode characters,
+// outside the reserved prefix.
+func validateAccountCode(code string) error {
+	if code == "" {
+		return fmt.Errorf("account code required")
+	}
+	if len(code) > maxAccountCodeLength {
+		return fmt.Errorf("account code is longer than %d characters", maxAccountCodeLength)
+	}
+	for _, r := range code {
+		if r <= ' ' || r > '~' {
+			return fmt.Errorf("account code %q may only hold printable ASCII characters without spaces", code)
+		}
+	}
+	segments := strings.Split(code, AccountCodeSeparator)
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("account code %q has an empty segment", code)
+		}
+	}
+	if strings.EqualFold(segments[0], ReservedAccountPrefix) {
+		return fmt.Errorf("account codes under %q are reserved", ReservedAccountPrefix+AccountCodeSeparator)
+	}
+	return nil
+}
+
+// AccountCodeRules are a ledger's own constraints on new account codes, on top of
+// validateAccountCode.
+type AccountCodeRules struct {
+	Pattern   string `json:"pattern,omitempty"`    // RE2, matched against the whole code
+	MaxLength int    `json:"max_length,omitempty"` // 0 for the global maximum
+	Case      string `json:"case,omitempty"`       // preserve (default), lower or upper
+}
+
+func (rules AccountCodeRules) validate() error {
+	if _, err := rules.compile(); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if rules.MaxLength < 0 || rules.MaxLength > maxAccountCodeLength {
+		return fmt.Errorf("max_length must be between 1 and %d", maxAccountCodeLength)
+	}
+	switch rules.Case {
+	case "", "preserve", "lower", "upper":
+	default:
+		return fmt.Errorf("case must be preserve, lower or upper")
+	}
+	return nil
+}
+
+func (rules AccountCodeRules) compile() (*regexp.Regexp, error) {
+	if rules.Pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(`^(?:` + rules.Pattern + `)$`)
+}
+
+// normalize returns code in the ledger's case, or why the ledger doesn't accept it.
+func (rules AccountCodeRules) normalize(code string) (string, error) {
+	switch rules.Case {
+	case "lower":
+		code = strings.ToLower(code)
+	case "upper":
+		code = strings.ToUpper(code)
+	}
+	if err := validateAccountCode(code); err != nil {
+		return code, err
+	}
+	if rules.MaxLength > 0 && len(code) > rules.MaxLength {
+		return code, fmt.Errorf("account code is longer than %d characters", rules.MaxLength)
+	}
+	pattern, err := rules.compile()
+	if err != nil {
+		return code, err
+	}
+	if pattern != nil && !pattern.MatchString(code) {
+		return code, fmt.Errorf("account code %q does not match the ledger's pattern %s", code, rules.Pattern)
+	}
+	return code, nil
+}
+
+// accountCodeRules loads a ledger's rules; a ledger without any has the zero rules.
+func (s *Service) accountCodeRules(ctx context.Context, ledgerID string) (AccountCodeRules, error) {
+	var rules AccountCodeRules
+	err := s.DB.QueryRow(ctx, `
+		SELECT COALESCE(pattern, ''), COALESCE(max_length, 0), case_mode FROM account_code_rules WHERE ledger_id = $1
+	`, ledgerID).Scan(&rules.Pattern, &rules.MaxLength, &rules.Case)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return AccountCodeRules{}, nil
+	}
+	return rules, err
+}
+
+// accountCodeDepth is the number of segments in code; the empty (root) code has none.
+func accountCodeDepth(code string) int {
+	if code == "" {
+		return 0
+	}
+	return strings.Count(code, AccountCodeSeparator) + 1
+}
+
+// descendantPattern is a LIKE pattern matching the codes below parent.
+func descendantPattern(parent string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(parent)
+	return escaped + AccountCodeSeparator + "%"
+}
@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"net/http"
+)
+
+// GET /v1/account-code-rules - The ledger's rules for new account codes
+func (h *Handler) GetAccountCodeRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := h.Service.accountCodeRules(ctx, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to load account code rules", http.StatusInternalServerError)
+		return
+	}
+	if rules.Case == "" {
+		rules.Case = "preserve"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// PUT /v1/account-code-rules - Replace the ledger's rules for new account codes
+//
+// Existing accounts are kept as they are, even where they break the new rules.
+func (h *Handler) SetAccountCodeRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var rules AccountCodeRules
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if rules.Case == "" {
+		rules.Case = "preserve"
+	}
+	if err := rules.validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO account_code_rules (ledger_id, pattern, max_length, case_mode)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, 0), $4)
+		ON CONFLICT (ledger_id) DO UPDATE
+			SET pattern = EXCLUDED.pattern, max_length = EXCLUDED.max_length, case_mode = EXCLUDED.case_mode, updated_at = NOW()
+	`, principal.LedgerID, rules.Pattern, rules.MaxLength, rules.Case)
+	if err != nil {
+		api.Error(w, "failed to save account code rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
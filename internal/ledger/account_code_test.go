@@ -0,0 +1,58 @@
+package ledger
+
+import "testing"
+
+func TestAccountCodes(t *testing.T) {
+	for _, code := range []string{"cash", "assets:cash:eur"} {
+		if err := validateAccountCode(code); err != nil {
+			t.Errorf("expected %q to be valid: %v", code, err)
+		}
+	}
+	for _, code := range []string{"", ":cash", "assets::eur", "assets:"} {
+		if err := validateAccountCode(code); err == nil {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+
+	if d := accountCodeDepth("assets:cash:eur"); d != 3 {
+		t.Errorf("expected depth 3, got %d", d)
+	}
+	if d := accountCodeDepth(""); d != 0 {
+		t.Errorf("expected root depth 0, got %d", d)
+	}
+	if p := descendantPattern("fees_100%"); p != `fees\_100\%:%` {
+		t.Errorf("unexpected pattern %s", p)
+	}
+}
+
+func TestAccountCodeCharactersAndReservedPrefix(t *testing.T) {
+	for _, code := range []string{"cash eur", "cash\t", "саsh", "system", "system:fx", "SYSTEM:fx"} {
+		if err := validateAccountCode(code); err == nil {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+	if err := validateAccountCode("systems:fx"); err != nil {
+		t.Errorf("expected only the system segment to be reserved: %v", err)
+	}
+}
+
+func TestAccountCodeRules(t *testing.T) {
+	rules := AccountCodeRules{Pattern: `wallets:[a-z0-9]+`, MaxLength: 16, Case: "lower"}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if code, err := rules.normalize("Wallets:ABC"); err != nil || code != "wallets:abc" {
+		t.Fatalf("expected the code lowercased, got %q, %v", code, err)
+	}
+	for _, code := range []string{"cash", "xwallets:abc", "wallets:abcdefghijk"} {
+		if _, err := rules.normalize(code); err == nil {
+			t.Errorf("expected %q to be rejected", code)
+		}
+	}
+
+	for _, bad := range []AccountCodeRules{{Pattern: "("}, {MaxLength: 300}, {Case: "title"}} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
@@ -1,38 +1,86 @@
 package ledger
 
 import (
+	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 )
 
 type AccountResponse struct {
-	ID        string `json:"id"`
-	Code      string `json:"code"`
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	Balance   string `json:"balance"`
-	CreatedAt string `json:"created_at"`
+	ID                   string         `json:"id"`
+	Code                 string         `json:"code"`
+	Name                 string         `json:"name"`
+	Type                 string         `json:"type"`
+	Balance              string         `json:"balance"`
+	HeldBalance          string         `json:"held_balance"`
+	AvailableBalance     string         `json:"available_balance"` // balance less pending holds
+	TaxCode              string         `json:"tax_code,omitempty"`
+	Entity               string         `json:"entity,omitempty"` // code of the linked counterparty entity
+	Status               string         `json:"status"`           // active or disabled
+	AllowNegativeBalance bool           `json:"allow_negative_balance"`
+	MinBalance           string         `json:"min_balance,omitempty"`
+	Metadata             map[string]any `json:"metadata"`
+	CreatedAt            string         `json:"created_at"`
+	Notes                []Note         `json:"notes,omitempty"` // single-account reads only
 }
 
-// GET /v1/accounts - List all accounts for the authenticated ledger
+// GET /v1/accounts - List the active accounts for the authenticated ledger
 func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	rows, err := h.Service.DB.Query(ctx, `
-		SELECT id, code, name, type, balance, created_at
+	metadataFilters, err := parseMetadataFilters(r.URL.Query())
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT id, code, name, type, balance, held_balance, balance - held_balance, COALESCE(tax_code, ''), COALESCE(entity_code, ''), status,
+			allow_negative_balance, COALESCE(min_balance::text, ''), metadata, created_at
 		FROM accounts
 		WHERE ledger_id = $1
-		ORDER BY code
-	`, principal.LedgerID)
+	`
+	args := []interface{}{principal.LedgerID}
+	// Disabled accounts are hidden unless asked for with status=disabled or status=all
+	switch status := r.URL.Query().Get("status"); status {
+	case "", "active":
+		query += ` AND status = 'active'`
+	case "disabled":
+		query += ` AND status = 'disabled'`
+	case "all":
+	default:
+		api.Error(w, "status must be active, disabled or all", http.StatusBadRequest)
+		return
+	}
+	if entity := r.URL.Query().Get("entity"); entity != "" {
+		args = append(args, entity)
+		query += fmt.Sprintf(` AND entity_code = $%d`, len(args))
+	}
+	// parent=assets:cash lists that account and every account below it
+	if parent := r.URL.Query().Get("parent"); parent != "" {
+		args = append(args, parent, descendantPattern(parent))
+		query += fmt.Sprintf(` AND (code = $%d OR code LIKE $%d)`, len(args)-1, len(args))
+	}
+	for _, f := range metadataFilters {
+		query += fmt.Sprintf(` AND metadata ->> $%d = $%d`, len(args)+1, len(args)+2)
+		args = append(args, f.Key, f.Value)
+	}
+	query += ` ORDER BY code`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
 	if err != nil {
-		http.Error(w, "failed to query accounts", http.StatusInternalServerError)
+		api.Error(w, "failed to query accounts", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -40,9 +88,10 @@ func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 	accounts := []AccountResponse{}
 	for rows.Next() {
 		var acc AccountResponse
-		err = rows.Scan(&acc.ID, &acc.Code, &acc.Name, &acc.Type, &acc.Balance, &acc.CreatedAt)
+		err = rows.Scan(&acc.ID, &acc.Code, &acc.Name, &acc.Type, &acc.Balance, &acc.HeldBalance, &acc.AvailableBalance, &acc.TaxCode, &acc.Entity, &acc.Status,
+			&acc.AllowNegativeBalance, &acc.MinBalance, &acc.Metadata, &acc.CreatedAt)
 		if err != nil {
-			http.Error(w, "failed to scan account", http.StatusInternalServerError)
+			api.Error(w, "failed to scan account", http.StatusInternalServerError)
 			return
 		}
 		accounts = append(accounts, acc)
@@ -58,25 +107,33 @@ func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Extract account code from URL path or query param
 	code := r.URL.Query().Get("code")
 	if code == "" {
-		http.Error(w, "account code required", http.StatusBadRequest)
+		api.Error(w, "account code required", http.StatusBadRequest)
 		return
 	}
 
 	var acc AccountResponse
 	err = h.Service.DB.QueryRow(ctx, `
-		SELECT id, code, name, type, balance, created_at
+		SELECT id, code, name, type, balance, held_balance, balance - held_balance, COALESCE(tax_code, ''), COALESCE(entity_code, ''), status,
+			allow_negative_balance, COALESCE(min_balance::text, ''), metadata, created_at
 		FROM accounts
 		WHERE ledger_id = $1 AND code = $2
-	`, principal.LedgerID, code).Scan(&acc.ID, &acc.Code, &acc.Name, &acc.Type, &acc.Balance, &acc.CreatedAt)
+	`, principal.LedgerID, code).Scan(&acc.ID, &acc.Code, &acc.Name, &acc.Type, &acc.Balance, &acc.HeldBalance, &acc.AvailableBalance, &acc.TaxCode, &acc.Entity, &acc.Status,
+		&acc.AllowNegativeBalance, &acc.MinBalance, &acc.Metadata, &acc.CreatedAt)
+	if err != nil {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	acc.Notes, err = h.loadNotes(ctx, principal.LedgerID, "account", acc.Code)
 	if err != nil {
-		http.Error(w, "account not found", http.StatusNotFound)
+		api.Error(w, "failed to load notes", http.StatusInternalServerError)
 		return
 	}
 
@@ -84,54 +141,297 @@ func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(acc)
 }
 
+type CreateAccountRequest struct {
+	Code     string         `json:"code"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	TaxCode  string         `json:"tax_code,omitempty"`
+	Entity   string         `json:"entity,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// Overdraft protection; accounts may go negative unless allow_negative_balance is false
+	AllowNegative *bool  `json:"allow_negative_balance,omitempty"`
+	MinBalance    string `json:"min_balance,omitempty"`
+}
+
+var accountTypes = map[string]bool{
+	"asset": true, "liability": true, "equity": true, "revenue": true, "expense": true,
+}
+
+// validate checks what can be checked without the database, puts the code in the
+// ledger's case and defaults the metadata.
+func (req *CreateAccountRequest) validate(rules AccountCodeRules) error {
+	code, err := rules.normalize(req.Code)
+	if err != nil {
+		return err
+	}
+	req.Code = code
+	if !accountTypes[req.Type] {
+		return fmt.Errorf("invalid account type")
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		return err
+	}
+	if req.Metadata == nil {
+		req.Metadata = map[string]any{}
+	}
+	return validateMinBalance(req.MinBalance)
+}
+
+func (req *CreateAccountRequest) allowNegative() bool {
+	return req.AllowNegative == nil || *req.AllowNegative
+}
+
 // POST /v1/accounts - Create a new account
 func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var req struct {
-		Code string `json:"code"`
-		Name string `json:"name"`
-		Type string `json:"type"`
-	}
+	var req CreateAccountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
-
-	// Validate account type
-	validTypes := map[string]bool{
-		"asset": true, "liability": true, "equity": true, "revenue": true, "expense": true,
+	rules, err := h.Service.accountCodeRules(ctx, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to load account code rules", http.StatusInternalServerError)
+		return
 	}
-	if !validTypes[req.Type] {
-		http.Error(w, "invalid account type", http.StatusBadRequest)
+	if err := req.validate(rules); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
 		return
 	}
 
+	if req.TaxCode != "" {
+		var exists bool
+		err = h.Service.DB.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM tax_codes WHERE ledger_id = $1 AND code = $2)
+		`, principal.LedgerID, req.TaxCode).Scan(&exists)
+		if err != nil || !exists {
+			api.Error(w, "tax code not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	allowNegative := req.allowNegative()
+
+	if req.Entity != "" {
+		var exists bool
+		err = h.Service.DB.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM entities WHERE ledger_id = $1 AND code = $2)
+		`, principal.LedgerID, req.Entity).Scan(&exists)
+		if err != nil || !exists {
+			api.Error(w, "entity not found", http.StatusBadRequest)
+			return
+		}
+	}
+
 	var accountID string
 	err = h.Service.DB.QueryRow(ctx, `
-		INSERT INTO accounts (ledger_id, code, name, type, balance)
-		VALUES ($1, $2, $3, $4, 0)
+		INSERT INTO accounts (ledger_id, code, name, type, balance, tax_code, entity_code, metadata,
+			allow_negative_balance, min_balance)
+		VALUES ($1, $2, $3, $4, 0, NULLIF($5, ''), NULLIF($6, ''), $7, $8, NULLIF($9, '')::numeric)
 		RETURNING id
-	`, principal.LedgerID, req.Code, req.Name, req.Type).Scan(&accountID)
+	`, principal.LedgerID, req.Code, req.Name, req.Type, req.TaxCode, req.Entity, req.Metadata,
+		allowNegative, req.MinBalance).Scan(&accountID)
 	if err != nil {
-		http.Error(w, "failed to create account", http.StatusInternalServerError)
+		api.Error(w, "failed to create account", http.StatusInternalServerError)
 		return
 	}
 
-	resp := map[string]string{
-		"id":   accountID,
-		"code": req.Code,
-		"name": req.Name,
-		"type": req.Type,
+	resp := map[string]any{
+		"id":                     accountID,
+		"code":                   req.Code,
+		"name":                   req.Name,
+		"type":                   req.Type,
+		"metadata":               req.Metadata,
+		"allow_negative_balance": allowNegative,
+	}
+	if req.MinBalance != "" {
+		resp["min_balance"] = req.MinBalance
+	}
+	if req.TaxCode != "" {
+		resp["tax_code"] = req.TaxCode
+	}
+	if req.Entity != "" {
+		resp["entity"] = req.Entity
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
 }
+
+type UpdateAccountMetadataRequest struct {
+	Metadata map[string]any `json:"metadata"`
+}
+
+type UpdateAccountMetadataResponse struct {
+	ID     string `json:"id"`
+	Code   string `json:"code"`
+	Status string `json:"status"`
+}
+
+type AccountConstraintsRequest struct {
+	AllowNegative bool   `json:"allow_negative_balance"`
+	MinBalance    string `json:"min_balance,omitempty"`
+}
+
+type AccountConstraintsResponse struct {
+	Code          string `json:"code"`
+	AllowNegative bool   `json:"allow_negative_balance"`
+	MinBalance    string `json:"min_balance"`
+}
+
+type AccountStatusResponse struct {
+	Code   string `json:"code"`
+	Status string `json:"status"` // active or disabled
+}
+
+// PATCH /v1/accounts?code= - Update account metadata (JSON merge patch; null removes a key)
+func (h *Handler) UpdateAccountMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		api.Error(w, "account code required", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateAccountMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	accountID, err := h.Service.UpdateAccountMetadata(ctx, principal.LedgerID, code, req.Metadata)
+	if errors.Is(err, ErrAccountNotFound) {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UpdateAccountMetadataResponse{ID: accountID, Code: code, Status: "accepted"})
+}
+
+// PUT /v1/accounts/constraints?code= - Set an account's overdraft protection
+func (h *Handler) SetAccountConstraints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		api.Error(w, "account code required", http.StatusBadRequest)
+		return
+	}
+
+	var req AccountConstraintsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if err := validateMinBalance(req.MinBalance); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Taken under the same row lock postings use, so it applies from the next posting on
+	tag, err := h.Service.DB.Exec(ctx, `
+		UPDATE accounts
+		SET allow_negative_balance = $3, min_balance = NULLIF($4, '')::numeric
+		WHERE ledger_id = $1 AND code = $2
+	`, principal.LedgerID, code, req.AllowNegative, req.MinBalance)
+	if err != nil {
+		api.Error(w, "failed to update account", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AccountConstraintsResponse{Code: code, AllowNegative: req.AllowNegative, MinBalance: req.MinBalance})
+}
+
+func validateMinBalance(s string) error {
+	if s == "" {
+		return nil
+	}
+	if _, ok := new(big.Rat).SetString(s); !ok {
+		return fmt.Errorf("min_balance must be a decimal")
+	}
+	return nil
+}
+
+// POST /v1/accounts/disable?code= - Retire an account with a zero balance
+func (h *Handler) DisableAccount(w http.ResponseWriter, r *http.Request) {
+	h.setAccountStatus(w, r, h.Service.DisableAccount)
+}
+
+// POST /v1/accounts/enable?code= - Accept postings to a disabled account again
+func (h *Handler) EnableAccount(w http.ResponseWriter, r *http.Request) {
+	h.setAccountStatus(w, r, h.Service.EnableAccount)
+}
+
+func (h *Handler) setAccountStatus(w http.ResponseWriter, r *http.Request, set func(ctx context.Context, ledgerID, code string) error) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		api.Error(w, "account code required", http.StatusBadRequest)
+		return
+	}
+
+	err = set(ctx, principal.LedgerID, code)
+	if errors.Is(err, ErrAccountNotFound) {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrAccountNotEmpty) {
+		api.WriteError(w, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to update account", http.StatusInternalServerError)
+		return
+	}
+
+	var status string
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT status FROM accounts WHERE ledger_id = $1 AND code = $2
+	`, principal.LedgerID, code).Scan(&status)
+	if err != nil {
+		api.Error(w, "failed to load account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AccountStatusResponse{Code: code, Status: status})
+}
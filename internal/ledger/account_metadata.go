@@ -0,0 +1,56 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAccountNotFound = errors.New("account not found")
+
+// UpdateAccountMetadata appends an AccountMetadataUpdated event carrying a JSON merge
+// patch: keys with a null value are removed, all others are set. The projector applies
+// it to the accounts read model.
+func (s *Service) UpdateAccountMetadata(ctx context.Context, ledgerID, code string, patch map[string]any) (string, error) {
+	if len(patch) == 0 {
+		return "", fmt.Errorf("metadata patch is empty")
+	}
+	if err := validateMetadata(patch); err != nil {
+		return "", err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var accountID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM accounts WHERE ledger_id = $1 AND code = $2
+	`, ledgerID, code).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrAccountNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = s.appendEvent(ctx, tx, ledgerID, "account", accountID, "AccountMetadataUpdated", &events.AccountMetadataUpdated{
+		AccountID: accountID,
+		Code:      code,
+		Metadata:  patch,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return accountID, nil
+}
@@ -0,0 +1,86 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAccountNotEmpty = errors.New("account balance and held balance must be zero")
+
+// DisableAccount retires an account: it stays readable but validation rejects postings
+// to it and default listings hide it. Only an account with zero balance and no pending
+// holds can be disabled. Disabling a disabled account is a no-op.
+func (s *Service) DisableAccount(ctx context.Context, ledgerID, code string) error {
+	return s.setAccountStatus(ctx, ledgerID, code, "disabled")
+}
+
+// EnableAccount reverses DisableAccount.
+func (s *Service) EnableAccount(ctx context.Context, ledgerID, code string) error {
+	return s.setAccountStatus(ctx, ledgerID, code, "active")
+}
+
+func (s *Service) setAccountStatus(ctx context.Context, ledgerID, code, status string) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Same lock postings take, so none can land between the check and the update
+	var accountID, current, balance, held string
+	err = tx.QueryRow(ctx, `
+		SELECT id, status, balance::text, held_balance::text
+		FROM accounts
+		WHERE ledger_id = $1 AND code = $2
+		FOR UPDATE
+	`, ledgerID, code).Scan(&accountID, &current, &balance, &held)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current == status {
+		return nil
+	}
+
+	if status == "disabled" {
+		for _, amount := range []string{balance, held} {
+			v, ok := new(big.Rat).SetString(amount)
+			if !ok {
+				return fmt.Errorf("invalid balance %s", amount)
+			}
+			if v.Sign() != 0 {
+				return ErrAccountNotEmpty
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE accounts
+		SET status = $2, disabled_at = CASE WHEN $2 = 'disabled' THEN NOW() END
+		WHERE id = $1
+	`, accountID, status)
+	if err != nil {
+		return err
+	}
+
+	eventType := "AccountDisabled"
+	if status == "active" {
+		eventType = "AccountEnabled"
+	}
+	err = s.appendEvent(ctx, tx, ledgerID, "account", accountID, eventType, &events.AccountStatusChanged{
+		AccountID: accountID,
+		Code:      code,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
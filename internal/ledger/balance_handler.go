@@ -1,10 +1,13 @@
 package ledger
 
 import (
+	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type BalanceSummaryResponse struct {
@@ -14,15 +17,19 @@ type BalanceSummaryResponse struct {
 	TotalRevenue     string            `json:"total_revenue"`
 	TotalExpenses    string            `json:"total_expenses"`
 	ByType           map[string]string `json:"by_type"`
+
+	// Converted is set when a currency is requested. It is computed from postings, so
+	// balances in several currencies are converted before they are added up.
+	Converted *ConvertedBalanceSummary `json:"converted,omitempty"`
 }
 
-// GET /v1/balance/summary - Get balance summary by account type
+// GET /v1/balance/summary?currency= - Get balance summary by account type, optionally converted
 func (h *Handler) GetBalanceSummary(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -33,7 +40,7 @@ func (h *Handler) GetBalanceSummary(w http.ResponseWriter, r *http.Request) {
 		GROUP BY type
 	`, principal.LedgerID)
 	if err != nil {
-		http.Error(w, "failed to query balances", http.StatusInternalServerError)
+		api.Error(w, "failed to query balances", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -51,7 +58,7 @@ func (h *Handler) GetBalanceSummary(w http.ResponseWriter, r *http.Request) {
 		var accountType, total string
 		err = rows.Scan(&accountType, &total)
 		if err != nil {
-			http.Error(w, "failed to scan balance", http.StatusInternalServerError)
+			api.Error(w, "failed to scan balance", http.StatusInternalServerError)
 			return
 		}
 
@@ -71,10 +78,110 @@ func (h *Handler) GetBalanceSummary(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		converted, err := h.Service.ConvertedBalances(ctx, principal.LedgerID, currency, time.Now().UTC())
+		if err != nil {
+			api.Error(w, "failed to convert balances", http.StatusInternalServerError)
+			return
+		}
+		summary.Converted = &converted
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(summary)
 }
 
+type BalanceRollupResponse struct {
+	Parent      string              `json:"parent"` // empty for the whole ledger
+	Balance     string              `json:"balance"`
+	HeldBalance string              `json:"held_balance"`
+	Accounts    int                 `json:"accounts"`
+	Children    []BalanceRollupNode `json:"children"`
+}
+
+// BalanceRollupNode totals the accounts whose code is Code or starts with Code + ":".
+type BalanceRollupNode struct {
+	Code        string `json:"code"`
+	Balance     string `json:"balance"`
+	HeldBalance string `json:"held_balance"`
+	Accounts    int    `json:"accounts"`
+}
+
+// GET /v1/balance/rollup?parent=&depth= - Balances aggregated by account code segments
+//
+// Totals parent (e.g. assets:cash) and its descendants, grouped into the nodes depth
+// segments below it (default 1, the direct children). Without parent the top-level
+// segments are returned.
+func (h *Handler) GetBalanceRollup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parent := r.URL.Query().Get("parent")
+	depth := 1
+	if d := r.URL.Query().Get("depth"); d != "" {
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 1 {
+			api.Error(w, "depth must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	scope := `ledger_id = $1`
+	args := []interface{}{principal.LedgerID}
+	if parent != "" {
+		scope += ` AND (code = $2 OR code LIKE $3)`
+		args = append(args, parent, descendantPattern(parent))
+	}
+
+	resp := BalanceRollupResponse{Parent: parent, Children: []BalanceRollupNode{}}
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT COALESCE(SUM(balance), 0)::text, COALESCE(SUM(held_balance), 0)::text, COUNT(*)
+		FROM accounts
+		WHERE `+scope, args...).Scan(&resp.Balance, &resp.HeldBalance, &resp.Accounts)
+	if err != nil {
+		api.Error(w, "failed to query balances", http.StatusInternalServerError)
+		return
+	}
+	if parent != "" && resp.Accounts == 0 {
+		api.Error(w, "no accounts under "+parent, http.StatusNotFound)
+		return
+	}
+
+	// Group descendants by their first parentDepth+depth segments; shallower accounts
+	// (the parent itself, or leaves above the requested depth) form their own node
+	args = append(args, accountCodeDepth(parent)+depth)
+	rows, err := h.Service.DB.Query(ctx, fmt.Sprintf(`
+		SELECT array_to_string((string_to_array(code, ':'))[1:$%d], ':') AS node,
+			SUM(balance)::text, SUM(held_balance)::text, COUNT(*)
+		FROM accounts
+		WHERE %s AND code <> $%d
+		GROUP BY node
+		ORDER BY node
+	`, len(args), scope, len(args)+1), append(args, parent)...)
+	if err != nil {
+		api.Error(w, "failed to query balances", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n BalanceRollupNode
+		if err := rows.Scan(&n.Code, &n.Balance, &n.HeldBalance, &n.Accounts); err != nil {
+			api.Error(w, "failed to scan balance", http.StatusInternalServerError)
+			return
+		}
+		resp.Children = append(resp.Children, n)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 type AccountBalanceHistoryResponse struct {
 	AccountCode string                `json:"account_code"`
 	History     []BalanceHistoryPoint `json:"history"`
@@ -86,18 +193,21 @@ type BalanceHistoryPoint struct {
 }
 
 // GET /v1/accounts/:code/balance-history - Get balance history for an account
+//
+// basis=value_date dates each change by its transaction's value date rather than the
+// day it occurred.
 func (h *Handler) GetAccountBalanceHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	accountCode := r.URL.Query().Get("code")
 	if accountCode == "" {
-		http.Error(w, "account code required", http.StatusBadRequest)
+		api.Error(w, "account code required", http.StatusBadRequest)
 		return
 	}
 
@@ -107,23 +217,33 @@ func (h *Handler) GetAccountBalanceHistory(w http.ResponseWriter, r *http.Reques
 		SELECT id FROM accounts WHERE ledger_id = $1 AND code = $2
 	`, principal.LedgerID, accountCode).Scan(&accountID)
 	if err != nil {
-		http.Error(w, "account not found", http.StatusNotFound)
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	dateColumn := "DATE(t.occurred_at)"
+	switch r.URL.Query().Get("basis") {
+	case "", "occurred_at":
+	case "value_date":
+		dateColumn = "t.value_date"
+	default:
+		api.Error(w, "basis must be occurred_at or value_date", http.StatusBadRequest)
 		return
 	}
 
 	// Query posting history grouped by date
 	rows, err := h.Service.DB.Query(ctx, `
 		SELECT 
-			DATE(t.occurred_at) as date,
+			`+dateColumn+`::text as date,
 			SUM(CASE WHEN p.direction = 'debit' THEN p.amount ELSE -p.amount END) as net_change
 		FROM postings p
 		JOIN transactions t ON t.id = p.transaction_id
 		WHERE p.account_id = $1
-		GROUP BY DATE(t.occurred_at)
+		GROUP BY 1
 		ORDER BY date ASC
 	`, accountID)
 	if err != nil {
-		http.Error(w, "failed to query balance history", http.StatusInternalServerError)
+		api.Error(w, "failed to query balance history", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -136,7 +256,7 @@ func (h *Handler) GetAccountBalanceHistory(w http.ResponseWriter, r *http.Reques
 		var netChange float64
 		err = rows.Scan(&date, &netChange)
 		if err != nil {
-			http.Error(w, "failed to scan history", http.StatusInternalServerError)
+			api.Error(w, "failed to scan history", http.StatusInternalServerError)
 			return
 		}
 
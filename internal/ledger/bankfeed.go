@@ -0,0 +1,225 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/bankfeed"
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const maxBankLinesPerPost = 1000
+
+var ErrBankFeedNotFound = errors.New("bank feed not found")
+
+// SyncBankFeed fetches a feed's new bank lines and stages them. The feed row stays
+// locked during the fetch and the cursor advances in the same transaction as the staged
+// lines, so concurrent or interrupted syncs never skip lines. Auto-post feeds then post
+// the lines matching a rule. A sync that staged new lines records an ImportCompleted
+// event.
+func (s *Service) SyncBankFeed(ctx context.Context, ledgerID, feedID string) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var provider, cursor string
+	var credentials bankfeed.Credentials
+	var config bankfeed.Config
+	err = tx.QueryRow(ctx, `
+		SELECT provider, credentials, config, COALESCE(cursor, '')
+		FROM bank_feeds
+		WHERE id::text = $1 AND ledger_id = $2
+		FOR UPDATE
+	`, feedID, ledgerID).Scan(&provider, &credentials, &config, &cursor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrBankFeedNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	adapter, ok := bankfeed.Lookup(provider)
+	if !ok {
+		return fmt.Errorf("unknown bank feed provider %q", provider)
+	}
+
+	lines, next, fetchErr := adapter.Fetch(ctx, credentials, cursor)
+	if fetchErr != nil {
+		_, err := tx.Exec(ctx, `
+			UPDATE bank_feeds SET last_error = $2, updated_at = NOW() WHERE id = $1
+		`, feedID, fetchErr.Error())
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		return fetchErr
+	}
+
+	staged := 0
+	for _, l := range lines {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO bank_lines (feed_id, ledger_id, external_id, amount, currency, booked_at, description, counterparty)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+			ON CONFLICT (feed_id, external_id) DO NOTHING
+		`, feedID, ledgerID, l.ExternalID, l.Amount.FloatString(10), l.Currency, l.BookedAt, l.Description, l.Counterparty)
+		if err != nil {
+			return err
+		}
+		staged += int(tag.RowsAffected())
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE bank_feeds
+		SET cursor = NULLIF($2, ''), last_synced_at = NOW(), last_error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, feedID, next)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	completed := &events.ImportCompleted{Kind: "bank_feed", ImportID: feedID, Records: staged}
+	if config.AutoPost {
+		if completed.Posted, err = s.PostBankLines(ctx, ledgerID, feedID); err != nil {
+			return err
+		}
+	}
+	if staged == 0 {
+		return nil
+	}
+	return s.recordBankFeedImport(ctx, ledgerID, completed)
+}
+
+// recordBankFeedImport records the ImportCompleted event of a sync. Its discrepancies are
+// the feed's lines, new or not, that posting left unmatched or failed.
+func (s *Service) recordBankFeedImport(ctx context.Context, ledgerID string, completed *events.ImportCompleted) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM bank_lines WHERE feed_id = $1 AND status IN ('unmatched', 'failed')
+	`, completed.ImportID).Scan(&completed.Discrepancies)
+	if err != nil {
+		return err
+	}
+	if err := s.appendEvent(ctx, tx, ledgerID, "bank_feed", completed.ImportID, "ImportCompleted", completed); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// PostBankLines posts the feed's open lines (staged, unmatched or failed) that match a
+// rule, and returns how many were posted. Lines matching no rule are marked unmatched
+// and are tried again on the next call, e.g. after the rules changed.
+func (s *Service) PostBankLines(ctx context.Context, ledgerID, feedID string) (int, error) {
+	var config bankfeed.Config
+	err := s.DB.QueryRow(ctx, `
+		SELECT config FROM bank_feeds WHERE id::text = $1 AND ledger_id = $2
+	`, feedID, ledgerID).Scan(&config)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrBankFeedNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := s.DB.Query(ctx, `
+		SELECT id, external_id, amount::text, currency, booked_at, COALESCE(description, ''), COALESCE(counterparty, '')
+		FROM bank_lines
+		WHERE feed_id = $1 AND status IN ('staged', 'unmatched', 'failed')
+		ORDER BY booked_at
+		LIMIT $2
+	`, feedID, maxBankLinesPerPost)
+	if err != nil {
+		return 0, err
+	}
+
+	type openLine struct {
+		ID string
+		bankfeed.Line
+	}
+	var open []openLine
+	for rows.Next() {
+		var l openLine
+		var amount string
+		err := rows.Scan(&l.ID, &l.ExternalID, &amount, &l.Currency, &l.BookedAt, &l.Description, &l.Counterparty)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		l.Amount, _ = new(big.Rat).SetString(amount)
+		open = append(open, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	for _, l := range open {
+		status, reason, transactionID := s.postBankLine(ctx, ledgerID, feedID, config, l.ID, l.Line)
+		if status == "posted" {
+			posted++
+		}
+		_, err := s.DB.Exec(ctx, `
+			UPDATE bank_lines
+			SET status = $2, reason = NULLIF($3, ''), transaction_id = NULLIF($4, '')::uuid, updated_at = NOW()
+			WHERE id = $1
+		`, l.ID, status, reason, transactionID)
+		if err != nil {
+			return posted, err
+		}
+	}
+	return posted, nil
+}
+
+// postBankLine books a line against the bank account and its rule's account.
+func (s *Service) postBankLine(ctx context.Context, ledgerID, feedID string, config bankfeed.Config, lineID string, l bankfeed.Line) (status, reason, transactionID string) {
+	rule, ok := config.RuleFor(l)
+	if !ok {
+		return "unmatched", "no rule matches", ""
+	}
+
+	debit, credit := config.BankAccount, rule.Account
+	if l.Amount.Sign() < 0 {
+		debit, credit = credit, debit
+	}
+	amount := new(big.Rat).Abs(l.Amount).FloatString(10)
+
+	metadata := map[string]any{"bank_feed_id": feedID}
+	if l.Description != "" {
+		metadata["description"] = l.Description
+	}
+	if l.Counterparty != "" {
+		metadata["counterparty"] = l.Counterparty
+	}
+
+	transactionID, err := s.PostTransaction(ctx, PostTransactionCommand{
+		LedgerID:       ledgerID,
+		ExternalID:     l.ExternalID,
+		IdempotencyKey: "bankline:" + lineID,
+		Currency:       l.Currency,
+		OccurredAt:     l.BookedAt,
+		Metadata:       metadata,
+		Postings: []PostingInput{
+			{AccountCode: debit, Direction: "debit", Amount: amount},
+			{AccountCode: credit, Direction: "credit", Amount: amount},
+		},
+	})
+	if err != nil {
+		return "failed", err.Error(), ""
+	}
+	return "posted", "", transactionID
+}
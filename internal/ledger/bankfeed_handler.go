@@ -0,0 +1,277 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/bankfeed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type BankFeedRequest struct {
+	Name        string               `json:"name"`
+	Provider    string               `json:"provider"` // plaid, truelayer or nordigen
+	Credentials bankfeed.Credentials `json:"credentials"`
+	Config      bankfeed.Config      `json:"config"`
+	Status      string               `json:"status,omitempty"` // active (default) or paused
+}
+
+type BankFeedResponse struct {
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Provider     string          `json:"provider"`
+	Config       bankfeed.Config `json:"config"`
+	Status       string          `json:"status"`
+	LastSyncedAt string          `json:"last_synced_at,omitempty"`
+	LastError    string          `json:"last_error,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+	UpdatedAt    string          `json:"updated_at"`
+}
+
+type BankLineResponse struct {
+	ID            string `json:"id"`
+	FeedID        string `json:"feed_id"`
+	ExternalID    string `json:"external_id"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	BookedAt      string `json:"booked_at"`
+	Description   string `json:"description,omitempty"`
+	Counterparty  string `json:"counterparty,omitempty"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// Credentials are write-only and never returned
+const bankFeedSelect = `
+	SELECT id, name, provider, config, status, last_synced_at, COALESCE(last_error, ''), created_at, updated_at
+	FROM bank_feeds
+	WHERE ledger_id = $1
+`
+
+// POST /v1/bank-feeds - Create or replace a named open banking feed
+func (h *Handler) SaveBankFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BankFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || req.Credentials.AccessToken == "" {
+		api.Error(w, "name and credentials.access_token required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := bankfeed.Lookup(req.Provider); !ok {
+		api.Error(w, "provider must be plaid, truelayer or nordigen", http.StatusBadRequest)
+		return
+	}
+	if req.Provider != "plaid" && req.Credentials.AccountID == "" {
+		api.Error(w, "credentials.account_id required", http.StatusBadRequest)
+		return
+	}
+	if req.Status == "" {
+		req.Status = "active"
+	}
+	if req.Status != "active" && req.Status != "paused" {
+		api.Error(w, "status must be active or paused", http.StatusBadRequest)
+		return
+	}
+	if err := req.Config.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Switching provider or account starts the feed over
+	var feedID string
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO bank_feeds (ledger_id, name, provider, credentials, config, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (ledger_id, name) DO UPDATE
+		SET provider = EXCLUDED.provider, credentials = EXCLUDED.credentials, config = EXCLUDED.config,
+			status = EXCLUDED.status, updated_at = NOW(),
+			cursor = CASE
+				WHEN bank_feeds.provider = EXCLUDED.provider
+					AND bank_feeds.credentials->>'account_id' IS NOT DISTINCT FROM EXCLUDED.credentials->>'account_id'
+				THEN bank_feeds.cursor
+			END
+		RETURNING id
+	`, principal.LedgerID, req.Name, req.Provider, req.Credentials, req.Config, req.Status).Scan(&feedID)
+	if err != nil {
+		api.Error(w, "failed to save bank feed", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeBankFeed(w, r, principal.LedgerID, feedID)
+}
+
+// GET /v1/bank-feeds - List open banking feeds
+func (h *Handler) ListBankFeeds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, bankFeedSelect+` ORDER BY name`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query bank feeds", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	feeds := []BankFeedResponse{}
+	for rows.Next() {
+		feed, err := scanBankFeed(rows)
+		if err != nil {
+			api.Error(w, "failed to scan bank feed", http.StatusInternalServerError)
+			return
+		}
+		feeds = append(feeds, feed)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feeds)
+}
+
+// POST /v1/bank-feeds/sync?id= - Fetch new bank lines now instead of waiting for the worker
+func (h *Handler) SyncBankFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	feedID := r.URL.Query().Get("id")
+	err = h.Service.SyncBankFeed(ctx, principal.LedgerID, feedID)
+	if errors.Is(err, ErrBankFeedNotFound) {
+		api.Error(w, "bank feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "sync failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	h.writeBankFeed(w, r, principal.LedgerID, feedID)
+}
+
+// POST /v1/bank-feeds/post?id= - Post the feed's open lines that match a rule
+func (h *Handler) PostBankLines(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	posted, err := h.Service.PostBankLines(ctx, principal.LedgerID, r.URL.Query().Get("id"))
+	if errors.Is(err, ErrBankFeedNotFound) {
+		api.Error(w, "bank feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to post bank lines", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"posted": posted})
+}
+
+// GET /v1/bank-lines?feed=&status= - Staged bank lines, e.g. the unmatched ones to reconcile
+func (h *Handler) ListBankLines(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := `
+		SELECT id, feed_id, external_id, amount::text, currency, booked_at, COALESCE(description, ''),
+			COALESCE(counterparty, ''), status, COALESCE(reason, ''), COALESCE(transaction_id::text, '')
+		FROM bank_lines
+		WHERE ledger_id = $1
+	`
+	args := []interface{}{principal.LedgerID}
+	if feed := r.URL.Query().Get("feed"); feed != "" {
+		args = append(args, feed)
+		query += fmt.Sprintf(` AND feed_id::text = $%d`, len(args))
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(` AND status = $%d`, len(args))
+	}
+	query += ` ORDER BY booked_at DESC, created_at DESC LIMIT 500`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query bank lines", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	lines := []BankLineResponse{}
+	for rows.Next() {
+		var l BankLineResponse
+		var bookedAt time.Time
+		err := rows.Scan(&l.ID, &l.FeedID, &l.ExternalID, &l.Amount, &l.Currency, &bookedAt, &l.Description,
+			&l.Counterparty, &l.Status, &l.Reason, &l.TransactionID)
+		if err != nil {
+			api.Error(w, "failed to scan bank line", http.StatusInternalServerError)
+			return
+		}
+		l.BookedAt = bookedAt.Format(time.RFC3339)
+		lines = append(lines, l)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lines)
+}
+
+func (h *Handler) writeBankFeed(w http.ResponseWriter, r *http.Request, ledgerID, feedID string) {
+	feed, err := scanBankFeed(h.Service.DB.QueryRow(r.Context(), bankFeedSelect+` AND id::text = $2`, ledgerID, feedID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "bank feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load bank feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+func scanBankFeed(row pgx.Row) (BankFeedResponse, error) {
+	var feed BankFeedResponse
+	var lastSyncedAt *time.Time
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&feed.ID, &feed.Name, &feed.Provider, &feed.Config, &feed.Status, &lastSyncedAt,
+		&feed.LastError, &createdAt, &updatedAt)
+	if lastSyncedAt != nil {
+		feed.LastSyncedAt = lastSyncedAt.Format(time.RFC3339)
+	}
+	feed.CreatedAt = createdAt.Format(time.RFC3339)
+	feed.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return feed, err
+}
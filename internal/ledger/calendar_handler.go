@@ -0,0 +1,252 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/calendar"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CalendarSettings are a ledger's weekend days, by lowercase English name.
+type CalendarSettings struct {
+	Weekend []string `json:"weekend"`
+}
+
+type HolidayRequest struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name,omitempty"`
+}
+
+type HolidayResponse struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// Calendar returns the ledger's business calendar: its weekend days, Saturday and Sunday
+// unless configured otherwise, plus its holidays.
+func (s *Service) Calendar(ctx context.Context, ledgerID string) (calendar.Calendar, error) {
+	cal := calendar.Default()
+	var weekend []int16
+	if err := s.DB.QueryRow(ctx, `SELECT weekend FROM ledgers WHERE id = $1`, ledgerID).Scan(&weekend); err != nil {
+		return cal, err
+	}
+	cal.Weekend = cal.Weekend[:0]
+	for _, day := range weekend {
+		cal.Weekend = append(cal.Weekend, time.Weekday(day))
+	}
+
+	rows, err := s.DB.Query(ctx, `SELECT date, name FROM ledger_holidays WHERE ledger_id = $1`, ledgerID)
+	if err != nil {
+		return cal, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date time.Time
+		var name string
+		if err := rows.Scan(&date, &name); err != nil {
+			return cal, err
+		}
+		cal.Holidays[calendar.FormatDate(date)] = name
+	}
+	return cal, rows.Err()
+}
+
+// GET /v1/calendar - The ledger's weekend days
+func (h *Handler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cal, err := h.Service.Calendar(ctx, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendarSettings(cal))
+}
+
+// PUT /v1/calendar - Set the ledger's weekend days, e.g. ["friday", "saturday"]
+//
+// As with holidays, schedules already created keep their installment dates. An empty
+// list makes every day not a holiday a business day.
+func (h *Handler) UpdateCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CalendarSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Weekend == nil {
+		api.Error(w, "weekend required", http.StatusBadRequest)
+		return
+	}
+	cal := calendar.Calendar{}
+	days := []int16{}
+	for _, name := range req.Weekend {
+		day, err := calendar.ParseWeekday(name)
+		if err != nil {
+			api.WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(cal.Weekend, day) {
+			cal.Weekend = append(cal.Weekend, day)
+			days = append(days, int16(day))
+		}
+	}
+	if len(days) == 7 {
+		api.Error(w, "a calendar needs at least one business day a week", http.StatusBadRequest)
+		return
+	}
+	slices.Sort(days)
+
+	_, err = h.Service.DB.Exec(ctx, `UPDATE ledgers SET weekend = $2 WHERE id = $1`, principal.LedgerID, days)
+	if err != nil {
+		api.Error(w, "failed to update calendar", http.StatusInternalServerError)
+		return
+	}
+	slices.Sort(cal.Weekend)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calendarSettings(cal))
+}
+
+func calendarSettings(cal calendar.Calendar) CalendarSettings {
+	settings := CalendarSettings{Weekend: []string{}}
+	for _, day := range cal.Weekend {
+		settings.Weekend = append(settings.Weekend, strings.ToLower(day.String()))
+	}
+	return settings
+}
+
+// GET /v1/holidays?from=&to= - List the ledger's holidays, optionally within dates
+func (h *Handler) ListHolidays(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var bounds [2]*time.Time
+	for i, key := range []string{"from", "to"} {
+		if value := r.URL.Query().Get(key); value != "" {
+			date, err := calendar.ParseDate(value)
+			if err != nil {
+				api.Error(w, key+" must be YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			bounds[i] = &date
+		}
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT date::text, name FROM ledger_holidays
+		WHERE ledger_id = $1 AND ($2::date IS NULL OR date >= $2) AND ($3::date IS NULL OR date <= $3)
+		ORDER BY date
+	`, principal.LedgerID, bounds[0], bounds[1])
+	if err != nil {
+		api.Error(w, "failed to query holidays", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	holidays := []HolidayResponse{}
+	for rows.Next() {
+		var holiday HolidayResponse
+		if err := rows.Scan(&holiday.Date, &holiday.Name); err != nil {
+			api.Error(w, "failed to scan holiday", http.StatusInternalServerError)
+			return
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
+
+// POST /v1/holidays - Add a holiday to the ledger's calendar, or rename it
+//
+// Schedules already created keep their installment dates; the calendar applies to
+// those created afterwards.
+func (h *Handler) SaveHoliday(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req HolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	date, err := calendar.ParseDate(req.Date)
+	if err != nil {
+		api.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO ledger_holidays (ledger_id, date, name) VALUES ($1, $2, $3)
+		ON CONFLICT (ledger_id, date) DO UPDATE SET name = EXCLUDED.name
+	`, principal.LedgerID, date, req.Name)
+	if err != nil {
+		api.Error(w, "failed to save holiday", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(HolidayResponse{Date: calendar.FormatDate(date), Name: req.Name})
+}
+
+// DELETE /v1/holidays/{date} - Remove a holiday from the ledger's calendar
+func (h *Handler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	date, err := calendar.ParseDate(r.PathValue("date"))
+	if err != nil {
+		api.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	tag, err := h.Service.DB.Exec(ctx, `
+		DELETE FROM ledger_holidays WHERE ledger_id = $1 AND date = $2
+	`, principal.LedgerID, date)
+	if err != nil {
+		api.Error(w, "failed to delete holiday", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "holiday not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
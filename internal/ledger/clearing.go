@@ -0,0 +1,228 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/clearing"
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/hooks"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	clearingPosted         = "posted"
+	clearingMatched        = "matched"
+	clearingAmountMismatch = "amount_mismatch"
+	clearingDuplicate      = "duplicate"
+	clearingUnmatched      = "unmatched"
+	clearingInvalid        = "invalid"
+	clearingFailed         = "failed"
+)
+
+type ClearingImportCommand struct {
+	LedgerID  string
+	MappingID string
+	Filename  string
+	File      io.Reader
+}
+
+// ClearingFileError is returned by ImportClearing when the file cannot be read with its
+// mapping; nothing is imported.
+type ClearingFileError struct {
+	Err error
+}
+
+func (e *ClearingFileError) Error() string { return e.Err.Error() }
+func (e *ClearingFileError) Unwrap() error { return e.Err }
+
+// ImportClearing parses a clearing file with the given mapping and reconciles each record
+// against the ledger: records already present (by external_id) are checked for amount
+// agreement, new records matching a posting rule are posted, and everything else is kept
+// as a discrepancy on the import.
+//
+// The whole file is imported in one transaction, so the import, its records, their
+// postings and the ImportCompleted event are recorded together or not at all. A record
+// whose posting is refused is kept as failed without failing the others.
+func (s *Service) ImportClearing(ctx context.Context, cmd ClearingImportCommand) (string, error) {
+	var configJSON []byte
+	err := s.DB.QueryRow(ctx, `
+		SELECT config FROM clearing_mappings WHERE id = $1 AND ledger_id = $2
+	`, cmd.MappingID, cmd.LedgerID).Scan(&configJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("clearing mapping not found")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var mapping clearing.Mapping
+	if err := json.Unmarshal(configJSON, &mapping); err != nil {
+		return "", err
+	}
+
+	records, err := clearing.Parse(cmd.File, mapping)
+	if err != nil {
+		return "", &ClearingFileError{Err: err}
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var importID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO clearing_imports (ledger_id, mapping_id, filename, total_records)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id
+	`, cmd.LedgerID, cmd.MappingID, cmd.Filename, len(records)).Scan(&importID)
+	if err != nil {
+		return "", err
+	}
+
+	seen := map[string]bool{}
+	var posted []*hooks.Transaction
+	for _, rec := range records {
+		status, reason, transactionID, transaction, err := s.clearRecord(ctx, tx, cmd.LedgerID, mapping, rec, seen)
+		if err != nil {
+			return "", err
+		}
+		posted = append(posted, transaction)
+
+		var amount *string
+		if rec.Amount != nil {
+			a := rec.Amount.FloatString(10)
+			amount = &a
+		}
+		var occurredAt any
+		if !rec.OccurredAt.IsZero() {
+			occurredAt = rec.OccurredAt
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO clearing_import_records (import_id, line, reference, record_type, amount, currency,
+				occurred_at, status, reason, transaction_id)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), $7, $8, NULLIF($9, ''), NULLIF($10, '')::uuid)
+		`, importID, rec.Line, rec.Reference, rec.Type, amount, rec.Currency, occurredAt, status, reason, transactionID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	completed := &events.ImportCompleted{Kind: "clearing", ImportID: importID, Filename: cmd.Filename, Records: len(records)}
+	err = tx.QueryRow(ctx, `
+		UPDATE clearing_imports
+		SET posted_count = c.posted, matched_count = c.matched, discrepancy_count = c.total - c.posted - c.matched
+		FROM (
+			SELECT COUNT(*) FILTER (WHERE status = 'posted') AS posted,
+				COUNT(*) FILTER (WHERE status = 'matched') AS matched,
+				COUNT(*) AS total
+			FROM clearing_import_records WHERE import_id = $1
+		) c
+		WHERE id = $1
+		RETURNING posted_count, matched_count, discrepancy_count
+	`, importID).Scan(&completed.Posted, &completed.Matched, &completed.Discrepancies)
+	if err != nil {
+		return "", err
+	}
+	if err := s.appendEvent(ctx, tx, cmd.LedgerID, "clearing_import", importID, "ImportCompleted", completed); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	for _, p := range posted {
+		s.postCommit(ctx, p)
+	}
+	return importID, nil
+}
+
+// clearRecord decides the outcome of one clearing record and posts it within tx when it
+// matches a rule, returning the transaction posted for postCommit. The posting is made
+// under a savepoint, so one refused leaves tx usable; err is set only when tx is not.
+func (s *Service) clearRecord(ctx context.Context, tx pgx.Tx, ledgerID string, m clearing.Mapping, rec clearing.Record, seen map[string]bool) (status, reason, transactionID string, posted *hooks.Transaction, err error) {
+	if rec.Err != nil {
+		return clearingInvalid, rec.Err.Error(), "", nil, nil
+	}
+	if rec.Currency == "" {
+		return clearingInvalid, "missing currency", "", nil, nil
+	}
+	if rec.Amount.Sign() == 0 {
+		return clearingInvalid, "zero amount", "", nil, nil
+	}
+
+	externalID := m.ExternalIDPrefix + rec.Reference
+	if seen[externalID] {
+		return clearingDuplicate, "reference appears more than once in the file", "", nil, nil
+	}
+	seen[externalID] = true
+
+	amount := new(big.Rat).Abs(rec.Amount)
+
+	// Already in the ledger: verify the amounts agree
+	var existingID, existingCurrency, existingAmount string
+	err = tx.QueryRow(ctx, `
+		SELECT t.id, t.currency, COALESCE(SUM(p.amount) FILTER (WHERE p.direction = 'debit'), 0)::text
+		FROM transactions t
+		LEFT JOIN postings p ON p.transaction_id = t.id
+		WHERE t.ledger_id = $1 AND t.external_id = $2
+		GROUP BY t.id, t.currency
+		LIMIT 1
+	`, ledgerID, externalID).Scan(&existingID, &existingCurrency, &existingAmount)
+	if err == nil {
+		ledgerAmount, _ := new(big.Rat).SetString(existingAmount)
+		if existingCurrency != rec.Currency || ledgerAmount.Cmp(amount) != 0 {
+			return clearingAmountMismatch, fmt.Sprintf("ledger has %s %s, clearing has %s %s",
+				ledgerAmount.FloatString(2), existingCurrency, amount.FloatString(2), rec.Currency), existingID, nil, nil
+		}
+		return clearingMatched, "", existingID, nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", "", "", nil, err
+	}
+
+	rule, ok := m.RuleFor(rec.Type)
+	if !ok {
+		return clearingUnmatched, fmt.Sprintf("no posting rule for type %q", rec.Type), "", nil, nil
+	}
+
+	debit, credit := rule.DebitAccount, rule.CreditAccount
+	if rec.Amount.Sign() < 0 {
+		debit, credit = credit, debit
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	amountStr := amount.FloatString(10)
+	transactionID, posted, err = s.postTransactionTx(ctx, savepoint, PostTransactionCommand{
+		LedgerID:       ledgerID,
+		ExternalID:     externalID,
+		IdempotencyKey: "clearing:" + externalID,
+		Currency:       rec.Currency,
+		OccurredAt:     rec.OccurredAt,
+		Postings: []PostingInput{
+			{AccountCode: debit, Direction: "debit", Amount: amountStr},
+			{AccountCode: credit, Direction: "credit", Amount: amountStr},
+		},
+	})
+	var pending *PendingReviewError
+	if errors.As(err, &pending) {
+		// Nothing is posted, but the review is kept
+		return clearingFailed, err.Error(), "", nil, savepoint.Commit(ctx)
+	}
+	if err != nil {
+		return clearingFailed, err.Error(), "", nil, savepoint.Rollback(ctx)
+	}
+	return clearingPosted, "", transactionID, posted, savepoint.Commit(ctx)
+}
@@ -0,0 +1,319 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/clearing"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const maxClearingFileSize = 32 << 20
+
+type ClearingMappingRequest struct {
+	Name    string           `json:"name"`
+	Mapping clearing.Mapping `json:"mapping"`
+}
+
+type ClearingMappingResponse struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Mapping   clearing.Mapping `json:"mapping"`
+	CreatedAt string           `json:"created_at"`
+	UpdatedAt string           `json:"updated_at"`
+}
+
+type ClearingImportResponse struct {
+	ID               string `json:"id"`
+	MappingID        string `json:"mapping_id"`
+	Filename         string `json:"filename,omitempty"`
+	TotalRecords     int    `json:"total_records"`
+	PostedCount      int    `json:"posted_count"`
+	MatchedCount     int    `json:"matched_count"`
+	DiscrepancyCount int    `json:"discrepancy_count"`
+	CreatedAt        string `json:"created_at"`
+}
+
+type ClearingDiscrepancy struct {
+	Line          int    `json:"line"`
+	Reference     string `json:"reference,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Amount        string `json:"amount,omitempty"`
+	Currency      string `json:"currency,omitempty"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+const clearingImportSelect = `
+	SELECT id, mapping_id, COALESCE(filename, ''), total_records, posted_count, matched_count,
+		discrepancy_count, created_at
+	FROM clearing_imports
+	WHERE ledger_id = $1
+`
+
+// POST /v1/clearing/mappings - Create or replace a named clearing file mapping
+func (h *Handler) SaveClearingMapping(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ClearingMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		api.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	if err := req.Mapping.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	config, err := json.Marshal(req.Mapping)
+	if err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	resp := ClearingMappingResponse{Name: req.Name, Mapping: req.Mapping}
+	var createdAt, updatedAt time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO clearing_mappings (ledger_id, name, config)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (ledger_id, name) DO UPDATE SET config = EXCLUDED.config, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, principal.LedgerID, req.Name, config).Scan(&resp.ID, &createdAt, &updatedAt)
+	if err != nil {
+		api.Error(w, "failed to save clearing mapping", http.StatusInternalServerError)
+		return
+	}
+	resp.CreatedAt = createdAt.Format(time.RFC3339)
+	resp.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /v1/clearing/mappings - List clearing file mappings
+func (h *Handler) ListClearingMappings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT id, name, config, created_at, updated_at
+		FROM clearing_mappings
+		WHERE ledger_id = $1
+		ORDER BY name
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query clearing mappings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	mappings := []ClearingMappingResponse{}
+	for rows.Next() {
+		var m ClearingMappingResponse
+		var config []byte
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&m.ID, &m.Name, &config, &createdAt, &updatedAt); err != nil {
+			api.Error(w, "failed to scan clearing mapping", http.StatusInternalServerError)
+			return
+		}
+		if err := json.Unmarshal(config, &m.Mapping); err != nil {
+			api.Error(w, "failed to decode clearing mapping", http.StatusInternalServerError)
+			return
+		}
+		m.CreatedAt = createdAt.Format(time.RFC3339)
+		m.UpdatedAt = updatedAt.Format(time.RFC3339)
+		mappings = append(mappings, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mappings)
+}
+
+// POST /v1/clearing/imports?mapping=<name>&filename= - Ingest a clearing file (CSV request body)
+func (h *Handler) CreateClearingImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mappingName := r.URL.Query().Get("mapping")
+	if mappingName == "" {
+		api.Error(w, "mapping required", http.StatusBadRequest)
+		return
+	}
+
+	var mappingID string
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT id FROM clearing_mappings WHERE ledger_id = $1 AND name = $2
+	`, principal.LedgerID, mappingName).Scan(&mappingID)
+	if err != nil {
+		api.Error(w, "clearing mapping not found", http.StatusNotFound)
+		return
+	}
+
+	importID, err := h.Service.ImportClearing(ctx, ClearingImportCommand{
+		LedgerID:  principal.LedgerID,
+		MappingID: mappingID,
+		Filename:  r.URL.Query().Get("filename"),
+		File:      http.MaxBytesReader(w, r.Body, maxClearingFileSize),
+	})
+	var fileErr *ClearingFileError
+	if errors.As(err, &fileErr) {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		api.Error(w, "clearing import failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	imp, err := scanClearingImport(h.Service.DB.QueryRow(ctx, clearingImportSelect+` AND id = $2`, principal.LedgerID, importID))
+	if err != nil {
+		api.Error(w, "failed to load clearing import", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(imp)
+}
+
+// GET /v1/clearing/imports - List clearing imports (or one with ?id=)
+func (h *Handler) ListClearingImports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if id := r.URL.Query().Get("id"); id != "" {
+		imp, err := scanClearingImport(h.Service.DB.QueryRow(ctx, clearingImportSelect+` AND id = $2`, principal.LedgerID, id))
+		if err != nil {
+			api.Error(w, "clearing import not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(imp)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, clearingImportSelect+` ORDER BY created_at DESC`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query clearing imports", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	imports := []ClearingImportResponse{}
+	for rows.Next() {
+		imp, err := scanClearingImport(rows)
+		if err != nil {
+			api.Error(w, "failed to scan clearing import", http.StatusInternalServerError)
+			return
+		}
+		imports = append(imports, imp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imports)
+}
+
+// GET /v1/clearing/discrepancies?id=&format=csv - Unmatched and mismatched records of an import
+func (h *Handler) GetClearingDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	importID := r.URL.Query().Get("id")
+	if importID == "" {
+		api.Error(w, "clearing import id required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := scanClearingImport(h.Service.DB.QueryRow(ctx, clearingImportSelect+` AND id = $2`, principal.LedgerID, importID)); err != nil {
+		api.Error(w, "clearing import not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT line, COALESCE(reference, ''), COALESCE(record_type, ''), COALESCE(amount::text, ''),
+			COALESCE(currency, ''), status, COALESCE(reason, ''), COALESCE(transaction_id::text, '')
+		FROM clearing_import_records
+		WHERE import_id = $1 AND status NOT IN ('posted', 'matched')
+		ORDER BY line
+	`, importID)
+	if err != nil {
+		api.Error(w, "failed to query discrepancies", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	discrepancies := []ClearingDiscrepancy{}
+	for rows.Next() {
+		var d ClearingDiscrepancy
+		if err := rows.Scan(&d.Line, &d.Reference, &d.Type, &d.Amount, &d.Currency, &d.Status, &d.Reason, &d.TransactionID); err != nil {
+			api.Error(w, "failed to scan discrepancy", http.StatusInternalServerError)
+			return
+		}
+		discrepancies = append(discrepancies, d)
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="clearing-discrepancies-`+importID+`.csv"`)
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"line", "reference", "type", "amount", "currency", "status", "reason", "transaction_id"})
+		for _, d := range discrepancies {
+			cw.Write([]string{strconv.Itoa(d.Line), d.Reference, d.Type, d.Amount, d.Currency, d.Status, d.Reason, d.TransactionID})
+		}
+		cw.Flush()
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discrepancies)
+}
+
+func scanClearingImport(row pgx.Row) (ClearingImportResponse, error) {
+	var imp ClearingImportResponse
+	var createdAt time.Time
+	err := row.Scan(&imp.ID, &imp.MappingID, &imp.Filename, &imp.TotalRecords, &imp.PostedCount,
+		&imp.MatchedCount, &imp.DiscrepancyCount, &createdAt)
+	if err != nil {
+		return imp, err
+	}
+	imp.CreatedAt = createdAt.Format(time.RFC3339)
+	return imp, nil
+}
@@ -0,0 +1,154 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/connectors"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	connectorPosted  = "posted"
+	connectorIgnored = "ignored"
+	connectorFailed  = "failed"
+)
+
+var ErrConnectorNotFound = errors.New("connector not found")
+
+type ConnectorWebhookCommand struct {
+	LedgerID    string
+	ConnectorID string
+	Header      http.Header
+	Body        []byte
+}
+
+type ConnectorEventResult struct {
+	EventID       string `json:"event_id"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+}
+
+// IngestConnectorWebhook verifies a provider webhook, records each event it carries
+// and posts the events matching the connector's rules. Providers redeliver webhooks,
+// so events already posted or ignored are skipped and failed ones are tried again.
+// The ack is the body the provider expects in response.
+func (s *Service) IngestConnectorWebhook(ctx context.Context, cmd ConnectorWebhookCommand) (results []ConnectorEventResult, ack string, err error) {
+	var providerName, secret string
+	var configJSON []byte
+	err = s.DB.QueryRow(ctx, `
+		SELECT provider, secret, config FROM connectors WHERE id::text = $1 AND ledger_id = $2
+	`, cmd.ConnectorID, cmd.LedgerID).Scan(&providerName, &secret, &configJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", ErrConnectorNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	provider, ok := connectors.Lookup(providerName)
+	if !ok {
+		return nil, "", fmt.Errorf("unknown provider %q", providerName)
+	}
+	if err := provider.Verify(cmd.Header, cmd.Body, secret); err != nil {
+		return nil, "", err
+	}
+
+	var mapping connectors.Mapping
+	if err := json.Unmarshal(configJSON, &mapping); err != nil {
+		return nil, "", err
+	}
+
+	events, err := provider.Parse(cmd.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, e := range events {
+		result, err := s.ingestConnectorEvent(ctx, cmd.LedgerID, cmd.ConnectorID, providerName, mapping, e)
+		if err != nil {
+			return results, "", err
+		}
+		results = append(results, result)
+	}
+	return results, provider.Ack(), nil
+}
+
+func (s *Service) ingestConnectorEvent(ctx context.Context, ledgerID, connectorID, providerName string, m connectors.Mapping, e connectors.Event) (ConnectorEventResult, error) {
+	result := ConnectorEventResult{EventID: e.ID}
+
+	var previous, previousTransaction string
+	err := s.DB.QueryRow(ctx, `
+		SELECT status, COALESCE(transaction_id::text, '') FROM connector_events
+		WHERE connector_id = $1 AND provider_event_id = $2
+	`, connectorID, e.ID).Scan(&previous, &previousTransaction)
+	if err == nil && previous != connectorFailed {
+		result.Status, result.TransactionID = previous, previousTransaction
+		return result, nil
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return result, err
+	}
+
+	result.Status, result.Reason, result.TransactionID = s.postConnectorEvent(ctx, ledgerID, connectorID, providerName, m, e)
+
+	var amount *string
+	if e.Amount != nil {
+		a := e.Amount.FloatString(10)
+		amount = &a
+	}
+	_, err = s.DB.Exec(ctx, `
+		INSERT INTO connector_events (connector_id, provider_event_id, event_type, reference, amount, currency,
+			status, reason, transaction_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, '')::uuid)
+		ON CONFLICT (connector_id, provider_event_id) DO UPDATE
+		SET status = EXCLUDED.status, reason = EXCLUDED.reason, transaction_id = EXCLUDED.transaction_id,
+			attempts = connector_events.attempts + 1, updated_at = NOW()
+	`, connectorID, e.ID, e.Type, e.Reference, amount, e.Currency, result.Status, result.Reason, result.TransactionID)
+	return result, err
+}
+
+// postConnectorEvent decides the outcome of one event and posts it when it matches a rule.
+func (s *Service) postConnectorEvent(ctx context.Context, ledgerID, connectorID, providerName string, m connectors.Mapping, e connectors.Event) (status, reason, transactionID string) {
+	if e.Amount == nil || e.Amount.Sign() == 0 {
+		return connectorIgnored, "no amount", ""
+	}
+	rule, ok := m.RuleFor(e.Type)
+	if !ok {
+		return connectorIgnored, fmt.Sprintf("no rule for event type %q", e.Type), ""
+	}
+	debit, credit, err := rule.Accounts(e)
+	if err != nil {
+		return connectorFailed, err.Error(), ""
+	}
+
+	metadata := map[string]any{}
+	for k, v := range e.Metadata {
+		metadata[k] = v
+	}
+	metadata["provider"] = providerName
+	metadata["provider_event_type"] = e.Type
+	metadata["provider_reference"] = e.Reference
+
+	amount := e.Amount.FloatString(10)
+	transactionID, err = s.PostTransaction(ctx, PostTransactionCommand{
+		LedgerID:       ledgerID,
+		ExternalID:     m.ExternalIDPrefix + e.ID,
+		IdempotencyKey: "connector:" + connectorID + ":" + e.ID,
+		Currency:       e.Currency,
+		OccurredAt:     e.OccurredAt,
+		Metadata:       metadata,
+		Postings: []PostingInput{
+			{AccountCode: debit, Direction: "debit", Amount: amount},
+			{AccountCode: credit, Direction: "credit", Amount: amount},
+		},
+	})
+	if err != nil {
+		return connectorFailed, err.Error(), ""
+	}
+	return connectorPosted, "", transactionID
+}
@@ -0,0 +1,240 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/connectors"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const maxConnectorWebhookSize = 1 << 20
+
+type ConnectorRequest struct {
+	Name     string             `json:"name"`
+	Provider string             `json:"provider"` // stripe or adyen
+	Secret   string             `json:"secret"`   // the provider's webhook signing secret
+	Mapping  connectors.Mapping `json:"mapping"`
+}
+
+type ConnectorResponse struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Provider   string             `json:"provider"`
+	Mapping    connectors.Mapping `json:"mapping"`
+	WebhookURL string             `json:"webhook_url"` // path to configure at the provider
+	CreatedAt  string             `json:"created_at"`
+	UpdatedAt  string             `json:"updated_at"`
+}
+
+type ConnectorEventResponse struct {
+	ProviderEventID string `json:"provider_event_id"`
+	EventType       string `json:"event_type"`
+	Reference       string `json:"reference,omitempty"`
+	Amount          string `json:"amount,omitempty"`
+	Currency        string `json:"currency,omitempty"`
+	Status          string `json:"status"`
+	Reason          string `json:"reason,omitempty"`
+	TransactionID   string `json:"transaction_id,omitempty"`
+	Attempts        int    `json:"attempts"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+// POST /v1/connectors - Create or replace a named PSP connector
+func (h *Handler) SaveConnector(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ConnectorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || req.Secret == "" {
+		api.Error(w, "name and secret required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := connectors.Lookup(req.Provider); !ok {
+		api.Error(w, "provider must be stripe or adyen", http.StatusBadRequest)
+		return
+	}
+	if err := req.Mapping.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	config, err := json.Marshal(req.Mapping)
+	if err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	resp := ConnectorResponse{Name: req.Name, Provider: req.Provider, Mapping: req.Mapping}
+	var createdAt, updatedAt time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO connectors (ledger_id, name, provider, secret, config)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (ledger_id, name) DO UPDATE
+		SET provider = EXCLUDED.provider, secret = EXCLUDED.secret, config = EXCLUDED.config, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, principal.LedgerID, req.Name, req.Provider, req.Secret, config).Scan(&resp.ID, &createdAt, &updatedAt)
+	if err != nil {
+		api.Error(w, "failed to save connector", http.StatusInternalServerError)
+		return
+	}
+	resp.WebhookURL = connectorWebhookURL(principal.LedgerID, resp.ID)
+	resp.CreatedAt = createdAt.Format(time.RFC3339)
+	resp.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /v1/connectors - List PSP connectors
+func (h *Handler) ListConnectors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT id, name, provider, config, created_at, updated_at
+		FROM connectors
+		WHERE ledger_id = $1
+		ORDER BY name
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query connectors", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []ConnectorResponse{}
+	for rows.Next() {
+		var c ConnectorResponse
+		var config []byte
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&c.ID, &c.Name, &c.Provider, &config, &createdAt, &updatedAt); err != nil {
+			api.Error(w, "failed to scan connector", http.StatusInternalServerError)
+			return
+		}
+		json.Unmarshal(config, &c.Mapping)
+		c.WebhookURL = connectorWebhookURL(principal.LedgerID, c.ID)
+		c.CreatedAt = createdAt.Format(time.RFC3339)
+		c.UpdatedAt = updatedAt.Format(time.RFC3339)
+		list = append(list, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GET /v1/connectors/events?id=&status= - Events received by a connector
+func (h *Handler) ListConnectorEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := `
+		SELECT e.provider_event_id, e.event_type, COALESCE(e.reference, ''), COALESCE(e.amount::text, ''),
+			COALESCE(e.currency, ''), e.status, COALESCE(e.reason, ''), COALESCE(e.transaction_id::text, ''),
+			e.attempts, e.created_at, e.updated_at
+		FROM connector_events e
+		JOIN connectors c ON c.id = e.connector_id
+		WHERE c.ledger_id = $1 AND c.id::text = $2
+	`
+	args := []interface{}{principal.LedgerID, r.URL.Query().Get("id")}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += ` AND e.status = $3`
+		args = append(args, status)
+	}
+	query += ` ORDER BY e.created_at DESC LIMIT 500`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query connector events", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	events := []ConnectorEventResponse{}
+	for rows.Next() {
+		var e ConnectorEventResponse
+		var createdAt, updatedAt time.Time
+		err := rows.Scan(&e.ProviderEventID, &e.EventType, &e.Reference, &e.Amount, &e.Currency, &e.Status,
+			&e.Reason, &e.TransactionID, &e.Attempts, &createdAt, &updatedAt)
+		if err != nil {
+			api.Error(w, "failed to scan connector event", http.StatusInternalServerError)
+			return
+		}
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		e.UpdatedAt = updatedAt.Format(time.RFC3339)
+		events = append(events, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// POST /hooks/connectors?ledger=&id= - Receive a provider webhook (authenticated by its
+// signature, not an API key)
+func (h *Handler) ReceiveConnectorWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConnectorWebhookSize))
+	if err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	results, ack, err := h.Service.IngestConnectorWebhook(ctx, ConnectorWebhookCommand{
+		LedgerID:    r.URL.Query().Get("ledger"),
+		ConnectorID: r.URL.Query().Get("id"),
+		Header:      r.Header,
+		Body:        body,
+	})
+	switch {
+	case errors.Is(err, ErrConnectorNotFound):
+		api.Error(w, "connector not found", http.StatusNotFound)
+		return
+	case errors.Is(err, connectors.ErrInvalidSignature):
+		api.WriteError(w, err, http.StatusUnauthorized)
+		return
+	case err != nil:
+		log.Printf("connector %s: webhook ingestion failed: %v", r.URL.Query().Get("id"), err)
+		api.Error(w, "failed to ingest webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// A failed event is answered with an error so the provider redelivers it
+	for _, result := range results {
+		if result.Status == connectorFailed {
+			api.Error(w, "event "+result.EventID+" failed: "+result.Reason, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(ack))
+}
+
+func connectorWebhookURL(ledgerID, connectorID string) string {
+	return "/hooks/connectors?ledger=" + ledgerID + "&id=" + connectorID
+}
@@ -0,0 +1,163 @@
+package ledger
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// RateSource resolves an exchange rate (units of `to` per unit of `from`).
+type RateSource interface {
+	Rate(ctx context.Context, ledgerID, from, to string, at time.Time) (*big.Rat, error)
+}
+
+type ConversionCommand struct {
+	LedgerID            string
+	IdempotencyKey      string
+	ExternalID          string
+	SourceAccount       string
+	SourceCurrency      string
+	DestinationAccount  string
+	DestinationCurrency string
+	Amount              string // in source currency
+	Rate                string // optional; defaults to the rate source or the ledger's exchange rates
+	Precision           *int   // decimal places of the destination currency; defaults to its registered precision, else 2
+	ConversionAccount   string
+	RoundingAccount     string
+	OccurredAt          time.Time
+}
+
+type ConversionResult struct {
+	TransactionID      string         `json:"transaction_id"`
+	SourceAmount       string         `json:"source_amount"`
+	DestinationAmount  string         `json:"destination_amount"`
+	Rate               string         `json:"rate"`
+	RoundingDifference string         `json:"rounding_difference"`
+	Postings           []PostingInput `json:"postings"`
+}
+
+// PostConversion posts a balanced two-currency transaction. Value leaves the source
+// account (debit) into the conversion account, and the conversion account pays the
+// destination (credit) in the destination currency. The difference between the exact
+// converted amount and the amount rounded to the destination precision is booked
+// against the rounding account so each currency leg stays balanced. A registered
+// destination currency cannot hold that sub-unit difference: the amount is rounded with
+// the currency's rounding mode and the conversion account takes the rounded amount.
+func (s *Service) PostConversion(ctx context.Context, cmd ConversionCommand) (ConversionResult, error) {
+	if cmd.SourceCurrency == "" || cmd.DestinationCurrency == "" {
+		return ConversionResult{}, invalidf("source and destination currencies required")
+	}
+	if cmd.SourceCurrency == cmd.DestinationCurrency {
+		return ConversionResult{}, invalidf("source and destination currencies must differ")
+	}
+	if cmd.ConversionAccount == "" {
+		cmd.ConversionAccount = s.FXConversionAccount
+	}
+	if cmd.RoundingAccount == "" {
+		cmd.RoundingAccount = s.FXRoundingAccount
+	}
+
+	amount, ok := new(big.Rat).SetString(cmd.Amount)
+	if !ok || amount.Sign() <= 0 {
+		return ConversionResult{}, invalidf("invalid amount: %s", cmd.Amount)
+	}
+
+	dest, registered, err := s.currency(ctx, cmd.LedgerID, cmd.DestinationCurrency)
+	if err != nil {
+		return ConversionResult{}, err
+	}
+	precision, rounding := 2, RoundHalfUp
+	if registered {
+		precision, rounding = dest.Precision, dest.Rounding
+	}
+	if cmd.Precision != nil {
+		precision = *cmd.Precision
+	}
+	if precision < 0 || precision > 10 {
+		return ConversionResult{}, invalidf("precision must be between 0 and 10")
+	}
+	if registered && precision > dest.Precision {
+		return ConversionResult{}, invalidf("precision exceeds %s precision of %d decimal places", dest.Code, dest.Precision)
+	}
+
+	rate, err := s.resolveRate(ctx, cmd)
+	if err != nil {
+		return ConversionResult{}, err
+	}
+
+	postings, converted, diff, err := conversionPostings(cmd, amount, rate, precision, rounding, registered)
+	if err != nil {
+		return ConversionResult{}, err
+	}
+
+	transactionID, err := s.PostTransaction(ctx, PostTransactionCommand{
+		LedgerID:       cmd.LedgerID,
+		ExternalID:     cmd.ExternalID,
+		IdempotencyKey: cmd.IdempotencyKey,
+		Currency:       cmd.SourceCurrency,
+		OccurredAt:     cmd.OccurredAt,
+		Postings:       postings,
+	})
+	if err != nil {
+		return ConversionResult{}, err
+	}
+
+	return ConversionResult{
+		TransactionID:      transactionID,
+		SourceAmount:       amount.FloatString(10),
+		DestinationAmount:  converted.FloatString(precision),
+		Rate:               rate.FloatString(10),
+		RoundingDifference: diff.FloatString(10),
+		Postings:           postings,
+	}, nil
+}
+
+// conversionPostings are the legs of a conversion of amount at rate, rounded to precision
+// in the destination currency, with the converted amount and its rounding difference.
+func conversionPostings(cmd ConversionCommand, amount, rate *big.Rat, precision int, rounding string, registered bool) ([]PostingInput, *big.Rat, *big.Rat, error) {
+	// Amounts are recorded to 10 decimal places, so the exact conversion is too; the
+	// difference is taken from that, for the legs to balance as recorded
+	exact := roundRat(new(big.Rat).Mul(amount, rate), 10)
+	converted := roundMode(exact, precision, rounding)
+	if converted.Sign() <= 0 {
+		return nil, nil, nil, invalidf("converted amount rounds to zero")
+	}
+	diff := new(big.Rat).Sub(exact, converted)
+
+	conversionLeg := exact
+	if registered {
+		conversionLeg = converted
+	}
+	postings := []PostingInput{
+		{AccountCode: cmd.SourceAccount, Direction: "debit", Amount: amount.FloatString(10), Currency: cmd.SourceCurrency},
+		{AccountCode: cmd.ConversionAccount, Direction: "credit", Amount: amount.FloatString(10), Currency: cmd.SourceCurrency},
+		{AccountCode: cmd.ConversionAccount, Direction: "debit", Amount: conversionLeg.FloatString(10), Currency: cmd.DestinationCurrency},
+		{AccountCode: cmd.DestinationAccount, Direction: "credit", Amount: converted.FloatString(precision), Currency: cmd.DestinationCurrency},
+	}
+	switch {
+	case registered:
+		// The difference is below the currency's smallest unit
+	case diff.Sign() > 0:
+		postings = append(postings, PostingInput{AccountCode: cmd.RoundingAccount, Direction: "credit", Amount: diff.FloatString(10), Currency: cmd.DestinationCurrency})
+	case diff.Sign() < 0:
+		postings = append(postings, PostingInput{AccountCode: cmd.RoundingAccount, Direction: "debit", Amount: new(big.Rat).Neg(diff).FloatString(10), Currency: cmd.DestinationCurrency})
+	}
+
+	return postings, converted, diff, nil
+}
+
+func (s *Service) resolveRate(ctx context.Context, cmd ConversionCommand) (*big.Rat, error) {
+	if cmd.Rate != "" {
+		rate, ok := new(big.Rat).SetString(cmd.Rate)
+		if !ok || rate.Sign() <= 0 {
+			return nil, invalidf("invalid rate: %s", cmd.Rate)
+		}
+		return rate, nil
+	}
+	return s.rates().Rate(ctx, cmd.LedgerID, cmd.SourceCurrency, cmd.DestinationCurrency, cmd.OccurredAt)
+}
+
+// roundRat rounds half away from zero to the given number of decimal places.
+func roundRat(r *big.Rat, places int) *big.Rat {
+	return roundMode(r, places, RoundHalfUp)
+}
@@ -0,0 +1,70 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type PostConversionRequest struct {
+	IdempotencyKey      string    `json:"idempotency_key"`
+	ExternalID          string    `json:"external_id"`
+	SourceAccount       string    `json:"source_account"`
+	SourceCurrency      string    `json:"source_currency"`
+	DestinationAccount  string    `json:"destination_account"`
+	DestinationCurrency string    `json:"destination_currency"`
+	Amount              string    `json:"amount"`
+	Rate                string    `json:"rate,omitempty"`
+	Precision           *int      `json:"precision,omitempty"`
+	ConversionAccount   string    `json:"conversion_account,omitempty"`
+	RoundingAccount     string    `json:"rounding_account,omitempty"`
+	OccurredAt          time.Time `json:"occurred_at"`
+}
+
+// POST /v1/conversions - Post a balanced cross-currency conversion
+func (h *Handler) PostConversion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PostConversionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	occurredAt := req.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now().UTC()
+	}
+
+	result, err := h.Service.PostConversion(ctx, ConversionCommand{
+		LedgerID:            principal.LedgerID,
+		IdempotencyKey:      req.IdempotencyKey,
+		ExternalID:          req.ExternalID,
+		SourceAccount:       req.SourceAccount,
+		SourceCurrency:      req.SourceCurrency,
+		DestinationAccount:  req.DestinationAccount,
+		DestinationCurrency: req.DestinationCurrency,
+		Amount:              req.Amount,
+		Rate:                req.Rate,
+		Precision:           req.Precision,
+		ConversionAccount:   req.ConversionAccount,
+		RoundingAccount:     req.RoundingAccount,
+		OccurredAt:          occurredAt,
+	})
+	if err != nil {
+		writePostTransactionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,51 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRoundRat(t *testing.T) {
+	cases := []struct {
+		in     string
+		places int
+		want   string
+	}{
+		{"1.005", 2, "1.01"},
+		{"1.004", 2, "1.00"},
+		{"-1.005", 2, "-1.01"},
+		{"92.3456789", 0, "92"},
+		{"0.5", 0, "1"},
+		{"110.123456", 4, "110.1235"},
+	}
+
+	for _, c := range cases {
+		in, _ := new(big.Rat).SetString(c.in)
+		got := roundRat(in, c.places).FloatString(c.places)
+		if got != c.want {
+			t.Errorf("roundRat(%s, %d) = %s, want %s", c.in, c.places, got, c.want)
+		}
+	}
+}
+
+func TestConversionPostingsBalance(t *testing.T) {
+	cmd := ConversionCommand{SourceAccount: "eur", SourceCurrency: "EUR", DestinationAccount: "usd", DestinationCurrency: "USD",
+		ConversionAccount: "fx", RoundingAccount: "rounding"}
+	accounts := map[string]Account{}
+	for _, code := range []string{"eur", "usd", "fx", "rounding"} {
+		accounts[code] = Account{Code: code}
+	}
+
+	// The exact amount, 0.18518518365, has more decimal places than amounts are recorded with
+	amount, rate := big.NewRat(3, 2), big.NewRat(1234567891, 10000000000)
+	postings, converted, diff, err := conversionPostings(cmd, amount, rate, 2, RoundHalfUp, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.FloatString(2) != "0.19" || diff.FloatString(10) != "-0.0048148163" {
+		t.Errorf("got %s with difference %s", converted.FloatString(10), diff.FloatString(10))
+	}
+	if err := validateDoubleEntry(PostTransactionCommand{Currency: "EUR", Postings: postings}, accounts, nil); err != nil {
+		t.Fatalf("expected balanced legs, got %v: %+v", err, postings)
+	}
+}
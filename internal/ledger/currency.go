@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rounding modes of a currency, applied when an amount is converted into it.
+const (
+	RoundHalfUp   = "half_up"   // half away from zero
+	RoundHalfEven = "half_even" // half to even (banker's rounding)
+	RoundDown     = "down"      // toward zero
+	RoundUp       = "up"        // away from zero
+)
+
+// Currency is a registered asset of a ledger.
+type Currency struct {
+	Code      string
+	Name      string
+	Precision int // decimal places
+	Rounding  string
+}
+
+func validRounding(mode string) bool {
+	switch mode {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+		return true
+	}
+	return false
+}
+
+// loadCurrencies returns the registered currencies among codes.
+func loadCurrencies(ctx context.Context, tx pgx.Tx, ledgerID string, codes []string) (map[string]Currency, error) {
+	currencies := map[string]Currency{}
+	if len(codes) == 0 {
+		return currencies, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT code, COALESCE(name, ''), precision, rounding
+		FROM currencies
+		WHERE ledger_id = $1 AND code = ANY($2)
+	`, ledgerID, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Currency
+		if err := rows.Scan(&c.Code, &c.Name, &c.Precision, &c.Rounding); err != nil {
+			return nil, err
+		}
+		currencies[c.Code] = c
+	}
+	return currencies, rows.Err()
+}
+
+// postingCurrencies lists the distinct currencies of a command's postings.
+func postingCurrencies(cmd PostTransactionCommand) []string {
+	set := map[string]struct{}{}
+	for _, p := range cmd.Postings {
+		set[postingCurrency(cmd, p)] = struct{}{}
+	}
+	codes := make([]string, 0, len(set))
+	for c := range set {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// fitsPrecision reports whether amount has at most places decimal places.
+func fitsPrecision(amount *big.Rat, places int) bool {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	return new(big.Rat).Mul(amount, new(big.Rat).SetInt(scale)).IsInt()
+}
+
+// roundMode rounds r to the given number of decimal places using a currency rounding
+// mode.
+func roundMode(r *big.Rat, places int, mode string) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+
+	// Truncated toward zero, then moved one unit away from zero when needed
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		away := false
+		switch mode {
+		case RoundUp:
+			away = true
+		case RoundDown:
+		default:
+			twiceRem := new(big.Int).Abs(rem)
+			twiceRem.Mul(twiceRem, big.NewInt(2))
+			cmp := twiceRem.Cmp(scaled.Denom())
+			away = cmp > 0 || (cmp == 0 && (mode != RoundHalfEven || quo.Bit(0) == 1))
+		}
+		if away {
+			if scaled.Sign() < 0 {
+				quo.Sub(quo, big.NewInt(1))
+			} else {
+				quo.Add(quo, big.NewInt(1))
+			}
+		}
+	}
+
+	return new(big.Rat).SetFrac(quo, scale)
+}
+
+// currency returns the registered currency with the given code.
+func (s *Service) currency(ctx context.Context, ledgerID, code string) (Currency, bool, error) {
+	var c Currency
+	err := s.DB.QueryRow(ctx, `
+		SELECT code, COALESCE(name, ''), precision, rounding
+		FROM currencies
+		WHERE ledger_id = $1 AND code = $2
+	`, ledgerID, code).Scan(&c.Code, &c.Name, &c.Precision, &c.Rounding)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Currency{}, false, nil
+	}
+	if err != nil {
+		return Currency{}, false, err
+	}
+	return c, true, nil
+}
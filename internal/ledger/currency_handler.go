@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type CurrencyRequest struct {
+	Code      string `json:"code"`
+	Name      string `json:"name,omitempty"`
+	Precision *int   `json:"precision"`
+	Rounding  string `json:"rounding,omitempty"` // half_up (default), half_even, down or up
+}
+
+type CurrencyResponse struct {
+	Code      string `json:"code"`
+	Name      string `json:"name,omitempty"`
+	Precision int    `json:"precision"`
+	Rounding  string `json:"rounding"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// POST /v1/currencies - Register a currency or update its precision and rounding
+//
+// Lowering the precision only affects new postings; existing balances are not rounded.
+func (h *Handler) SaveCurrency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" || req.Precision == nil {
+		api.Error(w, "code and precision required", http.StatusBadRequest)
+		return
+	}
+	if *req.Precision < 0 || *req.Precision > 10 {
+		api.Error(w, "precision must be between 0 and 10", http.StatusBadRequest)
+		return
+	}
+	if req.Rounding == "" {
+		req.Rounding = RoundHalfUp
+	}
+	if !validRounding(req.Rounding) {
+		api.Error(w, "rounding must be half_up, half_even, down or up", http.StatusBadRequest)
+		return
+	}
+
+	var createdAt, updatedAt time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO currencies (ledger_id, code, name, precision, rounding)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		ON CONFLICT (ledger_id, code) DO UPDATE
+			SET name = EXCLUDED.name, precision = EXCLUDED.precision, rounding = EXCLUDED.rounding, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, principal.LedgerID, req.Code, req.Name, *req.Precision, req.Rounding).Scan(&createdAt, &updatedAt)
+	if err != nil {
+		api.Error(w, "failed to save currency", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CurrencyResponse{
+		Code:      req.Code,
+		Name:      req.Name,
+		Precision: *req.Precision,
+		Rounding:  req.Rounding,
+		CreatedAt: createdAt.Format(time.RFC3339),
+		UpdatedAt: updatedAt.Format(time.RFC3339),
+	})
+}
+
+// GET /v1/currencies - List registered currencies
+func (h *Handler) ListCurrencies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT code, COALESCE(name, ''), precision, rounding, created_at, updated_at
+		FROM currencies
+		WHERE ledger_id = $1
+		ORDER BY code
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query currencies", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	currencies := []CurrencyResponse{}
+	for rows.Next() {
+		var c CurrencyResponse
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&c.Code, &c.Name, &c.Precision, &c.Rounding, &createdAt, &updatedAt); err != nil {
+			api.Error(w, "failed to scan currency", http.StatusInternalServerError)
+			return
+		}
+		c.CreatedAt = createdAt.Format(time.RFC3339)
+		c.UpdatedAt = updatedAt.Format(time.RFC3339)
+		currencies = append(currencies, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currencies)
+}
@@ -0,0 +1,54 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRoundMode(t *testing.T) {
+	cases := []struct {
+		in     string
+		places int
+		mode   string
+		want   string
+	}{
+		{"2.345", 2, RoundHalfEven, "2.34"},
+		{"2.355", 2, RoundHalfEven, "2.36"},
+		{"-2.345", 2, RoundHalfEven, "-2.34"},
+		{"2.3451", 2, RoundHalfEven, "2.35"},
+		{"2.349", 2, RoundDown, "2.34"},
+		{"-2.349", 2, RoundDown, "-2.34"},
+		{"2.341", 2, RoundUp, "2.35"},
+		{"-2.341", 2, RoundUp, "-2.35"},
+		{"2.34", 2, RoundUp, "2.34"},
+	}
+
+	for _, c := range cases {
+		in, _ := new(big.Rat).SetString(c.in)
+		got := roundMode(in, c.places, c.mode).FloatString(c.places)
+		if got != c.want {
+			t.Errorf("roundMode(%s, %d, %s) = %s, want %s", c.in, c.places, c.mode, got, c.want)
+		}
+	}
+}
+
+func TestValidateDoubleEntryPrecision(t *testing.T) {
+	accounts := map[string]Account{"a": {Code: "a"}, "b": {Code: "b"}}
+	currencies := map[string]Currency{"USD": {Code: "USD", Precision: 2}}
+	cmd := func(amount string) PostTransactionCommand {
+		return PostTransactionCommand{Currency: "USD", Postings: []PostingInput{
+			{AccountCode: "a", Direction: "debit", Amount: amount},
+			{AccountCode: "b", Direction: "credit", Amount: amount},
+		}}
+	}
+
+	if err := validateDoubleEntry(cmd("10.5000000000"), accounts, currencies); err != nil {
+		t.Fatalf("expected trailing zeros to fit the precision: %v", err)
+	}
+	if err := validateDoubleEntry(cmd("10.001"), accounts, currencies); err == nil {
+		t.Fatal("expected amount beyond the currency precision to be rejected")
+	}
+	if err := validateDoubleEntry(cmd("10.001"), accounts, nil); err != nil {
+		t.Fatalf("expected unregistered currency to accept any scale: %v", err)
+	}
+}
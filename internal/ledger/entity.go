@@ -0,0 +1,53 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrEntityNotFound = errors.New("entity not found")
+
+// validateEntity checks that the entity a transaction is booked for is defined on the ledger.
+func validateEntity(ctx context.Context, tx pgx.Tx, ledgerID, code string) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM entities WHERE ledger_id = $1 AND code = $2)
+	`, ledgerID, code).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return invalidf("%w: %s", ErrEntityNotFound, code)
+	}
+	return nil
+}
+
+// LinkAccountEntity links an account to an entity, or unlinks it when entityCode is empty.
+// Transactions keep the entity they were posted with.
+func (s *Service) LinkAccountEntity(ctx context.Context, ledgerID, accountCode, entityCode string) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if entityCode != "" {
+		if err := validateEntity(ctx, tx, ledgerID, entityCode); err != nil {
+			return err
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE accounts SET entity_code = NULLIF($3, '') WHERE ledger_id = $1 AND code = $2
+	`, ledgerID, accountCode, entityCode)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+
+	return tx.Commit(ctx)
+}
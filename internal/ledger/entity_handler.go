@@ -0,0 +1,384 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EntityRequest struct {
+	Code     string         `json:"code"`
+	Type     string         `json:"type"` // customer, vendor, partner, employee or other
+	Name     string         `json:"name"`
+	Email    string         `json:"email,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+type EntityResponse struct {
+	Code         string         `json:"code"`
+	Type         string         `json:"type"`
+	Name         string         `json:"name"`
+	Email        string         `json:"email,omitempty"`
+	KYCStatus    string         `json:"kyc_status"`
+	KYCUpdatedAt string         `json:"kyc_updated_at,omitempty"`
+	Metadata     map[string]any `json:"metadata"`
+	CreatedAt    string         `json:"created_at"`
+	UpdatedAt    string         `json:"updated_at"`
+}
+
+// EntityDetailResponse is an entity with the accounts linked to it and its transaction activity
+type EntityDetailResponse struct {
+	EntityResponse
+	Accounts          []EntityAccount `json:"accounts"`
+	TransactionCount  int64           `json:"transaction_count"`
+	LastTransactionAt string          `json:"last_transaction_at,omitempty"`
+}
+
+type EntityAccount struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Balance string `json:"balance"`
+}
+
+var entityTypes = map[string]bool{
+	"customer": true, "vendor": true, "partner": true, "employee": true, "other": true,
+}
+
+const entitySelect = `
+	SELECT code, type, name, COALESCE(email, ''), kyc_status, kyc_updated_at, metadata, created_at, updated_at
+	FROM entities
+	WHERE ledger_id = $1
+`
+
+// POST /v1/entities - Create or update a counterparty entity
+func (h *Handler) SaveEntity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req EntityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" || req.Name == "" {
+		api.Error(w, "code and name required", http.StatusBadRequest)
+		return
+	}
+	if !entityTypes[req.Type] {
+		api.Error(w, "type must be customer, vendor, partner, employee or other", http.StatusBadRequest)
+		return
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Metadata == nil {
+		req.Metadata = map[string]any{}
+	}
+
+	entity, err := scanEntity(h.Service.DB.QueryRow(ctx, `
+		INSERT INTO entities (ledger_id, code, type, name, email, metadata)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		ON CONFLICT (ledger_id, code) DO UPDATE
+			SET type = EXCLUDED.type, name = EXCLUDED.name, email = EXCLUDED.email,
+				metadata = EXCLUDED.metadata, updated_at = NOW()
+		RETURNING code, type, name, COALESCE(email, ''), kyc_status, kyc_updated_at, metadata, created_at, updated_at
+	`, principal.LedgerID, req.Code, req.Type, req.Name, req.Email, req.Metadata))
+	if err != nil {
+		api.Error(w, "failed to save entity", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entity)
+}
+
+// GET /v1/entities?type=&kyc_status=&q= - List entities, optionally by type, KYC status or a name/code search
+func (h *Handler) ListEntities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	metadataFilters, err := parseMetadataFilters(r.URL.Query())
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	query := entitySelect
+	args := []interface{}{principal.LedgerID}
+	if typ := r.URL.Query().Get("type"); typ != "" {
+		args = append(args, typ)
+		query += fmt.Sprintf(` AND type = $%d`, len(args))
+	}
+	if status := r.URL.Query().Get("kyc_status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(` AND kyc_status = $%d`, len(args))
+	}
+	if q := r.URL.Query().Get("q"); q != "" {
+		args = append(args, "%"+q+"%")
+		query += fmt.Sprintf(` AND (name ILIKE $%d OR code ILIKE $%d)`, len(args), len(args))
+	}
+	for _, f := range metadataFilters {
+		query += fmt.Sprintf(` AND metadata ->> $%d = $%d`, len(args)+1, len(args)+2)
+		args = append(args, f.Key, f.Value)
+	}
+	query += ` ORDER BY name, code LIMIT 500`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query entities", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entities := []EntityResponse{}
+	for rows.Next() {
+		entity, err := scanEntity(rows)
+		if err != nil {
+			api.Error(w, "failed to scan entity", http.StatusInternalServerError)
+			return
+		}
+		entities = append(entities, entity)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entities)
+}
+
+// GET /v1/entities?code= - Get an entity with its linked accounts and transaction activity
+func (h *Handler) GetEntity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	entity, err := scanEntity(h.Service.DB.QueryRow(ctx, entitySelect+` AND code = $2`, principal.LedgerID, code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "entity not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load entity", http.StatusInternalServerError)
+		return
+	}
+
+	resp := EntityDetailResponse{EntityResponse: entity, Accounts: []EntityAccount{}}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT code, name, type, balance
+		FROM accounts
+		WHERE ledger_id = $1 AND entity_code = $2
+		ORDER BY code
+	`, principal.LedgerID, code)
+	if err != nil {
+		api.Error(w, "failed to query accounts", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var acc EntityAccount
+		if err := rows.Scan(&acc.Code, &acc.Name, &acc.Type, &acc.Balance); err != nil {
+			api.Error(w, "failed to scan account", http.StatusInternalServerError)
+			return
+		}
+		resp.Accounts = append(resp.Accounts, acc)
+	}
+
+	var lastTransactionAt *time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(occurred_at)
+		FROM transactions
+		WHERE ledger_id = $1 AND entity_code = $2
+	`, principal.LedgerID, code).Scan(&resp.TransactionCount, &lastTransactionAt)
+	if err != nil {
+		api.Error(w, "failed to query transactions", http.StatusInternalServerError)
+		return
+	}
+	if lastTransactionAt != nil {
+		resp.LastTransactionAt = lastTransactionAt.Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// PUT /v1/accounts/entity?code= - Link an account to an entity; an empty entity unlinks it
+func (h *Handler) LinkAccountEntity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		api.Error(w, "account code required", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Entity string `json:"entity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.LinkAccountEntity(ctx, principal.LedgerID, code, req.Entity)
+	if errors.Is(err, ErrAccountNotFound) {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrEntityNotFound) {
+		api.Error(w, "entity not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to link account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":   code,
+		"entity": req.Entity,
+	})
+}
+
+// PUT /v1/entities/kyc?code= - Set an entity's KYC status
+func (h *Handler) SetEntityKYCStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+		Reason string `json:"reason,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	err = h.Service.SetEntityKYCStatus(ctx, principal.LedgerID, code, req.Status, req.Reason)
+	if errors.Is(err, ErrEntityNotFound) {
+		api.Error(w, "entity not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	entity, err := scanEntity(h.Service.DB.QueryRow(ctx, entitySelect+` AND code = $2`, principal.LedgerID, code))
+	if err != nil {
+		api.Error(w, "failed to load entity", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entity)
+}
+
+// GET /v1/kyc-policy - Get how transactions involving unverified entities are handled
+func (h *Handler) GetKYCPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	policy := KYCPolicy{Action: "allow"}
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT action, COALESCE(max_amount::text, '') FROM kyc_policies WHERE ledger_id = $1
+	`, principal.LedgerID).Scan(&policy.Action, &policy.MaxAmount)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "failed to load kyc policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// PUT /v1/kyc-policy - Allow, block or cap transactions involving unverified entities
+func (h *Handler) SetKYCPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var policy KYCPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if policy.Action != "cap" {
+		policy.MaxAmount = ""
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO kyc_policies (ledger_id, action, max_amount)
+		VALUES ($1, $2, NULLIF($3, '')::numeric)
+		ON CONFLICT (ledger_id) DO UPDATE
+			SET action = EXCLUDED.action, max_amount = EXCLUDED.max_amount, updated_at = NOW()
+	`, principal.LedgerID, policy.Action, policy.MaxAmount)
+	if err != nil {
+		api.Error(w, "failed to save kyc policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+func scanEntity(row pgx.Row) (EntityResponse, error) {
+	var e EntityResponse
+	var kycUpdatedAt *time.Time
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&e.Code, &e.Type, &e.Name, &e.Email, &e.KYCStatus, &kycUpdatedAt, &e.Metadata, &createdAt, &updatedAt)
+	if kycUpdatedAt != nil {
+		e.KYCUpdatedAt = kycUpdatedAt.Format(time.RFC3339)
+	}
+	e.CreatedAt = createdAt.Format(time.RFC3339)
+	e.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return e, err
+}
@@ -0,0 +1,108 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/archive"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type EventArchiveResponse struct {
+	ID            string `json:"id"`
+	ObjectKey     string `json:"object_key"`
+	Reason        string `json:"reason"` // age, or ledger while the ledger is in the archival tier
+	FromSequence  int64  `json:"from_sequence"`
+	ToSequence    int64  `json:"to_sequence"`
+	EventCount    int    `json:"event_count"`
+	SizeBytes     int64  `json:"size_bytes"`
+	OldestEventAt string `json:"oldest_event_at"`
+	NewestEventAt string `json:"newest_event_at"`
+	Status        string `json:"status"`
+	RestoredAt    string `json:"restored_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// GET /v1/event-archives - Batches of old events moved to object storage
+func (h *Handler) ListEventArchives(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT id, object_key, reason, from_sequence, to_sequence, event_count, size_bytes, oldest_event_at,
+			newest_event_at, status, restored_at, created_at
+		FROM event_archives
+		WHERE ledger_id = $1
+		ORDER BY from_sequence DESC
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query event archives", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	archives := []EventArchiveResponse{}
+	for rows.Next() {
+		var a EventArchiveResponse
+		var oldest, newest, createdAt time.Time
+		var restoredAt *time.Time
+		err := rows.Scan(&a.ID, &a.ObjectKey, &a.Reason, &a.FromSequence, &a.ToSequence, &a.EventCount, &a.SizeBytes,
+			&oldest, &newest, &a.Status, &restoredAt, &createdAt)
+		if err != nil {
+			api.Error(w, "failed to scan event archive", http.StatusInternalServerError)
+			return
+		}
+		a.OldestEventAt = oldest.Format(time.RFC3339)
+		a.NewestEventAt = newest.Format(time.RFC3339)
+		if restoredAt != nil {
+			a.RestoredAt = restoredAt.Format(time.RFC3339)
+		}
+		a.CreatedAt = createdAt.Format(time.RFC3339)
+		archives = append(archives, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(archives)
+}
+
+// POST /v1/event-archives/restore?id= - Put an archive's events back into the ledger
+//
+// Restored events keep their IDs and sequences and show up in the events API again;
+// they are not re-projected, re-published or re-delivered.
+func (h *Handler) RestoreEventArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.Archiver == nil {
+		api.Error(w, "event archival not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	restored, err := h.Archiver.Restore(ctx, principal.LedgerID, r.URL.Query().Get("id"))
+	if errors.Is(err, archive.ErrNotFound) {
+		api.Error(w, "event archive not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, archive.ErrAlreadyRestored) {
+		api.Error(w, "event archive already restored", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to restore event archive", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"restored": restored})
+}
@@ -11,6 +11,7 @@ import (
 
 type EventResponse struct {
 	ID            string                 `json:"id"`
+	Sequence      int64                  `json:"sequence"`
 	AggregateType string                 `json:"aggregate_type"`
 	AggregateID   string                 `json:"aggregate_id"`
 	EventType     string                 `json:"event_type"`
@@ -25,12 +26,15 @@ type ListEventsResponse struct {
 }
 
 // GET /v1/events - List events with pagination
+//
+// With Accept: application/x-ndjson every matching event is streamed instead, one per
+// line, ignoring limit.
 func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -45,7 +49,7 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	continuationToken := r.URL.Query().Get("continuation_token")
 	cursor, err := api.DecodeCursor(continuationToken)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		api.WriteError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -55,7 +59,7 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 
 	// Build query
 	query := `
-		SELECT id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at
+		SELECT id, sequence, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at
 		FROM events
 		WHERE ledger_id = $1
 	`
@@ -63,11 +67,10 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	argCount := 1
 
 	// Add cursor condition
-	if cursor.Timestamp.IsZero() == false {
-		argCount++
-		query += ` AND (created_at, id) < ($` + fmt.Sprintf("%d", argCount) + `, $` + fmt.Sprintf("%d", argCount+1) + `)`
-		args = append(args, cursor.Timestamp, cursor.ID)
+	if cursor.Sequence > 0 {
 		argCount++
+		query += ` AND sequence < $` + fmt.Sprintf("%d", argCount)
+		args = append(args, cursor.Sequence)
 	}
 
 	// Add filters
@@ -82,34 +85,38 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 		args = append(args, aggregateID)
 	}
 
+	if api.WantsNDJSON(r) {
+		h.streamEvents(w, r, query, args)
+		return
+	}
+
 	// Order and limit
-	query += ` ORDER BY created_at DESC, id DESC LIMIT $` + fmt.Sprintf("%d", argCount+1)
+	query += ` ORDER BY sequence DESC LIMIT $` + fmt.Sprintf("%d", argCount+1)
 	args = append(args, limit+1)
 
 	rows, err := h.Service.DB.Query(ctx, query, args...)
 	if err != nil {
-		http.Error(w, "failed to query events", http.StatusInternalServerError)
+		api.Error(w, "failed to query events", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
 	events := []EventResponse{}
-	var lastCreatedAt time.Time
-	var lastID string
+	var lastSequence int64
 
 	for rows.Next() {
 		var evt EventResponse
 		var createdAt, occurredAt time.Time
 		var payloadJSON []byte
 
-		err = rows.Scan(&evt.ID, &evt.AggregateType, &evt.AggregateID, &evt.EventType, &payloadJSON, &occurredAt, &createdAt)
+		err = rows.Scan(&evt.ID, &evt.Sequence, &evt.AggregateType, &evt.AggregateID, &evt.EventType, &payloadJSON, &occurredAt, &createdAt)
 		if err != nil {
-			http.Error(w, "failed to scan event", http.StatusInternalServerError)
+			api.Error(w, "failed to scan event", http.StatusInternalServerError)
 			return
 		}
 
 		if err := json.Unmarshal(payloadJSON, &evt.Payload); err != nil {
-			http.Error(w, "failed to parse event payload", http.StatusInternalServerError)
+			api.Error(w, "failed to parse event payload", http.StatusInternalServerError)
 			return
 		}
 
@@ -122,8 +129,7 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 		}
 
 		events = append(events, evt)
-		lastCreatedAt = createdAt
-		lastID = evt.ID
+		lastSequence = evt.Sequence
 	}
 
 	// Check if there are more results
@@ -138,8 +144,7 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	var nextToken string
 	if hasMore && len(events) > 0 {
 		nextCursor := api.Cursor{
-			Timestamp: lastCreatedAt,
-			ID:        lastID,
+			Sequence: lastSequence,
 		}
 		nextToken, _ = api.EncodeCursor(nextCursor)
 	}
@@ -157,19 +162,55 @@ func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// streamEvents writes the events matching query as NDJSON.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request, query string, args []interface{}) {
+	rows, err := h.Service.DB.Query(r.Context(), query+` ORDER BY sequence DESC`, args...)
+	if err != nil {
+		api.Error(w, "failed to query events", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	stream := api.NewNDJSONWriter(w)
+	for rows.Next() {
+		var evt EventResponse
+		var createdAt, occurredAt time.Time
+		var payloadJSON []byte
+		err := rows.Scan(&evt.ID, &evt.Sequence, &evt.AggregateType, &evt.AggregateID, &evt.EventType, &payloadJSON, &occurredAt, &createdAt)
+		if err != nil {
+			stream.Fail("failed to scan event")
+			return
+		}
+		if err := json.Unmarshal(payloadJSON, &evt.Payload); err != nil {
+			stream.Fail("failed to parse event payload")
+			return
+		}
+		evt.OccurredAt = occurredAt.Format(time.RFC3339)
+		evt.CreatedAt = createdAt.Format(time.RFC3339)
+		if err := stream.Write(evt); err != nil {
+			return
+		}
+	}
+	if rows.Err() != nil {
+		stream.Fail("failed to query events")
+		return
+	}
+	stream.Close()
+}
+
 // GET /v1/events/:id - Get a specific event
 func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	eventID := r.URL.Query().Get("id")
 	if eventID == "" {
-		http.Error(w, "event id required", http.StatusBadRequest)
+		api.Error(w, "event id required", http.StatusBadRequest)
 		return
 	}
 
@@ -178,17 +219,17 @@ func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
 	var payloadJSON []byte
 
 	err = h.Service.DB.QueryRow(ctx, `
-		SELECT id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at
+		SELECT id, sequence, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at
 		FROM events
 		WHERE ledger_id = $1 AND id = $2
-	`, principal.LedgerID, eventID).Scan(&evt.ID, &evt.AggregateType, &evt.AggregateID, &evt.EventType, &payloadJSON, &occurredAt, &createdAt)
+	`, principal.LedgerID, eventID).Scan(&evt.ID, &evt.Sequence, &evt.AggregateType, &evt.AggregateID, &evt.EventType, &payloadJSON, &occurredAt, &createdAt)
 	if err != nil {
-		http.Error(w, "event not found", http.StatusNotFound)
+		api.Error(w, "event not found", http.StatusNotFound)
 		return
 	}
 
 	if err := json.Unmarshal(payloadJSON, &evt.Payload); err != nil {
-		http.Error(w, "failed to parse event payload", http.StatusInternalServerError)
+		api.Error(w, "failed to parse event payload", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,275 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/websocket"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	eventHubInterval     = 500 * time.Millisecond
+	eventStreamHeartbeat = 30 * time.Second
+	// Events buffered per connection; slower clients are disconnected
+	eventStreamBuffer = 256
+)
+
+// EventHub pushes committed events to WebSocket subscribers. It polls the events table
+// by sequence, which becomes visible in order, so a subscriber sees every event of its
+// ledger from the moment it subscribed, once.
+type EventHub struct {
+	DB *pgxpool.Pool
+
+	mu   sync.Mutex
+	subs map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	ledgerID string
+	types    map[string]bool // "*" matches every event type; guarded by the hub's mu
+	events   chan EventResponse
+}
+
+// Run polls for new events until ctx ends.
+func (h *EventHub) Run(ctx context.Context) {
+	ticker := time.NewTicker(eventHubInterval)
+	defer ticker.Stop()
+
+	last := int64(-1)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ledgers := h.ledgers()
+		if len(ledgers) == 0 {
+			// Nobody listening; start from the tip again once someone is
+			last = -1
+			continue
+		}
+		if last < 0 {
+			if err := h.DB.QueryRow(ctx, `SELECT COALESCE(MAX(sequence), 0) FROM events`).Scan(&last); err != nil {
+				log.Printf("event hub: %v", err)
+				continue
+			}
+		}
+
+		next, err := h.poll(ctx, last, ledgers)
+		if err != nil {
+			log.Printf("event hub: %v", err)
+			continue
+		}
+		last = next
+	}
+}
+
+// poll publishes the subscribed ledgers' events after sequence last and returns the
+// new position.
+func (h *EventHub) poll(ctx context.Context, last int64, ledgers []string) (int64, error) {
+	rows, err := h.DB.Query(ctx, `
+		SELECT id, sequence, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at
+		FROM events
+		WHERE sequence > $1 AND ledger_id::text = ANY($2)
+		ORDER BY sequence
+		LIMIT 1000
+	`, last, ledgers)
+	if err != nil {
+		return last, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var evt EventResponse
+		var ledgerID string
+		var payloadJSON []byte
+		var occurredAt, createdAt time.Time
+		err := rows.Scan(&evt.ID, &evt.Sequence, &ledgerID, &evt.AggregateType, &evt.AggregateID, &evt.EventType,
+			&payloadJSON, &occurredAt, &createdAt)
+		if err != nil {
+			return last, err
+		}
+		json.Unmarshal(payloadJSON, &evt.Payload)
+		evt.OccurredAt = occurredAt.Format(time.RFC3339)
+		evt.CreatedAt = createdAt.Format(time.RFC3339)
+
+		h.publish(ledgerID, evt)
+		last = evt.Sequence
+	}
+	return last, rows.Err()
+}
+
+func (h *EventHub) publish(ledgerID string, evt EventResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if sub.ledgerID != ledgerID || !(sub.types["*"] || sub.types[evt.EventType]) {
+			continue
+		}
+		select {
+		case sub.events <- evt:
+		default:
+			// Too slow; closing the channel disconnects the client
+			delete(h.subs, sub)
+			close(sub.events)
+		}
+	}
+}
+
+func (h *EventHub) ledgers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var ledgers []string
+	for sub := range h.subs {
+		if !slices.Contains(ledgers, sub.ledgerID) {
+			ledgers = append(ledgers, sub.ledgerID)
+		}
+	}
+	return ledgers
+}
+
+func (h *EventHub) subscribe(ledgerID string, types []string) *eventSubscriber {
+	sub := &eventSubscriber{ledgerID: ledgerID, types: map[string]bool{}, events: make(chan EventResponse, eventStreamBuffer)}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = map[*eventSubscriber]struct{}{}
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+func (h *EventHub) unsubscribe(sub *eventSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+}
+
+// setTypes adds or removes event types from a subscription and returns the result.
+func (h *EventHub) setTypes(sub *eventSubscriber, types []string, subscribed bool) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range types {
+		if subscribed {
+			sub.types[t] = true
+		} else {
+			delete(sub.types, t)
+		}
+	}
+	current := []string{}
+	for t := range sub.types {
+		current = append(current, t)
+	}
+	slices.Sort(current)
+	return current
+}
+
+// Messages clients send to change their subscription
+type streamRequest struct {
+	Action     string   `json:"action"` // subscribe or unsubscribe
+	EventTypes []string `json:"event_types"`
+}
+
+// Messages pushed to clients; Type is event, subscribed, heartbeat or error
+type streamMessage struct {
+	Type       string         `json:"type"`
+	Event      *EventResponse `json:"event,omitempty"`
+	EventTypes []string       `json:"event_types,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// GET /v1/ws?event_types=TransactionPosted,HoldCreated - Push events over a WebSocket
+//
+// Without event_types every event type is pushed ("*"). Clients change their filter by
+// sending {"action":"subscribe"|"unsubscribe","event_types":[...]}. The server pings and
+// sends a heartbeat message every 30s, and drops clients that stop answering or fall
+// too far behind.
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	principal, err := auth.FromContext(r.Context())
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.Events == nil {
+		api.Error(w, "event streaming unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	types := []string{"*"}
+	if param := r.URL.Query().Get("event_types"); param != "" {
+		types = strings.Split(param, ",")
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	conn.ReadTimeout = 2*eventStreamHeartbeat + 15*time.Second
+
+	sub := h.Events.subscribe(principal.LedgerID, types)
+	defer h.Events.unsubscribe(sub)
+
+	send := func(msg streamMessage) error {
+		data, _ := json.Marshal(msg)
+		return conn.WriteText(data)
+	}
+	send(streamMessage{Type: "subscribed", EventTypes: h.Events.setTypes(sub, nil, true)})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req streamRequest
+			if err := json.Unmarshal(data, &req); err != nil || (req.Action != "subscribe" && req.Action != "unsubscribe") {
+				send(streamMessage{Type: "error", Error: `expected {"action":"subscribe"|"unsubscribe","event_types":[...]}`})
+				continue
+			}
+			send(streamMessage{Type: "subscribed", EventTypes: h.Events.setTypes(sub, req.EventTypes, req.Action == "subscribe")})
+		}
+	}()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-done:
+			conn.Close(websocket.CloseNormal, "")
+			return
+		case evt, ok := <-sub.events:
+			if !ok {
+				conn.Close(websocket.ClosePolicyViolation, "client too slow")
+				return
+			}
+			if err := send(streamMessage{Type: "event", Event: &evt}); err != nil {
+				conn.Close(websocket.CloseGoingAway, "")
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.Ping(); err != nil {
+				conn.Close(websocket.CloseGoingAway, "")
+				return
+			}
+			send(streamMessage{Type: "heartbeat"})
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEventHubPublish(t *testing.T) {
+	h := &EventHub{}
+	all := h.subscribe("l1", []string{"*"})
+	posted := h.subscribe("l1", []string{"TransactionPosted"})
+	other := h.subscribe("l2", []string{"*"})
+
+	h.publish("l1", EventResponse{ID: "e1", EventType: "TransactionPosted"})
+	h.publish("l1", EventResponse{ID: "e2", EventType: "HoldCreated"})
+
+	if len(all.events) != 2 || len(posted.events) != 1 || len(other.events) != 0 {
+		t.Fatalf("unexpected deliveries %d %d %d", len(all.events), len(posted.events), len(other.events))
+	}
+	if got := h.setTypes(posted, []string{"HoldCreated"}, true); fmt.Sprint(got) != "[HoldCreated TransactionPosted]" {
+		t.Fatalf("unexpected types %v", got)
+	}
+	if got := fmt.Sprint(h.ledgers()); got != "[l1 l2]" && got != "[l2 l1]" {
+		t.Fatalf("unexpected ledgers %s", got)
+	}
+
+	// A subscriber whose buffer is full is dropped
+	for i := 0; i < eventStreamBuffer; i++ {
+		h.publish("l2", EventResponse{EventType: "TransactionPosted"})
+	}
+	h.publish("l2", EventResponse{EventType: "TransactionPosted"})
+	for range other.events {
+	}
+	if _, ok := h.subs[other]; ok {
+		t.Fatal("expected the slow subscriber to be dropped")
+	}
+	h.unsubscribe(other) // already closed; must not panic
+}
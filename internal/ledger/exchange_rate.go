@@ -0,0 +1,163 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// ExchangeRates is the RateSource backed by the ledger's exchange_rates registry. It
+// uses the latest rate effective at the requested time, falling back to the inverse of
+// the opposite pair.
+type ExchangeRates struct {
+	DB *pgxpool.Pool
+}
+
+func (e *ExchangeRates) Rate(ctx context.Context, ledgerID, from, to string, at time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	var rate string
+	var inverse bool
+	err := e.DB.QueryRow(ctx, `
+		SELECT rate::text, base_currency <> $2
+		FROM exchange_rates
+		WHERE ledger_id = $1
+		  AND ((base_currency = $2 AND quote_currency = $3) OR (base_currency = $3 AND quote_currency = $2))
+		  AND effective_at <= $4
+		ORDER BY effective_at DESC, base_currency = $2 DESC
+		LIMIT 1
+	`, ledgerID, from, to, at).Scan(&rate, &inverse)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w for %s/%s at %s", ErrRateNotFound, from, to, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid stored rate %s", rate)
+	}
+	if inverse {
+		r.Inv(r)
+	}
+	return r, nil
+}
+
+// rates is the configured RateSource, or the ledger's exchange rate registry.
+func (s *Service) rates() RateSource {
+	if s.Rates != nil {
+		return s.Rates
+	}
+	return &ExchangeRates{DB: s.DB}
+}
+
+// Convert converts amount between currencies at the rate effective at the given time,
+// and returns the converted amount with the rate used.
+func (s *Service) Convert(ctx context.Context, ledgerID string, amount *big.Rat, from, to string, at time.Time) (*big.Rat, *big.Rat, error) {
+	rate, err := s.rates().Rate(ctx, ledgerID, from, to, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	return new(big.Rat).Mul(amount, rate), rate, nil
+}
+
+// ConvertedBalanceSummary totals balances by account type in one currency.
+type ConvertedBalanceSummary struct {
+	Currency     string            `json:"currency"`
+	ByType       map[string]string `json:"by_type"`
+	MissingRates []string          `json:"missing_rates,omitempty"` // FROM/TO pairs left out of the totals
+}
+
+// typeTotal is the signed total of one account type's postings in one currency.
+type typeTotal struct {
+	Type     string
+	Currency string
+	Total    *big.Rat
+}
+
+// ConvertedBalances totals the ledger's postings by account type, converting each
+// currency into the target currency at the rate effective at the given time.
+// Currencies without a rate are reported instead of failing the summary.
+func (s *Service) ConvertedBalances(ctx context.Context, ledgerID, currency string, at time.Time) (ConvertedBalanceSummary, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT a.type, COALESCE(p.currency, t.currency),
+			SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END)::text
+		FROM postings p
+		JOIN accounts a ON a.id = p.account_id
+		JOIN transactions t ON t.id = p.transaction_id
+		WHERE t.ledger_id = $1
+		GROUP BY 1, 2
+	`, ledgerID)
+	if err != nil {
+		return ConvertedBalanceSummary{}, err
+	}
+
+	var totals []typeTotal
+	for rows.Next() {
+		var t typeTotal
+		var total string
+		if err := rows.Scan(&t.Type, &t.Currency, &total); err != nil {
+			rows.Close()
+			return ConvertedBalanceSummary{}, err
+		}
+		t.Total, _ = new(big.Rat).SetString(total)
+		totals = append(totals, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ConvertedBalanceSummary{}, err
+	}
+
+	return convertTotals(totals, currency, func(from string) (*big.Rat, error) {
+		return s.rates().Rate(ctx, ledgerID, from, currency, at)
+	})
+}
+
+// convertTotals sums the totals per account type in the target currency. Pairs whose
+// rate is not found are listed as missing; other rate errors abort.
+func convertTotals(totals []typeTotal, currency string, rate func(from string) (*big.Rat, error)) (ConvertedBalanceSummary, error) {
+	summary := ConvertedBalanceSummary{Currency: currency, ByType: make(map[string]string)}
+	sums := make(map[string]*big.Rat)
+	rates := make(map[string]*big.Rat)
+	missing := make(map[string]bool)
+
+	for _, t := range totals {
+		if missing[t.Currency] {
+			continue
+		}
+		r, ok := rates[t.Currency]
+		if !ok {
+			var err error
+			r, err = rate(t.Currency)
+			if errors.Is(err, ErrRateNotFound) {
+				missing[t.Currency] = true
+				summary.MissingRates = append(summary.MissingRates, t.Currency+"/"+currency)
+				continue
+			}
+			if err != nil {
+				return ConvertedBalanceSummary{}, err
+			}
+			rates[t.Currency] = r
+		}
+
+		if sums[t.Type] == nil {
+			sums[t.Type] = new(big.Rat)
+		}
+		sums[t.Type].Add(sums[t.Type], new(big.Rat).Mul(t.Total, r))
+	}
+
+	for accountType, sum := range sums {
+		summary.ByType[accountType] = sum.FloatString(10)
+	}
+	return summary, nil
+}
@@ -0,0 +1,211 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+type ExchangeRateRequest struct {
+	Base        string    `json:"base"`
+	Quote       string    `json:"quote"`
+	Rate        string    `json:"rate"` // units of quote per unit of base
+	EffectiveAt time.Time `json:"effective_at"`
+}
+
+type ExchangeRateResponse struct {
+	ID          string `json:"id"`
+	Base        string `json:"base"`
+	Quote       string `json:"quote"`
+	Rate        string `json:"rate"`
+	EffectiveAt string `json:"effective_at"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type ConvertResponse struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+	Rate   string `json:"rate"`
+	Result string `json:"result"`
+	At     string `json:"at"`
+}
+
+// POST /v1/exchange-rates - Record a rate for a currency pair, replacing one with the same effective time
+func (h *Handler) SaveExchangeRate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Base == "" || req.Quote == "" || req.Base == req.Quote {
+		api.Error(w, "base and quote must be two different currencies", http.StatusBadRequest)
+		return
+	}
+	if rate, ok := new(big.Rat).SetString(req.Rate); !ok || rate.Sign() <= 0 {
+		api.Error(w, "rate must be a positive number", http.StatusBadRequest)
+		return
+	}
+	if req.EffectiveAt.IsZero() {
+		req.EffectiveAt = time.Now().UTC()
+	}
+
+	var rate ExchangeRateResponse
+	var effectiveAt, createdAt time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO exchange_rates (ledger_id, base_currency, quote_currency, rate, effective_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (ledger_id, base_currency, quote_currency, effective_at) DO UPDATE
+		SET rate = EXCLUDED.rate
+		RETURNING id, base_currency, quote_currency, rate::text, effective_at, created_at
+	`, principal.LedgerID, req.Base, req.Quote, req.Rate, req.EffectiveAt).Scan(
+		&rate.ID, &rate.Base, &rate.Quote, &rate.Rate, &effectiveAt, &createdAt)
+	if err != nil {
+		api.Error(w, "failed to save exchange rate", http.StatusInternalServerError)
+		return
+	}
+	rate.EffectiveAt = effectiveAt.Format(time.RFC3339)
+	rate.CreatedAt = createdAt.Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rate)
+}
+
+// GET /v1/exchange-rates?base=&quote= - List recorded rates, newest first
+func (h *Handler) ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := `
+		SELECT id, base_currency, quote_currency, rate::text, effective_at, created_at
+		FROM exchange_rates
+		WHERE ledger_id = $1
+	`
+	args := []interface{}{principal.LedgerID}
+	if base := r.URL.Query().Get("base"); base != "" {
+		args = append(args, base)
+		query += fmt.Sprintf(` AND base_currency = $%d`, len(args))
+	}
+	if quote := r.URL.Query().Get("quote"); quote != "" {
+		args = append(args, quote)
+		query += fmt.Sprintf(` AND quote_currency = $%d`, len(args))
+	}
+	query += ` ORDER BY effective_at DESC, base_currency, quote_currency LIMIT 500`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query exchange rates", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	rates := []ExchangeRateResponse{}
+	for rows.Next() {
+		var rate ExchangeRateResponse
+		var effectiveAt, createdAt time.Time
+		if err := rows.Scan(&rate.ID, &rate.Base, &rate.Quote, &rate.Rate, &effectiveAt, &createdAt); err != nil {
+			api.Error(w, "failed to scan exchange rate", http.StatusInternalServerError)
+			return
+		}
+		rate.EffectiveAt = effectiveAt.Format(time.RFC3339)
+		rate.CreatedAt = createdAt.Format(time.RFC3339)
+		rates = append(rates, rate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// DELETE /v1/exchange-rates?id= - Remove a recorded rate
+func (h *Handler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tag, err := h.Service.DB.Exec(ctx, `
+		DELETE FROM exchange_rates WHERE id::text = $1 AND ledger_id = $2
+	`, r.URL.Query().Get("id"), principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to delete exchange rate", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "exchange rate not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /v1/exchange-rates/convert?from=&to=&amount=&at= - Convert an amount at the rate effective at a time (default now)
+func (h *Handler) ConvertAmount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if from == "" || to == "" {
+		api.Error(w, "from and to required", http.StatusBadRequest)
+		return
+	}
+	amount, ok := new(big.Rat).SetString(q.Get("amount"))
+	if !ok {
+		api.Error(w, "invalid amount", http.StatusBadRequest)
+		return
+	}
+	at := time.Now().UTC()
+	if s := q.Get("at"); s != "" {
+		at, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			api.Error(w, "at must be RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, rate, err := h.Service.Convert(ctx, principal.LedgerID, amount, from, to, at)
+	if errors.Is(err, ErrRateNotFound) {
+		api.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to convert amount", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ConvertResponse{
+		From:   from,
+		To:     to,
+		Amount: amount.FloatString(10),
+		Rate:   rate.FloatString(10),
+		Result: result.FloatString(10),
+		At:     at.Format(time.RFC3339),
+	})
+}
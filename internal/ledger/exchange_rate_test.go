@@ -0,0 +1,46 @@
+package ledger
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestConvertTotals(t *testing.T) {
+	totals := []typeTotal{
+		{Type: "asset", Currency: "USD", Total: big.NewRat(100, 1)},
+		{Type: "asset", Currency: "EUR", Total: big.NewRat(50, 1)},
+		{Type: "liability", Currency: "EUR", Total: big.NewRat(-10, 1)},
+		{Type: "liability", Currency: "GBP", Total: big.NewRat(7, 1)},
+	}
+	calls := 0
+	rate := func(from string) (*big.Rat, error) {
+		calls++
+		switch from {
+		case "USD":
+			return big.NewRat(1, 1), nil
+		case "EUR":
+			return big.NewRat(11, 10), nil
+		}
+		return nil, ErrRateNotFound
+	}
+
+	summary, err := convertTotals(totals, "USD", rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.ByType["asset"] != "155.0000000000" || summary.ByType["liability"] != "-11.0000000000" {
+		t.Fatalf("unexpected totals %v", summary.ByType)
+	}
+	if len(summary.MissingRates) != 1 || summary.MissingRates[0] != "GBP/USD" {
+		t.Fatalf("unexpected missing rates %v", summary.MissingRates)
+	}
+	if calls != 3 {
+		t.Fatalf("expected one rate lookup per currency, got %d", calls)
+	}
+
+	failing := func(string) (*big.Rat, error) { return nil, errors.New("boom") }
+	if _, err := convertTotals(totals, "USD", failing); err == nil {
+		t.Fatal("expected rate errors other than not found to abort")
+	}
+}
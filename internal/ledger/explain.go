@@ -0,0 +1,192 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/screening"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Results of a step of an Explanation.
+const (
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// Explanation traces how a transaction was evaluated, for dry runs with explain=true: the
+// accounts it resolved, the order their locks were taken in, every check in the order it
+// ran, and what screening decided. Evaluation stops at the first failed check, so later
+// checks are missing from the trace of a refused transaction.
+//
+// Its methods do nothing on a nil Explanation, so validation records into it
+// unconditionally and posting, which leaves it nil, pays nothing.
+type Explanation struct {
+	Accounts      []ExplainedAccount `json:"accounts"`
+	LockOrder     []string           `json:"lock_order"`
+	Checks        []ExplainedCheck   `json:"checks"`
+	BalanceChecks []BalanceCheck     `json:"balance_checks"`
+	Screening     []ScreeningStep    `json:"screening"`
+}
+
+// ExplainedAccount is an account as validation saw it, locked.
+type ExplainedAccount struct {
+	Code                 string `json:"code"`
+	Type                 string `json:"type"`
+	Balance              string `json:"balance"` // projected; may trail unprojected events
+	AllowNegativeBalance bool   `json:"allow_negative_balance"`
+	MinBalance           string `json:"min_balance,omitempty"`
+	EntityCode           string `json:"entity_code,omitempty"`
+	Disabled             bool   `json:"disabled"`
+}
+
+type ExplainedCheck struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"` // the error of a failed check
+}
+
+// ScreeningStep is the decision of one screener: the ledger's rules, then the service's
+// Screener.
+type ScreeningStep struct {
+	Screener string `json:"screener"`
+	Outcome  string `json:"outcome"` // allow, review, deny, or skipped
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ExplainedErrorResponse is the error envelope of a refused dry run with explain=true.
+type ExplainedErrorResponse struct {
+	api.ErrorResponse
+	Explain *Explanation `json:"explain"`
+}
+
+func newExplanation() *Explanation {
+	return &Explanation{
+		Accounts:      []ExplainedAccount{},
+		LockOrder:     []string{},
+		Checks:        []ExplainedCheck{},
+		BalanceChecks: []BalanceCheck{},
+		Screening:     []ScreeningStep{},
+	}
+}
+
+// check records the outcome of the named check and returns its error.
+func (e *Explanation) check(name string, err error) error {
+	if e == nil {
+		return err
+	}
+	c := ExplainedCheck{Name: name, Result: CheckPassed}
+	if err != nil {
+		c.Result, c.Detail = CheckFailed, err.Error()
+		if status, _ := postTransactionError(err); status == http.StatusInternalServerError {
+			c.Detail = internalErrorMessage
+		}
+	}
+	e.Checks = append(e.Checks, c)
+	return err
+}
+
+func (e *Explanation) note(name, result, detail string) {
+	if e == nil {
+		return
+	}
+	e.Checks = append(e.Checks, ExplainedCheck{Name: name, Result: result, Detail: detail})
+}
+
+// locked records the accounts loadAndLockAccounts resolved, which it locks in code order.
+func (e *Explanation) locked(accounts map[string]Account) {
+	if e == nil {
+		return
+	}
+	for code := range accounts {
+		e.LockOrder = append(e.LockOrder, code)
+	}
+	sort.Strings(e.LockOrder)
+	for _, code := range e.LockOrder {
+		a := accounts[code]
+		e.Accounts = append(e.Accounts, ExplainedAccount{
+			Code:                 a.Code,
+			Type:                 a.Type,
+			Balance:              a.Balance,
+			AllowNegativeBalance: a.AllowNegativeBalance,
+			MinBalance:           a.MinBalance,
+			EntityCode:           a.EntityCode,
+			Disabled:             a.Disabled,
+		})
+	}
+}
+
+// balances records the minimum balance check of every account the postings move.
+func (e *Explanation) balances(postings []PostingInput, accounts map[string]Account) {
+	if e == nil {
+		return
+	}
+	if checks, err := balanceChecks(postings, accounts); err == nil {
+		e.BalanceChecks = checks
+	}
+}
+
+func (e *Explanation) screened(screener string, d screening.Decision) {
+	if e == nil {
+		return
+	}
+	e.Screening = append(e.Screening, ScreeningStep{Screener: screener, Outcome: string(d.Outcome), Reason: d.Reason})
+}
+
+// explainScreening records what screening would decide on a validated command, without
+// recording a review. The service's Screener is not called: a dry run must not reach a
+// screening provider.
+func (s *Service) explainScreening(ctx context.Context, tx pgx.Tx, cmd PostTransactionCommand) error {
+	var reviewID, status string
+	err := tx.QueryRow(ctx, `
+		SELECT id, status FROM screening_reviews WHERE ledger_id = $1 AND idempotency_key = $2
+	`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&reviewID, &status)
+	if err == nil {
+		cmd.explain.Screening = append(cmd.explain.Screening, ScreeningStep{
+			Screener: "review", Outcome: status, Reason: "idempotency key already screened in review " + reviewID,
+		})
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	rules, err := loadScreeningRules(ctx, tx, cmd.LedgerID)
+	if err != nil {
+		return err
+	}
+	decision, err := rules.Screen(ctx, screeningRequest(cmd))
+	if err != nil {
+		return err
+	}
+	cmd.explain.screened("rules", decision)
+	if s.Screener != nil {
+		cmd.explain.Screening = append(cmd.explain.Screening, ScreeningStep{
+			Screener: "screener", Outcome: CheckSkipped, Reason: "not called in dry runs",
+		})
+	}
+	return nil
+}
+
+// writeExplainedError is writePostTransactionError with the trace of the refused
+// transaction.
+func writeExplainedError(w http.ResponseWriter, err error, explain *Explanation) {
+	status, code := postTransactionError(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("dry run failed: %v", err)
+		message = internalErrorMessage
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ExplainedErrorResponse{
+		ErrorResponse: api.ErrorResponse{Error: api.ErrorBody{Code: code, Message: message}},
+		Explain:       explain,
+	})
+}
@@ -0,0 +1,57 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExplanationRecordsChecks(t *testing.T) {
+	var none *Explanation
+	boom := errors.New("boom")
+	if err := none.check("noop", boom); err != boom {
+		t.Fatalf("nil explanation changed the error: %v", err)
+	}
+	none.locked(map[string]Account{"cash": {Code: "cash"}})
+
+	e := newExplanation()
+	e.check("descriptions", nil)
+	e.check("double_entry", invalidf("unbalanced"))
+	e.check("accounts", boom)
+	if len(e.Checks) != 3 || e.Checks[0].Result != CheckPassed || e.Checks[1].Result != CheckFailed || e.Checks[1].Detail != "unbalanced" {
+		t.Fatalf("unexpected checks %+v", e.Checks)
+	}
+	// The ledger's own failures are not detailed to the caller
+	if e.Checks[2].Result != CheckFailed || e.Checks[2].Detail != internalErrorMessage {
+		t.Fatalf("unexpected check %+v", e.Checks[2])
+	}
+
+	e.locked(map[string]Account{"revenue": {Code: "revenue"}, "cash": {Code: "cash", Balance: "10"}})
+	if len(e.LockOrder) != 2 || e.LockOrder[0] != "cash" || e.Accounts[0].Balance != "10" {
+		t.Fatalf("expected accounts in lock order, got %v %+v", e.LockOrder, e.Accounts)
+	}
+}
+
+func TestBalanceChecks(t *testing.T) {
+	accounts := map[string]Account{
+		"wallet": {Code: "wallet", Balance: "50"},
+		"world":  {Code: "world", Balance: "0", AllowNegativeBalance: true},
+		"credit": {Code: "credit", Balance: "0", MinBalance: "-100"},
+	}
+	checks, err := balanceChecks([]PostingInput{
+		{AccountCode: "wallet", Direction: "debit", Amount: "60"},
+		{AccountCode: "credit", Direction: "debit", Amount: "40"},
+		{AccountCode: "world", Direction: "credit", Amount: "100"},
+	}, accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"wallet": CheckFailed, "credit": CheckPassed, "world": CheckSkipped}
+	for _, c := range checks {
+		if c.Result != want[c.AccountCode] {
+			t.Errorf("%s: got %s, want %s", c.AccountCode, c.Result, want[c.AccountCode])
+		}
+	}
+	if checks[0].AccountCode != "credit" || checks[0].MinBalance != "-100.0000000000" {
+		t.Errorf("unexpected credit check %+v", checks[0])
+	}
+}
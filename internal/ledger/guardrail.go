@@ -0,0 +1,86 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Metadata filters can't use an index, so a filtered listing scans every transaction in
+// its time range. On ledgers past guardrailMinRows that range has to be bounded, unless
+// the caller confirms with confirm_expensive=true or streams with NDJSON.
+const (
+	guardrailMinRows  = 1_000_000
+	guardrailMaxRange = 92 * 24 * time.Hour
+)
+
+// guardrailTimeLayouts are the start_time and end_time forms the guardrail can measure.
+var guardrailTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// boundedRange reports whether start and end are both set and at most
+// guardrailMaxRange apart. Times it can't parse count as unbounded.
+func boundedRange(start, end string) bool {
+	from, ok := parseGuardrailTime(start)
+	if !ok {
+		return false
+	}
+	to, ok := parseGuardrailTime(end)
+	if !ok {
+		return false
+	}
+	return to.Sub(from) <= guardrailMaxRange
+}
+
+func parseGuardrailTime(s string) (time.Time, bool) {
+	for _, layout := range guardrailTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// estimateTransactions returns the planner's estimate of the ledger's transaction count,
+// which costs nothing compared to counting them.
+func (s *Service) estimateTransactions(ctx context.Context, ledgerID string) (int64, error) {
+	var plan string
+	err := s.DB.QueryRow(ctx, `
+		EXPLAIN (FORMAT JSON) SELECT 1 FROM transactions WHERE ledger_id = $1
+	`, ledgerID).Scan(&plan)
+	if err != nil {
+		return 0, err
+	}
+
+	var explained []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explained); err != nil || len(explained) == 0 {
+		return 0, fmt.Errorf("unexpected plan: %s", plan)
+	}
+	return int64(explained[0].Plan.Rows), nil
+}
+
+// guardTransactionListing rejects a metadata-filtered transaction page over an
+// unbounded time range on a large ledger, and reports whether it did.
+func (h *Handler) guardTransactionListing(w http.ResponseWriter, r *http.Request, ledgerID string) bool {
+	query := r.URL.Query()
+	if query.Get("confirm_expensive") == "true" || boundedRange(query.Get("start_time"), query.Get("end_time")) {
+		return false
+	}
+
+	rows, err := h.Service.estimateTransactions(r.Context(), ledgerID)
+	if err != nil || rows < guardrailMinRows {
+		return false
+	}
+
+	api.Error(w, fmt.Sprintf(
+		"metadata filters on this ledger (about %d transactions) need start_time and end_time at most %d days apart; "+
+			"export the full result with Accept: application/x-ndjson instead, or repeat the request with confirm_expensive=true",
+		rows, int(guardrailMaxRange.Hours()/24)), http.StatusUnprocessableEntity)
+	return true
+}
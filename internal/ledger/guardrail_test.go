@@ -0,0 +1,22 @@
+package ledger
+
+import "testing"
+
+func TestBoundedRange(t *testing.T) {
+	cases := []struct {
+		start, end string
+		want       bool
+	}{
+		{"2026-01-01T00:00:00Z", "2026-03-31T23:59:59Z", true},
+		{"2026-01-01", "2026-04-03", true},
+		{"2026-01-01", "2026-06-01", false},
+		{"2026-01-01T00:00:00Z", "", false},
+		{"", "2026-01-01", false},
+		{"yesterday", "2026-01-01", false},
+	}
+	for _, c := range cases {
+		if got := boundedRange(c.start, c.end); got != c.want {
+			t.Errorf("boundedRange(%q, %q) = %v, want %v", c.start, c.end, got, c.want)
+		}
+	}
+}
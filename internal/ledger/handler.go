@@ -1,22 +1,51 @@
 package ledger
 
 import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/archive"
 	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/calendar"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/hooks"
+	"Go_FormanceLegder/internal/rules"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 )
 
 type Handler struct {
 	Service *Service
+
+	// WidgetSecret signs the public widget URLs (see package widget)
+	WidgetSecret []byte
+
+	// Events pushes committed events to /v1/ws subscribers
+	Events *EventHub
+
+	// Archiver restores archived events; nil when no archive bucket is configured
+	Archiver *archive.Archiver
 }
 
 type PostTransactionRequest struct {
 	IdempotencyKey string         `json:"idempotency_key"`
 	ExternalID     string         `json:"external_id"`
+	Description    string         `json:"description,omitempty"`
 	Currency       string         `json:"currency"`
 	OccurredAt     time.Time      `json:"occurred_at"`
+	ValueDate      string         `json:"value_date,omitempty"` // YYYY-MM-DD, defaults to occurred_at's date
 	Postings       []PostingInput `json:"postings"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	Entity         string         `json:"entity,omitempty"` // entity code of the counterparty
+
+	// Balance conditions checked under the posting's account locks; the transaction
+	// fails if any is violated
+	Assertions []BalanceAssertion `json:"assertions,omitempty"`
+
+	// Alternative to postings: a script describing sources, destinations and allocations
+	Script string            `json:"script,omitempty"`
+	Vars   map[string]string `json:"vars,omitempty"`
 }
 
 type PostTransactionResponse struct {
@@ -24,33 +53,99 @@ type PostTransactionResponse struct {
 	Status        string `json:"status"`
 }
 
+type PreviewTransactionResponse struct {
+	Status                string          `json:"status"` // always "valid"; invalid transactions are rejected as when posting
+	ExistingTransactionID string          `json:"existing_transaction_id,omitempty"`
+	Postings              []PostingInput  `json:"postings"`
+	BalanceImpact         []BalanceImpact `json:"balance_impact"`
+	Explain               *Explanation    `json:"explain,omitempty"` // with explain=true
+}
+
 func (h *Handler) PostTransaction(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req PostTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		api.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
+	var valueDate time.Time
+	if req.ValueDate != "" {
+		if valueDate, err = calendar.ParseDate(req.ValueDate); err != nil {
+			api.Error(w, "value_date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
 	cmd := PostTransactionCommand{
 		LedgerID:       principal.LedgerID,
 		ExternalID:     req.ExternalID,
+		Description:    req.Description,
 		IdempotencyKey: req.IdempotencyKey,
 		Currency:       req.Currency,
 		OccurredAt:     req.OccurredAt,
+		ValueDate:      valueDate,
 		Postings:       req.Postings,
+		Metadata:       req.Metadata,
+		EntityCode:     req.Entity,
+		Assertions:     req.Assertions,
+		Script:         req.Script,
+		Vars:           req.Vars,
+	}
+
+	// dry_run=true validates and returns the balance impact without posting; explain=true
+	// adds how the transaction was evaluated, to refusals too
+	explain := r.URL.Query().Get("explain") == "true"
+	if explain && r.URL.Query().Get("dry_run") != "true" {
+		api.Error(w, "explain requires dry_run=true", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		if explain {
+			cmd.explain = newExplanation()
+		}
+		preview, err := h.Service.PreviewTransaction(ctx, cmd)
+		if err != nil {
+			if explain {
+				writeExplainedError(w, err, cmd.explain)
+				return
+			}
+			writePostTransactionError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(PreviewTransactionResponse{
+			Status:                "valid",
+			ExistingTransactionID: preview.ExistingTransactionID,
+			Postings:              preview.Postings,
+			BalanceImpact:         preview.Impact,
+			Explain:               cmd.explain,
+		})
+		return
 	}
 
 	transactionID, err := h.Service.PostTransaction(ctx, cmd)
+	var pending *PendingReviewError
+	if errors.As(err, &pending) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":    "pending_review",
+			"review_id": pending.ReviewID,
+			"reason":    pending.Reason,
+		})
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writePostTransactionError(w, err)
 		return
 	}
 
@@ -63,3 +158,57 @@ func (h *Handler) PostTransaction(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
+
+// writePostTransactionError tells a transaction refused by the ledger's rules (unknown
+// or disabled accounts, overdraft, KYC, assertions, validation rules, screening, hooks)
+// apart from a malformed one, and from one the database or a hook could not accept.
+// Other failures are logged and answered without their details.
+func writePostTransactionError(w http.ResponseWriter, err error) {
+	status, code := postTransactionError(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("posting failed: %v", err)
+		api.Error(w, internalErrorMessage, status)
+		return
+	}
+	api.WriteErrorCode(w, err, status, code)
+}
+
+// internalErrorMessage answers failures that are not the caller's to fix.
+const internalErrorMessage = "internal error"
+
+func postTransactionError(err error) (int, string) {
+	var invalid *ValidationError
+	var account *AccountError
+	var insufficient *InsufficientFundsError
+	var kyc *KYCError
+	var assertion *AssertionError
+	var denied *ScreeningError
+	var violation *rules.Violation
+	var rejected *hooks.RejectionError
+	var hookFailure *hooks.FailureError
+	switch {
+	case errors.As(err, &invalid), errors.Is(err, ErrRateNotFound):
+		return http.StatusBadRequest, api.CodeValidationFailed
+	case errors.As(err, &account):
+		return http.StatusUnprocessableEntity, account.Code()
+	case errors.As(err, &insufficient):
+		return http.StatusUnprocessableEntity, CodeInsufficientFunds
+	case errors.As(err, &kyc):
+		return http.StatusUnprocessableEntity, CodeKYCRequired
+	case errors.As(err, &assertion):
+		return http.StatusUnprocessableEntity, CodeAssertionFailed
+	case errors.As(err, &denied):
+		return http.StatusUnprocessableEntity, CodeScreeningDenied
+	case errors.As(err, &violation):
+		return http.StatusUnprocessableEntity, CodeRuleViolated
+	case errors.As(err, &rejected):
+		return http.StatusUnprocessableEntity, CodeHookRejected
+	case errors.As(err, &hookFailure):
+		return http.StatusBadGateway, api.CodeBadGateway
+	}
+	// The region's primary is down and its standby not yet promoted; worth retrying
+	if db.IsWriteFenced(err) {
+		return http.StatusServiceUnavailable, api.CodeUnavailable
+	}
+	return http.StatusInternalServerError, api.CodeInternal
+}
@@ -0,0 +1,307 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrHoldNotFound   = errors.New("hold not found")
+	ErrHoldNotPending = errors.New("hold is no longer pending")
+)
+
+type HoldCommand struct {
+	LedgerID        string
+	AccountCode     string // account the amount is reserved on (debited on capture)
+	DestinationCode string // account credited on capture
+	Amount          string
+	Currency        string
+	Description     string
+	Metadata        map[string]any
+	IdempotencyKey  string
+}
+
+// holdState is a hold folded from its events, so capture and void decisions don't
+// depend on projector lag.
+type holdState struct {
+	AccountCode     string
+	DestinationCode string
+	Amount          string
+	Currency        string
+	Metadata        map[string]any
+	Status          string
+	TransactionID   string
+}
+
+// CreateHold reserves an amount on an account, which must be available like a debit of
+// the account would. The projector adds it to the account's held balance until the hold
+// is captured or voided.
+func (s *Service) CreateHold(ctx context.Context, cmd HoldCommand) (string, error) {
+	if cmd.AccountCode == "" || cmd.DestinationCode == "" || cmd.Currency == "" {
+		return "", invalidf("account, destination and currency required")
+	}
+	if cmd.AccountCode == cmd.DestinationCode {
+		return "", invalidf("destination must differ from the held account")
+	}
+	amount, ok := new(big.Rat).SetString(cmd.Amount)
+	if !ok || amount.Sign() <= 0 {
+		return "", invalidf("amount must be positive: %s", cmd.Amount)
+	}
+	if err := validateMetadata(cmd.Metadata); err != nil {
+		return "", err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if cmd.IdempotencyKey != "" {
+		var existingID string
+		err = tx.QueryRow(ctx, `
+			SELECT aggregate_id FROM event_idempotency_keys WHERE ledger_id = $1 AND idempotency_key = $2
+		`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&existingID)
+		if err == nil {
+			return existingID, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	accounts, err := s.loadAndLockAccounts(ctx, tx, cmd.LedgerID, []PostingInput{
+		{AccountCode: cmd.AccountCode}, {AccountCode: cmd.DestinationCode},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, code := range []string{cmd.AccountCode, cmd.DestinationCode} {
+		acc, ok := accounts[code]
+		if !ok {
+			return "", &AccountError{AccountCode: code}
+		}
+		if acc.Disabled {
+			return "", &AccountError{AccountCode: code, Disabled: true}
+		}
+	}
+	if err := validateBalanceConstraints([]PostingInput{
+		{AccountCode: cmd.AccountCode, Direction: "debit", Amount: cmd.Amount},
+	}, accounts); err != nil {
+		return "", err
+	}
+
+	holdID := uuid.NewString()
+	payloadJSON, err := events.Marshal("HoldCreated", &events.HoldCreated{
+		HoldID:          holdID,
+		AccountCode:     cmd.AccountCode,
+		DestinationCode: cmd.DestinationCode,
+		Amount:          cmd.Amount,
+		Currency:        cmd.Currency,
+		Description:     cmd.Description,
+		Metadata:        cmd.Metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Holds take an idempotency key like transactions, so HoldCreated is appended here
+	// rather than through appendEvent
+	eventID := uuid.NewString()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO events (
+			id,
+			ledger_id,
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			occurred_at,
+			idempotency_key
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+	`, eventID, cmd.LedgerID, "hold", holdID, "HoldCreated", payloadJSON, time.Now().UTC(), cmd.IdempotencyKey)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.enqueueWebhook(ctx, tx, cmd.LedgerID, eventID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return holdID, nil
+}
+
+// CaptureHold posts the held amount, or part of it, from the held account to the
+// destination and releases the hold. An empty amount captures the full hold. Capturing
+// an already captured hold returns its transaction.
+func (s *Service) CaptureHold(ctx context.Context, ledgerID, holdID, amount string) (string, error) {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := s.lockHold(ctx, tx, ledgerID, holdID)
+	if err != nil {
+		return "", err
+	}
+	if hold.Status == "captured" {
+		return hold.TransactionID, nil
+	}
+	if hold.Status != "pending" {
+		return "", ErrHoldNotPending
+	}
+
+	if amount == "" {
+		amount = hold.Amount
+	}
+	captured, ok := new(big.Rat).SetString(amount)
+	held, _ := new(big.Rat).SetString(hold.Amount)
+	if !ok || captured.Sign() <= 0 || captured.Cmp(held) > 0 {
+		return "", invalidf("capture amount must be positive and at most %s", hold.Amount)
+	}
+
+	metadata := map[string]any{}
+	for k, v := range hold.Metadata {
+		metadata[k] = v
+	}
+	metadata["hold_id"] = holdID
+
+	transactionID, posted, err := s.postTransactionTx(ctx, tx, PostTransactionCommand{
+		LedgerID:       ledgerID,
+		IdempotencyKey: "hold:" + holdID + ":capture",
+		Currency:       hold.Currency,
+		OccurredAt:     time.Now().UTC(),
+		Metadata:       metadata,
+		Postings: []PostingInput{
+			{AccountCode: hold.AccountCode, Direction: "debit", Amount: amount},
+			{AccountCode: hold.DestinationCode, Direction: "credit", Amount: amount},
+		},
+		// Captures post within the hold's own transaction, where a pending review
+		// could not be kept, so they are not screened
+		skipScreening: true,
+		capturing:     &hold,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = s.appendEvent(ctx, tx, ledgerID, "hold", holdID, "HoldCaptured", &events.HoldCaptured{
+		HoldID:        holdID,
+		Amount:        amount,
+		TransactionID: transactionID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	s.postCommit(ctx, posted)
+
+	return transactionID, nil
+}
+
+// VoidHold releases a pending hold without posting anything.
+func (s *Service) VoidHold(ctx context.Context, ledgerID, holdID string) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := s.lockHold(ctx, tx, ledgerID, holdID)
+	if err != nil {
+		return err
+	}
+	if hold.Status == "voided" {
+		return nil
+	}
+	if hold.Status != "pending" {
+		return ErrHoldNotPending
+	}
+
+	err = s.appendEvent(ctx, tx, ledgerID, "hold", holdID, "HoldVoided", &events.HoldVoided{HoldID: holdID})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockHold serializes capture and void of a hold and folds its current state from
+// its events.
+func (s *Service) lockHold(ctx context.Context, tx pgx.Tx, ledgerID, holdID string) (holdState, error) {
+	var hold holdState
+	if _, err := uuid.Parse(holdID); err != nil {
+		return hold, ErrHoldNotFound
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "hold:"+holdID); err != nil {
+		return hold, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT event_type, payload
+		FROM events
+		WHERE ledger_id = $1 AND aggregate_type = 'hold' AND aggregate_id = $2
+		ORDER BY sequence
+	`, ledgerID, holdID)
+	if err != nil {
+		return hold, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventType string
+		var payload struct {
+			AccountCode     string         `json:"account_code"`
+			DestinationCode string         `json:"destination_code"`
+			Amount          string         `json:"amount"`
+			Currency        string         `json:"currency"`
+			Metadata        map[string]any `json:"metadata"`
+			TransactionID   string         `json:"transaction_id"`
+		}
+		var payloadJSON []byte
+		if err := rows.Scan(&eventType, &payloadJSON); err != nil {
+			return hold, err
+		}
+		if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+			return hold, err
+		}
+		switch eventType {
+		case "HoldCreated":
+			hold = holdState{
+				AccountCode:     payload.AccountCode,
+				DestinationCode: payload.DestinationCode,
+				Amount:          payload.Amount,
+				Currency:        payload.Currency,
+				Metadata:        payload.Metadata,
+				Status:          "pending",
+			}
+		case "HoldCaptured":
+			hold.Status = "captured"
+			hold.TransactionID = payload.TransactionID
+		case "HoldVoided":
+			hold.Status = "voided"
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return hold, err
+	}
+	if hold.Status == "" {
+		return hold, ErrHoldNotFound
+	}
+	return hold, nil
+}
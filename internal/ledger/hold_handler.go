@@ -0,0 +1,233 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CreateHoldRequest struct {
+	IdempotencyKey string         `json:"idempotency_key,omitempty"`
+	Account        string         `json:"account"`
+	Destination    string         `json:"destination"`
+	Amount         string         `json:"amount"`
+	Currency       string         `json:"currency"`
+	Description    string         `json:"description,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+}
+
+type HoldResponse struct {
+	ID             string         `json:"id"`
+	Account        string         `json:"account"`
+	Destination    string         `json:"destination"`
+	Amount         string         `json:"amount"`
+	Currency       string         `json:"currency"`
+	Description    string         `json:"description,omitempty"`
+	Metadata       map[string]any `json:"metadata"`
+	Status         string         `json:"status"`
+	CapturedAmount string         `json:"captured_amount,omitempty"`
+	TransactionID  string         `json:"transaction_id,omitempty"`
+	CreatedAt      string         `json:"created_at"`
+	UpdatedAt      string         `json:"updated_at"`
+}
+
+const holdSelect = `
+	SELECT id, account_code, destination_code, amount::text, currency, COALESCE(description, ''), metadata,
+		status, COALESCE(captured_amount::text, ''), COALESCE(transaction_id::text, ''), created_at, updated_at
+	FROM holds
+	WHERE ledger_id = $1
+`
+
+// POST /v1/holds - Reserve an amount on an account
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	holdID, err := h.Service.CreateHold(ctx, HoldCommand{
+		LedgerID:        principal.LedgerID,
+		AccountCode:     req.Account,
+		DestinationCode: req.Destination,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		Description:     req.Description,
+		Metadata:        req.Metadata,
+		IdempotencyKey:  req.IdempotencyKey,
+	})
+	if err != nil {
+		writePostTransactionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"hold_id": holdID,
+		"status":  "pending",
+	})
+}
+
+// GET /v1/holds - List holds, optionally by status and account
+func (h *Handler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := holdSelect
+	args := []interface{}{principal.LedgerID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(` AND status = $%d`, len(args))
+	}
+	if account := r.URL.Query().Get("account"); account != "" {
+		args = append(args, account)
+		query += fmt.Sprintf(` AND account_code = $%d`, len(args))
+	}
+	query += ` ORDER BY created_at DESC LIMIT 500`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query holds", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	holds := []HoldResponse{}
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			api.Error(w, "failed to scan hold", http.StatusInternalServerError)
+			return
+		}
+		holds = append(holds, hold)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+// GET /v1/holds?id= - Get a hold
+func (h *Handler) GetHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	hold, err := scanHold(h.Service.DB.QueryRow(ctx, holdSelect+` AND id::text = $2`, principal.LedgerID, r.URL.Query().Get("id")))
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "hold not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load hold", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// POST /v1/holds/capture?id= - Post a hold (optionally a smaller amount) and release it
+func (h *Handler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Amount string `json:"amount,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	holdID := r.URL.Query().Get("id")
+	transactionID, err := h.Service.CaptureHold(ctx, principal.LedgerID, holdID, req.Amount)
+	if !writeHoldError(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"hold_id":        holdID,
+		"transaction_id": transactionID,
+		"status":         "captured",
+	})
+}
+
+// POST /v1/holds/void?id= - Release a hold without posting
+func (h *Handler) VoidHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	holdID := r.URL.Query().Get("id")
+	if !writeHoldError(w, h.Service.VoidHold(ctx, principal.LedgerID, holdID)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"hold_id": holdID,
+		"status":  "voided",
+	})
+}
+
+// writeHoldError writes the response for a failed capture or void and reports whether
+// the request can proceed.
+func writeHoldError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrHoldNotFound):
+		api.Error(w, "hold not found", http.StatusNotFound)
+	case errors.Is(err, ErrHoldNotPending):
+		api.WriteError(w, err, http.StatusConflict)
+	default:
+		writePostTransactionError(w, err)
+	}
+	return false
+}
+
+func scanHold(row pgx.Row) (HoldResponse, error) {
+	var hold HoldResponse
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&hold.ID, &hold.Account, &hold.Destination, &hold.Amount, &hold.Currency, &hold.Description,
+		&hold.Metadata, &hold.Status, &hold.CapturedAmount, &hold.TransactionID, &createdAt, &updatedAt)
+	hold.CreatedAt = createdAt.Format(time.RFC3339)
+	hold.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return hold, err
+}
@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/calendar"
+	"Go_FormanceLegder/internal/hooks"
+	"context"
+)
+
+// hookTransaction is cmd as hooks see it; transactionID is empty until it is committed.
+func hookTransaction(cmd PostTransactionCommand, transactionID string) hooks.Transaction {
+	t := hooks.Transaction{
+		LedgerID:       cmd.LedgerID,
+		TransactionID:  transactionID,
+		IdempotencyKey: cmd.IdempotencyKey,
+		ExternalID:     cmd.ExternalID,
+		Description:    cmd.Description,
+		Currency:       cmd.Currency,
+		OccurredAt:     cmd.OccurredAt.UTC(),
+		ValueDate:      calendar.FormatDate(cmd.valueDate()),
+		EntityCode:     cmd.EntityCode,
+		Postings:       make([]hooks.Posting, len(cmd.Postings)),
+		Metadata:       cmd.Metadata,
+	}
+	for i, p := range cmd.Postings {
+		t.Postings[i] = hooks.Posting{Account: p.AccountCode, Direction: p.Direction, Amount: p.Amount,
+			Currency: postingCurrency(cmd, p), TaxCode: p.TaxCode, Description: p.Description}
+	}
+	return t
+}
+
+// preValidate runs the pre-validate hooks on cmd and keeps what they changed.
+func (s *Service) preValidate(ctx context.Context, cmd *PostTransactionCommand) error {
+	if s.Hooks == nil {
+		return nil
+	}
+	t := hookTransaction(*cmd, "")
+	if err := s.Hooks.PreValidate(ctx, &t); err != nil {
+		return err
+	}
+	cmd.Description = t.Description
+	cmd.EntityCode = t.EntityCode
+	cmd.Metadata = t.Metadata
+	cmd.Postings = make([]PostingInput, len(t.Postings))
+	for i, p := range t.Postings {
+		cmd.Postings[i] = PostingInput{AccountCode: p.Account, Direction: p.Direction, Amount: p.Amount,
+			Currency: p.Currency, TaxCode: p.TaxCode, Description: p.Description}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/hooks"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPreValidateHooksEnrichCommand(t *testing.T) {
+	registry := &hooks.Registry{}
+	registry.Register("fees", hooks.PreValidateFunc(func(ctx context.Context, tx *hooks.Transaction) error {
+		if tx.Postings[0].Currency != "USD" || tx.ValueDate != "2026-01-31" {
+			t.Errorf("hook saw %+v", tx)
+		}
+		tx.Postings = append(tx.Postings,
+			hooks.Posting{Account: "cash", Direction: "debit", Amount: "1", Currency: "USD"},
+			hooks.Posting{Account: "fees", Direction: "credit", Amount: "1", Currency: "USD"})
+		tx.Metadata = map[string]any{"fee": "1"}
+		tx.LedgerID = "other" // not the hook's to change
+		return nil
+	}))
+	s := &Service{Hooks: registry}
+
+	cmd := PostTransactionCommand{
+		LedgerID:   "l1",
+		Currency:   "USD",
+		OccurredAt: time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC),
+		Postings: []PostingInput{
+			{AccountCode: "cash", Direction: "debit", Amount: "10"},
+			{AccountCode: "revenue", Direction: "credit", Amount: "10"},
+		},
+	}
+	if err := s.preValidate(context.Background(), &cmd); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd.Postings) != 4 || cmd.Postings[3].AccountCode != "fees" || cmd.Metadata["fee"] != "1" || cmd.LedgerID != "l1" {
+		t.Fatalf("unexpected command %+v", cmd)
+	}
+}
+
+func TestHookErrorStatus(t *testing.T) {
+	if status, code := postTransactionError(&hooks.RejectionError{Hook: "h", Reason: "no"}); status != http.StatusUnprocessableEntity || code != CodeHookRejected {
+		t.Errorf("rejection: %d %s", status, code)
+	}
+	if status, _ := postTransactionError(&hooks.FailureError{Hook: "h"}); status != http.StatusBadGateway {
+		t.Errorf("failure: %d", status)
+	}
+}
@@ -0,0 +1,234 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/calendar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// An accrual covers at most this many days.
+const maxAccrualDays = 1000
+
+// Day count conventions: the year length an annual rate is divided by per day.
+var dayCounts = map[string]int64{"act/365": 365, "act/360": 360}
+
+type InterestAccrualRequest struct {
+	Account    string `json:"account"`
+	Currency   string `json:"currency"`
+	AnnualRate string `json:"annual_rate"`         // e.g. "0.045" for 4.5%
+	From       string `json:"from"`                // YYYY-MM-DD, the first day accrued
+	To         string `json:"to"`                  // YYYY-MM-DD, the last day accrued
+	DayCount   string `json:"day_count,omitempty"` // act/365 (default) or act/360
+
+	// InterestAccount, when set, is posted the interest against the account, valued on
+	// To; otherwise the accrual is only computed
+	InterestAccount string `json:"interest_account,omitempty"`
+}
+
+type InterestAccrualResponse struct {
+	Account       string        `json:"account"`
+	Currency      string        `json:"currency"`
+	AnnualRate    string        `json:"annual_rate"`
+	DayCount      string        `json:"day_count"`
+	From          string        `json:"from"`
+	To            string        `json:"to"`
+	Interest      string        `json:"interest"` // rounded to the currency's precision
+	Days          []InterestDay `json:"days"`
+	TransactionID string        `json:"transaction_id,omitempty"`
+}
+
+// InterestDay is one day of an accrual: the account's balance at the end of the day, by
+// value date, and the interest it earned unrounded.
+type InterestDay struct {
+	Date     string `json:"date"`
+	Balance  string `json:"balance"`
+	Interest string `json:"interest"`
+}
+
+type InterestCommand struct {
+	LedgerID    string
+	AccountCode string
+	Currency    string
+	AnnualRate  *big.Rat
+	From, To    time.Time
+	DayCount    string
+}
+
+// POST /v1/interest/accruals - Accrue simple interest on an account over a date range
+//
+// Interest accrues every calendar day on the account's balance by value date, debits
+// positive, so back-valued transactions earn or cost interest from their value date.
+// With an interest account the result is posted once per account, currency and period.
+func (h *Handler) AccrueInterest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req InterestAccrualRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	cmd := InterestCommand{LedgerID: principal.LedgerID, AccountCode: req.Account, Currency: req.Currency, DayCount: req.DayCount}
+	var ok bool
+	if cmd.AnnualRate, ok = new(big.Rat).SetString(req.AnnualRate); !ok {
+		api.Error(w, "annual_rate must be a decimal", http.StatusBadRequest)
+		return
+	}
+	if cmd.From, err = calendar.ParseDate(req.From); err != nil {
+		api.Error(w, "from must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if cmd.To, err = calendar.ParseDate(req.To); err != nil {
+		api.Error(w, "to must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	accrual, err := h.Service.AccrueInterest(ctx, cmd)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	accrual.AnnualRate = req.AnnualRate
+
+	status := http.StatusOK
+	interest, _ := new(big.Rat).SetString(accrual.Interest)
+	if req.InterestAccount != "" && interest.Sign() != 0 {
+		// Positive interest is earned by the account, negative owed by it
+		accountDirection, interestDirection := "debit", "credit"
+		if interest.Sign() < 0 {
+			accountDirection, interestDirection = "credit", "debit"
+		}
+		amount := new(big.Rat).Abs(interest).FloatString(10)
+		key := fmt.Sprintf("interest:%s:%s:%s:%s", req.Account, req.Currency, accrual.From, accrual.To)
+
+		accrual.TransactionID, err = h.Service.PostTransaction(ctx, PostTransactionCommand{
+			LedgerID:       principal.LedgerID,
+			ExternalID:     key,
+			Description:    fmt.Sprintf("Interest %s to %s", accrual.From, accrual.To),
+			IdempotencyKey: key,
+			Currency:       req.Currency,
+			OccurredAt:     time.Now().UTC(),
+			ValueDate:      cmd.To,
+			Postings: []PostingInput{
+				{AccountCode: req.Account, Direction: accountDirection, Amount: amount},
+				{AccountCode: req.InterestAccount, Direction: interestDirection, Amount: amount},
+			},
+		})
+		if err != nil {
+			writePostTransactionError(w, err)
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(accrual)
+}
+
+// AccrueInterest computes simple interest on an account, day by day from cmd.From to
+// cmd.To inclusive, on its end-of-day balance in cmd.Currency by value date.
+func (s *Service) AccrueInterest(ctx context.Context, cmd InterestCommand) (InterestAccrualResponse, error) {
+	if cmd.DayCount == "" {
+		cmd.DayCount = "act/365"
+	}
+	resp := InterestAccrualResponse{Account: cmd.AccountCode, Currency: cmd.Currency, DayCount: cmd.DayCount,
+		From: calendar.FormatDate(cmd.From), To: calendar.FormatDate(cmd.To), Days: []InterestDay{}}
+	yearDays, ok := dayCounts[cmd.DayCount]
+	if !ok {
+		return resp, invalidf("day_count must be act/365 or act/360")
+	}
+	if cmd.AccountCode == "" || cmd.Currency == "" {
+		return resp, invalidf("account and currency required")
+	}
+	days := int(cmd.To.Sub(cmd.From)/(24*time.Hour)) + 1
+	if days < 1 || days > maxAccrualDays {
+		return resp, invalidf("to must be on or after from, at most %d days later", maxAccrualDays-1)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return resp, err
+	}
+	defer tx.Rollback(ctx)
+
+	var accountID string
+	err = tx.QueryRow(ctx, `SELECT id FROM accounts WHERE ledger_id = $1 AND code = $2`,
+		cmd.LedgerID, cmd.AccountCode).Scan(&accountID)
+	if err != nil {
+		return resp, err
+	}
+
+	// The balance going into From, then each day's change
+	rows, err := tx.Query(ctx, `
+		SELECT GREATEST(t.value_date, $4::date - 1)::text,
+			SUM(CASE WHEN p.direction = 'debit' THEN p.amount ELSE -p.amount END)::text
+		FROM postings p
+		JOIN transactions t ON t.id = p.transaction_id
+		WHERE p.ledger_id = $1 AND p.account_id = $2
+		  AND COALESCE(p.currency, t.currency) = $3
+		  AND t.value_date <= $5
+		GROUP BY 1
+	`, cmd.LedgerID, accountID, cmd.Currency, cmd.From, cmd.To)
+	if err != nil {
+		return resp, err
+	}
+	changes := map[string]*big.Rat{}
+	for rows.Next() {
+		var date, change string
+		if err := rows.Scan(&date, &change); err != nil {
+			rows.Close()
+			return resp, err
+		}
+		changes[date], _ = new(big.Rat).SetString(change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	currencies, err := loadCurrencies(ctx, tx, cmd.LedgerID, []string{cmd.Currency})
+	if err != nil {
+		return resp, err
+	}
+	currency, ok := currencies[cmd.Currency]
+	if !ok {
+		currency = Currency{Precision: 2, Rounding: RoundHalfUp}
+	}
+
+	daily := new(big.Rat).Quo(cmd.AnnualRate, big.NewRat(yearDays, 1))
+	balance := new(big.Rat)
+	if opening, ok := changes[calendar.FormatDate(cmd.From.AddDate(0, 0, -1))]; ok {
+		balance.Add(balance, opening)
+	}
+	total := new(big.Rat)
+	for d := cmd.From; !d.After(cmd.To); d = d.AddDate(0, 0, 1) {
+		date := calendar.FormatDate(d)
+		if change, ok := changes[date]; ok {
+			balance.Add(balance, change)
+		}
+		interest := new(big.Rat).Mul(balance, daily)
+		total.Add(total, interest)
+		resp.Days = append(resp.Days, InterestDay{Date: date, Balance: balance.FloatString(10), Interest: interest.FloatString(10)})
+	}
+	resp.Interest = roundMode(total, currency.Precision, currency.Rounding).FloatString(currency.Precision)
+	return resp, nil
+}
@@ -0,0 +1,242 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/journal"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxJournalExportPeriod caps one export; longer periods are exported in parts.
+const maxJournalExportPeriod = 366 * 24 * time.Hour
+
+type JournalExportSettings struct {
+	Mapping   journal.Mapping `json:"mapping"`
+	UpdatedAt string          `json:"updated_at,omitempty"`
+}
+
+// GET /v1/journal-exports/settings - Get the account mapping used by journal exports
+func (h *Handler) GetJournalExportSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.journalExportSettings(r, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query journal export settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// PUT /v1/journal-exports/settings - Replace the account mapping used by journal exports
+func (h *Handler) SaveJournalExportSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req JournalExportSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if err := req.Mapping.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := json.Marshal(req.Mapping)
+	if err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var updatedAt time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO journal_export_settings (ledger_id, mapping)
+		VALUES ($1, $2)
+		ON CONFLICT (ledger_id) DO UPDATE SET mapping = EXCLUDED.mapping, updated_at = NOW()
+		RETURNING updated_at
+	`, principal.LedgerID, mapping).Scan(&updatedAt)
+	if err != nil {
+		api.Error(w, "failed to save journal export settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(JournalExportSettings{Mapping: req.Mapping, UpdatedAt: updatedAt.Format(time.RFC3339)})
+}
+
+func (h *Handler) journalExportSettings(r *http.Request, ledgerID string) (JournalExportSettings, error) {
+	var settings JournalExportSettings
+	var mapping []byte
+	var updatedAt time.Time
+	err := h.Service.DB.QueryRow(r.Context(), `
+		SELECT mapping, updated_at FROM journal_export_settings WHERE ledger_id = $1
+	`, ledgerID).Scan(&mapping, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(mapping, &settings.Mapping); err != nil {
+		return settings, err
+	}
+	settings.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return settings, nil
+}
+
+// GET /v1/journal-exports - Export a period's transactions as a journal file
+//
+// format is gl (generic general ledger CSV), xero (manual journal CSV) or iif (QuickBooks
+// general journal). from and to are RFC3339 timestamps bounding occurred_at, to exclusive,
+// at most a year apart; basis=value_date bounds and dates entries by value date instead.
+// currency optionally keeps only transactions in that currency. Accounts are renamed
+// through the ledger's export settings.
+func (h *Handler) ExportJournal(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		api.Error(w, "from must be an RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		api.Error(w, "to must be an RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	if !to.After(from) {
+		api.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) > maxJournalExportPeriod {
+		api.Error(w, "period must not exceed a year", http.StatusBadRequest)
+		return
+	}
+
+	dateColumn, entryDate := "t.occurred_at", "(t.occurred_at AT TIME ZONE 'UTC')::date"
+	switch q.Get("basis") {
+	case "", "occurred_at":
+	case "value_date":
+		dateColumn, entryDate = "t.value_date", "t.value_date"
+	default:
+		api.Error(w, "basis must be occurred_at or value_date", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.journalExportSettings(r, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query journal export settings", http.StatusInternalServerError)
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = "gl"
+	}
+	jw, err := journal.NewWriter(w, format, settings.Mapping)
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	filter := `
+		FROM postings p
+		JOIN transactions t ON t.id = p.transaction_id
+		JOIN accounts a ON a.id = p.account_id
+		WHERE p.ledger_id = $1
+		  AND ` + dateColumn + ` >= $2
+		  AND ` + dateColumn + ` < $3
+		  AND ($4 = '' OR t.currency = $4)
+	`
+	args := []interface{}{principal.LedgerID, from, to, q.Get("currency")}
+
+	// Refuse unmapped accounts before anything is written, rather than cutting the file short.
+	if settings.Mapping.RequireMapped {
+		rows, err := h.Service.DB.Query(ctx, `SELECT DISTINCT a.code `+filter+` ORDER BY a.code`, args...)
+		if err != nil {
+			api.Error(w, "failed to query journal", http.StatusInternalServerError)
+			return
+		}
+		codes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			api.Error(w, "failed to query journal", http.StatusInternalServerError)
+			return
+		}
+		if unmapped := settings.Mapping.Unmapped(codes); len(unmapped) > 0 {
+			api.Error(w, "unmapped accounts: "+strings.Join(unmapped, ", "), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT t.id, t.external_id, COALESCE(t.description, ''), `+entryDate+`, a.code, a.name, p.direction,
+			p.amount::text, COALESCE(p.currency, t.currency), COALESCE(p.tax_code, ''), COALESCE(p.description, '')
+	`+filter+`
+		ORDER BY `+dateColumn+`, t.id, p.created_at, p.id
+	`, args...)
+	if err != nil {
+		api.Error(w, "failed to query journal", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", jw.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="journal-`+from.Format("20060102")+`-`+to.Format("20060102")+`.`+jw.Extension()+`"`)
+
+	var entry journal.Entry
+	for rows.Next() {
+		var transactionID, externalID, description string
+		var date time.Time
+		var l journal.Line
+		err := rows.Scan(&transactionID, &externalID, &description, &date, &l.Account, &l.AccountName, &l.Direction,
+			&l.Amount, &l.Currency, &l.TaxCode, &l.Description)
+		if err != nil {
+			return
+		}
+		if transactionID != entry.TransactionID {
+			if entry.TransactionID != "" {
+				if err := jw.Write(entry); err != nil {
+					return
+				}
+			}
+			entry = journal.Entry{TransactionID: transactionID, ExternalID: externalID, Description: description, Date: date}
+		}
+		entry.Lines = append(entry.Lines, l)
+	}
+	// The response has started; a failed query leaves the file truncated, so don't flush it.
+	if rows.Err() != nil {
+		return
+	}
+	if entry.TransactionID != "" {
+		if err := jw.Write(entry); err != nil {
+			return
+		}
+	}
+	jw.Flush()
+}
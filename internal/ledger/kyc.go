@@ -0,0 +1,190 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var kycStatuses = map[string]bool{
+	"unverified": true, "pending": true, "verified": true, "rejected": true,
+}
+
+// KYCError rejects a transaction involving an entity that is not verified, under the
+// ledger's KYC policy.
+type KYCError struct {
+	EntityCode string
+	Status     string
+	Reason     string
+}
+
+func (e *KYCError) Error() string {
+	return fmt.Sprintf("entity %s is %s: %s", e.EntityCode, e.Status, e.Reason)
+}
+
+// KYCPolicy decides what happens to transactions involving unverified entities: allow
+// (the default when none is set), block, or cap them at MaxAmount per currency.
+type KYCPolicy struct {
+	Action    string `json:"action"`
+	MaxAmount string `json:"max_amount,omitempty"`
+}
+
+func (p KYCPolicy) Validate() error {
+	switch p.Action {
+	case "allow", "block":
+		return nil
+	case "cap":
+		limit, ok := new(big.Rat).SetString(p.MaxAmount)
+		if !ok || limit.Sign() < 0 {
+			return fmt.Errorf("max_amount must be a non-negative decimal")
+		}
+		return nil
+	}
+	return fmt.Errorf("action must be allow, block or cap")
+}
+
+// SetEntityKYCStatus changes an entity's KYC status and records an EntityKYCStatusChanged
+// event, which is delivered to webhooks like any other event. Setting the current status
+// again is a no-op.
+func (s *Service) SetEntityKYCStatus(ctx context.Context, ledgerID, code, status, reason string) error {
+	if !kycStatuses[status] {
+		return fmt.Errorf("status must be unverified, pending, verified or rejected")
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var entityID, previous string
+	err = tx.QueryRow(ctx, `
+		SELECT id, kyc_status FROM entities WHERE ledger_id = $1 AND code = $2 FOR UPDATE
+	`, ledgerID, code).Scan(&entityID, &previous)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEntityNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if previous == status {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE entities SET kyc_status = $2, kyc_updated_at = NOW(), updated_at = NOW() WHERE id = $1
+	`, entityID, status)
+	if err != nil {
+		return err
+	}
+
+	payload := &events.EntityKYCStatusChanged{
+		EntityCode:     code,
+		PreviousStatus: previous,
+		Status:         status,
+		Reason:         reason,
+	}
+	if err := s.appendEvent(ctx, tx, ledgerID, "entity", entityID, "EntityKYCStatusChanged", payload); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// enforceKYCPolicy applies the ledger's KYC policy to a transaction. The entities
+// involved are the transaction's own and those linked to its accounts.
+func enforceKYCPolicy(ctx context.Context, tx pgx.Tx, cmd PostTransactionCommand, accounts map[string]Account) error {
+	var policy KYCPolicy
+	err := tx.QueryRow(ctx, `
+		SELECT action, COALESCE(max_amount::text, '') FROM kyc_policies WHERE ledger_id = $1
+	`, cmd.LedgerID).Scan(&policy.Action, &policy.MaxAmount)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && policy.Action == "allow") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	codesSet := map[string]struct{}{}
+	if cmd.EntityCode != "" {
+		codesSet[cmd.EntityCode] = struct{}{}
+	}
+	for _, a := range accounts {
+		if a.EntityCode != "" {
+			codesSet[a.EntityCode] = struct{}{}
+		}
+	}
+	if len(codesSet) == 0 {
+		return nil
+	}
+	codes := make([]string, 0, len(codesSet))
+	for c := range codesSet {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+
+	var entityCode, status string
+	err = tx.QueryRow(ctx, `
+		SELECT code, kyc_status
+		FROM entities
+		WHERE ledger_id = $1 AND code = ANY($2) AND kyc_status <> 'verified'
+		ORDER BY code
+		LIMIT 1
+	`, cmd.LedgerID, codes).Scan(&entityCode, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if policy.Action == "block" {
+		return &KYCError{EntityCode: entityCode, Status: status, Reason: "transactions are blocked until verified"}
+	}
+	if reason := exceedsKYCCap(cmd, policy.MaxAmount); reason != "" {
+		return &KYCError{EntityCode: entityCode, Status: status, Reason: reason}
+	}
+	return nil
+}
+
+// exceedsKYCCap describes the first currency whose total debits exceed maxAmount, or
+// returns "" when the transaction is within the cap.
+func exceedsKYCCap(cmd PostTransactionCommand, maxAmount string) string {
+	limit, ok := new(big.Rat).SetString(maxAmount)
+	if !ok {
+		return "invalid kyc cap " + maxAmount
+	}
+
+	totals := map[string]*big.Rat{}
+	for _, p := range cmd.Postings {
+		if p.Direction != "debit" {
+			continue
+		}
+		amount, ok := new(big.Rat).SetString(p.Amount)
+		if !ok {
+			continue
+		}
+		currency := postingCurrency(cmd, p)
+		if totals[currency] == nil {
+			totals[currency] = new(big.Rat)
+		}
+		totals[currency].Add(totals[currency], amount)
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for c := range totals {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+	for _, c := range currencies {
+		if totals[c].Cmp(limit) > 0 {
+			return fmt.Sprintf("amount %s %s exceeds the unverified limit of %s", totals[c].FloatString(2), c, limit.FloatString(2))
+		}
+	}
+	return ""
+}
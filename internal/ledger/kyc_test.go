@@ -0,0 +1,24 @@
+package ledger
+
+import "testing"
+
+func TestExceedsKYCCap(t *testing.T) {
+	cmd := PostTransactionCommand{
+		Currency: "USD",
+		Postings: []PostingInput{
+			{AccountCode: "a", Direction: "debit", Amount: "600"},
+			{AccountCode: "b", Direction: "debit", Amount: "500"},
+			{AccountCode: "c", Direction: "credit", Amount: "1100"},
+		},
+	}
+	if reason := exceedsKYCCap(cmd, "1100"); reason != "" {
+		t.Fatalf("expected a transaction at the cap to pass, got %q", reason)
+	}
+	if reason := exceedsKYCCap(cmd, "1000"); reason == "" {
+		t.Fatal("expected the summed debits to exceed the cap")
+	}
+
+	if err := (KYCPolicy{Action: "cap"}).Validate(); err == nil {
+		t.Fatal("expected cap without max_amount to be rejected")
+	}
+}
@@ -0,0 +1,61 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Encoded metadata is stored in every event and read-model row, so keep it small.
+const maxMetadataBytes = 4096
+
+func validateMetadata(metadata map[string]any) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return invalidf("invalid metadata: %w", err)
+	}
+	if len(encoded) > maxMetadataBytes {
+		return invalidf("metadata exceeds %d bytes", maxMetadataBytes)
+	}
+	for key := range metadata {
+		if key == "" {
+			return invalidf("metadata keys must not be empty")
+		}
+	}
+	return nil
+}
+
+type metadataFilter struct {
+	Key   string
+	Value string
+}
+
+// parseMetadataFilters extracts metadata[key]=value query parameters, sorted by key so
+// the generated SQL is stable.
+func parseMetadataFilters(query url.Values) ([]metadataFilter, error) {
+	filters := []metadataFilter{}
+	for param, values := range query {
+		if !strings.HasPrefix(param, "metadata[") {
+			continue
+		}
+		if !strings.HasSuffix(param, "]") || len(param) == len("metadata[]") {
+			return nil, fmt.Errorf("invalid metadata filter: %s", param)
+		}
+		key := param[len("metadata[") : len(param)-1]
+		for _, v := range values {
+			filters = append(filters, metadataFilter{Key: key, Value: v})
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Key != filters[j].Key {
+			return filters[i].Key < filters[j].Key
+		}
+		return filters[i].Value < filters[j].Value
+	})
+	return filters, nil
+}
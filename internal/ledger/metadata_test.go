@@ -0,0 +1,43 @@
+package ledger
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseMetadataFilters(t *testing.T) {
+	query, _ := url.ParseQuery("limit=10&metadata[order_id]=42&metadata[customer]=acme")
+	filters, err := parseMetadataFilters(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []metadataFilter{{Key: "customer", Value: "acme"}, {Key: "order_id", Value: "42"}}
+	if len(filters) != len(want) {
+		t.Fatalf("got %v, want %v", filters, want)
+	}
+	for i := range want {
+		if filters[i] != want[i] {
+			t.Fatalf("got %v, want %v", filters, want)
+		}
+	}
+
+	for _, bad := range []string{"metadata[]=x", "metadata[order_id=x"} {
+		query, _ := url.ParseQuery(bad)
+		if _, err := parseMetadataFilters(query); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	if err := validateMetadata(map[string]any{"order_id": "42", "tags": []any{"a"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateMetadata(map[string]any{"": "x"}); err == nil {
+		t.Fatal("expected empty key to be rejected")
+	}
+	if err := validateMetadata(map[string]any{"blob": strings.Repeat("x", maxMetadataBytes)}); err == nil {
+		t.Fatal("expected oversized metadata to be rejected")
+	}
+}
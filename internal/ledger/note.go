@@ -0,0 +1,48 @@
+package ledger
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Note is a dashboard user's comment on a transaction or account (see dashboard.NoteHandler).
+type Note struct {
+	AuthorEmail string `json:"author_email"`
+	Body        string `json:"body"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func (h *Handler) loadNotes(ctx context.Context, ledgerID, targetType, targetID string) ([]Note, error) {
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT author_email, body, created_at
+		FROM notes
+		WHERE ledger_id = $1 AND target_type = $2 AND target_id = $3
+		ORDER BY created_at
+	`, ledgerID, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		var n Note
+		var createdAt time.Time
+		if err := rows.Scan(&n.AuthorEmail, &n.Body, &createdAt); err != nil {
+			return nil, err
+		}
+		n.CreatedAt = createdAt.Format(time.RFC3339)
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// formatNotes flattens notes into one CSV cell, oldest first.
+func formatNotes(notes []Note) string {
+	parts := make([]string, len(notes))
+	for i, n := range notes {
+		parts[i] = n.CreatedAt + " " + n.AuthorEmail + ": " + n.Body
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,292 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/payout"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreatePayoutFileRequest struct {
+	Format        string                     `json:"format"`
+	SettlementID  string                     `json:"settlement_id,omitempty"`
+	Currency      string                     `json:"currency"`
+	ExecutionDate string                     `json:"execution_date,omitempty"` // YYYY-MM-DD, defaults to today
+	Originator    payout.Originator          `json:"originator"`
+	Instructions  []PayoutInstructionRequest `json:"instructions"`
+}
+
+// PayoutInstructionRequest describes one beneficiary. Amount may be omitted when
+// AccountCode is given, in which case the account's current balance is paid out.
+type PayoutInstructionRequest struct {
+	AccountCode   string `json:"account_code,omitempty"`
+	Amount        string `json:"amount,omitempty"`
+	Name          string `json:"name"`
+	Reference     string `json:"reference,omitempty"`
+	Description   string `json:"description,omitempty"`
+	IBAN          string `json:"iban,omitempty"`
+	BIC           string `json:"bic,omitempty"`
+	RoutingNumber string `json:"routing_number,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	AccountType   string `json:"account_type,omitempty"`
+}
+
+type PayoutFileResponse struct {
+	ID               string `json:"id"`
+	Format           string `json:"format"`
+	MessageID        string `json:"message_id"`
+	SettlementID     string `json:"settlement_id,omitempty"`
+	InstructionCount int    `json:"instruction_count"`
+	TotalAmount      string `json:"total_amount"`
+	Currency         string `json:"currency"`
+	ExecutionDate    string `json:"execution_date"`
+	Checksum         string `json:"checksum"`
+	CreatedAt        string `json:"created_at"`
+}
+
+// POST /v1/payout-files - Generate a pain.001 or NACHA file from a settlement batch or balances
+func (h *Handler) CreatePayoutFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreatePayoutFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	executionDate := now.Truncate(24 * time.Hour)
+	if req.ExecutionDate != "" {
+		executionDate, err = time.Parse("2006-01-02", req.ExecutionDate)
+		if err != nil {
+			api.Error(w, "execution_date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	currency := req.Currency
+	var settlementID *string
+
+	if req.SettlementID != "" {
+		// A settlement batch pays its net amount to a single beneficiary
+		if len(req.Instructions) != 1 {
+			api.Error(w, "exactly one instruction (the beneficiary) required for a settlement payout", http.StatusBadRequest)
+			return
+		}
+
+		var status, netStr string
+		err := h.Service.DB.QueryRow(ctx, `
+			SELECT status, net_amount::text, currency
+			FROM settlement_batches
+			WHERE id = $1 AND ledger_id = $2
+		`, req.SettlementID, principal.LedgerID).Scan(&status, &netStr, &currency)
+		if err != nil {
+			api.Error(w, "settlement not found", http.StatusNotFound)
+			return
+		}
+		if status != "settled" {
+			api.Error(w, "settlement is not settled", http.StatusUnprocessableEntity)
+			return
+		}
+
+		net, _ := new(big.Rat).SetString(netStr)
+		if net.Sign() <= 0 {
+			api.Error(w, "settlement has no positive net amount to pay out", http.StatusUnprocessableEntity)
+			return
+		}
+		req.Instructions[0].Amount = net.FloatString(10)
+		settlementID = &req.SettlementID
+	}
+
+	if currency == "" {
+		api.Error(w, "currency required", http.StatusBadRequest)
+		return
+	}
+
+	file := payout.File{
+		Format:        req.Format,
+		MessageID:     strings.ReplaceAll(uuid.NewString(), "-", "")[:16],
+		Originator:    req.Originator,
+		ExecutionDate: executionDate,
+		CreatedAt:     now,
+	}
+
+	for i, in := range req.Instructions {
+		amountStr := in.Amount
+		if amountStr == "" && in.AccountCode != "" {
+			err := h.Service.DB.QueryRow(ctx, `
+				SELECT balance::text FROM accounts WHERE ledger_id = $1 AND code = $2
+			`, principal.LedgerID, in.AccountCode).Scan(&amountStr)
+			if err != nil {
+				api.Error(w, fmt.Sprintf("account %s not found", in.AccountCode), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var amount *big.Rat
+		if amountStr != "" {
+			a, ok := new(big.Rat).SetString(amountStr)
+			if !ok {
+				api.Error(w, fmt.Sprintf("instruction %d: invalid amount", i+1), http.StatusBadRequest)
+				return
+			}
+			amount = a
+		}
+
+		reference := in.Reference
+		if reference == "" {
+			reference = in.AccountCode
+		}
+
+		file.Instructions = append(file.Instructions, payout.Instruction{
+			Reference:     reference,
+			Name:          in.Name,
+			Amount:        amount,
+			Currency:      currency,
+			Description:   in.Description,
+			IBAN:          in.IBAN,
+			BIC:           in.BIC,
+			RoutingNumber: in.RoutingNumber,
+			AccountNumber: in.AccountNumber,
+			AccountType:   in.AccountType,
+		})
+	}
+
+	var content []byte
+	switch req.Format {
+	case payout.FormatPain001:
+		content, err = payout.RenderPain001(file)
+	case payout.FormatNACHA:
+		content, err = payout.RenderNACHA(file)
+	default:
+		err = payout.Validate(file)
+	}
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	resp := PayoutFileResponse{
+		Format:           file.Format,
+		MessageID:        file.MessageID,
+		InstructionCount: len(file.Instructions),
+		TotalAmount:      file.Total().FloatString(2),
+		Currency:         currency,
+		ExecutionDate:    executionDate.Format("2006-01-02"),
+		Checksum:         hex.EncodeToString(sum[:]),
+		CreatedAt:        now.Format(time.RFC3339),
+	}
+	if settlementID != nil {
+		resp.SettlementID = *settlementID
+	}
+
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO payout_files (ledger_id, format, message_id, settlement_batch_id, instruction_count,
+			total_amount, currency, execution_date, checksum, content, api_key_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`, principal.LedgerID, file.Format, file.MessageID, settlementID, resp.InstructionCount,
+		resp.TotalAmount, currency, executionDate, resp.Checksum, content, principal.APIKeyID, now).Scan(&resp.ID)
+	if err != nil {
+		api.Error(w, "failed to store payout file", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /v1/payout-files - List generated payout files
+func (h *Handler) ListPayoutFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT id, format, message_id, COALESCE(settlement_batch_id::text, ''), instruction_count,
+			total_amount::text, currency, execution_date, checksum, created_at
+		FROM payout_files
+		WHERE ledger_id = $1
+		ORDER BY created_at DESC
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query payout files", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	files := []PayoutFileResponse{}
+	for rows.Next() {
+		var f PayoutFileResponse
+		var executionDate, createdAt time.Time
+		if err := rows.Scan(&f.ID, &f.Format, &f.MessageID, &f.SettlementID, &f.InstructionCount,
+			&f.TotalAmount, &f.Currency, &executionDate, &f.Checksum, &createdAt); err != nil {
+			api.Error(w, "failed to scan payout file", http.StatusInternalServerError)
+			return
+		}
+		f.ExecutionDate = executionDate.Format("2006-01-02")
+		f.CreatedAt = createdAt.Format(time.RFC3339)
+		files = append(files, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// GET /v1/payout-files/download?id= - Download the generated file content
+func (h *Handler) DownloadPayoutFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fileID := r.URL.Query().Get("id")
+	if fileID == "" {
+		api.Error(w, "payout file id required", http.StatusBadRequest)
+		return
+	}
+
+	var format, checksum string
+	var content []byte
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT format, checksum, content FROM payout_files WHERE id = $1 AND ledger_id = $2
+	`, fileID, principal.LedgerID).Scan(&format, &checksum, &content)
+	if err != nil {
+		api.Error(w, "payout file not found", http.StatusNotFound)
+		return
+	}
+
+	contentType, ext := "application/xml", "xml"
+	if format == payout.FormatNACHA {
+		contentType, ext = "text/plain", "ach"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="payout-`+fileID+`.`+ext+`"`)
+	w.Header().Set("X-Checksum-SHA256", checksum)
+	w.Write(content)
+}
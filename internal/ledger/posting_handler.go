@@ -0,0 +1,161 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// PostingResponse is a posting of the flat journal, with what it needs from its
+// transaction to stand on its own.
+type PostingResponse struct {
+	PostingDetail
+	TransactionID string `json:"transaction_id"`
+	ExternalID    string `json:"external_id"`
+	OccurredAt    string `json:"occurred_at"`
+	ValueDate     string `json:"value_date"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type ListPostingsResponse struct {
+	Postings   []PostingResponse      `json:"postings"`
+	Pagination api.PaginationResponse `json:"pagination"`
+}
+
+// GET /v1/postings - List postings across transactions, newest first, with pagination
+//
+// Filters, all optional: account (code), direction (debit or credit), currency,
+// min_amount and max_amount (inclusive), and start_time and end_time on the
+// transaction's occurred_at (inclusive).
+func (h *Handler) ListPostings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		fmt.Sscanf(limitStr, "%d", &limit)
+	}
+	limit = api.ValidateLimit(limit)
+
+	cursor, err := api.DecodeCursor(query.Get("continuation_token"))
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sql := `
+		SELECT p.id, a.code, a.name, p.direction, p.amount, COALESCE(p.currency, t.currency), COALESCE(p.tax_code, ''),
+			COALESCE(p.description, ''), t.id, t.external_id, t.occurred_at, t.value_date::text, p.created_at
+		FROM postings p
+		JOIN accounts a ON a.id = p.account_id
+		JOIN transactions t ON t.id = p.transaction_id
+		WHERE p.ledger_id = $1
+	`
+	args := []interface{}{principal.LedgerID}
+	where := func(condition string, value any) {
+		args = append(args, value)
+		sql += fmt.Sprintf(" AND "+condition, len(args))
+	}
+
+	if !cursor.Timestamp.IsZero() {
+		args = append(args, cursor.Timestamp, cursor.ID)
+		sql += fmt.Sprintf(` AND (p.created_at, p.id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	if account := query.Get("account"); account != "" {
+		where("a.code = $%d", account)
+	}
+	if direction := query.Get("direction"); direction != "" {
+		if direction != "debit" && direction != "credit" {
+			api.Error(w, "direction must be debit or credit", http.StatusBadRequest)
+			return
+		}
+		where("p.direction = $%d", direction)
+	}
+	if currency := query.Get("currency"); currency != "" {
+		where("COALESCE(p.currency, t.currency) = $%d", currency)
+	}
+	for _, bound := range []struct{ param, op string }{{"min_amount", ">="}, {"max_amount", "<="}} {
+		value := query.Get(bound.param)
+		if value == "" {
+			continue
+		}
+		if _, ok := new(big.Rat).SetString(value); !ok {
+			api.Error(w, bound.param+" must be a decimal", http.StatusBadRequest)
+			return
+		}
+		where("p.amount "+bound.op+" $%d::numeric", value)
+	}
+	for _, bound := range []struct{ param, op string }{{"start_time", ">="}, {"end_time", "<="}} {
+		value := query.Get(bound.param)
+		if value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			api.Error(w, bound.param+" must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		where("t.occurred_at "+bound.op+" $%d", at)
+	}
+
+	args = append(args, limit+1)
+	sql += fmt.Sprintf(` ORDER BY p.created_at DESC, p.id DESC LIMIT $%d`, len(args))
+
+	rows, err := h.Service.DB.Query(ctx, sql, args...)
+	if err != nil {
+		api.Error(w, "failed to query postings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	postings := []PostingResponse{}
+	var lastCreatedAt time.Time
+	hasMore := false
+	for rows.Next() {
+		if len(postings) >= limit {
+			hasMore = true
+			break
+		}
+		var p PostingResponse
+		var occurredAt, createdAt time.Time
+		err := rows.Scan(&p.ID, &p.AccountCode, &p.AccountName, &p.Direction, &p.Amount, &p.Currency, &p.TaxCode,
+			&p.Description, &p.TransactionID, &p.ExternalID, &occurredAt, &p.ValueDate, &createdAt)
+		if err != nil {
+			api.Error(w, "failed to scan posting", http.StatusInternalServerError)
+			return
+		}
+		p.OccurredAt = occurredAt.Format(time.RFC3339)
+		p.CreatedAt = createdAt.Format(time.RFC3339)
+		postings = append(postings, p)
+		lastCreatedAt = createdAt
+	}
+	if err := rows.Err(); err != nil {
+		api.Error(w, "failed to query postings", http.StatusInternalServerError)
+		return
+	}
+
+	var nextToken string
+	if hasMore {
+		nextToken, _ = api.EncodeCursor(api.Cursor{Timestamp: lastCreatedAt, ID: postings[len(postings)-1].ID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListPostingsResponse{
+		Postings: postings,
+		Pagination: api.PaginationResponse{
+			HasMore:           hasMore,
+			ContinuationToken: nextToken,
+			Count:             len(postings),
+		},
+	})
+}
@@ -0,0 +1,107 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TransactionPreview is what posting a transaction would do, computed without writing it.
+type TransactionPreview struct {
+	// ExistingTransactionID is set when the idempotency key was already used; posting
+	// would return that transaction instead of creating a new one
+	ExistingTransactionID string
+	Postings              []PostingInput // after script compilation
+	Impact                []BalanceImpact
+}
+
+// BalanceImpact is the change a transaction makes to one account. Before counts the
+// events the projector has not applied yet.
+type BalanceImpact struct {
+	AccountCode string `json:"account_code"`
+	Before      string `json:"before"`
+	Change      string `json:"change"`
+	After       string `json:"after"`
+}
+
+// PreviewTransaction runs the same validation as PostTransaction and returns the
+// resulting balance change per account, without appending an event. With cmd.explain set
+// it also records what screening would decide.
+func (s *Service) PreviewTransaction(ctx context.Context, cmd PostTransactionCommand) (TransactionPreview, error) {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return TransactionPreview{}, err
+	}
+	// Always rolled back; the transaction only holds the account locks validation takes
+	defer tx.Rollback(ctx)
+
+	var preview TransactionPreview
+	err = tx.QueryRow(ctx, `
+		SELECT aggregate_id
+		FROM event_idempotency_keys
+		WHERE ledger_id = $1
+		  AND idempotency_key = $2
+	`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&preview.ExistingTransactionID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return TransactionPreview{}, err
+	}
+	if preview.ExistingTransactionID != "" {
+		cmd.explain.note("idempotency", CheckPassed, "key already used by transaction "+preview.ExistingTransactionID+", which posting would return")
+	}
+
+	accounts, err := s.validateTransactionTx(ctx, tx, &cmd)
+	if err != nil {
+		return TransactionPreview{}, err
+	}
+
+	if cmd.explain != nil {
+		if err := s.explainScreening(ctx, tx, cmd); err != nil {
+			return TransactionPreview{}, err
+		}
+	}
+
+	preview.Postings = cmd.Postings
+	preview.Impact = balanceImpact(cmd.Postings, accounts)
+	return preview, nil
+}
+
+// balanceImpact applies postings the way the projector does: credits increase an
+// account's balance and debits decrease it. Postings must already be validated.
+func balanceImpact(postings []PostingInput, accounts map[string]Account) []BalanceImpact {
+	changes := map[string]*big.Rat{}
+	for _, p := range postings {
+		amount, _ := new(big.Rat).SetString(p.Amount)
+		if p.Direction == "debit" {
+			amount.Neg(amount)
+		}
+		if changes[p.AccountCode] == nil {
+			changes[p.AccountCode] = new(big.Rat)
+		}
+		changes[p.AccountCode].Add(changes[p.AccountCode], amount)
+	}
+
+	codes := make([]string, 0, len(changes))
+	for code := range changes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	impact := make([]BalanceImpact, 0, len(codes))
+	for _, code := range codes {
+		before, ok := new(big.Rat).SetString(accounts[code].Balance)
+		if !ok {
+			before = new(big.Rat)
+		}
+		after := new(big.Rat).Add(before, changes[code])
+		impact = append(impact, BalanceImpact{
+			AccountCode: code,
+			Before:      before.FloatString(10),
+			Change:      changes[code].FloatString(10),
+			After:       after.FloatString(10),
+		})
+	}
+	return impact
+}
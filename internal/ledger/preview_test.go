@@ -0,0 +1,26 @@
+package ledger
+
+import "testing"
+
+func TestBalanceImpact(t *testing.T) {
+	accounts := map[string]Account{
+		"cash":    {Code: "cash", Balance: "100"},
+		"revenue": {Code: "revenue", Balance: "0"},
+	}
+	postings := []PostingInput{
+		{AccountCode: "revenue", Direction: "credit", Amount: "30"},
+		{AccountCode: "cash", Direction: "debit", Amount: "25"},
+		{AccountCode: "cash", Direction: "debit", Amount: "5"},
+	}
+
+	impact := balanceImpact(postings, accounts)
+	if len(impact) != 2 {
+		t.Fatalf("expected one entry per account, got %+v", impact)
+	}
+	if impact[0].AccountCode != "cash" || impact[0].Change != "-30.0000000000" || impact[0].After != "70.0000000000" {
+		t.Errorf("unexpected cash impact %+v", impact[0])
+	}
+	if impact[1].AccountCode != "revenue" || impact[1].After != "30.0000000000" {
+		t.Errorf("unexpected revenue impact %+v", impact[1])
+	}
+}
@@ -0,0 +1,306 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSagaNotFound = errors.New("saga not found")
+	ErrSagaBusy     = errors.New("saga is being executed by another request")
+)
+
+// A saga holds its lease while executing; a crashed executor's saga can be resumed once
+// the lease has expired.
+const sagaLease = time.Minute
+
+type SagaStep struct {
+	LedgerID   string
+	ExternalID string
+	Currency   string
+	Postings   []PostingInput
+	Metadata   map[string]any
+}
+
+type SagaCommand struct {
+	LedgerID       string // ledger that owns the saga
+	IdempotencyKey string
+	Steps          []SagaStep
+}
+
+// RunSaga persists the saga and executes it. Steps are posted in order; if one fails,
+// the steps already posted are reversed in the opposite order. Each posting uses an
+// idempotency key derived from the saga and step, so a saga interrupted at any point
+// can be resumed with ResumeSaga without posting anything twice.
+func (s *Service) RunSaga(ctx context.Context, cmd SagaCommand) (string, error) {
+	if cmd.IdempotencyKey == "" {
+		return "", invalidf("idempotency_key required")
+	}
+	if len(cmd.Steps) < 1 {
+		return "", invalidf("saga must have at least 1 step")
+	}
+	for i, step := range cmd.Steps {
+		if step.LedgerID == "" || step.Currency == "" || len(step.Postings) < 2 {
+			return "", invalidf("step %d: ledger, currency and at least 2 postings required", i+1)
+		}
+		if err := validateMetadata(step.Metadata); err != nil {
+			return "", fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var sagaID string
+	var created bool
+	err = tx.QueryRow(ctx, `
+		INSERT INTO sagas (ledger_id, idempotency_key)
+		VALUES ($1, $2)
+		ON CONFLICT (ledger_id, idempotency_key) DO UPDATE SET idempotency_key = EXCLUDED.idempotency_key
+		RETURNING id, xmax = 0
+	`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&sagaID, &created)
+	if err != nil {
+		return "", err
+	}
+
+	if created {
+		for i, step := range cmd.Steps {
+			postingsJSON, err := json.Marshal(step.Postings)
+			if err != nil {
+				return "", err
+			}
+			metadata := step.Metadata
+			if metadata == nil {
+				metadata = map[string]any{}
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO saga_steps (saga_id, sequence, ledger_id, external_id, currency, postings, metadata)
+				VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			`, sagaID, i+1, step.LedgerID, step.ExternalID, step.Currency, postingsJSON, metadata)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	// A retried request for a saga that is already executing just reports it
+	if err := s.ResumeSaga(ctx, cmd.LedgerID, sagaID); err != nil && !errors.Is(err, ErrSagaBusy) {
+		return sagaID, err
+	}
+	return sagaID, nil
+}
+
+// ResumeSaga continues a running or compensating saga. It is a no-op for sagas that
+// have finished.
+func (s *Service) ResumeSaga(ctx context.Context, ledgerID, sagaID string) error {
+	var status string
+	err := s.DB.QueryRow(ctx, `
+		UPDATE sagas
+		SET lease_expires_at = NOW() + $3::interval, updated_at = NOW()
+		WHERE id = $1 AND ledger_id = $2
+		  AND status IN ('running', 'compensating')
+		  AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+		RETURNING status
+	`, sagaID, ledgerID, sagaLease.String()).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = s.DB.QueryRow(ctx, `SELECT status FROM sagas WHERE id = $1 AND ledger_id = $2`, sagaID, ledgerID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSagaNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status == "running" || status == "compensating" {
+			return ErrSagaBusy
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	steps, err := s.loadSagaSteps(ctx, sagaID)
+	if err != nil {
+		return err
+	}
+
+	if status == "running" {
+		failure := s.postSagaSteps(ctx, sagaID, steps)
+		if failure == nil {
+			return s.finishSaga(ctx, sagaID, "completed", "")
+		}
+		// The lease is kept while compensating
+		_, err := s.DB.Exec(ctx, `
+			UPDATE sagas SET status = 'compensating', error_message = $2, updated_at = NOW() WHERE id = $1
+		`, sagaID, failure.Error())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.compensateSagaSteps(ctx, sagaID, steps); err != nil {
+		// Compensation must eventually succeed; leave the saga for an operator to resume
+		return s.finishSaga(ctx, sagaID, "failed", "compensation failed: "+err.Error())
+	}
+	return s.finishSaga(ctx, sagaID, "compensated", "")
+}
+
+type sagaStepState struct {
+	Sequence      int
+	LedgerID      string
+	ExternalID    string
+	Currency      string
+	Postings      []PostingInput
+	Metadata      map[string]any
+	Status        string
+	TransactionID string
+}
+
+func (s *Service) loadSagaSteps(ctx context.Context, sagaID string) ([]*sagaStepState, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT sequence, ledger_id, COALESCE(external_id, ''), currency, postings, metadata, status,
+			COALESCE(transaction_id::text, '')
+		FROM saga_steps
+		WHERE saga_id = $1
+		ORDER BY sequence
+	`, sagaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []*sagaStepState
+	for rows.Next() {
+		var step sagaStepState
+		var postingsJSON []byte
+		err := rows.Scan(&step.Sequence, &step.LedgerID, &step.ExternalID, &step.Currency, &postingsJSON,
+			&step.Metadata, &step.Status, &step.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(postingsJSON, &step.Postings); err != nil {
+			return nil, err
+		}
+		steps = append(steps, &step)
+	}
+	return steps, rows.Err()
+}
+
+// postSagaSteps posts the pending steps in order and returns the first failure.
+func (s *Service) postSagaSteps(ctx context.Context, sagaID string, steps []*sagaStepState) error {
+	for _, step := range steps {
+		if step.Status == "failed" {
+			return fmt.Errorf("step %d failed", step.Sequence)
+		}
+		if step.Status != "pending" {
+			continue
+		}
+
+		key := fmt.Sprintf("saga:%s:%d", sagaID, step.Sequence)
+		transactionID, err := s.PostTransaction(ctx, PostTransactionCommand{
+			LedgerID:       step.LedgerID,
+			ExternalID:     step.ExternalID,
+			IdempotencyKey: key,
+			Currency:       step.Currency,
+			Postings:       step.Postings,
+			OccurredAt:     time.Now().UTC(),
+			Metadata:       step.Metadata,
+		})
+		if err != nil {
+			// A failed commit may still have been applied; never leave such a step
+			// out of compensation
+			if existing, lookupErr := s.transactionByIdempotencyKey(ctx, step.LedgerID, key); lookupErr == nil {
+				transactionID, err = existing, nil
+			}
+		}
+		if err != nil {
+			step.Status = "failed"
+			s.DB.Exec(ctx, `
+				UPDATE saga_steps SET status = 'failed', error_message = $3 WHERE saga_id = $1 AND sequence = $2
+			`, sagaID, step.Sequence, err.Error())
+			return fmt.Errorf("step %d: %w", step.Sequence, err)
+		}
+
+		step.Status = "posted"
+		step.TransactionID = transactionID
+		_, err = s.DB.Exec(ctx, `
+			UPDATE saga_steps SET status = 'posted', transaction_id = $3 WHERE saga_id = $1 AND sequence = $2
+		`, sagaID, step.Sequence, transactionID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compensateSagaSteps reverses the posted steps, last first.
+func (s *Service) compensateSagaSteps(ctx context.Context, sagaID string, steps []*sagaStepState) error {
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.Status != "posted" {
+			continue
+		}
+
+		reversal := make([]PostingInput, len(step.Postings))
+		for j, p := range step.Postings {
+			reversal[j] = p
+			if p.Direction == "debit" {
+				reversal[j].Direction = "credit"
+			} else {
+				reversal[j].Direction = "debit"
+			}
+		}
+
+		transactionID, err := s.PostTransaction(ctx, PostTransactionCommand{
+			LedgerID:       step.LedgerID,
+			ExternalID:     step.ExternalID,
+			IdempotencyKey: fmt.Sprintf("saga:%s:%d:compensate", sagaID, step.Sequence),
+			Currency:       step.Currency,
+			Postings:       reversal,
+			OccurredAt:     time.Now().UTC(),
+			Metadata:       map[string]any{"saga_id": sagaID, "compensates": step.TransactionID},
+		})
+		if err != nil {
+			return fmt.Errorf("step %d: %w", step.Sequence, err)
+		}
+
+		step.Status = "compensated"
+		_, err = s.DB.Exec(ctx, `
+			UPDATE saga_steps SET status = 'compensated', compensation_transaction_id = $3
+			WHERE saga_id = $1 AND sequence = $2
+		`, sagaID, step.Sequence, transactionID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) finishSaga(ctx context.Context, sagaID, status, errorMessage string) error {
+	_, err := s.DB.Exec(ctx, `
+		UPDATE sagas
+		SET status = $2, error_message = COALESCE(NULLIF($3, ''), error_message), lease_expires_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, sagaID, status, errorMessage)
+	return err
+}
+
+func (s *Service) transactionByIdempotencyKey(ctx context.Context, ledgerID, key string) (string, error) {
+	var transactionID string
+	err := s.DB.QueryRow(ctx, `
+		SELECT aggregate_id FROM event_idempotency_keys WHERE ledger_id = $1 AND idempotency_key = $2
+	`, ledgerID, key).Scan(&transactionID)
+	return transactionID, err
+}
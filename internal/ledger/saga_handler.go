@@ -0,0 +1,255 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CreateSagaRequest struct {
+	IdempotencyKey string              `json:"idempotency_key"`
+	Steps          []CreateSagaStepReq `json:"steps"`
+}
+
+type CreateSagaStepReq struct {
+	Ledger     string         `json:"ledger,omitempty"` // ledger code in the same project; defaults to the caller's ledger
+	ExternalID string         `json:"external_id"`
+	Currency   string         `json:"currency"`
+	Postings   []PostingInput `json:"postings"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+}
+
+type SagaResponse struct {
+	ID             string             `json:"id"`
+	IdempotencyKey string             `json:"idempotency_key"`
+	Status         string             `json:"status"`
+	ErrorMessage   string             `json:"error_message,omitempty"`
+	CreatedAt      string             `json:"created_at"`
+	UpdatedAt      string             `json:"updated_at"`
+	Steps          []SagaStepResponse `json:"steps,omitempty"`
+}
+
+type SagaStepResponse struct {
+	Sequence                  int    `json:"sequence"`
+	LedgerID                  string `json:"ledger_id"`
+	ExternalID                string `json:"external_id,omitempty"`
+	Status                    string `json:"status"`
+	TransactionID             string `json:"transaction_id,omitempty"`
+	CompensationTransactionID string `json:"compensation_transaction_id,omitempty"`
+	ErrorMessage              string `json:"error_message,omitempty"`
+}
+
+// POST /v1/sagas - Run a multi-step (optionally cross-ledger) workflow with compensation
+func (h *Handler) CreateSaga(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateSagaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	cmd := SagaCommand{LedgerID: principal.LedgerID, IdempotencyKey: req.IdempotencyKey}
+	for i, step := range req.Steps {
+		ledgerID := principal.LedgerID
+		if step.Ledger != "" {
+			// Steps may only target ledgers of the caller's project
+			err := h.Service.DB.QueryRow(ctx, `
+				SELECT id FROM ledgers WHERE project_id = $1 AND code = $2
+			`, principal.ProjectID, step.Ledger).Scan(&ledgerID)
+			if err != nil {
+				api.Error(w, fmt.Sprintf("step %d: ledger %s not found", i+1, step.Ledger), http.StatusBadRequest)
+				return
+			}
+		}
+		cmd.Steps = append(cmd.Steps, SagaStep{
+			LedgerID:   ledgerID,
+			ExternalID: step.ExternalID,
+			Currency:   step.Currency,
+			Postings:   step.Postings,
+			Metadata:   step.Metadata,
+		})
+	}
+
+	sagaID, err := h.Service.RunSaga(ctx, cmd)
+	if err != nil && sagaID == "" {
+		writePostTransactionError(w, err)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to execute saga", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.loadSaga(ctx, principal.LedgerID, sagaID)
+	if err != nil {
+		api.Error(w, "failed to load saga", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /v1/sagas?id= - Get a saga with its steps
+func (h *Handler) GetSaga(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.loadSaga(ctx, principal.LedgerID, r.URL.Query().Get("id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "saga not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load saga", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /v1/sagas - List the most recent sagas, optionally by status
+func (h *Handler) ListSagas(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := `
+		SELECT id, idempotency_key, status, COALESCE(error_message, ''), created_at, updated_at
+		FROM sagas
+		WHERE ledger_id = $1
+	`
+	args := []interface{}{principal.LedgerID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC LIMIT 100`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query sagas", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	sagas := []SagaResponse{}
+	for rows.Next() {
+		saga, err := scanSaga(rows)
+		if err != nil {
+			api.Error(w, "failed to scan saga", http.StatusInternalServerError)
+			return
+		}
+		sagas = append(sagas, saga)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sagas)
+}
+
+// POST /v1/sagas/resume?id= - Continue a saga whose executor stopped (after its lease expired)
+func (h *Handler) ResumeSaga(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sagaID := r.URL.Query().Get("id")
+	if sagaID == "" {
+		api.Error(w, "saga id required", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.ResumeSaga(ctx, principal.LedgerID, sagaID)
+	switch {
+	case errors.Is(err, ErrSagaNotFound):
+		api.Error(w, "saga not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrSagaBusy):
+		api.WriteError(w, err, http.StatusConflict)
+		return
+	case err != nil:
+		api.Error(w, "failed to resume saga", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.loadSaga(ctx, principal.LedgerID, sagaID)
+	if err != nil {
+		api.Error(w, "failed to load saga", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) loadSaga(ctx context.Context, ledgerID, sagaID string) (SagaResponse, error) {
+	saga, err := scanSaga(h.Service.DB.QueryRow(ctx, `
+		SELECT id, idempotency_key, status, COALESCE(error_message, ''), created_at, updated_at
+		FROM sagas
+		WHERE ledger_id = $1 AND id = $2
+	`, ledgerID, sagaID))
+	if err != nil {
+		return saga, err
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT sequence, ledger_id, COALESCE(external_id, ''), status, COALESCE(transaction_id::text, ''),
+			COALESCE(compensation_transaction_id::text, ''), COALESCE(error_message, '')
+		FROM saga_steps
+		WHERE saga_id = $1
+		ORDER BY sequence
+	`, sagaID)
+	if err != nil {
+		return saga, err
+	}
+	defer rows.Close()
+
+	saga.Steps = []SagaStepResponse{}
+	for rows.Next() {
+		var step SagaStepResponse
+		err := rows.Scan(&step.Sequence, &step.LedgerID, &step.ExternalID, &step.Status, &step.TransactionID,
+			&step.CompensationTransactionID, &step.ErrorMessage)
+		if err != nil {
+			return saga, err
+		}
+		saga.Steps = append(saga.Steps, step)
+	}
+	return saga, rows.Err()
+}
+
+func scanSaga(row pgx.Row) (SagaResponse, error) {
+	var saga SagaResponse
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&saga.ID, &saga.IdempotencyKey, &saga.Status, &saga.ErrorMessage, &createdAt, &updatedAt)
+	saga.CreatedAt = createdAt.Format(time.RFC3339)
+	saga.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return saga, err
+}
@@ -0,0 +1,335 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const maxDueViews = 100
+
+var ErrViewNotFound = errors.New("saved view not found")
+
+// viewReads are the reads a saved view can run, by kind.
+var viewReads = map[string]func(*Handler, http.ResponseWriter, *http.Request){
+	"transactions":    (*Handler).ListTransactions,
+	"accounts":        (*Handler).ListAccounts,
+	"balance_summary": (*Handler).GetBalanceSummary,
+}
+
+var viewSchedules = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// SavedView is a named ledger read with its query parameters, e.g. the transactions of
+// an entity or a balance summary converted to EUR.
+type SavedView struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Kind        string            `json:"kind"`   // transactions, accounts or balance_summary
+	Params      map[string]string `json:"params"` // query parameters of the read
+	Schedule    string            `json:"schedule,omitempty"`
+	AuthorEmail string            `json:"author_email"`
+	NextRunAt   string            `json:"next_run_at,omitempty"`
+	LastRunAt   string            `json:"last_run_at,omitempty"`
+	LastResult  json.RawMessage   `json:"last_result,omitempty"` // single-view reads only
+	LastError   string            `json:"last_error,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   string            `json:"updated_at"`
+}
+
+type SaveViewRequest struct {
+	Name     string            `json:"name"`
+	Kind     string            `json:"kind"`
+	Params   map[string]string `json:"params,omitempty"`
+	Schedule string            `json:"schedule,omitempty"` // hourly, daily or weekly; empty to run on demand only
+}
+
+// Query returns the view's query parameters.
+func (v SavedView) Query() url.Values {
+	q := url.Values{}
+	for k, val := range v.Params {
+		q.Set(k, val)
+	}
+	return q
+}
+
+const savedViewSelect = `
+	SELECT id, name, kind, params, COALESCE(schedule, ''), author_email, next_run_at, last_run_at,
+		COALESCE(last_error, ''), created_at, updated_at
+	FROM saved_views
+	WHERE ledger_id = $1
+`
+
+// POST /api/ledgers/{id}/views - Save a view, replacing the one with the same name
+func (h *Handler) SaveView(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil || principal.UserEmail == "" {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SaveViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		api.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := viewReads[req.Kind]; !ok {
+		api.Error(w, "kind must be transactions, accounts or balance_summary", http.StatusBadRequest)
+		return
+	}
+	interval, scheduled := viewSchedules[req.Schedule]
+	if req.Schedule != "" && !scheduled {
+		api.Error(w, "schedule must be hourly, daily or weekly", http.StatusBadRequest)
+		return
+	}
+	if req.Params == nil {
+		req.Params = map[string]string{}
+	}
+
+	// A new or changed schedule runs first after one interval
+	var nextRunAt *time.Time
+	if scheduled {
+		next := time.Now().UTC().Add(interval)
+		nextRunAt = &next
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO saved_views (ledger_id, name, kind, params, schedule, author_email, next_run_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		ON CONFLICT (ledger_id, name) DO UPDATE
+		SET kind = EXCLUDED.kind, params = EXCLUDED.params, schedule = EXCLUDED.schedule,
+			author_email = EXCLUDED.author_email, updated_at = NOW(),
+			next_run_at = CASE
+				WHEN saved_views.schedule IS NOT DISTINCT FROM EXCLUDED.schedule THEN saved_views.next_run_at
+				ELSE EXCLUDED.next_run_at
+			END
+	`, principal.LedgerID, req.Name, req.Kind, req.Params, req.Schedule, principal.UserEmail, nextRunAt)
+	if err != nil {
+		api.Error(w, "failed to save view", http.StatusInternalServerError)
+		return
+	}
+
+	view, err := h.LoadView(ctx, principal.LedgerID, req.Name)
+	if err != nil {
+		api.Error(w, "failed to load view", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// GET /api/ledgers/{id}/views?name= - List saved views, or get one with its last scheduled result
+func (h *Handler) ListViews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if name := r.URL.Query().Get("name"); name != "" {
+		view, err := h.LoadView(ctx, principal.LedgerID, name)
+		if errors.Is(err, ErrViewNotFound) {
+			api.Error(w, "view not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			api.Error(w, "failed to load view", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(view)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, savedViewSelect+` ORDER BY name`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query views", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	views := []SavedView{}
+	for rows.Next() {
+		view, err := scanSavedView(rows)
+		if err != nil {
+			api.Error(w, "failed to scan view", http.StatusInternalServerError)
+			return
+		}
+		views = append(views, view)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+// DELETE /api/ledgers/{id}/views?name= - Delete a saved view
+func (h *Handler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tag, err := h.Service.DB.Exec(ctx, `
+		DELETE FROM saved_views WHERE ledger_id = $1 AND name = $2
+	`, principal.LedgerID, r.URL.Query().Get("name"))
+	if err != nil {
+		api.Error(w, "failed to delete view", http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		api.Error(w, "view not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoadView returns a saved view with its last scheduled result.
+func (h *Handler) LoadView(ctx context.Context, ledgerID, name string) (SavedView, error) {
+	view, err := scanSavedView(h.Service.DB.QueryRow(ctx, savedViewSelect+` AND name = $2`, ledgerID, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return SavedView{}, ErrViewNotFound
+	}
+	if err != nil {
+		return SavedView{}, err
+	}
+
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT last_result FROM saved_views WHERE id = $1
+	`, view.ID).Scan(&view.LastResult)
+	return view, err
+}
+
+// RunDueViews runs the scheduled views that are due and keeps their results. A failed
+// run keeps the previous result and records the error; either way the view is next run
+// one interval later.
+func (h *Handler) RunDueViews(ctx context.Context) error {
+	tx, err := h.Service.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, ledger_id, kind, params, schedule
+		FROM saved_views
+		WHERE schedule IS NOT NULL AND next_run_at <= NOW()
+		ORDER BY next_run_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, maxDueViews)
+	if err != nil {
+		return err
+	}
+
+	type dueView struct {
+		LedgerID string
+		SavedView
+	}
+	var due []dueView
+	for rows.Next() {
+		var v dueView
+		if err := rows.Scan(&v.ID, &v.LedgerID, &v.Kind, &v.Params, &v.Schedule); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, v := range due {
+		result, runErr := h.runView(ctx, v.LedgerID, v.SavedView)
+		errText := ""
+		if runErr != nil {
+			errText = runErr.Error()
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE saved_views
+			SET last_run_at = NOW(), next_run_at = NOW() + $2::interval,
+				last_result = COALESCE($3, last_result), last_error = NULLIF($4, '')
+			WHERE id = $1
+		`, v.ID, viewSchedules[v.Schedule], result, errText)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// runView runs a view's read in process, as its ledger, and returns the JSON response.
+func (h *Handler) runView(ctx context.Context, ledgerID string, view SavedView) (json.RawMessage, error) {
+	read, ok := viewReads[view.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown view kind %q", view.Kind)
+	}
+
+	ctx = auth.WithPrincipal(ctx, auth.Principal{LedgerID: ledgerID})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/?"+view.Query().Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &viewRecorder{header: http.Header{}, status: http.StatusOK}
+	read(h, rec, req)
+	if rec.status != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", rec.status, bytes.TrimSpace(rec.body.Bytes()))
+	}
+	return rec.body.Bytes(), nil
+}
+
+// viewRecorder captures a read's response.
+type viewRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *viewRecorder) Header() http.Header         { return r.header }
+func (r *viewRecorder) WriteHeader(status int)      { r.status = status }
+func (r *viewRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+
+func scanSavedView(row pgx.Row) (SavedView, error) {
+	var v SavedView
+	var nextRunAt, lastRunAt *time.Time
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&v.ID, &v.Name, &v.Kind, &v.Params, &v.Schedule, &v.AuthorEmail, &nextRunAt, &lastRunAt,
+		&v.LastError, &createdAt, &updatedAt)
+	if nextRunAt != nil {
+		v.NextRunAt = nextRunAt.Format(time.RFC3339)
+	}
+	if lastRunAt != nil {
+		v.LastRunAt = lastRunAt.Format(time.RFC3339)
+	}
+	v.CreatedAt = createdAt.Format(time.RFC3339)
+	v.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return v, err
+}
@@ -0,0 +1,532 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/calendar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CreateScheduleRequest struct {
+	Reference          string `json:"reference"`
+	Principal          string `json:"principal"`
+	Currency           string `json:"currency"`
+	SourceAccount      string `json:"source_account"`
+	DestinationAccount string `json:"destination_account"`
+	Installments       int    `json:"installments"`
+	Frequency          string `json:"frequency,omitempty"`
+	StartDate          string `json:"start_date"` // YYYY-MM-DD, first due date
+
+	// Recurrence is an RRULE (see calendar.Rule) used instead of frequency, e.g.
+	// FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1 for the last weekday of each month
+	Recurrence string `json:"recurrence,omitempty"`
+	// HolidayPolicy moves due dates off the ledger's weekends and holidays: none
+	// (default), following, preceding, modified_following or skip
+	HolidayPolicy string `json:"holiday_policy,omitempty"`
+}
+
+type PreviewScheduleRequest struct {
+	Frequency     string `json:"frequency,omitempty"`
+	Recurrence    string `json:"recurrence,omitempty"`
+	StartDate     string `json:"start_date,omitempty"` // defaults to today
+	HolidayPolicy string `json:"holiday_policy,omitempty"`
+	Count         int    `json:"count,omitempty"` // defaults to 10, at most 100
+}
+
+type PreviewScheduleResponse struct {
+	Occurrences []string `json:"occurrences"`
+}
+
+const (
+	maxInstallments       = 1200
+	defaultPreviewCount   = 10
+	maxPreviewOccurrences = 100
+)
+
+type ScheduleResponse struct {
+	ID                 string                `json:"id"`
+	Reference          string                `json:"reference"`
+	Principal          string                `json:"principal"`
+	Currency           string                `json:"currency"`
+	SourceAccount      string                `json:"source_account"`
+	DestinationAccount string                `json:"destination_account"`
+	InstallmentCount   int                   `json:"installment_count"`
+	Frequency          string                `json:"frequency"`
+	Recurrence         string                `json:"recurrence,omitempty"`
+	HolidayPolicy      string                `json:"holiday_policy"`
+	StartDate          string                `json:"start_date"`
+	Status             string                `json:"status"`
+	PaidAmount         string                `json:"paid_amount"`
+	OutstandingAmount  string                `json:"outstanding_amount"`
+	OverdueCount       int                   `json:"overdue_count"`
+	Overdue            bool                  `json:"overdue"`
+	NextDueDate        string                `json:"next_due_date,omitempty"`
+	CreatedAt          string                `json:"created_at"`
+	Installments       []InstallmentResponse `json:"installments,omitempty"`
+}
+
+type InstallmentResponse struct {
+	Sequence      int    `json:"sequence"`
+	DueDate       string `json:"due_date"`
+	Amount        string `json:"amount"`
+	Status        string `json:"status"`
+	Overdue       bool   `json:"overdue"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error,omitempty"`
+	PostedAt      string `json:"posted_at,omitempty"`
+}
+
+const scheduleSelect = `
+	SELECT s.id, s.reference, s.principal::text, s.currency, s.source_account, s.destination_account,
+		s.installment_count, s.frequency, COALESCE(s.recurrence, ''), s.holiday_policy, s.start_date::text, s.status, s.created_at,
+		COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'posted'), 0)::text,
+		(s.principal - COALESCE(SUM(i.amount) FILTER (WHERE i.status = 'posted'), 0))::text,
+		COUNT(i.id) FILTER (WHERE i.status = 'pending' AND i.due_date < CURRENT_DATE),
+		(MIN(i.due_date) FILTER (WHERE i.status = 'pending'))::text
+	FROM schedules s
+	LEFT JOIN schedule_installments i ON i.schedule_id = s.id
+	WHERE s.ledger_id = $1
+`
+
+// POST /v1/schedules - Create a repayment schedule and its installments
+func (h *Handler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.Reference == "" || req.Currency == "" || req.SourceAccount == "" || req.DestinationAccount == "" {
+		api.Error(w, "reference, currency, source_account and destination_account required", http.StatusBadRequest)
+		return
+	}
+	amount, ok := new(big.Rat).SetString(req.Principal)
+	if !ok || amount.Sign() <= 0 {
+		api.Error(w, "invalid principal", http.StatusBadRequest)
+		return
+	}
+	if req.Installments <= 0 || req.Installments > maxInstallments {
+		api.Error(w, fmt.Sprintf("installments must be between 1 and %d", maxInstallments), http.StatusBadRequest)
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		api.Error(w, "start_date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	plan, err := parseRecurrence(req.Frequency, req.Recurrence, req.HolidayPolicy)
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	cal, err := h.Service.Calendar(ctx, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+	dueDates := plan.dates(startDate, req.Installments, cal)
+	if len(dueDates) < req.Installments {
+		api.Error(w, fmt.Sprintf("recurrence yields only %d due dates", len(dueDates)), http.StatusBadRequest)
+		return
+	}
+
+	// Installments are split at the currency's precision, cents when it is not registered
+	currency, registered, err := h.Service.currency(ctx, principal.LedgerID, req.Currency)
+	if err != nil {
+		api.Error(w, "failed to load currency", http.StatusInternalServerError)
+		return
+	}
+	if !registered {
+		currency = Currency{Code: req.Currency, Precision: 2, Rounding: RoundHalfUp}
+	} else if !fitsPrecision(amount, currency.Precision) {
+		api.Error(w, fmt.Sprintf("principal %s exceeds %s precision of %d decimal places", req.Principal, req.Currency, currency.Precision), http.StatusBadRequest)
+		return
+	}
+
+	installments := buildInstallments(amount, dueDates, currency)
+	for _, inst := range installments {
+		if inst.amount.Sign() <= 0 {
+			api.Error(w, "principal too small for the number of installments", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Both accounts must exist in this ledger
+	var found int
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT COUNT(*) FROM accounts WHERE ledger_id = $1 AND code = ANY($2)
+	`, principal.LedgerID, []string{req.SourceAccount, req.DestinationAccount}).Scan(&found)
+	if err != nil || found != 2 {
+		api.Error(w, "source or destination account not found", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.Service.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var scheduleID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO schedules (ledger_id, reference, principal, currency, source_account, destination_account,
+			installment_count, frequency, start_date, recurrence, holiday_policy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11)
+		RETURNING id
+	`, principal.LedgerID, req.Reference, amount.FloatString(10), req.Currency, req.SourceAccount,
+		req.DestinationAccount, req.Installments, plan.frequency, startDate, req.Recurrence, plan.policy).Scan(&scheduleID)
+	if err != nil {
+		api.Error(w, "failed to create schedule (duplicate reference?)", http.StatusConflict)
+		return
+	}
+
+	for i, inst := range installments {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO schedule_installments (schedule_id, sequence, due_date, amount)
+			VALUES ($1, $2, $3, $4)
+		`, scheduleID, i+1, inst.dueDate, inst.amount.FloatString(10))
+		if err != nil {
+			api.Error(w, "failed to create installments", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit schedule", http.StatusInternalServerError)
+		return
+	}
+
+	schedule, err := h.loadSchedule(r, principal.LedgerID, scheduleID)
+	if err != nil {
+		api.Error(w, "failed to load schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// POST /v1/schedules/preview - The next due dates of a frequency or recurrence
+//
+// Dates are planned as for POST /v1/schedules, against the ledger's calendar, without
+// creating anything.
+func (h *Handler) PreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PreviewScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Count == 0 {
+		req.Count = defaultPreviewCount
+	}
+	if req.Count < 1 || req.Count > maxPreviewOccurrences {
+		api.Error(w, fmt.Sprintf("count must be between 1 and %d", maxPreviewOccurrences), http.StatusBadRequest)
+		return
+	}
+	startDate := calendar.Date(time.Now().UTC())
+	if req.StartDate != "" {
+		if startDate, err = calendar.ParseDate(req.StartDate); err != nil {
+			api.Error(w, "start_date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	plan, err := parseRecurrence(req.Frequency, req.Recurrence, req.HolidayPolicy)
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	cal, err := h.Service.Calendar(ctx, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	resp := PreviewScheduleResponse{Occurrences: []string{}}
+	for _, d := range plan.dates(startDate, req.Count, cal) {
+		resp.Occurrences = append(resp.Occurrences, calendar.FormatDate(d))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /v1/schedules - List schedules (overdue=true for overdue only)
+func (h *Handler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := scheduleSelect + ` GROUP BY s.id`
+	if r.URL.Query().Get("overdue") == "true" {
+		query += ` HAVING COUNT(i.id) FILTER (WHERE i.status = 'pending' AND i.due_date < CURRENT_DATE) > 0`
+	}
+	query += ` ORDER BY s.created_at DESC`
+
+	rows, err := h.Service.DB.Query(ctx, query, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query schedules", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	schedules := []ScheduleResponse{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			api.Error(w, "failed to scan schedule", http.StatusInternalServerError)
+			return
+		}
+		schedules = append(schedules, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// GET /v1/schedules/:id - Get a schedule with its installments
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	scheduleID := r.URL.Query().Get("id")
+	if scheduleID == "" {
+		api.Error(w, "schedule id required", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := h.loadSchedule(r, principal.LedgerID, scheduleID)
+	if err != nil {
+		api.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// POST /v1/schedules/:id/cancel - Cancel a schedule and its pending installments
+func (h *Handler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	scheduleID := r.URL.Query().Get("id")
+	if scheduleID == "" {
+		api.Error(w, "schedule id required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.Service.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE schedules SET status = 'cancelled'
+		WHERE id = $1 AND ledger_id = $2 AND status = 'active'
+	`, scheduleID, principal.LedgerID)
+	if err != nil || tag.RowsAffected() == 0 {
+		api.Error(w, "active schedule not found", http.StatusNotFound)
+		return
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE schedule_installments SET status = 'cancelled'
+		WHERE schedule_id = $1 AND status = 'pending'
+	`, scheduleID)
+	if err != nil {
+		api.Error(w, "failed to cancel installments", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to cancel schedule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) loadSchedule(r *http.Request, ledgerID, scheduleID string) (ScheduleResponse, error) {
+	ctx := r.Context()
+
+	row := h.Service.DB.QueryRow(ctx, scheduleSelect+` AND s.id = $2 GROUP BY s.id`, ledgerID, scheduleID)
+	schedule, err := scanSchedule(row)
+	if err != nil {
+		return ScheduleResponse{}, err
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT sequence, due_date::text, amount::text, status, due_date < CURRENT_DATE AND status = 'pending',
+			COALESCE(transaction_id::text, ''), attempts, COALESCE(last_error, ''), posted_at
+		FROM schedule_installments
+		WHERE schedule_id = $1
+		ORDER BY sequence
+	`, scheduleID)
+	if err != nil {
+		return ScheduleResponse{}, err
+	}
+	defer rows.Close()
+
+	schedule.Installments = []InstallmentResponse{}
+	for rows.Next() {
+		var inst InstallmentResponse
+		var postedAt *time.Time
+		err = rows.Scan(&inst.Sequence, &inst.DueDate, &inst.Amount, &inst.Status, &inst.Overdue,
+			&inst.TransactionID, &inst.Attempts, &inst.LastError, &postedAt)
+		if err != nil {
+			return ScheduleResponse{}, err
+		}
+		if postedAt != nil {
+			inst.PostedAt = postedAt.Format(time.RFC3339)
+		}
+		schedule.Installments = append(schedule.Installments, inst)
+	}
+
+	return schedule, rows.Err()
+}
+
+func scanSchedule(row pgx.Row) (ScheduleResponse, error) {
+	var s ScheduleResponse
+	var createdAt time.Time
+	var nextDue *string
+	err := row.Scan(&s.ID, &s.Reference, &s.Principal, &s.Currency, &s.SourceAccount, &s.DestinationAccount,
+		&s.InstallmentCount, &s.Frequency, &s.Recurrence, &s.HolidayPolicy, &s.StartDate, &s.Status, &createdAt,
+		&s.PaidAmount, &s.OutstandingAmount, &s.OverdueCount, &nextDue)
+	if err != nil {
+		return s, err
+	}
+	s.CreatedAt = createdAt.Format(time.RFC3339)
+	s.Overdue = s.OverdueCount > 0
+	if nextDue != nil {
+		s.NextDueDate = *nextDue
+	}
+	return s, nil
+}
+
+// recurrencePlan is how a schedule's due dates are planned: a recurrence rule, or else
+// a plain frequency, with a holiday policy either way.
+type recurrencePlan struct {
+	frequency string
+	rule      *calendar.Rule
+	policy    calendar.Policy
+}
+
+// parseRecurrence reads the frequency or, when set, the recurrence replacing it.
+func parseRecurrence(frequency, recurrence, holidayPolicy string) (recurrencePlan, error) {
+	var plan recurrencePlan
+	var err error
+	if plan.policy, err = calendar.ParsePolicy(holidayPolicy); err != nil {
+		return plan, err
+	}
+	if recurrence != "" {
+		rule, err := calendar.ParseRule(recurrence)
+		if err != nil {
+			return plan, fmt.Errorf("invalid recurrence: %w", err)
+		}
+		plan.frequency, plan.rule = rule.Freq, &rule
+		return plan, nil
+	}
+	if frequency != "daily" && frequency != "weekly" && frequency != "monthly" {
+		return plan, errors.New("frequency must be daily, weekly or monthly, or a recurrence given")
+	}
+	plan.frequency = frequency
+	return plan, nil
+}
+
+// dates returns up to count due dates from start, adjusted to cal.
+func (p recurrencePlan) dates(start time.Time, count int, cal calendar.Calendar) []time.Time {
+	if p.rule != nil {
+		return cal.Dates(p.rule.All(start), count, p.policy)
+	}
+	// A plain frequency steps from the start date; monthly dates past the end of a
+	// shorter month roll over into the next, as they always have
+	return cal.Dates(func(yield func(time.Time) bool) {
+		for i := 0; ; i++ {
+			var due time.Time
+			switch p.frequency {
+			case "daily":
+				due = start.AddDate(0, 0, i)
+			case "weekly":
+				due = start.AddDate(0, 0, 7*i)
+			default:
+				due = start.AddDate(0, i, 0)
+			}
+			if !yield(due) {
+				return
+			}
+		}
+	}, count, p.policy)
+}
+
+type plannedInstallment struct {
+	dueDate time.Time
+	amount  *big.Rat
+}
+
+// buildInstallments splits the principal into equal installments rounded to the
+// currency's precision with its rounding mode, one per due date, putting any remainder
+// on the final installment so the total matches exactly.
+func buildInstallments(principal *big.Rat, dueDates []time.Time, currency Currency) []plannedInstallment {
+	count := len(dueDates)
+	each := roundMode(new(big.Rat).Quo(principal, big.NewRat(int64(count), 1)), currency.Precision, currency.Rounding)
+	remaining := new(big.Rat).Set(principal)
+
+	installments := make([]plannedInstallment, 0, count)
+	for i, due := range dueDates {
+		amount := each
+		if i == count-1 {
+			amount = remaining
+		}
+		remaining = new(big.Rat).Sub(remaining, amount)
+		installments = append(installments, plannedInstallment{dueDate: due, amount: amount})
+	}
+
+	return installments
+}
+
+// InstallmentIdempotencyKey makes installment postings exactly-once across job retries.
+func InstallmentIdempotencyKey(scheduleID string, sequence int) string {
+	return fmt.Sprintf("schedule:%s:%d", scheduleID, sequence)
+}
@@ -0,0 +1,37 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestBuildInstallments(t *testing.T) {
+	dates := make([]time.Time, 3)
+	cases := []struct {
+		principal string
+		currency  Currency
+		want      []string
+	}{
+		{"1000", Currency{Code: "JPY", Precision: 0, Rounding: RoundHalfUp}, []string{"333", "333", "334"}},
+		{"100", Currency{Code: "USD", Precision: 2, Rounding: RoundHalfUp}, []string{"33.33", "33.33", "33.34"}},
+		{"1", Currency{Code: "BTC", Precision: 8, Rounding: RoundDown}, []string{"0.33333333", "0.33333333", "0.33333334"}},
+		// Unregistered currencies split at cents; the remainder is kept in full
+		{"100.005", Currency{Code: "XYZ", Precision: 2, Rounding: RoundHalfUp}, []string{"33.34", "33.34", "33.325"}},
+	}
+	for _, c := range cases {
+		principal, _ := new(big.Rat).SetString(c.principal)
+		installments := buildInstallments(principal, dates, c.currency)
+		total := new(big.Rat)
+		for i, inst := range installments {
+			want, _ := new(big.Rat).SetString(c.want[i])
+			if inst.amount.Cmp(want) != 0 {
+				t.Errorf("%s %s: installment %d is %s, want %s", c.principal, c.currency.Code, i+1, inst.amount.FloatString(10), c.want[i])
+			}
+			total.Add(total, inst.amount)
+		}
+		if total.Cmp(principal) != 0 {
+			t.Errorf("%s %s: installments total %s", c.principal, c.currency.Code, total.FloatString(10))
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/screening"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrReviewNotFound   = errors.New("screening review not found")
+	ErrReviewNotPending = errors.New("screening review is no longer pending")
+)
+
+// ScreeningError rejects a transaction denied by screening.
+type ScreeningError struct {
+	Reason string
+}
+
+func (e *ScreeningError) Error() string {
+	return "transaction denied by screening: " + e.Reason
+}
+
+// PendingReviewError reports a transaction held by screening. It is posted with its
+// original idempotency key once the review is approved, so retrying the request returns
+// the same review until then and the transaction afterwards.
+type PendingReviewError struct {
+	ReviewID string
+	Reason   string
+}
+
+func (e *PendingReviewError) Error() string {
+	return fmt.Sprintf("transaction held for review %s: %s", e.ReviewID, e.Reason)
+}
+
+// screenTx runs the ledger's screening rules and the service's Screener on a validated
+// command. A review outcome records the command for review within tx.
+func (s *Service) screenTx(ctx context.Context, tx pgx.Tx, cmd PostTransactionCommand) error {
+	var reviewID, status, reason string
+	err := tx.QueryRow(ctx, `
+		SELECT id, status, reason FROM screening_reviews WHERE ledger_id = $1 AND idempotency_key = $2
+	`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&reviewID, &status, &reason)
+	if err == nil {
+		if status == "rejected" {
+			return &ScreeningError{Reason: "rejected in review " + reviewID}
+		}
+		// Approved reviews are posted under this key, which the idempotency check catches
+		return &PendingReviewError{ReviewID: reviewID, Reason: reason}
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	rules, err := loadScreeningRules(ctx, tx, cmd.LedgerID)
+	if err != nil {
+		return err
+	}
+
+	req := screeningRequest(cmd)
+	decision, err := rules.Screen(ctx, req)
+	if err != nil {
+		return err
+	}
+	cmd.explain.screened("rules", decision)
+	if s.Screener != nil && decision.Outcome != screening.Deny {
+		plugged, err := s.Screener.Screen(ctx, req)
+		if err != nil {
+			return fmt.Errorf("screening: %w", err)
+		}
+		cmd.explain.screened("screener", plugged)
+		decision = screening.Combine(decision, plugged)
+	}
+
+	switch decision.Outcome {
+	case screening.Deny:
+		return &ScreeningError{Reason: decision.Reason}
+	case screening.Review:
+		commandJSON, err := json.Marshal(cmd)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO screening_reviews (ledger_id, idempotency_key, command, reason)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, cmd.LedgerID, cmd.IdempotencyKey, commandJSON, decision.Reason).Scan(&reviewID)
+		if err != nil {
+			return err
+		}
+		err = s.appendEvent(ctx, tx, cmd.LedgerID, "screening_review", reviewID, "TransactionHeldForReview", &events.TransactionHeldForReview{
+			ReviewID:       reviewID,
+			IdempotencyKey: cmd.IdempotencyKey,
+			ExternalID:     cmd.ExternalID,
+			Reason:         decision.Reason,
+		})
+		if err != nil {
+			return err
+		}
+		return &PendingReviewError{ReviewID: reviewID, Reason: decision.Reason}
+	}
+	return nil
+}
+
+// ApproveReview posts a held transaction without screening it again. Validation runs
+// again, so an approval fails if balances changed such that the transaction no longer
+// passes; the review then stays pending.
+func (s *Service) ApproveReview(ctx context.Context, ledgerID, reviewID, note string) (string, error) {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	cmd, err := lockPendingReview(ctx, tx, ledgerID, reviewID)
+	if err != nil {
+		return "", err
+	}
+	cmd.skipScreening = true
+	if cmd.Script != "" {
+		// Stored after compilation; compile again rather than post both
+		cmd.Postings = nil
+	} else {
+		// Stored after the pre-validate hooks; running them again would enrich twice
+		cmd.preValidated = true
+	}
+
+	transactionID, posted, err := s.postTransactionTx(ctx, tx, cmd)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE screening_reviews
+		SET status = 'approved', transaction_id = $2, review_note = NULLIF($3, ''), reviewed_at = NOW()
+		WHERE id = $1
+	`, reviewID, transactionID, note)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	s.postCommit(ctx, posted)
+	return transactionID, nil
+}
+
+// RejectReview discards a held transaction. Requests retried with its idempotency key
+// are denied.
+func (s *Service) RejectReview(ctx context.Context, ledgerID, reviewID, note string) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockPendingReview(ctx, tx, ledgerID, reviewID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE screening_reviews
+		SET status = 'rejected', review_note = NULLIF($2, ''), reviewed_at = NOW()
+		WHERE id = $1
+	`, reviewID, note)
+	if err != nil {
+		return err
+	}
+
+	err = s.appendEvent(ctx, tx, ledgerID, "screening_review", reviewID, "TransactionReviewRejected", &events.TransactionReviewRejected{
+		ReviewID: reviewID,
+		Note:     note,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func lockPendingReview(ctx context.Context, tx pgx.Tx, ledgerID, reviewID string) (PostTransactionCommand, error) {
+	var cmd PostTransactionCommand
+	var status string
+	err := tx.QueryRow(ctx, `
+		SELECT command, status FROM screening_reviews WHERE id::text = $1 AND ledger_id = $2 FOR UPDATE
+	`, reviewID, ledgerID).Scan(&cmd, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return cmd, ErrReviewNotFound
+	}
+	if err != nil {
+		return cmd, err
+	}
+	if status != "pending" {
+		return cmd, ErrReviewNotPending
+	}
+	return cmd, nil
+}
+
+// loadScreeningRules returns the ledger's screening rules; a ledger without any allows
+// everything.
+func loadScreeningRules(ctx context.Context, tx pgx.Tx, ledgerID string) (screening.Rules, error) {
+	var rules screening.Rules
+	err := tx.QueryRow(ctx, `
+		SELECT rules FROM screening_rules WHERE ledger_id = $1
+	`, ledgerID).Scan(&rules)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return rules, err
+	}
+	return rules, nil
+}
+
+func screeningRequest(cmd PostTransactionCommand) screening.Request {
+	req := screening.Request{
+		LedgerID:   cmd.LedgerID,
+		ExternalID: cmd.ExternalID,
+		Currency:   cmd.Currency,
+		EntityCode: cmd.EntityCode,
+		Metadata:   cmd.Metadata,
+	}
+	for _, p := range cmd.Postings {
+		req.Postings = append(req.Postings, screening.Posting{
+			Account:   p.AccountCode,
+			Direction: p.Direction,
+			Amount:    p.Amount,
+			Currency:  postingCurrency(cmd, p),
+		})
+	}
+	return req
+}
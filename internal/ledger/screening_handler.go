@@ -0,0 +1,220 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/screening"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ScreeningReviewResponse struct {
+	ID             string         `json:"id"`
+	IdempotencyKey string         `json:"idempotency_key"`
+	ExternalID     string         `json:"external_id,omitempty"`
+	Currency       string         `json:"currency"`
+	Postings       []PostingInput `json:"postings"`
+	Reason         string         `json:"reason"`
+	Status         string         `json:"status"`
+	Note           string         `json:"note,omitempty"`
+	TransactionID  string         `json:"transaction_id,omitempty"`
+	CreatedAt      string         `json:"created_at"`
+	ReviewedAt     string         `json:"reviewed_at,omitempty"`
+}
+
+type ReviewDecisionRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+// GET /v1/screening/rules - Get the ledger's screening rules
+func (h *Handler) GetScreeningRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var rules screening.Rules
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT rules FROM screening_rules WHERE ledger_id = $1
+	`, principal.LedgerID).Scan(&rules)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "failed to load screening rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// PUT /v1/screening/rules - Replace the ledger's screening rules
+func (h *Handler) SetScreeningRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var rules screening.Rules
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if err := rules.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO screening_rules (ledger_id, rules)
+		VALUES ($1, $2)
+		ON CONFLICT (ledger_id) DO UPDATE SET rules = EXCLUDED.rules, updated_at = NOW()
+	`, principal.LedgerID, rules)
+	if err != nil {
+		api.Error(w, "failed to save screening rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GET /v1/screening/reviews?status= - List transactions held by screening, pending first
+func (h *Handler) ListScreeningReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := `
+		SELECT id, idempotency_key, command, reason, status, COALESCE(review_note, ''),
+			COALESCE(transaction_id::text, ''), created_at, reviewed_at
+		FROM screening_reviews
+		WHERE ledger_id = $1
+	`
+	args := []interface{}{principal.LedgerID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(` AND status = $%d`, len(args))
+	}
+	query += ` ORDER BY status = 'pending' DESC, created_at DESC LIMIT 500`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query screening reviews", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	reviews := []ScreeningReviewResponse{}
+	for rows.Next() {
+		var rv ScreeningReviewResponse
+		var cmd PostTransactionCommand
+		var createdAt time.Time
+		var reviewedAt *time.Time
+		err := rows.Scan(&rv.ID, &rv.IdempotencyKey, &cmd, &rv.Reason, &rv.Status, &rv.Note,
+			&rv.TransactionID, &createdAt, &reviewedAt)
+		if err != nil {
+			api.Error(w, "failed to scan screening review", http.StatusInternalServerError)
+			return
+		}
+		rv.ExternalID = cmd.ExternalID
+		rv.Currency = cmd.Currency
+		rv.Postings = cmd.Postings
+		rv.CreatedAt = createdAt.Format(time.RFC3339)
+		if reviewedAt != nil {
+			rv.ReviewedAt = reviewedAt.Format(time.RFC3339)
+		}
+		reviews = append(reviews, rv)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// POST /v1/screening/reviews/approve?id= - Post a held transaction
+func (h *Handler) ApproveScreeningReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReviewDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	transactionID, err := h.Service.ApproveReview(ctx, principal.LedgerID, r.URL.Query().Get("id"), req.Note)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PostTransactionResponse{
+		TransactionID: transactionID,
+		Status:        "accepted",
+	})
+}
+
+// POST /v1/screening/reviews/reject?id= - Discard a held transaction
+func (h *Handler) RejectScreeningReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReviewDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	reviewID := r.URL.Query().Get("id")
+	if err := h.Service.RejectReview(ctx, principal.LedgerID, reviewID, req.Note); err != nil {
+		writeReviewError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":     reviewID,
+		"status": "rejected",
+	})
+}
+
+func writeReviewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrReviewNotFound):
+		api.WriteError(w, err, http.StatusNotFound)
+	case errors.Is(err, ErrReviewNotPending):
+		api.WriteError(w, err, http.StatusConflict)
+	default:
+		writePostTransactionError(w, err)
+	}
+}
@@ -1,16 +1,22 @@
 package ledger
 
 import (
+	"Go_FormanceLegder/internal/calendar"
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/hooks"
+	"Go_FormanceLegder/internal/screening"
+	"Go_FormanceLegder/internal/script"
 	"Go_FormanceLegder/internal/webhook"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/riverqueue/river"
 )
@@ -18,6 +24,17 @@ import (
 type Service struct {
 	DB          *pgxpool.Pool
 	RiverClient *river.Client[pgx.Tx]
+
+	// Conversion defaults (see PostConversion)
+	FXConversionAccount string
+	FXRoundingAccount   string
+	Rates               RateSource
+
+	// Screener, when set, screens transactions after the ledger's own screening rules
+	Screener screening.Screener
+
+	// Hooks, when set, run around the posting of every transaction (see package hooks)
+	Hooks *hooks.Registry
 }
 
 func NewService(db *pgxpool.Pool, riverClient *river.Client[pgx.Tx]) *Service {
@@ -34,48 +51,116 @@ func (s *Service) PostTransaction(ctx context.Context, cmd PostTransactionComman
 	}
 	defer tx.Rollback(ctx)
 
+	transactionID, posted, err := s.postTransactionTx(ctx, tx, cmd)
+	var pending *PendingReviewError
+	if errors.As(err, &pending) {
+		// Nothing is posted, but the review is kept
+		if err := tx.Commit(ctx); err != nil {
+			return "", err
+		}
+		return "", pending
+	}
+	if isUniqueViolation(err) && cmd.IdempotencyKey != "" {
+		// A concurrent request with the same key posted first: answer like a retry
+		// instead of surfacing the database error
+		tx.Rollback(ctx)
+		var existingID string
+		if s.DB.QueryRow(ctx, `
+			SELECT aggregate_id FROM event_idempotency_keys WHERE ledger_id = $1 AND idempotency_key = $2
+		`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&existingID) == nil {
+			return existingID, nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	s.postCommit(ctx, posted)
+
+	return transactionID, nil
+}
+
+// postCommit runs the post-commit hooks on a transaction postTransactionTx posted, once
+// committed.
+func (s *Service) postCommit(ctx context.Context, posted *hooks.Transaction) {
+	if posted != nil {
+		s.Hooks.PostCommit(ctx, *posted)
+	}
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation, which is how
+// a write losing an idempotency race fails.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// postTransactionTx validates the command and appends its event within tx, so callers
+// can record other events atomically with the transaction. It returns the transaction
+// posted, nil when the idempotency key was used already, for callers to pass to
+// postCommit once tx is committed.
+func (s *Service) postTransactionTx(ctx context.Context, tx pgx.Tx, cmd PostTransactionCommand) (string, *hooks.Transaction, error) {
 	// Check idempotency
 	var existingID string
-	err = tx.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		SELECT aggregate_id
-		FROM events
+		FROM event_idempotency_keys
 		WHERE ledger_id = $1
 		  AND idempotency_key = $2
 	`, cmd.LedgerID, cmd.IdempotencyKey).Scan(&existingID)
 	if err == nil {
 		// Already processed
-		return existingID, nil
+		return existingID, nil, nil
 	}
 	if !errors.Is(err, pgx.ErrNoRows) {
-		return "", err
+		return "", nil, err
 	}
 
-	// Load and lock accounts
-	accounts, err := s.loadAndLockAccounts(ctx, tx, cmd.LedgerID, cmd.Postings)
-	if err != nil {
-		return "", err
+	if _, err := s.validateTransactionTx(ctx, tx, &cmd); err != nil {
+		return "", nil, err
 	}
 
-	// Validate double-entry
-	if err := validateDoubleEntry(cmd, accounts); err != nil {
-		return "", err
+	if !cmd.skipScreening {
+		if err := s.screenTx(ctx, tx, cmd); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if err := s.Hooks.PreCommit(ctx, hookTransaction(cmd, "")); err != nil {
+		return "", nil, err
 	}
 
 	// Append event
 	eventID := uuid.NewString()
 	transactionID := uuid.NewString()
 
-	payload := map[string]any{
-		"transaction_id": transactionID,
-		"external_id":    cmd.ExternalID,
-		"currency":       cmd.Currency,
-		"occurred_at":    cmd.OccurredAt.UTC().Format(time.RFC3339Nano),
-		"postings":       cmd.Postings,
+	payload := &events.TransactionPosted{
+		TransactionID: transactionID,
+		ExternalID:    cmd.ExternalID,
+		Description:   cmd.Description,
+		Currency:      cmd.Currency,
+		OccurredAt:    cmd.OccurredAt.UTC(),
+		ValueDate:     calendar.FormatDate(cmd.valueDate()),
+		Postings:      make([]events.Posting, len(cmd.Postings)),
+		Metadata:      cmd.Metadata,
+		Script:        cmd.Script,
+		EntityCode:    cmd.EntityCode,
+	}
+	for i, p := range cmd.Postings {
+		// Postings carry their currency explicitly, as of schema version 2
+		if p.Currency == "" {
+			p.Currency = cmd.Currency
+		}
+		payload.Postings[i] = events.Posting{AccountCode: p.AccountCode, Direction: p.Direction,
+			Amount: p.Amount, Currency: p.Currency, TaxCode: p.TaxCode, Description: p.Description}
 	}
 
-	payloadJSON, err := json.Marshal(payload)
+	payloadJSON, err := events.Marshal("TransactionPosted", payload)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	_, err = tx.Exec(ctx, `
@@ -91,7 +176,7 @@ func (s *Service) PostTransaction(ctx context.Context, cmd PostTransactionComman
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`, eventID, cmd.LedgerID, "ledger", transactionID, "TransactionPosted", payloadJSON, cmd.OccurredAt, cmd.IdempotencyKey)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// Enqueue webhook job atomically
@@ -100,14 +185,100 @@ func (s *Service) PostTransaction(ctx context.Context, cmd PostTransactionComman
 		LedgerID: cmd.LedgerID,
 	}, nil)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return "", err
+	posted := hookTransaction(cmd, transactionID)
+	return transactionID, &posted, nil
+}
+
+// validateTransactionTx compiles the command's script and runs the pre-validate hooks,
+// then loads and locks its accounts and runs every check a posting must pass. It writes
+// nothing.
+func (s *Service) validateTransactionTx(ctx context.Context, tx pgx.Tx, cmd *PostTransactionCommand) (map[string]Account, error) {
+	if cmd.Script != "" {
+		if err := cmd.explain.check("script", compileScript(cmd)); err != nil {
+			return nil, err
+		}
 	}
 
-	return transactionID, nil
+	if s.Hooks != nil && !cmd.preValidated {
+		if err := cmd.explain.check("pre_validate_hooks", s.preValidate(ctx, cmd)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cmd.explain.check("descriptions", validateDescriptions(*cmd)); err != nil {
+		return nil, err
+	}
+	if err := cmd.explain.check("value_date", validateValueDate(*cmd)); err != nil {
+		return nil, err
+	}
+
+	// Load and lock accounts, including those only asserted on
+	locked := append([]PostingInput{}, cmd.Postings...)
+	for _, a := range cmd.Assertions {
+		locked = append(locked, PostingInput{AccountCode: a.Account})
+	}
+	accounts, err := s.loadAndLockAccounts(ctx, tx, cmd.LedgerID, locked)
+	if err := cmd.explain.check("accounts", err); err != nil {
+		return nil, err
+	}
+	if h := cmd.capturing; h != nil {
+		if acc, ok := accounts[h.AccountCode]; ok {
+			amount, _ := new(big.Rat).SetString(h.Amount)
+			acc.HeldBalance = addDecimal(acc.HeldBalance, amount.Neg(amount))
+			accounts[h.AccountCode] = acc
+		}
+	}
+	cmd.explain.locked(accounts)
+
+	currencies, err := loadCurrencies(ctx, tx, cmd.LedgerID, postingCurrencies(*cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate double-entry
+	if err := cmd.explain.check("double_entry", validateDoubleEntry(*cmd, accounts, currencies)); err != nil {
+		return nil, err
+	}
+
+	// Checked while the accounts are locked, against balances that count the events not
+	// projected yet, so concurrent postings are serialized
+	cmd.explain.balances(cmd.Postings, accounts)
+	if err := cmd.explain.check("balance_constraints", validateBalanceConstraints(cmd.Postings, accounts)); err != nil {
+		return nil, err
+	}
+
+	if len(cmd.Assertions) > 0 {
+		if err := cmd.explain.check("assertions", checkAssertions(cmd.Assertions, cmd.Postings, accounts)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cmd.explain.check("tax_codes", validateTaxCodes(ctx, tx, cmd.LedgerID, cmd.Postings)); err != nil {
+		return nil, err
+	}
+
+	if err := cmd.explain.check("metadata", validateMetadata(cmd.Metadata)); err != nil {
+		return nil, err
+	}
+
+	if cmd.EntityCode != "" {
+		if err := cmd.explain.check("entity", validateEntity(ctx, tx, cmd.LedgerID, cmd.EntityCode)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cmd.explain.check("kyc", enforceKYCPolicy(ctx, tx, *cmd, accounts)); err != nil {
+		return nil, err
+	}
+
+	if err := cmd.explain.check("validation_rules", enforceValidationRules(ctx, tx, *cmd, accounts)); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
 }
 
 func (s *Service) loadAndLockAccounts(ctx context.Context, tx pgx.Tx, ledgerID string, postings []PostingInput) (map[string]Account, error) {
@@ -122,10 +293,12 @@ func (s *Service) loadAndLockAccounts(ctx context.Context, tx pgx.Tx, ledgerID s
 	sort.Strings(codes) // Deterministic lock order
 
 	rows, err := tx.Query(ctx, `
-		SELECT id, code, type, balance
+		SELECT id, code, type, balance, held_balance, allow_negative_balance, COALESCE(min_balance::text, ''),
+			COALESCE(entity_code, ''), status = 'disabled'
 		FROM accounts
 		WHERE ledger_id = $1
 		  AND code = ANY($2)
+		ORDER BY code
 		FOR UPDATE
 	`, ledgerID, codes)
 	if err != nil {
@@ -136,7 +309,8 @@ func (s *Service) loadAndLockAccounts(ctx context.Context, tx pgx.Tx, ledgerID s
 	accounts := map[string]Account{}
 	for rows.Next() {
 		var a Account
-		err = rows.Scan(&a.ID, &a.Code, &a.Type, &a.Balance)
+		err = rows.Scan(&a.ID, &a.Code, &a.Type, &a.Balance, &a.HeldBalance, &a.AllowNegativeBalance, &a.MinBalance,
+			&a.EntityCode, &a.Disabled)
 		if err != nil {
 			return nil, err
 		}
@@ -146,9 +320,188 @@ func (s *Service) loadAndLockAccounts(ctx context.Context, tx pgx.Tx, ledgerID s
 		return nil, err
 	}
 
-	if len(accounts) != len(codes) {
-		return nil, fmt.Errorf("one or more accounts not found for ledger %s", ledgerID)
+	for _, code := range codes {
+		if _, ok := accounts[code]; !ok {
+			return nil, &AccountError{AccountCode: code}
+		}
 	}
 
+	if err := addUnprojected(ctx, tx, ledgerID, accounts); err != nil {
+		return nil, err
+	}
 	return accounts, nil
 }
+
+// addUnprojected brings the balances and held balances of locked accounts up to date
+// with the ledger's events the projector has not applied yet, so checks against them
+// don't depend on projector lag. It is consistent under the account locks: a later
+// event moving one of the accounts waits for them, and so does the projector before it
+// can change their balances.
+func addUnprojected(ctx context.Context, tx pgx.Tx, ledgerID string, accounts map[string]Account) error {
+	// Events at or below the lowest offset of the ledger projection's shards are all
+	// applied; above it, an event is applied when its transaction or hold shows it
+	rows, err := tx.Query(ctx, `
+		SELECT e.event_type, e.payload, COALESCE(h.account_code, ''), COALESCE(h.amount::text, '')
+		FROM events e
+		LEFT JOIN holds h ON e.aggregate_type = 'hold' AND h.id = e.aggregate_id
+		WHERE e.ledger_id = $1
+		  AND e.sequence > COALESCE((
+			SELECT MIN(last_processed_sequence) FROM projector_offsets WHERE split_part(projector_name, '#', 1) = 'ledger'
+		  ), 0)
+		  AND e.event_type IN ('TransactionPosted', 'HoldCreated', 'HoldCaptured', 'HoldVoided')
+		  AND NOT EXISTS (SELECT 1 FROM projector_dead_letters d WHERE d.projector_name = 'ledger' AND d.event_id = e.id)
+		  AND CASE e.event_type
+			WHEN 'TransactionPosted' THEN NOT EXISTS (SELECT 1 FROM transactions t WHERE t.id = e.aggregate_id)
+			WHEN 'HoldCreated' THEN h.id IS NULL
+			ELSE h.id IS NULL OR h.status = 'pending'
+		  END
+		ORDER BY e.sequence
+	`, ledgerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	balances, held := balanceDeltas{}, balanceDeltas{}
+	created := map[string]*events.HoldCreated{} // holds created since, by ID
+	for rows.Next() {
+		var eventType, holdAccount, holdAmount string
+		var raw []byte
+		if err := rows.Scan(&eventType, &raw, &holdAccount, &holdAmount); err != nil {
+			return err
+		}
+		payload, err := events.Decode(eventType, raw)
+		if err != nil {
+			return err
+		}
+		switch p := payload.(type) {
+		case *events.TransactionPosted:
+			for _, posting := range p.Postings {
+				if err := balances.add(posting.AccountCode, posting.Amount, posting.Direction != "credit"); err != nil {
+					return err
+				}
+			}
+		case *events.HoldCreated:
+			created[p.HoldID] = p
+			err = held.add(p.AccountCode, p.Amount, false)
+		case *events.HoldCaptured:
+			err = held.release(created[p.HoldID], holdAccount, holdAmount)
+		case *events.HoldVoided:
+			err = held.release(created[p.HoldID], holdAccount, holdAmount)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for code, acc := range accounts {
+		if delta, ok := balances[code]; ok {
+			acc.Balance = addDecimal(acc.Balance, delta)
+		}
+		if delta, ok := held[code]; ok {
+			acc.HeldBalance = addDecimal(acc.HeldBalance, delta)
+		}
+		accounts[code] = acc
+	}
+	return nil
+}
+
+// balanceDeltas sums changes to balances, by account code.
+type balanceDeltas map[string]*big.Rat
+
+func (d balanceDeltas) add(code, amount string, negate bool) error {
+	delta, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return fmt.Errorf("invalid amount: %s", amount)
+	}
+	if negate {
+		delta.Neg(delta)
+	}
+	if d[code] == nil {
+		d[code] = new(big.Rat)
+	}
+	d[code].Add(d[code], delta)
+	return nil
+}
+
+// release releases a hold captured or voided since the projector ran: the one created
+// since, or else the projected pending hold of account and amount.
+func (d balanceDeltas) release(created *events.HoldCreated, account, amount string) error {
+	if created != nil {
+		account, amount = created.AccountCode, created.Amount
+	}
+	if account == "" {
+		return nil
+	}
+	return d.add(account, amount, true)
+}
+
+// addDecimal adds delta to a decimal string, empty meaning zero.
+func addDecimal(value string, delta *big.Rat) string {
+	sum, ok := new(big.Rat).SetString(value)
+	if !ok {
+		sum = new(big.Rat)
+	}
+	return sum.Add(sum, delta).FloatString(10)
+}
+
+// compileScript replaces the command's postings with those its script describes.
+func compileScript(cmd *PostTransactionCommand) error {
+	if len(cmd.Postings) > 0 {
+		return invalidf("postings and script are mutually exclusive")
+	}
+	compiled, err := script.Compile(cmd.Script, cmd.Vars)
+	if err != nil {
+		return invalidf("script: %w", err)
+	}
+	for _, p := range compiled {
+		cmd.Postings = append(cmd.Postings, PostingInput{
+			AccountCode: p.Account,
+			Direction:   p.Direction,
+			Amount:      p.Amount,
+			Currency:    p.Currency,
+		})
+	}
+	if cmd.Currency == "" && len(compiled) > 0 {
+		cmd.Currency = compiled[0].Currency
+	}
+	return nil
+}
+
+// appendEvent appends a non-transaction event (holds, account updates, ...) and enqueues
+// its webhook delivery within tx.
+func (s *Service) appendEvent(ctx context.Context, tx pgx.Tx, ledgerID, aggregateType, aggregateID, eventType string, payload events.Payload) error {
+	payloadJSON, err := events.Marshal(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	eventID := uuid.NewString()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO events (
+			id,
+			ledger_id,
+			aggregate_type,
+			aggregate_id,
+			event_type,
+			payload,
+			occurred_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, eventID, ledgerID, aggregateType, aggregateID, eventType, payloadJSON, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return s.enqueueWebhook(ctx, tx, ledgerID, eventID)
+}
+
+func (s *Service) enqueueWebhook(ctx context.Context, tx pgx.Tx, ledgerID, eventID string) error {
+	_, err := s.RiverClient.InsertTx(ctx, tx, webhook.WebhookArgs{
+		EventID:  eventID,
+		LedgerID: ledgerID,
+	}, nil)
+	return err
+}
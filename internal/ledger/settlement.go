@@ -0,0 +1,193 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/calendar"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNothingToSettle    = errors.New("no unsettled transactions match the settlement criteria")
+	ErrSettlementNotFound = errors.New("settlement batch not found")
+)
+
+type SettlementCommand struct {
+	LedgerID          string
+	AccountCode       string
+	PayoutAccountCode string
+	Currency          string
+	ExternalIDPrefix  string // optional tag: only transactions whose external_id starts with it
+	Cutoff            time.Time
+
+	// Basis is what the cutoff is compared with: occurred_at (default), or value_date,
+	// when transactions valued after the cutoff's date wait for a later batch
+	Basis string
+}
+
+// Settlement bases
+const (
+	SettleByOccurredAt = "occurred_at"
+	SettleByValueDate  = "value_date"
+)
+
+// CreateSettlement claims every unsettled transaction on the account up to the cutoff
+// into a new batch and posts the net settlement transaction to the payout account.
+// Claiming and posting are separate steps; a batch whose posting failed stays claimed
+// and can be retried with PostSettlement.
+func (s *Service) CreateSettlement(ctx context.Context, cmd SettlementCommand) (string, error) {
+	if cmd.AccountCode == "" || cmd.PayoutAccountCode == "" || cmd.Currency == "" {
+		return "", invalidf("account, payout_account and currency required")
+	}
+	if cmd.AccountCode == cmd.PayoutAccountCode {
+		return "", invalidf("payout account must differ from the settled account")
+	}
+	if cmd.Basis == "" {
+		cmd.Basis = SettleByOccurredAt
+	}
+	if cmd.Basis != SettleByOccurredAt && cmd.Basis != SettleByValueDate {
+		return "", invalidf("basis must be occurred_at or value_date")
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the account row so concurrent batches for it are serialized
+	var accountID string
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM accounts WHERE ledger_id = $1 AND code = $2 FOR UPDATE
+	`, cmd.LedgerID, cmd.AccountCode).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", &AccountError{AccountCode: cmd.AccountCode}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var batchID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO settlement_batches (ledger_id, account_code, payout_account_code, currency, external_id_prefix, cutoff, basis)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		RETURNING id
+	`, cmd.LedgerID, cmd.AccountCode, cmd.PayoutAccountCode, cmd.Currency, cmd.ExternalIDPrefix, cmd.Cutoff, cmd.Basis).Scan(&batchID)
+	if err != nil {
+		return "", err
+	}
+
+	// Members contribute their net effect on the account (credits positive, like balances).
+	// Previous settlement transactions are never members of a later batch.
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO settlement_batch_members (batch_id, transaction_id, net_amount)
+		SELECT $1, t.id, SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END)
+		FROM transactions t
+		JOIN postings p ON p.transaction_id = t.id
+		WHERE t.ledger_id = $2
+		  AND p.account_id = $3
+		  AND CASE WHEN $7::text = 'value_date' THEN t.value_date <= ($4::timestamptz AT TIME ZONE 'UTC')::date
+		           ELSE t.occurred_at <= $4 END
+		  AND COALESCE(p.currency, t.currency) = $5
+		  AND ($6 = '' OR left(COALESCE(t.external_id, ''), length($6)) = $6)
+		  AND NOT EXISTS (SELECT 1 FROM settlement_batch_members m WHERE m.transaction_id = t.id)
+		  AND NOT EXISTS (SELECT 1 FROM settlement_batches b WHERE b.settlement_transaction_id = t.id)
+		GROUP BY t.id
+	`, batchID, cmd.LedgerID, accountID, cmd.Cutoff, cmd.Currency, cmd.ExternalIDPrefix, cmd.Basis)
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() == 0 {
+		return "", ErrNothingToSettle
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE settlement_batches b
+		SET net_amount = m.total, transaction_count = m.n
+		FROM (SELECT SUM(net_amount) AS total, COUNT(*) AS n FROM settlement_batch_members WHERE batch_id = $1) m
+		WHERE b.id = $1
+	`, batchID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return batchID, s.PostSettlement(ctx, cmd.LedgerID, batchID)
+}
+
+// PostSettlement posts (or re-posts) the net settlement transaction of a batch, valued
+// on the ledger's next business day from today. The batch ID is the idempotency key, so
+// retries never double-post.
+func (s *Service) PostSettlement(ctx context.Context, ledgerID, batchID string) error {
+	var status, netStr, accountCode, payoutCode, currency string
+	err := s.DB.QueryRow(ctx, `
+		SELECT status, net_amount::text, account_code, payout_account_code, currency
+		FROM settlement_batches
+		WHERE id = $1 AND ledger_id = $2
+	`, batchID, ledgerID).Scan(&status, &netStr, &accountCode, &payoutCode, &currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSettlementNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == "settled" {
+		return nil
+	}
+
+	net, _ := new(big.Rat).SetString(netStr)
+
+	var transactionID *string
+	if net.Sign() != 0 {
+		// Drain the account's net position into the payout account
+		accountDirection, payoutDirection := "debit", "credit"
+		if net.Sign() < 0 {
+			accountDirection, payoutDirection = "credit", "debit"
+		}
+		amount := new(big.Rat).Abs(net).FloatString(10)
+
+		cal, err := s.Calendar(ctx, ledgerID)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		valueDate, ok := cal.Adjust(calendar.Date(now), calendar.PolicyFollowing)
+		if !ok {
+			valueDate = calendar.Date(now)
+		}
+
+		id, postErr := s.PostTransaction(ctx, PostTransactionCommand{
+			LedgerID:       ledgerID,
+			ExternalID:     "settlement:" + batchID,
+			IdempotencyKey: "settlement:" + batchID,
+			Currency:       currency,
+			OccurredAt:     now,
+			ValueDate:      valueDate,
+			Postings: []PostingInput{
+				{AccountCode: accountCode, Direction: accountDirection, Amount: amount},
+				{AccountCode: payoutCode, Direction: payoutDirection, Amount: amount},
+			},
+		})
+		if postErr != nil {
+			_, _ = s.DB.Exec(ctx, `
+				UPDATE settlement_batches SET status = 'failed', error_message = $2 WHERE id = $1
+			`, batchID, postErr.Error())
+			return fmt.Errorf("settlement posting failed: %w", postErr)
+		}
+		transactionID = &id
+	}
+
+	_, err = s.DB.Exec(ctx, `
+		UPDATE settlement_batches
+		SET status = 'settled', settlement_transaction_id = $2, error_message = NULL, settled_at = NOW()
+		WHERE id = $1
+	`, batchID, transactionID)
+	return err
+}
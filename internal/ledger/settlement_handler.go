@@ -0,0 +1,302 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CreateSettlementRequest struct {
+	Account          string     `json:"account"`
+	PayoutAccount    string     `json:"payout_account"`
+	Currency         string     `json:"currency"`
+	ExternalIDPrefix string     `json:"external_id_prefix,omitempty"`
+	Cutoff           *time.Time `json:"cutoff,omitempty"`
+	Basis            string     `json:"basis,omitempty"` // occurred_at (default) or value_date
+}
+
+type SettlementBatchResponse struct {
+	ID                      string                     `json:"id"`
+	Account                 string                     `json:"account"`
+	PayoutAccount           string                     `json:"payout_account"`
+	Currency                string                     `json:"currency"`
+	ExternalIDPrefix        string                     `json:"external_id_prefix,omitempty"`
+	Cutoff                  string                     `json:"cutoff"`
+	Basis                   string                     `json:"basis"`
+	Status                  string                     `json:"status"`
+	NetAmount               string                     `json:"net_amount"`
+	TransactionCount        int                        `json:"transaction_count"`
+	SettlementTransactionID string                     `json:"settlement_transaction_id,omitempty"`
+	ErrorMessage            string                     `json:"error_message,omitempty"`
+	CreatedAt               string                     `json:"created_at"`
+	SettledAt               string                     `json:"settled_at,omitempty"`
+	Members                 []SettlementMemberResponse `json:"members,omitempty"`
+}
+
+type SettlementMemberResponse struct {
+	TransactionID string `json:"transaction_id"`
+	ExternalID    string `json:"external_id"`
+	Description   string `json:"description,omitempty"`
+	OccurredAt    string `json:"occurred_at"`
+	ValueDate     string `json:"value_date"`
+	NetAmount     string `json:"net_amount"`
+}
+
+const settlementSelect = `
+	SELECT id, account_code, payout_account_code, currency, COALESCE(external_id_prefix, ''), cutoff, basis,
+		status, net_amount::text, transaction_count, COALESCE(settlement_transaction_id::text, ''),
+		COALESCE(error_message, ''), created_at, settled_at
+	FROM settlement_batches
+	WHERE ledger_id = $1
+`
+
+// POST /v1/settlements - Build a settlement batch and post its net transaction
+func (h *Handler) CreateSettlement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateSettlementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	cutoff := time.Now().UTC()
+	if req.Cutoff != nil {
+		cutoff = req.Cutoff.UTC()
+	}
+
+	batchID, err := h.Service.CreateSettlement(ctx, SettlementCommand{
+		LedgerID:          principal.LedgerID,
+		AccountCode:       req.Account,
+		PayoutAccountCode: req.PayoutAccount,
+		Currency:          req.Currency,
+		ExternalIDPrefix:  req.ExternalIDPrefix,
+		Cutoff:            cutoff,
+		Basis:             req.Basis,
+	})
+	if errors.Is(err, ErrNothingToSettle) {
+		api.WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil && batchID == "" {
+		writePostTransactionError(w, err)
+		return
+	}
+
+	// A batch whose posting failed is still returned (status "failed") so it can be retried
+	batch, loadErr := h.loadSettlement(r, principal.LedgerID, batchID)
+	if loadErr != nil {
+		api.Error(w, "failed to load settlement", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(batch)
+}
+
+// GET /v1/settlements - List settlement batches
+func (h *Handler) ListSettlements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := settlementSelect
+	args := []interface{}{principal.LedgerID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query settlements", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	batches := []SettlementBatchResponse{}
+	for rows.Next() {
+		b, err := scanSettlement(rows)
+		if err != nil {
+			api.Error(w, "failed to scan settlement", http.StatusInternalServerError)
+			return
+		}
+		batches = append(batches, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+// GET /v1/settlements/:id - Get a settlement batch with its member transactions
+func (h *Handler) GetSettlement(w http.ResponseWriter, r *http.Request) {
+	principal, err := auth.FromContext(r.Context())
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	batchID := r.URL.Query().Get("id")
+	if batchID == "" {
+		api.Error(w, "settlement id required", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.loadSettlement(r, principal.LedgerID, batchID)
+	if err != nil {
+		api.Error(w, "settlement not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// POST /v1/settlements/:id/post - Retry posting a failed settlement batch
+func (h *Handler) RetrySettlement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	batchID := r.URL.Query().Get("id")
+	if batchID == "" {
+		api.Error(w, "settlement id required", http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.PostSettlement(ctx, principal.LedgerID, batchID)
+	if errors.Is(err, ErrSettlementNotFound) {
+		api.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writePostTransactionError(w, err)
+		return
+	}
+
+	batch, err := h.loadSettlement(r, principal.LedgerID, batchID)
+	if err != nil {
+		api.Error(w, "settlement not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// GET /v1/settlements/:id/export - CSV of the batch members for payout reconciliation
+func (h *Handler) ExportSettlement(w http.ResponseWriter, r *http.Request) {
+	principal, err := auth.FromContext(r.Context())
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	batchID := r.URL.Query().Get("id")
+	if batchID == "" {
+		api.Error(w, "settlement id required", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := h.loadSettlement(r, principal.LedgerID, batchID)
+	if err != nil {
+		api.Error(w, "settlement not found", http.StatusNotFound)
+		return
+	}
+
+	// Notes are loaded up front so a failure can still be reported as an error
+	memberNotes := make([]string, len(batch.Members))
+	for i, m := range batch.Members {
+		notes, err := h.loadNotes(r.Context(), principal.LedgerID, "transaction", m.TransactionID)
+		if err != nil {
+			api.Error(w, "failed to load notes", http.StatusInternalServerError)
+			return
+		}
+		memberNotes[i] = formatNotes(notes)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="settlement-`+batch.ID+`.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"batch_id", "account", "currency", "transaction_id", "external_id", "description", "occurred_at", "value_date", "net_amount", "notes"})
+	for i, m := range batch.Members {
+		cw.Write([]string{batch.ID, batch.Account, batch.Currency, m.TransactionID, m.ExternalID, m.Description, m.OccurredAt, m.ValueDate, m.NetAmount, memberNotes[i]})
+	}
+	cw.Write([]string{batch.ID, batch.Account, batch.Currency, batch.SettlementTransactionID, "TOTAL", "", batch.SettledAt, "", batch.NetAmount, ""})
+	cw.Flush()
+}
+
+func (h *Handler) loadSettlement(r *http.Request, ledgerID, batchID string) (SettlementBatchResponse, error) {
+	ctx := r.Context()
+
+	batch, err := scanSettlement(h.Service.DB.QueryRow(ctx, settlementSelect+` AND id = $2`, ledgerID, batchID))
+	if err != nil {
+		return batch, err
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT m.transaction_id, COALESCE(t.external_id, ''), COALESCE(t.description, ''), t.occurred_at, t.value_date::text, m.net_amount::text
+		FROM settlement_batch_members m
+		JOIN transactions t ON t.id = m.transaction_id
+		WHERE m.batch_id = $1
+		ORDER BY t.occurred_at, t.id
+	`, batchID)
+	if err != nil {
+		return batch, err
+	}
+	defer rows.Close()
+
+	batch.Members = []SettlementMemberResponse{}
+	for rows.Next() {
+		var m SettlementMemberResponse
+		var occurredAt time.Time
+		if err := rows.Scan(&m.TransactionID, &m.ExternalID, &m.Description, &occurredAt, &m.ValueDate, &m.NetAmount); err != nil {
+			return batch, err
+		}
+		m.OccurredAt = occurredAt.Format(time.RFC3339)
+		batch.Members = append(batch.Members, m)
+	}
+
+	return batch, rows.Err()
+}
+
+func scanSettlement(row pgx.Row) (SettlementBatchResponse, error) {
+	var b SettlementBatchResponse
+	var cutoff, createdAt time.Time
+	var settledAt *time.Time
+	err := row.Scan(&b.ID, &b.Account, &b.PayoutAccount, &b.Currency, &b.ExternalIDPrefix, &cutoff, &b.Basis,
+		&b.Status, &b.NetAmount, &b.TransactionCount, &b.SettlementTransactionID,
+		&b.ErrorMessage, &createdAt, &settledAt)
+	if err != nil {
+		return b, err
+	}
+	b.Cutoff = cutoff.Format(time.RFC3339)
+	b.CreatedAt = createdAt.Format(time.RFC3339)
+	if settledAt != nil {
+		b.SettledAt = settledAt.Format(time.RFC3339)
+	}
+	return b, nil
+}
@@ -0,0 +1,273 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type BalanceSnapshotResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TakenAt   string `json:"taken_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CreateBalanceSnapshotRequest struct {
+	Name string     `json:"name"`
+	At   *time.Time `json:"at,omitempty"`
+}
+
+type BalanceDiffResponse struct {
+	From     string               `json:"from"`
+	To       string               `json:"to"`
+	Accounts []AccountBalanceDiff `json:"accounts"`
+}
+
+type AccountBalanceDiff struct {
+	AccountCode      string `json:"account_code"`
+	AccountName      string `json:"account_name"`
+	AccountType      string `json:"account_type"`
+	BalanceFrom      string `json:"balance_from"`
+	BalanceTo        string `json:"balance_to"`
+	Delta            string `json:"delta"`
+	TransactionCount int    `json:"transaction_count"`
+}
+
+// POST /v1/balance/snapshots - Freeze every account balance as of a point in time
+func (h *Handler) CreateBalanceSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateBalanceSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		api.Error(w, "snapshot name required", http.StatusBadRequest)
+		return
+	}
+
+	takenAt := time.Now().UTC()
+	if req.At != nil {
+		takenAt = req.At.UTC()
+	}
+
+	tx, err := h.Service.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var snap BalanceSnapshotResponse
+	var createdAt time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO balance_snapshots (ledger_id, name, taken_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, principal.LedgerID, req.Name, takenAt).Scan(&snap.ID, &createdAt)
+	if err != nil {
+		api.Error(w, "failed to create snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	// Balances follow the read model convention: credits increase, debits decrease
+	_, err = tx.Exec(ctx, `
+		INSERT INTO balance_snapshot_entries (snapshot_id, account_id, balance)
+		SELECT $1, a.id, COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END), 0)
+		FROM accounts a
+		LEFT JOIN postings p ON p.account_id = a.id
+			AND p.transaction_id IN (SELECT t.id FROM transactions t WHERE t.ledger_id = $2 AND t.occurred_at <= $3)
+		WHERE a.ledger_id = $2
+		GROUP BY a.id
+	`, snap.ID, principal.LedgerID, takenAt)
+	if err != nil {
+		api.Error(w, "failed to record snapshot balances", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	snap.Name = req.Name
+	snap.TakenAt = takenAt.Format(time.RFC3339)
+	snap.CreatedAt = createdAt.Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(snap)
+}
+
+// GET /v1/balance/snapshots - List balance snapshots
+func (h *Handler) ListBalanceSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT id, name, taken_at, created_at
+		FROM balance_snapshots
+		WHERE ledger_id = $1
+		ORDER BY taken_at DESC
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query snapshots", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	snapshots := []BalanceSnapshotResponse{}
+	for rows.Next() {
+		var snap BalanceSnapshotResponse
+		var takenAt, createdAt time.Time
+		if err := rows.Scan(&snap.ID, &snap.Name, &takenAt, &createdAt); err != nil {
+			api.Error(w, "failed to scan snapshot", http.StatusInternalServerError)
+			return
+		}
+		snap.TakenAt = takenAt.Format(time.RFC3339)
+		snap.CreatedAt = createdAt.Format(time.RFC3339)
+		snapshots = append(snapshots, snap)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+// GET /v1/balance/diff - Per-account balance movement between two timestamps or snapshots
+//
+// Use either from/to (RFC3339) or from_snapshot/to_snapshot (snapshot IDs).
+// changed_only=true omits accounts without movement.
+func (h *Handler) GetBalanceDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	fromSnapshot, toSnapshot := q.Get("from_snapshot"), q.Get("to_snapshot")
+
+	var from, to time.Time
+	var rows pgx.Rows
+	if fromSnapshot != "" || toSnapshot != "" {
+		if fromSnapshot == "" || toSnapshot == "" {
+			api.Error(w, "both from_snapshot and to_snapshot required", http.StatusBadRequest)
+			return
+		}
+
+		err = h.Service.DB.QueryRow(ctx, `
+			SELECT f.taken_at, t.taken_at
+			FROM balance_snapshots f, balance_snapshots t
+			WHERE f.id = $2 AND t.id = $3 AND f.ledger_id = $1 AND t.ledger_id = $1
+		`, principal.LedgerID, fromSnapshot, toSnapshot).Scan(&from, &to)
+		if err != nil {
+			api.Error(w, "snapshot not found", http.StatusNotFound)
+			return
+		}
+		if to.Before(from) {
+			api.Error(w, "to_snapshot must not be older than from_snapshot", http.StatusBadRequest)
+			return
+		}
+
+		rows, err = h.Service.DB.Query(ctx, `
+			SELECT a.code, a.name, a.type,
+				COALESCE(f.balance, 0)::text,
+				COALESCE(t.balance, 0)::text,
+				(COALESCE(t.balance, 0) - COALESCE(f.balance, 0))::text,
+				(SELECT COUNT(DISTINCT p.transaction_id)
+				 FROM postings p
+				 JOIN transactions tx ON tx.id = p.transaction_id
+				 WHERE p.account_id = a.id AND tx.occurred_at > $4 AND tx.occurred_at <= $5)
+			FROM accounts a
+			LEFT JOIN balance_snapshot_entries f ON f.account_id = a.id AND f.snapshot_id = $2
+			LEFT JOIN balance_snapshot_entries t ON t.account_id = a.id AND t.snapshot_id = $3
+			WHERE a.ledger_id = $1
+			ORDER BY a.code
+		`, principal.LedgerID, fromSnapshot, toSnapshot, from, to)
+	} else {
+		from, err = time.Parse(time.RFC3339, q.Get("from"))
+		if err != nil {
+			api.Error(w, "from must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		to, err = time.Parse(time.RFC3339, q.Get("to"))
+		if err != nil {
+			api.Error(w, "to must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		if to.Before(from) {
+			api.Error(w, "to must not be before from", http.StatusBadRequest)
+			return
+		}
+
+		rows, err = h.Service.DB.Query(ctx, `
+			WITH movements AS (
+				SELECT p.account_id,
+					CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END AS signed_amount,
+					t.id AS transaction_id,
+					t.occurred_at
+				FROM postings p
+				JOIN transactions t ON t.id = p.transaction_id
+				WHERE p.ledger_id = $1 AND t.occurred_at <= $3
+			)
+			SELECT a.code, a.name, a.type,
+				COALESCE(SUM(m.signed_amount) FILTER (WHERE m.occurred_at <= $2), 0)::text,
+				COALESCE(SUM(m.signed_amount), 0)::text,
+				COALESCE(SUM(m.signed_amount) FILTER (WHERE m.occurred_at > $2), 0)::text,
+				COUNT(DISTINCT m.transaction_id) FILTER (WHERE m.occurred_at > $2)
+			FROM accounts a
+			LEFT JOIN movements m ON m.account_id = a.id
+			WHERE a.ledger_id = $1
+			GROUP BY a.id, a.code, a.name, a.type
+			ORDER BY a.code
+		`, principal.LedgerID, from, to)
+	}
+	if err != nil {
+		api.Error(w, "failed to query balance diff", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	changedOnly := q.Get("changed_only") == "true"
+	diffs := []AccountBalanceDiff{}
+	for rows.Next() {
+		var d AccountBalanceDiff
+		err = rows.Scan(&d.AccountCode, &d.AccountName, &d.AccountType, &d.BalanceFrom, &d.BalanceTo, &d.Delta, &d.TransactionCount)
+		if err != nil {
+			api.Error(w, "failed to scan balance diff", http.StatusInternalServerError)
+			return
+		}
+		if changedOnly && d.TransactionCount == 0 && d.BalanceFrom == d.BalanceTo {
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+
+	response := BalanceDiffResponse{
+		From:     from.Format(time.RFC3339),
+		To:       to.Format(time.RFC3339),
+		Accounts: diffs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,53 @@
+package ledger
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// validateTaxCodes checks that every explicit posting tax code is defined on the ledger.
+func validateTaxCodes(ctx context.Context, tx pgx.Tx, ledgerID string, postings []PostingInput) error {
+	codesSet := map[string]struct{}{}
+	for _, p := range postings {
+		if p.TaxCode != "" {
+			codesSet[p.TaxCode] = struct{}{}
+		}
+	}
+	if len(codesSet) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(codesSet))
+	for c := range codesSet {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+
+	rows, err := tx.Query(ctx, `
+		SELECT code FROM tax_codes WHERE ledger_id = $1 AND code = ANY($2)
+	`, ledgerID, codes)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return err
+		}
+		delete(codesSet, code)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range codes {
+		if _, missing := codesSet[c]; missing {
+			return invalidf("tax code %s not found", c)
+		}
+	}
+	return nil
+}
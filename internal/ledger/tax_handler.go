@@ -0,0 +1,230 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+type TaxCodeRequest struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Rate        string `json:"rate"` // e.g. "0.20" for 20%
+	TaxAccount  string `json:"tax_account"`
+}
+
+type TaxCodeResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Rate        string `json:"rate"`
+	TaxAccount  string `json:"tax_account"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type TaxReportLine struct {
+	Period       string `json:"period"` // start of the period, YYYY-MM-DD
+	TaxCode      string `json:"tax_code"`
+	Rate         string `json:"rate"`
+	Currency     string `json:"currency"`
+	TaxableBase  string `json:"taxable_base"`
+	TaxCollected string `json:"tax_collected"`
+	ExpectedTax  string `json:"expected_tax"`
+	Difference   string `json:"difference"`
+}
+
+// POST /v1/tax-codes - Define or update a tax code
+func (h *Handler) SaveTaxCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req TaxCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" || req.TaxAccount == "" {
+		api.Error(w, "code and tax_account required", http.StatusBadRequest)
+		return
+	}
+	rate, ok := new(big.Rat).SetString(req.Rate)
+	if !ok || rate.Sign() < 0 {
+		api.Error(w, "rate must be a non-negative decimal", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM accounts WHERE ledger_id = $1 AND code = $2)
+	`, principal.LedgerID, req.TaxAccount).Scan(&exists)
+	if err != nil || !exists {
+		api.Error(w, "tax account not found", http.StatusBadRequest)
+		return
+	}
+
+	var createdAt time.Time
+	err = h.Service.DB.QueryRow(ctx, `
+		INSERT INTO tax_codes (ledger_id, code, description, rate, tax_account)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		ON CONFLICT (ledger_id, code) DO UPDATE
+			SET description = EXCLUDED.description, rate = EXCLUDED.rate, tax_account = EXCLUDED.tax_account
+		RETURNING created_at
+	`, principal.LedgerID, req.Code, req.Description, rate.FloatString(6), req.TaxAccount).Scan(&createdAt)
+	if err != nil {
+		api.Error(w, "failed to save tax code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TaxCodeResponse{
+		Code:        req.Code,
+		Description: req.Description,
+		Rate:        rate.FloatString(6),
+		TaxAccount:  req.TaxAccount,
+		CreatedAt:   createdAt.Format(time.RFC3339),
+	})
+}
+
+// GET /v1/tax-codes - List tax codes
+func (h *Handler) ListTaxCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT code, COALESCE(description, ''), rate::text, tax_account, created_at
+		FROM tax_codes
+		WHERE ledger_id = $1
+		ORDER BY code
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query tax codes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	codes := []TaxCodeResponse{}
+	for rows.Next() {
+		var c TaxCodeResponse
+		var createdAt time.Time
+		if err := rows.Scan(&c.Code, &c.Description, &c.Rate, &c.TaxAccount, &createdAt); err != nil {
+			api.Error(w, "failed to scan tax code", http.StatusInternalServerError)
+			return
+		}
+		c.CreatedAt = createdAt.Format(time.RFC3339)
+		codes = append(codes, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codes)
+}
+
+// GET /v1/tax/report?from=&to=&period=month&format=csv - Taxable base and tax collected per code per period
+//
+// Postings tagged with a tax code on the code's tax account count as tax collected; postings
+// tagged with the code on any other account form the taxable base. Both are signed credit
+// minus debit, so refunds and credit notes reduce the figures.
+func (h *Handler) GetTaxReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		api.Error(w, "from must be an RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		api.Error(w, "to must be an RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	if !to.After(from) {
+		api.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+
+	period := q.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	if period != "day" && period != "month" && period != "quarter" && period != "year" {
+		api.Error(w, "period must be day, month, quarter or year", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT date_trunc($4, t.occurred_at AT TIME ZONE 'UTC')::date::text, tc.code, tc.rate::text, p.currency,
+			COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END)
+				FILTER (WHERE a.code <> tc.tax_account), 0)::text,
+			COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END)
+				FILTER (WHERE a.code = tc.tax_account), 0)::text
+		FROM postings p
+		JOIN transactions t ON t.id = p.transaction_id
+		JOIN accounts a ON a.id = p.account_id
+		JOIN tax_codes tc ON tc.ledger_id = p.ledger_id AND tc.code = p.tax_code
+		WHERE p.ledger_id = $1
+		  AND t.occurred_at >= $2
+		  AND t.occurred_at < $3
+		GROUP BY 1, tc.code, tc.rate, p.currency
+		ORDER BY 1, tc.code, p.currency
+	`, principal.LedgerID, from, to, period)
+	if err != nil {
+		api.Error(w, "failed to query tax report", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	lines := []TaxReportLine{}
+	for rows.Next() {
+		var l TaxReportLine
+		if err := rows.Scan(&l.Period, &l.TaxCode, &l.Rate, &l.Currency, &l.TaxableBase, &l.TaxCollected); err != nil {
+			api.Error(w, "failed to scan tax report", http.StatusInternalServerError)
+			return
+		}
+
+		base, _ := new(big.Rat).SetString(l.TaxableBase)
+		collected, _ := new(big.Rat).SetString(l.TaxCollected)
+		rate, _ := new(big.Rat).SetString(l.Rate)
+		expected := new(big.Rat).Mul(base, rate)
+
+		l.TaxableBase = base.FloatString(2)
+		l.TaxCollected = collected.FloatString(2)
+		l.ExpectedTax = expected.FloatString(2)
+		l.Difference = new(big.Rat).Sub(collected, expected).FloatString(2)
+		lines = append(lines, l)
+	}
+
+	if q.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="tax-report-`+from.Format("20060102")+`-`+to.Format("20060102")+`.csv"`)
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"period", "tax_code", "rate", "currency", "taxable_base", "tax_collected", "expected_tax", "difference"})
+		for _, l := range lines {
+			cw.Write([]string{l.Period, l.TaxCode, l.Rate, l.Currency, l.TaxableBase, l.TaxCollected, l.ExpectedTax, l.Difference})
+		}
+		cw.Flush()
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lines)
+}
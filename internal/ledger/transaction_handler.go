@@ -3,6 +3,7 @@ package ledger
 import (
 	"Go_FormanceLegder/internal/api"
 	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/calendar"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,13 +12,18 @@ import (
 )
 
 type TransactionResponse struct {
-	ID         string          `json:"id"`
-	ExternalID string          `json:"external_id"`
-	Amount     string          `json:"amount"`
-	Currency   string          `json:"currency"`
-	OccurredAt string          `json:"occurred_at"`
-	CreatedAt  string          `json:"created_at"`
-	Postings   []PostingDetail `json:"postings"`
+	ID          string          `json:"id"`
+	ExternalID  string          `json:"external_id"`
+	Description string          `json:"description,omitempty"`
+	Amount      string          `json:"amount"`
+	Currency    string          `json:"currency"`
+	OccurredAt  string          `json:"occurred_at"`
+	ValueDate   string          `json:"value_date"`
+	CreatedAt   string          `json:"created_at"`
+	Entity      string          `json:"entity,omitempty"`
+	Metadata    map[string]any  `json:"metadata"`
+	Postings    []PostingDetail `json:"postings"`
+	Notes       []Note          `json:"notes,omitempty"` // single-transaction reads only
 }
 
 type PostingDetail struct {
@@ -26,6 +32,9 @@ type PostingDetail struct {
 	AccountName string `json:"account_name"`
 	Direction   string `json:"direction"`
 	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	TaxCode     string `json:"tax_code,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 type ListTransactionsResponse struct {
@@ -34,12 +43,16 @@ type ListTransactionsResponse struct {
 }
 
 // GET /v1/transactions - List transactions with pagination
+//
+// With Accept: application/x-ndjson every matching transaction is streamed instead, one
+// per line, ignoring limit. On large ledgers, metadata filters need a bounded time range
+// or confirm_expensive=true (see guardTransactionListing).
 func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -54,7 +67,7 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	continuationToken := r.URL.Query().Get("continuation_token")
 	cursor, err := api.DecodeCursor(continuationToken)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		api.WriteError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -62,9 +75,17 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	startTime := r.URL.Query().Get("start_time")
 	endTime := r.URL.Query().Get("end_time")
 
+	// metadata[key]=value filters match the value's text form, so numbers match too
+	metadataFilters, err := parseMetadataFilters(r.URL.Query())
+	if err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
 	// Build query
 	query := `
-		SELECT t.id, t.external_id, t.amount, t.currency, t.occurred_at, t.created_at
+		SELECT t.id, t.external_id, COALESCE(t.description, ''), t.amount, t.currency, t.occurred_at, t.value_date::text,
+			t.created_at, COALESCE(t.entity_code, ''), t.metadata
 		FROM transactions t
 		WHERE t.ledger_id = $1
 	`
@@ -90,6 +111,39 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 		query += ` AND t.occurred_at <= $` + fmt.Sprintf("%d", argCount)
 		args = append(args, endTime)
 	}
+	// value_from and value_to bound the value date, inclusive
+	for _, bound := range []struct{ param, op string }{{"value_from", ">="}, {"value_to", "<="}} {
+		value := r.URL.Query().Get(bound.param)
+		if value == "" {
+			continue
+		}
+		date, err := calendar.ParseDate(value)
+		if err != nil {
+			api.Error(w, bound.param+" must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		argCount++
+		query += ` AND t.value_date ` + bound.op + ` $` + fmt.Sprintf("%d", argCount)
+		args = append(args, date)
+	}
+	if entity := r.URL.Query().Get("entity"); entity != "" {
+		argCount++
+		query += ` AND t.entity_code = $` + fmt.Sprintf("%d", argCount)
+		args = append(args, entity)
+	}
+	for _, f := range metadataFilters {
+		query += ` AND t.metadata ->> $` + fmt.Sprintf("%d", argCount+1) + ` = $` + fmt.Sprintf("%d", argCount+2)
+		args = append(args, f.Key, f.Value)
+		argCount += 2
+	}
+
+	if api.WantsNDJSON(r) {
+		h.streamTransactions(w, r, query, args)
+		return
+	}
+	if len(metadataFilters) > 0 && h.guardTransactionListing(w, r, principal.LedgerID) {
+		return
+	}
 
 	// Order and limit (fetch limit + 1 to check if there are more)
 	query += ` ORDER BY t.created_at DESC, t.id DESC LIMIT $` + fmt.Sprintf("%d", argCount+1)
@@ -97,7 +151,7 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := h.Service.DB.Query(ctx, query, args...)
 	if err != nil {
-		http.Error(w, "failed to query transactions", http.StatusInternalServerError)
+		api.Error(w, "failed to query transactions", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -109,9 +163,10 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var txn TransactionResponse
 		var createdAt time.Time
-		err = rows.Scan(&txn.ID, &txn.ExternalID, &txn.Amount, &txn.Currency, &txn.OccurredAt, &createdAt)
+		err = rows.Scan(&txn.ID, &txn.ExternalID, &txn.Description, &txn.Amount, &txn.Currency, &txn.OccurredAt, &txn.ValueDate, &createdAt,
+			&txn.Entity, &txn.Metadata)
 		if err != nil {
-			http.Error(w, "failed to scan transaction", http.StatusInternalServerError)
+			api.Error(w, "failed to scan transaction", http.StatusInternalServerError)
 			return
 		}
 		txn.CreatedAt = createdAt.Format(time.RFC3339)
@@ -148,7 +203,7 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	for i := range transactions {
 		postings, err := h.loadPostings(ctx, principal.LedgerID, transactions[i].ID)
 		if err != nil {
-			http.Error(w, "failed to load postings", http.StatusInternalServerError)
+			api.Error(w, "failed to load postings", http.StatusInternalServerError)
 			return
 		}
 		transactions[i].Postings = postings
@@ -167,31 +222,80 @@ func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// streamTransactions writes the transactions matching query as NDJSON, with each one's
+// postings aggregated in the same query rather than loaded per row.
+func (h *Handler) streamTransactions(w http.ResponseWriter, r *http.Request, query string, args []interface{}) {
+	ctx := r.Context()
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT q.*, COALESCE((
+			SELECT json_agg(json_build_object(
+				'id', p.id, 'account_code', a.code, 'account_name', a.name, 'direction', p.direction,
+				'amount', p.amount::text, 'currency', COALESCE(p.currency, ''), 'tax_code', p.tax_code,
+				'description', p.description
+			) ORDER BY p.created_at)
+			FROM postings p
+			JOIN accounts a ON a.id = p.account_id
+			WHERE p.ledger_id = $1 AND p.transaction_id = q.id
+		), '[]')
+		FROM (`+query+`) q
+		ORDER BY q.created_at DESC, q.id DESC
+	`, args...)
+	if err != nil {
+		api.Error(w, "failed to query transactions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	stream := api.NewNDJSONWriter(w)
+	for rows.Next() {
+		var txn TransactionResponse
+		var createdAt time.Time
+		err := rows.Scan(&txn.ID, &txn.ExternalID, &txn.Description, &txn.Amount, &txn.Currency, &txn.OccurredAt, &txn.ValueDate, &createdAt,
+			&txn.Entity, &txn.Metadata, &txn.Postings)
+		if err != nil {
+			stream.Fail("failed to scan transaction")
+			return
+		}
+		txn.CreatedAt = createdAt.Format(time.RFC3339)
+		if err := stream.Write(txn); err != nil {
+			return
+		}
+	}
+	if rows.Err() != nil {
+		stream.Fail("failed to query transactions")
+		return
+	}
+	stream.Close()
+}
+
 // GET /v1/transactions/:id - Get a specific transaction
 func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	principal, err := auth.FromContext(ctx)
 	if err != nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	transactionID := r.URL.Query().Get("id")
 	if transactionID == "" {
-		http.Error(w, "transaction id required", http.StatusBadRequest)
+		api.Error(w, "transaction id required", http.StatusBadRequest)
 		return
 	}
 
 	var txn TransactionResponse
 	var createdAt time.Time
 	err = h.Service.DB.QueryRow(ctx, `
-		SELECT id, external_id, amount, currency, occurred_at, created_at
+		SELECT id, external_id, COALESCE(description, ''), amount, currency, occurred_at, value_date::text, created_at,
+			COALESCE(entity_code, ''), metadata
 		FROM transactions
 		WHERE ledger_id = $1 AND id = $2
-	`, principal.LedgerID, transactionID).Scan(&txn.ID, &txn.ExternalID, &txn.Amount, &txn.Currency, &txn.OccurredAt, &createdAt)
+	`, principal.LedgerID, transactionID).Scan(&txn.ID, &txn.ExternalID, &txn.Description, &txn.Amount, &txn.Currency, &txn.OccurredAt,
+		&txn.ValueDate, &createdAt, &txn.Entity, &txn.Metadata)
 	if err != nil {
-		http.Error(w, "transaction not found", http.StatusNotFound)
+		api.Error(w, "transaction not found", http.StatusNotFound)
 		return
 	}
 	txn.CreatedAt = createdAt.Format(time.RFC3339)
@@ -199,18 +303,25 @@ func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	// Load postings
 	postings, err := h.loadPostings(ctx, principal.LedgerID, txn.ID)
 	if err != nil {
-		http.Error(w, "failed to load postings", http.StatusInternalServerError)
+		api.Error(w, "failed to load postings", http.StatusInternalServerError)
 		return
 	}
 	txn.Postings = postings
 
+	txn.Notes, err = h.loadNotes(ctx, principal.LedgerID, "transaction", txn.ID)
+	if err != nil {
+		api.Error(w, "failed to load notes", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(txn)
 }
 
 func (h *Handler) loadPostings(ctx context.Context, ledgerID, transactionID string) ([]PostingDetail, error) {
 	rows, err := h.Service.DB.Query(ctx, `
-		SELECT p.id, a.code, a.name, p.direction, p.amount
+		SELECT p.id, a.code, a.name, p.direction, p.amount, COALESCE(p.currency, ''), COALESCE(p.tax_code, ''),
+			COALESCE(p.description, '')
 		FROM postings p
 		JOIN accounts a ON a.id = p.account_id
 		WHERE p.ledger_id = $1 AND p.transaction_id = $2
@@ -224,7 +335,7 @@ func (h *Handler) loadPostings(ctx context.Context, ledgerID, transactionID stri
 	postings := []PostingDetail{}
 	for rows.Next() {
 		var p PostingDetail
-		err = rows.Scan(&p.ID, &p.AccountCode, &p.AccountName, &p.Direction, &p.Amount)
+		err = rows.Scan(&p.ID, &p.AccountCode, &p.AccountName, &p.Direction, &p.Amount, &p.Currency, &p.TaxCode, &p.Description)
 		if err != nil {
 			return nil, err
 		}
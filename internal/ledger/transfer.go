@@ -0,0 +1,203 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/hooks"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrLedgerNotInProject = errors.New("ledger not found in project")
+
+// TransferCommand moves value between two ledgers of a project, e.g. between the
+// ledgers of two companies of a group. Each ledger books its side against its own
+// inter-company account:
+//
+//	source ledger:      debit SourceAccount, credit SourceClearingAccount
+//	destination ledger: debit DestinationClearingAccount, credit DestinationAccount
+type TransferCommand struct {
+	ProjectID      string
+	IdempotencyKey string
+	ExternalID     string
+	Amount         string
+	Currency       string
+	OccurredAt     time.Time
+	Metadata       map[string]any
+
+	SourceLedgerID        string
+	SourceAccount         string
+	SourceClearingAccount string // e.g. due_to:<destination company>
+
+	DestinationLedgerID        string
+	DestinationAccount         string
+	DestinationClearingAccount string // e.g. due_from:<source company>
+}
+
+type Transfer struct {
+	ID                       string `json:"id"`
+	SourceLedgerID           string `json:"source_ledger_id"`
+	SourceTransactionID      string `json:"source_transaction_id"`
+	DestinationLedgerID      string `json:"destination_ledger_id"`
+	DestinationTransactionID string `json:"destination_transaction_id"`
+	Amount                   string `json:"amount"`
+	Currency                 string `json:"currency"`
+	CreatedAt                string `json:"created_at"`
+}
+
+// PostTransfer posts both sides of a transfer in one database transaction: either both
+// ledgers get their transaction and TransactionPosted event, or neither does. The
+// transactions carry the transfer ID and the other ledger in their metadata. Screening
+// applies to both sides; a side held for review fails the transfer, since a review
+// covers a single ledger.
+func (s *Service) PostTransfer(ctx context.Context, cmd TransferCommand) (Transfer, error) {
+	if cmd.IdempotencyKey == "" {
+		return Transfer{}, invalidf("idempotency_key required")
+	}
+	if cmd.SourceLedgerID == cmd.DestinationLedgerID {
+		return Transfer{}, invalidf("source and destination ledgers must differ")
+	}
+	if cmd.SourceAccount == "" || cmd.SourceClearingAccount == "" || cmd.DestinationAccount == "" || cmd.DestinationClearingAccount == "" {
+		return Transfer{}, invalidf("source, destination and clearing accounts required")
+	}
+	amount, ok := new(big.Rat).SetString(cmd.Amount)
+	if !ok || amount.Sign() <= 0 {
+		return Transfer{}, invalidf("invalid amount: %s", cmd.Amount)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Transfer{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Keys are scoped to the source ledger, so one ledger cannot probe for another's
+	// transfers with guessed keys
+	existing, err := scanTransfer(tx.QueryRow(ctx, transferSelect+`
+		WHERE source_ledger_id = $1 AND idempotency_key = $2
+	`, cmd.SourceLedgerID, cmd.IdempotencyKey))
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Transfer{}, err
+	}
+
+	var inProject int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM ledgers WHERE project_id = $1 AND id::text IN ($2, $3)
+	`, cmd.ProjectID, cmd.SourceLedgerID, cmd.DestinationLedgerID).Scan(&inProject)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if inProject != 2 {
+		return Transfer{}, ErrLedgerNotInProject
+	}
+
+	transferID := uuid.NewString()
+	if cmd.OccurredAt.IsZero() {
+		cmd.OccurredAt = time.Now().UTC()
+	}
+	legMetadata := func(counterpartyLedgerID string) map[string]any {
+		metadata := make(map[string]any, len(cmd.Metadata)+2)
+		for k, v := range cmd.Metadata {
+			metadata[k] = v
+		}
+		metadata["transfer_id"] = transferID
+		metadata["counterparty_ledger_id"] = counterpartyLedgerID
+		return metadata
+	}
+	value := amount.FloatString(10)
+
+	legs := []PostTransactionCommand{
+		{
+			LedgerID:       cmd.SourceLedgerID,
+			ExternalID:     cmd.ExternalID,
+			IdempotencyKey: "transfer:" + transferID,
+			Currency:       cmd.Currency,
+			OccurredAt:     cmd.OccurredAt,
+			Metadata:       legMetadata(cmd.DestinationLedgerID),
+			Postings: []PostingInput{
+				{AccountCode: cmd.SourceAccount, Direction: "debit", Amount: value},
+				{AccountCode: cmd.SourceClearingAccount, Direction: "credit", Amount: value},
+			},
+		},
+		{
+			LedgerID:       cmd.DestinationLedgerID,
+			ExternalID:     cmd.ExternalID,
+			IdempotencyKey: "transfer:" + transferID,
+			Currency:       cmd.Currency,
+			OccurredAt:     cmd.OccurredAt,
+			Metadata:       legMetadata(cmd.SourceLedgerID),
+			Postings: []PostingInput{
+				{AccountCode: cmd.DestinationClearingAccount, Direction: "debit", Amount: value},
+				{AccountCode: cmd.DestinationAccount, Direction: "credit", Amount: value},
+			},
+		},
+	}
+
+	// Accounts are locked leg by leg; taking the ledgers in a fixed order keeps
+	// concurrent transfers in opposite directions from deadlocking
+	order := []int{0, 1}
+	if cmd.DestinationLedgerID < cmd.SourceLedgerID {
+		order = []int{1, 0}
+	}
+	transactionIDs := make([]string, 2)
+	posted := make([]*hooks.Transaction, 2)
+	for _, i := range order {
+		transactionIDs[i], posted[i], err = s.postTransactionTx(ctx, tx, legs[i])
+		var pending *PendingReviewError
+		if errors.As(err, &pending) {
+			return Transfer{}, &ScreeningError{Reason: fmt.Sprintf("ledger %s holds the transfer for review: %s", legs[i].LedgerID, pending.Reason)}
+		}
+		if err != nil {
+			return Transfer{}, fmt.Errorf("ledger %s: %w", legs[i].LedgerID, err)
+		}
+	}
+
+	transfer, err := scanTransfer(tx.QueryRow(ctx, `
+		INSERT INTO transfers (id, project_id, idempotency_key, source_ledger_id, source_transaction_id,
+			destination_ledger_id, destination_transaction_id, amount, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, source_ledger_id, source_transaction_id, destination_ledger_id, destination_transaction_id,
+			amount::text, currency, created_at
+	`, transferID, cmd.ProjectID, cmd.IdempotencyKey, cmd.SourceLedgerID, transactionIDs[0],
+		cmd.DestinationLedgerID, transactionIDs[1], value, cmd.Currency))
+	if isUniqueViolation(err) {
+		// A concurrent request with the same key posted first
+		tx.Rollback(ctx)
+		return scanTransfer(s.DB.QueryRow(ctx, transferSelect+`
+			WHERE source_ledger_id = $1 AND idempotency_key = $2
+		`, cmd.SourceLedgerID, cmd.IdempotencyKey))
+	}
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Transfer{}, err
+	}
+	for _, p := range posted {
+		s.postCommit(ctx, p)
+	}
+	return transfer, nil
+}
+
+const transferSelect = `
+	SELECT id, source_ledger_id, source_transaction_id, destination_ledger_id, destination_transaction_id,
+		amount::text, currency, created_at
+	FROM transfers
+`
+
+func scanTransfer(row pgx.Row) (Transfer, error) {
+	var t Transfer
+	var createdAt time.Time
+	err := row.Scan(&t.ID, &t.SourceLedgerID, &t.SourceTransactionID, &t.DestinationLedgerID,
+		&t.DestinationTransactionID, &t.Amount, &t.Currency, &createdAt)
+	t.CreatedAt = createdAt.Format(time.RFC3339)
+	return t, err
+}
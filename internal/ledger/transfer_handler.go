@@ -0,0 +1,127 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type PostTransferRequest struct {
+	IdempotencyKey             string         `json:"idempotency_key"`
+	ExternalID                 string         `json:"external_id"`
+	Amount                     string         `json:"amount"`
+	Currency                   string         `json:"currency"`
+	OccurredAt                 time.Time      `json:"occurred_at"`
+	Metadata                   map[string]any `json:"metadata,omitempty"`
+	SourceAccount              string         `json:"source_account"`
+	SourceClearingAccount      string         `json:"source_clearing_account"`
+	DestinationLedgerID        string         `json:"destination_ledger_id"` // a ledger of the same project
+	DestinationAccount         string         `json:"destination_account"`
+	DestinationClearingAccount string         `json:"destination_clearing_account"`
+}
+
+// POST /v1/transfers - Move value from this ledger to another ledger of the project
+func (h *Handler) PostTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PostTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.DestinationLedgerID == "" {
+		api.Error(w, "destination_ledger_id required", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.Service.PostTransfer(ctx, TransferCommand{
+		ProjectID:                  principal.ProjectID,
+		IdempotencyKey:             req.IdempotencyKey,
+		ExternalID:                 req.ExternalID,
+		Amount:                     req.Amount,
+		Currency:                   req.Currency,
+		OccurredAt:                 req.OccurredAt,
+		Metadata:                   req.Metadata,
+		SourceLedgerID:             principal.LedgerID,
+		SourceAccount:              req.SourceAccount,
+		SourceClearingAccount:      req.SourceClearingAccount,
+		DestinationLedgerID:        req.DestinationLedgerID,
+		DestinationAccount:         req.DestinationAccount,
+		DestinationClearingAccount: req.DestinationClearingAccount,
+	})
+	if errors.Is(err, ErrLedgerNotInProject) {
+		api.Error(w, "destination ledger not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writePostTransactionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// GET /v1/transfers?id= - Get a transfer, or list the transfers into and out of this ledger
+func (h *Handler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if id := r.URL.Query().Get("id"); id != "" {
+		transfer, err := scanTransfer(h.Service.DB.QueryRow(ctx, transferSelect+`
+			WHERE id::text = $1 AND (source_ledger_id = $2 OR destination_ledger_id = $2)
+		`, id, principal.LedgerID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			api.Error(w, "transfer not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			api.Error(w, "failed to load transfer", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transfer)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, transferSelect+`
+		WHERE source_ledger_id = $1 OR destination_ledger_id = $1
+		ORDER BY created_at DESC
+		LIMIT 500
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query transfers", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	transfers := []Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			api.Error(w, "failed to scan transfer", http.StatusInternalServerError)
+			return
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
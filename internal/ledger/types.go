@@ -1,20 +1,67 @@
 package ledger
 
-import "time"
+import (
+	"Go_FormanceLegder/internal/calendar"
+	"time"
+)
 
 type PostingInput struct {
 	AccountCode string `json:"account_code"`
 	Direction   string `json:"direction"`
 	Amount      string `json:"amount"`
+	Currency    string `json:"currency,omitempty"` // defaults to the transaction currency
+	TaxCode     string `json:"tax_code,omitempty"` // defaults to the account's tax code
+	Description string `json:"description,omitempty"`
+}
+
+// BalanceAssertion is a condition on an account's balance after the transaction, checked
+// while the account is locked against a balance that counts the events the projector
+// has not applied yet. Bounds left empty are not checked.
+type BalanceAssertion struct {
+	Account    string `json:"account"`
+	BalanceGTE string `json:"balance_gte,omitempty"`
+	BalanceLTE string `json:"balance_lte,omitempty"`
+	BalanceEQ  string `json:"balance_eq,omitempty"`
 }
 
 type PostTransactionCommand struct {
 	LedgerID       string
 	ExternalID     string
+	Description    string // human-readable, e.g. "Refund for order 1234"
 	IdempotencyKey string
 	Currency       string
 	Postings       []PostingInput
 	OccurredAt     time.Time
+	ValueDate      time.Time // date the transaction takes effect; zero is OccurredAt's (UTC) date
+	Metadata       map[string]any
+	EntityCode     string // counterparty the transaction belongs to (see entities)
+	Assertions     []BalanceAssertion
+
+	// skipScreening is set when posting an approved review, or funds already reserved
+	skipScreening bool
+
+	// capturing is the hold the transaction captures, whose held amount is released
+	// with it and so no longer counts against the held account
+	capturing *holdState
+
+	// preValidated is set when the pre-validate hooks ran already, on a review stored
+	// after them
+	preValidated bool
+
+	// explain, when set, records how validation went (see Explanation)
+	explain *Explanation
+
+	// Script, when set, is compiled into Postings (see package script)
+	Script string
+	Vars   map[string]string
+}
+
+// valueDate is the date the transaction takes effect, by default the day it occurred.
+func (cmd PostTransactionCommand) valueDate() time.Time {
+	if cmd.ValueDate.IsZero() {
+		return calendar.Date(cmd.OccurredAt.UTC())
+	}
+	return calendar.Date(cmd.ValueDate)
 }
 
 type Account struct {
@@ -22,4 +69,16 @@ type Account struct {
 	Code    string
 	Type    string
 	Balance string
+
+	// HeldBalance is reserved by pending holds; postings that lower the balance are
+	// checked against Balance less HeldBalance
+	HeldBalance string
+
+	// AllowNegativeBalance false means postings may not take Balance below
+	// MinBalance, or below zero when MinBalance is empty
+	AllowNegativeBalance bool
+	MinBalance           string
+
+	EntityCode string // linked entity, whose KYC status applies to the account
+	Disabled   bool
 }
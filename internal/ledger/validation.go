@@ -1,53 +1,296 @@
 package ledger
 
 import (
+	"Go_FormanceLegder/internal/calendar"
 	"fmt"
 	"math/big"
+	"sort"
+	"time"
 )
 
-func validateDoubleEntry(cmd PostTransactionCommand, accounts map[string]Account) error {
+// Error codes of transactions refused by the ledger's rules.
+const (
+	CodeAccountNotFound   = "ACCOUNT_NOT_FOUND"
+	CodeAccountDisabled   = "ACCOUNT_DISABLED"
+	CodeInsufficientFunds = "INSUFFICIENT_FUNDS"
+	CodeKYCRequired       = "KYC_REQUIRED"
+	CodeAssertionFailed   = "ASSERTION_FAILED"
+	CodeScreeningDenied   = "SCREENING_DENIED"
+	CodeHookRejected      = "HOOK_REJECTED"
+	CodeRuleViolated      = "RULE_VIOLATED"
+)
+
+// ValidationError refuses a malformed command: missing fields, invalid amounts,
+// unbalanced postings and the like. Errors of other types are the ledger's, not the
+// caller's.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// invalidf returns a ValidationError with the formatted message.
+func invalidf(format string, args ...any) error {
+	return &ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+// AccountError rejects a posting to an account that doesn't exist or is disabled.
+type AccountError struct {
+	AccountCode string
+	Disabled    bool // otherwise not found
+}
+
+func (e *AccountError) Error() string {
+	if e.Disabled {
+		return fmt.Sprintf("account %s is disabled", e.AccountCode)
+	}
+	return fmt.Sprintf("account %s not found", e.AccountCode)
+}
+
+func (e *AccountError) Code() string {
+	if e.Disabled {
+		return CodeAccountDisabled
+	}
+	return CodeAccountNotFound
+}
+
+// InsufficientFundsError rejects a posting that would take a constrained account below
+// its minimum balance.
+type InsufficientFundsError struct {
+	AccountCode string
+	Balance     string // available balance after the posting: the balance less held amounts
+	MinBalance  string
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("account %s would be overdrawn: balance %s below minimum %s", e.AccountCode, e.Balance, e.MinBalance)
+}
+
+// Descriptions are for people; longer text belongs in metadata or notes.
+const maxDescriptionLength = 1000
+
+// validateDescriptions bounds the transaction's and its postings' descriptions.
+func validateDescriptions(cmd PostTransactionCommand) error {
+	if len(cmd.Description) > maxDescriptionLength {
+		return invalidf("description must be at most %d bytes", maxDescriptionLength)
+	}
+	for i, p := range cmd.Postings {
+		if len(p.Description) > maxDescriptionLength {
+			return invalidf("posting %d: description must be at most %d bytes", i, maxDescriptionLength)
+		}
+	}
+	return nil
+}
+
+// A transaction may be back- or forward-valued by at most this many days.
+const maxValueDateOffset = 366
+
+// validateValueDate keeps the value date within maxValueDateOffset days of the day the
+// transaction occurred.
+func validateValueDate(cmd PostTransactionCommand) error {
+	offset := cmd.valueDate().Sub(calendar.Date(cmd.OccurredAt.UTC())) / (24 * time.Hour)
+	if offset > maxValueDateOffset || offset < -maxValueDateOffset {
+		return invalidf("value_date must be within %d days of occurred_at", maxValueDateOffset)
+	}
+	return nil
+}
+
+// validateDoubleEntry checks the postings balance per currency. Amounts in a registered
+// currency may not exceed its precision.
+func validateDoubleEntry(cmd PostTransactionCommand, accounts map[string]Account, currencies map[string]Currency) error {
 	if len(cmd.Postings) < 2 {
-		return fmt.Errorf("transaction must have at least 2 postings")
+		return invalidf("transaction must have at least 2 postings")
 	}
 
 	// Group by currency and sum debits/credits
-	totalDebits := new(big.Rat)
-	totalCredits := new(big.Rat)
+	totalDebits := map[string]*big.Rat{}
+	totalCredits := map[string]*big.Rat{}
 
 	for _, p := range cmd.Postings {
-		// Verify account exists
-		if _, ok := accounts[p.AccountCode]; !ok {
-			return fmt.Errorf("account %s not found", p.AccountCode)
+		// Verify account exists and accepts postings
+		acc, ok := accounts[p.AccountCode]
+		if !ok {
+			return &AccountError{AccountCode: p.AccountCode}
+		}
+		if acc.Disabled {
+			return &AccountError{AccountCode: p.AccountCode, Disabled: true}
 		}
 
 		// Verify direction
 		if p.Direction != "debit" && p.Direction != "credit" {
-			return fmt.Errorf("invalid direction: %s", p.Direction)
+			return invalidf("invalid direction: %s", p.Direction)
 		}
 
 		// Parse amount
 		amount := new(big.Rat)
 		if _, ok := amount.SetString(p.Amount); !ok {
-			return fmt.Errorf("invalid amount: %s", p.Amount)
+			return invalidf("invalid amount: %s", p.Amount)
 		}
 
 		// Check positive
 		if amount.Sign() <= 0 {
-			return fmt.Errorf("amount must be positive: %s", p.Amount)
+			return invalidf("amount must be positive: %s", p.Amount)
+		}
+
+		currency := postingCurrency(cmd, p)
+		if c, ok := currencies[currency]; ok && !fitsPrecision(amount, c.Precision) {
+			return invalidf("amount %s exceeds %s precision of %d decimal places", p.Amount, currency, c.Precision)
 		}
 
 		// Accumulate
+		if totalDebits[currency] == nil {
+			totalDebits[currency] = new(big.Rat)
+			totalCredits[currency] = new(big.Rat)
+		}
 		if p.Direction == "debit" {
-			totalDebits.Add(totalDebits, amount)
+			totalDebits[currency].Add(totalDebits[currency], amount)
 		} else {
-			totalCredits.Add(totalCredits, amount)
+			totalCredits[currency].Add(totalCredits[currency], amount)
 		}
 	}
 
-	// Verify balance
-	if totalDebits.Cmp(totalCredits) != 0 {
-		return fmt.Errorf("debits (%s) must equal credits (%s)", totalDebits.FloatString(10), totalCredits.FloatString(10))
+	// Verify balance per currency
+	codes := make([]string, 0, len(totalDebits))
+	for c := range totalDebits {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	for _, c := range codes {
+		if totalDebits[c].Cmp(totalCredits[c]) != 0 {
+			return invalidf("debits (%s) must equal credits (%s) in %s", totalDebits[c].FloatString(10), totalCredits[c].FloatString(10), c)
+		}
+	}
+
+	return nil
+}
+
+func postingCurrency(cmd PostTransactionCommand, p PostingInput) string {
+	if p.Currency != "" {
+		return p.Currency
+	}
+	return cmd.Currency
+}
+
+// AssertionError rejects a transaction whose balance assertion does not hold.
+type AssertionError struct {
+	Account   string
+	Condition string // e.g. "balance_gte 100"
+	Balance   string // balance after the transaction
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("assertion failed: account %s %s, balance would be %s", e.Account, e.Condition, e.Balance)
+}
+
+// validateBalanceConstraints rejects postings that lower a constrained account's balance
+// below its minimum. Postings that raise the balance are always accepted, so an account
+// already below its minimum can still be topped up.
+func validateBalanceConstraints(postings []PostingInput, accounts map[string]Account) error {
+	checks, err := balanceChecks(postings, accounts)
+	if err != nil {
+		return err
+	}
+	for _, c := range checks {
+		if c.Result == CheckFailed {
+			return &InsufficientFundsError{AccountCode: c.AccountCode, Balance: c.Available, MinBalance: c.MinBalance}
+		}
+	}
+	return nil
+}
+
+// BalanceCheck is the minimum balance check of one account a transaction moves.
+type BalanceCheck struct {
+	BalanceImpact
+	HeldBalance string `json:"held_balance,omitempty"` // reserved by pending holds
+	Available   string `json:"available,omitempty"`    // After less HeldBalance, checked against the floor
+	MinBalance  string `json:"min_balance,omitempty"`  // the floor checked against
+	Result      string `json:"result"`
+	Reason      string `json:"reason,omitempty"` // why the check was skipped
+}
+
+// balanceChecks checks every account the postings move against its minimum balance;
+// amounts held on an account are not available to lower its balance.
+func balanceChecks(postings []PostingInput, accounts map[string]Account) ([]BalanceCheck, error) {
+	impacts := balanceImpact(postings, accounts)
+	checks := make([]BalanceCheck, 0, len(impacts))
+	for _, impact := range impacts {
+		acc := accounts[impact.AccountCode]
+		check := BalanceCheck{BalanceImpact: impact, Result: CheckPassed}
+		change, _ := new(big.Rat).SetString(impact.Change)
+		switch {
+		case acc.AllowNegativeBalance:
+			check.Result, check.Reason = CheckSkipped, "negative balance allowed"
+		case change.Sign() >= 0:
+			check.Result, check.Reason = CheckSkipped, "balance not lowered"
+		default:
+			floor := new(big.Rat)
+			if acc.MinBalance != "" {
+				if _, ok := floor.SetString(acc.MinBalance); !ok {
+					return nil, fmt.Errorf("account %s has invalid min_balance %s", acc.Code, acc.MinBalance)
+				}
+			}
+			check.MinBalance = floor.FloatString(10)
+			available, _ := new(big.Rat).SetString(impact.After)
+			if held, ok := new(big.Rat).SetString(acc.HeldBalance); ok && held.Sign() != 0 {
+				check.HeldBalance = held.FloatString(10)
+				available.Sub(available, held)
+			}
+			check.Available = available.FloatString(10)
+			if available.Cmp(floor) < 0 {
+				check.Result = CheckFailed
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// checkAssertions evaluates balance assertions against the balances the postings leave,
+// from the locked accounts' balances including unprojected events (see addUnprojected).
+func checkAssertions(assertions []BalanceAssertion, postings []PostingInput, accounts map[string]Account) error {
+	if len(assertions) == 0 {
+		return nil
 	}
 
+	after := map[string]string{}
+	for code, acc := range accounts {
+		after[code] = acc.Balance
+	}
+	for _, impact := range balanceImpact(postings, accounts) {
+		after[impact.AccountCode] = impact.After
+	}
+
+	for _, a := range assertions {
+		balance, ok := new(big.Rat).SetString(after[a.Account])
+		if !ok {
+			return invalidf("assertion: account %s not found", a.Account)
+		}
+		checks := []struct {
+			name, bound string
+			holds       func(cmp int) bool
+		}{
+			{"balance_gte", a.BalanceGTE, func(cmp int) bool { return cmp >= 0 }},
+			{"balance_lte", a.BalanceLTE, func(cmp int) bool { return cmp <= 0 }},
+			{"balance_eq", a.BalanceEQ, func(cmp int) bool { return cmp == 0 }},
+		}
+		checked := false
+		for _, c := range checks {
+			if c.bound == "" {
+				continue
+			}
+			bound, ok := new(big.Rat).SetString(c.bound)
+			if !ok {
+				return invalidf("assertion: invalid %s %s", c.name, c.bound)
+			}
+			if !c.holds(balance.Cmp(bound)) {
+				return &AssertionError{Account: a.Account, Condition: c.name + " " + c.bound, Balance: balance.FloatString(10)}
+			}
+			checked = true
+		}
+		if !checked {
+			return invalidf("assertion on %s has no condition", a.Account)
+		}
+	}
 	return nil
 }
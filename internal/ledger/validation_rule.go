@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/calendar"
+	"Go_FormanceLegder/internal/rules"
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// enforceValidationRules checks the command against the ledger's own rules, with the
+// balances its postings leave on the locked accounts.
+func enforceValidationRules(ctx context.Context, tx pgx.Tx, cmd PostTransactionCommand, accounts map[string]Account) error {
+	policy, err := loadValidationRules(ctx, tx, cmd.LedgerID)
+	if err != nil || len(policy.Rules) == 0 {
+		return err
+	}
+	return policy.Check(ctx, ruleInput(cmd, accounts))
+}
+
+func loadValidationRules(ctx context.Context, tx pgx.Tx, ledgerID string) (rules.Policy, error) {
+	var policy rules.Policy
+	err := tx.QueryRow(ctx, `SELECT rules FROM validation_rules WHERE ledger_id = $1`, ledgerID).Scan(&policy)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// ruleInput is what validation rules see of a validated command (see package rules).
+func ruleInput(cmd PostTransactionCommand, accounts map[string]Account) rules.Input {
+	metadata := cmd.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	postings := make([]any, len(cmd.Postings))
+	for i, p := range cmd.Postings {
+		postings[i] = map[string]any{
+			"account":   p.AccountCode,
+			"direction": p.Direction,
+			"amount":    decimalValue(p.Amount),
+			"currency":  postingCurrency(cmd, p),
+			"tax_code":  p.TaxCode,
+		}
+	}
+
+	after := map[string]string{}
+	for _, impact := range balanceImpact(cmd.Postings, accounts) {
+		after[impact.AccountCode] = impact.After
+	}
+	inputAccounts := map[string]any{}
+	for code, a := range accounts {
+		balanceAfter, moved := after[code]
+		if !moved {
+			balanceAfter = a.Balance
+		}
+		account := map[string]any{
+			"type":                   a.Type,
+			"balance":                decimalValue(a.Balance),
+			"balance_after":          decimalValue(balanceAfter),
+			"allow_negative_balance": a.AllowNegativeBalance,
+			"entity":                 a.EntityCode,
+		}
+		if a.MinBalance != "" {
+			account["min_balance"] = decimalValue(a.MinBalance)
+		}
+		inputAccounts[code] = account
+	}
+
+	return rules.Input{
+		Transaction: map[string]any{
+			"external_id": cmd.ExternalID,
+			"description": cmd.Description,
+			"currency":    cmd.Currency,
+			"occurred_at": cmd.OccurredAt.UTC(),
+			"value_date":  calendar.FormatDate(cmd.valueDate()),
+			"entity":      cmd.EntityCode,
+			"metadata":    metadata,
+			"postings":    postings,
+		},
+		Accounts: inputAccounts,
+	}
+}
+
+func decimalValue(s string) float64 {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0
+	}
+	f, _ := r.Float64()
+	return f
+}
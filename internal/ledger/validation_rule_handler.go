@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/rules"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GET /v1/validation-rules - The ledger's own validation rules
+func (h *Handler) GetValidationRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	policy := rules.Policy{Rules: []rules.Rule{}}
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT rules FROM validation_rules WHERE ledger_id = $1
+	`, principal.LedgerID).Scan(&policy)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "failed to load validation rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// PUT /v1/validation-rules - Replace the ledger's own validation rules
+//
+// Rules are CEL expressions every transaction must satisfy, evaluated in order after the
+// ledger's checks (see package rules). Each is compiled before any is saved; try them
+// with a dry run and explain=true.
+func (h *Handler) SetValidationRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var policy rules.Policy
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policy); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if policy.Rules == nil {
+		policy.Rules = []rules.Rule{}
+	}
+	if err := policy.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO validation_rules (ledger_id, rules)
+		VALUES ($1, $2)
+		ON CONFLICT (ledger_id) DO UPDATE SET rules = EXCLUDED.rules, updated_at = NOW()
+	`, principal.LedgerID, policy)
+	if err != nil {
+		api.Error(w, "failed to save validation rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
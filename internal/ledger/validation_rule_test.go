@@ -0,0 +1,40 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/rules"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestValidationRulesSeeBalancesAfter(t *testing.T) {
+	accounts := map[string]Account{
+		"customers:alice": {Code: "customers:alice", Type: "asset", Balance: "100", MinBalance: "-50"},
+		"revenue":         {Code: "revenue", Type: "revenue", Balance: "0"},
+	}
+	cmd := PostTransactionCommand{
+		Currency:   "USD",
+		OccurredAt: time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC),
+		Metadata:   map[string]any{"channel": "web"},
+		Postings: []PostingInput{
+			{AccountCode: "revenue", Direction: "debit", Amount: "30.5"},
+			{AccountCode: "customers:alice", Direction: "credit", Amount: "30.5"},
+		},
+	}
+	policy := rules.Policy{Rules: []rules.Rule{
+		{Name: "alice", Expression: `accounts["customers:alice"].balance_after == 130.5 && accounts["customers:alice"].min_balance == -50`},
+		{Name: "channel", Expression: `transaction.metadata.channel == "web" && transaction.value_date == "2026-01-31"`},
+		{Name: "small", Expression: `transaction.postings.all(p, p.amount < 30)`, Message: "amounts must be below 30"},
+	}}
+
+	var v *rules.Violation
+	err := policy.Check(context.Background(), ruleInput(cmd, accounts))
+	if !errors.As(err, &v) || v.Rule != "small" {
+		t.Fatalf("expected only the small rule to refuse, got %v", err)
+	}
+	if status, code := postTransactionError(err); status != http.StatusUnprocessableEntity || code != CodeRuleViolated {
+		t.Errorf("violation replied %d %s", status, code)
+	}
+}
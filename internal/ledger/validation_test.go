@@ -0,0 +1,172 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestValidateBalanceConstraints(t *testing.T) {
+	accounts := map[string]Account{
+		"wallet":    {Code: "wallet", Balance: "50"},
+		"credit":    {Code: "credit", Balance: "0", MinBalance: "-100"},
+		"overdrawn": {Code: "overdrawn", Balance: "-10"},
+		"held":      {Code: "held", Balance: "50", HeldBalance: "20"},
+		"world":     {Code: "world", Balance: "0", AllowNegativeBalance: true},
+	}
+	transfer := func(from, to, amount string) []PostingInput {
+		return []PostingInput{
+			{AccountCode: from, Direction: "debit", Amount: amount},
+			{AccountCode: to, Direction: "credit", Amount: amount},
+		}
+	}
+
+	if err := validateBalanceConstraints(transfer("wallet", "world", "50"), accounts); err != nil {
+		t.Fatalf("expected spending the full balance to pass: %v", err)
+	}
+
+	var insufficient *InsufficientFundsError
+	err := validateBalanceConstraints(transfer("wallet", "world", "50.01"), accounts)
+	if !errors.As(err, &insufficient) || insufficient.AccountCode != "wallet" {
+		t.Fatalf("expected wallet to be overdrawn, got %v", err)
+	}
+
+	if err := validateBalanceConstraints(transfer("credit", "world", "100"), accounts); err != nil {
+		t.Fatalf("expected the overdraft limit to allow -100: %v", err)
+	}
+	if err := validateBalanceConstraints(transfer("credit", "world", "101"), accounts); err == nil {
+		t.Fatal("expected the overdraft limit to be enforced")
+	}
+
+	// Held amounts are not available
+	if err := validateBalanceConstraints(transfer("held", "world", "30"), accounts); err != nil {
+		t.Fatalf("expected the available balance to be spendable: %v", err)
+	}
+	err = validateBalanceConstraints(transfer("held", "world", "30.01"), accounts)
+	if !errors.As(err, &insufficient) || insufficient.Balance != "-0.0100000000" {
+		t.Fatalf("expected held funds to be refused, got %v", err)
+	}
+
+	// Topping up an account that is already below its minimum is allowed
+	if err := validateBalanceConstraints(transfer("world", "overdrawn", "5"), accounts); err != nil {
+		t.Fatalf("expected a credit to pass: %v", err)
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	accounts := map[string]Account{
+		"cash":    {Code: "cash", Balance: "150"},
+		"revenue": {Code: "revenue", Balance: "0"},
+		"audit":   {Code: "audit", Balance: "7"},
+	}
+	postings := []PostingInput{
+		{AccountCode: "cash", Direction: "debit", Amount: "50"},
+		{AccountCode: "revenue", Direction: "credit", Amount: "50"},
+	}
+
+	ok := []BalanceAssertion{
+		{Account: "cash", BalanceGTE: "100.00"},
+		{Account: "revenue", BalanceEQ: "50", BalanceLTE: "50"},
+		{Account: "audit", BalanceEQ: "7"}, // locked but not posted to
+	}
+	if err := checkAssertions(ok, postings, accounts); err != nil {
+		t.Fatalf("expected assertions to hold: %v", err)
+	}
+
+	var failed *AssertionError
+	err := checkAssertions([]BalanceAssertion{{Account: "cash", BalanceGTE: "100.01"}}, postings, accounts)
+	if !errors.As(err, &failed) || failed.Account != "cash" {
+		t.Fatalf("expected cash assertion to fail, got %v", err)
+	}
+
+	if err := checkAssertions([]BalanceAssertion{{Account: "cash"}}, postings, accounts); err == nil {
+		t.Fatal("expected an assertion without a condition to be rejected")
+	}
+}
+
+func TestValidateDescriptions(t *testing.T) {
+	long := strings.Repeat("x", maxDescriptionLength+1)
+	cmd := PostTransactionCommand{
+		Description: "Refund for order 1234",
+		Postings: []PostingInput{
+			{AccountCode: "cash", Direction: "debit", Amount: "10", Description: "Card refund"},
+			{AccountCode: "revenue", Direction: "credit", Amount: "10"},
+		},
+	}
+	if err := validateDescriptions(cmd); err != nil {
+		t.Fatalf("expected descriptions to pass: %v", err)
+	}
+
+	cmd.Postings[1].Description = long
+	if err := validateDescriptions(cmd); err == nil || !strings.Contains(err.Error(), "posting 1") {
+		t.Fatalf("expected the posting's description to be rejected, got %v", err)
+	}
+	cmd.Postings[1].Description = ""
+	cmd.Description = long
+	if err := validateDescriptions(cmd); err == nil {
+		t.Fatal("expected the transaction's description to be rejected")
+	}
+}
+
+func TestValidateValueDate(t *testing.T) {
+	occurredAt := time.Date(2024, 3, 1, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	cmd := PostTransactionCommand{OccurredAt: occurredAt}
+	if got := cmd.valueDate(); !got.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the value date to default to occurred_at's UTC date, got %v", got)
+	}
+
+	for _, tt := range []struct {
+		valueDate time.Time
+		ok        bool
+	}{
+		{time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC), false},
+	} {
+		cmd.ValueDate = tt.valueDate
+		if err := validateValueDate(cmd); (err == nil) != tt.ok {
+			t.Errorf("value date %s: got %v, want ok=%v", tt.valueDate.Format("2006-01-02"), err, tt.ok)
+		}
+	}
+}
+
+func TestPostTransactionErrorStatus(t *testing.T) {
+	unbalanced := validateDoubleEntry(PostTransactionCommand{Currency: "USD", Postings: []PostingInput{
+		{AccountCode: "cash", Direction: "debit", Amount: "10"},
+		{AccountCode: "revenue", Direction: "credit", Amount: "9"},
+	}}, map[string]Account{"cash": {Code: "cash"}, "revenue": {Code: "revenue"}}, nil)
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"unbalanced", unbalanced, http.StatusBadRequest},
+		{"wrapped validation", fmt.Errorf("step 1: %w", invalidf("invalid amount: x")), http.StatusBadRequest},
+		{"unknown account", &AccountError{AccountCode: "cash"}, http.StatusUnprocessableEntity},
+		{"write fenced", &pgconn.PgError{Code: "25006"}, http.StatusServiceUnavailable},
+		{"serialization failure", &pgconn.PgError{Code: "40001", Message: "could not serialize access"}, http.StatusInternalServerError},
+		{"canceled", context.Canceled, http.StatusInternalServerError},
+		{"scan", fmt.Errorf("can't scan into dest[0]: %w", errors.New("cannot scan NULL")), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if status, _ := postTransactionError(c.err); status != c.status {
+			t.Errorf("%s: got %d, want %d", c.name, status, c.status)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	writePostTransactionError(rec, &pgconn.PgError{Code: "40001", Message: "could not serialize access"})
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "serialize") || !strings.Contains(rec.Body.String(), api.CodeInternal) {
+		t.Errorf("expected a generic internal error, got %d %s", rec.Code, rec.Body)
+	}
+}
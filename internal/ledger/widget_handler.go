@@ -0,0 +1,230 @@
+package ledger
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/widget"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultWidgetTTL      = time.Hour
+	maxWidgetTTL          = 30 * 24 * time.Hour
+	maxStatementEntries   = 1000
+	defaultStatementRange = 30 * 24 * time.Hour
+)
+
+type CreateWidgetRequest struct {
+	Kind      string    `json:"kind"` // balance or statement
+	Account   string    `json:"account"`
+	ExpiresIn int       `json:"expires_in,omitempty"` // seconds, default 3600, at most 30 days
+	From      time.Time `json:"from,omitempty"`       // statement period, default the 30 days before creation
+	To        time.Time `json:"to,omitempty"`
+}
+
+type CreateWidgetResponse struct {
+	Token     string `json:"token"`
+	URL       string `json:"url"` // relative to the API host
+	ExpiresAt string `json:"expires_at"`
+}
+
+type BalanceWidget struct {
+	Account          string `json:"account"`
+	Name             string `json:"name"`
+	Balance          string `json:"balance"`
+	AvailableBalance string `json:"available_balance"`
+	AsOf             string `json:"as_of"`
+}
+
+type StatementWidget struct {
+	Account        string           `json:"account"`
+	Name           string           `json:"name"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	OpeningBalance string           `json:"opening_balance"`
+	ClosingBalance string           `json:"closing_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	Truncated      bool             `json:"truncated,omitempty"` // more than 1000 entries in the period
+}
+
+type StatementEntry struct {
+	OccurredAt    string `json:"occurred_at"`
+	TransactionID string `json:"transaction_id"`
+	ExternalID    string `json:"external_id,omitempty"`
+	Description   string `json:"description,omitempty"` // the posting's, else the transaction's
+	Direction     string `json:"direction"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	Balance       string `json:"balance"` // running balance after the entry
+}
+
+// POST /v1/widgets - Sign an expiring public URL showing an account's balance or statement
+func (h *Handler) CreateWidget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateWidgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Kind != widget.KindBalance && req.Kind != widget.KindStatement {
+		api.Error(w, "kind must be balance or statement", http.StatusBadRequest)
+		return
+	}
+	ttl := defaultWidgetTTL
+	if req.ExpiresIn != 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl <= 0 || ttl > maxWidgetTTL {
+		api.Error(w, "expires_in must be between 1 second and 30 days", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM accounts WHERE ledger_id = $1 AND code = $2)
+	`, principal.LedgerID, req.Account).Scan(&exists)
+	if err != nil || !exists {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	claims := widget.Claims{
+		LedgerID:  principal.LedgerID,
+		Kind:      req.Kind,
+		Account:   req.Account,
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	if req.Kind == widget.KindStatement {
+		if req.To.IsZero() {
+			req.To = now
+		}
+		if req.From.IsZero() {
+			req.From = req.To.Add(-defaultStatementRange)
+		}
+		if !req.From.Before(req.To) {
+			api.Error(w, "from must be before to", http.StatusBadRequest)
+			return
+		}
+		claims.From, claims.To = req.From.Unix(), req.To.Unix()
+	}
+
+	token, err := widget.Sign(h.WidgetSecret, claims)
+	if err != nil {
+		api.Error(w, "failed to sign widget", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CreateWidgetResponse{
+		Token:     token,
+		URL:       "/public/widgets?token=" + url.QueryEscape(token),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339),
+	})
+}
+
+// GET /public/widgets?token= - The balance or statement a widget token grants (no other auth)
+//
+// The caller verifies the token; claims are trusted as signed.
+func (h *Handler) ServeWidget(w http.ResponseWriter, r *http.Request, c widget.Claims) {
+	ctx := r.Context()
+
+	var accountID, name, balance, available string
+	err := h.Service.DB.QueryRow(ctx, `
+		SELECT id, name, balance::text, (balance - held_balance)::text
+		FROM accounts
+		WHERE ledger_id = $1 AND code = $2
+	`, c.LedgerID, c.Account).Scan(&accountID, &name, &balance, &available)
+	if err != nil {
+		api.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	// Embedding pages call from any origin; tokens are the only credential
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+
+	if c.Kind == widget.KindBalance {
+		json.NewEncoder(w).Encode(BalanceWidget{
+			Account:          c.Account,
+			Name:             name,
+			Balance:          balance,
+			AvailableBalance: available,
+			AsOf:             time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	from, to := time.Unix(c.From, 0).UTC(), time.Unix(c.To, 0).UTC()
+	var opening string
+	err = h.Service.DB.QueryRow(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END), 0)::text
+		FROM postings p
+		JOIN transactions t ON t.id = p.transaction_id AND t.ledger_id = p.ledger_id
+		WHERE p.account_id = $1 AND t.occurred_at < $2
+	`, accountID, from).Scan(&opening)
+	if err != nil {
+		api.Error(w, "failed to compute opening balance", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT t.occurred_at, t.id::text, t.external_id, COALESCE(p.description, t.description, ''), p.direction,
+			p.amount::text, COALESCE(p.currency, t.currency)
+		FROM postings p
+		JOIN transactions t ON t.id = p.transaction_id AND t.ledger_id = p.ledger_id
+		WHERE p.account_id = $1 AND t.occurred_at >= $2 AND t.occurred_at < $3
+		ORDER BY t.occurred_at, t.id
+		LIMIT $4
+	`, accountID, from, to, maxStatementEntries+1)
+	if err != nil {
+		api.Error(w, "failed to query statement", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	running, _ := new(big.Rat).SetString(opening)
+	statement := StatementWidget{
+		Account:        c.Account,
+		Name:           name,
+		From:           from.Format(time.RFC3339),
+		To:             to.Format(time.RFC3339),
+		OpeningBalance: running.FloatString(10),
+		Entries:        []StatementEntry{},
+	}
+	for rows.Next() {
+		if len(statement.Entries) == maxStatementEntries {
+			statement.Truncated = true
+			break
+		}
+		var e StatementEntry
+		var occurredAt time.Time
+		if err := rows.Scan(&occurredAt, &e.TransactionID, &e.ExternalID, &e.Description, &e.Direction, &e.Amount, &e.Currency); err != nil {
+			api.Error(w, "failed to scan statement", http.StatusInternalServerError)
+			return
+		}
+		amount, _ := new(big.Rat).SetString(e.Amount)
+		if e.Direction == "debit" {
+			amount.Neg(amount)
+		}
+		running.Add(running, amount)
+		e.OccurredAt = occurredAt.Format(time.RFC3339)
+		e.Balance = running.FloatString(10)
+		statement.Entries = append(statement.Entries, e)
+	}
+	statement.ClosingBalance = running.FloatString(10)
+
+	json.NewEncoder(w).Encode(statement)
+}
@@ -0,0 +1,389 @@
+// Package ledgerdiff compares two ledgers, possibly in different databases, account by
+// account and transaction by transaction, e.g. a production ledger and its replay in a
+// test environment, or a ledger before and after a migration.
+//
+// Accounts are matched by code and transactions by external id, falling back to their
+// id when they have none, since a replayed ledger generates new ids.
+package ledgerdiff
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultLimit is how many differences of each kind a report lists by default.
+const DefaultLimit = 1000
+
+// Side is one of the compared ledgers.
+type Side struct {
+	DB       *pgxpool.Pool
+	LedgerID string
+}
+
+type Options struct {
+	// From and To, when set, restrict the compared transactions to those that occurred
+	// in [From, To). Accounts are always compared in full.
+	From, To time.Time
+	// Limit caps the differences listed per kind; the summary still counts them all.
+	Limit int
+}
+
+// FieldDiff is a field whose value differs between the two sides.
+type FieldDiff struct {
+	Field string `json:"field"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// Statuses of a differing account or transaction.
+const (
+	OnlyLeft  = "only_left"
+	OnlyRight = "only_right"
+	Changed   = "changed"
+)
+
+type AccountDiff struct {
+	Code   string      `json:"code"`
+	Status string      `json:"status"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+type TransactionDiff struct {
+	Key     string      `json:"key"` // external id, or id:<id> without one
+	LeftID  string      `json:"left_id,omitempty"`
+	RightID string      `json:"right_id,omitempty"`
+	Status  string      `json:"status"`
+	Fields  []FieldDiff `json:"fields,omitempty"`
+	// Postings lists the postings found on only one of the sides
+	Postings []PostingDiff `json:"postings,omitempty"`
+}
+
+type PostingDiff struct {
+	Side string `json:"side"` // left or right
+	Posting
+}
+
+type Posting struct {
+	Account   string `json:"account"`
+	Direction string `json:"direction"`
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	TaxCode   string `json:"tax_code,omitempty"`
+}
+
+type Summary struct {
+	LeftAccounts          int `json:"left_accounts"`
+	RightAccounts         int `json:"right_accounts"`
+	AccountsDiffering     int `json:"accounts_differing"`
+	LeftTransactions      int `json:"left_transactions"`
+	RightTransactions     int `json:"right_transactions"`
+	TransactionsOnlyLeft  int `json:"transactions_only_left"`
+	TransactionsOnlyRight int `json:"transactions_only_right"`
+	TransactionsChanged   int `json:"transactions_changed"`
+}
+
+// Report is the difference between two ledgers. Truncated is set when a list was cut
+// at Options.Limit.
+type Report struct {
+	LeftLedgerID  string            `json:"left_ledger_id"`
+	RightLedgerID string            `json:"right_ledger_id"`
+	Summary       Summary           `json:"summary"`
+	Accounts      []AccountDiff     `json:"accounts"`
+	Transactions  []TransactionDiff `json:"transactions"`
+	Truncated     bool              `json:"truncated"`
+}
+
+// Matches reports whether the two ledgers hold the same accounts and transactions.
+func (r Report) Matches() bool {
+	return r.Summary.AccountsDiffering == 0 && r.Summary.TransactionsOnlyLeft == 0 &&
+		r.Summary.TransactionsOnlyRight == 0 && r.Summary.TransactionsChanged == 0
+}
+
+// Account is the compared state of an account.
+type Account struct {
+	Code   string
+	Fields map[string]string
+}
+
+// Transaction is the compared state of a transaction.
+type Transaction struct {
+	Key      string
+	ID       string
+	Fields   map[string]string
+	Postings []Posting
+}
+
+// Compare diffs the two ledgers. Each side is read within a single snapshot, so a ledger
+// taking writes during the comparison can't differ from itself.
+func Compare(ctx context.Context, left, right Side, opts Options) (Report, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultLimit
+	}
+	report := Report{LeftLedgerID: left.LedgerID, RightLedgerID: right.LedgerID, Accounts: []AccountDiff{}, Transactions: []TransactionDiff{}}
+
+	leftTx, err := left.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return report, err
+	}
+	defer leftTx.Rollback(ctx)
+	rightTx, err := right.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return report, err
+	}
+	defer rightTx.Rollback(ctx)
+
+	leftAccounts, err := loadAccounts(ctx, leftTx, left.LedgerID)
+	if err != nil {
+		return report, err
+	}
+	rightAccounts, err := loadAccounts(ctx, rightTx, right.LedgerID)
+	if err != nil {
+		return report, err
+	}
+	report.Summary.LeftAccounts, report.Summary.RightAccounts = len(leftAccounts), len(rightAccounts)
+	for _, d := range DiffAccounts(leftAccounts, rightAccounts) {
+		report.Summary.AccountsDiffering++
+		if len(report.Accounts) < opts.Limit {
+			report.Accounts = append(report.Accounts, d)
+		} else {
+			report.Truncated = true
+		}
+	}
+
+	leftRows, err := queryTransactions(ctx, leftTx, left.LedgerID, opts)
+	if err != nil {
+		return report, err
+	}
+	defer leftRows.Close()
+	rightRows, err := queryTransactions(ctx, rightTx, right.LedgerID, opts)
+	if err != nil {
+		return report, err
+	}
+	defer rightRows.Close()
+
+	err = MergeTransactions(scanner(leftRows, &report.Summary.LeftTransactions), scanner(rightRows, &report.Summary.RightTransactions),
+		func(d TransactionDiff) {
+			switch d.Status {
+			case OnlyLeft:
+				report.Summary.TransactionsOnlyLeft++
+			case OnlyRight:
+				report.Summary.TransactionsOnlyRight++
+			default:
+				report.Summary.TransactionsChanged++
+			}
+			if len(report.Transactions) < opts.Limit {
+				report.Transactions = append(report.Transactions, d)
+			} else {
+				report.Truncated = true
+			}
+		})
+	return report, err
+}
+
+func loadAccounts(ctx context.Context, tx pgx.Tx, ledgerID string) ([]Account, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT code, name, type, balance::text, held_balance::text, status, COALESCE(entity_code, ''),
+			COALESCE(tax_code, ''), allow_negative_balance::text, COALESCE(min_balance::text, ''), metadata::text
+		FROM accounts
+		WHERE ledger_id = $1
+	`, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []Account
+	for rows.Next() {
+		var code, name, kind, balance, held, status, entity, taxCode, allowNegative, minBalance, metadata string
+		err := rows.Scan(&code, &name, &kind, &balance, &held, &status, &entity, &taxCode, &allowNegative, &minBalance, &metadata)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, Account{Code: code, Fields: map[string]string{
+			"name": name, "type": kind, "balance": decimal(balance), "held_balance": decimal(held), "status": status,
+			"entity_code": entity, "tax_code": taxCode, "allow_negative_balance": allowNegative,
+			"min_balance": decimal(minBalance), "metadata": metadata,
+		}})
+	}
+	return accounts, rows.Err()
+}
+
+// queryTransactions returns a ledger's transactions ordered by key, bytewise so that the
+// order agrees with Go's string comparison in MergeTransactions.
+func queryTransactions(ctx context.Context, tx pgx.Tx, ledgerID string, opts Options) (pgx.Rows, error) {
+	var from, to *time.Time
+	if !opts.From.IsZero() {
+		from = &opts.From
+	}
+	if !opts.To.IsZero() {
+		to = &opts.To
+	}
+	return tx.Query(ctx, `
+		SELECT COALESCE(NULLIF(t.external_id, ''), 'id:' || t.id::text) COLLATE "C", t.id::text, t.amount::text,
+			t.currency, t.occurred_at, t.value_date::text, COALESCE(t.description, ''), COALESCE(t.entity_code, ''),
+			t.metadata::text,
+			COALESCE((
+				SELECT json_agg(json_build_array(a.code, p.direction, p.amount::text, COALESCE(p.currency, t.currency),
+					COALESCE(p.tax_code, '')))
+				FROM postings p
+				JOIN accounts a ON a.id = p.account_id
+				WHERE p.transaction_id = t.id
+			), '[]')
+		FROM transactions t
+		WHERE t.ledger_id = $1
+		  AND ($2::timestamptz IS NULL OR t.occurred_at >= $2)
+		  AND ($3::timestamptz IS NULL OR t.occurred_at < $3)
+		ORDER BY 1, t.id
+	`, ledgerID, from, to)
+}
+
+// scanner iterates over rows from queryTransactions, counting them into n.
+func scanner(rows pgx.Rows, n *int) func() (Transaction, bool, error) {
+	return func() (Transaction, bool, error) {
+		if !rows.Next() {
+			return Transaction{}, false, rows.Err()
+		}
+		var t Transaction
+		var amount, currency, valueDate, description, entity, metadata string
+		var occurredAt time.Time
+		var postings [][5]string
+		err := rows.Scan(&t.Key, &t.ID, &amount, &currency, &occurredAt, &valueDate, &description, &entity, &metadata, &postings)
+		if err != nil {
+			return t, false, err
+		}
+		*n++
+		t.Fields = map[string]string{
+			"amount": decimal(amount), "currency": currency, "occurred_at": occurredAt.UTC().Format(time.RFC3339Nano),
+			"value_date": valueDate, "description": description, "entity_code": entity, "metadata": metadata,
+		}
+		for _, p := range postings {
+			t.Postings = append(t.Postings, Posting{Account: p[0], Direction: p[1], Amount: decimal(p[2]), Currency: p[3], TaxCode: p[4]})
+		}
+		return t, true, nil
+	}
+}
+
+// DiffAccounts returns the accounts that differ between left and right, by code.
+func DiffAccounts(left, right []Account) []AccountDiff {
+	byCode := make(map[string]Account, len(right))
+	for _, a := range right {
+		byCode[a.Code] = a
+	}
+
+	var diffs []AccountDiff
+	for _, l := range left {
+		r, ok := byCode[l.Code]
+		if !ok {
+			diffs = append(diffs, AccountDiff{Code: l.Code, Status: OnlyLeft})
+			continue
+		}
+		delete(byCode, l.Code)
+		if fields := diffFields(l.Fields, r.Fields); len(fields) > 0 {
+			diffs = append(diffs, AccountDiff{Code: l.Code, Status: Changed, Fields: fields})
+		}
+	}
+	for code := range byCode {
+		diffs = append(diffs, AccountDiff{Code: code, Status: OnlyRight})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Code < diffs[j].Code })
+	return diffs
+}
+
+// MergeTransactions walks two transaction streams ordered by key and reports each
+// transaction found on only one side, or whose fields or postings differ. Transactions
+// sharing a key on one side are paired with the other side's in order.
+func MergeTransactions(left, right func() (Transaction, bool, error), report func(TransactionDiff)) error {
+	l, lok, err := left()
+	if err != nil {
+		return err
+	}
+	r, rok, err := right()
+	if err != nil {
+		return err
+	}
+	for lok || rok {
+		switch {
+		case !rok || (lok && l.Key < r.Key):
+			report(TransactionDiff{Key: l.Key, LeftID: l.ID, Status: OnlyLeft})
+			if l, lok, err = left(); err != nil {
+				return err
+			}
+		case !lok || r.Key < l.Key:
+			report(TransactionDiff{Key: r.Key, RightID: r.ID, Status: OnlyRight})
+			if r, rok, err = right(); err != nil {
+				return err
+			}
+		default:
+			if d := diffTransaction(l, r); d.Status != "" {
+				report(d)
+			}
+			if l, lok, err = left(); err != nil {
+				return err
+			}
+			if r, rok, err = right(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func diffTransaction(l, r Transaction) TransactionDiff {
+	d := TransactionDiff{Key: l.Key, LeftID: l.ID, RightID: r.ID, Fields: diffFields(l.Fields, r.Fields)}
+
+	// Postings are compared as multisets; their ids and order are not meaningful
+	counts := map[Posting]int{}
+	for _, p := range l.Postings {
+		counts[p]++
+	}
+	for _, p := range r.Postings {
+		counts[p]--
+	}
+	for _, side := range []struct {
+		name     string
+		postings []Posting
+		sign     int
+	}{{"left", l.Postings, 1}, {"right", r.Postings, -1}} {
+		for _, p := range side.postings {
+			if counts[p]*side.sign > 0 {
+				counts[p] -= side.sign
+				d.Postings = append(d.Postings, PostingDiff{Side: side.name, Posting: p})
+			}
+		}
+	}
+
+	if len(d.Fields) > 0 || len(d.Postings) > 0 {
+		d.Status = Changed
+	}
+	return d
+}
+
+func diffFields(left, right map[string]string) []FieldDiff {
+	var diffs []FieldDiff
+	for field, l := range left {
+		if r := right[field]; r != l {
+			diffs = append(diffs, FieldDiff{Field: field, Left: l, Right: r})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// decimal normalizes a numeric so that equal amounts of different scales compare equal.
+func decimal(s string) string {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return s
+	}
+	s = r.FloatString(10)
+	if strings.Contains(s, ".") {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package ledgerdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func stream(txs ...Transaction) func() (Transaction, bool, error) {
+	return func() (Transaction, bool, error) {
+		if len(txs) == 0 {
+			return Transaction{}, false, nil
+		}
+		t := txs[0]
+		txs = txs[1:]
+		return t, true, nil
+	}
+}
+
+func TestDiffAccounts(t *testing.T) {
+	left := []Account{
+		{Code: "cash", Fields: map[string]string{"balance": "100", "type": "asset"}},
+		{Code: "fees", Fields: map[string]string{"balance": "0", "type": "revenue"}},
+	}
+	right := []Account{
+		{Code: "cash", Fields: map[string]string{"balance": "90", "type": "asset"}},
+		{Code: "bank", Fields: map[string]string{"balance": "0", "type": "asset"}},
+	}
+	want := []AccountDiff{
+		{Code: "bank", Status: OnlyRight},
+		{Code: "cash", Status: Changed, Fields: []FieldDiff{{Field: "balance", Left: "100", Right: "90"}}},
+		{Code: "fees", Status: OnlyLeft},
+	}
+	if got := DiffAccounts(left, right); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeTransactions(t *testing.T) {
+	cash := Posting{Account: "cash", Direction: "debit", Amount: "10", Currency: "EUR"}
+	sales := Posting{Account: "sales", Direction: "credit", Amount: "10", Currency: "EUR"}
+	fees := Posting{Account: "fees", Direction: "credit", Amount: "10", Currency: "EUR"}
+	fields := map[string]string{"amount": "10", "currency": "EUR"}
+
+	left := stream(
+		Transaction{Key: "a", ID: "l1", Fields: fields, Postings: []Posting{cash, sales}},
+		Transaction{Key: "b", ID: "l2", Fields: fields, Postings: []Posting{cash, sales}},
+		Transaction{Key: "c", ID: "l3", Fields: fields, Postings: []Posting{cash, sales}},
+		Transaction{Key: "c", ID: "l4", Fields: fields, Postings: []Posting{cash, sales}},
+	)
+	right := stream(
+		Transaction{Key: "a", ID: "r1", Fields: fields, Postings: []Posting{sales, cash}},
+		Transaction{Key: "b", ID: "r2", Fields: map[string]string{"amount": "10", "currency": "USD"}, Postings: []Posting{cash, fees}},
+		Transaction{Key: "c", ID: "r3", Fields: fields, Postings: []Posting{cash, sales}},
+		Transaction{Key: "d", ID: "r4", Fields: fields, Postings: []Posting{cash, sales}},
+	)
+
+	var got []TransactionDiff
+	if err := MergeTransactions(left, right, func(d TransactionDiff) { got = append(got, d) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []TransactionDiff{
+		{Key: "b", LeftID: "l2", RightID: "r2", Status: Changed,
+			Fields:   []FieldDiff{{Field: "currency", Left: "EUR", Right: "USD"}},
+			Postings: []PostingDiff{{Side: "left", Posting: sales}, {Side: "right", Posting: fees}}},
+		{Key: "c", LeftID: "l4", Status: OnlyLeft},
+		{Key: "d", RightID: "r4", Status: OnlyRight},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	for in, want := range map[string]string{"10.0000000000": "10", "0.5000": "0.5", "-3": "-3", "": ""} {
+		if got := decimal(in); got != want {
+			t.Errorf("decimal(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
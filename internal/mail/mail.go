@@ -0,0 +1,50 @@
+// Package mail sends plain-text notification emails to dashboard users over SMTP.
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Mailer struct {
+	Addr string // host:port; without it messages are only logged
+	From string
+	Auth smtp.Auth
+}
+
+func New(addr, username, password, from string) *Mailer {
+	m := &Mailer{Addr: addr, From: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send emails subject and body to each recipient.
+func (m *Mailer) Send(to []string, subject, body string) error {
+	if len(to) == 0 {
+		return nil
+	}
+	if m.Addr == "" {
+		log.Printf("mail (no SMTP server configured) to %s: %s", strings.Join(to, ", "), subject)
+		return nil
+	}
+	return smtp.SendMail(m.Addr, m.Auth, m.From, to, Message(m.From, to, subject, body, time.Now()))
+}
+
+// Message formats an RFC 5322 plain-text message.
+func Message(from string, to []string, subject, body string, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", "", "\n", " ").Replace(subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
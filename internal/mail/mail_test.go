@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessage(t *testing.T) {
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	msg := string(Message("ledger@localhost", []string{"a@example.com", "b@example.com"},
+		"Deleted\r\nBcc: x@example.com", "line one\nline two", date))
+
+	for _, want := range []string{
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: Deleted Bcc: x@example.com\r\n",
+		"Date: Sun, 01 Mar 2026 12:00:00 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message misses %q:\n%s", want, msg)
+		}
+	}
+}
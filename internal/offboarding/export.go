@@ -0,0 +1,134 @@
+package offboarding
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exportTables are the per-ledger tables in the export bundle. Tables holding secrets,
+// e.g. webhook endpoints, bank feed credentials and API key hashes, are left out.
+var exportTables = []string{
+	"accounts", "transactions", "postings", "holds", "entities", "notes", "events", "event_archives",
+}
+
+// Export writes an organization's export bundle to w as a zip: organization.json with
+// its projects, ledgers and members, then one NDJSON file per table and ledger under
+// projects/<project>/ledgers/<ledger>/, along with the ledger's archived event objects
+// when object storage is configured.
+func (s *Service) Export(ctx context.Context, orgID string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	var summary []byte
+	err := s.Control.QueryRow(ctx, `
+		SELECT json_build_object(
+			'organization', (SELECT row_to_json(o) FROM (SELECT id, name, region, created_at FROM organizations WHERE id = $1) o),
+			'projects', (SELECT COALESCE(json_agg(p ORDER BY p.code), '[]') FROM (
+				SELECT id, name, code, created_at FROM projects WHERE organization_id = $1) p),
+			'ledgers', (SELECT COALESCE(json_agg(l ORDER BY l.code), '[]') FROM (
+				SELECT l.id, l.project_id, l.name, l.code, l.currency, l.created_at
+				FROM ledgers l JOIN projects p ON p.id = l.project_id WHERE p.organization_id = $1) l),
+			'members', (SELECT COALESCE(json_agg(m ORDER BY m.email), '[]') FROM (
+				SELECT u.email, ou.role, ou.created_at AS joined_at
+				FROM org_users ou JOIN users u ON u.id = ou.user_id WHERE ou.organization_id = $1) m)
+		)
+	`, orgID).Scan(&summary)
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create("organization.json")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(summary); err != nil {
+		return err
+	}
+
+	pool := s.Control
+	if s.Router != nil {
+		if _, regional, err := s.Router.ForOrganization(ctx, orgID); err == nil {
+			pool = regional
+		} else {
+			return err
+		}
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT l.id::text, p.code, l.code
+		FROM ledgers l JOIN projects p ON p.id = l.project_id
+		WHERE p.organization_id = $1
+		ORDER BY p.code, l.code
+	`, orgID)
+	if err != nil {
+		return err
+	}
+	type ledgerRef struct{ ID, Project, Code string }
+	ledgers, err := pgx.CollectRows(rows, pgx.RowToStructByPos[ledgerRef])
+	if err != nil {
+		return err
+	}
+
+	for _, l := range ledgers {
+		dir := path.Join("projects", l.Project, "ledgers", l.Code)
+		for _, table := range exportTables {
+			if err := exportTable(ctx, zw, pool, path.Join(dir, table+".ndjson"), table, l.ID); err != nil {
+				return fmt.Errorf("export %s of ledger %s: %w", table, l.ID, err)
+			}
+		}
+		if s.Store == nil {
+			continue
+		}
+		rows, err := pool.Query(ctx, `SELECT object_key FROM event_archives WHERE ledger_id::text = $1 ORDER BY from_sequence`, l.ID)
+		if err != nil {
+			return err
+		}
+		keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			body, err := s.Store.Get(ctx, key)
+			if err != nil {
+				return err
+			}
+			f, err := zw.Create(path.Join(dir, "archives", path.Base(key)))
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(body); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// exportTable writes a ledger's rows of table as NDJSON.
+func exportTable(ctx context.Context, zw *zip.Writer, pool *pgxpool.Pool, name, table, ledgerID string) error {
+	rows, err := pool.Query(ctx, fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t WHERE ledger_id::text = $1`,
+		pgx.Identifier{table}.Sanitize()), ledgerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
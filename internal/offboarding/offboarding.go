@@ -0,0 +1,328 @@
+// Package offboarding deletes organizations on request: an owner schedules the
+// deletion, the organization keeps working and can download its export bundle during a
+// grace period in which any owner can cancel, and the worker then deletes every trace
+// of it. Members are emailed when the deletion is scheduled, a week before it runs,
+// when it is cancelled and once it is done.
+//
+// Deleting the organization row cascades to its projects, ledgers and everything
+// recorded against them, including the event stream, notes and other audit trails, in
+// the control and the regional database. River jobs and archived event objects are not
+// linked by foreign keys and are deleted first.
+package offboarding
+
+import (
+	"Go_FormanceLegder/internal/archive"
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/mail"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Members are reminded this long before their organization is deleted.
+const reminderLead = 7 * 24 * time.Hour
+
+var (
+	ErrNotScheduled     = errors.New("no deletion scheduled")
+	ErrAlreadyScheduled = errors.New("deletion already scheduled")
+)
+
+// Offboarding is an organization's deletion request. It outlives the organization as
+// the record of its deletion.
+type Offboarding struct {
+	ID               string `json:"id"`
+	OrganizationID   string `json:"organization_id"`
+	OrganizationName string `json:"organization_name"`
+	RequestedBy      string `json:"requested_by"`
+	Status           string `json:"status"` // scheduled, cancelled or deleted
+	DeleteAfter      string `json:"delete_after"`
+	RemindedAt       string `json:"reminded_at,omitempty"`
+	CancelledBy      string `json:"cancelled_by,omitempty"`
+	CancelledAt      string `json:"cancelled_at,omitempty"`
+	DeletedAt        string `json:"deleted_at,omitempty"`
+	LastError        string `json:"last_error,omitempty"`
+	CreatedAt        string `json:"created_at"`
+}
+
+type Service struct {
+	Control *pgxpool.Pool
+	Router  *db.Router
+	Store   *archive.S3 // holds archived events; nil when archival is off
+	Mailer  *mail.Mailer
+	Grace   time.Duration
+}
+
+const offboardingSelect = `
+	SELECT id, organization_id, organization_name, requested_by, status, delete_after, reminded_at,
+		COALESCE(cancelled_by, ''), cancelled_at, deleted_at, COALESCE(last_error, ''), created_at
+	FROM org_offboardings
+`
+
+// Schedule starts the grace period of an organization's deletion.
+func (s *Service) Schedule(ctx context.Context, orgID, requestedBy string) (Offboarding, error) {
+	var id string
+	err := s.Control.QueryRow(ctx, `
+		INSERT INTO org_offboardings (organization_id, organization_name, requested_by, delete_after)
+		SELECT id, name, $2, $3 FROM organizations WHERE id = $1
+		ON CONFLICT (organization_id) WHERE status = 'scheduled' DO NOTHING
+		RETURNING id
+	`, orgID, requestedBy, time.Now().Add(s.Grace)).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Offboarding{}, ErrAlreadyScheduled
+	}
+	if err != nil {
+		return Offboarding{}, err
+	}
+
+	o, err := s.load(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return o, err
+	}
+	s.notify(ctx, o, "Your organization is scheduled for deletion", fmt.Sprintf(
+		"%s asked to delete the organization %s and all of its data on %s.\n\n"+
+			"Until then the organization keeps working, owners can download an export of its data from the "+
+			"dashboard, and any owner can cancel the deletion.",
+		o.RequestedBy, o.OrganizationName, o.DeleteAfter))
+	return o, nil
+}
+
+// Cancel stops a scheduled deletion during its grace period.
+func (s *Service) Cancel(ctx context.Context, orgID, cancelledBy string) (Offboarding, error) {
+	var id string
+	err := s.Control.QueryRow(ctx, `
+		UPDATE org_offboardings
+		SET status = 'cancelled', cancelled_by = $2, cancelled_at = NOW()
+		WHERE organization_id = $1 AND status = 'scheduled'
+		RETURNING id
+	`, orgID, cancelledBy).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Offboarding{}, ErrNotScheduled
+	}
+	if err != nil {
+		return Offboarding{}, err
+	}
+
+	o, err := s.load(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return o, err
+	}
+	s.notify(ctx, o, "Organization deletion cancelled", fmt.Sprintf(
+		"%s cancelled the deletion of the organization %s. Nothing was deleted.", o.CancelledBy, o.OrganizationName))
+	return o, nil
+}
+
+// Latest returns the organization's most recent deletion request.
+func (s *Service) Latest(ctx context.Context, orgID string) (Offboarding, error) {
+	o, err := s.load(ctx, `WHERE organization_id = $1 ORDER BY created_at DESC LIMIT 1`, orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return o, ErrNotScheduled
+	}
+	return o, err
+}
+
+// Run sends reminders and deletes organizations whose grace period is over, hourly
+// until ctx is done.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ProcessDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("organization offboarding: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue sends due reminders and runs due deletions. A failed deletion is recorded
+// on its request and retried on the next run.
+func (s *Service) ProcessDue(ctx context.Context) error {
+	rows, err := s.Control.Query(ctx, `
+		UPDATE org_offboardings
+		SET reminded_at = NOW()
+		WHERE status = 'scheduled' AND reminded_at IS NULL AND delete_after <= $1
+		RETURNING id
+	`, time.Now().Add(reminderLead))
+	if err != nil {
+		return err
+	}
+	reminders, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, id := range reminders {
+		o, err := s.load(ctx, `WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		s.notify(ctx, o, "Reminder: your organization will be deleted", fmt.Sprintf(
+			"The organization %s and all of its data will be deleted on %s. "+
+				"Download its export from the dashboard before then if you need it, or cancel the deletion.",
+			o.OrganizationName, o.DeleteAfter))
+	}
+
+	rows, err = s.Control.Query(ctx, `
+		SELECT id FROM org_offboardings WHERE status = 'scheduled' AND delete_after <= NOW() ORDER BY delete_after
+	`)
+	if err != nil {
+		return err
+	}
+	due, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, id := range due {
+		if err := s.delete(ctx, id); err != nil {
+			log.Printf("delete organization (offboarding %s): %v", id, err)
+			s.Control.Exec(ctx, `UPDATE org_offboardings SET last_error = $2 WHERE id = $1`, id, err.Error())
+		}
+	}
+	return nil
+}
+
+// delete removes the organization from its regional database, then from the control
+// database. Each step is idempotent, so a failure part-way is finished on retry.
+func (s *Service) delete(ctx context.Context, id string) error {
+	o, err := s.load(ctx, `WHERE id = $1 AND status = 'scheduled'`, id)
+	if err != nil {
+		return err
+	}
+	// Members are gone with the organization, so collect them first
+	recipients, err := s.members(ctx, o.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	pool := s.Control
+	if s.Router != nil {
+		if _, regional, err := s.Router.ForOrganization(ctx, o.OrganizationID); err == nil {
+			pool = regional
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+	if pool != s.Control {
+		if err := s.purge(ctx, pool, o.OrganizationID); err != nil {
+			return fmt.Errorf("regional database: %w", err)
+		}
+	}
+	if err := s.purge(ctx, s.Control, o.OrganizationID); err != nil {
+		return err
+	}
+
+	tag, err := s.Control.Exec(ctx, `
+		UPDATE org_offboardings SET status = 'deleted', deleted_at = NOW(), last_error = NULL
+		WHERE id = $1 AND status = 'scheduled'
+	`, id)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	if err := s.Mailer.Send(recipients, "Your organization was deleted", fmt.Sprintf(
+		"The organization %s and all of its data were deleted as requested by %s.", o.OrganizationName, o.RequestedBy)); err != nil {
+		log.Printf("offboarding %s: notify: %v", id, err)
+	}
+	return nil
+}
+
+// purge deletes an organization's archived events, River jobs and rows from one
+// database.
+func (s *Service) purge(ctx context.Context, pool *pgxpool.Pool, orgID string) error {
+	rows, err := pool.Query(ctx, `
+		SELECT l.id::text FROM ledgers l JOIN projects p ON p.id = l.project_id WHERE p.organization_id = $1
+	`, orgID)
+	if err != nil {
+		return err
+	}
+	ledgerIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	rows, err = pool.Query(ctx, `SELECT object_key FROM event_archives WHERE ledger_id::text = ANY($1)`, ledgerIDs)
+	if err != nil {
+		return err
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 && s.Store == nil {
+		return fmt.Errorf("%d archived event objects to delete but no object storage configured", len(keys))
+	}
+	for _, key := range keys {
+		if err := s.Store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Jobs of every kind that carries a ledger, e.g. webhook deliveries and workflow steps
+	if _, err := tx.Exec(ctx, `DELETE FROM river_job WHERE args->>'ledger_id' = ANY($1)`, ledgerIDs); err != nil {
+		return err
+	}
+	// Dead letters copy the events they hold back, and have no foreign key to cascade
+	if _, err := tx.Exec(ctx, `DELETE FROM projector_dead_letters WHERE ledger_id::text = ANY($1)`, ledgerIDs); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, orgID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Service) notify(ctx context.Context, o Offboarding, subject, body string) {
+	recipients, err := s.members(ctx, o.OrganizationID)
+	if err == nil {
+		err = s.Mailer.Send(recipients, subject, body)
+	}
+	if err != nil {
+		log.Printf("offboarding %s: notify: %v", o.ID, err)
+	}
+}
+
+func (s *Service) members(ctx context.Context, orgID string) ([]string, error) {
+	rows, err := s.Control.Query(ctx, `
+		SELECT u.email FROM org_users ou JOIN users u ON u.id = ou.user_id WHERE ou.organization_id = $1 ORDER BY u.email
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (s *Service) load(ctx context.Context, where string, args ...any) (Offboarding, error) {
+	var o Offboarding
+	var deleteAfter, createdAt time.Time
+	var remindedAt, cancelledAt, deletedAt *time.Time
+	err := s.Control.QueryRow(ctx, offboardingSelect+where, args...).Scan(&o.ID, &o.OrganizationID,
+		&o.OrganizationName, &o.RequestedBy, &o.Status, &deleteAfter, &remindedAt, &o.CancelledBy, &cancelledAt,
+		&deletedAt, &o.LastError, &createdAt)
+	if err != nil {
+		return o, err
+	}
+	o.DeleteAfter = deleteAfter.Format(time.RFC3339)
+	o.CreatedAt = createdAt.Format(time.RFC3339)
+	for _, t := range []struct {
+		from *time.Time
+		to   *string
+	}{{remindedAt, &o.RemindedAt}, {cancelledAt, &o.CancelledAt}, {deletedAt, &o.DeletedAt}} {
+		if t.from != nil {
+			*t.to = t.from.Format(time.RFC3339)
+		}
+	}
+	return o, nil
+}
@@ -0,0 +1,360 @@
+// Package openapi builds an OpenAPI 3 document from the Go types of the API's requests
+// and responses, so the specification can't drift from what the handlers encode.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document is an OpenAPI 3.0 document, restricted to what the API uses.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers,omitempty"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+	Tags       []Tag                           `json:"tags,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // query, path or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 defines it.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Param describes a parameter of an Endpoint; its schema is a string unless Type is set.
+type Param struct {
+	Name        string
+	In          string // query unless set
+	Type        string // string, integer or boolean
+	Format      string
+	Required    bool
+	Description string
+	Enum        []string
+}
+
+// Endpoint is an operation of the API. Request and Response are values of the types the
+// handler decodes and encodes; nil means no body.
+type Endpoint struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Tag         string
+	Params      []Param
+	Request     any
+	Response    any
+	Status      int    // of a successful response; 200 unless set
+	ContentType string // of a successful response; application/json unless set
+}
+
+// Builder collects endpoints into a document, naming a component after each struct type.
+type Builder struct {
+	doc       Document
+	names     map[reflect.Type]string
+	errorBody any
+}
+
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI:    "3.0.3",
+			Info:       info,
+			Paths:      map[string]map[string]Operation{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+		names: map[reflect.Type]string{},
+	}
+}
+
+// BearerAuth requires an Authorization: Bearer header on every operation.
+func (b *Builder) BearerAuth(name, description string) {
+	if b.doc.Components.SecuritySchemes == nil {
+		b.doc.Components.SecuritySchemes = map[string]SecurityScheme{}
+	}
+	b.doc.Components.SecuritySchemes[name] = SecurityScheme{Type: "http", Scheme: "bearer", Description: description}
+	b.doc.Security = append(b.doc.Security, map[string][]string{name: {}})
+}
+
+func (b *Builder) Tag(name, description string) {
+	b.doc.Tags = append(b.doc.Tags, Tag{Name: name, Description: description})
+}
+
+// Errors documents body, the error envelope, as the reply of every operation under any
+// error status.
+func (b *Builder) Errors(body any) {
+	b.errorBody = body
+}
+
+// Add adds e to the document.
+func (b *Builder) Add(e Endpoint) {
+	op := Operation{OperationID: e.ID, Summary: e.Summary, Description: e.Description, Responses: map[string]Response{}}
+	if e.Tag != "" {
+		op.Tags = []string{e.Tag}
+	}
+	for _, p := range e.Params {
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: p.Name, In: in, Description: p.Description, Required: p.Required || in == "path",
+			Schema: &Schema{Type: typ, Format: p.Format, Enum: p.Enum},
+		})
+	}
+	if e.Request != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: b.schema(reflect.TypeOf(e.Request))},
+		}}
+	}
+
+	status, contentType := e.Status, e.ContentType
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	resp := Response{Description: http.StatusText(status)}
+	if e.Response != nil {
+		resp.Content = map[string]MediaType{contentType: {Schema: b.schema(reflect.TypeOf(e.Response))}}
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+	if b.errorBody != nil {
+		op.Responses["default"] = Response{Description: "Error", Content: map[string]MediaType{
+			"application/json": {Schema: b.schema(reflect.TypeOf(b.errorBody))},
+		}}
+	}
+
+	if b.doc.Paths[e.Path] == nil {
+		b.doc.Paths[e.Path] = map[string]Operation{}
+	}
+	b.doc.Paths[e.Path][strings.ToLower(e.Method)] = op
+}
+
+func (b *Builder) Document() *Document {
+	return &b.doc
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of t, a reference for named structs.
+func (b *Builder) schema(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			// Registered before its fields, so recursive types terminate
+			b.doc.Components.Schemas[name] = &Schema{}
+			b.doc.Components.Schemas[name] = b.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// componentName is the type's name, qualified by its package when two packages have a
+// type of that name.
+func (b *Builder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	if _, taken := b.doc.Components.Schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndexByte(pkg, '/')+1:]
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+// object returns the schema of a struct's JSON encoding: fields without omitempty are
+// required, and embedded structs are flattened as encoding/json does.
+func (b *Builder) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	var fields func(t reflect.Type)
+	fields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					fields(ft)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := b.schema(f.Type)
+			if f.Type.Kind() == reflect.Pointer && prop.Ref == "" {
+				prop.Nullable = true
+			}
+			s.Properties[name] = prop
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	fields(t)
+	sort.Strings(s.Required)
+	return s
+}
+
+// Handler serves doc as JSON.
+func Handler(doc *Document) http.Handler {
+	body, err := json.Marshal(doc)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "failed to encode the OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write(body)
+	})
+}
+
+// SwaggerUI serves a Swagger UI page rendering the document at specURL.
+func SwaggerUI(specURL string) http.Handler {
+	page := strings.ReplaceAll(swaggerPage, "{{spec}}", specURL)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+}
+
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Ledger API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "{{spec}}", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
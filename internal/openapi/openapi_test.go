@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Value string `json:"value"`
+}
+
+type outer struct {
+	ID       string            `json:"id"`
+	Note     string            `json:"note,omitempty"`
+	Parent   *outer            `json:"parent,omitempty"`
+	Items    []inner           `json:"items"`
+	Metadata map[string]string `json:"metadata"`
+	At       time.Time         `json:"at"`
+	Hidden   string            `json:"-"`
+	inner
+}
+
+func TestSchemaFromType(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	b.Add(Endpoint{Method: http.MethodPost, Path: "/things", ID: "createThing", Request: outer{}, Response: []outer{}, Status: http.StatusCreated})
+	doc := b.Document()
+
+	op := doc.Paths["/things"]["post"]
+	if ref := op.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/outer" {
+		t.Fatalf("request ref = %q", ref)
+	}
+	if items := op.Responses["201"].Content["application/json"].Schema.Items; items == nil || items.Ref != "#/components/schemas/outer" {
+		t.Fatalf("response = %+v", op.Responses["201"])
+	}
+
+	s := doc.Components.Schemas["outer"]
+	if got := strings.Join(s.Required, ","); got != "at,id,items,metadata,value" {
+		t.Errorf("required = %s", got)
+	}
+	if _, ok := s.Properties["Hidden"]; ok {
+		t.Error("json:\"-\" field documented")
+	}
+	if s.Properties["at"].Format != "date-time" {
+		t.Errorf("at = %+v", s.Properties["at"])
+	}
+	if s.Properties["parent"].Ref != "#/components/schemas/outer" {
+		t.Errorf("parent = %+v", s.Properties["parent"])
+	}
+	if s.Properties["metadata"].AdditionalProperties.Type != "string" {
+		t.Errorf("metadata = %+v", s.Properties["metadata"])
+	}
+	if _, ok := doc.Components.Schemas["inner"]; !ok {
+		t.Error("inner not registered")
+	}
+}
+
+func TestV1(t *testing.T) {
+	doc := V1()
+	body, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/v1/transactions", "/v1/accounts", "/v1/events", "/v1/balance/summary", "/v1/webhook-endpoints"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("%s not documented", path)
+		}
+	}
+
+	// Every reference names a component and every operation id is unique
+	for _, ref := range strings.Split(string(body), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.IndexByte(ref, '"')]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("dangling reference to %s", name)
+		}
+	}
+	ids := map[string]bool{}
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if op.OperationID == "" || ids[op.OperationID] {
+				t.Errorf("%s %s: operation id %q missing or repeated", method, path, op.OperationID)
+			}
+			ids[op.OperationID] = true
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler(V1()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || doc["openapi"] != "3.0.3" {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+}
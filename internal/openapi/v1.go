@@ -0,0 +1,274 @@
+package openapi
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/dashboard"
+	"Go_FormanceLegder/internal/ledger"
+	"net/http"
+)
+
+// Parameters shared by several endpoints.
+var (
+	limitParam = Param{Name: "limit", Type: "integer", Description: "Page size, at most 1000 (default 100)"}
+	tokenParam = Param{Name: "continuation_token", Description: "pagination.continuation_token of the previous page"}
+	codeParam  = Param{Name: "code", Required: true, Description: "Account code"}
+	idParam    = Param{Name: "id", In: "path"}
+	basisParam = Param{Name: "basis", Enum: []string{"occurred_at", "value_date"},
+		Description: "Date transactions by the day they occurred (default) or by their value date"}
+)
+
+// V1 describes the /v1 API: transactions, accounts, events, balances and webhooks.
+func V1() *Document {
+	b := NewBuilder(Info{
+		Title:   "Ledger API",
+		Version: "1.0.0",
+		Description: "Double-entry ledger API. Amounts are decimal strings. Errors are replied with " +
+			`{"error":{"code":"...","message":"...","details":[...]}}.`,
+	})
+	b.BearerAuth("apiKey", "A ledger API key: Authorization: Bearer <key>")
+	b.Errors(api.ErrorResponse{})
+	b.Tag("transactions", "Post and list transactions and their postings")
+	b.Tag("accounts", "Chart of accounts")
+	b.Tag("events", "The ledger's event log")
+	b.Tag("balances", "Balances, roll-ups and snapshots")
+	b.Tag("webhooks", "Webhook endpoints and deliveries")
+
+	for _, e := range v1Endpoints {
+		b.Add(e)
+	}
+	return b.Document()
+}
+
+var v1Endpoints = []Endpoint{
+	// Transactions
+	{
+		Method: http.MethodPost, Path: "/v1/transactions", ID: "postTransaction", Tag: "transactions",
+		Summary: "Post a transaction",
+		Description: "Postings must balance per currency. Retrying with the same idempotency_key returns the " +
+			"first transaction. A transaction held by screening is answered with 202 and its review id. " +
+			"Rules refusing a transaction (unknown or disabled accounts, overdraft, KYC, assertions, the ledger's " +
+			"validation rules, screening, posting hooks) reply 422 with a specific error code.",
+		Params: []Param{
+			{Name: "dry_run", Type: "boolean", Description: "Validate and return the balance impact without posting; replies with a PreviewTransactionResponse"},
+			{Name: "explain", Type: "boolean", Description: "With dry_run, add the evaluation trace (accounts, lock order, checks, balance checks, screening); refusals reply with an ExplainedErrorResponse"},
+		},
+		Request:  ledger.PostTransactionRequest{},
+		Response: ledger.PostTransactionResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/transactions", ID: "listTransactions", Tag: "transactions",
+		Summary: "List transactions, or get one by id",
+		Description: "With id, replies with that TransactionResponse. With Accept: application/x-ndjson every " +
+			"matching transaction is streamed, one per line. metadata[key]=value filters match metadata values.",
+		Params: []Param{
+			{Name: "id", Description: "Get this transaction instead of listing"},
+			limitParam, tokenParam,
+			{Name: "start_time", Format: "date-time", Description: "occurred_at lower bound, inclusive"},
+			{Name: "end_time", Format: "date-time", Description: "occurred_at upper bound, inclusive"},
+			{Name: "value_from", Format: "date", Description: "Value date lower bound, inclusive"},
+			{Name: "value_to", Format: "date", Description: "Value date upper bound, inclusive"},
+			{Name: "entity", Description: "Entity code of the counterparty"},
+			{Name: "confirm_expensive", Type: "boolean", Description: "Allow metadata filters without a bounded time range on large ledgers"},
+		},
+		Response: ledger.ListTransactionsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/postings", ID: "listPostings", Tag: "transactions",
+		Summary: "List postings across transactions, newest first",
+		Params: []Param{
+			limitParam, tokenParam,
+			{Name: "account", Description: "Account code"},
+			{Name: "direction", Enum: []string{"debit", "credit"}},
+			{Name: "currency"},
+			{Name: "min_amount", Description: "Inclusive"},
+			{Name: "max_amount", Description: "Inclusive"},
+			{Name: "start_time", Format: "date-time", Description: "Transaction occurred_at lower bound, inclusive"},
+			{Name: "end_time", Format: "date-time", Description: "Transaction occurred_at upper bound, inclusive"},
+		},
+		Response: ledger.ListPostingsResponse{},
+	},
+
+	// Accounts
+	{
+		Method: http.MethodGet, Path: "/v1/accounts", ID: "listAccounts", Tag: "accounts",
+		Summary:     "List accounts, or get one by code",
+		Description: "With code, replies with that AccountResponse, including its notes.",
+		Params: []Param{
+			{Name: "code", Description: "Get this account instead of listing"},
+			{Name: "status", Enum: []string{"active", "disabled", "all"}, Description: "Default active"},
+			{Name: "entity", Description: "Entity code linked to the accounts"},
+			{Name: "parent", Description: "Only accounts whose code is parent or starts with parent:"},
+		},
+		Response: []ledger.AccountResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/accounts", ID: "createAccount", Tag: "accounts",
+		Summary:  "Create an account",
+		Request:  ledger.CreateAccountRequest{},
+		Response: map[string]any{},
+		Status:   http.StatusCreated,
+	},
+	{
+		Method: http.MethodPatch, Path: "/v1/accounts", ID: "updateAccountMetadata", Tag: "accounts",
+		Summary:  "Update an account's metadata as a JSON merge patch; null removes a key",
+		Params:   []Param{codeParam},
+		Request:  ledger.UpdateAccountMetadataRequest{},
+		Response: ledger.UpdateAccountMetadataResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/accounts/batch", ID: "createAccounts", Tag: "accounts",
+		Summary: "Create many accounts at once",
+		Description: "Either every new account is created or, when any item is invalid, none is and the reply " +
+			"is a 422 with the same body naming the invalid items.",
+		Request:  ledger.CreateAccountsRequest{},
+		Response: ledger.CreateAccountsResponse{},
+		Status:   http.StatusCreated,
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/accounts/disable", ID: "disableAccount", Tag: "accounts",
+		Summary:  "Retire an account with a zero balance",
+		Params:   []Param{codeParam},
+		Response: ledger.AccountStatusResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/accounts/enable", ID: "enableAccount", Tag: "accounts",
+		Summary:  "Accept postings to a disabled account again",
+		Params:   []Param{codeParam},
+		Response: ledger.AccountStatusResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/accounts/constraints", ID: "setAccountConstraints", Tag: "accounts",
+		Summary:  "Set an account's overdraft protection",
+		Params:   []Param{codeParam},
+		Request:  ledger.AccountConstraintsRequest{},
+		Response: ledger.AccountConstraintsResponse{},
+	},
+
+	// Events
+	{
+		Method: http.MethodGet, Path: "/v1/events", ID: "listEvents", Tag: "events",
+		Summary:     "List events in sequence order, or get one by id",
+		Description: "With id, replies with that EventResponse. With Accept: application/x-ndjson every matching event is streamed, one per line.",
+		Params: []Param{
+			{Name: "id", Description: "Get this event instead of listing"},
+			limitParam, tokenParam,
+			{Name: "event_type"},
+			{Name: "aggregate_id"},
+		},
+		Response: ledger.ListEventsResponse{},
+	},
+
+	// Balances
+	{
+		Method: http.MethodGet, Path: "/v1/balance/summary", ID: "getBalanceSummary", Tag: "balances",
+		Summary:  "Balances totaled by account type",
+		Params:   []Param{{Name: "currency", Description: "Also convert every balance to this currency"}},
+		Response: ledger.BalanceSummaryResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/balance/rollup", ID: "getBalanceRollup", Tag: "balances",
+		Summary: "Balances aggregated by account code segments",
+		Params: []Param{
+			{Name: "parent", Description: "Code whose children are totaled; the whole ledger when empty"},
+			{Name: "depth", Type: "integer", Description: "Segments below parent to group by (default 1)"},
+		},
+		Response: ledger.BalanceRollupResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/accounts/balance-history", ID: "getAccountBalanceHistory", Tag: "balances",
+		Summary:  "Daily balance history of an account",
+		Params:   []Param{codeParam, basisParam},
+		Response: ledger.AccountBalanceHistoryResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/balance/diff", ID: "getBalanceDiff", Tag: "balances",
+		Summary: "Per-account balance movement between two timestamps or snapshots",
+		Params: []Param{
+			{Name: "from", Format: "date-time"},
+			{Name: "to", Format: "date-time"},
+			{Name: "from_snapshot", Description: "Snapshot id, instead of from"},
+			{Name: "to_snapshot", Description: "Snapshot id, instead of to"},
+			{Name: "changed_only", Type: "boolean"},
+		},
+		Response: ledger.BalanceDiffResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/balance/snapshots", ID: "listBalanceSnapshots", Tag: "balances",
+		Summary:  "List balance snapshots",
+		Response: []ledger.BalanceSnapshotResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/balance/snapshots", ID: "createBalanceSnapshot", Tag: "balances",
+		Summary:  "Freeze every account balance as of a point in time",
+		Request:  ledger.CreateBalanceSnapshotRequest{},
+		Response: ledger.BalanceSnapshotResponse{},
+		Status:   http.StatusCreated,
+	},
+
+	// Webhooks
+	{
+		Method: http.MethodGet, Path: "/v1/webhook-endpoints", ID: "listWebhookEndpoints", Tag: "webhooks",
+		Summary:  "List webhook endpoints",
+		Response: []dashboard.WebhookEndpointResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/webhook-endpoints", ID: "createWebhookEndpoint", Tag: "webhooks",
+		Summary:  "Register a webhook endpoint; the reply holds its signing secret",
+		Request:  dashboard.CreateWebhookEndpointRequest{},
+		Response: dashboard.CreateWebhookEndpointResponse{},
+		Status:   http.StatusCreated,
+	},
+	{
+		Method: http.MethodPatch, Path: "/v1/webhook-endpoints/{id}", ID: "updateWebhookEndpoint", Tag: "webhooks",
+		Summary: "Change an endpoint's URL, state, retry policy, rate limit, batch mode, headers, client certificate or filter",
+		Params:  []Param{idParam}, Request: dashboard.UpdateWebhookEndpointRequest{},
+		Response: dashboard.WebhookEndpointResponse{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/webhook-endpoints/{id}", ID: "deleteWebhookEndpoint", Tag: "webhooks",
+		Summary: "Delete an endpoint along with its delivery history",
+		Params:  []Param{idParam}, Status: http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/webhook-endpoints/{id}/rotate-secret", ID: "rotateWebhookSecret", Tag: "webhooks",
+		Summary: "Issue a new signing secret; the old one stays valid for the grace period",
+		Params:  []Param{idParam}, Request: dashboard.RotateWebhookSecretRequest{},
+		Response: dashboard.RotateWebhookSecretResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/webhook-endpoints/{id}/test", ID: "testWebhookEndpoint", Tag: "webhooks",
+		Summary:  "Send a signed WebhookTest event to an endpoint",
+		Params:   []Param{idParam},
+		Response: dashboard.TestWebhookEndpointResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/webhook-deliveries", ID: "listWebhookDeliveries", Tag: "webhooks",
+		Summary:  "List delivery attempts, newest first",
+		Params:   []Param{limitParam},
+		Response: []dashboard.WebhookDeliveryResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/webhook-deliveries/{id}/retry", ID: "retryWebhookDelivery", Tag: "webhooks",
+		Summary:  "Deliver a failed delivery's event again",
+		Params:   []Param{idParam},
+		Response: dashboard.RetriedDelivery{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/webhook-deliveries/retry", ID: "retryWebhookDeliveries", Tag: "webhooks",
+		Summary:  "Deliver failed events again in bulk",
+		Request:  dashboard.RetryWebhookDeliveriesRequest{},
+		Response: dashboard.RetryWebhookDeliveriesResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/events/{id}/deliveries", ID: "listEventDeliveries", Tag: "webhooks",
+		Summary:  "Delivery attempts of one event, oldest first",
+		Params:   []Param{idParam},
+		Response: dashboard.EventDeliveriesResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/webhook-replays", ID: "replayWebhookEvents", Tag: "webhooks",
+		Summary:  "Send a range of historical events to a staging URL",
+		Request:  dashboard.ReplayWebhookEventsRequest{},
+		Response: dashboard.ReplayWebhookEventsResponse{},
+	},
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka publishes events as JSON messages keyed by ledger ID, so each ledger's events
+// land on one partition in order.
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+func NewKafka(brokers []string, topic string) *Kafka {
+	return &Kafka{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+		// Keep per-ledger order when a batch is retried
+		MaxAttempts: 1,
+	}}
+}
+
+func (k *Kafka) Publish(ctx context.Context, events []Event) error {
+	messages := make([]kafka.Message, len(events))
+	for i, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		messages[i] = kafka.Message{
+			Key:   []byte(e.LedgerID),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: "event_id", Value: []byte(e.ID)},
+				{Key: "event_type", Value: []byte(e.EventType)},
+			},
+			Time: e.OccurredAt,
+		}
+	}
+	return k.writer.WriteMessages(ctx, messages...)
+}
+
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS publishes events to a JetStream stream on subjects <subject>.<ledger ID>.<event
+// type>, so consumers can filter by ledger or type. Each message's Nats-Msg-Id is the
+// event ID, so JetStream drops the duplicates of a republished batch.
+type NATS struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+}
+
+// NewNATS connects to url and creates the stream if it does not exist yet; an existing
+// stream's config is left to the operator.
+func NewNATS(ctx context.Context, url, stream, subject string) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("ledger-outbox"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	_, err = js.Stream(ctx, stream)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:       stream,
+			Subjects:   []string{subject + ".>"},
+			Duplicates: 10 * time.Minute,
+		})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats stream %s: %w", stream, err)
+	}
+	return &NATS{conn: conn, js: js, subject: subject}, nil
+}
+
+// Publish sends the events one at a time, waiting for each ack, so a failed event is
+// never overtaken by a later one.
+func (n *NATS) Publish(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		msg := nats.NewMsg(n.subject + "." + e.LedgerID + "." + e.EventType)
+		msg.Data = data
+		msg.Header.Set("Ledger-Event-Type", e.EventType)
+		if _, err := n.js.PublishMsg(ctx, msg, jetstream.WithMsgID(e.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
@@ -0,0 +1,134 @@
+// Package outbox publishes committed events to external systems (Kafka for data
+// warehouses, NATS JetStream for downstream services), using the events table as a
+// transactional outbox: every event is published at least once, in sequence order,
+// with a relay offset tracked like the projector's.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Event as published. Consumers deduplicate on ID (or Sequence, per region).
+type Event struct {
+	ID            string          `json:"id"`
+	Sequence      int64           `json:"sequence"`
+	LedgerID      string          `json:"ledger_id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+}
+
+// Publisher delivers a batch of events in order; an error makes the relay publish the
+// whole batch again.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+type Relay struct {
+	DB        *pgxpool.Pool
+	Publisher Publisher
+
+	// Name keys the relay's offset
+	Name      string
+	BatchSize int
+}
+
+func NewRelay(db *pgxpool.Pool, name string, publisher Publisher) *Relay {
+	return &Relay{DB: db, Publisher: publisher, Name: name, BatchSize: 500}
+}
+
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// Drain the backlog before waiting for the next tick
+			for {
+				n, err := r.relayBatch(ctx)
+				if err != nil {
+					log.Printf("outbox %s: %v", r.Name, err)
+				}
+				if err != nil || n < r.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// relayBatch publishes the next events after the relay's offset and advances it. The
+// offset row stays locked while publishing, so concurrent relays of the same name
+// (e.g. several workers) take turns instead of publishing twice.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox_offsets (relay_name) VALUES ($1) ON CONFLICT (relay_name) DO NOTHING
+	`, r.Name)
+	if err != nil {
+		return 0, err
+	}
+	var last int64
+	err = tx.QueryRow(ctx, `
+		SELECT last_sequence FROM outbox_offsets WHERE relay_name = $1 FOR UPDATE
+	`, r.Name).Scan(&last)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, sequence, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at
+		FROM events
+		WHERE sequence > $1
+		ORDER BY sequence
+		LIMIT $2
+	`, last, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var events []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Sequence, &e.LedgerID, &e.AggregateType, &e.AggregateID, &e.EventType, &e.Payload, &e.OccurredAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, tx.Commit(ctx)
+	}
+
+	if err := r.Publisher.Publish(ctx, events); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE outbox_offsets SET last_sequence = $2, updated_at = NOW() WHERE relay_name = $1
+	`, r.Name, events[len(events)-1].Sequence)
+	if err != nil {
+		return 0, err
+	}
+	return len(events), tx.Commit(ctx)
+}
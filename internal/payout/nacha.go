@@ -0,0 +1,146 @@
+package payout
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const nachaRecordLength = 94
+
+// RenderNACHA renders a single-batch PPD credit file (service class 220).
+func RenderNACHA(f File) ([]byte, error) {
+	if err := Validate(f); err != nil {
+		return nil, err
+	}
+
+	o := f.Originator
+	odfi := o.RoutingNumber[:8]
+	var lines []string
+
+	// File header
+	lines = append(lines, "1"+
+		"01"+
+		" "+o.RoutingNumber+
+		" "+o.RoutingNumber+
+		f.CreatedAt.Format("060102")+
+		f.CreatedAt.Format("1504")+
+		"A"+
+		"094"+
+		"10"+
+		"1"+
+		alpha(o.DestinationName, 23)+
+		alpha(o.Name, 23)+
+		alpha(f.MessageID, 8))
+
+	// Batch header
+	lines = append(lines, "5"+
+		"220"+
+		alpha(o.Name, 16)+
+		alpha("", 20)+
+		alpha(o.CompanyID, 10)+
+		"PPD"+
+		alpha("PAYOUT", 10)+
+		alpha("", 6)+
+		f.ExecutionDate.Format("060102")+
+		"   "+
+		"1"+
+		odfi+
+		numeric(1, 7))
+
+	entryHash := new(big.Int)
+	totalCredit := new(big.Int)
+	for i, in := range f.Instructions {
+		txCode := "22"
+		if in.AccountType == "savings" {
+			txCode = "32"
+		}
+		cents := toCents(in.Amount)
+		totalCredit.Add(totalCredit, cents)
+		rdfi, _ := strconv.ParseInt(in.RoutingNumber[:8], 10, 64)
+		entryHash.Add(entryHash, big.NewInt(rdfi))
+
+		lines = append(lines, "6"+
+			txCode+
+			in.RoutingNumber+
+			alpha(in.AccountNumber, 17)+
+			numericBig(cents, 10)+
+			alpha(in.Reference, 15)+
+			alpha(in.Name, 22)+
+			"  "+
+			"0"+
+			odfi+numeric(i+1, 7))
+	}
+
+	hash := lastDigits(entryHash, 10)
+	entries := len(f.Instructions)
+
+	// Batch control
+	lines = append(lines, "8"+
+		"220"+
+		numeric(entries, 6)+
+		hash+
+		numeric(0, 12)+
+		numericBig(totalCredit, 12)+
+		alpha(o.CompanyID, 10)+
+		alpha("", 19)+
+		alpha("", 6)+
+		odfi+
+		numeric(1, 7))
+
+	// File control; block count covers the padding to a multiple of ten records
+	records := len(lines) + 1
+	blocks := (records + 9) / 10
+	lines = append(lines, "9"+
+		numeric(1, 6)+
+		numeric(blocks, 6)+
+		numeric(entries, 8)+
+		hash+
+		numeric(0, 12)+
+		numericBig(totalCredit, 12)+
+		alpha("", 39))
+
+	for len(lines)%10 != 0 {
+		lines = append(lines, strings.Repeat("9", nachaRecordLength))
+	}
+
+	for i, line := range lines {
+		if len(line) != nachaRecordLength {
+			return nil, fmt.Errorf("nacha record %d has length %d", i+1, len(line))
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// alpha left-justifies and space-pads (or truncates) an alphanumeric field.
+func alpha(s string, width int) string {
+	s = strings.ToUpper(s)
+	if len(s) > width {
+		return s[:width]
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// numeric right-justifies and zero-pads a numeric field.
+func numeric(n, width int) string {
+	return fmt.Sprintf("%0*d", width, n)
+}
+
+func numericBig(n *big.Int, width int) string {
+	s := n.String()
+	if len(s) > width {
+		return s[len(s)-width:]
+	}
+	return strings.Repeat("0", width-len(s)) + s
+}
+
+func lastDigits(n *big.Int, width int) string {
+	return numericBig(n, width)
+}
+
+func toCents(amount *big.Rat) *big.Int {
+	cents := new(big.Rat).Mul(amount, big.NewRat(100, 1))
+	return new(big.Int).Quo(cents.Num(), cents.Denom())
+}
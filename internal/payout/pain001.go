@@ -0,0 +1,139 @@
+package payout
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type pain001Document struct {
+	XMLName xml.Name          `xml:"Document"`
+	Xmlns   string            `xml:"xmlns,attr"`
+	Init    pain001Initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001Initiation struct {
+	GrpHdr pain001GroupHeader `xml:"GrpHdr"`
+	PmtInf pain001PaymentInfo `xml:"PmtInf"`
+}
+
+type pain001GroupHeader struct {
+	MsgId    string       `xml:"MsgId"`
+	CreDtTm  string       `xml:"CreDtTm"`
+	NbOfTxs  string       `xml:"NbOfTxs"`
+	CtrlSum  string       `xml:"CtrlSum"`
+	InitgPty pain001Party `xml:"InitgPty"`
+}
+
+type pain001PaymentInfo struct {
+	PmtInfId    string            `xml:"PmtInfId"`
+	PmtMtd      string            `xml:"PmtMtd"`
+	NbOfTxs     string            `xml:"NbOfTxs"`
+	CtrlSum     string            `xml:"CtrlSum"`
+	ReqdExctnDt string            `xml:"ReqdExctnDt"`
+	Dbtr        pain001Party      `xml:"Dbtr"`
+	DbtrAcct    pain001Account    `xml:"DbtrAcct"`
+	DbtrAgt     *pain001Agent     `xml:"DbtrAgt,omitempty"`
+	ChrgBr      string            `xml:"ChrgBr"`
+	Txs         []pain001CreditTx `xml:"CdtTrfTxInf"`
+}
+
+type pain001Party struct {
+	Nm string `xml:"Nm"`
+}
+
+type pain001Account struct {
+	IBAN string `xml:"Id>IBAN"`
+}
+
+type pain001Agent struct {
+	BIC string `xml:"FinInstnId>BIC"`
+}
+
+type pain001Amount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type pain001CreditTx struct {
+	EndToEndId string         `xml:"PmtId>EndToEndId"`
+	InstdAmt   pain001Amount  `xml:"Amt>InstdAmt"`
+	CdtrAgt    *pain001Agent  `xml:"CdtrAgt,omitempty"`
+	Cdtr       pain001Party   `xml:"Cdtr"`
+	CdtrAcct   pain001Account `xml:"CdtrAcct"`
+	Ustrd      string         `xml:"RmtInf>Ustrd,omitempty"`
+}
+
+// RenderPain001 renders a pain.001.001.03 customer credit transfer initiation.
+func RenderPain001(f File) ([]byte, error) {
+	if err := Validate(f); err != nil {
+		return nil, err
+	}
+
+	count := strconv.Itoa(len(f.Instructions))
+	total := f.Total().FloatString(2)
+
+	info := pain001PaymentInfo{
+		PmtInfId:    f.MessageID,
+		PmtMtd:      "TRF",
+		NbOfTxs:     count,
+		CtrlSum:     total,
+		ReqdExctnDt: f.ExecutionDate.Format("2006-01-02"),
+		Dbtr:        pain001Party{Nm: truncate(f.Originator.Name, 70)},
+		DbtrAcct:    pain001Account{IBAN: normalizeIBAN(f.Originator.IBAN)},
+		ChrgBr:      "SLEV",
+	}
+	if f.Originator.BIC != "" {
+		info.DbtrAgt = &pain001Agent{BIC: f.Originator.BIC}
+	}
+
+	for i, in := range f.Instructions {
+		endToEnd := in.Reference
+		if endToEnd == "" {
+			endToEnd = f.MessageID + "-" + strconv.Itoa(i+1)
+		}
+		tx := pain001CreditTx{
+			EndToEndId: truncate(endToEnd, 35),
+			InstdAmt:   pain001Amount{Ccy: in.Currency, Value: in.Amount.FloatString(2)},
+			Cdtr:       pain001Party{Nm: truncate(in.Name, 70)},
+			CdtrAcct:   pain001Account{IBAN: normalizeIBAN(in.IBAN)},
+			Ustrd:      truncate(in.Description, 140),
+		}
+		if in.BIC != "" {
+			tx.CdtrAgt = &pain001Agent{BIC: in.BIC}
+		}
+		info.Txs = append(info.Txs, tx)
+	}
+
+	doc := pain001Document{
+		Xmlns: "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03",
+		Init: pain001Initiation{
+			GrpHdr: pain001GroupHeader{
+				MsgId:    truncate(f.MessageID, 35),
+				CreDtTm:  f.CreatedAt.UTC().Format(time.RFC3339),
+				NbOfTxs:  count,
+				CtrlSum:  total,
+				InitgPty: pain001Party{Nm: truncate(f.Originator.Name, 70)},
+			},
+			PmtInf: info,
+		},
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,81 @@
+package payout
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidIBAN(t *testing.T) {
+	if !ValidIBAN("GB82 WEST 1234 5698 7654 32") {
+		t.Error("expected valid IBAN")
+	}
+	if ValidIBAN("GB82WEST12345698765433") {
+		t.Error("expected checksum failure")
+	}
+}
+
+func TestValidRoutingNumber(t *testing.T) {
+	if !ValidRoutingNumber("011000015") {
+		t.Error("expected valid routing number")
+	}
+	if ValidRoutingNumber("011000016") {
+		t.Error("expected checksum failure")
+	}
+}
+
+func TestRenderNACHA(t *testing.T) {
+	f := File{
+		Format:    FormatNACHA,
+		MessageID: "abc12345",
+		Originator: Originator{
+			Name:          "Acme Corp",
+			RoutingNumber: "011000015",
+			CompanyID:     "1234567890",
+		},
+		Instructions: []Instruction{
+			{Reference: "m-1", Name: "Jane Doe", Amount: big.NewRat(12345, 100), Currency: "USD", RoutingNumber: "011000015", AccountNumber: "123456789"},
+			{Reference: "m-2", Name: "John Doe", Amount: big.NewRat(50, 1), Currency: "USD", RoutingNumber: "011000015", AccountNumber: "987654321", AccountType: "savings"},
+		},
+		ExecutionDate: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		CreatedAt:     time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := RenderNACHA(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines)%10 != 0 {
+		t.Errorf("expected a multiple of 10 records, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) != nachaRecordLength {
+			t.Errorf("record %d: length %d", i+1, len(line))
+		}
+	}
+	if got := lines[2][29:39]; got != "0000012345" {
+		t.Errorf("entry amount = %s", got)
+	}
+	if got := lines[4][32:44]; got != "000000017345" {
+		t.Errorf("batch credit total = %s", got)
+	}
+}
+
+func TestValidateCollectsErrors(t *testing.T) {
+	err := Validate(File{
+		Format:       FormatPain001,
+		Originator:   Originator{Name: "Acme", IBAN: "bad"},
+		Instructions: []Instruction{{Name: "", Amount: big.NewRat(1, 1000), Currency: "EUR", IBAN: "bad"}},
+	})
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"originator IBAN", "beneficiary name", "decimal places", "IBAN \"bad\""} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package payout
+
+import (
+	"math/big"
+	"time"
+)
+
+const (
+	FormatPain001 = "pain.001"
+	FormatNACHA   = "nacha"
+)
+
+// Originator is the party whose bank account funds the payouts.
+type Originator struct {
+	Name string `json:"name"`
+
+	// SEPA / ISO 20022
+	IBAN string `json:"iban,omitempty"`
+	BIC  string `json:"bic,omitempty"`
+
+	// NACHA
+	RoutingNumber   string `json:"routing_number,omitempty"` // originating DFI (ODFI)
+	CompanyID       string `json:"company_id,omitempty"`     // 10 chars, usually "1" + EIN
+	DestinationName string `json:"destination_name,omitempty"`
+}
+
+// Instruction is a single credit transfer to a beneficiary.
+type Instruction struct {
+	Reference   string
+	Name        string
+	Amount      *big.Rat
+	Currency    string
+	Description string
+
+	IBAN string
+	BIC  string
+
+	RoutingNumber string
+	AccountNumber string
+	AccountType   string // checking (default) or savings
+}
+
+type File struct {
+	Format        string
+	MessageID     string
+	Originator    Originator
+	Instructions  []Instruction
+	ExecutionDate time.Time
+	CreatedAt     time.Time
+}
+
+// Total returns the control sum of all instructions.
+func (f File) Total() *big.Rat {
+	total := new(big.Rat)
+	for _, in := range f.Instructions {
+		total.Add(total, in.Amount)
+	}
+	return total
+}
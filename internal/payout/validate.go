@@ -0,0 +1,138 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	bicPattern     = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	ibanPattern    = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	routingPattern = regexp.MustCompile(`^[0-9]{9}$`)
+	accountPattern = regexp.MustCompile(`^[0-9A-Za-z-]{1,17}$`)
+)
+
+// Validate checks the file against the rules of its format and returns every problem found.
+func Validate(f File) error {
+	var errs []error
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(f.Instructions) == 0 {
+		addErr("at least one payout instruction required")
+	}
+	if f.Originator.Name == "" {
+		addErr("originator name required")
+	}
+
+	switch f.Format {
+	case FormatPain001:
+		if !ValidIBAN(f.Originator.IBAN) {
+			addErr("originator IBAN %q is invalid", f.Originator.IBAN)
+		}
+		if f.Originator.BIC != "" && !bicPattern.MatchString(f.Originator.BIC) {
+			addErr("originator BIC %q is invalid", f.Originator.BIC)
+		}
+	case FormatNACHA:
+		if !ValidRoutingNumber(f.Originator.RoutingNumber) {
+			addErr("originator routing number %q is invalid", f.Originator.RoutingNumber)
+		}
+		if len(f.Originator.CompanyID) == 0 || len(f.Originator.CompanyID) > 10 {
+			addErr("originator company_id must be 1-10 characters")
+		}
+	default:
+		addErr("unsupported format %q", f.Format)
+	}
+
+	for i, in := range f.Instructions {
+		label := fmt.Sprintf("instruction %d", i+1)
+		if in.Reference != "" {
+			label = fmt.Sprintf("instruction %q", in.Reference)
+		}
+
+		if in.Name == "" {
+			addErr("%s: beneficiary name required", label)
+		}
+		if in.Amount == nil || in.Amount.Sign() <= 0 {
+			addErr("%s: amount must be positive", label)
+		} else if !hasAtMostDecimals(in.Amount, 2) {
+			addErr("%s: amount %s has more than 2 decimal places", label, in.Amount.FloatString(10))
+		}
+
+		switch f.Format {
+		case FormatPain001:
+			if in.Currency == "" {
+				addErr("%s: currency required", label)
+			}
+			if !ValidIBAN(in.IBAN) {
+				addErr("%s: IBAN %q is invalid", label, in.IBAN)
+			}
+			if in.BIC != "" && !bicPattern.MatchString(in.BIC) {
+				addErr("%s: BIC %q is invalid", label, in.BIC)
+			}
+		case FormatNACHA:
+			if in.Currency != "USD" {
+				addErr("%s: NACHA files only support USD, got %q", label, in.Currency)
+			}
+			if !ValidRoutingNumber(in.RoutingNumber) {
+				addErr("%s: routing number %q is invalid", label, in.RoutingNumber)
+			}
+			if !accountPattern.MatchString(in.AccountNumber) {
+				addErr("%s: account number must be 1-17 alphanumeric characters", label)
+			}
+			if in.AccountType != "" && in.AccountType != "checking" && in.AccountType != "savings" {
+				addErr("%s: account_type must be checking or savings", label)
+			}
+			if in.Amount != nil && in.Amount.Cmp(big.NewRat(99999999_99, 100)) > 0 {
+				addErr("%s: amount exceeds the NACHA entry maximum", label)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidIBAN checks the IBAN structure and its ISO 7064 mod-97 checksum.
+func ValidIBAN(iban string) bool {
+	iban = strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for _, c := range rearranged {
+		var v int
+		if c >= '0' && c <= '9' {
+			v = int(c - '0')
+			remainder = (remainder*10 + v) % 97
+		} else {
+			v = int(c-'A') + 10
+			remainder = (remainder*100 + v) % 97
+		}
+	}
+	return remainder == 1
+}
+
+// ValidRoutingNumber checks an ABA routing number's weighted checksum.
+func ValidRoutingNumber(rtn string) bool {
+	if !routingPattern.MatchString(rtn) {
+		return false
+	}
+	weights := []int{3, 7, 1, 3, 7, 1, 3, 7, 1}
+	sum := 0
+	for i, c := range rtn {
+		sum += int(c-'0') * weights[i]
+	}
+	return sum%10 == 0
+}
+
+func hasAtMostDecimals(r *big.Rat, places int) bool {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+	return scaled.IsInt()
+}
@@ -0,0 +1,329 @@
+// Package privacy answers data subject requests for dashboard users: an export of the
+// personal data held about a user, and the deletion of their account.
+//
+// A user's personal data is their account and memberships in the control database,
+// their email copied onto notes, saved views, organization deletion requests and the
+// support access trail, and their ID on the API keys they created or approved.
+// Deleting the account removes it and its memberships and replaces those copies,
+// keeping the records themselves since they belong to the organization's audit trail.
+// Ledger events keep the bare user ID, which nothing links back to the person once the
+// account is gone.
+package privacy
+
+import (
+	"Go_FormanceLegder/internal/db"
+	"Go_FormanceLegder/internal/mail"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// deletedUser replaces a deleted user's email wherever a record outlives the account.
+const deletedUser = "deleted user"
+
+var ErrUserNotFound = errors.New("user not found")
+
+// OwnershipError blocks deleting the last owner of an organization.
+type OwnershipError struct {
+	Organizations []string
+}
+
+func (e *OwnershipError) Error() string {
+	return fmt.Sprintf("sole owner of %s: transfer ownership or delete the organization first",
+		strings.Join(e.Organizations, ", "))
+}
+
+type Service struct {
+	Control *pgxpool.Pool
+	Router  *db.Router
+	Mailer  *mail.Mailer
+}
+
+// Export is the personal data held about a user.
+type Export struct {
+	User               ExportUser         `json:"user"`
+	Memberships        []ExportMembership `json:"memberships"`
+	APIKeys            []ExportAPIKey     `json:"api_keys"`
+	Notes              []ExportNote       `json:"notes"`
+	SavedViews         []ExportSavedView  `json:"saved_views"`
+	OffboardingActions []ExportAction     `json:"organization_deletion_requests"`
+	ExportedAt         string             `json:"exported_at"`
+}
+
+type ExportUser struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ExportMembership struct {
+	OrganizationID   string `json:"organization_id"`
+	OrganizationName string `json:"organization_name"`
+	Role             string `json:"role"`
+	JoinedAt         string `json:"joined_at"`
+}
+
+type ExportAPIKey struct {
+	ID       string `json:"id"`
+	LedgerID string `json:"ledger_id"`
+	Prefix   string `json:"prefix"`
+	Role     string `json:"role"` // created or approved
+	At       string `json:"at"`
+}
+
+type ExportNote struct {
+	ID         string `json:"id"`
+	LedgerID   string `json:"ledger_id"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Body       string `json:"body"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type ExportSavedView struct {
+	ID        string `json:"id"`
+	LedgerID  string `json:"ledger_id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ExportAction struct {
+	OrganizationName string `json:"organization_name"`
+	Action           string `json:"action"` // requested or cancelled
+	At               string `json:"at"`
+}
+
+// Export collects the personal data held about a user across the control and the
+// regional databases.
+func (s *Service) Export(ctx context.Context, userID string) (Export, error) {
+	var e Export
+	var createdAt time.Time
+	err := s.Control.QueryRow(ctx, `SELECT id, email, created_at FROM users WHERE id = $1`, userID).
+		Scan(&e.User.ID, &e.User.Email, &createdAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e, ErrUserNotFound
+	}
+	if err != nil {
+		return e, err
+	}
+	e.User.CreatedAt = createdAt.Format(time.RFC3339)
+	email := e.User.Email
+
+	e.Memberships, err = collect(ctx, s.Control, `
+		SELECT o.id::text, o.name, ou.role, ou.created_at
+		FROM org_users ou JOIN organizations o ON o.id = ou.organization_id
+		WHERE ou.user_id = $1
+		ORDER BY ou.created_at
+	`, func(row pgx.CollectableRow) (ExportMembership, error) {
+		var m ExportMembership
+		var at time.Time
+		err := row.Scan(&m.OrganizationID, &m.OrganizationName, &m.Role, &at)
+		m.JoinedAt = at.Format(time.RFC3339)
+		return m, err
+	}, userID)
+	if err != nil {
+		return e, err
+	}
+
+	// API keys are identity data, kept in the control database
+	e.APIKeys, err = collect(ctx, s.Control, `
+		SELECT id::text, ledger_id::text, prefix, 'created', created_at FROM api_keys WHERE created_by = $1
+		UNION ALL
+		SELECT id::text, ledger_id::text, prefix, 'approved', COALESCE(approved_at, created_at) FROM api_keys WHERE approved_by = $1
+		ORDER BY 5
+	`, func(row pgx.CollectableRow) (ExportAPIKey, error) {
+		var k ExportAPIKey
+		var at time.Time
+		err := row.Scan(&k.ID, &k.LedgerID, &k.Prefix, &k.Role, &at)
+		k.At = at.Format(time.RFC3339)
+		return k, err
+	}, userID)
+	if err != nil {
+		return e, err
+	}
+
+	e.OffboardingActions, err = collect(ctx, s.Control, `
+		SELECT organization_name, 'requested', created_at FROM org_offboardings WHERE requested_by = $1
+		UNION ALL
+		SELECT organization_name, 'cancelled', cancelled_at FROM org_offboardings WHERE cancelled_by = $1
+		ORDER BY 3
+	`, func(row pgx.CollectableRow) (ExportAction, error) {
+		var a ExportAction
+		var at time.Time
+		err := row.Scan(&a.OrganizationName, &a.Action, &at)
+		a.At = at.Format(time.RFC3339)
+		return a, err
+	}, email)
+	if err != nil {
+		return e, err
+	}
+
+	// Notes and saved views live with the ledger data, in every regional database
+	e.Notes, e.SavedViews = []ExportNote{}, []ExportSavedView{}
+	for region, pool := range s.pools() {
+		notes, err := collect(ctx, pool, `
+			SELECT id::text, ledger_id::text, target_type, target_id, body, created_at
+			FROM notes WHERE author_id = $1 ORDER BY created_at
+		`, func(row pgx.CollectableRow) (ExportNote, error) {
+			var n ExportNote
+			var at time.Time
+			err := row.Scan(&n.ID, &n.LedgerID, &n.TargetType, &n.TargetID, &n.Body, &at)
+			n.CreatedAt = at.Format(time.RFC3339)
+			return n, err
+		}, userID)
+		if err != nil {
+			return e, fmt.Errorf("region %s: %w", region, err)
+		}
+		e.Notes = append(e.Notes, notes...)
+
+		views, err := collect(ctx, pool, `
+			SELECT id::text, ledger_id::text, name, created_at FROM saved_views WHERE author_email = $1 ORDER BY created_at
+		`, func(row pgx.CollectableRow) (ExportSavedView, error) {
+			var v ExportSavedView
+			var at time.Time
+			err := row.Scan(&v.ID, &v.LedgerID, &v.Name, &at)
+			v.CreatedAt = at.Format(time.RFC3339)
+			return v, err
+		}, email)
+		if err != nil {
+			return e, fmt.Errorf("region %s: %w", region, err)
+		}
+		e.SavedViews = append(e.SavedViews, views...)
+	}
+
+	e.ExportedAt = time.Now().UTC().Format(time.RFC3339)
+	return e, nil
+}
+
+// Delete deletes a user's account. It fails with an *OwnershipError while the user is
+// the only owner of an organization. The regional copies are replaced first, so a
+// failure part-way is finished by retrying.
+func (s *Service) Delete(ctx context.Context, userID string) error {
+	var email string
+	err := s.Control.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.Control.Query(ctx, `
+		SELECT o.name
+		FROM org_users ou JOIN organizations o ON o.id = ou.organization_id
+		WHERE ou.user_id = $1 AND ou.role = 'owner'
+		  AND NOT EXISTS (
+			SELECT 1 FROM org_users other
+			WHERE other.organization_id = ou.organization_id AND other.role = 'owner' AND other.user_id <> $1
+		  )
+		ORDER BY o.name
+	`, userID)
+	if err != nil {
+		return err
+	}
+	sole, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	if len(sole) > 0 {
+		return &OwnershipError{Organizations: sole}
+	}
+
+	for region, pool := range s.pools() {
+		if pool == s.Control {
+			continue
+		}
+		err := pgx.BeginTxFunc(ctx, pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			return redact(ctx, tx, userID, email)
+		})
+		if err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+
+	tx, err := s.Control.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := redact(ctx, tx, userID, email); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE org_offboardings SET requested_by = $2 WHERE requested_by = $1
+	`, email, deletedUser); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE org_offboardings SET cancelled_by = $2 WHERE cancelled_by = $1
+	`, email, deletedUser); err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		`UPDATE support_audit SET actor_email = $2 WHERE actor_email = $1`,
+		`UPDATE support_audit SET subject_email = $2 WHERE subject_email = $1`,
+		`UPDATE support_consents SET used_by = $2 WHERE used_by = $1`,
+	} {
+		if _, err := tx.Exec(ctx, stmt, email, deletedUser); err != nil {
+			return err
+		}
+	}
+	// Cascades to the memberships
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if err := s.Mailer.Send([]string{email}, "Your account was deleted",
+		"Your dashboard account and the personal data held with it were deleted as you requested."); err != nil {
+		log.Printf("user deletion: notify: %v", err)
+	}
+	return nil
+}
+
+// redact replaces a user's email and ID on the records of one database that outlive
+// the account.
+func redact(ctx context.Context, tx pgx.Tx, userID, email string) error {
+	for _, stmt := range []struct {
+		sql string
+		arg string
+	}{
+		{`UPDATE notes SET author_email = '` + deletedUser + `' WHERE author_id = $1`, userID},
+		{`UPDATE saved_views SET author_email = '` + deletedUser + `' WHERE author_email = $1`, email},
+		{`UPDATE api_keys SET created_by = NULL WHERE created_by = $1`, userID},
+		{`UPDATE api_keys SET approved_by = NULL WHERE approved_by = $1`, userID},
+	} {
+		if _, err := tx.Exec(ctx, stmt.sql, stmt.arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) pools() map[string]*pgxpool.Pool {
+	if s.Router == nil {
+		return map[string]*pgxpool.Pool{db.ControlRegion: s.Control}
+	}
+	return s.Router.Pools()
+}
+
+func collect[T any](ctx context.Context, pool *pgxpool.Pool, sql string, scan pgx.RowToFunc[T], args ...any) ([]T, error) {
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, err := pgx.CollectRows(rows, scan)
+	if items == nil {
+		items = []T{}
+	}
+	return items, err
+}
@@ -0,0 +1,30 @@
+package projector
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// applyAccountMetadataUpdated applies a JSON merge patch to an account's metadata: null
+// values remove keys, everything else is set. Replaying patches in order over the final
+// state yields the final state, so this is safe to re-apply.
+func applyAccountMetadataUpdated(ctx context.Context, tx pgx.Tx, ledgerID string, payload *events.AccountMetadataUpdated) error {
+	set := map[string]any{}
+	remove := []string{}
+	for key, value := range payload.Metadata {
+		if value == nil {
+			remove = append(remove, key)
+		} else {
+			set[key] = value
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE accounts
+		SET metadata = (metadata - $3::text[]) || $4::jsonb
+		WHERE id = $1 AND ledger_id = $2
+	`, payload.AccountID, ledgerID, remove, set)
+	return err
+}
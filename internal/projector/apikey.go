@@ -0,0 +1,63 @@
+package projector
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// APIKeys maintains the api_keys read model from key lifecycle events. It runs apart
+// from Ledger, so a backlog of postings doesn't hold up key approvals.
+var APIKeys = Projection{
+	Name:   "api_keys",
+	Events: []string{"APIKeyRequested", "APIKeyCreated", "APIKeyApproved", "APIKeyRejected", "APIKeyRevoked"},
+	Tables: []string{"api_keys"},
+	Apply: func(ctx context.Context, tx pgx.Tx, e Event) error {
+		payload, ok := e.Payload.(*events.APIKeyChanged)
+		if !ok {
+			return fmt.Errorf("invalid api key payload")
+		}
+		return applyAPIKeyEvent(ctx, tx, e.LedgerID, e.Type, e.OccurredAt, payload)
+	},
+}
+
+func init() {
+	Register(APIKeys)
+}
+
+func applyAPIKeyEvent(ctx context.Context, tx pgx.Tx, ledgerID, eventType string, occurredAt time.Time, payload *events.APIKeyChanged) error {
+	keyID, userID := payload.APIKeyID, payload.UserID
+
+	var err error
+	switch eventType {
+	case "APIKeyRequested", "APIKeyCreated":
+		// The row, with the key's hash, is written with the event; only later transitions
+		// are projected
+	case "APIKeyApproved":
+		_, err = tx.Exec(ctx, `
+			UPDATE api_keys
+			SET status = 'active', is_active = true, approved_by = NULLIF($2, '')::uuid, approved_at = $3
+			WHERE id = $1 AND status = 'pending_approval'
+		`, keyID, userID, occurredAt)
+	case "APIKeyRejected":
+		_, err = tx.Exec(ctx, `
+			UPDATE api_keys
+			SET status = 'rejected', is_active = false
+			WHERE id = $1 AND status = 'pending_approval'
+		`, keyID)
+	case "APIKeyRevoked":
+		_, err = tx.Exec(ctx, `
+			UPDATE api_keys
+			SET status = 'revoked', is_active = false, revoked_at = $2
+			WHERE id = $1
+		`, keyID, occurredAt)
+	}
+	if err != nil {
+		return fmt.Errorf("project %s failed: %w", eventType, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package projector
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BackfillTransactionAmounts recomputes the amount of every projected transaction from
+// its postings, as applyTransactionPosted does for new ones (transactions projected
+// before amounts were computed carry 0). It walks the table in id order, one batch per
+// statement, so it can run next to the live projector; it returns the number of rows
+// changed.
+func BackfillTransactionAmounts(ctx context.Context, db *pgxpool.Pool, batchSize int) (int64, error) {
+	var updated int64
+	after := "00000000-0000-0000-0000-000000000000"
+	for {
+		var last *string
+		var n int64
+		err := db.QueryRow(ctx, `
+			WITH batch AS (
+				SELECT id, ledger_id, currency FROM transactions WHERE id > $1::uuid ORDER BY id LIMIT $2
+			), totals AS (
+				SELECT b.id, b.ledger_id,
+					COALESCE(SUM(p.amount) FILTER (
+						WHERE p.direction = 'debit' AND COALESCE(p.currency, b.currency) = b.currency
+					), 0) AS total
+				FROM batch b
+				LEFT JOIN postings p ON p.transaction_id = b.id AND p.ledger_id = b.ledger_id
+				GROUP BY b.id, b.ledger_id
+			), changed AS (
+				UPDATE transactions t
+				SET amount = totals.total
+				FROM totals
+				WHERE t.id = totals.id AND t.ledger_id = totals.ledger_id AND t.amount <> totals.total
+				RETURNING 1
+			)
+			SELECT (SELECT id::text FROM batch ORDER BY id DESC LIMIT 1), (SELECT COUNT(*) FROM changed)
+		`, after, batchSize).Scan(&last, &n)
+		if err != nil {
+			return updated, err
+		}
+		updated += n
+		if last == nil {
+			return updated, nil
+		}
+		after = *last
+	}
+}
@@ -0,0 +1,128 @@
+package projector
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// accountCacheSize bounds the account cache; it is emptied when full, which only costs
+// lookups until the active accounts are cached again.
+const accountCacheSize = 100_000
+
+type accountKey struct{ LedgerID, Code string }
+
+type accountRef struct {
+	ID      string
+	TaxCode string
+}
+
+// accountCache maps account codes to IDs for posting projection. An account's ID and
+// tax code never change, and shadow schemas copy accounts with their IDs, so one cache
+// serves every projector of the process.
+type accountCache struct {
+	mu   sync.RWMutex
+	refs map[accountKey]accountRef
+}
+
+var accounts = newAccountCache()
+
+func newAccountCache() *accountCache {
+	return &accountCache{refs: map[accountKey]accountRef{}}
+}
+
+func (c *accountCache) get(key accountKey) (accountRef, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ref, ok := c.refs[key]
+	return ref, ok
+}
+
+func (c *accountCache) put(key accountKey, ref accountRef) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.refs) >= accountCacheSize {
+		c.refs = map[accountKey]accountRef{}
+	}
+	c.refs[key] = ref
+}
+
+// invalidate drops an account after an event about it, so the next posting reads it
+// again.
+func (c *accountCache) invalidate(key accountKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.refs, key)
+}
+
+// lookupAccount resolves a posting's account from the cache, or from the accounts table
+// on a miss.
+func lookupAccount(ctx context.Context, tx pgx.Tx, ledgerID, code string) (accountRef, error) {
+	key := accountKey{ledgerID, code}
+	if ref, ok := accounts.get(key); ok {
+		return ref, nil
+	}
+
+	var ref accountRef
+	var taxCode *string
+	err := tx.QueryRow(ctx, `
+		SELECT id, tax_code FROM accounts WHERE ledger_id = $1 AND code = $2
+	`, ledgerID, code).Scan(&ref.ID, &taxCode)
+	if err != nil {
+		return ref, fmt.Errorf("account %s not found: %w", code, err)
+	}
+	if taxCode != nil {
+		ref.TaxCode = *taxCode
+	}
+	accounts.put(key, ref)
+	return ref, nil
+}
+
+// resolveAccounts caches the accounts a batch posts to in one query, so projecting
+// the batch looks none of them up one by one.
+func resolveAccounts(ctx context.Context, tx pgx.Tx, batch []Event) error {
+	var ledgerIDs, codes []string
+	seen := map[accountKey]bool{}
+	for _, e := range batch {
+		posted, ok := e.Payload.(*events.TransactionPosted)
+		if !ok {
+			continue
+		}
+		for _, p := range posted.Postings {
+			key := accountKey{e.LedgerID, p.AccountCode}
+			if p.AccountCode == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := accounts.get(key); !ok {
+				ledgerIDs = append(ledgerIDs, e.LedgerID)
+				codes = append(codes, p.AccountCode)
+			}
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT a.ledger_id::text, a.code, a.id, COALESCE(a.tax_code, '')
+		FROM accounts a
+		JOIN unnest($1::uuid[], $2::text[]) AS k (ledger_id, code) ON a.ledger_id = k.ledger_id AND a.code = k.code
+	`, ledgerIDs, codes)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key accountKey
+		var ref accountRef
+		if err := rows.Scan(&key.LedgerID, &key.Code, &ref.ID, &ref.TaxCode); err != nil {
+			return err
+		}
+		accounts.put(key, ref)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,35 @@
+package projector
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestAccountCache(t *testing.T) {
+	c := newAccountCache()
+	key := accountKey{"l1", "assets:cash"}
+	if _, ok := c.get(key); ok {
+		t.Fatal("empty cache should miss")
+	}
+
+	c.put(key, accountRef{ID: "a1", TaxCode: "VAT"})
+	if ref, ok := c.get(key); !ok || ref.ID != "a1" || ref.TaxCode != "VAT" {
+		t.Fatalf("expected the cached account, got %+v (%v)", ref, ok)
+	}
+	if _, ok := c.get(accountKey{"l2", "assets:cash"}); ok {
+		t.Fatal("the same code in another ledger should miss")
+	}
+
+	c.invalidate(key)
+	if _, ok := c.get(key); ok {
+		t.Fatal("invalidated account should miss")
+	}
+
+	for i := range accountCacheSize {
+		c.put(accountKey{"l1", strconv.Itoa(i)}, accountRef{ID: "x"})
+	}
+	c.put(key, accountRef{ID: "a1"})
+	if len(c.refs) != 1 {
+		t.Fatalf("a full cache should be emptied before adding, got %d entries", len(c.refs))
+	}
+}
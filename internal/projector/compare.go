@@ -0,0 +1,146 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Reports list at most this many differing rows per category.
+const shadowReportLimit = 100
+
+type AccountMismatch struct {
+	LedgerID      string `json:"ledger_id"`
+	Code          string `json:"code"`
+	LiveBalance   string `json:"live_balance"`
+	ShadowBalance string `json:"shadow_balance"`
+}
+
+// ShadowReport is the difference between the live read model and a shadow projection.
+// Differences are only meaningful once CaughtUp is true, i.e. both projectors have
+// processed the same events.
+type ShadowReport struct {
+	Schema              string            `json:"schema"`
+	LiveOffset          int64             `json:"live_offset"` // sequence of the last event projected
+	ShadowOffset        int64             `json:"shadow_offset"`
+	CaughtUp            bool              `json:"caught_up"`
+	AccountMismatches   []AccountMismatch `json:"account_mismatches"`
+	MissingTransactions []string          `json:"missing_transactions"` // projected live but not in shadow
+	ExtraTransactions   []string          `json:"extra_transactions"`   // projected in shadow but not live
+	PostingMismatches   []string          `json:"posting_mismatches"`   // transactions whose postings differ
+}
+
+// Matches reports whether the shadow projection is identical to the live one.
+func (r ShadowReport) Matches() bool {
+	return r.CaughtUp && len(r.AccountMismatches) == 0 && len(r.MissingTransactions) == 0 &&
+		len(r.ExtraTransactions) == 0 && len(r.PostingMismatches) == 0
+}
+
+// CompareShadow diffs the shadow schema against the live read model within a single
+// snapshot, so a projector committing mid-comparison cannot produce false positives.
+func CompareShadow(ctx context.Context, db *pgxpool.Pool, schema string) (ShadowReport, error) {
+	report := ShadowReport{Schema: schema}
+	if !schemaNamePattern.MatchString(schema) || schema == "public" {
+		return report, fmt.Errorf("invalid shadow schema name %q", schema)
+	}
+	ident := pgx.Identifier{schema}.Sanitize()
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT MIN(last_processed_sequence) FROM projector_offsets WHERE split_part(projector_name, '#', 1) = 'ledger'), 0),
+			COALESCE((SELECT last_processed_sequence FROM projector_offsets WHERE projector_name = $1), 0)
+	`, "shadow:"+schema).Scan(&report.LiveOffset, &report.ShadowOffset)
+	if err != nil {
+		return report, err
+	}
+	report.CaughtUp = report.LiveOffset == report.ShadowOffset
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT COALESCE(l.ledger_id, s.ledger_id), COALESCE(l.code, s.code),
+			COALESCE(l.balance::text, ''), COALESCE(s.balance::text, '')
+		FROM public.accounts l
+		FULL JOIN %s.accounts s ON s.id = l.id
+		WHERE l.balance IS DISTINCT FROM s.balance
+		ORDER BY 1, 2
+		LIMIT %d
+	`, ident, shadowReportLimit))
+	if err != nil {
+		return report, err
+	}
+	report.AccountMismatches = []AccountMismatch{}
+	for rows.Next() {
+		var m AccountMismatch
+		if err := rows.Scan(&m.LedgerID, &m.Code, &m.LiveBalance, &m.ShadowBalance); err != nil {
+			rows.Close()
+			return report, err
+		}
+		report.AccountMismatches = append(report.AccountMismatches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	txColumns := `id::text, ledger_id, external_id, amount, currency, occurred_at, metadata, entity_code, description, value_date`
+	report.MissingTransactions, err = queryIDs(ctx, tx, fmt.Sprintf(`
+		SELECT id FROM (
+			SELECT %[2]s FROM public.transactions
+			EXCEPT
+			SELECT %[2]s FROM %[1]s.transactions
+		) d ORDER BY id LIMIT %[3]d
+	`, ident, txColumns, shadowReportLimit))
+	if err != nil {
+		return report, err
+	}
+	report.ExtraTransactions, err = queryIDs(ctx, tx, fmt.Sprintf(`
+		SELECT id FROM (
+			SELECT %[2]s FROM %[1]s.transactions
+			EXCEPT
+			SELECT %[2]s FROM public.transactions
+		) d ORDER BY id LIMIT %[3]d
+	`, ident, txColumns, shadowReportLimit))
+	if err != nil {
+		return report, err
+	}
+
+	// Posting ids are generated during projection, so postings are compared by content
+	postingColumns := `transaction_id, account_id, direction, amount, currency, tax_code`
+	report.PostingMismatches, err = queryIDs(ctx, tx, fmt.Sprintf(`
+		SELECT DISTINCT transaction_id::text FROM (
+			(SELECT %[2]s FROM public.postings EXCEPT ALL SELECT %[2]s FROM %[1]s.postings)
+			UNION ALL
+			(SELECT %[2]s FROM %[1]s.postings EXCEPT ALL SELECT %[2]s FROM public.postings)
+		) d ORDER BY 1 LIMIT %[3]d
+	`, ident, postingColumns, shadowReportLimit))
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func queryIDs(ctx context.Context, tx pgx.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,51 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// poisonEvent is an event that cannot be applied because of the event itself: its
+// payload does not decode, or applying it panicked. Retrying the batch would fail the
+// same way forever, so the projector dead-letters the event and moves on.
+type poisonEvent struct {
+	ID, LedgerID, Type string
+	Sequence           int64
+	Err                error
+	Stack              []byte // of the panic, if any
+}
+
+func (e *poisonEvent) Error() string { return e.Err.Error() }
+func (e *poisonEvent) Unwrap() error { return e.Err }
+
+// applyRecovered applies e with projection, turning a panic into a poisonEvent so one
+// pathological payload doesn't take the worker down.
+func applyRecovered(ctx context.Context, tx pgx.Tx, projection Projection, e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &poisonEvent{ID: e.ID, LedgerID: e.LedgerID, Type: e.Type, Sequence: e.Sequence,
+				Err: fmt.Errorf("panic: %v", r), Stack: debug.Stack()}
+		}
+	}()
+	return projection.Apply(ctx, tx, e)
+}
+
+// deadLetter records a poison event for the projector, outside the batch that failed on
+// it, so the retried batch skips it.
+func (p *Projector) deadLetter(ctx context.Context, poison *poisonEvent) error {
+	_, err := p.DB.Exec(ctx, `
+		INSERT INTO projector_dead_letters (projector_name, event_id, sequence, ledger_id, event_type, error, stack)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (projector_name, event_id) DO NOTHING
+	`, p.Name, poison.ID, poison.Sequence, poison.LedgerID, poison.Type, poison.Err.Error(), string(poison.Stack))
+	if err != nil {
+		return fmt.Errorf("dead-letter event %s: %w", poison.ID, err)
+	}
+	log.Printf("projector %s dead-lettered event %s (%s, sequence %d): %v",
+		p.OffsetName(), poison.ID, poison.Type, poison.Sequence, poison.Err)
+	return nil
+}
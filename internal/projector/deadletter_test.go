@@ -0,0 +1,35 @@
+package projector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestApplyRecovered(t *testing.T) {
+	panicking := Projection{Name: "broken", Apply: func(ctx context.Context, tx pgx.Tx, e Event) error {
+		var m map[string]any
+		_ = m["transaction_id"].(string)
+		return nil
+	}}
+	e := Event{ID: "e1", Sequence: 7, LedgerID: "l1", Type: "TransactionPosted"}
+
+	err := applyRecovered(context.Background(), nil, panicking, e)
+	var poison *poisonEvent
+	if !errors.As(err, &poison) {
+		t.Fatalf("expected a poison event, got %v", err)
+	}
+	if poison.ID != "e1" || poison.Sequence != 7 || !strings.HasPrefix(poison.Error(), "panic: ") || len(poison.Stack) == 0 {
+		t.Fatalf("unexpected capture: %+v", poison)
+	}
+
+	failing := Projection{Name: "failing", Apply: func(ctx context.Context, tx pgx.Tx, e Event) error {
+		return errors.New("conn reset")
+	}}
+	if err := applyRecovered(context.Background(), nil, failing, e); err == nil || errors.As(err, &poison) {
+		t.Fatalf("an ordinary error should be retried, not dead-lettered, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package projector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// health counts a projector's failed batches since the process started.
+type health struct {
+	mu          sync.Mutex
+	errors      int64
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func (h *health) record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errors++
+	h.lastError = err.Error()
+	h.lastErrorAt = time.Now()
+}
+
+// Status is how far a projector's read model trails the event stream.
+type Status struct {
+	Region       string  `json:"region"`
+	Projector    string  `json:"projector"`
+	LastSequence int64   `json:"last_processed_sequence"`
+	HeadSequence int64   `json:"head_sequence"`
+	LagEvents    int64   `json:"lag_events"`  // events after the offset, of any shard
+	LagSeconds   float64 `json:"lag_seconds"` // age of the oldest unprocessed event
+	Errors       int64   `json:"errors"`
+	LastError    string  `json:"last_error,omitempty"`
+	LastErrorAt  string  `json:"last_error_at,omitempty"`
+}
+
+// Status reads the projector's offset against the head of the event stream.
+func (p *Projector) Status(ctx context.Context) (Status, error) {
+	s := Status{Projector: p.OffsetName()}
+	var oldestPending *time.Time
+	err := p.DB.QueryRow(ctx, `
+		WITH o AS (SELECT `+offsetSQL+` AS seq)
+		SELECT o.seq,
+			COALESCE((SELECT MAX(sequence) FROM events), 0),
+			(SELECT created_at FROM events WHERE sequence > o.seq AND `+shardSQL+` ORDER BY sequence LIMIT 1)
+		FROM o
+	`, p.OffsetName(), p.Name, p.Shards, p.Shard).Scan(&s.LastSequence, &s.HeadSequence, &oldestPending)
+	if err != nil {
+		return s, err
+	}
+	s.LagEvents = max(s.HeadSequence-s.LastSequence, 0)
+	if oldestPending != nil {
+		s.LagSeconds = max(time.Since(*oldestPending).Seconds(), 0)
+	}
+
+	p.health.mu.Lock()
+	s.Errors = p.health.errors
+	s.LastError = p.health.lastError
+	if !p.health.lastErrorAt.IsZero() {
+		s.LastErrorAt = p.health.lastErrorAt.Format(time.RFC3339)
+	}
+	p.health.mu.Unlock()
+	return s, nil
+}
+
+// LedgerLag is how far a projector trails on one ledger's events.
+type LedgerLag struct {
+	LedgerID        string
+	PendingEvents   int64
+	OldestPendingAt time.Time
+}
+
+// LaggingLedgers returns the ledgers of the projector's shard with an event it has left
+// unapplied for longer than threshold.
+func (p *Projector) LaggingLedgers(ctx context.Context, threshold time.Duration) ([]LedgerLag, error) {
+	rows, err := p.DB.Query(ctx, `
+		WITH o AS (SELECT `+offsetSQL+` AS seq)
+		SELECT ledger_id, COUNT(*), MIN(created_at)
+		FROM events, o
+		WHERE sequence > o.seq AND `+shardSQL+`
+		GROUP BY ledger_id
+		HAVING MIN(created_at) < NOW() - $5::interval
+		ORDER BY ledger_id
+	`, p.OffsetName(), p.Name, p.Shards, p.Shard, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lagging []LedgerLag
+	for rows.Next() {
+		var l LedgerLag
+		if err := rows.Scan(&l.LedgerID, &l.PendingEvents, &l.OldestPendingAt); err != nil {
+			return nil, err
+		}
+		lagging = append(lagging, l)
+	}
+	return lagging, rows.Err()
+}
+
+// Monitor serves the status of the projectors running in this process, for operators
+// to alert on read-model staleness:
+//
+//	GET /projectors - JSON, one entry per region and projector
+//	GET /metrics    - the same in the Prometheus text format
+type Monitor struct {
+	mu         sync.Mutex
+	projectors []monitored
+}
+
+type monitored struct {
+	region    string
+	projector *Projector
+}
+
+// Add reports p under region.
+func (m *Monitor) Add(region string, p *Projector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.projectors = append(m.projectors, monitored{region, p})
+}
+
+// Statuses reads every projector's status. A projector whose status cannot be read,
+// e.g. because its region's database is down, is logged and left out.
+func (m *Monitor) Statuses(ctx context.Context) []Status {
+	m.mu.Lock()
+	projectors := append([]monitored(nil), m.projectors...)
+	m.mu.Unlock()
+
+	statuses := []Status{}
+	for _, mp := range projectors {
+		s, err := mp.projector.Status(ctx)
+		if err != nil {
+			log.Printf("projector status (%s, region %s): %v", mp.projector.OffsetName(), mp.region, err)
+			continue
+		}
+		s.Region = mp.region
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /projectors", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"projectors": m.Statuses(r.Context())})
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w, m.Statuses(r.Context()))
+	})
+	return mux
+}
+
+// WriteMetrics writes statuses in the Prometheus text exposition format.
+func WriteMetrics(w io.Writer, statuses []Status) {
+	metrics := []struct {
+		name, kind, help string
+		value            func(Status) string
+	}{
+		{"ledger_projector_last_processed_sequence", "gauge", "Sequence of the last event the projector applied.",
+			func(s Status) string { return fmt.Sprint(s.LastSequence) }},
+		{"ledger_projector_lag_events", "gauge", "Events recorded but not yet applied by the projector.",
+			func(s Status) string { return fmt.Sprint(s.LagEvents) }},
+		{"ledger_projector_lag_seconds", "gauge", "Age of the oldest event not yet applied by the projector.",
+			func(s Status) string { return fmt.Sprintf("%.3f", s.LagSeconds) }},
+		{"ledger_projector_errors_total", "counter", "Failed projector batches since the worker started.",
+			func(s Status) string { return fmt.Sprint(s.Errors) }},
+	}
+	labels := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s{region=\"%s\",projector=\"%s\"} %s\n",
+				m.name, labels.Replace(s.Region), labels.Replace(s.Projector), m.value(s))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package projector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	var b strings.Builder
+	WriteMetrics(&b, []Status{
+		{Region: "eu", Projector: "ledger", LastSequence: 90, HeadSequence: 100, LagEvents: 10, LagSeconds: 2.5, Errors: 3},
+		{Region: `a"b`, Projector: "api_keys"},
+	})
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE ledger_projector_lag_events gauge\n",
+		"# TYPE ledger_projector_errors_total counter\n",
+		`ledger_projector_last_processed_sequence{region="eu",projector="ledger"} 90` + "\n",
+		`ledger_projector_lag_events{region="eu",projector="ledger"} 10` + "\n",
+		`ledger_projector_lag_seconds{region="eu",projector="ledger"} 2.500` + "\n",
+		`ledger_projector_errors_total{region="eu",projector="ledger"} 3` + "\n",
+		`ledger_projector_lag_events{region="a\"b",projector="api_keys"} 0` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics miss %q:\n%s", want, out)
+		}
+	}
+}
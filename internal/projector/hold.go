@@ -0,0 +1,59 @@
+package projector
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// applyHoldCreated maintains the holds read model and the held balance of the held
+// account: a hold counts against the account from creation until it is captured or
+// voided. Capture's debit arrives separately as a TransactionPosted event.
+func applyHoldCreated(ctx context.Context, tx pgx.Tx, ledgerID string, occurredAt time.Time, payload *events.HoldCreated) error {
+	metadata := payload.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO holds (id, ledger_id, account_code, destination_code, amount, currency, description, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $9)
+		ON CONFLICT (id) DO NOTHING
+	`, payload.HoldID, ledgerID, payload.AccountCode, payload.DestinationCode, payload.Amount, payload.Currency,
+		payload.Description, metadata, occurredAt)
+	if err != nil {
+		return fmt.Errorf("insert hold failed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE accounts SET held_balance = held_balance + $3 WHERE ledger_id = $1 AND code = $2
+	`, ledgerID, payload.AccountCode, payload.Amount)
+	return err
+}
+
+// releaseHold closes a pending hold as captured or voided and releases its held amount.
+func releaseHold(ctx context.Context, tx pgx.Tx, ledgerID, holdID, status, capturedAmount, transactionID string, occurredAt time.Time) error {
+	// Only a pending hold is released, so a replayed event is a no-op
+	var accountCode, amount string
+	err := tx.QueryRow(ctx, `
+		UPDATE holds
+		SET status = $3, captured_amount = NULLIF($4, '')::numeric, transaction_id = NULLIF($5, '')::uuid, updated_at = $6
+		WHERE id = $1 AND ledger_id = $2 AND status = 'pending'
+		RETURNING account_code, amount::text
+	`, holdID, ledgerID, status, capturedAmount, transactionID, occurredAt).Scan(&accountCode, &amount)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("update hold failed: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE accounts SET held_balance = held_balance - $3 WHERE ledger_id = $1 AND code = $2
+	`, ledgerID, accountCode, amount)
+	return err
+}
@@ -1,8 +1,10 @@
 package projector
 
 import (
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/faults"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -13,12 +15,113 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Projector feeds the event stream to one or more projections under a single offset.
 type Projector struct {
-	DB *pgxpool.Pool
+	DB          *pgxpool.Pool
+	Projections []Projection
+
+	// Name keys the projector's offset; Schema, when set, redirects the read-model
+	// writes to a staging schema (see NewShadowProjector).
+	Name   string
+	Schema string
+
+	// Shard of Shards, when Shards > 1, restricts the projector to the ledgers hashing to
+	// Shard, under its own offset (see NewShardedProjectors).
+	Shard, Shards int
+
+	health health
+}
+
+// NewProjector returns the projector of a single projection, keyed by its name.
+func NewProjector(db *pgxpool.Pool, projection Projection) *Projector {
+	return &Projector{DB: db, Projections: []Projection{projection}, Name: projection.Name}
+}
+
+// NewShardedProjectors splits a projection over shards projectors, each projecting the
+// events of a fixed subset of ledgers with its own offset, so ledgers are projected
+// concurrently while each ledger's events stay in order. Every worker instance can run
+// all of them: a shard's batches take an advisory lock, so instances share the shards
+// rather than applying the same events twice.
+//
+// A shard without an offset yet starts from the lowest offset of the projection's
+// other rows, re-applying what it may have missed, which the projections tolerate. Once
+// every shard of the current count has an offset, the rows of a previous count are
+// deleted, so the shard count can be changed with a restart.
+func NewShardedProjectors(db *pgxpool.Pool, projection Projection, shards int) []*Projector {
+	if shards <= 1 {
+		return []*Projector{NewProjector(db, projection)}
+	}
+	projectors := make([]*Projector, shards)
+	for i := range projectors {
+		projectors[i] = &Projector{DB: db, Projections: []Projection{projection}, Name: projection.Name, Shard: i, Shards: shards}
+	}
+	return projectors
+}
+
+// OffsetName keys the projector's row in projector_offsets: its name, suffixed with
+// #<shard>/<shards> when sharded.
+func (p *Projector) OffsetName() string {
+	if p.Shards <= 1 {
+		return p.Name
+	}
+	return fmt.Sprintf("%s#%d/%d", p.Name, p.Shard, p.Shards)
 }
 
-func NewProjector(db *pgxpool.Pool) *Projector {
-	return &Projector{DB: db}
+// offsetNames are the rows of the projector's current shard count.
+func (p *Projector) offsetNames() []string {
+	if p.Shards <= 1 {
+		return []string{p.Name}
+	}
+	names := make([]string, p.Shards)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s#%d/%d", p.Name, i, p.Shards)
+	}
+	return names
+}
+
+// offsetSQL is the projector's offset: its own row, falling back to the lowest row of
+// the same projection under another shard count. $1 is the offset name, $2 the name.
+const offsetSQL = `COALESCE(
+	(SELECT last_processed_sequence FROM projector_offsets WHERE projector_name = $1),
+	(SELECT MIN(last_processed_sequence) FROM projector_offsets WHERE split_part(projector_name, '#', 1) = $2),
+	0)`
+
+// shardSQL selects the events of shard $4 of $3, by ledger. hashtext is stable across
+// sessions and servers of the same major version.
+const shardSQL = `($3 <= 1 OR mod(hashtext(ledger_id::text)::bigint + 2147483648, $3) = $4)`
+
+// Ledger is the core read model: transactions, their postings and account balances,
+// holds and account metadata. Its writes stay in one projection since postings and
+// balances are only applied when the transaction is new.
+var Ledger = Projection{
+	Name: "ledger",
+	Events: []string{"TransactionPosted", "HoldCreated", "HoldCaptured", "HoldVoided",
+		"AccountMetadataUpdated", "AccountDisabled", "AccountEnabled"},
+	Tables:  []string{"accounts", "transactions", "postings", "holds"},
+	Prepare: resolveAccounts,
+	Apply: func(ctx context.Context, tx pgx.Tx, e Event) error {
+		switch p := e.Payload.(type) {
+		case *events.TransactionPosted:
+			return applyTransactionPosted(ctx, tx, e.LedgerID, p)
+		case *events.HoldCreated:
+			return applyHoldCreated(ctx, tx, e.LedgerID, e.OccurredAt, p)
+		case *events.HoldCaptured:
+			return releaseHold(ctx, tx, e.LedgerID, p.HoldID, "captured", p.Amount, p.TransactionID, e.OccurredAt)
+		case *events.HoldVoided:
+			return releaseHold(ctx, tx, e.LedgerID, p.HoldID, "voided", "", "", e.OccurredAt)
+		case *events.AccountMetadataUpdated:
+			accounts.invalidate(accountKey{e.LedgerID, p.Code})
+			return applyAccountMetadataUpdated(ctx, tx, e.LedgerID, p)
+		case *events.AccountStatusChanged:
+			// The status itself is written with the event; only the cache follows it
+			accounts.invalidate(accountKey{e.LedgerID, p.Code})
+		}
+		return nil
+	},
+}
+
+func init() {
+	Register(Ledger)
 }
 
 func (p *Projector) Run(ctx context.Context) error {
@@ -30,74 +133,161 @@ func (p *Projector) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			if err := p.projectBatch(ctx); err != nil {
-				log.Printf("projection error: %v", err)
+			if err := p.projectBatch(ctx); err != nil && ctx.Err() == nil {
+				p.health.record(err)
+				log.Printf("projection error (%s): %v", p.OffsetName(), err)
 			}
 		}
 	}
 }
 
+// projectBatch projects the next batch of events. A batch that fails on a poison event
+// is rolled back and the event dead-lettered, so the next batch skips it; the failure is
+// still returned, to be counted and logged.
 func (p *Projector) projectBatch(ctx context.Context) error {
-	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	err := p.applyBatch(ctx)
+	var poison *poisonEvent
+	if errors.As(err, &poison) && ctx.Err() == nil {
+		if dlErr := p.deadLetter(ctx, poison); dlErr != nil {
+			return errors.Join(err, dlErr)
+		}
+	}
+	return err
+}
+
+func (p *Projector) applyBatch(ctx context.Context) error {
+	// A shard reads the head of the stream in the snapshot of its events (see below)
+	opts := pgx.TxOptions{}
+	if p.Shards > 1 {
+		opts.IsoLevel = pgx.RepeatableRead
+	}
+	tx, err := p.DB.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
+	// Another worker instance is projecting this shard
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1))`, "projector:"+p.OffsetName()).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	if p.Schema != "" {
+		if err := p.enterShadowSchema(ctx, tx); err != nil {
+			return err
+		}
+	}
+
 	// Load Events
 	type EventData struct {
 		ID, LedgerID, Type string
+		Sequence           int64
 		Payload            []byte
+		OccurredAt         time.Time
+		DeadLettered       bool
 	}
-	var events []EventData
+	var loaded []EventData
 
 	rows, err := tx.Query(ctx, `
-       SELECT id, ledger_id, event_type, payload
+       SELECT id, sequence, ledger_id, event_type, payload, occurred_at,
+              EXISTS (SELECT 1 FROM projector_dead_letters d WHERE d.projector_name = $2 AND d.event_id = events.id)
        FROM events
-       WHERE event_type = 'TransactionPosted'
-         AND id > COALESCE((SELECT last_processed_event_id FROM projector_offsets WHERE projector_name = 'ledger'), '00000000-0000-0000-0000-000000000000')
-       ORDER BY created_at, id
+       WHERE sequence > `+offsetSQL+` AND `+shardSQL+`
+       ORDER BY sequence
        LIMIT 100
-    `)
+    `, p.OffsetName(), p.Name, p.Shards, p.Shard)
 	if err != nil {
 		return err
 	}
 	for rows.Next() {
 		var e EventData
-		if err := rows.Scan(&e.ID, &e.LedgerID, &e.Type, &e.Payload); err != nil {
+		if err := rows.Scan(&e.ID, &e.Sequence, &e.LedgerID, &e.Type, &e.Payload, &e.OccurredAt, &e.DeadLettered); err != nil {
 			rows.Close() // Nhớ close nếu return sớm
 			return err
 		}
-		events = append(events, e)
+		loaded = append(loaded, e)
 	}
 	rows.Close()
 
-	if len(events) == 0 {
+	if len(loaded) == 0 && p.Shards <= 1 {
 		return tx.Commit(ctx)
 	}
 
-	// Process
-	var maxEventID string
-	for _, event := range events {
-		var payload map[string]any
-		if err := json.Unmarshal(event.Payload, &payload); err != nil {
-			return fmt.Errorf("bad payload event %s: %w", event.ID, err)
+	// Process; events no projection handles, and dead-lettered ones, still advance the
+	// offset
+	var last EventData
+	var batch []Event
+	for _, event := range loaded {
+		last = event
+		if !p.handles(event.Type) || event.DeadLettered {
+			continue
+		}
+		payload, err := events.Decode(event.Type, event.Payload)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", event.ID, &poisonEvent{ID: event.ID, LedgerID: event.LedgerID,
+				Type: event.Type, Sequence: event.Sequence, Err: err})
+		}
+		batch = append(batch, Event{ID: event.ID, Sequence: event.Sequence, LedgerID: event.LedgerID,
+			Type: event.Type, OccurredAt: event.OccurredAt, Payload: payload})
+	}
+	if err := p.prepare(ctx, tx, batch); err != nil {
+		return err
+	}
+	writes := newPostingWrites()
+	batchCtx := deferPostingWrites(ctx, writes)
+	for _, e := range batch {
+		if err := p.Apply(batchCtx, tx, e); err != nil {
+			return fmt.Errorf("failed apply event %s: %w", e.ID, err)
 		}
+	}
+	if err := writes.flush(ctx, tx); err != nil {
+		return err
+	}
 
-		// Pass tx xuống để xử lý
-		if err := p.applyTransactionPosted(ctx, tx, event.LedgerID, payload); err != nil {
-			return fmt.Errorf("failed apply event %s: %w", event.ID, err)
+	// A shard that has caught up skips to the head of the stream, so one whose ledgers
+	// are quiet doesn't hold back event archival
+	if p.Shards > 1 && len(loaded) < 100 {
+		var head EventData
+		err := tx.QueryRow(ctx, `SELECT id, sequence FROM events ORDER BY sequence DESC LIMIT 1`).Scan(&head.ID, &head.Sequence)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if head.Sequence > last.Sequence {
+			last = head
 		}
-		maxEventID = event.ID
+	}
+	if last.ID == "" {
+		return tx.Commit(ctx)
+	}
+
+	// Fault injection: fail the batch after applying it (no-op unless built with -tags faults)
+	if err := faults.ProjectorBatch(); err != nil {
+		return err
 	}
 
 	// Update Offset
 	_, err = tx.Exec(ctx, `
-       INSERT INTO projector_offsets (projector_name, last_processed_event_id)
-       VALUES ('ledger', $1)
+       INSERT INTO projector_offsets (projector_name, last_processed_event_id, last_processed_sequence)
+       VALUES ($1, $2, $3)
        ON CONFLICT (projector_name)
-       DO UPDATE SET last_processed_event_id = EXCLUDED.last_processed_event_id
-    `, maxEventID)
+       DO UPDATE SET last_processed_event_id = EXCLUDED.last_processed_event_id,
+                     last_processed_sequence = EXCLUDED.last_processed_sequence
+       WHERE projector_offsets.last_processed_sequence < EXCLUDED.last_processed_sequence
+    `, p.OffsetName(), last.ID, last.Sequence)
+	if err != nil {
+		return err
+	}
+
+	// Drop the rows of a previous shard count once the current count has them all
+	_, err = tx.Exec(ctx, `
+       DELETE FROM projector_offsets
+       WHERE split_part(projector_name, '#', 1) = $1 AND projector_name <> ALL($2)
+         AND (SELECT COUNT(*) FROM projector_offsets WHERE projector_name = ANY($2)) = cardinality($2::text[])
+    `, p.Name, p.offsetNames())
 	if err != nil {
 		return err
 	}
@@ -105,14 +295,60 @@ func (p *Projector) projectBatch(ctx context.Context) error {
 	return tx.Commit(ctx)
 }
 
-func (p *Projector) applyTransactionPosted(ctx context.Context, tx pgx.Tx, ledgerID string, payload map[string]any) error {
-	transactionID := payload["transaction_id"].(string)
-	externalID, _ := payload["external_id"].(string)
-	currency := payload["currency"].(string)
-	occurredAtStr := payload["occurred_at"].(string)
-	occurredAt, err := time.Parse(time.RFC3339Nano, occurredAtStr)
+func (p *Projector) handles(eventType string) bool {
+	for _, projection := range p.Projections {
+		if projection.handles(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare hands each projection with a Prepare hook the batch's events it handles.
+func (p *Projector) prepare(ctx context.Context, tx pgx.Tx, events []Event) error {
+	for _, projection := range p.Projections {
+		if projection.Prepare == nil {
+			continue
+		}
+		var handled []Event
+		for _, e := range events {
+			if projection.handles(e.Type) {
+				handled = append(handled, e)
+			}
+		}
+		if len(handled) == 0 {
+			continue
+		}
+		if err := projection.Prepare(ctx, tx, handled); err != nil {
+			return fmt.Errorf("%s: %w", projection.Name, err)
+		}
+	}
+	return nil
+}
+
+// Apply projects one event into the projector's read models in tx. Replaying an event
+// the read model already reflects is a no-op. A projection that panics on the event
+// fails it with an error instead; the transaction should then be rolled back.
+func (p *Projector) Apply(ctx context.Context, tx pgx.Tx, e Event) error {
+	for _, projection := range p.Projections {
+		if !projection.handles(e.Type) {
+			continue
+		}
+		if err := applyRecovered(ctx, tx, projection, e); err != nil {
+			return fmt.Errorf("%s: %w", projection.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyTransactionPosted(ctx context.Context, tx pgx.Tx, ledgerID string, payload *events.TransactionPosted) error {
+	metadata := payload.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	amount, err := transactionAmount(payload.Postings, payload.Currency)
 	if err != nil {
-		return fmt.Errorf("invalid time format: %w", err)
+		return err
 	}
 
 	// Insert transaction
@@ -120,10 +356,12 @@ func (p *Projector) applyTransactionPosted(ctx context.Context, tx pgx.Tx, ledge
 	// tag.RowsAffected() == 0: (Old Transaction) -> RETURN
 	tag, err := tx.Exec(ctx, `
        INSERT INTO transactions (
-          id, ledger_id, external_id, amount, currency, occurred_at
-       ) VALUES ($1, $2, $3, $4, $5, $6)
+          id, ledger_id, external_id, amount, currency, occurred_at, metadata, entity_code, description, value_date
+       ) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''),
+          COALESCE(NULLIF($10, '')::date, ($6::timestamptz AT TIME ZONE 'UTC')::date))
        ON CONFLICT (id, ledger_id) DO NOTHING
-    `, transactionID, ledgerID, externalID, "0", currency, occurredAt)
+    `, payload.TransactionID, ledgerID, payload.ExternalID, amount, payload.Currency, payload.OccurredAt, metadata,
+		payload.EntityCode, payload.Description, payload.ValueDate)
 	if err != nil {
 		return fmt.Errorf("insert transaction failed: %w", err)
 	}
@@ -132,71 +370,50 @@ func (p *Projector) applyTransactionPosted(ctx context.Context, tx pgx.Tx, ledge
 		return nil
 	}
 
-	// Process postings
-	postings, ok := payload["postings"].([]any)
-	if !ok {
-		return fmt.Errorf("invalid postings payload")
+	// Within a batch, postings and balance changes are written when the batch is
+	// flushed; otherwise right away
+	writes, deferred := ctx.Value(postingWritesKey{}).(*postingWrites)
+	if !deferred {
+		writes = newPostingWrites()
 	}
-
-	for _, raw := range postings {
-		pMap := raw.(map[string]any)
-		accountCode := pMap["account_code"].(string)
-		direction := pMap["direction"].(string)
-		amount := pMap["amount"].(string)
-
-		// TODO: Find AccountID, using cache if possible
-		var accountID string
-		err = tx.QueryRow(ctx, `
-          SELECT id FROM accounts WHERE ledger_id = $1 AND code = $2
-       `, ledgerID, accountCode).Scan(&accountID)
-
-		if err != nil {
-			return fmt.Errorf("account %s not found: %w", accountCode, err)
-		}
-
-		// Persist Posting Log
-		postingID := uuid.NewString()
-		_, err = tx.Exec(ctx, `
-			INSERT INTO postings (
-				id,
-				ledger_id,
-				transaction_id,
-				account_id,
-				amount,
-				direction
-			) VALUES ($1, $2, $3, $4, $5, $6)
-		`, postingID, ledgerID, transactionID, accountID, amount, direction)
+	for _, p := range payload.Postings {
+		account, err := lookupAccount(ctx, tx, ledgerID, p.AccountCode)
 		if err != nil {
-			return fmt.Errorf("insert posting failed: %w", err)
+			return err
+		}
+		taxCode := p.TaxCode
+		if taxCode == "" {
+			taxCode = account.TaxCode
 		}
 
-		// Update account balance
-		if err := p.updateAccountBalance(ctx, tx, accountID, direction, amount); err != nil {
+		err = writes.add(uuid.NewString(), ledgerID, payload.TransactionID, account.ID, p.Amount, p.Direction, p.Currency,
+			taxCode, p.Description)
+		if err != nil {
 			return err
 		}
 	}
-
+	if !deferred {
+		return writes.flush(ctx, tx)
+	}
 	return nil
 }
 
-func (p *Projector) updateAccountBalance(ctx context.Context, tx pgx.Tx, accountID, direction, amountStr string) error {
-	amount := new(big.Rat)
-	if _, ok := amount.SetString(amountStr); !ok {
-		return fmt.Errorf("invalid amount: %s", amountStr)
-	}
-
-	var finalAmount *big.Rat
-	if direction == "credit" {
-		finalAmount = amount
-	} else {
-		finalAmount = new(big.Rat).Neg(amount)
+// transactionAmount is the total debited in the transaction's currency. Legs in other
+// currencies (e.g. the destination side of a conversion) are not counted.
+func transactionAmount(postings []events.Posting, currency string) (string, error) {
+	total := new(big.Rat)
+	for _, p := range postings {
+		if p.Direction != "debit" {
+			continue
+		}
+		if p.Currency != "" && p.Currency != currency {
+			continue
+		}
+		amount, ok := new(big.Rat).SetString(p.Amount)
+		if !ok {
+			return "", fmt.Errorf("invalid amount: %s", p.Amount)
+		}
+		total.Add(total, amount)
 	}
-
-	_, err := tx.Exec(ctx, `
-       UPDATE accounts 
-       SET balance = balance + $1 
-       WHERE id = $2
-    `, finalAmount.FloatString(10), accountID)
-
-	return err
+	return total.FloatString(10), nil
 }
@@ -0,0 +1,49 @@
+package projector
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"slices"
+	"testing"
+)
+
+func TestTransactionAmount(t *testing.T) {
+	postings := []events.Posting{
+		{AccountCode: "a", Direction: "debit", Amount: "10.50"},
+		{AccountCode: "b", Direction: "debit", Amount: "4.50", Currency: "USD"},
+		{AccountCode: "c", Direction: "credit", Amount: "15", Currency: "USD"},
+		{AccountCode: "d", Direction: "debit", Amount: "13.80", Currency: "EUR"},
+		{AccountCode: "e", Direction: "credit", Amount: "13.80", Currency: "EUR"},
+	}
+
+	amount, err := transactionAmount(postings, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != "15.0000000000" {
+		t.Fatalf("expected 15 debited in USD, got %s", amount)
+	}
+
+	bad := []events.Posting{{Direction: "debit", Amount: "x"}}
+	if _, err := transactionAmount(bad, "USD"); err == nil {
+		t.Fatal("expected an invalid amount to be rejected")
+	}
+}
+
+func TestShardedProjectors(t *testing.T) {
+	if single := NewShardedProjectors(nil, Ledger, 1); len(single) != 1 || single[0].OffsetName() != "ledger" {
+		t.Fatalf("one shard should keep the unsharded offset, got %d projectors", len(single))
+	}
+
+	shards := NewShardedProjectors(nil, Ledger, 3)
+	var names []string
+	for _, p := range shards {
+		names = append(names, p.OffsetName())
+	}
+	want := []string{"ledger#0/3", "ledger#1/3", "ledger#2/3"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected offsets %v, got %v", want, names)
+	}
+	if !slices.Equal(shards[1].offsetNames(), want) {
+		t.Fatalf("every shard should know the full set, got %v", shards[1].offsetNames())
+	}
+}
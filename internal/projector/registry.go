@@ -0,0 +1,73 @@
+package projector
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Event is an event as projections see it.
+type Event struct {
+	ID         string
+	Sequence   int64
+	LedgerID   string
+	Type       string
+	OccurredAt time.Time
+	Payload    events.Payload // upcast to the current schema version
+}
+
+// Projection is one read model built from the event stream. Each registered projection
+// runs in its own projector with its own offset, so a new read model is added by
+// registering it rather than by touching the projector loop.
+type Projection struct {
+	// Name keys the projection's offset in projector_offsets.
+	Name string
+
+	// Events are the event types Apply is called for; the projector skips the others.
+	Events []string
+
+	// Tables are the read-model tables Apply writes to, which a shadow schema copies.
+	Tables []string
+
+	// Prepare, when set, sees a batch's events before they are applied one by one, to
+	// load what the batch needs in bulk.
+	Prepare func(ctx context.Context, tx pgx.Tx, events []Event) error
+
+	// Apply projects one event in tx, which commits together with the offset.
+	Apply func(ctx context.Context, tx pgx.Tx, e Event) error
+}
+
+func (p Projection) handles(eventType string) bool {
+	for _, t := range p.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+var registry = map[string]Projection{}
+
+// Register adds a projection for the worker to run. Call it from an init function. A
+// new projection starts from the beginning of the event stream; when one is removed,
+// delete its projector_offsets rows, since event archival waits for every offset.
+func Register(p Projection) {
+	if _, ok := registry[p.Name]; ok {
+		panic(fmt.Sprintf("projection %q registered twice", p.Name))
+	}
+	registry[p.Name] = p
+}
+
+// Registered returns the registered projections by name.
+func Registered() []Projection {
+	projections := make([]Projection, 0, len(registry))
+	for _, p := range registry {
+		projections = append(projections, p)
+	}
+	sort.Slice(projections, func(i, j int) bool { return projections[i].Name < projections[j].Name })
+	return projections
+}
@@ -0,0 +1,35 @@
+package projector
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	var names []string
+	for _, p := range Registered() {
+		names = append(names, p.Name)
+	}
+	if !slices.Equal(names, []string{"api_keys", "ledger"}) {
+		t.Fatalf("unexpected projections %v", names)
+	}
+
+	tables := shadowTables()
+	for _, table := range []string{"accounts", "transactions", "postings", "holds", "api_keys"} {
+		if !slices.Contains(tables, table) {
+			t.Errorf("shadow schema misses %s", table)
+		}
+	}
+
+	shadow := NewShadowProjector(nil, "staging")
+	if !shadow.handles("TransactionPosted") || !shadow.handles("APIKeyRevoked") || shadow.handles("WebhookTested") {
+		t.Fatal("shadow projector should handle exactly the registered event types")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a duplicate registration to panic")
+		}
+	}()
+	Register(Projection{Name: "ledger"})
+}
@@ -0,0 +1,117 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// shadowTables are the read-model tables of every registered projection. A shadow
+// schema holds its own copy of each, so a shadow projector can never touch the live
+// read model.
+func shadowTables() []string {
+	var tables []string
+	seen := map[string]bool{}
+	for _, projection := range Registered() {
+		for _, table := range projection.Tables {
+			if !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables
+}
+
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// NewShadowProjector returns a projector that replays the same event stream as the live
+// ones into a staging schema, running every registered projection under one offset. Run it from a build of the new
+// projection code, then use CompareShadow before cutting over.
+func NewShadowProjector(db *pgxpool.Pool, schema string) *Projector {
+	return &Projector{DB: db, Projections: Registered(), Name: "shadow:" + schema, Schema: schema}
+}
+
+// PrepareShadowSchema (re)creates the staging schema with empty read-model tables and
+// rewinds the shadow projector to the start of the event stream.
+func PrepareShadowSchema(ctx context.Context, db *pgxpool.Pool, schema string) error {
+	if !schemaNamePattern.MatchString(schema) || schema == "public" {
+		return fmt.Errorf("invalid shadow schema name %q", schema)
+	}
+	ident := pgx.Identifier{schema}.Sanitize()
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DROP SCHEMA IF EXISTS `+ident+` CASCADE`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `CREATE SCHEMA `+ident); err != nil {
+		return err
+	}
+	for _, table := range shadowTables() {
+		// Constraints and indexes are copied; foreign keys are not, so the shadow
+		// tables still reference nothing outside the schema.
+		_, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE %s.%s (LIKE public.%s INCLUDING ALL)`, ident, table, table))
+		if err != nil {
+			return fmt.Errorf("create shadow table %s: %w", table, err)
+		}
+	}
+
+	// API keys issued before lifecycle events existed are not in the event stream
+	_, err = tx.Exec(ctx, `INSERT INTO `+ident+`.api_keys SELECT * FROM public.api_keys k
+		WHERE NOT EXISTS (SELECT 1 FROM events e WHERE e.aggregate_type = 'api_key' AND e.aggregate_id = k.id)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM projector_offsets WHERE projector_name = $1`, "shadow:"+schema)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// enterShadowSchema points the transaction's unqualified table names at the shadow
+// schema (events and projector_offsets fall through to public) and copies any accounts
+// and API keys created since the last batch. They are created directly rather than from
+// events, so they are an input to the projection; only account balances and metadata
+// patches, and key lifecycle transitions, are projected. Keys are copied as they were
+// created, before any transition.
+func (p *Projector) enterShadowSchema(ctx context.Context, tx pgx.Tx) error {
+	ident := pgx.Identifier{p.Schema}.Sanitize()
+
+	if _, err := tx.Exec(ctx, `SET LOCAL search_path TO `+ident+`, public`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO `+ident+`.accounts (id, ledger_id, code, name, type, balance, tax_code, metadata, entity_code,
+			allow_negative_balance, min_balance, status, disabled_at, created_at)
+		SELECT id, ledger_id, code, name, type, 0, tax_code, metadata, entity_code,
+			allow_negative_balance, min_balance, status, disabled_at, created_at
+		FROM public.accounts
+		ON CONFLICT (id) DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO `+ident+`.api_keys (id, ledger_id, key_hash, prefix, description, is_active, status, created_by, created_at)
+		SELECT k.id, k.ledger_id, k.key_hash, k.prefix, k.description, e.event_type = 'APIKeyCreated',
+			CASE e.event_type WHEN 'APIKeyCreated' THEN 'active' ELSE 'pending_approval' END, k.created_by, k.created_at
+		FROM public.api_keys k
+		JOIN events e ON e.aggregate_type = 'api_key' AND e.aggregate_id = k.id
+			AND e.event_type IN ('APIKeyRequested', 'APIKeyCreated')
+		ON CONFLICT (id) DO NOTHING
+	`)
+	return err
+}
@@ -0,0 +1,94 @@
+package projector
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// postingWrites collects the postings and balance changes of the transactions of a
+// batch, so they are written with one COPY and one UPDATE instead of a statement per
+// posting.
+type postingWrites struct {
+	postings [][]any
+	deltas   map[string]*big.Rat // by account ID
+}
+
+type postingWritesKey struct{}
+
+func newPostingWrites() *postingWrites {
+	return &postingWrites{deltas: map[string]*big.Rat{}}
+}
+
+// deferPostingWrites returns a context under which applyTransactionPosted collects its
+// writes in w instead of writing them; the caller flushes w before the batch commits.
+func deferPostingWrites(ctx context.Context, w *postingWrites) context.Context {
+	return context.WithValue(ctx, postingWritesKey{}, w)
+}
+
+// add records a posting and its effect on the account's balance: credits add to it,
+// debits subtract from it.
+func (w *postingWrites) add(id, ledgerID, transactionID, accountID, amountStr, direction, currency, taxCode, description string) error {
+	amount, ok := new(big.Rat).SetString(amountStr)
+	if !ok {
+		return fmt.Errorf("invalid amount: %s", amountStr)
+	}
+	var tax, desc any
+	if taxCode != "" {
+		tax = taxCode
+	}
+	if description != "" {
+		desc = description
+	}
+	w.postings = append(w.postings, []any{id, ledgerID, transactionID, accountID, amountStr, direction, currency, tax, desc})
+
+	if direction != "credit" {
+		amount.Neg(amount)
+	}
+	if delta, ok := w.deltas[accountID]; ok {
+		delta.Add(delta, amount)
+	} else {
+		w.deltas[accountID] = amount
+	}
+	return nil
+}
+
+// flush writes the collected postings and applies the summed balance changes, one row
+// per account, in account ID order.
+func (w *postingWrites) flush(ctx context.Context, tx pgx.Tx) error {
+	if len(w.postings) > 0 {
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"postings"},
+			[]string{"id", "ledger_id", "transaction_id", "account_id", "amount", "direction", "currency", "tax_code", "description"},
+			pgx.CopyFromRows(w.postings))
+		if err != nil {
+			return fmt.Errorf("copy postings failed: %w", err)
+		}
+	}
+
+	if len(w.deltas) > 0 {
+		ids := make([]string, 0, len(w.deltas))
+		for id := range w.deltas {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		amounts := make([]string, len(ids))
+		for i, id := range ids {
+			amounts[i] = w.deltas[id].FloatString(10)
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE accounts a
+			SET balance = a.balance + d.delta
+			FROM unnest($1::uuid[], $2::numeric[]) AS d (id, delta)
+			WHERE a.id = d.id
+		`, ids, amounts)
+		if err != nil {
+			return fmt.Errorf("update balances failed: %w", err)
+		}
+	}
+
+	w.postings, w.deltas = nil, map[string]*big.Rat{}
+	return nil
+}
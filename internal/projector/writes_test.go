@@ -0,0 +1,34 @@
+package projector
+
+import "testing"
+
+func TestPostingWritesSumBalanceChanges(t *testing.T) {
+	w := newPostingWrites()
+	for _, p := range []struct{ account, amount, direction, tax string }{
+		{"cash", "100.00", "debit", ""},
+		{"revenue", "100.00", "credit", "VAT"},
+		{"cash", "30.50", "credit", ""},
+		{"revenue", "30.50", "debit", ""},
+	} {
+		if err := w.add("p", "l1", "t1", p.account, p.amount, p.direction, "USD", p.tax, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(w.postings) != 4 {
+		t.Fatalf("expected 4 postings, got %d", len(w.postings))
+	}
+	if w.postings[0][7] != nil || w.postings[1][7] != "VAT" {
+		t.Errorf("tax codes: got %v and %v, want NULL and VAT", w.postings[0][7], w.postings[1][7])
+	}
+	if got := w.deltas["cash"].FloatString(2); got != "-69.50" {
+		t.Errorf("cash: got %s, want -69.50", got)
+	}
+	if got := w.deltas["revenue"].FloatString(2); got != "69.50" {
+		t.Errorf("revenue: got %s, want 69.50", got)
+	}
+
+	if err := w.add("p", "l1", "t1", "cash", "abc", "debit", "USD", "", ""); err == nil {
+		t.Error("expected an invalid amount to fail")
+	}
+}
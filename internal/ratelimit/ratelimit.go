@@ -0,0 +1,231 @@
+// Package ratelimit limits API requests per ledger with a token bucket. Each ledger
+// gets the default rate unless it has its own, and scheduled windows (e.g. a nightly
+// batch run) multiply it for part of the day. Buckets are kept per API instance, so
+// the effective limit scales with the number of instances.
+package ratelimit
+
+import (
+	"Go_FormanceLegder/internal/auth"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Limit is a sustained rate with the burst a full bucket allows.
+type Limit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Window raises a ledger's limit every day between Start and End (minutes after
+// midnight in Location). A window ending before it starts runs past midnight.
+type Window struct {
+	Name       string
+	Start, End int
+	Location   *time.Location
+	Multiplier float64
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w Window) Active(t time.Time) bool {
+	local := t.In(w.Location)
+	minute := local.Hour()*60 + local.Minute()
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// Policy is a ledger's limit and its burst windows.
+type Policy struct {
+	Limit   Limit
+	Windows []Window
+}
+
+// At is the limit in effect at t: the base limit scaled by the largest multiplier of
+// the active windows.
+func (p Policy) At(t time.Time) Limit {
+	multiplier := 1.0
+	for _, w := range p.Windows {
+		if w.Active(t) && w.Multiplier > multiplier {
+			multiplier = w.Multiplier
+		}
+	}
+	return Limit{PerSecond: p.Limit.PerSecond * multiplier, Burst: int(math.Ceil(float64(p.Limit.Burst) * multiplier))}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time since its last use and takes a token. When
+// empty it returns how long until a token is available.
+func (b *bucket) take(limit Limit, now time.Time) (bool, time.Duration) {
+	if b.last.IsZero() {
+		b.tokens = float64(limit.Burst)
+	} else {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.PerSecond)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.PerSecond * float64(time.Second))
+}
+
+type cachedPolicy struct {
+	policy   Policy
+	loadedAt time.Time
+}
+
+// Limiter holds the buckets of the ledgers seen by this instance. Policies are
+// reloaded after policyTTL, so changes apply within a minute.
+type Limiter struct {
+	DB      *pgxpool.Pool
+	Default Limit
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	policies map[string]cachedPolicy
+}
+
+const policyTTL = time.Minute
+
+func NewLimiter(db *pgxpool.Pool, defaultLimit Limit) *Limiter {
+	return &Limiter{DB: db, Default: defaultLimit, buckets: map[string]*bucket{}, policies: map[string]cachedPolicy{}}
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// Allow takes a token from the ledger's bucket. When the ledger is over its limit it
+// returns false and how long to wait.
+func (l *Limiter) Allow(ctx context.Context, ledgerID string) (bool, Limit, time.Duration) {
+	policy := l.policy(ctx, ledgerID)
+	t := now()
+	limit := policy.At(t)
+	if limit.PerSecond <= 0 {
+		return true, limit, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[ledgerID]
+	if !ok {
+		b = &bucket{}
+		l.buckets[ledgerID] = b
+	}
+	allowed, wait := b.take(limit, t)
+	return allowed, limit, wait
+}
+
+func (l *Limiter) policy(ctx context.Context, ledgerID string) Policy {
+	l.mu.Lock()
+	cached, ok := l.policies[ledgerID]
+	l.mu.Unlock()
+	if ok && now().Sub(cached.loadedAt) < policyTTL {
+		return cached.policy
+	}
+
+	policy, err := LoadPolicy(ctx, l.DB, ledgerID, l.Default)
+	if err != nil {
+		// Keep limiting with what we had rather than failing requests
+		if ok {
+			return cached.policy
+		}
+		return Policy{Limit: l.Default}
+	}
+	l.mu.Lock()
+	l.policies[ledgerID] = cachedPolicy{policy: policy, loadedAt: now()}
+	l.mu.Unlock()
+	return policy
+}
+
+// LoadPolicy reads a ledger's limit and windows, falling back to defaultLimit for
+// what the ledger doesn't override.
+func LoadPolicy(ctx context.Context, db *pgxpool.Pool, ledgerID string, defaultLimit Limit) (Policy, error) {
+	policy := Policy{Limit: defaultLimit}
+
+	var perSecond *float64
+	var burst *int
+	err := db.QueryRow(ctx, `
+		SELECT rate_limit_per_second::float8, rate_limit_burst FROM ledgers WHERE id = $1
+	`, ledgerID).Scan(&perSecond, &burst)
+	if err != nil {
+		return policy, err
+	}
+	if perSecond != nil {
+		policy.Limit.PerSecond = *perSecond
+	}
+	if burst != nil {
+		policy.Limit.Burst = *burst
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT name, start_time, end_time, timezone, multiplier::float8
+		FROM rate_limit_windows
+		WHERE ledger_id = $1
+	`, ledgerID)
+	if err != nil {
+		return policy, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w Window
+		var start, end, timezone string
+		if err := rows.Scan(&w.Name, &start, &end, &timezone, &w.Multiplier); err != nil {
+			return policy, err
+		}
+		if w.Start, err = ParseClock(start); err != nil {
+			return policy, err
+		}
+		if w.End, err = ParseClock(end); err != nil {
+			return policy, err
+		}
+		if w.Location, err = time.LoadLocation(timezone); err != nil {
+			return policy, err
+		}
+		policy.Windows = append(policy.Windows, w)
+	}
+	return policy, rows.Err()
+}
+
+// Middleware rejects requests over the authenticated ledger's limit with 429 and a
+// Retry-After. It runs after API key authentication.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.FromContext(r.Context())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, limit, wait := l.Allow(r.Context(), principal.LedgerID)
+		if limit.PerSecond > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatFloat(limit.PerSecond, 'f', -1, 64))
+			w.Header().Set("X-RateLimit-Burst", strconv.Itoa(limit.Burst))
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
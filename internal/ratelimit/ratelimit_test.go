@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowActive(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	nightly := Window{Start: 22 * 60, End: 4 * 60, Location: ny, Multiplier: 10}
+
+	cases := map[string]bool{
+		"2026-03-02T03:30:00Z": true,  // 22:30 in New York
+		"2026-03-02T08:59:00Z": true,  // 03:59
+		"2026-03-02T09:00:00Z": false, // 04:00
+		"2026-03-02T18:00:00Z": false, // 13:00
+	}
+	for ts, want := range cases {
+		at, _ := time.Parse(time.RFC3339, ts)
+		if got := nightly.Active(at); got != want {
+			t.Errorf("Active(%s) = %v, want %v", ts, got, want)
+		}
+	}
+}
+
+func TestPolicyAtAndBucket(t *testing.T) {
+	p := Policy{
+		Limit: Limit{PerSecond: 10, Burst: 20},
+		Windows: []Window{
+			{Start: 60, End: 120, Location: time.UTC, Multiplier: 10},
+			{Start: 90, End: 180, Location: time.UTC, Multiplier: 3},
+		},
+	}
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	if l := p.At(day.Add(95 * time.Minute)); l.PerSecond != 100 || l.Burst != 200 {
+		t.Fatalf("expected the largest active multiplier, got %+v", l)
+	}
+	if l := p.At(day.Add(150 * time.Minute)); l.PerSecond != 30 {
+		t.Fatalf("expected the second window, got %+v", l)
+	}
+	if l := p.At(day.Add(12 * time.Hour)); l.PerSecond != 10 || l.Burst != 20 {
+		t.Fatalf("expected the base limit, got %+v", l)
+	}
+
+	var b bucket
+	limit := Limit{PerSecond: 2, Burst: 3}
+	for i := range 3 {
+		if ok, _ := b.take(limit, day); !ok {
+			t.Fatalf("request %d within the burst was rejected", i+1)
+		}
+	}
+	ok, wait := b.take(limit, day)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected rejection with a 500ms wait, got %v %v", ok, wait)
+	}
+	if ok, _ := b.take(limit, day.Add(500*time.Millisecond)); !ok {
+		t.Fatal("expected a token after refilling")
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	if m, err := ParseClock("01:30"); err != nil || m != 90 {
+		t.Fatalf("unexpected %d %v", m, err)
+	}
+	if _, err := ParseClock("25:00"); err == nil {
+		t.Fatal("expected an invalid time error")
+	}
+}
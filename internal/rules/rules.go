@@ -0,0 +1,167 @@
+// Package rules runs a ledger's own validation rules: CEL expressions (https://cel.dev)
+// a transaction must satisfy to be posted, with access to the transaction and to the
+// balances of the accounts it moves. Tenants write them, so they are sandboxed: CEL has
+// no loops, I/O or side effects, and each evaluation is cut off past a cost limit.
+//
+// An expression sees two variables:
+//
+//	transaction  external_id, description, currency, occurred_at (timestamp), value_date
+//	             (YYYY-MM-DD), entity, metadata (map), and postings: a list of account,
+//	             direction (debit or credit), amount, currency and tax_code
+//	accounts     the transaction's accounts by code: type, balance, balance_after,
+//	             min_balance, allow_negative_balance and entity
+//
+// Amounts and balances are doubles, which compare exactly enough for thresholds but not
+// for sums of many cents. For example:
+//
+//	transaction.postings.all(p, p.amount <= 10000 || has(transaction.metadata.approved_by))
+//	accounts.all(code, !code.startsWith("customers:") || accounts[code].balance_after >= -500)
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// Limits on what a ledger's rules may cost to evaluate.
+const (
+	MaxRules            = 50
+	MaxExpressionLength = 4096
+	costLimit           = 100000
+)
+
+// Policy is a ledger's validation rules, all of which a transaction must satisfy.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"` // true lets the transaction through
+	Message    string `json:"message,omitempty"`
+}
+
+// Violation refuses a transaction a rule evaluated false on, or failed to evaluate on:
+// rules fail closed.
+type Violation struct {
+	Rule    string
+	Message string
+	Err     error // of the evaluation, if it failed
+}
+
+func (v *Violation) Error() string {
+	if v.Err != nil {
+		return fmt.Sprintf("validation rule %s failed to evaluate: %v", v.Rule, v.Err)
+	}
+	return fmt.Sprintf("validation rule %s: %s", v.Rule, v.Message)
+}
+
+// Input is what the rules are evaluated against, as built by the ledger.
+type Input struct {
+	Transaction map[string]any
+	Accounts    map[string]any
+}
+
+func (p Policy) Validate() error {
+	var errs []error
+	if len(p.Rules) > MaxRules {
+		errs = append(errs, fmt.Errorf("at most %d rules", MaxRules))
+	}
+	names := map[string]bool{}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("rule %d: name required", i))
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Errorf("rule %s: name used twice", r.Name))
+		}
+		names[r.Name] = true
+		if len(r.Expression) > MaxExpressionLength {
+			errs = append(errs, fmt.Errorf("rule %s: expression must be at most %d bytes", r.Name, MaxExpressionLength))
+			continue
+		}
+		if _, err := compile(r.Expression); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Check evaluates the rules in order and returns a *Violation for the first one the
+// transaction doesn't satisfy.
+func (p Policy) Check(ctx context.Context, in Input) error {
+	vars := map[string]any{"transaction": in.Transaction, "accounts": in.Accounts}
+	for _, r := range p.Rules {
+		program, err := compile(r.Expression)
+		if err != nil {
+			return &Violation{Rule: r.Name, Err: err}
+		}
+		out, _, err := program.ContextEval(ctx, vars)
+		if err != nil {
+			return &Violation{Rule: r.Name, Err: err}
+		}
+		if ok, isBool := out.Value().(bool); !isBool {
+			return &Violation{Rule: r.Name, Err: fmt.Errorf("evaluated to %v, not a bool", out.Value())}
+		} else if !ok {
+			message := r.Message
+			if message == "" {
+				message = "not satisfied"
+			}
+			return &Violation{Rule: r.Name, Message: message}
+		}
+	}
+	return nil
+}
+
+var env = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("transaction", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("accounts", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+	)
+})
+
+// Compiled programs by expression; every ledger's rules are compiled once, not on every
+// transaction.
+var (
+	programsMu sync.Mutex
+	programs   = map[string]cel.Program{}
+)
+
+const maxPrograms = 4096
+
+func compile(expression string) (cel.Program, error) {
+	programsMu.Lock()
+	program, ok := programs[expression]
+	programsMu.Unlock()
+	if ok {
+		return program, nil
+	}
+
+	e, err := env()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := e.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression must be a bool, not %s", t)
+	}
+	program, err = e.Program(ast, cel.CostLimit(costLimit), cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return nil, err
+	}
+
+	programsMu.Lock()
+	if len(programs) >= maxPrograms {
+		programs = map[string]cel.Program{}
+	}
+	programs[expression] = program
+	programsMu.Unlock()
+	return program, nil
+}
@@ -0,0 +1,97 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func input(amount float64, metadata map[string]any, balanceAfter float64) Input {
+	return Input{
+		Transaction: map[string]any{
+			"currency":    "USD",
+			"occurred_at": time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC),
+			"metadata":    metadata,
+			"postings": []any{
+				map[string]any{"account": "customers:alice", "direction": "debit", "amount": amount, "currency": "USD"},
+				map[string]any{"account": "revenue", "direction": "credit", "amount": amount, "currency": "USD"},
+			},
+		},
+		Accounts: map[string]any{
+			"customers:alice": map[string]any{"type": "asset", "balance": 0.0, "balance_after": balanceAfter},
+			"revenue":         map[string]any{"type": "revenue", "balance": 0.0, "balance_after": amount},
+		},
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		{Name: "large-needs-approval", Expression: `transaction.postings.all(p, p.amount <= 10000 || has(transaction.metadata.approved_by))`,
+			Message: "amounts above 10000 need metadata.approved_by"},
+		{Name: "customer-overdraft", Expression: `accounts.all(code, !code.startsWith("customers:") || accounts[code].balance_after >= -500)`},
+		{Name: "business-hours", Expression: `transaction.occurred_at.getHours("UTC") >= 8`},
+	}}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := policy.Check(ctx, input(500, map[string]any{}, -500)); err != nil {
+		t.Fatalf("expected the transaction to pass: %v", err)
+	}
+	if err := policy.Check(ctx, input(20000, map[string]any{"approved_by": "bob"}, -100)); err != nil {
+		t.Fatalf("expected an approved transaction to pass: %v", err)
+	}
+
+	var v *Violation
+	err := policy.Check(ctx, input(20000, map[string]any{}, -100))
+	if !errors.As(err, &v) || v.Rule != "large-needs-approval" || v.Message != "amounts above 10000 need metadata.approved_by" {
+		t.Fatalf("expected large-needs-approval to refuse, got %v", err)
+	}
+	err = policy.Check(ctx, input(600, map[string]any{}, -600))
+	if !errors.As(err, &v) || v.Rule != "customer-overdraft" || v.Err != nil {
+		t.Fatalf("expected customer-overdraft to refuse, got %v", err)
+	}
+}
+
+func TestPolicyFailsClosed(t *testing.T) {
+	policy := Policy{Rules: []Rule{{Name: "missing", Expression: `transaction.metadata.region == "eu"`}}}
+	var v *Violation
+	if err := policy.Check(context.Background(), input(1, map[string]any{}, 0)); !errors.As(err, &v) || v.Err == nil {
+		t.Fatalf("expected an evaluation error to refuse, got %v", err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		{Name: "a", Expression: `size(transaction.postings)`},
+		{Name: "a", Expression: `transaction.postings.size() > 1`},
+		{Name: "", Expression: `true`},
+		{Name: "syntax", Expression: `transaction.postings.all(p,`},
+	}}
+	err := policy.Validate()
+	if err == nil {
+		t.Fatal("expected invalid rules to be refused")
+	}
+	for _, want := range []string{"rule a: expression must be a bool", "rule a: name used twice", "rule 2: name required", "rule syntax:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestPolicyCostLimit(t *testing.T) {
+	// Nested comprehensions over a large list exceed the cost limit instead of running on
+	postings := make([]any, 2000)
+	for i := range postings {
+		postings[i] = map[string]any{"amount": 1.0}
+	}
+	policy := Policy{Rules: []Rule{{Name: "quadratic", Expression: `transaction.postings.all(p, transaction.postings.all(q, p.amount == q.amount))`}}}
+	var v *Violation
+	err := policy.Check(context.Background(), Input{Transaction: map[string]any{"postings": postings}, Accounts: map[string]any{}})
+	if !errors.As(err, &v) || v.Err == nil || !strings.Contains(v.Err.Error(), "cost limit") {
+		t.Fatalf("expected the cost limit to stop evaluation, got %v", err)
+	}
+}
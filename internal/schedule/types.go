@@ -0,0 +1,15 @@
+package schedule
+
+// InstallmentArgs is the periodic job that posts due schedule installments.
+type InstallmentArgs struct{}
+
+func (InstallmentArgs) Kind() string {
+	return "schedule_installments"
+}
+
+// ViewArgs is the periodic job that runs due scheduled saved views.
+type ViewArgs struct{}
+
+func (ViewArgs) Kind() string {
+	return "saved_views"
+}
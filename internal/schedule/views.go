@@ -0,0 +1,22 @@
+package schedule
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"context"
+	"fmt"
+
+	"github.com/riverqueue/river"
+)
+
+type ViewWorker struct {
+	river.WorkerDefaults[ViewArgs]
+	Handler *ledger.Handler
+}
+
+// Work runs the due saved views; their results are kept on the view for the dashboard.
+func (w *ViewWorker) Work(ctx context.Context, job *river.Job[ViewArgs]) error {
+	if err := w.Handler.RunDueViews(ctx); err != nil {
+		return fmt.Errorf("failed to run saved views: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package schedule
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+)
+
+const batchSize = 500
+
+type Worker struct {
+	river.WorkerDefaults[InstallmentArgs]
+	DB      *pgxpool.Pool
+	Service *ledger.Service
+}
+
+type dueInstallment struct {
+	ID, ScheduleID, LedgerID, Reference string
+	Sequence                            int
+	Amount, Currency                    string
+	SourceAccount, DestinationAccount   string
+}
+
+// Work posts every pending installment that is due. Failures are recorded on the
+// installment and retried on the next run; the installment shows as overdue until posted.
+func (w *Worker) Work(ctx context.Context, job *river.Job[InstallmentArgs]) error {
+	rows, err := w.DB.Query(ctx, `
+		SELECT i.id, i.schedule_id, s.ledger_id, s.reference, i.sequence, i.amount::text,
+			s.currency, s.source_account, s.destination_account
+		FROM schedule_installments i
+		JOIN schedules s ON s.id = i.schedule_id
+		WHERE i.status = 'pending'
+		  AND s.status = 'active'
+		  AND i.due_date <= CURRENT_DATE
+		ORDER BY i.due_date, i.sequence
+		LIMIT $1
+	`, batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load due installments: %w", err)
+	}
+
+	var due []dueInstallment
+	for rows.Next() {
+		var d dueInstallment
+		err := rows.Scan(&d.ID, &d.ScheduleID, &d.LedgerID, &d.Reference, &d.Sequence, &d.Amount,
+			&d.Currency, &d.SourceAccount, &d.DestinationAccount)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+
+	for _, d := range due {
+		transactionID, postErr := w.Service.PostTransaction(ctx, ledger.PostTransactionCommand{
+			LedgerID:       d.LedgerID,
+			ExternalID:     d.Reference + "#" + strconv.Itoa(d.Sequence),
+			IdempotencyKey: ledger.InstallmentIdempotencyKey(d.ScheduleID, d.Sequence),
+			Currency:       d.Currency,
+			OccurredAt:     time.Now().UTC(),
+			Postings: []ledger.PostingInput{
+				{AccountCode: d.SourceAccount, Direction: "debit", Amount: d.Amount},
+				{AccountCode: d.DestinationAccount, Direction: "credit", Amount: d.Amount},
+			},
+		})
+		if postErr != nil {
+			log.Printf("installment %d of schedule %s failed: %v", d.Sequence, d.ScheduleID, postErr)
+			_, err = w.DB.Exec(ctx, `
+				UPDATE schedule_installments
+				SET attempts = attempts + 1, last_error = $2
+				WHERE id = $1
+			`, d.ID, postErr.Error())
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = w.DB.Exec(ctx, `
+			UPDATE schedule_installments
+			SET status = 'posted', transaction_id = $2, attempts = attempts + 1, last_error = NULL, posted_at = NOW()
+			WHERE id = $1
+		`, d.ID, transactionID)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.DB.Exec(ctx, `
+			UPDATE schedules SET status = 'completed'
+			WHERE id = $1
+			  AND status = 'active'
+			  AND NOT EXISTS (SELECT 1 FROM schedule_installments WHERE schedule_id = $1 AND status = 'pending')
+		`, d.ScheduleID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
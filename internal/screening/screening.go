@@ -0,0 +1,157 @@
+// Package screening decides whether a transaction may be posted before the ledger
+// appends it. Screeners can allow it, deny it, or send it to review, in which case the
+// ledger keeps it pending until someone approves or rejects it. Rules is the built-in,
+// per-ledger screener; others (e.g. a sanctions provider) plug in through Screener.
+package screening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type Outcome string
+
+const (
+	Allow  Outcome = "allow"
+	Review Outcome = "review"
+	Deny   Outcome = "deny"
+)
+
+// Request is the transaction being screened, after script compilation and validation.
+type Request struct {
+	LedgerID   string
+	ExternalID string
+	Currency   string
+	EntityCode string
+	Postings   []Posting
+	Metadata   map[string]any
+}
+
+type Posting struct {
+	Account   string
+	Direction string
+	Amount    string
+	Currency  string
+}
+
+type Decision struct {
+	Outcome Outcome
+	Reason  string
+}
+
+type Screener interface {
+	Screen(ctx context.Context, req Request) (Decision, error)
+}
+
+// Func adapts a function to Screener.
+type Func func(ctx context.Context, req Request) (Decision, error)
+
+func (f Func) Screen(ctx context.Context, req Request) (Decision, error) {
+	return f(ctx, req)
+}
+
+// Combine returns the strictest decision: any deny wins over review, and review over allow.
+func Combine(decisions ...Decision) Decision {
+	result := Decision{Outcome: Allow}
+	for _, d := range decisions {
+		if rank(d.Outcome) > rank(result.Outcome) {
+			result = d
+		}
+	}
+	return result
+}
+
+func rank(o Outcome) int {
+	switch o {
+	case Deny:
+		return 2
+	case Review:
+		return 1
+	}
+	return 0
+}
+
+// Rules is a ledger's deny list. Account patterns are exact codes, or prefixes ending
+// in "*" (e.g. "blocked:*"). Amount thresholds apply to each posting in its own currency.
+type Rules struct {
+	DenyAccounts []string            `json:"deny_accounts,omitempty"`
+	DenyMetadata map[string][]string `json:"deny_metadata,omitempty"` // metadata key -> denied values
+	ReviewAbove  string              `json:"review_above,omitempty"`
+	DenyAbove    string              `json:"deny_above,omitempty"`
+}
+
+func (r Rules) Validate() error {
+	var errs []error
+	for _, threshold := range []struct{ name, value string }{
+		{"review_above", r.ReviewAbove}, {"deny_above", r.DenyAbove},
+	} {
+		if threshold.value == "" {
+			continue
+		}
+		if v, ok := new(big.Rat).SetString(threshold.value); !ok || v.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("%s must be a non-negative decimal", threshold.name))
+		}
+	}
+	for _, pattern := range r.DenyAccounts {
+		if pattern == "" || pattern == "*" {
+			errs = append(errs, fmt.Errorf("deny_accounts: pattern %q would deny every account", pattern))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r Rules) Screen(ctx context.Context, req Request) (Decision, error) {
+	for _, p := range req.Postings {
+		for _, pattern := range r.DenyAccounts {
+			if matchAccount(pattern, p.Account) {
+				return Decision{Outcome: Deny, Reason: fmt.Sprintf("account %s is deny-listed", p.Account)}, nil
+			}
+		}
+	}
+
+	for key, denied := range r.DenyMetadata {
+		value, ok := req.Metadata[key]
+		if !ok {
+			continue
+		}
+		text := fmt.Sprint(value)
+		for _, d := range denied {
+			if strings.EqualFold(text, d) {
+				return Decision{Outcome: Deny, Reason: fmt.Sprintf("metadata %s=%s is deny-listed", key, text)}, nil
+			}
+		}
+	}
+
+	decision := Decision{Outcome: Allow}
+	for _, p := range req.Postings {
+		amount, ok := new(big.Rat).SetString(p.Amount)
+		if !ok {
+			continue
+		}
+		if exceeds(amount, r.DenyAbove) {
+			return Decision{Outcome: Deny, Reason: fmt.Sprintf("amount %s %s is above the limit of %s", p.Amount, p.Currency, r.DenyAbove)}, nil
+		}
+		if decision.Outcome == Allow && exceeds(amount, r.ReviewAbove) {
+			decision = Decision{Outcome: Review, Reason: fmt.Sprintf("amount %s %s is above the review threshold of %s", p.Amount, p.Currency, r.ReviewAbove)}
+		}
+	}
+	return decision, nil
+}
+
+func matchAccount(pattern, account string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(account, prefix)
+	}
+	return pattern == account
+}
+
+func exceeds(amount *big.Rat, threshold string) bool {
+	if threshold == "" {
+		return false
+	}
+	limit, ok := new(big.Rat).SetString(threshold)
+	return ok && amount.Cmp(limit) > 0
+}
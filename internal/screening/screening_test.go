@@ -0,0 +1,63 @@
+package screening
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRulesScreen(t *testing.T) {
+	rules := Rules{
+		DenyAccounts: []string{"blocked:*", "suspense"},
+		DenyMetadata: map[string][]string{"country": {"KP"}},
+		ReviewAbove:  "1000",
+		DenyAbove:    "50000",
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	transfer := func(from, to, amount string) Request {
+		return Request{Currency: "USD", Postings: []Posting{
+			{Account: from, Direction: "debit", Amount: amount, Currency: "USD"},
+			{Account: to, Direction: "credit", Amount: amount, Currency: "USD"},
+		}}
+	}
+
+	cases := []struct {
+		name string
+		req  Request
+		want Outcome
+	}{
+		{"small", transfer("cash", "revenue", "10"), Allow},
+		{"prefix", transfer("cash", "blocked:acme", "10"), Deny},
+		{"exact", transfer("suspense", "revenue", "10"), Deny},
+		{"review", transfer("cash", "revenue", "1000.01"), Review},
+		{"deny amount", transfer("cash", "revenue", "60000"), Deny},
+	}
+	for _, c := range cases {
+		d, err := rules.Screen(context.Background(), c.req)
+		if err != nil || d.Outcome != c.want {
+			t.Errorf("%s: got %+v %v, want %s", c.name, d, err, c.want)
+		}
+	}
+
+	req := transfer("cash", "revenue", "10")
+	req.Metadata = map[string]any{"country": "kp"}
+	if d, _ := rules.Screen(context.Background(), req); d.Outcome != Deny {
+		t.Errorf("expected deny-listed metadata to deny, got %+v", d)
+	}
+
+	if err := (Rules{DenyAccounts: []string{"*"}}).Validate(); err == nil {
+		t.Error("expected a catch-all pattern to be rejected")
+	}
+}
+
+func TestCombine(t *testing.T) {
+	d := Combine(Decision{Outcome: Review, Reason: "r"}, Decision{Outcome: Allow}, Decision{Outcome: Deny, Reason: "d"})
+	if d.Outcome != Deny || d.Reason != "d" {
+		t.Fatalf("expected deny to win, got %+v", d)
+	}
+	if d := Combine(); d.Outcome != Allow {
+		t.Fatalf("expected no decisions to allow, got %+v", d)
+	}
+}
@@ -0,0 +1,270 @@
+// Package script compiles a small Numscript-like language into balanced postings:
+//
+//	vars {
+//		monetary $amount
+//		account $customer
+//	}
+//	send $amount (
+//		source = $customer
+//		destination = {
+//			2% to @fees
+//			[USD 0.30] to @fees:fixed
+//			remaining to @merchant:042
+//		}
+//	)
+//
+// Sources and destinations are a single @account or a block of parts: portions (2%,
+// 1/3), fixed amounts ([USD 0.30]) and at most one remaining part. Sources are
+// debited and destinations credited.
+package script
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Amounts are allocated in units of 10^-minScale at least, so 1/3 of [USD 100] is
+// 33.33, 33.33 and 33.34 rather than 33, 33 and 34.
+const minScale = 2
+
+// Posting is one leg of a compiled script. Sources are debited, destinations credited.
+type Posting struct {
+	Account   string
+	Direction string
+	Amount    string
+	Currency  string
+}
+
+// Compile parses src, binds vars (values like "USD 100.00", "customer:001" or "2%")
+// and returns the balanced postings it describes.
+func Compile(src string, vars map[string]string) ([]Posting, error) {
+	prog, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := map[string]string{}
+	for _, v := range prog.Vars {
+		if _, dup := declared[v.Name]; dup {
+			return nil, fmt.Errorf("variable $%s declared twice", v.Name)
+		}
+		if _, ok := vars[v.Name]; !ok {
+			return nil, fmt.Errorf("missing value for variable $%s", v.Name)
+		}
+		declared[v.Name] = v.Type
+	}
+	lookup := func(name, typ string) (string, error) {
+		if declared[name] != typ {
+			return "", fmt.Errorf("variable $%s is not declared as %s", name, typ)
+		}
+		return strings.TrimSpace(vars[name]), nil
+	}
+
+	var postings []Posting
+	for _, send := range prog.Sends {
+		legs, err := compileSend(send, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", send.Line, err)
+		}
+		postings = append(postings, legs...)
+	}
+	return postings, nil
+}
+
+type resolvedPart struct {
+	account   string
+	portion   *big.Rat
+	fixed     *big.Rat
+	remaining bool
+}
+
+func compileSend(send Send, lookup func(name, typ string) (string, error)) ([]Posting, error) {
+	currency, total, err := resolveMonetary(send.Amount, lookup)
+	if err != nil {
+		return nil, err
+	}
+	if total.Sign() <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	scale := decimals(total)
+	resolve := func(parts []Part) ([]resolvedPart, error) {
+		resolved := make([]resolvedPart, len(parts))
+		for i, part := range parts {
+			r := &resolved[i]
+			r.remaining = part.Remaining
+			r.account = part.Account
+			if part.AccountV != "" {
+				if r.account, err = lookup(part.AccountV, "account"); err != nil {
+					return nil, err
+				}
+				r.account = strings.TrimPrefix(r.account, "@")
+			}
+			if r.account == "" {
+				return nil, fmt.Errorf("empty account name")
+			}
+
+			portion := part.Portion
+			if part.Var != "" {
+				if portion, err = lookup(part.Var, "portion"); err != nil {
+					return nil, err
+				}
+			}
+			if portion != "" {
+				if r.portion, err = parsePortion(portion); err != nil {
+					return nil, err
+				}
+			}
+			if part.Fixed != nil {
+				cur, amount, err := resolveMonetary(*part.Fixed, lookup)
+				if err != nil {
+					return nil, err
+				}
+				if cur != currency {
+					return nil, fmt.Errorf("amount in %s cannot be allocated from %s", cur, currency)
+				}
+				r.fixed = amount
+				scale = max(scale, decimals(amount))
+			}
+		}
+		return resolved, nil
+	}
+
+	sources, err := resolve(send.Source)
+	if err != nil {
+		return nil, err
+	}
+	destinations, err := resolve(send.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	var postings []Posting
+	for _, side := range []struct {
+		parts     []resolvedPart
+		direction string
+	}{{sources, "debit"}, {destinations, "credit"}} {
+		amounts, err := allocate(side.parts, total, scale)
+		if err != nil {
+			return nil, err
+		}
+		for i, amount := range amounts {
+			if amount.Sign() == 0 {
+				continue
+			}
+			postings = append(postings, Posting{
+				Account:   side.parts[i].account,
+				Direction: side.direction,
+				Amount:    amount.FloatString(scale),
+				Currency:  currency,
+			})
+		}
+	}
+	return postings, nil
+}
+
+// allocate splits total between parts. Fixed amounts are served first, then portions
+// of the total (rounded down to the scale); the rounding remainder goes to the
+// remaining part, or one unit at a time to the portions in order.
+func allocate(parts []resolvedPart, total *big.Rat, scale int) ([]*big.Rat, error) {
+	unit := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	amounts := make([]*big.Rat, len(parts))
+	left := new(big.Rat).Set(total)
+	portionSum := new(big.Rat)
+	fixedSum := new(big.Rat)
+	remaining := -1
+
+	for i, part := range parts {
+		switch {
+		case part.remaining:
+			if remaining >= 0 {
+				return nil, fmt.Errorf("only one remaining part is allowed")
+			}
+			remaining = i
+			amounts[i] = new(big.Rat)
+		case part.fixed != nil:
+			amounts[i] = new(big.Rat).Set(part.fixed)
+			fixedSum.Add(fixedSum, part.fixed)
+		default:
+			// Round down to whole units
+			q := new(big.Rat).Quo(new(big.Rat).Mul(total, part.portion), unit)
+			units := new(big.Int).Quo(q.Num(), q.Denom())
+			amounts[i] = new(big.Rat).Mul(new(big.Rat).SetInt(units), unit)
+			portionSum.Add(portionSum, part.portion)
+		}
+		left.Sub(left, amounts[i])
+	}
+
+	if left.Sign() < 0 {
+		return nil, fmt.Errorf("allocation exceeds the amount")
+	}
+	if remaining >= 0 {
+		amounts[remaining] = left
+		return amounts, nil
+	}
+
+	// Without a remaining part the allocation must cover the amount exactly
+	covered := new(big.Rat).Add(new(big.Rat).Mul(total, portionSum), fixedSum)
+	if covered.Cmp(total) != 0 {
+		return nil, fmt.Errorf("allocation does not cover the amount; add a remaining part")
+	}
+	for i := 0; left.Sign() > 0; i = (i + 1) % len(parts) {
+		if parts[i].portion != nil {
+			amounts[i].Add(amounts[i], unit)
+			left.Sub(left, unit)
+		}
+	}
+	return amounts, nil
+}
+
+func resolveMonetary(m Monetary, lookup func(name, typ string) (string, error)) (string, *big.Rat, error) {
+	currency, amount := m.Currency, m.Amount
+	if m.Var != "" {
+		value, err := lookup(m.Var, "monetary")
+		if err != nil {
+			return "", nil, err
+		}
+		fields := strings.Fields(strings.Trim(value, "[]"))
+		if len(fields) != 2 {
+			return "", nil, fmt.Errorf("variable $%s: expected a value like \"USD 10.00\"", m.Var)
+		}
+		currency, amount = fields[0], fields[1]
+	}
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok || r.Sign() < 0 || strings.ContainsAny(amount, "eE/") {
+		return "", nil, fmt.Errorf("invalid amount %s", amount)
+	}
+	return currency, r, nil
+}
+
+func parsePortion(s string) (*big.Rat, error) {
+	var r *big.Rat
+	var ok bool
+	if strings.HasSuffix(s, "%") {
+		r, ok = new(big.Rat).SetString(strings.TrimSuffix(s, "%"))
+		if ok {
+			r.Quo(r, big.NewRat(100, 1))
+		}
+	} else if strings.Contains(s, "/") {
+		r, ok = new(big.Rat).SetString(s)
+	}
+	if !ok || r.Sign() <= 0 || r.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, fmt.Errorf("invalid portion %s", s)
+	}
+	return r, nil
+}
+
+// decimals returns the number of decimal places needed to represent r exactly, at least
+// minScale.
+func decimals(r *big.Rat) int {
+	scale := minScale
+	ten := big.NewInt(10)
+	denom := new(big.Int).Set(r.Denom())
+	pow := new(big.Int).Exp(ten, big.NewInt(int64(scale)), nil)
+	for new(big.Int).Mod(pow, denom).Sign() != 0 && scale < 18 {
+		scale++
+		pow.Mul(pow, ten)
+	}
+	return scale
+}
@@ -0,0 +1,107 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF      tokenKind = iota
+	tokIdent              // keyword or currency: send, source, remaining, USD, ...
+	tokAccount            // @customer:001
+	tokVar                // $amount
+	tokNumber             // 100, 12.50
+	tokPercent            // 2%, 12.5%
+	tokFraction           // 1/3
+	tokPunct              // [ ] ( ) { } =
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of script"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == ':' || r == '-' || r == '.'
+}
+
+// lex splits a script into tokens. Comments run from // to the end of the line.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	line := 1
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r) || r == ',':
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case strings.ContainsRune("[](){}=", r):
+			tokens = append(tokens, token{tokPunct, string(r), line})
+			i++
+		case r == '@' || r == '$':
+			start := i
+			i++
+			for i < len(runes) && isNameRune(runes[i]) {
+				i++
+			}
+			if i == start+1 {
+				return nil, fmt.Errorf("line %d: expected a name after %q", line, r)
+			}
+			kind := tokAccount
+			if r == '$' {
+				kind = tokVar
+			}
+			tokens = append(tokens, token{kind, string(runes[start+1 : i]), line})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			kind := tokNumber
+			if i < len(runes) && runes[i] == '%' {
+				kind = tokPercent
+				i++
+			} else if i < len(runes) && runes[i] == '/' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				kind = tokFraction
+				i++
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			text := string(runes[start:i])
+			if kind == tokPercent {
+				text = strings.TrimSuffix(text, "%")
+			}
+			tokens = append(tokens, token{kind, text, line})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[start:i]), line})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, r)
+		}
+	}
+
+	return append(tokens, token{tokEOF, "", line}), nil
+}
@@ -0,0 +1,265 @@
+package script
+
+import (
+	"fmt"
+)
+
+// Program is a parsed script: variable declarations followed by send statements.
+type Program struct {
+	Vars  []VarDecl
+	Sends []Send
+}
+
+type VarDecl struct {
+	Type string // monetary, account or portion
+	Name string
+}
+
+// Send moves Amount from the source accounts to the destination accounts.
+type Send struct {
+	Line        int
+	Amount      Monetary
+	Source      []Part
+	Destination []Part
+}
+
+// Monetary is a literal like [USD 100.00] or a $variable.
+type Monetary struct {
+	Currency string
+	Amount   string
+	Var      string
+}
+
+// Part is one leg of an allocation: a portion of the amount (2%, 1/3), a fixed amount,
+// or whatever remains once the other parts are served.
+type Part struct {
+	Portion   string // "2%" or "1/3"
+	Fixed     *Monetary
+	Remaining bool
+	Var       string // portion variable
+	Account   string
+	AccountV  string // account variable
+}
+
+var varTypes = map[string]bool{"monetary": true, "account": true, "portion": true}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a script without resolving its variables.
+func Parse(src string) (*Program, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	prog := &Program{}
+
+	if p.peekIdent("vars") {
+		p.next()
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for {
+			end, err := p.blockEnds()
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				break
+			}
+			typ := p.next()
+			if typ.kind != tokIdent || !varTypes[typ.text] {
+				return nil, fmt.Errorf("line %d: expected a variable type (monetary, account, portion), got %s", typ.line, typ)
+			}
+			name := p.next()
+			if name.kind != tokVar {
+				return nil, fmt.Errorf("line %d: expected a $variable, got %s", name.line, name)
+			}
+			prog.Vars = append(prog.Vars, VarDecl{Type: typ.text, Name: name.text})
+		}
+	}
+
+	for p.peek().kind != tokEOF {
+		send, err := p.parseSend()
+		if err != nil {
+			return nil, err
+		}
+		prog.Sends = append(prog.Sends, send)
+	}
+	if len(prog.Sends) == 0 {
+		return nil, fmt.Errorf("script has no send statement")
+	}
+	return prog, nil
+}
+
+func (p *parser) parseSend() (Send, error) {
+	kw := p.next()
+	if kw.kind != tokIdent || kw.text != "send" {
+		return Send{}, fmt.Errorf("line %d: expected send, got %s", kw.line, kw)
+	}
+	send := Send{Line: kw.line}
+
+	var err error
+	if send.Amount, err = p.parseMonetary(); err != nil {
+		return send, err
+	}
+	if err := p.expect("("); err != nil {
+		return send, err
+	}
+	if err := p.expectAssign("source"); err != nil {
+		return send, err
+	}
+	if send.Source, err = p.parseAllocation("from"); err != nil {
+		return send, err
+	}
+	if err := p.expectAssign("destination"); err != nil {
+		return send, err
+	}
+	if send.Destination, err = p.parseAllocation("to"); err != nil {
+		return send, err
+	}
+	return send, p.expect(")")
+}
+
+func (p *parser) parseMonetary() (Monetary, error) {
+	t := p.next()
+	if t.kind == tokVar {
+		return Monetary{Var: t.text}, nil
+	}
+	if t.kind != tokPunct || t.text != "[" {
+		return Monetary{}, fmt.Errorf("line %d: expected an amount like [USD 10.00], got %s", t.line, t)
+	}
+	cur := p.next()
+	if cur.kind != tokIdent {
+		return Monetary{}, fmt.Errorf("line %d: expected a currency, got %s", cur.line, cur)
+	}
+	amt := p.next()
+	if amt.kind != tokNumber {
+		return Monetary{}, fmt.Errorf("line %d: expected an amount, got %s", amt.line, amt)
+	}
+	return Monetary{Currency: cur.text, Amount: amt.text}, p.expect("]")
+}
+
+// parseAllocation parses either a single account or a { part keyword account ... } block.
+func (p *parser) parseAllocation(keyword string) ([]Part, error) {
+	t := p.peek()
+	if t.kind == tokAccount || t.kind == tokVar {
+		p.next()
+		part := Part{Remaining: true}
+		setAccount(&part, t)
+		return []Part{part}, nil
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var parts []Part
+	for {
+		end, err := p.blockEnds()
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			break
+		}
+		var part Part
+		t := p.peek()
+		switch {
+		case t.kind == tokPercent:
+			part.Portion = p.next().text + "%"
+		case t.kind == tokFraction:
+			part.Portion = p.next().text
+		case t.kind == tokVar:
+			part.Var = p.next().text
+		case t.kind == tokIdent && t.text == "remaining":
+			p.next()
+			part.Remaining = true
+		case t.kind == tokPunct && t.text == "[":
+			m, err := p.parseMonetary()
+			if err != nil {
+				return nil, err
+			}
+			part.Fixed = &m
+		default:
+			return nil, fmt.Errorf("line %d: expected a portion, amount or remaining, got %s", t.line, t)
+		}
+
+		kw := p.next()
+		if kw.kind != tokIdent || kw.text != keyword {
+			return nil, fmt.Errorf("line %d: expected %s, got %s", kw.line, keyword, kw)
+		}
+		acct := p.next()
+		if acct.kind != tokAccount && acct.kind != tokVar {
+			return nil, fmt.Errorf("line %d: expected an @account, got %s", acct.line, acct)
+		}
+		setAccount(&part, acct)
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("line %d: empty allocation", t.line)
+	}
+	return parts, nil
+}
+
+func setAccount(part *Part, t token) {
+	if t.kind == tokVar {
+		part.AccountV = t.text
+	} else {
+		part.Account = t.text
+	}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekIdent(text string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == text
+}
+
+func (p *parser) peekPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == text
+}
+
+// blockEnds consumes the closing brace of a block, or fails at the end of the script.
+func (p *parser) blockEnds() (bool, error) {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return false, fmt.Errorf("line %d: missing \"}\"", t.line)
+	}
+	if p.peekPunct("}") {
+		p.next()
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *parser) expect(punct string) error {
+	t := p.next()
+	if t.kind != tokPunct || t.text != punct {
+		return fmt.Errorf("line %d: expected %q, got %s", t.line, punct, t)
+	}
+	return nil
+}
+
+func (p *parser) expectAssign(keyword string) error {
+	t := p.next()
+	if t.kind != tokIdent || t.text != keyword {
+		return fmt.Errorf("line %d: expected %s, got %s", t.line, keyword, t)
+	}
+	return p.expect("=")
+}
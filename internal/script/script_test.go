@@ -0,0 +1,92 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func render(postings []Posting) string {
+	var lines []string
+	for _, p := range postings {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s", p.Direction, p.Account, p.Amount, p.Currency))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestCompileFeeSplit(t *testing.T) {
+	postings, err := Compile(`
+		// 2% platform fee, the rest to the merchant
+		send [USD 100.00] (
+			source = @customer:001
+			destination = {
+				2% to @fees
+				remaining to @merchant:042
+			}
+		)
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "debit customer:001 100.00 USD\ncredit fees 2.00 USD\ncredit merchant:042 98.00 USD"
+	if got := render(postings); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompileRoundingAndVars(t *testing.T) {
+	postings, err := Compile(`
+		vars {
+			monetary $amount
+			account $payer
+			portion $share
+		}
+		send $amount (
+			source = { $share from $payer  remaining from @treasury }
+			destination = { 1/3 to @a  1/3 to @b  1/3 to @c }
+		)
+	`, map[string]string{"amount": "EUR 100", "payer": "@alice", "share": "50%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"debit alice 50.00 EUR",
+		"debit treasury 50.00 EUR",
+		"credit a 33.34 EUR",
+		"credit b 33.33 EUR",
+		"credit c 33.33 EUR",
+	}, "\n")
+	if got := render(postings); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompileFixedAmounts(t *testing.T) {
+	postings, err := Compile(`send [USD 10.005] ( source = @a destination = { [USD 0.30] to @fee  remaining to @b } )`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "debit a 10.005 USD\ncredit fee 0.300 USD\ncredit b 9.705 USD"
+	if got := render(postings); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := map[string]string{
+		"missing remaining":    `send [USD 10] ( source = @a destination = { 50% to @b } )`,
+		"over allocated":       `send [USD 10] ( source = @a destination = { 60% to @b 60% to @c } )`,
+		"fixed too large":      `send [USD 10] ( source = @a destination = { [USD 11] to @b remaining to @c } )`,
+		"currency mismatch":    `send [USD 10] ( source = @a destination = { [EUR 1] to @b remaining to @c } )`,
+		"undeclared variable":  `send $amount ( source = @a destination = @b )`,
+		"unterminated block":   `send [USD 10] ( source = @a destination = { remaining to @b`,
+		"two remaining parts":  `send [USD 10] ( source = @a destination = { remaining to @b remaining to @c } )`,
+		"no send":              `vars { monetary $x }`,
+		"unexpected character": `send [USD 10] ( source = @a destination = @b ) ;`,
+	}
+	for name, src := range cases {
+		if _, err := Compile(src, map[string]string{"x": "USD 1"}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package sdkgen
+
+import (
+	"Go_FormanceLegder/internal/openapi"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// python generates ledger_client/api.py: a TypedDict per schema and LedgerClient, whose
+// methods send the operations through the runtime's BaseClient. Path parameters and the
+// body are positional, query parameters keyword-only.
+func python(doc *openapi.Document, schemas []string, ops []operation) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", Header)
+	b.WriteString("from __future__ import annotations\n\n")
+	b.WriteString("from typing import Any, Dict, List, Literal, NotRequired, Optional, TypedDict\n\n")
+	b.WriteString("from ._runtime import BaseClient, quote_path\n\n")
+
+	exports := append([]string{"LedgerClient"}, schemas...)
+	b.WriteString("__all__ = [\n")
+	for _, name := range exports {
+		fmt.Fprintf(&b, "    %q,\n", name)
+	}
+	b.WriteString("]\n")
+
+	for _, name := range schemas {
+		s := doc.Components.Schemas[name]
+		b.WriteString("\n\n")
+		if s.Type != "object" || len(s.Properties) == 0 {
+			fmt.Fprintf(&b, "%s = %s\n", name, pyType(s))
+			continue
+		}
+		props := propertyNames(s)
+		fields := make([]string, len(props))
+		functional := false
+		for i, prop := range props {
+			t := pyType(s.Properties[prop])
+			if !isRequired(s, prop) {
+				t = "NotRequired[" + t + "]"
+			}
+			fields[i] = t
+			functional = functional || pyName(prop) != prop || !pyIdentifier.MatchString(prop)
+		}
+		// A property named like a keyword can only be declared with the functional syntax
+		if functional {
+			fmt.Fprintf(&b, "%s = TypedDict(\n    %q,\n    {\n", name, name)
+			for i, prop := range props {
+				fmt.Fprintf(&b, "        %q: %s,\n", prop, fields[i])
+			}
+			b.WriteString("    },\n)\n")
+			continue
+		}
+		fmt.Fprintf(&b, "class %s(TypedDict):\n", name)
+		pyDoc(&b, "    ", docLines(s.Description, ""))
+		for i, prop := range props {
+			fmt.Fprintf(&b, "    %s: %s\n", prop, fields[i])
+		}
+	}
+
+	b.WriteString("\n\nclass LedgerClient(BaseClient):\n")
+	b.WriteString("    \"\"\"A client of the ledger API, authenticated with an API key.\"\"\"\n")
+	for _, op := range ops {
+		b.WriteString("\n")
+		pyMethod(&b, op)
+	}
+	return []byte(b.String())
+}
+
+func pyMethod(b *strings.Builder, op operation) {
+	args := []string{"self"}
+	for _, p := range op.pathParams {
+		args = append(args, fmt.Sprintf("%s: %s", pyName(p.Name), pyType(p.Schema)))
+	}
+	if op.body != nil {
+		args = append(args, "body: "+pyType(op.body))
+	}
+	if len(op.queryParams) > 0 {
+		args = append(args, "*")
+	}
+	for _, p := range op.queryParams {
+		if p.Required {
+			args = append(args, fmt.Sprintf("%s: %s", pyName(p.Name), pyType(p.Schema)))
+		} else {
+			args = append(args, fmt.Sprintf("%s: Optional[%s] = None", pyName(p.Name), pyType(p.Schema)))
+		}
+	}
+	result := "None"
+	if op.result != nil {
+		result = pyType(op.result)
+	}
+
+	signature := fmt.Sprintf("    def %s(%s) -> %s:", snake(op.OperationID), strings.Join(args, ", "), result)
+	if len(signature) <= 100 {
+		b.WriteString(signature + "\n")
+	} else {
+		fmt.Fprintf(b, "    def %s(\n", snake(op.OperationID))
+		for _, arg := range args {
+			fmt.Fprintf(b, "        %s,\n", arg)
+		}
+		fmt.Fprintf(b, "    ) -> %s:\n", result)
+	}
+	pyDoc(b, "        ", docLines(op.Summary, op.Description))
+
+	path := strconv.Quote(op.path)
+	if len(op.pathParams) > 0 {
+		path = "f" + strconv.Quote(pathParam.ReplaceAllStringFunc(op.path, func(m string) string {
+			return "{quote_path(" + pyName(pathParam.FindStringSubmatch(m)[1]) + ")}"
+		}))
+	}
+	call := []string{strconv.Quote(op.method), path}
+	if len(op.queryParams) > 0 {
+		var query []string
+		for _, p := range op.queryParams {
+			query = append(query, fmt.Sprintf("%q: %s", p.Name, pyName(p.Name)))
+		}
+		call = append(call, "query={"+strings.Join(query, ", ")+"}")
+	}
+	if op.body != nil {
+		call = append(call, "body=body")
+	}
+	line := fmt.Sprintf("        return self._request(%s)", strings.Join(call, ", "))
+	if len(line) <= 100 {
+		b.WriteString(line + "\n")
+		return
+	}
+	b.WriteString("        return self._request(\n")
+	for _, arg := range call {
+		if strings.HasPrefix(arg, "query={") && len(arg) > 80 {
+			b.WriteString("            query={\n")
+			for _, p := range op.queryParams {
+				fmt.Fprintf(b, "                %q: %s,\n", p.Name, pyName(p.Name))
+			}
+			b.WriteString("            },\n")
+			continue
+		}
+		fmt.Fprintf(b, "            %s,\n", arg)
+	}
+	b.WriteString("        )\n")
+}
+
+func pyDoc(b *strings.Builder, indent string, lines []string) {
+	for i, line := range lines {
+		lines[i] = strings.ReplaceAll(line, `"""`, `\"\"\"`)
+	}
+	switch len(lines) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, lines[0])
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"%s\n", indent, lines[0])
+	for _, line := range lines[1:] {
+		if line == "" {
+			b.WriteString("\n")
+		} else {
+			fmt.Fprintf(b, "%s%s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+// pyName is the Python name of a parameter, suffixed with _ when it is a keyword.
+func pyName(name string) string {
+	if pyKeywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+var (
+	pyKeywords   = map[string]bool{}
+	pyIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+func init() {
+	for _, k := range strings.Fields(`False None True and as assert async await break class continue def del
+		elif else except finally for from global if import in is lambda nonlocal not or pass raise return try
+		while with yield`) {
+		pyKeywords[k] = true
+	}
+}
+
+func pyType(s *openapi.Schema) string {
+	if s == nil {
+		return "Any"
+	}
+	t := pyBaseType(s)
+	if s.Nullable {
+		t = "Optional[" + t + "]"
+	}
+	return t
+}
+
+func pyBaseType(s *openapi.Schema) string {
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = strconv.Quote(v)
+		}
+		return "Literal[" + strings.Join(values, ", ") + "]"
+	}
+	switch s.Type {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "List[" + pyType(s.Items) + "]"
+	case "object":
+		if len(s.Properties) > 0 {
+			return "Dict[str, Any]"
+		}
+		return "Dict[str, " + pyType(s.AdditionalProperties) + "]"
+	}
+	return "Any"
+}
@@ -0,0 +1,185 @@
+// Package sdkgen generates the TypeScript and Python clients in sdks/ from the API's
+// OpenAPI document: a type per component schema and a client method per operation. The
+// generated files sit next to a hand-written runtime that sends the requests, so only
+// the API's shape is generated and the clients need no code generator to build.
+package sdkgen
+
+import (
+	"Go_FormanceLegder/internal/openapi"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Header starts every generated file, after the language's comment marker.
+const Header = "Code generated by cmd/sdkgen from api/openapi.json. DO NOT EDIT."
+
+// Generate returns the generated files of both clients, by path relative to sdks/.
+func Generate(doc *openapi.Document) (map[string][]byte, error) {
+	ops, err := operations(doc)
+	if err != nil {
+		return nil, err
+	}
+	schemas := schemaNames(doc)
+	return map[string][]byte{
+		"typescript/src/api.ts":       typescript(doc, schemas, ops),
+		"python/ledger_client/api.py": python(doc, schemas, ops),
+	}, nil
+}
+
+// operation is an operation of the document with what both generators need of it.
+type operation struct {
+	openapi.Operation
+	method, path string
+	pathParams   []openapi.Parameter
+	queryParams  []openapi.Parameter // required first
+	body         *openapi.Schema
+	result       *openapi.Schema // nil when the response has no body
+}
+
+// paramsRequired reports whether the operation has a parameter callers must pass.
+func (op operation) paramsRequired() bool {
+	return len(op.pathParams) > 0 || len(op.queryParams) > 0 && op.queryParams[0].Required
+}
+
+var methodOrder = map[string]int{"get": 0, "post": 1, "put": 2, "patch": 3, "delete": 4}
+
+// operations lists the document's operations by path, then method.
+func operations(doc *openapi.Document) ([]operation, error) {
+	var ops []operation
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			o := operation{Operation: op, method: strings.ToUpper(method), path: path}
+			for _, p := range op.Parameters {
+				switch p.In {
+				case "path":
+					o.pathParams = append(o.pathParams, p)
+				case "query":
+					o.queryParams = append(o.queryParams, p)
+				default:
+					return nil, fmt.Errorf("%s: %s parameters are not supported", op.OperationID, p.In)
+				}
+			}
+			sort.SliceStable(o.queryParams, func(i, j int) bool {
+				return o.queryParams[i].Required && !o.queryParams[j].Required
+			})
+			if op.RequestBody != nil {
+				media, ok := op.RequestBody.Content["application/json"]
+				if !ok {
+					return nil, fmt.Errorf("%s: only JSON request bodies are supported", op.OperationID)
+				}
+				o.body = media.Schema
+			}
+			result, err := successResult(op)
+			if err != nil {
+				return nil, err
+			}
+			o.result = result
+			ops = append(ops, o)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].path != ops[j].path {
+			return ops[i].path < ops[j].path
+		}
+		return methodOrder[strings.ToLower(ops[i].method)] < methodOrder[strings.ToLower(ops[j].method)]
+	})
+	return ops, nil
+}
+
+// successResult returns the schema of the operation's 2xx response.
+func successResult(op openapi.Operation) (*openapi.Schema, error) {
+	var statuses []string
+	for status := range op.Responses {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("%s: no successful response", op.OperationID)
+	}
+	sort.Strings(statuses)
+	resp := op.Responses[statuses[0]]
+	if len(resp.Content) == 0 {
+		return nil, nil
+	}
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return nil, fmt.Errorf("%s: only JSON responses are supported", op.OperationID)
+	}
+	return media.Schema, nil
+}
+
+func schemaNames(doc *openapi.Document) []string {
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// refName is the component a reference points to.
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// isRequired reports whether the object schema s requires property name.
+func isRequired(s *openapi.Schema, name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+func propertyNames(s *openapi.Schema) []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pascal turns an operation ID such as listAccounts into ListAccounts.
+func pascal(id string) string {
+	if id == "" {
+		return id
+	}
+	r := []rune(id)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// snake turns an operation ID such as listAccounts into list_accounts.
+func snake(id string) string {
+	var b strings.Builder
+	for i, r := range id {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// docLines is the text of an operation's doc comment: its summary, then description.
+func docLines(summary, description string) []string {
+	var lines []string
+	if summary != "" {
+		lines = append(lines, strings.Split(summary, "\n")...)
+	}
+	if description != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, strings.Split(description, "\n")...)
+	}
+	return lines
+}
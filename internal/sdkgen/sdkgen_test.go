@@ -0,0 +1,87 @@
+package sdkgen
+
+import (
+	"Go_FormanceLegder/internal/openapi"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type thing struct {
+	ID   string `json:"id"`
+	Note string `json:"note,omitempty"`
+}
+
+type window struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func testDocument() *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{Title: "test", Version: "1"})
+	b.Add(openapi.Endpoint{Method: http.MethodGet, Path: "/things", ID: "listThings", Summary: "List things",
+		Params: []openapi.Param{
+			{Name: "from", Description: "Oldest thing"},
+			{Name: "state", Enum: []string{"open", "closed"}},
+			{Name: "limit", Type: "integer", Required: true},
+		},
+		Response: []thing{}})
+	b.Add(openapi.Endpoint{Method: http.MethodPatch, Path: "/things/{id}", ID: "updateThing",
+		Params: []openapi.Param{{Name: "id", In: "path"}}, Request: thing{}, Response: window{}})
+	b.Add(openapi.Endpoint{Method: http.MethodDelete, Path: "/things/{id}", ID: "deleteThing",
+		Params: []openapi.Param{{Name: "id", In: "path"}}, Status: http.StatusNoContent})
+	return b.Document()
+}
+
+func TestGenerate(t *testing.T) {
+	files, err := Generate(testDocument())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := string(files["typescript/src/api.ts"])
+	for _, want := range []string{
+		"// " + Header,
+		"export interface thing {\n  id: string;\n  note?: string;\n}",
+		"export interface ListThingsParams {\n  limit: number;\n  /** Oldest thing */\n  from?: string;\n  state?: \"open\" | \"closed\";\n}",
+		"listThings(params: ListThingsParams): Promise<thing[]> {",
+		"query: { limit: params.limit, from: params.from, state: params.state }",
+		"updateThing(params: UpdateThingParams, body: thing): Promise<window> {",
+		"`/things/${encodeURIComponent(params.id)}`, { body }",
+		"deleteThing(params: DeleteThingParams): Promise<void> {",
+	} {
+		if !strings.Contains(ts, want) {
+			t.Errorf("api.ts lacks %q:\n%s", want, ts)
+		}
+	}
+	if strings.Index(ts, "updateThing(") > strings.Index(ts, "deleteThing(") {
+		t.Errorf("expected operations of a path ordered by method:\n%s", ts)
+	}
+
+	py := string(files["python/ledger_client/api.py"])
+	for _, want := range []string{
+		"# " + Header,
+		"class thing(TypedDict):\n    id: str\n    note: NotRequired[str]\n",
+		"window = TypedDict(\n    \"window\",\n    {\n        \"from\": str,\n        \"to\": str,\n    },\n)",
+		"limit: int,\n        from_: Optional[str] = None,",
+		"state: Optional[Literal[\"open\", \"closed\"]] = None,",
+		"query={\"limit\": limit, \"from\": from_, \"state\": state}",
+		"def update_thing(self, id: str, body: thing) -> window:",
+		"f\"/things/{quote_path(id)}\"",
+		"def delete_thing(self, id: str) -> None:",
+	} {
+		if !strings.Contains(py, want) {
+			t.Errorf("api.py lacks %q:\n%s", want, py)
+		}
+	}
+}
+
+func TestGenerateRefusesUnsupportedOperations(t *testing.T) {
+	doc := testDocument()
+	op := doc.Paths["/things"]["get"]
+	op.Parameters = append(op.Parameters, openapi.Parameter{Name: "X-Trace", In: "header", Schema: &openapi.Schema{Type: "string"}})
+	doc.Paths["/things"]["get"] = op
+	if _, err := Generate(doc); err == nil || !strings.Contains(err.Error(), "header parameters") {
+		t.Fatalf("expected header parameters to be refused, got %v", err)
+	}
+}
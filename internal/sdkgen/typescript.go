@@ -0,0 +1,203 @@
+package sdkgen
+
+import (
+	"Go_FormanceLegder/internal/openapi"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// typescript generates src/api.ts: an interface per schema, a parameters interface per
+// operation with parameters, and LedgerClient, whose methods send the operations
+// through the runtime's BaseClient.
+func typescript(doc *openapi.Document, schemas []string, ops []operation) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n\n", Header)
+	b.WriteString("import { BaseClient } from \"./runtime.js\";\n")
+
+	for _, name := range schemas {
+		s := doc.Components.Schemas[name]
+		b.WriteString("\n")
+		tsDoc(&b, "", docLines(s.Description, ""))
+		if s.Type == "object" && len(s.Properties) > 0 {
+			fmt.Fprintf(&b, "export interface %s {\n", name)
+			for _, prop := range propertyNames(s) {
+				tsProperty(&b, prop, s.Properties[prop], isRequired(s, prop))
+			}
+			b.WriteString("}\n")
+		} else {
+			fmt.Fprintf(&b, "export type %s = %s;\n", name, tsType(s))
+		}
+	}
+
+	for _, op := range ops {
+		params := append(append([]openapi.Parameter{}, op.pathParams...), op.queryParams...)
+		if len(params) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n/** Parameters of {@link LedgerClient.%s}. */\n", op.OperationID)
+		fmt.Fprintf(&b, "export interface %sParams {\n", pascal(op.OperationID))
+		for _, p := range params {
+			s := *p.Schema
+			s.Description = p.Description
+			tsProperty(&b, p.Name, &s, p.Required)
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\n/** A client of the ledger API, authenticated with an API key. */\n")
+	b.WriteString("export class LedgerClient extends BaseClient {\n")
+	for i, op := range ops {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		tsMethod(&b, op)
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func tsMethod(b *strings.Builder, op operation) {
+	tsDoc(b, "  ", docLines(op.Summary, op.Description))
+
+	var args []string
+	paramsArg := ""
+	if len(op.pathParams)+len(op.queryParams) > 0 {
+		paramsArg = fmt.Sprintf("params: %sParams", pascal(op.OperationID))
+		if !op.paramsRequired() {
+			paramsArg += " = {}"
+		}
+	}
+	if paramsArg != "" && op.paramsRequired() {
+		args = append(args, paramsArg)
+	}
+	if op.body != nil {
+		args = append(args, "body: "+tsType(op.body))
+	}
+	if paramsArg != "" && !op.paramsRequired() {
+		args = append(args, paramsArg)
+	}
+
+	result := "void"
+	if op.result != nil {
+		result = tsType(op.result)
+	}
+	fmt.Fprintf(b, "  %s(%s): Promise<%s> {\n", op.OperationID, strings.Join(args, ", "), result)
+
+	path := strconv.Quote(op.path)
+	if len(op.pathParams) > 0 {
+		path = "`" + pathParam.ReplaceAllString(op.path, "$${encodeURIComponent(params.$1)}") + "`"
+	}
+	var options []string
+	if len(op.queryParams) > 0 {
+		var query []string
+		for _, p := range op.queryParams {
+			query = append(query, fmt.Sprintf("%s: params.%s", tsKey(p.Name), p.Name))
+		}
+		options = append(options, "query: { "+strings.Join(query, ", ")+" }")
+	}
+	if op.body != nil {
+		options = append(options, "body")
+	}
+	call := fmt.Sprintf("this.request<%s>(%q, %s", result, op.method, path)
+	if len(options) > 0 {
+		call += ", { " + strings.Join(options, ", ") + " }"
+	}
+	fmt.Fprintf(b, "    return %s);\n  }\n", call)
+}
+
+var pathParam = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func tsProperty(b *strings.Builder, name string, s *openapi.Schema, required bool) {
+	tsDoc(b, "  ", docLines(s.Description, ""))
+	optional := "?"
+	if required {
+		optional = ""
+	}
+	fmt.Fprintf(b, "  %s%s: %s;\n", tsKey(name), optional, tsType(s))
+}
+
+func tsDoc(b *strings.Builder, indent string, lines []string) {
+	switch len(lines) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(b, "%s/** %s */\n", indent, tsComment(lines[0]))
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for _, line := range lines {
+		if line == "" {
+			fmt.Fprintf(b, "%s *\n", indent)
+		} else {
+			fmt.Fprintf(b, "%s * %s\n", indent, tsComment(line))
+		}
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
+
+func tsComment(s string) string {
+	return strings.ReplaceAll(s, "*/", "*\\/")
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func tsType(s *openapi.Schema) string {
+	if s == nil {
+		return "unknown"
+	}
+	t := tsBaseType(s)
+	if s.Nullable {
+		t += " | null"
+	}
+	return t
+}
+
+func tsBaseType(s *openapi.Schema) string {
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = strconv.Quote(v)
+		}
+		return strings.Join(values, " | ")
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := tsType(s.Items)
+		if strings.Contains(item, " ") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if len(s.Properties) > 0 {
+			var fields []string
+			for _, name := range propertyNames(s) {
+				optional := "?"
+				if isRequired(s, name) {
+					optional = ""
+				}
+				fields = append(fields, fmt.Sprintf("%s%s: %s", tsKey(name), optional, tsType(s.Properties[name])))
+			}
+			return "{ " + strings.Join(fields, "; ") + " }"
+		}
+		return "{ [key: string]: " + tsType(s.AdditionalProperties) + " }"
+	}
+	return "unknown"
+}
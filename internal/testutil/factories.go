@@ -0,0 +1,188 @@
+package testutil
+
+import (
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+)
+
+// APIKeySecret is the HMAC secret factory-created API keys are hashed with; pass it as
+// auth.Middleware.APIKeySecret when testing authenticated routes.
+var APIKeySecret = []byte("test-api-key-secret")
+
+var seq atomic.Int64
+
+// Factory creates fixtures in a test database. Every fixture gets unique names and codes,
+// so a test can create as many as it needs without coordinating with other tests.
+type Factory struct {
+	t       testing.TB
+	DB      *pgxpool.Pool
+	Service *ledger.Service
+}
+
+// NewFactory returns a factory for pool. Its Service enqueues webhook jobs through a River
+// client that is never started, so jobs stay queued until a test works them explicitly.
+func NewFactory(t testing.TB, pool *pgxpool.Pool) *Factory {
+	t.Helper()
+
+	workers := river.NewWorkers()
+	deliveries := webhook.NewWorker(pool)
+	river.AddWorker(workers, deliveries)
+	river.AddWorker(workers, &webhook.BatchWorker{Deliveries: deliveries})
+	riverClient, err := river.NewClient(riverpgxv5.New(pool), &river.Config{Workers: workers})
+	if err != nil {
+		t.Fatalf("failed to create river client: %v", err)
+	}
+
+	return &Factory{t: t, DB: pool, Service: ledger.NewService(pool, riverClient)}
+}
+
+type Org struct {
+	ID   string
+	Name string
+}
+
+type Project struct {
+	ID    string
+	OrgID string
+	Code  string
+}
+
+type Ledger struct {
+	ID        string
+	ProjectID string
+	OrgID     string
+	Code      string
+	Currency  string
+}
+
+type Account struct {
+	ID       string
+	LedgerID string
+	Code     string
+	Type     string
+}
+
+type APIKey struct {
+	ID       string
+	LedgerID string
+	Key      string // raw key, for the Authorization header
+}
+
+// Org creates an organization.
+func (f *Factory) Org() Org {
+	f.t.Helper()
+	o := Org{Name: fmt.Sprintf("Org %d", seq.Add(1))}
+	f.queryRow(&o.ID, `INSERT INTO organizations (name) VALUES ($1) RETURNING id`, o.Name)
+	return o
+}
+
+// Project creates a project in org, or in a new organization if org is empty.
+func (f *Factory) Project(orgID string) Project {
+	f.t.Helper()
+	if orgID == "" {
+		orgID = f.Org().ID
+	}
+	p := Project{OrgID: orgID, Code: fmt.Sprintf("project-%d", seq.Add(1))}
+	f.queryRow(&p.ID, `INSERT INTO projects (organization_id, name, code) VALUES ($1, $2, $2) RETURNING id`,
+		p.OrgID, p.Code)
+	return p
+}
+
+// Ledger creates a USD ledger in a new organization and project.
+func (f *Factory) Ledger() Ledger {
+	f.t.Helper()
+	p := f.Project("")
+	return f.LedgerIn(p, "USD")
+}
+
+// LedgerIn creates a ledger in an existing project.
+func (f *Factory) LedgerIn(p Project, currency string) Ledger {
+	f.t.Helper()
+	l := Ledger{ProjectID: p.ID, OrgID: p.OrgID, Code: fmt.Sprintf("ledger-%d", seq.Add(1)), Currency: currency}
+	f.queryRow(&l.ID, `INSERT INTO ledgers (project_id, name, code, currency) VALUES ($1, $2, $2, $3) RETURNING id`,
+		l.ProjectID, l.Code, l.Currency)
+	return l
+}
+
+// Account creates an account with the given code and type (asset, liability, equity,
+// revenue or expense).
+func (f *Factory) Account(ledgerID, code, accountType string) Account {
+	f.t.Helper()
+	a := Account{LedgerID: ledgerID, Code: code, Type: accountType}
+	f.queryRow(&a.ID, `INSERT INTO accounts (ledger_id, code, name, type, balance) VALUES ($1, $2, $2, $3, 0) RETURNING id`,
+		a.LedgerID, a.Code, a.Type)
+	return a
+}
+
+// APIKey creates an active API key for the ledger, hashed with APIKeySecret.
+func (f *Factory) APIKey(ledgerID string) APIKey {
+	f.t.Helper()
+	k := APIKey{LedgerID: ledgerID, Key: fmt.Sprintf("sk_test_%d_%d", time.Now().UnixNano(), seq.Add(1))}
+	hash, err := auth.ComputeKeyHash(APIKeySecret, k.Key)
+	if err != nil {
+		f.t.Fatalf("failed to hash api key: %v", err)
+	}
+	f.queryRow(&k.ID, `INSERT INTO api_keys (ledger_id, key_hash, prefix, description) VALUES ($1, $2, $3, 'test') RETURNING id`,
+		k.LedgerID, hash, k.Key[:10])
+	return k
+}
+
+// Transfer posts a transaction debiting one account and crediting another, and returns
+// its id. Like the API, it only appends the event; run a projector to update balances.
+func (f *Factory) Transfer(ledgerID, debit, credit, amount string) string {
+	f.t.Helper()
+	n := seq.Add(1)
+	return f.Transaction(ledger.PostTransactionCommand{
+		LedgerID:       ledgerID,
+		ExternalID:     fmt.Sprintf("tx-%d", n),
+		IdempotencyKey: fmt.Sprintf("tx-%d", n),
+		Postings: []ledger.PostingInput{
+			{AccountCode: debit, Direction: "debit", Amount: amount},
+			{AccountCode: credit, Direction: "credit", Amount: amount},
+		},
+	})
+}
+
+// Transaction posts cmd, filling in the currency (the ledger's) and the occurrence time
+// when they are empty.
+func (f *Factory) Transaction(cmd ledger.PostTransactionCommand) string {
+	f.t.Helper()
+	ctx := context.Background()
+	if cmd.Currency == "" {
+		f.queryRow(&cmd.Currency, `SELECT currency FROM ledgers WHERE id = $1`, cmd.LedgerID)
+	}
+	if cmd.OccurredAt.IsZero() {
+		cmd.OccurredAt = time.Now()
+	}
+	id, err := f.Service.PostTransaction(ctx, cmd)
+	if err != nil {
+		f.t.Fatalf("failed to post transaction: %v", err)
+	}
+	return id
+}
+
+func (f *Factory) queryRow(dest *string, sql string, args ...any) {
+	f.t.Helper()
+	if err := f.DB.QueryRow(context.Background(), sql, args...).Scan(dest); err != nil {
+		f.t.Fatalf("fixture query failed: %v", err)
+	}
+}
+
+// WebhookEndpoint registers an active webhook endpoint for the ledger and returns its id.
+func (f *Factory) WebhookEndpoint(ledgerID, url, secret string) string {
+	f.t.Helper()
+	var id string
+	f.queryRow(&id, `INSERT INTO webhook_endpoints (ledger_id, url, secret) VALUES ($1, $2, $3) RETURNING id`,
+		ledgerID, url, secret)
+	return id
+}
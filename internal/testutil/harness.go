@@ -0,0 +1,171 @@
+// Package testutil provides a shared PostgreSQL test harness and factories for the
+// common fixtures (organizations, ledgers, accounts, API keys, transactions).
+//
+// A single container is started per test binary and the schema is migrated once into
+// a template database; every NewDB call then clones the template, so each test gets an
+// isolated, fully migrated database in milliseconds.
+//
+//	func TestMain(m *testing.M) { os.Exit(testutil.Run(m)) }
+//
+//	func TestSomething(t *testing.T) {
+//		pool := testutil.NewDB(t)
+//		f := testutil.NewFactory(t, pool)
+//		l := f.Ledger()
+//		...
+//	}
+package testutil
+
+import (
+	"Go_FormanceLegder/internal/db"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+	"github.com/riverqueue/river/rivermigrate"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const templateDB = "ledger_template"
+
+var (
+	harnessOnce sync.Once
+	harnessErr  error
+	container   testcontainers.Container
+	adminURL    string // connection string of the container's maintenance database
+	dbCounter   atomic.Int64
+)
+
+// Run runs the package's tests and terminates the shared container afterwards.
+func Run(m *testing.M) int {
+	code := m.Run()
+	if container != nil {
+		container.Terminate(context.Background())
+	}
+	return code
+}
+
+// NewDB returns a pool connected to a fresh, fully migrated database that is dropped
+// when the test ends. The pool is created with db.NewPool, so build-tagged hooks such
+// as fault injection apply.
+func NewDB(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	harnessOnce.Do(func() { harnessErr = startHarness(ctx) })
+	if harnessErr != nil {
+		t.Fatalf("failed to start test database: %v", harnessErr)
+	}
+
+	name := fmt.Sprintf("test_%d_%d", os.Getpid(), dbCounter.Add(1))
+	if err := adminExec(ctx, fmt.Sprintf(`CREATE DATABASE %s TEMPLATE %s`, name, templateDB)); err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+
+	pool, err := db.NewPool(ctx, databaseURL(name))
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+		adminExec(context.Background(), fmt.Sprintf(`DROP DATABASE IF EXISTS %s WITH (FORCE)`, name))
+	})
+
+	return pool
+}
+
+// MigrationsDir returns the repository's migrations directory.
+func MigrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
+// Migrate applies the repository's SQL migrations, then River's.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	files, err := filepath.Glob(filepath.Join(MigrationsDir(), "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err := pool.Exec(ctx, string(content)); err != nil {
+			return fmt.Errorf("migration %s: %w", filepath.Base(file), err)
+		}
+	}
+
+	migrator, err := rivermigrate.New(riverpgxv5.New(pool), nil)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Migrate(ctx, rivermigrate.DirectionUp, nil)
+	return err
+}
+
+func startHarness(ctx context.Context) error {
+	c, err := postgres.Run(ctx, "postgres:16",
+		postgres.WithDatabase("postgres"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second)),
+	)
+	if err != nil {
+		return err
+	}
+	container = c
+
+	adminURL, err = c.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		return err
+	}
+
+	// Migrate once into the template; tests clone it
+	if err := adminExec(ctx, `CREATE DATABASE `+templateDB); err != nil {
+		return err
+	}
+	pool, err := pgxpool.New(ctx, databaseURL(templateDB))
+	if err != nil {
+		return err
+	}
+	err = Migrate(ctx, pool)
+	pool.Close()
+	return err
+}
+
+// databaseURL swaps the database name in the container's connection string.
+func databaseURL(name string) string {
+	config, err := pgx.ParseConfig(adminURL)
+	if err != nil {
+		return adminURL
+	}
+	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
+		config.User, config.Password, config.Host, config.Port, name)
+}
+
+func adminExec(ctx context.Context, sql string) error {
+	conn, err := pgx.Connect(ctx, adminURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	_, err = conn.Exec(ctx, sql)
+	return err
+}
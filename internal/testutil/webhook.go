@@ -0,0 +1,98 @@
+package testutil
+
+import (
+	"Go_FormanceLegder/internal/webhook"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ReceivedWebhook is one request recorded by a WebhookReceiver.
+type ReceivedWebhook struct {
+	Header         http.Header
+	Body           []byte
+	SignatureValid bool
+	Status         int // status the receiver responded with
+	ReceivedAt     time.Time
+}
+
+// WebhookReceiver is a test webhook endpoint. It records every request, verifies its
+// timestamped signature against Secret and answers with a scripted sequence of statuses, e.g.
+// 500, 500, 200 to exercise retries; once the script runs out it answers 200.
+type WebhookReceiver struct {
+	*httptest.Server
+	Secret string
+
+	mu       sync.Mutex
+	script   []int
+	requests []ReceivedWebhook
+}
+
+// NewWebhookReceiver starts a receiver that is closed when the test ends.
+func NewWebhookReceiver(t testing.TB, secret string, statuses ...int) *WebhookReceiver {
+	t.Helper()
+	rec := &WebhookReceiver{Secret: secret, script: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(rec.serve))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+// Script replaces the remaining status sequence.
+func (rec *WebhookReceiver) Script(statuses ...int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.script = statuses
+}
+
+// Requests returns a copy of the requests received so far.
+func (rec *WebhookReceiver) Requests() []ReceivedWebhook {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]ReceivedWebhook(nil), rec.requests...)
+}
+
+// Count returns the number of requests received so far.
+func (rec *WebhookReceiver) Count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.requests)
+}
+
+// WaitFor blocks until n requests have been received, failing the test after timeout.
+func (rec *WebhookReceiver) WaitFor(t testing.TB, n int, timeout time.Duration) []ReceivedWebhook {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for rec.Count() < n {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("expected %d webhook requests, got %d", n, rec.Count())
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	return rec.Requests()
+}
+
+func (rec *WebhookReceiver) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	status := http.StatusOK
+	if len(rec.script) > 0 {
+		status, rec.script = rec.script[0], rec.script[1:]
+	}
+	rec.requests = append(rec.requests, ReceivedWebhook{
+		Header:         r.Header.Clone(),
+		Body:           body,
+		SignatureValid: webhook.VerifyTimestamped([]byte(rec.Secret), body, r.Header.Get("X-Ledger-Webhook-Signature"), time.Now()) == nil,
+		Status:         status,
+		ReceivedAt:     time.Now(),
+	})
+	rec.mu.Unlock()
+
+	w.WriteHeader(status)
+}
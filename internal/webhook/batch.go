@@ -0,0 +1,345 @@
+package webhook
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+)
+
+// BatchPolicy makes an endpoint receive its events in batches: those arriving within
+// Window of the first are sent together, MaxEvents at most, as one signed request.
+// A zero Window delivers each event on its own.
+type BatchPolicy struct {
+	Window    time.Duration
+	MaxEvents int
+}
+
+// DefaultBatchMaxEvents caps batches of endpoints that don't set a maximum.
+const DefaultBatchMaxEvents = 100
+
+// A batch job that hasn't run this long after it was due is taken as lost, and the next
+// event queued for the endpoint schedules another.
+const batchStale = 15 * time.Minute
+
+// BatchArgs is the job that sends an endpoint's queued events as a batch. At most one is
+// pending per endpoint: webhook_endpoints.batch_due_at is set while it is.
+type BatchArgs struct {
+	EndpointID string `json:"endpoint_id"`
+	LedgerID   string `json:"ledger_id"`
+}
+
+func (BatchArgs) Kind() string {
+	return "webhook_batch"
+}
+
+// queueBatch adds the event to the endpoint's next batch, scheduling the batch job
+// unless one is pending. The endpoint's row lock orders this against the job deciding
+// it is done, so no event is left queued without a job.
+func (w *Worker) queueBatch(ctx context.Context, ledgerID, eventID string, ep WebhookEndpoint) error {
+	client, err := river.ClientFromContextSafely[pgx.Tx](ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := w.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var dueAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT batch_due_at FROM webhook_endpoints WHERE id = $1 FOR UPDATE`, ep.ID).Scan(&dueAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_batch_items (webhook_endpoint_id, event_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, ep.ID, eventID)
+	if err != nil {
+		return err
+	}
+	if dueAt == nil || time.Since(*dueAt) > batchStale {
+		due := time.Now().Add(ep.Batch.Window)
+		if _, err := tx.Exec(ctx, `UPDATE webhook_endpoints SET batch_due_at = $2 WHERE id = $1`, ep.ID, due); err != nil {
+			return err
+		}
+		_, err = client.InsertTx(ctx, tx, BatchArgs{EndpointID: ep.ID, LedgerID: ledgerID}, &river.InsertOpts{ScheduledAt: due})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// BatchWorker sends batches through Deliveries, the worker whose limits, HTTP client
+// and endpoint health they share with single deliveries.
+type BatchWorker struct {
+	river.WorkerDefaults[BatchArgs]
+	Deliveries *Worker
+}
+
+func (b *BatchWorker) Timeout(*river.Job[BatchArgs]) time.Duration {
+	return jobTimeout
+}
+
+type batchItem struct {
+	EventID  string
+	Attempts int
+}
+
+// Work sends the endpoint's oldest queued events as a JSON array of envelopes, each as
+// delivered on its own, under one signature and an X-Ledger-Batch-Id header. Every event
+// is logged as a delivery of its own. A failed batch is retried whole by the endpoint's
+// retry policy, events that exhausted it being dropped; the job snoozes until the next
+// batch is due and completes once the queue is empty.
+func (b *BatchWorker) Work(ctx context.Context, job *river.Job[BatchArgs]) error {
+	w, args := b.Deliveries, job.Args
+
+	ep, active, err := b.loadEndpoint(ctx, args)
+	if err != nil {
+		return err
+	}
+	if !active {
+		// Deleted or paused: its queued events are skipped, as its single deliveries are
+		_, err := w.DB.Exec(ctx, `DELETE FROM webhook_batch_items WHERE webhook_endpoint_id = $1`, args.EndpointID)
+		if err != nil {
+			return err
+		}
+		_, err = w.DB.Exec(ctx, `UPDATE webhook_endpoints SET batch_due_at = NULL WHERE id = $1`, args.EndpointID)
+		return err
+	}
+
+	rows, err := w.DB.Query(ctx, `
+		SELECT event_id, attempts FROM webhook_batch_items
+		WHERE webhook_endpoint_id = $1
+		ORDER BY queued_at, event_id
+		LIMIT $2
+	`, ep.ID, ep.Batch.MaxEvents)
+	if err != nil {
+		return err
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[batchItem])
+	if err != nil {
+		return err
+	}
+
+	var retryAt time.Time
+	if len(items) > 0 {
+		releaseEndpoint, ok := w.Endpoints.Acquire(ctx, ep.ID, ep.Limit, endpointWait)
+		if !ok {
+			return b.reschedule(ctx, ep, time.Now().Add(hostSnooze+rand.N(hostSnooze)))
+		}
+		release, ok := w.Limiter.Acquire(ctx, hostOf(ep.URL), hostWait)
+		if !ok {
+			releaseEndpoint()
+			return b.reschedule(ctx, ep, time.Now().Add(hostSnooze+rand.N(hostSnooze)))
+		}
+		retryAt, err = b.send(ctx, ep, args.LedgerID, items)
+		release()
+		releaseEndpoint()
+		if err != nil {
+			return err
+		}
+	}
+
+	next := retryAt
+	if next.IsZero() && len(items) == ep.Batch.MaxEvents {
+		// A backlog: the next batch goes right away
+		next = time.Now()
+	}
+	return b.reschedule(ctx, ep, next)
+}
+
+func (b *BatchWorker) loadEndpoint(ctx context.Context, args BatchArgs) (WebhookEndpoint, bool, error) {
+	ep := WebhookEndpoint{ID: args.EndpointID}
+	var active bool
+	var maxAttempts, baseSeconds, maxConcurrent, perSecond, maxEvents *int
+	var backoff *string
+	err := b.Deliveries.DB.QueryRow(ctx, `
+		SELECT url, secret,
+			CASE WHEN previous_secret_expires_at > NOW() THEN COALESCE(previous_secret, '') ELSE '' END,
+			is_active, retry_max_attempts, retry_backoff, retry_backoff_base_seconds,
+			rate_limit_max_concurrency, rate_limit_per_second,
+			custom_headers, COALESCE(client_certificate, ''), COALESCE(client_key, ''), batch_max_events
+		FROM webhook_endpoints
+		WHERE id = $1 AND ledger_id = $2
+	`, args.EndpointID, args.LedgerID).Scan(&ep.URL, &ep.Secret, &ep.PreviousSecret, &active, &maxAttempts, &backoff,
+		&baseSeconds, &maxConcurrent, &perSecond, &ep.Headers, &ep.ClientCertificate, &ep.ClientKey, &maxEvents)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ep, false, nil
+	}
+	if err != nil {
+		return ep, false, err
+	}
+	ep.Retry = DefaultRetryPolicy.Override(maxAttempts, backoff, baseSeconds)
+	ep.Limit = DefaultEndpointLimit.Override(maxConcurrent, perSecond)
+	ep.Batch.MaxEvents = DefaultBatchMaxEvents
+	if maxEvents != nil {
+		ep.Batch.MaxEvents = *maxEvents
+	}
+	return ep, active, nil
+}
+
+// send delivers items as one batch and logs the result against each of their events. It
+// returns when to retry the batch, zero when it succeeded or has no attempts left.
+func (b *BatchWorker) send(ctx context.Context, ep WebhookEndpoint, ledgerID string, items []batchItem) (time.Time, error) {
+	w := b.Deliveries
+	eventIDs := make([]string, len(items))
+	for i, item := range items {
+		eventIDs[i] = item.EventID
+	}
+	rows, err := w.DB.Query(ctx, `
+		SELECT id, event_type, payload, occurred_at FROM events WHERE id::text = ANY($1) AND ledger_id = $2
+	`, eventIDs, ledgerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loaded := map[string]deliveryEvent{}
+	for rows.Next() {
+		event := deliveryEvent{LedgerID: ledgerID}
+		if err := rows.Scan(&event.ID, &event.Type, &event.Payload, &event.OccurredAt); err != nil {
+			rows.Close()
+			return time.Time{}, err
+		}
+		loaded[event.ID] = event
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return time.Time{}, err
+	}
+
+	// In the order queued; an event that can't be built fails on its own
+	var envelopes []json.RawMessage
+	var sent []batchItem
+	for _, item := range items {
+		event, ok := loaded[item.EventID]
+		if !ok {
+			continue
+		}
+		payload, err := events.Current(event.Type, event.Payload)
+		var envelope []byte
+		if err == nil {
+			envelope, err = Wrap(DeliveryID(event.ID, ep.ID), event.ID, event.Type, ledgerID, event.OccurredAt, payload)
+		}
+		if err != nil {
+			w.logDelivery(ctx, event.ID, ep.ID, Outcome{Status: "non_retryable_error", Error: err.Error()}, time.Time{})
+			continue
+		}
+		envelopes = append(envelopes, envelope)
+		sent = append(sent, item)
+	}
+
+	var outcome Outcome
+	if len(sent) > 0 {
+		body, err := json.Marshal(envelopes)
+		var client *http.Client
+		if err == nil {
+			client, err = ClientFor(w.HttpClient, ep)
+		}
+		if err != nil {
+			outcome = Outcome{Status: "non_retryable_error", Error: err.Error()}
+		} else {
+			header := ep.Header()
+			header.Set("X-Ledger-Batch-Id", uuid.NewString())
+			header.Set("X-Ledger-Batch-Size", strconv.Itoa(len(sent)))
+			outcome = Deliver(ctx, client, ep.URL, ep.Secrets(), body, header)
+		}
+		w.recordHealth(ctx, ledgerID, ep, outcome)
+	}
+
+	// Each event goes with the batch's retries while it has attempts left
+	var retryAt time.Time
+	var retried []string
+	for _, item := range sent {
+		var itemRetryAt time.Time
+		if outcome.Retryable() && item.Attempts+1 < ep.Retry.MaxAttempts {
+			wait := outcome.RetryAfter
+			if wait == 0 {
+				wait = ep.Retry.Delay(item.Attempts + 1)
+			}
+			itemRetryAt = time.Now().Add(wait)
+			retryAt = earliest(retryAt, itemRetryAt)
+			retried = append(retried, item.EventID)
+		}
+		w.logDelivery(ctx, item.EventID, ep.ID, outcome, itemRetryAt)
+	}
+
+	_, err = w.DB.Exec(ctx, `
+		UPDATE webhook_batch_items SET attempts = attempts + 1
+		WHERE webhook_endpoint_id = $1 AND event_id::text = ANY($2)
+	`, ep.ID, retried)
+	if err != nil {
+		return retryAt, fmt.Errorf("failed to record batch attempt: %w", err)
+	}
+	_, err = w.DB.Exec(ctx, `
+		DELETE FROM webhook_batch_items
+		WHERE webhook_endpoint_id = $1 AND event_id::text = ANY($2) AND NOT (event_id::text = ANY($3))
+	`, ep.ID, eventIDs, retried)
+	if err != nil {
+		return retryAt, fmt.Errorf("failed to dequeue batch: %w", err)
+	}
+	return retryAt, nil
+}
+
+// reschedule snoozes the job until next, or until the endpoint's window from now when
+// next is zero, if events are still queued; otherwise it completes the job, after which
+// the next event queued schedules a new one.
+func (b *BatchWorker) reschedule(ctx context.Context, ep WebhookEndpoint, next time.Time) error {
+	tx, err := b.Deliveries.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var window int
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(batch_window_seconds, 0) FROM webhook_endpoints WHERE id = $1 FOR UPDATE
+	`, ep.ID).Scan(&window)
+	if err != nil {
+		return err
+	}
+	var queued bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_batch_items WHERE webhook_endpoint_id = $1)`, ep.ID).Scan(&queued)
+	if err != nil {
+		return err
+	}
+	var dueAt *time.Time
+	if queued {
+		if next.IsZero() {
+			next = time.Now().Add(time.Duration(window) * time.Second)
+		}
+		dueAt = &next
+	}
+	if _, err := tx.Exec(ctx, `UPDATE webhook_endpoints SET batch_due_at = $2 WHERE id = $1`, ep.ID, dueAt); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if dueAt == nil {
+		return nil
+	}
+	return river.JobSnooze(max(time.Until(next), time.Second))
+}
+
+// batchPolicy is an endpoint's batching from its columns: off without a window.
+func batchPolicy(windowSeconds, maxEvents *int) BatchPolicy {
+	if windowSeconds == nil {
+		return BatchPolicy{}
+	}
+	p := BatchPolicy{Window: time.Duration(*windowSeconds) * time.Second, MaxEvents: DefaultBatchMaxEvents}
+	if maxEvents != nil {
+		p.MaxEvents = *maxEvents
+	}
+	return p
+}
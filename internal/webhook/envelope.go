@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"Go_FormanceLegder/internal/events"
+	"encoding/json"
+	"time"
+)
+
+// Envelope is the body of every delivery: the event payload under Data, with what a
+// receiver needs to route and deduplicate it without calling the API. It is identical
+// across a delivery's attempts, so a retried body can be compared with the first.
+type Envelope struct {
+	DeliveryID       string          `json:"delivery_id"`       // as X-Ledger-Delivery-Id
+	EventFingerprint string          `json:"event_fingerprint"` // as X-Ledger-Event-Fingerprint
+	EventID          string          `json:"event_id"`
+	EventType        string          `json:"event_type"`
+	LedgerID         string          `json:"ledger_id"`
+	OccurredAt       time.Time       `json:"occurred_at"`
+	SchemaVersion    int             `json:"schema_version"` // of Data, for EventType
+	Data             json.RawMessage `json:"data"`
+}
+
+// Wrap builds the envelope of payload, which must be at eventType's current schema
+// version, and encodes it.
+func Wrap(deliveryID, eventID, eventType, ledgerID string, occurredAt time.Time, payload []byte) ([]byte, error) {
+	return json.Marshal(Envelope{
+		DeliveryID:       deliveryID,
+		EventFingerprint: Fingerprint(payload),
+		EventID:          eventID,
+		EventType:        eventType,
+		LedgerID:         ledgerID,
+		OccurredAt:       occurredAt.UTC(),
+		SchemaVersion:    events.SchemaVersion(eventType),
+		Data:             payload,
+	})
+}
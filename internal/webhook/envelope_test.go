@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWrap(t *testing.T) {
+	occurredAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	body, err := Wrap("d1", "e1", "TransactionPosted", "l1", occurredAt, []byte(`{"transaction_id": "t1"}`))
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+
+	var got Envelope
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if got.DeliveryID != "d1" || got.EventID != "e1" || got.EventType != "TransactionPosted" || got.LedgerID != "l1" {
+		t.Errorf("unexpected envelope %s", body)
+	}
+	if !got.OccurredAt.Equal(occurredAt) || got.OccurredAt.Location() != time.UTC {
+		t.Errorf("occurred_at: got %v, want %v in UTC", got.OccurredAt, occurredAt)
+	}
+	if got.SchemaVersion != 2 {
+		t.Errorf("schema_version: got %d, want 2", got.SchemaVersion)
+	}
+	if string(got.Data) != `{"transaction_id":"t1"}` {
+		t.Errorf("data: got %s", got.Data)
+	}
+
+	if _, err := Wrap("d1", "e1", "TransactionPosted", "l1", occurredAt, []byte("not json")); err == nil {
+		t.Error("expected an invalid payload to fail")
+	}
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// MaxFilterLength bounds an endpoint's filter expression.
+const MaxFilterLength = 4096
+
+const filterCostLimit = 10000
+
+// ValidateFilter checks an endpoint's filter: a CEL expression (https://cel.dev) an event
+// must satisfy to be delivered to the endpoint, e.g.
+//
+//	event_type == "HoldCreated" && amount > 1000 && currency == "USD"
+//
+// The fields of the event's payload, at its current schema version, are variables of
+// their own, and the whole payload is also event, for has(event.metadata). Amounts are
+// doubles. An event the filter fails to evaluate on, e.g. because it lacks a field the
+// filter reads, is not delivered. An empty filter delivers every event.
+func ValidateFilter(filter string) error {
+	if filter == "" {
+		return nil
+	}
+	if len(filter) > MaxFilterLength {
+		return fmt.Errorf("filter must be at most %d bytes", MaxFilterLength)
+	}
+	if _, err := compileFilter(filter); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	return nil
+}
+
+// FilterVars are the variables filters see for an event of eventType with payload.
+func FilterVars(eventType string, payload []byte) (map[string]any, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	fields = amountsAsNumbers(fields).(map[string]any)
+	vars := make(map[string]any, len(fields)+2)
+	for name, value := range fields {
+		vars[name] = value
+	}
+	vars["event_type"] = eventType
+	vars["event"] = fields
+	return vars, nil
+}
+
+// amountsAsNumbers turns the decimal strings of every amount field in v into doubles.
+func amountsAsNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if s, ok := field.(string); ok && k == "amount" {
+				if f, err := strconv.ParseFloat(s, 64); err == nil {
+					v[k] = f
+				}
+				continue
+			}
+			v[k] = amountsAsNumbers(field)
+		}
+	case []any:
+		for i := range v {
+			v[i] = amountsAsNumbers(v[i])
+		}
+	}
+	return v
+}
+
+// MatchFilter reports whether the event of vars satisfies filter.
+func MatchFilter(ctx context.Context, filter string, vars map[string]any) (bool, error) {
+	if filter == "" {
+		return true, nil
+	}
+	program, err := compileFilter(filter)
+	if err != nil {
+		return false, err
+	}
+	out, _, err := program.ContextEval(ctx, vars)
+	if err != nil {
+		return false, err
+	}
+	ok, isBool := out.Value().(bool)
+	if !isBool {
+		return false, fmt.Errorf("filter evaluated to %v, not a bool", out.Value())
+	}
+	return ok, nil
+}
+
+// Filters are only parsed, not type-checked: the variables they can use depend on the
+// event type, so they are resolved when the filter is evaluated.
+var filterEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(cel.CrossTypeNumericComparisons(true))
+})
+
+// Compiled filters by expression, so each endpoint's is compiled once rather than for
+// every event.
+var (
+	filtersMu sync.Mutex
+	filters   = map[string]cel.Program{}
+)
+
+const maxFilters = 4096
+
+func compileFilter(filter string) (cel.Program, error) {
+	filtersMu.Lock()
+	program, ok := filters[filter]
+	filtersMu.Unlock()
+	if ok {
+		return program, nil
+	}
+
+	env, err := filterEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Parse(filter)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	program, err = env.Program(ast, cel.CostLimit(filterCostLimit), cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return nil, err
+	}
+
+	filtersMu.Lock()
+	if len(filters) >= maxFilters {
+		filters = map[string]cel.Program{}
+	}
+	filters[filter] = program
+	filtersMu.Unlock()
+	return program, nil
+}
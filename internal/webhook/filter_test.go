@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateFilter(t *testing.T) {
+	for _, filter := range []string{"", `amount > 1000 && currency == "USD"`, `has(event.metadata) && metadata.tier == "gold"`} {
+		if err := ValidateFilter(filter); err != nil {
+			t.Errorf("ValidateFilter(%q) = %v", filter, err)
+		}
+	}
+	for _, filter := range []string{`amount >`, `currency == "USD`} {
+		if err := ValidateFilter(filter); err == nil {
+			t.Errorf("ValidateFilter(%q) accepted", filter)
+		}
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	hold := []byte(`{"schema_version":1,"hold_id":"h1","account_code":"customers:1","amount":"1500.50","currency":"USD","metadata":{"tier":"gold"}}`)
+	posted := []byte(`{"schema_version":2,"transaction_id":"t1","currency":"EUR","postings":[{"account_code":"cash","direction":"debit","amount":"20","currency":"EUR"}]}`)
+
+	cases := []struct {
+		name, filter, eventType string
+		payload                 []byte
+		want, fails             bool
+	}{
+		{"empty", "", "HoldCreated", hold, true, false},
+		{"amount and currency", `amount > 1000 && currency == "USD"`, "HoldCreated", hold, true, false},
+		{"below threshold", `amount > 2000`, "HoldCreated", hold, false, false},
+		{"event type", `event_type == "TransactionPosted"`, "HoldCreated", hold, false, false},
+		{"metadata", `metadata.tier == "gold"`, "HoldCreated", hold, true, false},
+		{"postings", `postings.exists(p, p.amount >= 20.0 && p.account_code == "cash")`, "TransactionPosted", posted, true, false},
+		{"missing field", `amount > 1000`, "TransactionPosted", posted, false, true},
+		{"has guard", `has(event.amount) && amount > 1000`, "TransactionPosted", posted, false, false},
+		{"not a bool", `currency`, "HoldCreated", hold, false, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vars, err := FilterVars(c.eventType, c.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := MatchFilter(context.Background(), c.filter, vars)
+			if (err != nil) != c.fails {
+				t.Fatalf("MatchFilter error = %v, want failure %v", err, c.fails)
+			}
+			if got != c.want {
+				t.Errorf("MatchFilter = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimiter caps concurrent webhook requests per destination host and spaces their
+// starts by Delay, so a burst of jobs (e.g. after an import) cannot flood a single
+// receiver. Limits apply per worker process. A nil limiter allows everything.
+type HostLimiter struct {
+	MaxConcurrent int
+	Delay         time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots chan struct{}
+	next  time.Time // earliest start of the next request
+}
+
+func NewHostLimiter(maxConcurrent int, delay time.Duration) *HostLimiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &HostLimiter{MaxConcurrent: maxConcurrent, Delay: delay, hosts: map[string]*hostState{}}
+}
+
+// Acquire waits up to wait for a free slot on host and then for its politeness delay.
+// It returns false when the host stayed saturated or ctx ended; otherwise release must
+// be called once the request is done.
+func (l *HostLimiter) Acquire(ctx context.Context, host string, wait time.Duration) (release func(), ok bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	h := l.host(host)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case h.slots <- struct{}{}:
+	case <-timer.C:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+	release = func() { <-h.slots }
+
+	l.mu.Lock()
+	start := time.Now()
+	if h.next.After(start) {
+		start = h.next
+	}
+	h.next = start.Add(l.Delay)
+	l.mu.Unlock()
+
+	if d := time.Until(start); d > 0 {
+		delay := time.NewTimer(d)
+		defer delay.Stop()
+		select {
+		case <-delay.C:
+		case <-ctx.Done():
+			release()
+			return nil, false
+		}
+	}
+	return release, true
+}
+
+func (l *HostLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, l.MaxConcurrent)}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// hostOf is the limiter key of an endpoint URL: its lowercased host and port.
+func hostOf(endpointURL string) string {
+	u, err := url.Parse(endpointURL)
+	if err != nil {
+		return endpointURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+// EndpointLimit caps the deliveries to one endpoint: at most MaxConcurrent in flight,
+// started at PerSecond on average, in bursts of up to PerSecond.
+type EndpointLimit struct {
+	MaxConcurrent int
+	PerSecond     int
+}
+
+// DefaultEndpointLimit applies to endpoints without overrides.
+var DefaultEndpointLimit = EndpointLimit{MaxConcurrent: 2, PerSecond: 10}
+
+func (l EndpointLimit) Validate() error {
+	if l.MaxConcurrent < 1 {
+		return errors.New("endpoint max concurrency must be positive")
+	}
+	if l.PerSecond < 1 {
+		return errors.New("endpoint rate must be positive")
+	}
+	return nil
+}
+
+// Override replaces the fields an endpoint sets.
+func (l EndpointLimit) Override(maxConcurrent, perSecond *int) EndpointLimit {
+	if maxConcurrent != nil {
+		l.MaxConcurrent = *maxConcurrent
+	}
+	if perSecond != nil {
+		l.PerSecond = *perSecond
+	}
+	return l
+}
+
+// EndpointLimiter holds each endpoint's in-flight requests and rate, so one slow or busy
+// endpoint only ever occupies its own few worker slots. Limits apply per worker process.
+// A nil limiter allows everything.
+type EndpointLimiter struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointState
+}
+
+type endpointState struct {
+	limit  EndpointLimit
+	slots  chan struct{}
+	tokens float64 // may go negative: requests already promised a later start
+	last   time.Time
+}
+
+func NewEndpointLimiter() *EndpointLimiter {
+	return &EndpointLimiter{endpoints: map[string]*endpointState{}}
+}
+
+// Acquire waits up to wait for a free slot on the endpoint and then for its turn under
+// limit. It returns false, without waiting further, when the endpoint stayed saturated
+// or its turn is more than wait away, so the caller can come back later instead of
+// holding a worker; otherwise release must be called once the request is done.
+func (l *EndpointLimiter) Acquire(ctx context.Context, endpointID string, limit EndpointLimit, wait time.Duration) (release func(), ok bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	e := l.endpoint(endpointID, limit)
+
+	select {
+	case e.slots <- struct{}{}:
+	default:
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case e.slots <- struct{}{}:
+		case <-timer.C:
+			return nil, false
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+	release = func() { <-e.slots }
+
+	l.mu.Lock()
+	now := time.Now()
+	rate := float64(limit.PerSecond)
+	e.tokens = min(rate, e.tokens+now.Sub(e.last).Seconds()*rate)
+	e.last = now
+	var delay time.Duration
+	if e.tokens < 1 {
+		delay = time.Duration((1 - e.tokens) / rate * float64(time.Second))
+	}
+	if delay > wait {
+		l.mu.Unlock()
+		release()
+		return nil, false
+	}
+	e.tokens--
+	l.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, false
+		}
+	}
+	return release, true
+}
+
+// endpoint returns the endpoint's state, starting it over with a full burst when its
+// limit changed; requests in flight under the old limit release their old slots.
+func (l *EndpointLimiter) endpoint(endpointID string, limit EndpointLimit) *endpointState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.endpoints[endpointID]
+	if !ok || e.limit != limit {
+		e = &endpointState{
+			limit:  limit,
+			slots:  make(chan struct{}, max(limit.MaxConcurrent, 1)),
+			tokens: float64(limit.PerSecond),
+			last:   time.Now(),
+		}
+		l.endpoints[endpointID] = e
+	}
+	return e
+}
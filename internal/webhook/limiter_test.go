@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewHostLimiter(2, 20*time.Millisecond)
+
+	start := time.Now()
+	r1, ok1 := l.Acquire(ctx, "a.example", time.Second)
+	r2, ok2 := l.Acquire(ctx, "a.example", time.Second)
+	if !ok1 || !ok2 {
+		t.Fatal("expected two slots")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("expected the second request to wait for the politeness delay")
+	}
+	if _, ok := l.Acquire(ctx, "a.example", 10*time.Millisecond); ok {
+		t.Fatal("expected a saturated host to time out")
+	}
+	if r, ok := l.Acquire(ctx, "b.example", 10*time.Millisecond); !ok {
+		t.Fatal("expected other hosts to be unaffected")
+	} else {
+		r()
+	}
+
+	r1()
+	if r, ok := l.Acquire(ctx, "a.example", time.Second); !ok {
+		t.Fatal("expected a released slot to be reusable")
+	} else {
+		r()
+	}
+	r2()
+
+	var nilLimiter *HostLimiter
+	if _, ok := nilLimiter.Acquire(ctx, "a.example", 0); !ok {
+		t.Fatal("expected a nil limiter to allow everything")
+	}
+	if hostOf("https://Hooks.Example.com:8443/x") != "hooks.example.com:8443" {
+		t.Fatalf("unexpected host key %q", hostOf("https://Hooks.Example.com:8443/x"))
+	}
+}
+
+func TestEndpointLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewEndpointLimiter()
+	limit := EndpointLimit{MaxConcurrent: 1, PerSecond: 20}
+
+	r1, ok := l.Acquire(ctx, "ep1", limit, time.Second)
+	if !ok {
+		t.Fatal("expected a slot")
+	}
+	if _, ok := l.Acquire(ctx, "ep1", limit, 10*time.Millisecond); ok {
+		t.Fatal("expected a saturated endpoint to time out")
+	}
+	if r, ok := l.Acquire(ctx, "ep2", limit, 10*time.Millisecond); !ok {
+		t.Fatal("expected other endpoints to be unaffected")
+	} else {
+		r()
+	}
+	r1()
+
+	// The burst is spent after PerSecond starts; the next waits for its turn
+	burst := EndpointLimit{MaxConcurrent: 5, PerSecond: 2}
+	for range 2 {
+		r, ok := l.Acquire(ctx, "ep3", burst, 0)
+		if !ok {
+			t.Fatal("expected the burst to start at once")
+		}
+		r()
+	}
+	if _, ok := l.Acquire(ctx, "ep3", burst, 100*time.Millisecond); ok {
+		t.Fatal("expected a start more than wait away to be refused")
+	}
+	start := time.Now()
+	if r, ok := l.Acquire(ctx, "ep3", burst, time.Second); !ok {
+		t.Fatal("expected the start to wait for its turn")
+	} else {
+		r()
+	}
+	if waited := time.Since(start); waited < 300*time.Millisecond {
+		t.Fatalf("expected to wait about 500ms, waited %v", waited)
+	}
+
+	var nilLimiter *EndpointLimiter
+	if _, ok := nilLimiter.Acquire(ctx, "ep1", limit, 0); !ok {
+		t.Fatal("expected a nil limiter to allow everything")
+	}
+	concurrency, rate := 3, 7
+	if got := DefaultEndpointLimit.Override(&concurrency, &rate); got != (EndpointLimit{MaxConcurrent: 3, PerSecond: 7}) {
+		t.Fatalf("unexpected override %+v", got)
+	}
+}
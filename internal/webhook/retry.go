@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/riverqueue/river"
+)
+
+// RetryPolicy decides how often and how soon failed deliveries to an endpoint are tried
+// again. Endpoints can override MaxAttempts, Backoff and Base; MaxDelay is global.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts at a delivery, the first included
+	Backoff     string        // exponential, linear or fixed
+	Base        time.Duration // delay after the first failed attempt
+	MaxDelay    time.Duration // cap on any one delay
+}
+
+// BackoffStrategies are the valid RetryPolicy.Backoff values.
+var BackoffStrategies = []string{"exponential", "linear", "fixed"}
+
+// DefaultRetryPolicy applies to endpoints without overrides, and to webhook jobs that
+// fail before delivering, e.g. on a database error. Set it before starting workers.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 25, Backoff: "exponential", Base: 30 * time.Second, MaxDelay: 12 * time.Hour}
+
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return errors.New("max_attempts must be at least 1")
+	}
+	if !slices.Contains(BackoffStrategies, p.Backoff) {
+		return fmt.Errorf("backoff must be one of %v", BackoffStrategies)
+	}
+	if p.Base < 0 || p.MaxDelay < 0 {
+		return errors.New("backoff delays cannot be negative")
+	}
+	return nil
+}
+
+// Delay is the wait before the next attempt after failed attempts so far: Base for the
+// first, then doubling (exponential), growing by Base (linear) or constant (fixed).
+func (p RetryPolicy) Delay(failed int) time.Duration {
+	failed = max(failed, 1)
+	limit := p.MaxDelay
+	if limit <= 0 {
+		limit = math.MaxInt64 / 2
+	}
+	d := p.Base
+	switch p.Backoff {
+	case "exponential":
+		for i := 1; i < failed && d < limit; i++ {
+			d *= 2
+		}
+	case "linear":
+		if p.Base > 0 && time.Duration(failed) > limit/p.Base {
+			return limit
+		}
+		d = p.Base * time.Duration(failed)
+	}
+	return min(d, limit)
+}
+
+// Override returns p with an endpoint's stored overrides, each nil when not set.
+func (p RetryPolicy) Override(maxAttempts *int, backoff *string, baseSeconds *int) RetryPolicy {
+	if maxAttempts != nil {
+		p.MaxAttempts = *maxAttempts
+	}
+	if backoff != nil {
+		p.Backoff = *backoff
+	}
+	if baseSeconds != nil {
+		p.Base = time.Duration(*baseSeconds) * time.Second
+	}
+	return p
+}
+
+// InsertOpts gives webhook jobs the default policy's attempts rather than River's.
+// Failed deliveries snooze the job instead of failing it, so these only bound
+// failures of the job itself.
+func (WebhookArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: DefaultRetryPolicy.MaxAttempts}
+}
+
+// NextRetry schedules a failed job by the default policy rather than River's backoff.
+func (w *Worker) NextRetry(job *river.Job[WebhookArgs]) time.Time {
+	return time.Now().Add(DefaultRetryPolicy.Delay(job.Attempt))
+}
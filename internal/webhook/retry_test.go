@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		policy RetryPolicy
+		failed int
+		want   time.Duration
+	}{
+		{RetryPolicy{Backoff: "exponential", Base: time.Second, MaxDelay: time.Minute}, 1, time.Second},
+		{RetryPolicy{Backoff: "exponential", Base: time.Second, MaxDelay: time.Minute}, 4, 8 * time.Second},
+		{RetryPolicy{Backoff: "exponential", Base: time.Second, MaxDelay: time.Minute}, 200, time.Minute},
+		{RetryPolicy{Backoff: "exponential", Base: time.Second}, 200, 1<<62 - 1},
+		{RetryPolicy{Backoff: "linear", Base: 10 * time.Second, MaxDelay: time.Minute}, 3, 30 * time.Second},
+		{RetryPolicy{Backoff: "linear", Base: 10 * time.Second, MaxDelay: time.Minute}, 10, time.Minute},
+		{RetryPolicy{Backoff: "fixed", Base: 10 * time.Second}, 7, 10 * time.Second},
+		{RetryPolicy{Backoff: "fixed", Base: 10 * time.Second}, 0, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Delay(tt.failed); got != tt.want {
+			t.Errorf("%+v after %d failures: got %v, want %v", tt.policy, tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyOverride(t *testing.T) {
+	attempts, backoff, base := 3, "fixed", 60
+	p := DefaultRetryPolicy.Override(&attempts, &backoff, &base)
+	want := RetryPolicy{MaxAttempts: 3, Backoff: "fixed", Base: time.Minute, MaxDelay: DefaultRetryPolicy.MaxDelay}
+	if p != want {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+	if DefaultRetryPolicy.Override(nil, nil, nil) != DefaultRetryPolicy {
+		t.Fatal("expected no overrides to keep the default")
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	if err := DefaultRetryPolicy.Validate(); err != nil {
+		t.Fatalf("expected the default policy to be valid: %v", err)
+	}
+	for _, p := range []RetryPolicy{
+		{MaxAttempts: 0, Backoff: "fixed"},
+		{MaxAttempts: 3, Backoff: "random"},
+		{MaxAttempts: 3, Backoff: "fixed", Base: -time.Second},
+	} {
+		if p.Validate() == nil {
+			t.Errorf("expected %+v to be invalid", p)
+		}
+	}
+}
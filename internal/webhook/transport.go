@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// maxCustomHeaders caps the static headers an endpoint can add to its deliveries.
+const maxCustomHeaders = 20
+
+// reservedHeaders are set by the worker on every delivery, so endpoints can't override
+// them; neither can they set any X-Ledger- header.
+var reservedHeaders = []string{"Content-Type", "Content-Length", "Host", "User-Agent", "Transfer-Encoding", "Connection"}
+
+// ValidateHeaders checks an endpoint's custom headers: valid names that the worker
+// doesn't set itself, and values on a single line.
+func ValidateHeaders(headers map[string]string) error {
+	if len(headers) > maxCustomHeaders {
+		return fmt.Errorf("at most %d headers", maxCustomHeaders)
+	}
+	for name, value := range headers {
+		if name == "" || strings.ContainsFunc(name, func(r rune) bool { return r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) }) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		for _, reserved := range reservedHeaders {
+			if canonical == reserved {
+				return fmt.Errorf("header %s is set by the worker", canonical)
+			}
+		}
+		if strings.HasPrefix(canonical, "X-Ledger-") {
+			return fmt.Errorf("X-Ledger- headers are set by the worker")
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("header %s must be on a single line", canonical)
+		}
+	}
+	return nil
+}
+
+// Header returns the endpoint's custom headers, to which a delivery adds its own.
+func (ep WebhookEndpoint) Header() http.Header {
+	header := http.Header{}
+	for name, value := range ep.Headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+// ParseClientCertificate checks that certPEM and keyPEM are a certificate chain and its
+// private key, and returns the leaf certificate.
+func ParseClientCertificate(certPEM, keyPEM string) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return x509.ParseCertificate(pair.Certificate[0])
+}
+
+// tlsClients reuses one client, and so its connections, per base client and endpoint
+// certificate. A rotated certificate gets a new client; the cache starts over when full.
+var tlsClients = struct {
+	sync.Mutex
+	m map[tlsClientKey]*http.Client
+}{m: map[tlsClientKey]*http.Client{}}
+
+type tlsClientKey struct {
+	base *http.Client
+	pair [sha256.Size]byte
+}
+
+const maxTLSClients = 1000
+
+// ClientFor returns the client deliveries to ep are sent with: base, or the default
+// client when nil, presenting ep's client certificate if it has one.
+func ClientFor(base *http.Client, ep WebhookEndpoint) (*http.Client, error) {
+	if base == nil {
+		base = defaultClient
+	}
+	if ep.ClientCertificate == "" {
+		return base, nil
+	}
+
+	key := tlsClientKey{base: base, pair: sha256.Sum256([]byte(ep.ClientCertificate + "\x00" + ep.ClientKey))}
+	tlsClients.Lock()
+	defer tlsClients.Unlock()
+	if client, ok := tlsClients.m[key]; ok {
+		return client, nil
+	}
+
+	pair, err := tls.X509KeyPair([]byte(ep.ClientCertificate), []byte(ep.ClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	var transport *http.Transport
+	switch t := base.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, errors.New("client certificates need an *http.Transport")
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{pair}
+
+	if len(tlsClients.m) >= maxTLSClients {
+		clear(tlsClients.m)
+	}
+	client := &http.Client{Transport: transport, CheckRedirect: base.CheckRedirect, Jar: base.Jar, Timeout: base.Timeout}
+	tlsClients.m[key] = client
+	return client, nil
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateHeaders(t *testing.T) {
+	if err := ValidateHeaders(map[string]string{"Authorization": "Bearer abc", "x-gateway-key": "k"}); err != nil {
+		t.Fatalf("expected valid headers, got %v", err)
+	}
+	for _, headers := range []map[string]string{
+		{"content-type": "text/plain"},
+		{"X-Ledger-Event-Id": "forged"},
+		{"Bad Name": "v"},
+		{"X-Split": "a\r\nX-Injected: b"},
+	} {
+		if err := ValidateHeaders(headers); err == nil {
+			t.Errorf("expected %v to be rejected", headers)
+		}
+	}
+}
+
+func TestDeliverWithClientCertificate(t *testing.T) {
+	certPEM, keyPEM := selfSigned(t, "ledger-client")
+	leaf, err := ParseClientCertificate(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.Subject.CommonName != "ledger-client" {
+		t.Fatalf("unexpected subject %s", leaf.Subject)
+	}
+	if _, err := ParseClientCertificate(certPEM, "not a key"); err == nil {
+		t.Fatal("expected a certificate without its key to be rejected")
+	}
+
+	var gotCN, gotAuth string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	ep := WebhookEndpoint{ID: "ep", URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer gateway"}}
+	plain, err := ClientFor(srv.Client(), ep)
+	if err != nil || plain != srv.Client() {
+		t.Fatalf("expected the base client without a certificate, got %v", err)
+	}
+	if o := Deliver(context.Background(), plain, srv.URL, []string{"whsec"}, []byte(`{}`), ep.Header()); o.Status == "success" {
+		t.Fatal("expected the server to require a client certificate")
+	}
+
+	ep.ClientCertificate, ep.ClientKey = certPEM, keyPEM
+	client, err := ClientFor(srv.Client(), ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ClientFor(srv.Client(), ep); again != client {
+		t.Fatal("expected the client to be reused")
+	}
+	if o := Deliver(context.Background(), client, srv.URL, []string{"whsec"}, []byte(`{}`), ep.Header()); o.Status != "success" {
+		t.Fatalf("expected success, got %+v", o)
+	}
+	if gotCN != "ledger-client" || gotAuth != "Bearer gateway" {
+		t.Fatalf("expected the certificate and header, got %q and %q", gotCN, gotAuth)
+	}
+}
+
+// selfSigned returns a PEM certificate and key usable for TLS client authentication.
+func selfSigned(t *testing.T, cn string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
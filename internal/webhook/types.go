@@ -3,6 +3,15 @@ package webhook
 type WebhookArgs struct {
 	EventID  string `json:"event_id"`
 	LedgerID string `json:"ledger_id"`
+
+	// EndpointIDs restrict the job to these endpoints, for a manual retry of a delivery
+	// or the follow-up of a job that ran out of time; empty delivers to every active
+	// endpoint of the ledger
+	EndpointIDs []string `json:"endpoint_ids,omitempty"`
+
+	// Manual retries are sent on the job's first run even if the endpoint's retry
+	// policy gave up on the delivery or has it waiting
+	Manual bool `json:"manual,omitempty"`
 }
 
 func (WebhookArgs) Kind() string {
@@ -11,4 +20,25 @@ func (WebhookArgs) Kind() string {
 
 type WebhookEndpoint struct {
 	ID, URL, Secret string
+	PreviousSecret  string // set during a rotation's overlap window
+	Retry           RetryPolicy
+	Limit           EndpointLimit
+	Batch           BatchPolicy
+
+	// Filter is the CEL expression an event must satisfy to be delivered; see
+	// ValidateFilter
+	Filter string
+
+	// Headers are added to every delivery, e.g. an Authorization header for a gateway
+	Headers map[string]string
+	// ClientCertificate and ClientKey, PEM, are presented for mutual TLS when set
+	ClientCertificate, ClientKey string
+}
+
+// Secrets are the secrets deliveries to the endpoint are signed with, current first.
+func (ep WebhookEndpoint) Secrets() []string {
+	if ep.PreviousSecret == "" {
+		return []string{ep.Secret}
+	}
+	return []string{ep.Secret, ep.PreviousSecret}
 }
@@ -1,17 +1,25 @@
 package webhook
 
 import (
+	"Go_FormanceLegder/internal/events"
+	"Go_FormanceLegder/internal/faults"
 	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/riverqueue/river"
 )
@@ -20,39 +28,91 @@ type Worker struct {
 	river.WorkerDefaults[WebhookArgs]
 	DB         *pgxpool.Pool
 	HttpClient *http.Client
+	Limiter    *HostLimiter     // per-host concurrency cap; nil sends without limits
+	Endpoints  *EndpointLimiter // per-endpoint concurrency and rate; nil sends without limits
+
+	// DisableAfter failed deliveries in a row disable an endpoint; 0 never does
+	DisableAfter int
 }
 
+const (
+	// How long a job waits for a saturated host before snoozing
+	hostWait = 5 * time.Second
+	// How long a job waits for a saturated endpoint, or its turn under the endpoint's
+	// rate, before snoozing; kept short so a busy endpoint doesn't tie up workers
+	endpointWait = time.Second
+	// Snoozed jobs come back after this plus up to as much jitter
+	hostSnooze = 10 * time.Second
+	// Longest Retry-After honored; receivers asking for more are retried sooner
+	maxRetryAfter = time.Hour
+
+	// Each request gets at most attemptTimeout, within the job's own timeout
+	attemptTimeout = 10 * time.Second
+	jobTimeout     = time.Minute
+	// Left over after the last endpoint for recording results and queueing a follow-up
+	jobMargin = 5 * time.Second
+)
+
+// defaultClient has no timeout of its own: Deliver bounds each request by the caller's
+// context and attemptTimeout.
+var defaultClient = &http.Client{}
+
 func NewWorker(db *pgxpool.Pool) *Worker {
 	return &Worker{
-		DB: db,
-		HttpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		DB:         db,
+		HttpClient: defaultClient,
 	}
 }
 
+// Timeout is the job's whole budget, across all its endpoints.
+func (w *Worker) Timeout(*river.Job[WebhookArgs]) time.Duration {
+	return jobTimeout
+}
+
+// Work delivers the event to the ledger's endpoints one after the other. An endpoint is
+// only started while the job's budget still covers waiting for its host and a full
+// attempt; the endpoints left over go to a follow-up job, so a ledger with many slow
+// endpoints doesn't time the job out mid-delivery and start over.
 func (w *Worker) Work(ctx context.Context, job *river.Job[WebhookArgs]) error {
 	args := job.Args
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(jobTimeout)
+	}
 
 	// Load event payload
-	var payloadJSON []byte
+	event := deliveryEvent{ID: args.EventID, LedgerID: args.LedgerID}
 	err := w.DB.QueryRow(ctx, `
-        SELECT payload
+        SELECT event_type, payload, occurred_at
         FROM events
         WHERE id = $1 AND ledger_id = $2
-    `, args.EventID, args.LedgerID).Scan(&payloadJSON)
+    `, args.EventID, args.LedgerID).Scan(&event.Type, &event.Payload, &event.OccurredAt)
 
 	if err != nil {
 		return fmt.Errorf("event not found (id=%s, ledger=%s): %w", args.EventID, args.LedgerID, err)
 	}
 
-	// Load active webhook endpoints
+	// Subscribers only see the current schema version, even for events written before it
+	event.Payload, err = events.Current(event.Type, event.Payload)
+	if err != nil {
+		return river.JobCancel(fmt.Errorf("event %s: %w", args.EventID, err))
+	}
+
+	// Load active webhook endpoints, with the previous secret of a rotation still in
+	// its overlap window
 	rows, err := w.DB.Query(ctx, `
-		SELECT id, url, secret
+		SELECT id, url, secret,
+			CASE WHEN previous_secret_expires_at > NOW() THEN COALESCE(previous_secret, '') ELSE '' END,
+			retry_max_attempts, retry_backoff, retry_backoff_base_seconds,
+			rate_limit_max_concurrency, rate_limit_per_second,
+			custom_headers, COALESCE(client_certificate, ''), COALESCE(client_key, ''),
+			batch_window_seconds, batch_max_events, COALESCE(filter, '')
 		FROM webhook_endpoints
 		WHERE ledger_id = $1
 		  AND is_active = true
-	`, args.LedgerID)
+		  AND (COALESCE(cardinality($2::text[]), 0) = 0 OR id::text = ANY($2))
+		ORDER BY created_at, id
+	`, args.LedgerID, args.EndpointIDs)
 	if err != nil {
 		return fmt.Errorf("failed to load endpoints: %w", err)
 	}
@@ -60,119 +120,386 @@ func (w *Worker) Work(ctx context.Context, job *river.Job[WebhookArgs]) error {
 	var endpoints []WebhookEndpoint
 	for rows.Next() {
 		var ep WebhookEndpoint
-		if err := rows.Scan(&ep.ID, &ep.URL, &ep.Secret); err == nil {
+		var maxAttempts, baseSeconds, maxConcurrent, perSecond, batchWindow, batchMax *int
+		var backoff *string
+		err := rows.Scan(&ep.ID, &ep.URL, &ep.Secret, &ep.PreviousSecret, &maxAttempts, &backoff, &baseSeconds,
+			&maxConcurrent, &perSecond, &ep.Headers, &ep.ClientCertificate, &ep.ClientKey, &batchWindow, &batchMax, &ep.Filter)
+		if err == nil {
+			ep.Retry = DefaultRetryPolicy.Override(maxAttempts, backoff, baseSeconds)
+			ep.Limit = DefaultEndpointLimit.Override(maxConcurrent, perSecond)
+			ep.Batch = batchPolicy(batchWindow, batchMax)
 			endpoints = append(endpoints, ep)
 		}
 	}
 	defer rows.Close()
 
+	// Manual retries go out whatever the endpoint's filter says now
+	if !args.Manual {
+		endpoints = w.matchingEndpoints(ctx, event, endpoints)
+	}
 	if len(endpoints) == 0 {
 		return nil
 	}
 
-	// Deliver to each endpoint with idempotency checks.
-	var retryableFailures int
+	// Deliver to each endpoint that is due, by its own retry policy. The job comes back
+	// when the earliest failed delivery is due again; only errors of its own fail it.
+	var failures, deferred int
+	var nextAttempt time.Time
+	manual := args.Manual && job.Attempt <= 1
+
+	for i, ep := range endpoints {
+		if time.Until(deadline) < endpointWait+hostWait+attemptTimeout+jobMargin {
+			if err := w.enqueueFollowUp(ctx, args, endpoints[i:]); err != nil {
+				return err
+			}
+			break
+		}
 
-	for _, ep := range endpoints {
 		// Idempotency: if already delivered successfully for this (event, endpoint), skip.
 		var alreadySent bool
+		var attempts int
+		var dueAt *time.Time
 		err := w.DB.QueryRow(ctx, `
-			SELECT EXISTS (
-				SELECT 1
-				FROM webhook_deliveries
-				WHERE event_id = $1
-				  AND webhook_endpoint_id = $2
-				  AND status = 'success'
-			)
-		`, args.EventID, ep.ID).Scan(&alreadySent)
+			SELECT COALESCE(bool_or(status = 'success'), false), COALESCE(MAX(attempt), 0),
+				(array_agg(next_attempt_at ORDER BY attempt DESC))[1]
+			FROM webhook_deliveries
+			WHERE event_id = $1
+			  AND webhook_endpoint_id = $2
+		`, args.EventID, ep.ID).Scan(&alreadySent, &attempts, &dueAt)
 		if err != nil {
 			// Treat DB check errors as retryable: job should retry.
-			retryableFailures++
+			failures++
 			continue
 		}
-		if alreadySent {
+		if alreadySent || (!manual && attempts >= ep.Retry.MaxAttempts) {
+			continue
+		}
+		// Batching endpoints get the event with others by a BatchWorker; manual retries
+		// still go on their own
+		if ep.Batch.Window > 0 && !args.Manual {
+			if err := w.queueBatch(ctx, args.LedgerID, args.EventID, ep); err != nil {
+				log.Printf("failed to queue event %s for webhook endpoint %s: %v", args.EventID, ep.ID, err)
+				failures++
+			}
+			continue
+		}
+		if !manual && dueAt != nil && dueAt.After(time.Now()) {
+			nextAttempt = earliest(nextAttempt, *dueAt)
+			continue
+		}
+
+		// Saturated endpoints and hosts are tried again later rather than holding a
+		// worker slot; the snooze's jitter spreads a burst's deliveries over time
+		releaseEndpoint, ok := w.Endpoints.Acquire(ctx, ep.ID, ep.Limit, endpointWait)
+		if !ok {
+			deferred++
+			continue
+		}
+		release, ok := w.Limiter.Acquire(ctx, hostOf(ep.URL), hostWait)
+		if !ok {
+			releaseEndpoint()
+			deferred++
 			continue
 		}
 
 		// Send single webhook and record delivery result.
-		shouldRetry, sendErr := w.sendSingleWebhook(ctx, ep, args.EventID, payloadJSON, job.Attempt)
-		if sendErr != nil {
-			// sendErr is informational here; delivery was logged. We decide retry based on shouldRetry.
-			if shouldRetry {
-				retryableFailures++
-			}
+		retryAt := w.sendSingleWebhook(ctx, ep, event, attempts+1)
+		release()
+		releaseEndpoint()
+		if !retryAt.IsZero() {
+			nextAttempt = earliest(nextAttempt, retryAt)
+		}
+	}
+
+	// 4) Tell River when to run this job again. Snoozing doesn't use up the job's
+	// attempts, which the endpoints' retry policies count for themselves.
+	if failures > 0 {
+		return fmt.Errorf("webhook delivery failed to check %d endpoints", failures)
+	}
+	if deferred > 0 {
+		nextAttempt = earliest(nextAttempt, time.Now().Add(hostSnooze+rand.N(hostSnooze)))
+	}
+	if !nextAttempt.IsZero() {
+		return river.JobSnooze(max(time.Until(nextAttempt), time.Second))
+	}
+	return nil
+}
+
+// matchingEndpoints returns the endpoints whose filter the event satisfies.
+func (w *Worker) matchingEndpoints(ctx context.Context, event deliveryEvent, endpoints []WebhookEndpoint) []WebhookEndpoint {
+	var vars map[string]any
+	var varsErr error
+	matching := endpoints[:0]
+	for _, ep := range endpoints {
+		if ep.Filter == "" {
+			matching = append(matching, ep)
+			continue
 		}
+		if vars == nil && varsErr == nil {
+			vars, varsErr = FilterVars(event.Type, event.Payload)
+		}
+		if varsErr != nil {
+			log.Printf("webhook endpoint %s: filter skipped event %s: %v", ep.ID, event.ID, varsErr)
+			continue
+		}
+		if ok, _ := MatchFilter(ctx, ep.Filter, vars); ok {
+			matching = append(matching, ep)
+		}
+	}
+	return matching
+}
+
+// earliest returns the earlier of a and b, ignoring a zero a.
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
 	}
+	return a
+}
 
-	// 4) Tell River whether to retry this job.
-	if retryableFailures > 0 {
-		return fmt.Errorf("webhook delivery had %d retryable failures", retryableFailures)
+// enqueueFollowUp hands the endpoints the job has no time left for to a new job. If the
+// job itself is retried it goes over every endpoint again, skipping those delivered to
+// since; receivers drop the rare duplicate by delivery ID.
+func (w *Worker) enqueueFollowUp(ctx context.Context, args WebhookArgs, remaining []WebhookEndpoint) error {
+	client, err := river.ClientFromContextSafely[pgx.Tx](ctx)
+	if err != nil {
+		return fmt.Errorf("queue follow-up for %d endpoints: %w", len(remaining), err)
+	}
+	followUp := WebhookArgs{EventID: args.EventID, LedgerID: args.LedgerID}
+	for _, ep := range remaining {
+		followUp.EndpointIDs = append(followUp.EndpointIDs, ep.ID)
+	}
+	if _, err := client.Insert(ctx, followUp, nil); err != nil {
+		return fmt.Errorf("queue follow-up for %d endpoints: %w", len(remaining), err)
 	}
 	return nil
 }
 
-// sendSingleWebhook sends the webhook request once and logs the result.
-// Returns (shouldRetry, err). `shouldRetry=true` only for retryable cases (network errors, 5xx).
-func (w *Worker) sendSingleWebhook(ctx context.Context, ep WebhookEndpoint, eventID string,
-	payload []byte, attempt int) (bool, error) {
-	// Compute signature (HMAC SHA-256).
-	sig := computeWebhookSignature([]byte(ep.Secret), payload)
+// deliveryEvent is the event a job delivers, its payload at the current schema version.
+type deliveryEvent struct {
+	ID, Type, LedgerID string
+	OccurredAt         time.Time
+	Payload            []byte
+}
+
+// sendSingleWebhook makes the attempt-th attempt at delivering the event to ep and logs
+// the result. It returns when to try again, zero unless the failure is retryable
+// (network errors, 429, 5xx) and ep's retry policy has attempts left. A receiver's
+// Retry-After wins over the policy's backoff.
+func (w *Worker) sendSingleWebhook(ctx context.Context, ep WebhookEndpoint, event deliveryEvent, attempt int) time.Time {
+	deliveryID := DeliveryID(event.ID, ep.ID)
+	header := ep.Header()
+	header.Set("X-Ledger-Event-Id", event.ID)
+	header.Set("X-Ledger-Delivery-Id", deliveryID)
+	header.Set("X-Ledger-Event-Fingerprint", Fingerprint(event.Payload))
+
+	var outcome Outcome
+	body, err := Wrap(deliveryID, event.ID, event.Type, event.LedgerID, event.OccurredAt, event.Payload)
+	var client *http.Client
+	if err == nil {
+		client, err = ClientFor(w.HttpClient, ep)
+	}
+	if err != nil {
+		outcome = Outcome{Status: "non_retryable_error", Error: err.Error()}
+	} else {
+		outcome = Deliver(ctx, client, ep.URL, ep.Secrets(), body, header)
+	}
+
+	var retryAt time.Time
+	if outcome.Retryable() && attempt < ep.Retry.MaxAttempts {
+		wait := outcome.RetryAfter
+		if wait == 0 {
+			wait = ep.Retry.Delay(attempt)
+		}
+		retryAt = time.Now().Add(wait)
+	}
+
+	// Persist delivery attempt.
+	w.logDelivery(ctx, event.ID, ep.ID, outcome, retryAt)
+	w.recordHealth(ctx, event.LedgerID, ep, outcome)
+	return retryAt
+}
+
+// recordHealth counts the endpoint's failed deliveries in a row, resetting the count on
+// a success, and disables the endpoint once DisableAfter have failed. A 429 counts as
+// neither, since the receiver is up.
+func (w *Worker) recordHealth(ctx context.Context, ledgerID string, ep WebhookEndpoint, outcome Outcome) {
+	if outcome.HTTPStatus == http.StatusTooManyRequests {
+		return
+	}
+	if outcome.Status == "success" {
+		_, _ = w.DB.Exec(ctx, `UPDATE webhook_endpoints SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures <> 0`, ep.ID)
+		return
+	}
+
+	var failures int
+	err := w.DB.QueryRow(ctx, `
+		UPDATE webhook_endpoints SET consecutive_failures = consecutive_failures + 1 WHERE id = $1
+		RETURNING consecutive_failures
+	`, ep.ID).Scan(&failures)
+	if err != nil || w.DisableAfter <= 0 || failures < w.DisableAfter {
+		return
+	}
+	if err := w.disableEndpoint(ctx, ledgerID, ep, failures, outcome.Error); err != nil {
+		log.Printf("failed to disable webhook endpoint %s: %v", ep.ID, err)
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(payload))
+// disableEndpoint deactivates a failing endpoint and records a WebhookEndpointDisabled
+// event, delivered to the ledger's other endpoints and the outbox like any other. The
+// endpoint is re-enabled by setting is_active again.
+func (w *Worker) disableEndpoint(ctx context.Context, ledgerID string, ep WebhookEndpoint, failures int, lastError string) error {
+	tx, err := w.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	reason := fmt.Sprintf("%d failed deliveries in a row, the last: %s", failures, lastError)
+	tag, err := tx.Exec(ctx, `
+		UPDATE webhook_endpoints SET is_active = false, disabled_at = NOW(), disabled_reason = $2
+		WHERE id = $1 AND is_active
+	`, ep.ID, reason)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+
+	payload, err := events.Marshal("WebhookEndpointDisabled", &events.WebhookEndpointDisabled{
+		EndpointID: ep.ID, URL: ep.URL, ConsecutiveFailures: failures, LastError: lastError,
+	})
+	if err != nil {
+		return err
+	}
+	eventID := uuid.NewString()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO events (id, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at)
+		VALUES ($1, $2, 'webhook_endpoint', $3, 'WebhookEndpointDisabled', $4, NOW())
+	`, eventID, ledgerID, ep.ID, payload)
+	if err != nil {
+		return err
+	}
+	if client, err := river.ClientFromContextSafely[pgx.Tx](ctx); err == nil {
+		if _, err := client.InsertTx(ctx, tx, WebhookArgs{EventID: eventID, LedgerID: ledgerID}, nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("disabled webhook endpoint %s (ledger %s): %s", ep.ID, ledgerID, reason)
+	return nil
+}
+
+// Outcome of one webhook request. Status is success, retryable_error or
+// non_retryable_error; HTTPStatus is 0 when no response was received. RetryAfter is
+// the receiver's Retry-After on a 429 or 503.
+type Outcome struct {
+	Status     string
+	HTTPStatus int
+	Error      string
+	RetryAfter time.Duration
+}
+
+func (o Outcome) Retryable() bool {
+	return o.Status == "retryable_error"
+}
+
+// Deliver posts one signed delivery body to url with any extra headers, giving up after
+// attemptTimeout or when ctx ends. It records nothing, so the worker and staging
+// replays share the same request and retry policy.
+// The body is signed under each of secrets, the current one first: once in
+// X-Ledger-Webhook-Signature and, while LegacySignature is set, once per secret in
+// X-Ledger-Signature.
+func Deliver(ctx context.Context, client *http.Client, url string, secrets []string, payload []byte, header http.Header) Outcome {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 	if err != nil {
 		// Bad URL or request build error -> non-retryable.
-		w.logDelivery(ctx, eventID, ep.ID, "non_retryable_error", attempt, 0, err.Error())
-		return false, err
+		return Outcome{Status: "non_retryable_error", Error: err.Error()}
 	}
 
+	for k, v := range header {
+		req.Header[k] = v
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Ledger-Signature", sig)
+	req.Header.Set("X-Ledger-Webhook-Signature", SignTimestamped(secrets, payload, time.Now()))
+	if LegacySignature {
+		for _, secret := range secrets {
+			req.Header.Add("X-Ledger-Signature", Sign(secret, payload))
+		}
+	}
 	req.Header.Set("User-Agent", "LedgerKiro-Webhook/1.0")
 
-	resp, err := w.HttpClient.Do(req)
-
-	status := "success"
-	httpStatus := 0
-	errorMessage := ""
-	shouldRetry := false
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		// Fault injection: simulate a slow endpoint (no-op unless built with -tags faults)
+		if delayErr := faults.WebhookDelay(ctx); delayErr != nil {
+			resp.Body.Close()
+			resp, err = nil, delayErr
+		}
+	}
 
 	if err != nil {
 		// Network/timeout/DNS errors -> retryable.
-		status = "retryable_error"
-		errorMessage = err.Error()
-		shouldRetry = true
-	} else {
-		httpStatus = resp.StatusCode
+		return Outcome{Status: "retryable_error", Error: err.Error()}
+	}
 
-		// Always fully read+close response body to allow connection reuse.
-		_, _ = io.Copy(io.Discard, resp.Body)
-		_ = resp.Body.Close()
+	// Always fully read+close response body to allow connection reuse.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
 
-		// Decide retry policy based on HTTP status.
-		if resp.StatusCode >= 500 {
-			status = "retryable_error"
-			errorMessage = fmt.Sprintf("server error: %d", resp.StatusCode)
-			shouldRetry = true
-		} else if resp.StatusCode >= 400 {
-			// 4xx typically indicates a bad endpoint config/auth; do not retry forever.
-			status = "non_retryable_error"
-			errorMessage = fmt.Sprintf("client error: %d", resp.StatusCode)
-			shouldRetry = false
-		}
+	// Decide retry policy based on HTTP status.
+	outcome := Outcome{Status: "success", HTTPStatus: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		outcome.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// Rate limited: retry, after the delay the receiver asked for if any
+		outcome.Status = "retryable_error"
+		outcome.Error = fmt.Sprintf("rate limited: %d", resp.StatusCode)
+	} else if resp.StatusCode >= 500 {
+		outcome.Status = "retryable_error"
+		outcome.Error = fmt.Sprintf("server error: %d", resp.StatusCode)
+	} else if resp.StatusCode >= 400 {
+		// 4xx typically indicates a bad endpoint config/auth; do not retry forever.
+		outcome.Status = "non_retryable_error"
+		outcome.Error = fmt.Sprintf("client error: %d", resp.StatusCode)
+	}
+	return outcome
+}
 
-	// Persist delivery attempt.
-	w.logDelivery(ctx, eventID, ep.ID, status, attempt, httpStatus, errorMessage)
-
-	if shouldRetry {
-		return true, fmt.Errorf("retryable failure for %s: %s", ep.URL, errorMessage)
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date,
+// capped at maxRetryAfter. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		d = at.Sub(now)
 	}
-	return false, nil
+	if d <= 0 {
+		return 0
+	}
+	return min(d, maxRetryAfter)
 }
 
-// logDelivery writes one delivery attempt row.
+// logDelivery writes one delivery attempt row, numbered after the endpoint's earlier
+// attempts at the event rather than by the job's attempt, which counts retries caused
+// by any of the ledger's endpoints. retryAt is zero when the attempt won't be retried.
 // Note: errors are intentionally ignored here to avoid masking webhook send results.
-func (w *Worker) logDelivery(ctx context.Context, eventID, endpointID, status string, attempt, httpStatus int, errorMessage string) {
+func (w *Worker) logDelivery(ctx context.Context, eventID, endpointID string, outcome Outcome, retryAt time.Time) {
+	var nextAttemptAt *time.Time
+	if !retryAt.IsZero() {
+		nextAttemptAt = &retryAt
+	}
 	_, _ = w.DB.Exec(ctx, `
 		INSERT INTO webhook_deliveries (
 			id,
@@ -182,9 +509,114 @@ func (w *Worker) logDelivery(ctx context.Context, eventID, endpointID, status st
 			attempt,
 			last_attempt_at,
 			http_status,
-			error_message
-		) VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7)
-	`, uuid.NewString(), eventID, endpointID, status, attempt, httpStatus, errorMessage)
+			error_message,
+			next_attempt_at
+		)
+		SELECT $1::uuid, $2::uuid, $3::uuid, $4::text, COALESCE(MAX(attempt), 0) + 1, NOW(), $5::int, $6::text, $7::timestamptz
+		FROM webhook_deliveries
+		WHERE event_id = $2 AND webhook_endpoint_id = $3
+	`, uuid.NewString(), eventID, endpointID, outcome.Status, outcome.HTTPStatus, outcome.Error, nextAttemptAt)
+}
+
+// deliveryNamespace scopes delivery IDs derived from (event, endpoint).
+var deliveryNamespace = uuid.MustParse("6f1c2b1e-3d4a-5e6f-8a9b-0c1d2e3f4a5b")
+
+// DeliveryID identifies the delivery of an event to an endpoint. It is the same on every
+// attempt, so receivers can drop at-least-once duplicates by it.
+func DeliveryID(eventID, endpointID string) string {
+	return uuid.NewSHA1(deliveryNamespace, []byte(eventID+":"+endpointID)).String()
+}
+
+// Fingerprint is the SHA-256 of an event payload, the envelope's data, identical across
+// endpoints and attempts.
+func Fingerprint(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignatureTolerance is how far a X-Ledger-Webhook-Signature timestamp may be from the
+// receiver's clock before VerifyTimestamped rejects the delivery as a replay.
+const SignatureTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned by VerifyTimestamped for a missing, malformed, stale
+// or wrong signature.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// LegacySignature keeps the deprecated X-Ledger-Signature header, the HMAC of the body
+// alone, on deliveries next to X-Ledger-Webhook-Signature, until receivers have moved.
+var LegacySignature = true
+
+// SignTimestamped returns the X-Ledger-Webhook-Signature of payload sent at t:
+// "t=<unix>,v1=<hex hmac>", with one v1 per secret, where each HMAC covers "<t>.<body>".
+// Binding the timestamp into the signature lets receivers reject replayed deliveries.
+func SignTimestamped(secrets []string, payload []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	header := "t=" + timestamp
+	for _, secret := range secrets {
+		header += ",v1=" + computeTimestampedSignature([]byte(secret), timestamp, payload)
+	}
+	return header
+}
+
+// VerifyTimestamped checks an X-Ledger-Webhook-Signature header: its timestamp must be
+// within SignatureTolerance of now and one of its v1 signatures must be payload signed
+// with the endpoint secret at that timestamp. Unknown schemes are ignored.
+func VerifyTimestamped(secret, payload []byte, header string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := computeTimestampedSignature(secret, timestamp, payload)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(expected), []byte(signature)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Sign returns the X-Ledger-Signature of payload under secret.
+//
+// Deprecated: receivers should verify X-Ledger-Webhook-Signature with VerifyTimestamped.
+func Sign(secret string, payload []byte) string {
+	return computeWebhookSignature([]byte(secret), payload)
+}
+
+// VerifySignature reports whether signature is the X-Ledger-Signature of payload
+// signed with the endpoint secret.
+//
+// Deprecated: the legacy signature does not cover a timestamp, so it cannot tell a
+// replayed delivery apart; use VerifyTimestamped.
+func VerifySignature(secret, payload []byte, signature string) bool {
+	expected := computeWebhookSignature(secret, payload)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// VerifySignatures reports whether any of signatures, the X-Ledger-Signature values of
+// a delivery, is payload signed with the endpoint secret. During a secret rotation a
+// delivery carries one signature per valid secret, so receivers should check them all.
+func VerifySignatures(secret, payload []byte, signatures []string) bool {
+	for _, signature := range signatures {
+		if VerifySignature(secret, payload, signature) {
+			return true
+		}
+	}
+	return false
 }
 
 func computeWebhookSignature(secret []byte, payload []byte) string {
@@ -193,3 +625,10 @@ func computeWebhookSignature(secret []byte, payload []byte) string {
 	sum := mac.Sum(nil)
 	return hex.EncodeToString(sum)
 }
+
+func computeTimestampedSignature(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+	payload := []byte(`{"transaction_id":"t1"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifySignature([]byte("whsec"), body, r.Header.Get("X-Ledger-Signature")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Ledger-Replay") == "true" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	if o := Deliver(context.Background(), nil, srv.URL, []string{"whsec"}, payload, nil); o.Status != "success" || o.HTTPStatus != 200 {
+		t.Fatalf("expected success, got %+v", o)
+	}
+	if o := Deliver(context.Background(), nil, srv.URL, []string{"other"}, payload, nil); o.Status != "non_retryable_error" || o.Retryable() {
+		t.Fatalf("expected a non-retryable 401, got %+v", o)
+	}
+	header := http.Header{}
+	header.Set("X-Ledger-Replay", "true")
+	if o := Deliver(context.Background(), nil, srv.URL, []string{"whsec"}, payload, header); !o.Retryable() || o.HTTPStatus != 503 {
+		t.Fatalf("expected a retryable 503, got %+v", o)
+	}
+	if o := Deliver(context.Background(), nil, "://bad", []string{"whsec"}, payload, nil); o.Status != "non_retryable_error" {
+		t.Fatalf("expected a bad URL to be non-retryable, got %+v", o)
+	}
+}
+
+func TestDeliverRateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	o := Deliver(context.Background(), nil, srv.URL, []string{"whsec"}, []byte(`{}`), nil)
+	if !o.Retryable() || o.HTTPStatus != 429 || o.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected a retryable 429 with a 2m delay, got %+v", o)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-5":                            0,
+		"soon":                          0,
+		"Sun, 01 Mar 2026 12:05:00 GMT": 5 * time.Minute,
+		"Sun, 01 Mar 2026 11:00:00 GMT": 0,
+		"86400":                         maxRetryAfter,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestDeliveryIDAndFingerprint(t *testing.T) {
+	id := DeliveryID("e1", "ep1")
+	if id != DeliveryID("e1", "ep1") {
+		t.Fatal("expected a stable delivery id")
+	}
+	if id == DeliveryID("e1", "ep2") || id == DeliveryID("e2", "ep1") {
+		t.Fatal("expected delivery ids to differ per event and endpoint")
+	}
+	if Fingerprint([]byte(`{"a":1}`)) != Fingerprint([]byte(`{"a":1}`)) || Fingerprint([]byte(`{"a":1}`)) == Fingerprint([]byte(`{"a":2}`)) {
+		t.Fatal("expected fingerprints to follow the payload")
+	}
+
+	// Receivers that keep only the body can deduplicate by it too
+	payload := []byte(`{"transaction_id":"t1"}`)
+	body, err := Wrap(id, "e1", "TransactionPosted", "l1", time.Now(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.DeliveryID != id || envelope.EventFingerprint != Fingerprint(payload) {
+		t.Fatalf("expected the delivery id and fingerprint in the envelope, got %s", body)
+	}
+}
+
+func TestDeliverDuringSecretRotation(t *testing.T) {
+	payload := []byte(`{"transaction_id":"t1"}`)
+	var signatures []string
+	var timestamped string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures = r.Header.Values("X-Ledger-Signature")
+		timestamped = r.Header.Get("X-Ledger-Webhook-Signature")
+	}))
+	defer srv.Close()
+
+	if o := Deliver(context.Background(), nil, srv.URL, []string{"new", "old"}, payload, nil); o.Status != "success" {
+		t.Fatalf("expected success, got %+v", o)
+	}
+	if len(signatures) != 2 || !VerifySignature([]byte("new"), payload, signatures[0]) {
+		t.Fatalf("expected the new secret's signature first, got %v", signatures)
+	}
+	for _, secret := range []string{"new", "old"} {
+		if !VerifySignatures([]byte(secret), payload, signatures) {
+			t.Errorf("expected a signature under %q", secret)
+		}
+	}
+	if VerifySignatures([]byte("other"), payload, signatures) {
+		t.Error("expected no signature under an unrelated secret")
+	}
+	for _, secret := range []string{"new", "old"} {
+		if err := VerifyTimestamped([]byte(secret), payload, timestamped, time.Now()); err != nil {
+			t.Errorf("expected a timestamped signature under %q: %v", secret, err)
+		}
+	}
+}
+
+func TestVerifyTimestamped(t *testing.T) {
+	payload := []byte(`{"transaction_id":"t1"}`)
+	sentAt := time.Unix(1700000000, 0)
+	header := SignTimestamped([]string{"whsec"}, payload, sentAt)
+	if !strings.HasPrefix(header, "t=1700000000,v1=") {
+		t.Fatalf("unexpected header %q", header)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		payload []byte
+		header  string
+		now     time.Time
+		valid   bool
+	}{
+		{"valid", "whsec", payload, header, sentAt.Add(time.Minute), true},
+		{"unknown scheme ignored", "whsec", payload, header + ",v0=abc", sentAt, true},
+		{"wrong secret", "other", payload, header, sentAt, false},
+		{"tampered body", "whsec", []byte(`{"transaction_id":"t2"}`), header, sentAt, false},
+		{"replayed too late", "whsec", payload, header, sentAt.Add(SignatureTolerance + time.Second), false},
+		{"from the future", "whsec", payload, header, sentAt.Add(-SignatureTolerance - time.Second), false},
+		{"timestamp swapped", "whsec", payload, strings.Replace(header, "t=1700000000", "t=1700000060", 1), sentAt, false},
+		{"missing", "whsec", payload, "", sentAt, false},
+		{"legacy signature only", "whsec", payload, "v1=" + computeWebhookSignature([]byte("whsec"), payload), sentAt, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyTimestamped([]byte(tt.secret), tt.payload, tt.header, tt.now)
+			if (err == nil) != tt.valid {
+				t.Fatalf("expected valid=%v, got %v", tt.valid, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeliverWithoutLegacySignature(t *testing.T) {
+	LegacySignature = false
+	defer func() { LegacySignature = true }()
+
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	if o := Deliver(context.Background(), nil, srv.URL, []string{"whsec"}, []byte(`{}`), nil); o.Status != "success" {
+		t.Fatalf("expected success, got %+v", o)
+	}
+	if header.Get("X-Ledger-Signature") != "" || header.Get("X-Ledger-Webhook-Signature") == "" {
+		t.Fatalf("expected only the timestamped signature, got %v", header)
+	}
+}
+
+func TestDeliverHonorsContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	o := Deliver(ctx, nil, srv.URL, []string{"whsec"}, []byte(`{}`), nil)
+	if !o.Retryable() || o.HTTPStatus != 0 {
+		t.Fatalf("expected a retryable timeout, got %+v", o)
+	}
+	if elapsed := time.Since(start); elapsed > attemptTimeout/2 {
+		t.Fatalf("expected the job's deadline to cut the attempt short, took %v", elapsed)
+	}
+}
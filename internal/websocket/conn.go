@@ -0,0 +1,234 @@
+// Package websocket is a minimal server-side RFC 6455 implementation: the opening
+// handshake, unfragmented and fragmented text/binary messages from clients, ping/pong
+// and close. Extensions and subprotocols are not supported.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OpText   = 0x1
+	OpBinary = 0x2
+	opClose  = 0x8
+	opPing   = 0x9
+	opPong   = 0xA
+)
+
+// Close codes
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	ClosePolicyViolation = 1008
+	CloseTooBig          = 1009
+)
+
+// Client messages larger than this are rejected.
+const MaxMessageSize = 64 << 10
+
+var (
+	ErrNotWebSocket = errors.New("not a websocket handshake")
+	ErrProtocol     = errors.New("websocket protocol error")
+	ErrTooBig       = errors.New("websocket message too big")
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	wmu  sync.Mutex
+
+	// ReadTimeout, when set, closes connections that send nothing (not even a pong)
+	// for that long
+	ReadTimeout time.Duration
+}
+
+// Upgrade completes the opening handshake and takes over the connection. On error a
+// response has already been written.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		r.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, ErrNotWebSocket
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, err
+	}
+	// Clear any deadlines the HTTP server set
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + acceptGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: rw.Reader}, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and pongs
+// skipped; a close frame is acknowledged and returned as io.EOF.
+func (c *Conn) ReadMessage() (opcode int, data []byte, err error) {
+	for {
+		if c.ReadTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+		}
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return 0, nil, io.EOF
+		case OpText, OpBinary:
+			if opcode != 0 {
+				return 0, nil, ErrProtocol // new message inside a fragmented one
+			}
+			opcode = op
+		case 0:
+			if opcode == 0 {
+				return 0, nil, ErrProtocol // continuation without a message
+			}
+		default:
+			return 0, nil, ErrProtocol
+		}
+
+		if len(data)+len(payload) > MaxMessageSize {
+			c.Close(CloseTooBig, "message too big")
+			return 0, nil, ErrTooBig
+		}
+		data = append(data, payload...)
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, op int, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = int(head[0] & 0x0F)
+	if head[0]&0x70 != 0 || head[1]&0x80 == 0 {
+		return false, 0, nil, ErrProtocol // reserved bits set, or unmasked client frame
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > MaxMessageSize {
+		c.Close(CloseTooBig, "message too big")
+		return false, 0, nil, ErrTooBig
+	}
+	if op >= opClose && (!fin || length > 125) {
+		return false, 0, nil, ErrProtocol
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteText sends a text message. Safe for concurrent use.
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OpText, data)
+}
+
+// Ping sends a ping; clients answer with a pong, which resets the read timeout.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame and closes the connection.
+func (c *Conn) Close(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	c.writeFrame(opClose, payload)
+	return c.conn.Close()
+}
+
+// Server frames are never masked or fragmented.
+func (c *Conn) writeFrame(op int, payload []byte) error {
+	frame := []byte{0x80 | byte(op)}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(frame)
+	return err
+}
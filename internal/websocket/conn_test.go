@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// clientFrame builds a masked client frame.
+func clientFrame(fin bool, op int, payload []byte) []byte {
+	first := byte(op)
+	if fin {
+		first |= 0x80
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func readServerFrame(t *testing.T, r *bufio.Reader) (int, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return int(head[0] & 0x0F), payload
+}
+
+func TestEchoAndControlFrames(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		for {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				c.conn.Close()
+				return
+			}
+			c.WriteText(data)
+		}
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Accept value from RFC 6455 section 1.3
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// A fragmented message with a ping in between
+	conn.Write(clientFrame(false, OpText, []byte("hel")))
+	conn.Write(clientFrame(true, opPing, []byte("p")))
+	conn.Write(clientFrame(true, 0, []byte("lo")))
+
+	if op, payload := readServerFrame(t, r); op != opPong || string(payload) != "p" {
+		t.Fatalf("expected pong, got %d %q", op, payload)
+	}
+	if op, payload := readServerFrame(t, r); op != OpText || string(payload) != "hello" {
+		t.Fatalf("expected echo, got %d %q", op, payload)
+	}
+
+	conn.Write(clientFrame(true, opClose, binary.BigEndian.AppendUint16(nil, CloseNormal)))
+	if op, _ := readServerFrame(t, r); op != opClose {
+		t.Fatalf("expected close acknowledgement, got %d", op)
+	}
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if _, err := Upgrade(rec, httptest.NewRequest(http.MethodGet, "/", nil)); err != ErrNotWebSocket || rec.Code != http.StatusUpgradeRequired {
+		t.Fatalf("expected an upgrade required error, got %v %d", err, rec.Code)
+	}
+}
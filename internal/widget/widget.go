@@ -0,0 +1,76 @@
+// Package widget signs the read-only URLs customers embed in their own portals to show
+// an account's current balance or statement, without an API key or a backend proxy.
+// A token is valid until it expires; it cannot be revoked earlier except by rotating
+// the signing secret, so expiries should be kept short.
+package widget
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	KindBalance   = "balance"
+	KindStatement = "statement"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid widget token")
+	ErrExpired      = errors.New("widget token expired")
+)
+
+// Claims are what a token grants: one read of one account, until ExpiresAt.
+type Claims struct {
+	LedgerID  string `json:"l"`
+	Kind      string `json:"k"`
+	Account   string `json:"a"`
+	From      int64  `json:"f,omitempty"` // statement period as Unix times; To is exclusive
+	To        int64  `json:"t,omitempty"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// Sign returns the token for c: the base64url-encoded claims and their HMAC-SHA256.
+func Sign(secret []byte, c Claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac(secret, encoded)), nil
+}
+
+// Verify checks a token's signature and expiry and returns its claims.
+func Verify(secret []byte, token string, now time.Time) (Claims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac(secret, encoded)) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if now.Unix() >= c.ExpiresAt {
+		return Claims{}, ErrExpired
+	}
+	return c, nil
+}
+
+func mac(secret []byte, encoded string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
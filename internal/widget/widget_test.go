@@ -0,0 +1,39 @@
+package widget
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1767225600, 0)
+	claims := Claims{LedgerID: "l1", Kind: KindBalance, Account: "customer:42", ExpiresAt: now.Add(time.Hour).Unix()}
+
+	token, err := Sign([]byte("secret"), claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Verify([]byte("secret"), token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != claims {
+		t.Fatalf("unexpected claims %+v", got)
+	}
+
+	if _, err := Verify([]byte("other"), token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a token signed with another secret to be rejected, got %v", err)
+	}
+	if _, err := Verify([]byte("secret"), token, now.Add(2*time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+
+	// Claims cannot be swapped under a valid signature
+	other, _ := Sign([]byte("secret"), Claims{LedgerID: "l1", Kind: KindBalance, Account: "treasury", ExpiresAt: claims.ExpiresAt})
+	forged := strings.Split(other, ".")[0] + "." + strings.Split(token, ".")[1]
+	if _, err := Verify([]byte("secret"), forged, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected forged claims to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Package workflow runs multi-step ledger flows: postings, holds and their capture,
+// waits on external signals and delays. Definitions are JSON documents saved per
+// ledger; each started instance snapshots its definition and is advanced by River
+// jobs, so waits survive restarts. When a step fails or a wait times out, the steps
+// already executed are compensated in reverse order.
+package workflow
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Step types
+const (
+	StepTransaction = "transaction"  // post postings or a script
+	StepHold        = "hold"         // reserve an amount (see ledger.Service.CreateHold)
+	StepCaptureHold = "capture_hold" // capture the hold created by an earlier step
+	StepVoidHold    = "void_hold"    // release the hold created by an earlier step
+	StepWaitSignal  = "wait_signal"  // wait for POST /v1/workflows/signal, optionally with a timeout
+	StepDelay       = "delay"        // wait for a fixed duration
+)
+
+type Definition struct {
+	Steps []Step `json:"steps"`
+}
+
+// Step is one step of a definition. String fields other than name, type, hold,
+// signal, timeout and duration may reference ${input.<key>} and
+// ${steps.<name>.<key>}; they are resolved when the step runs.
+type Step struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// transaction
+	Currency string                `json:"currency,omitempty"`
+	Postings []ledger.PostingInput `json:"postings,omitempty"`
+	Script   string                `json:"script,omitempty"`
+	Vars     map[string]string     `json:"vars,omitempty"`
+	Metadata map[string]any        `json:"metadata,omitempty"`
+
+	// hold (amount is also the optional partial amount of capture_hold)
+	Account     string `json:"account,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+
+	// capture_hold, void_hold: name of the hold step
+	Hold string `json:"hold,omitempty"`
+
+	// wait_signal
+	Signal  string `json:"signal,omitempty"`
+	Timeout string `json:"timeout,omitempty"` // Go duration, e.g. "24h"; empty waits forever
+
+	// delay
+	Duration string `json:"duration,omitempty"`
+}
+
+// Validate checks step names, types and the fields each type requires.
+func (d Definition) Validate() error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("workflow must have at least 1 step")
+	}
+
+	types := map[string]string{}
+	for i, step := range d.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("step %d: name required", i+1)
+		}
+		if _, dup := types[step.Name]; dup {
+			return fmt.Errorf("step %s: duplicate name", step.Name)
+		}
+
+		var err error
+		switch step.Type {
+		case StepTransaction:
+			switch {
+			case step.Script == "" && len(step.Postings) < 2:
+				err = fmt.Errorf("script or at least 2 postings required")
+			case step.Script != "" && len(step.Postings) > 0:
+				err = fmt.Errorf("postings and script are mutually exclusive")
+			case step.Script == "" && step.Currency == "":
+				err = fmt.Errorf("currency required")
+			}
+		case StepHold:
+			if step.Account == "" || step.Destination == "" || step.Amount == "" || step.Currency == "" {
+				err = fmt.Errorf("account, destination, amount and currency required")
+			}
+		case StepCaptureHold, StepVoidHold:
+			if types[step.Hold] != StepHold {
+				err = fmt.Errorf("hold must name an earlier hold step")
+			}
+		case StepWaitSignal:
+			if step.Signal == "" {
+				err = fmt.Errorf("signal required")
+			} else if step.Timeout != "" {
+				err = positiveDuration("timeout", step.Timeout)
+			}
+		case StepDelay:
+			err = positiveDuration("duration", step.Duration)
+		default:
+			err = fmt.Errorf("unknown type %q", step.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		types[step.Name] = step.Type
+	}
+	return nil
+}
+
+func positiveDuration(field, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s must be a positive duration like 30m or 24h", field)
+	}
+	return nil
+}
+
+var reference = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// render resolves the references in s against scope ({"input": ..., "steps": ...}).
+func render(s string, scope map[string]any) (string, error) {
+	var renderErr error
+	out := reference.ReplaceAllStringFunc(s, func(match string) string {
+		path := reference.FindStringSubmatch(match)[1]
+		var value any = scope
+		for _, key := range strings.Split(path, ".") {
+			m, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+			value = m[key]
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case json.Number, bool:
+			return fmt.Sprint(v)
+		default:
+			if renderErr == nil {
+				renderErr = fmt.Errorf("unresolved reference ${%s}", path)
+			}
+			return match
+		}
+	})
+	return out, renderErr
+}
+
+// resolve returns a copy of the step with every reference resolved.
+func (step Step) resolve(scope map[string]any) (Step, error) {
+	var err error
+	field := func(s string) string {
+		if err != nil {
+			return s
+		}
+		s, err = render(s, scope)
+		return s
+	}
+
+	out := step
+	out.Currency = field(step.Currency)
+	out.Account = field(step.Account)
+	out.Destination = field(step.Destination)
+	out.Amount = field(step.Amount)
+	out.Script = field(step.Script)
+	out.Postings = make([]ledger.PostingInput, len(step.Postings))
+	for i, p := range step.Postings {
+		p.AccountCode = field(p.AccountCode)
+		p.Amount = field(p.Amount)
+		p.Currency = field(p.Currency)
+		out.Postings[i] = p
+	}
+	if step.Vars != nil {
+		out.Vars = map[string]string{}
+		for k, v := range step.Vars {
+			out.Vars[k] = field(v)
+		}
+	}
+	if step.Metadata != nil {
+		out.Metadata = map[string]any{}
+		for k, v := range step.Metadata {
+			if s, ok := v.(string); ok {
+				v = field(s)
+			}
+			out.Metadata[k] = v
+		}
+	}
+	return out, err
+}
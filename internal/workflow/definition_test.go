@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	hold := Step{Name: "reserve", Type: StepHold, Account: "wallet", Destination: "payouts", Amount: "10", Currency: "USD"}
+
+	tests := []struct {
+		name  string
+		steps []Step
+		err   string
+	}{
+		{"empty", nil, "at least 1 step"},
+		{"unnamed", []Step{{Type: StepDelay, Duration: "1h"}}, "name required"},
+		{"duplicate", []Step{hold, hold}, "duplicate name"},
+		{"unknown type", []Step{{Name: "a", Type: "email"}}, "unknown type"},
+		{"capture before hold", []Step{{Name: "capture", Type: StepCaptureHold, Hold: "reserve"}, hold}, "earlier hold step"},
+		{"bad timeout", []Step{{Name: "bank", Type: StepWaitSignal, Signal: "ok", Timeout: "tomorrow"}}, "positive duration"},
+		{"script and postings", []Step{{Name: "t", Type: StepTransaction, Script: "send", Postings: []ledger.PostingInput{{}}}}, "mutually exclusive"},
+		{"valid", []Step{
+			hold,
+			{Name: "bank", Type: StepWaitSignal, Signal: "settled", Timeout: "24h"},
+			{Name: "capture", Type: StepCaptureHold, Hold: "reserve"},
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		err := Definition{Steps: tt.steps}.Validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var input map[string]any
+	if err := decodeObject([]byte(`{"wallet": "customer:42", "amount": 12.50}`), &input); err != nil {
+		t.Fatal(err)
+	}
+	scope := map[string]any{
+		"input": input,
+		"steps": map[string]any{"reserve": map[string]any{"hold_id": "h-1"}},
+	}
+
+	step, err := Step{
+		Name:     "fee",
+		Type:     StepTransaction,
+		Currency: "USD",
+		Postings: []ledger.PostingInput{
+			{AccountCode: "${input.wallet}", Direction: "debit", Amount: "${input.amount}"},
+			{AccountCode: "fees", Direction: "credit", Amount: "${input.amount}"},
+		},
+		Metadata: map[string]any{"hold": "${steps.reserve.hold_id}", "count": json.Number("1")},
+	}.resolve(scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Postings[0].AccountCode != "customer:42" || step.Postings[0].Amount != "12.50" {
+		t.Fatalf("unexpected postings: %+v", step.Postings)
+	}
+	if step.Metadata["hold"] != "h-1" {
+		t.Fatalf("unexpected metadata: %v", step.Metadata)
+	}
+
+	_, err = Step{Name: "x", Type: StepHold, Account: "${input.missing}"}.resolve(scope)
+	if err == nil || !strings.Contains(err.Error(), "${input.missing}") {
+		t.Fatalf("expected unresolved reference error, got %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package workflow
+
+import (
+	"Go_FormanceLegder/internal/api"
+	"Go_FormanceLegder/internal/auth"
+	"Go_FormanceLegder/internal/ledger"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Handler struct {
+	Service *ledger.Service
+}
+
+type SaveDefinitionRequest struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+type DefinitionResponse struct {
+	Name      string `json:"name"`
+	Steps     []Step `json:"steps"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type StartWorkflowRequest struct {
+	Definition     string          `json:"definition"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	Input          json.RawMessage `json:"input,omitempty"`
+}
+
+type InstanceResponse struct {
+	ID           string          `json:"id"`
+	Definition   string          `json:"definition"`
+	Status       string          `json:"status"`
+	CurrentStep  string          `json:"current_step,omitempty"`
+	Input        json.RawMessage `json:"input"`
+	Outputs      json.RawMessage `json:"outputs"`
+	WaitUntil    string          `json:"wait_until,omitempty"`
+	ErrorMessage string          `json:"error_message,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+	UpdatedAt    string          `json:"updated_at"`
+}
+
+const instanceSelect = `
+	SELECT id, definition_name, definition, status, current_step, input, outputs, wait_until,
+		COALESCE(error_message, ''), created_at, updated_at
+	FROM workflow_instances
+	WHERE ledger_id = $1
+`
+
+// POST /v1/workflows/definitions - Create or replace a workflow definition
+func (h *Handler) SaveDefinition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Unknown fields are rejected so a misspelt step field fails here, not mid-workflow
+	var req SaveDefinitionRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		api.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		api.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	def := Definition{Steps: req.Steps}
+	if err := def.Validate(); err != nil {
+		api.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Service.DB.Exec(ctx, `
+		INSERT INTO workflow_definitions (ledger_id, name, definition)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (ledger_id, name) DO UPDATE SET definition = EXCLUDED.definition, updated_at = NOW()
+	`, principal.LedgerID, req.Name, def)
+	if err != nil {
+		api.Error(w, "failed to save definition", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"name": req.Name})
+}
+
+// GET /v1/workflows/definitions - List workflow definitions
+func (h *Handler) ListDefinitions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.Service.DB.Query(ctx, `
+		SELECT name, definition, created_at, updated_at
+		FROM workflow_definitions
+		WHERE ledger_id = $1
+		ORDER BY name
+	`, principal.LedgerID)
+	if err != nil {
+		api.Error(w, "failed to query definitions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	definitions := []DefinitionResponse{}
+	for rows.Next() {
+		var d DefinitionResponse
+		var def Definition
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&d.Name, &def, &createdAt, &updatedAt); err != nil {
+			api.Error(w, "failed to scan definition", http.StatusInternalServerError)
+			return
+		}
+		d.Steps = def.Steps
+		d.CreatedAt = createdAt.Format(time.RFC3339)
+		d.UpdatedAt = updatedAt.Format(time.RFC3339)
+		definitions = append(definitions, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(definitions)
+}
+
+// POST /v1/workflows - Start an instance of a saved definition
+func (h *Handler) StartWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req StartWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	input := map[string]any{}
+	if len(req.Input) > 0 && !bytes.Equal(req.Input, []byte("null")) {
+		if err := decodeObject(req.Input, &input); err != nil {
+			api.Error(w, "input must be a JSON object", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := h.Service.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var definition []byte
+	err = tx.QueryRow(ctx, `
+		SELECT definition FROM workflow_definitions WHERE ledger_id = $1 AND name = $2
+	`, principal.LedgerID, req.Definition).Scan(&definition)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "workflow definition not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load definition", http.StatusInternalServerError)
+		return
+	}
+
+	var instanceID string
+	var created bool
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workflow_instances (ledger_id, definition_name, definition, idempotency_key, input)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT (ledger_id, idempotency_key) DO UPDATE SET idempotency_key = EXCLUDED.idempotency_key
+		RETURNING id, xmax = 0
+	`, principal.LedgerID, req.Definition, definition, req.IdempotencyKey, input).Scan(&instanceID, &created)
+	if err != nil {
+		api.Error(w, "failed to create workflow", http.StatusInternalServerError)
+		return
+	}
+
+	if created {
+		_, err = h.Service.RiverClient.InsertTx(ctx, tx, Args{InstanceID: instanceID, LedgerID: principal.LedgerID}, nil)
+		if err != nil {
+			api.Error(w, "failed to enqueue workflow", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id": instanceID})
+}
+
+// GET /v1/workflows?id= - Get a workflow instance with its step outputs
+func (h *Handler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	inst, err := scanInstance(h.Service.DB.QueryRow(ctx, instanceSelect+` AND id::text = $2`,
+		principal.LedgerID, r.URL.Query().Get("id")))
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "workflow not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to load workflow", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inst)
+}
+
+// GET /v1/workflows - List the most recent workflow instances, optionally by status
+func (h *Handler) ListWorkflows(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := instanceSelect
+	args := []interface{}{principal.LedgerID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC LIMIT 100`
+
+	rows, err := h.Service.DB.Query(ctx, query, args...)
+	if err != nil {
+		api.Error(w, "failed to query workflows", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	instances := []InstanceResponse{}
+	for rows.Next() {
+		inst, err := scanInstance(rows)
+		if err != nil {
+			api.Error(w, "failed to scan workflow", http.StatusInternalServerError)
+			return
+		}
+		instances = append(instances, inst)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instances)
+}
+
+// POST /v1/workflows/signal?id=&signal= - Deliver an external signal (the body, if any,
+// becomes the output of the waiting step)
+func (h *Handler) Signal(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, err := auth.FromContext(ctx)
+	if err != nil {
+		api.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	signal := r.URL.Query().Get("signal")
+	if signal == "" {
+		api.Error(w, "signal required", http.StatusBadRequest)
+		return
+	}
+	payload := map[string]any{}
+	if r.ContentLength != 0 {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || decodeObject(body, &payload) != nil {
+			api.Error(w, "signal payload must be a JSON object", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := h.Service.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		api.Error(w, "failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// The first delivery of a signal wins; redeliveries are accepted and ignored
+	var instanceID, status string
+	err = tx.QueryRow(ctx, `
+		UPDATE workflow_instances
+		SET signals = CASE WHEN signals ? $3 THEN signals ELSE signals || jsonb_build_object($3::text, $4::jsonb) END,
+			updated_at = NOW()
+		WHERE ledger_id = $1 AND id::text = $2
+		RETURNING id, status
+	`, principal.LedgerID, r.URL.Query().Get("id"), signal, payload).Scan(&instanceID, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		api.Error(w, "workflow not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.Error(w, "failed to record signal", http.StatusInternalServerError)
+		return
+	}
+
+	if status == "waiting" {
+		_, err = h.Service.RiverClient.InsertTx(ctx, tx, Args{InstanceID: instanceID, LedgerID: principal.LedgerID}, nil)
+		if err != nil {
+			api.Error(w, "failed to enqueue workflow", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		api.Error(w, "failed to commit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":     instanceID,
+		"signal": signal,
+		"status": "accepted",
+	})
+}
+
+func scanInstance(row pgx.Row) (InstanceResponse, error) {
+	var inst InstanceResponse
+	var def Definition
+	var currentStep int
+	var waitUntil *time.Time
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&inst.ID, &inst.Definition, &def, &inst.Status, &currentStep, &inst.Input, &inst.Outputs,
+		&waitUntil, &inst.ErrorMessage, &createdAt, &updatedAt)
+	if currentStep < len(def.Steps) && inst.Status != "completed" {
+		inst.CurrentStep = def.Steps[currentStep].Name
+	}
+	if waitUntil != nil {
+		inst.WaitUntil = waitUntil.Format(time.RFC3339)
+	}
+	inst.CreatedAt = createdAt.Format(time.RFC3339)
+	inst.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return inst, err
+}
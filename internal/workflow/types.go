@@ -0,0 +1,12 @@
+package workflow
+
+// Args advances one workflow instance. Jobs are inserted when an instance starts,
+// when it receives a signal and at the deadline of each wait.
+type Args struct {
+	InstanceID string `json:"instance_id"`
+	LedgerID   string `json:"ledger_id"`
+}
+
+func (Args) Kind() string {
+	return "workflow_step"
+}
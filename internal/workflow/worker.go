@@ -0,0 +1,379 @@
+package workflow
+
+import (
+	"Go_FormanceLegder/internal/ledger"
+	"Go_FormanceLegder/internal/script"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+)
+
+type Worker struct {
+	river.WorkerDefaults[Args]
+	DB      *pgxpool.Pool
+	Service *ledger.Service
+}
+
+type instance struct {
+	ID          string
+	LedgerID    string
+	Definition  Definition
+	Input       map[string]any
+	Status      string
+	CurrentStep int
+	Outputs     map[string]any // step name -> output object
+	Signals     map[string]any // signal name -> payload
+	WaitUntil   *time.Time
+	Error       string
+}
+
+// Work advances an instance as far as it can: it runs steps until one waits, the
+// workflow completes, or a failure has been compensated. The instance row stays
+// locked meanwhile, so concurrent jobs for the same instance run one after another.
+// Every ledger operation uses an idempotency key derived from the instance and step,
+// so a job retried after a crash never applies a step twice.
+func (w *Worker) Work(ctx context.Context, job *river.Job[Args]) error {
+	tx, err := w.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	inst, err := lockInstance(ctx, tx, job.Args.LedgerID, job.Args.InstanceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load workflow instance: %w", err)
+	}
+
+	if err := w.advance(ctx, tx, inst); err != nil {
+		if job.Attempt < job.MaxAttempts {
+			return err
+		}
+		// Out of retries; leave the instance for an operator
+		log.Printf("workflow %s: compensation failed: %v", inst.ID, err)
+		inst.Status = "failed"
+		inst.Error = "compensation failed: " + err.Error()
+	}
+
+	if err := saveInstance(ctx, tx, inst); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// advance runs the instance's state machine. It only returns an error when
+// compensation fails.
+func (w *Worker) advance(ctx context.Context, tx pgx.Tx, inst *instance) error {
+	for {
+		switch inst.Status {
+		case "running", "waiting":
+			if inst.CurrentStep >= len(inst.Definition.Steps) {
+				inst.Status = "completed"
+				return nil
+			}
+			step := inst.Definition.Steps[inst.CurrentStep]
+
+			output, waiting, err := w.execute(ctx, tx, inst, step)
+			if err != nil {
+				inst.Status = "compensating"
+				inst.Error = fmt.Sprintf("step %s: %v", step.Name, err)
+				continue
+			}
+			if waiting {
+				inst.Status = "waiting"
+				return nil
+			}
+			inst.Outputs[step.Name] = output
+			inst.Status = "running"
+			inst.WaitUntil = nil
+			inst.CurrentStep++
+
+		case "compensating":
+			if err := w.compensate(ctx, inst); err != nil {
+				return err
+			}
+			inst.Status = "compensated"
+			return nil
+
+		default:
+			// completed, compensated or failed
+			return nil
+		}
+	}
+}
+
+// execute runs one step. Waiting steps report waiting until their signal arrives or
+// their time has come.
+func (w *Worker) execute(ctx context.Context, tx pgx.Tx, inst *instance, step Step) (map[string]any, bool, error) {
+	step, err := step.resolve(map[string]any{"input": inst.Input, "steps": inst.Outputs})
+	if err != nil {
+		return nil, false, err
+	}
+	key := fmt.Sprintf("wf:%s:%s", inst.ID, step.Name)
+	now := time.Now().UTC()
+
+	switch step.Type {
+	case StepTransaction:
+		currency, postings := step.Currency, step.Postings
+		if step.Script != "" {
+			compiled, err := script.Compile(step.Script, step.Vars)
+			if err != nil {
+				return nil, false, fmt.Errorf("script: %w", err)
+			}
+			// Compiled here rather than by the ledger so the postings can be reversed
+			postings = nil
+			for _, p := range compiled {
+				postings = append(postings, ledger.PostingInput{
+					AccountCode: p.Account, Direction: p.Direction, Amount: p.Amount, Currency: p.Currency,
+				})
+			}
+			if currency == "" && len(compiled) > 0 {
+				currency = compiled[0].Currency
+			}
+		}
+		transactionID, err := w.Service.PostTransaction(ctx, ledger.PostTransactionCommand{
+			LedgerID:       inst.LedgerID,
+			ExternalID:     key,
+			IdempotencyKey: key,
+			Currency:       currency,
+			Postings:       postings,
+			OccurredAt:     now,
+			Metadata:       withWorkflow(step.Metadata, inst.ID),
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]any{"transaction_id": transactionID, "currency": currency, "postings": postings}, false, nil
+
+	case StepHold:
+		holdID, err := w.Service.CreateHold(ctx, ledger.HoldCommand{
+			LedgerID:        inst.LedgerID,
+			AccountCode:     step.Account,
+			DestinationCode: step.Destination,
+			Amount:          step.Amount,
+			Currency:        step.Currency,
+			Metadata:        withWorkflow(step.Metadata, inst.ID),
+			IdempotencyKey:  key,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]any{
+			"hold_id":     holdID,
+			"account":     step.Account,
+			"destination": step.Destination,
+			"amount":      step.Amount,
+			"currency":    step.Currency,
+		}, false, nil
+
+	case StepCaptureHold:
+		hold, _ := inst.Outputs[step.Hold].(map[string]any)
+		holdID, _ := hold["hold_id"].(string)
+		transactionID, err := w.Service.CaptureHold(ctx, inst.LedgerID, holdID, step.Amount)
+		if err != nil {
+			return nil, false, err
+		}
+		amount := step.Amount
+		if amount == "" {
+			amount, _ = hold["amount"].(string)
+		}
+		return map[string]any{"hold_id": holdID, "transaction_id": transactionID, "amount": amount}, false, nil
+
+	case StepVoidHold:
+		hold, _ := inst.Outputs[step.Hold].(map[string]any)
+		holdID, _ := hold["hold_id"].(string)
+		if err := w.Service.VoidHold(ctx, inst.LedgerID, holdID); err != nil {
+			return nil, false, err
+		}
+		return map[string]any{"hold_id": holdID}, false, nil
+
+	case StepWaitSignal:
+		// A signal delivered before the step is reached is kept and satisfies it at once
+		if payload, ok := inst.Signals[step.Signal]; ok {
+			output, _ := payload.(map[string]any)
+			if output == nil {
+				output = map[string]any{}
+			}
+			return output, false, nil
+		}
+		if inst.Status != "waiting" {
+			if step.Timeout == "" {
+				return nil, true, nil
+			}
+			timeout, _ := time.ParseDuration(step.Timeout)
+			return nil, true, w.wakeAt(ctx, tx, inst, now.Add(timeout))
+		}
+		if inst.WaitUntil != nil && !now.Before(*inst.WaitUntil) {
+			return nil, false, fmt.Errorf("timed out waiting for signal %s", step.Signal)
+		}
+		return nil, true, nil
+
+	case StepDelay:
+		if inst.Status != "waiting" {
+			duration, _ := time.ParseDuration(step.Duration)
+			return nil, true, w.wakeAt(ctx, tx, inst, now.Add(duration))
+		}
+		if inst.WaitUntil != nil && now.Before(*inst.WaitUntil) {
+			return nil, true, nil
+		}
+		return map[string]any{}, false, nil
+	}
+
+	return nil, false, fmt.Errorf("unknown step type %q", step.Type)
+}
+
+// wakeAt records the deadline of the current wait and schedules the job that checks it.
+func (w *Worker) wakeAt(ctx context.Context, tx pgx.Tx, inst *instance, at time.Time) error {
+	inst.WaitUntil = &at
+	_, err := w.Service.RiverClient.InsertTx(ctx, tx, Args{InstanceID: inst.ID, LedgerID: inst.LedgerID},
+		&river.InsertOpts{ScheduledAt: at})
+	return err
+}
+
+// compensate undoes the executed steps, last first: transactions and captures are
+// reversed and pending holds are voided. Compensations use their own idempotency keys,
+// so running them again after a partial failure is safe.
+func (w *Worker) compensate(ctx context.Context, inst *instance) error {
+	for i := inst.CurrentStep - 1; i >= 0; i-- {
+		step := inst.Definition.Steps[i]
+		output, _ := inst.Outputs[step.Name].(map[string]any)
+		if output == nil {
+			continue
+		}
+		key := fmt.Sprintf("wf:%s:%s:compensate", inst.ID, step.Name)
+
+		var reversal []ledger.PostingInput
+		var currency string
+		switch step.Type {
+		case StepTransaction:
+			currency, _ = output["currency"].(string)
+			postingsJSON, err := json.Marshal(output["postings"])
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(postingsJSON, &reversal); err != nil {
+				return err
+			}
+			for j := range reversal {
+				if reversal[j].Direction == "debit" {
+					reversal[j].Direction = "credit"
+				} else {
+					reversal[j].Direction = "debit"
+				}
+			}
+
+		case StepCaptureHold:
+			hold, _ := inst.Outputs[step.Hold].(map[string]any)
+			account, _ := hold["account"].(string)
+			destination, _ := hold["destination"].(string)
+			amount, _ := output["amount"].(string)
+			currency, _ = hold["currency"].(string)
+			reversal = []ledger.PostingInput{
+				{AccountCode: destination, Direction: "debit", Amount: amount},
+				{AccountCode: account, Direction: "credit", Amount: amount},
+			}
+
+		case StepHold:
+			holdID, _ := output["hold_id"].(string)
+			// A hold captured by a later step was reversed with that step
+			err := w.Service.VoidHold(ctx, inst.LedgerID, holdID)
+			if err != nil && !errors.Is(err, ledger.ErrHoldNotPending) {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+			continue
+
+		default:
+			continue
+		}
+
+		transactionID, err := w.Service.PostTransaction(ctx, ledger.PostTransactionCommand{
+			LedgerID:       inst.LedgerID,
+			ExternalID:     key,
+			IdempotencyKey: key,
+			Currency:       currency,
+			Postings:       reversal,
+			OccurredAt:     time.Now().UTC(),
+			Metadata:       map[string]any{"workflow_id": inst.ID, "compensates": output["transaction_id"]},
+		})
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		output["compensation_transaction_id"] = transactionID
+	}
+	return nil
+}
+
+func withWorkflow(metadata map[string]any, instanceID string) map[string]any {
+	out := map[string]any{"workflow_id": instanceID}
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
+
+func lockInstance(ctx context.Context, tx pgx.Tx, ledgerID, instanceID string) (*instance, error) {
+	inst := &instance{}
+	var definitionJSON, inputJSON, outputsJSON, signalsJSON []byte
+	err := tx.QueryRow(ctx, `
+		SELECT id, ledger_id, definition, input, status, current_step, outputs, signals, wait_until,
+			COALESCE(error_message, '')
+		FROM workflow_instances
+		WHERE id = $1 AND ledger_id = $2
+		FOR UPDATE
+	`, instanceID, ledgerID).Scan(&inst.ID, &inst.LedgerID, &definitionJSON, &inputJSON, &inst.Status,
+		&inst.CurrentStep, &outputsJSON, &signalsJSON, &inst.WaitUntil, &inst.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(definitionJSON, &inst.Definition); err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		data []byte
+		dst  *map[string]any
+	}{{inputJSON, &inst.Input}, {outputsJSON, &inst.Outputs}, {signalsJSON, &inst.Signals}} {
+		if err := decodeObject(field.data, field.dst); err != nil {
+			return nil, err
+		}
+	}
+	return inst, nil
+}
+
+func saveInstance(ctx context.Context, tx pgx.Tx, inst *instance) error {
+	outputsJSON, err := json.Marshal(inst.Outputs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE workflow_instances
+		SET status = $2, current_step = $3, outputs = $4, wait_until = $5, error_message = NULLIF($6, ''),
+			updated_at = NOW()
+		WHERE id = $1
+	`, inst.ID, inst.Status, inst.CurrentStep, outputsJSON, inst.WaitUntil, inst.Error)
+	return err
+}
+
+// decodeObject decodes a JSON object keeping numbers as json.Number, so amounts in
+// inputs and signals are substituted exactly as sent.
+func decodeObject(data []byte, dst *map[string]any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	if *dst == nil {
+		*dst = map[string]any{}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_api_keys_status;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS approved_at;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS approved_by;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS created_by;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS status;
+ALTER TABLE organizations DROP COLUMN IF EXISTS require_api_key_approval;
@@ -0,0 +1,16 @@
+-- Optional owner approval for API keys requested by developers
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS require_api_key_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- API key lifecycle state (projected from api_key events)
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('pending_approval', 'active', 'rejected', 'revoked'));
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS approved_by UUID REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS approved_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_status ON api_keys (ledger_id, status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS balance_snapshot_entries;
+DROP TABLE IF EXISTS balance_snapshots;
@@ -0,0 +1,19 @@
+-- Balance snapshots (frozen per-account balances at a point in time)
+CREATE TABLE IF NOT EXISTS balance_snapshots
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    taken_at   TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_ledger ON balance_snapshots (ledger_id, taken_at);
+
+CREATE TABLE IF NOT EXISTS balance_snapshot_entries
+(
+    snapshot_id UUID            NOT NULL REFERENCES balance_snapshots (id) ON DELETE CASCADE,
+    account_id  UUID            NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    balance     NUMERIC(38, 10) NOT NULL,
+    PRIMARY KEY (snapshot_id, account_id)
+);
@@ -0,0 +1,9 @@
+-- Per-posting currency so one transaction can carry several currency legs (e.g. conversions)
+ALTER TABLE postings
+    ADD COLUMN IF NOT EXISTS currency TEXT;
+
+UPDATE postings p
+SET currency = t.currency
+FROM transactions t
+WHERE t.id = p.transaction_id
+  AND p.currency IS NULL;
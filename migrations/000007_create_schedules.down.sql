@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS schedule_installments;
+DROP TABLE IF EXISTS schedules;
@@ -0,0 +1,36 @@
+-- Repayment / installment schedules
+CREATE TABLE IF NOT EXISTS schedules
+(
+    id                  UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    ledger_id           UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    reference           TEXT            NOT NULL,
+    principal           NUMERIC(38, 10) NOT NULL CHECK (principal > 0),
+    currency            TEXT            NOT NULL,
+    source_account      TEXT            NOT NULL,
+    destination_account TEXT            NOT NULL,
+    installment_count   INT             NOT NULL CHECK (installment_count > 0),
+    frequency           TEXT            NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    start_date          DATE            NOT NULL,
+    status              TEXT            NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'cancelled')),
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, reference)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_ledger ON schedules (ledger_id);
+
+CREATE TABLE IF NOT EXISTS schedule_installments
+(
+    id             UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    schedule_id    UUID            NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    sequence       INT             NOT NULL,
+    due_date       DATE            NOT NULL,
+    amount         NUMERIC(38, 10) NOT NULL,
+    status         TEXT            NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'posted', 'cancelled')),
+    transaction_id UUID,
+    attempts       INT             NOT NULL DEFAULT 0,
+    last_error     TEXT,
+    posted_at      TIMESTAMPTZ,
+    UNIQUE (schedule_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_installments_due ON schedule_installments (status, due_date);
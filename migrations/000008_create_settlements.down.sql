@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS settlement_batch_members;
+DROP TABLE IF EXISTS settlement_batches;
@@ -0,0 +1,30 @@
+-- Settlement batches (net payout of unsettled activity on an account)
+CREATE TABLE IF NOT EXISTS settlement_batches
+(
+    id                        UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    ledger_id                 UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    account_code              TEXT            NOT NULL,
+    payout_account_code       TEXT            NOT NULL,
+    currency                  TEXT            NOT NULL,
+    external_id_prefix        TEXT,
+    cutoff                    TIMESTAMPTZ     NOT NULL,
+    status                    TEXT            NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'settled', 'failed')),
+    net_amount                NUMERIC(38, 10) NOT NULL DEFAULT 0,
+    transaction_count         INT             NOT NULL DEFAULT 0,
+    settlement_transaction_id UUID,
+    error_message             TEXT,
+    created_at                TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    settled_at                TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlement_batches_ledger ON settlement_batches (ledger_id, created_at);
+
+-- A transaction can belong to at most one settlement batch
+CREATE TABLE IF NOT EXISTS settlement_batch_members
+(
+    batch_id       UUID            NOT NULL REFERENCES settlement_batches (id) ON DELETE CASCADE,
+    transaction_id UUID            NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    net_amount     NUMERIC(38, 10) NOT NULL,
+    PRIMARY KEY (batch_id, transaction_id),
+    UNIQUE (transaction_id)
+);
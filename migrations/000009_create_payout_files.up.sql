@@ -0,0 +1,20 @@
+-- Generated bank payout files (audit trail of every pain.001 / NACHA file handed out)
+CREATE TABLE IF NOT EXISTS payout_files
+(
+    id                  UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    ledger_id           UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    format              TEXT            NOT NULL CHECK (format IN ('pain.001', 'nacha')),
+    message_id          TEXT            NOT NULL,
+    settlement_batch_id UUID REFERENCES settlement_batches (id) ON DELETE SET NULL,
+    instruction_count   INT             NOT NULL,
+    total_amount        NUMERIC(38, 10) NOT NULL,
+    currency            TEXT            NOT NULL,
+    execution_date      DATE            NOT NULL,
+    checksum            TEXT            NOT NULL,
+    content             BYTEA           NOT NULL,
+    api_key_id          UUID,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payout_files_ledger ON payout_files (ledger_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_payout_files_settlement ON payout_files (settlement_batch_id);
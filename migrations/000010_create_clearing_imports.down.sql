@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS clearing_import_records;
+DROP TABLE IF EXISTS clearing_imports;
+DROP TABLE IF EXISTS clearing_mappings;
@@ -0,0 +1,43 @@
+-- Clearing file layouts (column mapping and posting rules per network/processor)
+CREATE TABLE IF NOT EXISTS clearing_mappings
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    config     JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, name)
+);
+
+-- One row per ingested clearing file
+CREATE TABLE IF NOT EXISTS clearing_imports
+(
+    id                UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id         UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    mapping_id        UUID        NOT NULL REFERENCES clearing_mappings (id),
+    filename          TEXT,
+    total_records     INT         NOT NULL DEFAULT 0,
+    posted_count      INT         NOT NULL DEFAULT 0,
+    matched_count     INT         NOT NULL DEFAULT 0,
+    discrepancy_count INT         NOT NULL DEFAULT 0,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_clearing_imports_ledger ON clearing_imports (ledger_id, created_at);
+
+-- Outcome of every record in a clearing file; anything not posted/matched is a discrepancy
+CREATE TABLE IF NOT EXISTS clearing_import_records
+(
+    import_id      UUID        NOT NULL REFERENCES clearing_imports (id) ON DELETE CASCADE,
+    line           INT         NOT NULL,
+    reference      TEXT,
+    record_type    TEXT,
+    amount         NUMERIC(38, 10),
+    currency       TEXT,
+    occurred_at    TIMESTAMPTZ,
+    status         TEXT        NOT NULL CHECK (status IN ('posted', 'matched', 'amount_mismatch', 'duplicate', 'unmatched', 'invalid', 'failed')),
+    reason         TEXT,
+    transaction_id UUID,
+    PRIMARY KEY (import_id, line)
+);
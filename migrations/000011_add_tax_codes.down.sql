@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_postings_tax_code;
+ALTER TABLE postings
+    DROP COLUMN IF EXISTS tax_code;
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS tax_code;
+DROP TABLE IF EXISTS tax_codes;
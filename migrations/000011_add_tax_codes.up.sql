@@ -0,0 +1,20 @@
+-- Tax codes (VAT/GST rates) and the account the collected tax is booked to
+CREATE TABLE IF NOT EXISTS tax_codes
+(
+    ledger_id   UUID           NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    code        TEXT           NOT NULL,
+    description TEXT,
+    rate        NUMERIC(10, 6) NOT NULL CHECK (rate >= 0),
+    tax_account TEXT           NOT NULL,
+    created_at  TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, code)
+);
+
+-- Default tax code of an account; postings without an explicit code inherit it
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS tax_code TEXT;
+
+ALTER TABLE postings
+    ADD COLUMN IF NOT EXISTS tax_code TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_postings_tax_code ON postings (ledger_id, tax_code) WHERE tax_code IS NOT NULL;
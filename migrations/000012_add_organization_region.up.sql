@@ -0,0 +1,3 @@
+-- Data residency: region holding the organization's ledger data (NULL = default region)
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS region TEXT;
@@ -0,0 +1,3 @@
+-- Arbitrary integrator data (order ids, customer ids, ...) attached to a transaction
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
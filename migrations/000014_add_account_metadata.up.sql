@@ -0,0 +1,3 @@
+-- Customer references, tags, ... attached to an account; updated through AccountMetadataUpdated events
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,35 @@
+-- Sagas: workflows of several transactions, possibly in different ledgers of a project,
+-- that are compensated (reversed) as a whole when a step fails
+CREATE TABLE IF NOT EXISTS sagas
+(
+    id               UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id        UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    idempotency_key  TEXT        NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'completed', 'compensating', 'compensated', 'failed')),
+    error_message    TEXT,
+    lease_expires_at TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sagas_ledger ON sagas (ledger_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sagas_unfinished ON sagas (updated_at) WHERE status IN ('running', 'compensating');
+
+CREATE TABLE IF NOT EXISTS saga_steps
+(
+    saga_id                     UUID        NOT NULL REFERENCES sagas (id) ON DELETE CASCADE,
+    sequence                    INT         NOT NULL,
+    ledger_id                   UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    external_id                 TEXT,
+    currency                    TEXT        NOT NULL,
+    postings                    JSONB       NOT NULL,
+    metadata                    JSONB       NOT NULL DEFAULT '{}',
+    status                      TEXT        NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'posted', 'failed', 'compensated')),
+    transaction_id              UUID,
+    compensation_transaction_id UUID,
+    error_message               TEXT,
+    PRIMARY KEY (saga_id, sequence)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS held_balance;
+DROP TABLE IF EXISTS holds;
@@ -0,0 +1,24 @@
+-- Holds (two-phase authorize, then capture or void); read model projected from hold events
+CREATE TABLE IF NOT EXISTS holds
+(
+    id               UUID PRIMARY KEY,
+    ledger_id        UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    account_code     TEXT            NOT NULL,
+    destination_code TEXT            NOT NULL,
+    amount           NUMERIC(38, 10) NOT NULL CHECK (amount > 0),
+    currency         TEXT            NOT NULL,
+    description      TEXT,
+    metadata         JSONB           NOT NULL DEFAULT '{}',
+    status           TEXT            NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'captured', 'voided')),
+    captured_amount  NUMERIC(38, 10),
+    transaction_id   UUID,
+    created_at       TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ     NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_ledger ON holds (ledger_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_holds_account ON holds (ledger_id, account_code) WHERE status = 'pending';
+
+-- Sum of pending holds on the account; available balance = balance - held_balance
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS held_balance NUMERIC(38, 10) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS workflow_instances;
+DROP TABLE IF EXISTS workflow_definitions;
@@ -0,0 +1,32 @@
+-- Workflow definitions (JSON step lists) and their running instances
+CREATE TABLE IF NOT EXISTS workflow_definitions
+(
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    definition JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS workflow_instances
+(
+    id              UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id       UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    definition_name TEXT        NOT NULL,
+    definition      JSONB       NOT NULL, -- snapshot, so editing a definition never affects running instances
+    idempotency_key TEXT,
+    input           JSONB       NOT NULL DEFAULT '{}',
+    status          TEXT        NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'waiting', 'completed', 'compensating', 'compensated', 'failed')),
+    current_step    INT         NOT NULL DEFAULT 0,
+    outputs         JSONB       NOT NULL DEFAULT '{}',
+    signals         JSONB       NOT NULL DEFAULT '{}',
+    wait_until      TIMESTAMPTZ,
+    error_message   TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_ledger ON workflow_instances (ledger_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS connector_events;
+DROP TABLE IF EXISTS connectors;
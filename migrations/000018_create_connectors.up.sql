@@ -0,0 +1,33 @@
+-- Payment service provider connectors: signed webhooks mapped to ledger postings
+CREATE TABLE IF NOT EXISTS connectors
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    provider   TEXT        NOT NULL,
+    secret     TEXT        NOT NULL, -- webhook signing secret issued by the provider
+    config     JSONB       NOT NULL, -- connectors.Mapping
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS connector_events
+(
+    id                UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    connector_id      UUID        NOT NULL REFERENCES connectors (id) ON DELETE CASCADE,
+    provider_event_id TEXT        NOT NULL,
+    event_type        TEXT        NOT NULL,
+    reference         TEXT,
+    amount            NUMERIC(38, 10),
+    currency          TEXT,
+    status            TEXT        NOT NULL CHECK (status IN ('posted', 'ignored', 'failed')),
+    reason            TEXT,
+    transaction_id    UUID,
+    attempts          INT         NOT NULL DEFAULT 1,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (connector_id, provider_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_connector_events_connector ON connector_events (connector_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bank_lines;
+DROP TABLE IF EXISTS bank_feeds;
@@ -0,0 +1,40 @@
+-- Open banking feeds and the bank lines they stage for posting and reconciliation
+CREATE TABLE IF NOT EXISTS bank_feeds
+(
+    id             UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id      UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name           TEXT        NOT NULL,
+    provider       TEXT        NOT NULL,
+    credentials    JSONB       NOT NULL, -- bankfeed.Credentials
+    config         JSONB       NOT NULL, -- bankfeed.Config
+    cursor         TEXT,
+    status         TEXT        NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused')),
+    last_synced_at TIMESTAMPTZ,
+    last_error     TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS bank_lines
+(
+    id             UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    feed_id        UUID            NOT NULL REFERENCES bank_feeds (id) ON DELETE CASCADE,
+    ledger_id      UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    external_id    TEXT            NOT NULL,
+    amount         NUMERIC(38, 10) NOT NULL,
+    currency       TEXT            NOT NULL,
+    booked_at      TIMESTAMPTZ     NOT NULL,
+    description    TEXT,
+    counterparty   TEXT,
+    status         TEXT            NOT NULL DEFAULT 'staged'
+        CHECK (status IN ('staged', 'posted', 'unmatched', 'failed')),
+    reason         TEXT,
+    transaction_id UUID,
+    created_at     TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    UNIQUE (feed_id, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_lines_ledger ON bank_lines (ledger_id, booked_at);
+CREATE INDEX IF NOT EXISTS idx_bank_lines_open ON bank_lines (feed_id) WHERE status <> 'posted';
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_transactions_entity;
+DROP INDEX IF EXISTS idx_accounts_entity;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS entity_code;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS entity_code;
+
+DROP TABLE IF EXISTS entities;
@@ -0,0 +1,24 @@
+-- Business counterparties (customers, vendors, ...) that accounts and transactions belong to
+CREATE TABLE IF NOT EXISTS entities
+(
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    code       TEXT        NOT NULL, -- the caller's own reference, e.g. a CRM customer id
+    type       TEXT        NOT NULL CHECK (type IN ('customer', 'vendor', 'partner', 'employee', 'other')),
+    name       TEXT        NOT NULL,
+    email      TEXT,
+    metadata   JSONB       NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entities_type ON entities (ledger_id, type, name);
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS entity_code TEXT;
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS entity_code TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_entity ON accounts (ledger_id, entity_code) WHERE entity_code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_entity ON transactions (ledger_id, entity_code, occurred_at) WHERE entity_code IS NOT NULL;
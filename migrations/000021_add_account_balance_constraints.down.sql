@@ -0,0 +1,3 @@
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS min_balance,
+    DROP COLUMN IF EXISTS allow_negative_balance;
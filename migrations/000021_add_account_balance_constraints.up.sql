@@ -0,0 +1,5 @@
+-- Overdraft protection: when allow_negative_balance is false, postings may not take the
+-- balance below min_balance (0 when unset). A negative min_balance models an overdraft limit.
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS allow_negative_balance BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS min_balance            NUMERIC(38, 10);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS kyc_policies;
+
+DROP INDEX IF EXISTS idx_entities_id;
+
+ALTER TABLE entities
+    DROP COLUMN IF EXISTS kyc_updated_at,
+    DROP COLUMN IF EXISTS kyc_status,
+    DROP COLUMN IF EXISTS id;
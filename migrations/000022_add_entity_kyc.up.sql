@@ -0,0 +1,19 @@
+-- KYC status per entity; accounts inherit the status of the entity they are linked to.
+-- id gives entities an event aggregate id (events.aggregate_id is a UUID).
+ALTER TABLE entities
+    ADD COLUMN IF NOT EXISTS id             UUID        NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN IF NOT EXISTS kyc_status     TEXT        NOT NULL DEFAULT 'unverified'
+        CHECK (kyc_status IN ('unverified', 'pending', 'verified', 'rejected')),
+    ADD COLUMN IF NOT EXISTS kyc_updated_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_entities_id ON entities (id);
+
+-- What happens to transactions involving an entity that is not verified
+CREATE TABLE IF NOT EXISTS kyc_policies
+(
+    ledger_id  UUID PRIMARY KEY REFERENCES ledgers (id) ON DELETE CASCADE,
+    action     TEXT        NOT NULL CHECK (action IN ('allow', 'block', 'cap')),
+    max_amount NUMERIC(38, 10), -- per transaction and currency, for action 'cap'
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (action <> 'cap' OR max_amount IS NOT NULL)
+);
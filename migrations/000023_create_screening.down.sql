@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS screening_reviews;
+DROP TABLE IF EXISTS screening_rules;
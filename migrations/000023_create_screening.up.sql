@@ -0,0 +1,25 @@
+-- Per-ledger deny list applied before transactions are posted (see package screening)
+CREATE TABLE IF NOT EXISTS screening_rules
+(
+    ledger_id  UUID PRIMARY KEY REFERENCES ledgers (id) ON DELETE CASCADE,
+    rules      JSONB       NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Transactions held by screening until they are approved (and posted) or rejected
+CREATE TABLE IF NOT EXISTS screening_reviews
+(
+    id              UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id       UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    idempotency_key TEXT        NOT NULL,
+    command         JSONB       NOT NULL,
+    reason          TEXT        NOT NULL,
+    status          TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    review_note     TEXT,
+    transaction_id  UUID,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reviewed_at     TIMESTAMPTZ,
+    UNIQUE (ledger_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_screening_reviews_status ON screening_reviews (ledger_id, status, created_at);
@@ -0,0 +1,3 @@
+-- Account codes are segmented with ':' (e.g. assets:cash:eur); prefix lookups for
+-- rollups need a pattern index since the ledger's collation may not support LIKE
+CREATE INDEX IF NOT EXISTS idx_accounts_code_prefix ON accounts (ledger_id, code text_pattern_ops);
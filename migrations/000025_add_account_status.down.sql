@@ -0,0 +1,3 @@
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS status;
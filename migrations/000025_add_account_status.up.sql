@@ -0,0 +1,4 @@
+-- Disabled accounts keep their history but accept no new postings
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS status      TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'disabled')),
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
@@ -0,0 +1,16 @@
+-- Human notes on transactions and accounts, written from the dashboard for audit context.
+-- Accounts are referenced by code, transactions by id. Author email is copied from the
+-- IAM database, which regional databases cannot join.
+CREATE TABLE IF NOT EXISTS notes
+(
+    id           UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id    UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    target_type  TEXT        NOT NULL CHECK (target_type IN ('transaction', 'account')),
+    target_id    TEXT        NOT NULL,
+    author_id    UUID        NOT NULL,
+    author_email TEXT        NOT NULL,
+    body         TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_target ON notes (ledger_id, target_type, target_id, created_at);
@@ -0,0 +1,14 @@
+-- Exchange rates per ledger: units of quote_currency per unit of base_currency, valid
+-- from effective_at until the next rate for the pair
+CREATE TABLE IF NOT EXISTS exchange_rates
+(
+    id             UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    ledger_id      UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    base_currency  TEXT            NOT NULL,
+    quote_currency TEXT            NOT NULL,
+    rate           NUMERIC(38, 18) NOT NULL CHECK (rate > 0),
+    effective_at   TIMESTAMPTZ     NOT NULL,
+    created_at     TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, base_currency, quote_currency, effective_at),
+    CHECK (base_currency <> quote_currency)
+);
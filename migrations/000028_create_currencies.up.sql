@@ -0,0 +1,14 @@
+-- Currencies (assets) registered per ledger. Posting amounts in a registered currency
+-- may not have more decimal places than its precision; unregistered currencies are
+-- accepted as before.
+CREATE TABLE IF NOT EXISTS currencies
+(
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    code       TEXT        NOT NULL,
+    name       TEXT,
+    precision  SMALLINT    NOT NULL CHECK (precision BETWEEN 0 AND 10),
+    rounding   TEXT        NOT NULL DEFAULT 'half_up' CHECK (rounding IN ('half_up', 'half_even', 'down', 'up')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, code)
+);
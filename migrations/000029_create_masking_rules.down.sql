@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS masking_rules;
+
+UPDATE org_users SET role = 'developer' WHERE role = 'support';
+ALTER TABLE org_users DROP CONSTRAINT IF EXISTS org_users_role_check;
+ALTER TABLE org_users
+    ADD CONSTRAINT org_users_role_check CHECK (role IN ('owner', 'developer'));
@@ -0,0 +1,15 @@
+-- Support staff triage ledgers from the dashboard, usually with values masked
+ALTER TABLE org_users DROP CONSTRAINT IF EXISTS org_users_role_check;
+ALTER TABLE org_users
+    ADD CONSTRAINT org_users_role_check CHECK (role IN ('owner', 'developer', 'support'));
+
+-- Values hidden from a role in dashboard ledger reads; owners always see everything
+CREATE TABLE IF NOT EXISTS masking_rules
+(
+    organization_id UUID        NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    role            TEXT        NOT NULL CHECK (role IN ('developer', 'support')),
+    hide_amounts    BOOLEAN     NOT NULL DEFAULT FALSE,
+    hidden_metadata TEXT[]      NOT NULL DEFAULT '{}', -- metadata keys, or '*' for all
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, role)
+);
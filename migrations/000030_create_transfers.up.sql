@@ -0,0 +1,19 @@
+-- Cross-ledger transfers: mirrored transactions in two ledgers of one project
+CREATE TABLE IF NOT EXISTS transfers
+(
+    id                         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    project_id                 UUID            NOT NULL,
+    idempotency_key            TEXT            NOT NULL,
+    source_ledger_id           UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    source_transaction_id      UUID            NOT NULL,
+    destination_ledger_id      UUID            NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    destination_transaction_id UUID            NOT NULL,
+    amount                     NUMERIC(38, 10) NOT NULL,
+    currency                   TEXT            NOT NULL,
+    created_at                 TIMESTAMPTZ     NOT NULL DEFAULT NOW(),
+    UNIQUE (project_id, idempotency_key),
+    CHECK (source_ledger_id <> destination_ledger_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_source ON transfers (source_ledger_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transfers_destination ON transfers (destination_ledger_id, created_at);
@@ -0,0 +1,21 @@
+-- Saved dashboard views: a named ledger read with its query parameters, optionally
+-- run on a schedule with the last result kept
+CREATE TABLE IF NOT EXISTS saved_views
+(
+    id           UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id    UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL,
+    kind         TEXT        NOT NULL CHECK (kind IN ('transactions', 'accounts', 'balance_summary')),
+    params       JSONB       NOT NULL DEFAULT '{}',
+    schedule     TEXT CHECK (schedule IN ('hourly', 'daily', 'weekly')),
+    author_email TEXT        NOT NULL,
+    next_run_at  TIMESTAMPTZ,
+    last_run_at  TIMESTAMPTZ,
+    last_result  JSONB,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_due ON saved_views (next_run_at) WHERE schedule IS NOT NULL;
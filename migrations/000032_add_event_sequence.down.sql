@@ -0,0 +1,8 @@
+ALTER TABLE projector_offsets
+    DROP COLUMN IF EXISTS last_processed_sequence;
+
+DROP TRIGGER IF EXISTS events_assign_sequence ON events;
+DROP FUNCTION IF EXISTS events_assign_sequence();
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS sequence;
@@ -0,0 +1,44 @@
+-- Monotonic event position; all event ordering (projector, events API, cursors) uses it
+-- instead of created_at and random UUIDs
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS sequence BIGSERIAL;
+
+-- Number existing events in their previous (created_at, id) order
+WITH ordered AS (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS n FROM events)
+UPDATE events e
+SET sequence = o.n
+FROM ordered o
+WHERE e.id = o.id;
+
+SELECT setval(pg_get_serial_sequence('events', 'sequence'), COALESCE((SELECT MAX(sequence) FROM events), 0) + 1, false);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_sequence ON events (sequence);
+
+-- A sequence value is taken at insert but becomes visible at commit, so a concurrent
+-- writer could commit a lower value after a reader moved past it. Taking the value under
+-- a transaction-scoped lock makes commit order match sequence order; writers only
+-- serialize from their event insert to their commit.
+CREATE OR REPLACE FUNCTION events_assign_sequence() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('events_sequence'));
+    NEW.sequence := nextval(pg_get_serial_sequence('events', 'sequence'));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS events_assign_sequence ON events;
+CREATE TRIGGER events_assign_sequence
+    BEFORE INSERT
+    ON events
+    FOR EACH ROW
+EXECUTE FUNCTION events_assign_sequence();
+
+-- Projectors resume from the sequence of their last processed event
+ALTER TABLE projector_offsets
+    ADD COLUMN IF NOT EXISTS last_processed_sequence BIGINT NOT NULL DEFAULT 0;
+
+UPDATE projector_offsets o
+SET last_processed_sequence = e.sequence
+FROM events e
+WHERE e.id = o.last_processed_event_id;
@@ -0,0 +1,7 @@
+-- Position of each outbox relay in the events table, by event sequence
+CREATE TABLE IF NOT EXISTS outbox_offsets
+(
+    relay_name    TEXT PRIMARY KEY,
+    last_sequence BIGINT      NOT NULL DEFAULT 0,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION events_assign_sequence() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('events_sequence'));
+    NEW.sequence := nextval(pg_get_serial_sequence('events', 'sequence'));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS event_archives;
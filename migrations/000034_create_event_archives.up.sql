@@ -0,0 +1,33 @@
+-- Events exported to object storage and pruned; restored archives are kept in Postgres
+-- and not archived again
+CREATE TABLE IF NOT EXISTS event_archives
+(
+    id               UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id        UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    object_key       TEXT        NOT NULL UNIQUE,
+    from_sequence    BIGINT      NOT NULL,
+    to_sequence      BIGINT      NOT NULL,
+    event_count      INT         NOT NULL,
+    size_bytes       BIGINT      NOT NULL,
+    oldest_event_at  TIMESTAMPTZ NOT NULL,
+    newest_event_at  TIMESTAMPTZ NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'archived' CHECK (status IN ('archived', 'restored')),
+    restored_at      TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_archives_ledger ON event_archives (ledger_id, from_sequence);
+
+-- Restores re-insert events with their original sequence, so projectors and relays that
+-- already passed it do not apply them again
+CREATE OR REPLACE FUNCTION events_assign_sequence() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF current_setting('ledger.restoring_events', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_advisory_xact_lock(hashtext('events_sequence'));
+    NEW.sequence := nextval(pg_get_serial_sequence('events', 'sequence'));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS rate_limit_windows;
+
+ALTER TABLE ledgers
+    DROP COLUMN IF EXISTS rate_limit_per_second,
+    DROP COLUMN IF EXISTS rate_limit_burst;
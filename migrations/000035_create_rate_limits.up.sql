@@ -0,0 +1,18 @@
+-- Per-ledger API rate limits; NULL uses the server default
+ALTER TABLE ledgers
+    ADD COLUMN IF NOT EXISTS rate_limit_per_second NUMERIC(12, 3) CHECK (rate_limit_per_second >= 0),
+    ADD COLUMN IF NOT EXISTS rate_limit_burst      INT CHECK (rate_limit_burst >= 1);
+
+-- Daily windows multiplying a ledger's limit, e.g. 10x for a nightly batch run
+CREATE TABLE IF NOT EXISTS rate_limit_windows
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id  UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    start_time TEXT        NOT NULL, -- HH:MM in timezone
+    end_time   TEXT        NOT NULL, -- before start_time for windows past midnight
+    timezone   TEXT        NOT NULL DEFAULT 'UTC',
+    multiplier NUMERIC(8, 2) NOT NULL CHECK (multiplier >= 1),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, name)
+);
@@ -0,0 +1,50 @@
+ALTER TABLE events
+    RENAME TO events_partitioned;
+
+ALTER SEQUENCE events_sequence_seq OWNED BY NONE;
+
+CREATE TABLE events
+(
+    id              UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    ledger_id       UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    aggregate_type  TEXT        NOT NULL,
+    aggregate_id    UUID        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    payload         JSONB       NOT NULL,
+    occurred_at     TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    idempotency_key TEXT,
+    sequence        BIGINT      NOT NULL DEFAULT nextval('events_sequence_seq'),
+    UNIQUE (ledger_id, idempotency_key)
+);
+
+INSERT INTO events (id, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at,
+                    idempotency_key, sequence)
+SELECT id, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at,
+       idempotency_key, sequence
+FROM events_partitioned;
+
+ALTER SEQUENCE events_sequence_seq OWNED BY events.sequence;
+
+DROP TABLE events_partitioned;
+DROP FUNCTION IF EXISTS ensure_event_partitions(DATE, DATE);
+DROP FUNCTION IF EXISTS events_claim_idempotency_key();
+DROP TABLE IF EXISTS event_idempotency_keys;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_sequence ON events (sequence);
+CREATE INDEX IF NOT EXISTS idx_events_ledger ON events (ledger_id);
+CREATE INDEX IF NOT EXISTS idx_events_aggregate ON events (aggregate_type, aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_events_type ON events (event_type);
+CREATE INDEX IF NOT EXISTS idx_events_created ON events (created_at);
+
+CREATE TRIGGER events_assign_sequence
+    BEFORE INSERT
+    ON events
+    FOR EACH ROW
+EXECUTE FUNCTION events_assign_sequence();
+
+DELETE FROM webhook_deliveries d
+WHERE NOT EXISTS (SELECT 1 FROM events e WHERE e.id = d.event_id);
+
+ALTER TABLE webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_event_id_fkey FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE;
@@ -0,0 +1,124 @@
+-- Partition events by month of created_at, so a large ledger's history is spread over
+-- bounded tables and old months can be dropped or detached without a bulk DELETE:
+--   ALTER TABLE events DETACH PARTITION events_p202401 CONCURRENTLY;
+-- Event IDs stay unique in practice (random UUIDs) but are only enforced per partition,
+-- and nothing can reference events by foreign key any more.
+
+ALTER TABLE webhook_deliveries
+    DROP CONSTRAINT IF EXISTS webhook_deliveries_event_id_fkey;
+
+ALTER TABLE events
+    RENAME TO events_legacy;
+
+-- The sequence outlives the legacy table and keeps numbering the partitioned one
+ALTER SEQUENCE events_sequence_seq OWNED BY NONE;
+
+CREATE TABLE events
+(
+    id              UUID        NOT NULL DEFAULT gen_random_uuid(),
+    ledger_id       UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    aggregate_type  TEXT        NOT NULL,
+    aggregate_id    UUID        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    payload         JSONB       NOT NULL,
+    occurred_at     TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    idempotency_key TEXT,
+    sequence        BIGINT      NOT NULL DEFAULT nextval('events_sequence_seq')
+) PARTITION BY RANGE (created_at);
+
+ALTER SEQUENCE events_sequence_seq OWNED BY events.sequence;
+
+-- Catches rows outside the monthly partitions, so inserts never fail for lack of one;
+-- ensure_event_partitions moves them into their month when it is created
+CREATE TABLE events_default PARTITION OF events DEFAULT;
+
+-- Creates the monthly partitions covering [from_month, to_month], moving any rows the
+-- default partition holds for them. Run daily by the worker to stay ahead of time.
+CREATE OR REPLACE FUNCTION ensure_event_partitions(from_month DATE, to_month DATE) RETURNS VOID AS
+$$
+DECLARE
+    month_start DATE := date_trunc('month', from_month);
+    month_end   DATE;
+    partition   TEXT;
+BEGIN
+    WHILE month_start <= to_month
+        LOOP
+            month_end := month_start + INTERVAL '1 month';
+            partition := 'events_p' || to_char(month_start, 'YYYYMM');
+            IF to_regclass(partition) IS NULL THEN
+                EXECUTE format('CREATE TABLE %I (LIKE events INCLUDING DEFAULTS)', partition);
+                EXECUTE format(
+                        'WITH moved AS (DELETE FROM events_default WHERE created_at >= %L AND created_at < %L RETURNING *)
+                         INSERT INTO %I SELECT * FROM moved', month_start, month_end, partition);
+                EXECUTE format('ALTER TABLE events ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+                               partition, month_start, month_end);
+            END IF;
+            month_start := month_end;
+        END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT ensure_event_partitions(
+               COALESCE((SELECT MIN(created_at) FROM events_legacy), NOW())::date,
+               (NOW() + INTERVAL '3 months')::date);
+
+-- Copied before the sequence trigger exists, so events keep their sequence
+INSERT INTO events (id, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at,
+                    idempotency_key, sequence)
+SELECT id, ledger_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, created_at,
+       idempotency_key, sequence
+FROM events_legacy;
+
+-- Idempotency keys must be unique per ledger across partitions, which a partitioned
+-- unique index cannot enforce (it must include created_at); they are claimed here instead
+CREATE TABLE IF NOT EXISTS event_idempotency_keys
+(
+    ledger_id       UUID NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    event_id        UUID NOT NULL,
+    aggregate_id    UUID NOT NULL,
+    PRIMARY KEY (ledger_id, idempotency_key)
+);
+
+INSERT INTO event_idempotency_keys (ledger_id, idempotency_key, event_id, aggregate_id)
+SELECT ledger_id, idempotency_key, id, aggregate_id
+FROM events_legacy
+WHERE idempotency_key IS NOT NULL;
+
+DROP TABLE events_legacy;
+
+ALTER TABLE events
+    ADD PRIMARY KEY (id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_events_sequence ON events (sequence);
+CREATE INDEX IF NOT EXISTS idx_events_ledger ON events (ledger_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_events_aggregate ON events (aggregate_type, aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_events_type ON events (event_type);
+CREATE INDEX IF NOT EXISTS idx_events_created ON events (created_at);
+
+CREATE TRIGGER events_assign_sequence
+    BEFORE INSERT
+    ON events
+    FOR EACH ROW
+EXECUTE FUNCTION events_assign_sequence();
+
+-- A taken key fails the insert with a unique violation, as the old constraint did
+CREATE OR REPLACE FUNCTION events_claim_idempotency_key() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.idempotency_key IS NOT NULL THEN
+        INSERT INTO event_idempotency_keys (ledger_id, idempotency_key, event_id, aggregate_id)
+        VALUES (NEW.ledger_id, NEW.idempotency_key, NEW.id, NEW.aggregate_id);
+    ELSIF TG_OP = 'DELETE' AND OLD.idempotency_key IS NOT NULL THEN
+        DELETE FROM event_idempotency_keys WHERE ledger_id = OLD.ledger_id AND event_id = OLD.id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_claim_idempotency_key
+    AFTER INSERT OR DELETE
+    ON events
+    FOR EACH ROW
+EXECUTE FUNCTION events_claim_idempotency_key();
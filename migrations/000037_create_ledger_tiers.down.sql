@@ -0,0 +1,6 @@
+ALTER TABLE event_archives DROP COLUMN IF EXISTS reason;
+
+ALTER TABLE ledgers
+    DROP COLUMN IF EXISTS rehydrated_at,
+    DROP COLUMN IF EXISTS archived_at,
+    DROP COLUMN IF EXISTS storage_tier;
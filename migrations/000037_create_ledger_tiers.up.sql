@@ -0,0 +1,11 @@
+-- Ledgers inactive for months move to the archival tier: their events live in object
+-- storage and their transactions and postings are dropped until the next request
+ALTER TABLE ledgers
+    ADD COLUMN IF NOT EXISTS storage_tier  TEXT NOT NULL DEFAULT 'hot' CHECK (storage_tier IN ('hot', 'archived')),
+    ADD COLUMN IF NOT EXISTS archived_at   TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS rehydrated_at TIMESTAMPTZ;
+
+-- age archives come from the event archiver; ledger archives hold an archived ledger's
+-- events and are dropped once it is rehydrated
+ALTER TABLE event_archives
+    ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT 'age' CHECK (reason IN ('age', 'ledger'));
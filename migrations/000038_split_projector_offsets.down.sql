@@ -0,0 +1,9 @@
+-- Rewind the ledger projector to the slower of the two so no API key event is skipped
+UPDATE projector_offsets l
+SET last_processed_event_id = k.last_processed_event_id,
+    last_processed_sequence = k.last_processed_sequence
+FROM projector_offsets k
+WHERE l.projector_name = 'ledger' AND k.projector_name = 'api_keys'
+  AND k.last_processed_sequence < l.last_processed_sequence;
+
+DELETE FROM projector_offsets WHERE projector_name = 'api_keys';
@@ -0,0 +1,7 @@
+-- API keys are projected apart from the ledger read model; the new projector carries on
+-- from where the combined one stopped
+INSERT INTO projector_offsets (projector_name, last_processed_event_id, last_processed_sequence)
+SELECT 'api_keys', last_processed_event_id, last_processed_sequence
+FROM projector_offsets
+WHERE projector_name = 'ledger'
+ON CONFLICT (projector_name) DO NOTHING;
@@ -0,0 +1,20 @@
+-- Organization deletion requests. No foreign key: a request outlives its organization
+-- as the record of the deletion.
+CREATE TABLE IF NOT EXISTS org_offboardings
+(
+    id                UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    organization_id   UUID        NOT NULL,
+    organization_name TEXT        NOT NULL,
+    requested_by      TEXT        NOT NULL,
+    status            TEXT        NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'cancelled', 'deleted')),
+    delete_after      TIMESTAMPTZ NOT NULL,
+    reminded_at       TIMESTAMPTZ,
+    cancelled_by      TEXT,
+    cancelled_at      TIMESTAMPTZ,
+    deleted_at        TIMESTAMPTZ,
+    last_error        TEXT,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_offboardings_scheduled ON org_offboardings (organization_id) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_org_offboardings_org ON org_offboardings (organization_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS support_audit;
+DROP TABLE IF EXISTS support_consents;
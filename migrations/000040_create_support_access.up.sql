@@ -0,0 +1,33 @@
+-- Support access: a dashboard user grants consent by handing support a single-use token,
+-- which lets one of the SUPPORT_EMAILS staff log in as them for a short session.
+CREATE TABLE IF NOT EXISTS support_consents
+(
+    id              UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    organization_id UUID        NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id         UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash      TEXT        NOT NULL UNIQUE,
+    reason          TEXT        NOT NULL DEFAULT '',
+    expires_at      TIMESTAMPTZ NOT NULL,
+    used_by         TEXT,
+    used_at         TIMESTAMPTZ,
+    revoked_at      TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_consents_org ON support_consents (organization_id, created_at);
+
+-- Audit trail of support access, shown to the organization's members. Emails are copied
+-- so entries stay readable after either side's account is gone.
+CREATE TABLE IF NOT EXISTS support_audit
+(
+    id              UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    organization_id UUID        NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    consent_id      UUID        NOT NULL,
+    event_type      TEXT        NOT NULL CHECK (event_type IN
+        ('consent_granted', 'consent_revoked', 'impersonation_started', 'impersonation_ended')),
+    actor_email     TEXT        NOT NULL,
+    subject_email   TEXT        NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_audit_org ON support_audit (organization_id, created_at);
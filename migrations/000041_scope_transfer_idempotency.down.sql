@@ -0,0 +1,5 @@
+ALTER TABLE transfers
+    DROP CONSTRAINT IF EXISTS transfers_source_ledger_id_idempotency_key_key;
+
+ALTER TABLE transfers
+    ADD CONSTRAINT transfers_project_id_idempotency_key_key UNIQUE (project_id, idempotency_key);
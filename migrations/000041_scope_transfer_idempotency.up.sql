@@ -0,0 +1,8 @@
+-- Transfer idempotency keys were unique per project, so a ledger retrying with a key
+-- another ledger of the project had used got that ledger's transfer back. Scope them
+-- to the source ledger, like transaction keys are scoped to their ledger.
+ALTER TABLE transfers
+    DROP CONSTRAINT IF EXISTS transfers_project_id_idempotency_key_key;
+
+ALTER TABLE transfers
+    ADD CONSTRAINT transfers_source_ledger_id_idempotency_key_key UNIQUE (source_ledger_id, idempotency_key);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS projector_dead_letters;
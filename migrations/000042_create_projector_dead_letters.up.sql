@@ -0,0 +1,16 @@
+-- Events a projector could not apply because of the event itself (an undecodable
+-- payload, a panic while applying it). The projector skips them so the rest of the
+-- stream keeps flowing; once fixed, delete the row and rebuild the projection to
+-- apply the event. Events are archived independently, so there is no foreign key.
+CREATE TABLE IF NOT EXISTS projector_dead_letters
+(
+    projector_name TEXT        NOT NULL,
+    event_id       UUID        NOT NULL,
+    sequence       BIGINT      NOT NULL,
+    ledger_id      UUID        NOT NULL,
+    event_type     TEXT        NOT NULL,
+    error          TEXT        NOT NULL,
+    stack          TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (projector_name, event_id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE webhook_endpoints
+    DROP COLUMN IF EXISTS previous_secret_expires_at,
+    DROP COLUMN IF EXISTS previous_secret;
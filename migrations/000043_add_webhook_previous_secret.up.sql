@@ -0,0 +1,6 @@
+-- A rotated endpoint keeps its previous secret until previous_secret_expires_at; until
+-- then deliveries carry a signature under each secret, so receivers can switch over
+-- without rejecting anything
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS previous_secret            TEXT,
+    ADD COLUMN IF NOT EXISTS previous_secret_expires_at TIMESTAMPTZ;
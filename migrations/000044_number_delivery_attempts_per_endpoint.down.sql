@@ -0,0 +1,2 @@
+-- The job attempts the old numbers came from are gone; the per-endpoint numbers stay
+DROP INDEX IF EXISTS idx_webhook_deliveries_event_endpoint;
@@ -0,0 +1,10 @@
+-- Delivery attempts were numbered by the job's attempt, which also counts retries
+-- caused by the ledger's other endpoints. Renumber them per (event, endpoint), as the
+-- worker now records them.
+UPDATE webhook_deliveries d
+SET attempt = n.attempt
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY event_id, webhook_endpoint_id ORDER BY created_at, id) AS attempt
+      FROM webhook_deliveries) n
+WHERE d.id = n.id AND d.attempt <> n.attempt;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_endpoint ON webhook_deliveries (event_id, webhook_endpoint_id);
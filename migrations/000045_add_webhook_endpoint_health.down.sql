@@ -0,0 +1,4 @@
+ALTER TABLE webhook_endpoints
+    DROP COLUMN IF EXISTS disabled_reason,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS consecutive_failures;
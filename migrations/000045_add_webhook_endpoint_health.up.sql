@@ -0,0 +1,6 @@
+-- Endpoints failing this many deliveries in a row are disabled by the worker;
+-- disabled_at and disabled_reason are only set when it does
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS consecutive_failures INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS disabled_at          TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS disabled_reason      TEXT;
@@ -0,0 +1,9 @@
+-- A ledger's constraints on new account codes, on top of the rules every ledger has
+CREATE TABLE IF NOT EXISTS account_code_rules
+(
+    ledger_id  UUID PRIMARY KEY REFERENCES ledgers (id) ON DELETE CASCADE,
+    pattern    TEXT,
+    max_length INT CHECK (max_length BETWEEN 1 AND 255),
+    case_mode  TEXT        NOT NULL DEFAULT 'preserve' CHECK (case_mode IN ('preserve', 'lower', 'upper')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+ALTER TABLE webhook_deliveries
+    DROP COLUMN IF EXISTS next_attempt_at;
+
+ALTER TABLE webhook_endpoints
+    DROP COLUMN IF EXISTS retry_backoff_base_seconds,
+    DROP COLUMN IF EXISTS retry_backoff,
+    DROP COLUMN IF EXISTS retry_max_attempts;
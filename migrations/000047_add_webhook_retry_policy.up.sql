@@ -0,0 +1,9 @@
+-- Per-endpoint overrides of the worker's retry policy; NULL keeps the default
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS retry_max_attempts         INT CHECK (retry_max_attempts > 0),
+    ADD COLUMN IF NOT EXISTS retry_backoff              TEXT CHECK (retry_backoff IN ('exponential', 'linear', 'fixed')),
+    ADD COLUMN IF NOT EXISTS retry_backoff_base_seconds INT CHECK (retry_backoff_base_seconds > 0);
+
+-- When a failed attempt is due to be retried; NULL once it succeeded or was given up
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ;
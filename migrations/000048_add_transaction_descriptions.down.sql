@@ -0,0 +1,2 @@
+ALTER TABLE postings DROP COLUMN IF EXISTS description;
+ALTER TABLE transactions DROP COLUMN IF EXISTS description;
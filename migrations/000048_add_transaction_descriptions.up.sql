@@ -0,0 +1,4 @@
+-- Free-text descriptions, e.g. "Refund for order 1234"; a posting's falls back to its
+-- transaction's where shown
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE postings ADD COLUMN IF NOT EXISTS description TEXT;
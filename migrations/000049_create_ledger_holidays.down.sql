@@ -0,0 +1,5 @@
+ALTER TABLE schedules
+    DROP COLUMN IF EXISTS holiday_policy,
+    DROP COLUMN IF EXISTS recurrence;
+
+DROP TABLE IF EXISTS ledger_holidays;
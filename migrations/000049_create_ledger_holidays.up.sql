@@ -0,0 +1,15 @@
+-- Non-business days of a ledger's calendar, besides weekends
+CREATE TABLE IF NOT EXISTS ledger_holidays
+(
+    ledger_id UUID NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    date      DATE NOT NULL,
+    name      TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (ledger_id, date)
+);
+
+-- RRULE-style recurrence, used instead of frequency when set, and what happens to
+-- installments falling on a non-business day
+ALTER TABLE schedules
+    ADD COLUMN IF NOT EXISTS recurrence     TEXT,
+    ADD COLUMN IF NOT EXISTS holiday_policy TEXT NOT NULL DEFAULT 'none'
+        CHECK (holiday_policy IN ('none', 'following', 'preceding', 'modified_following', 'skip'));
@@ -0,0 +1,4 @@
+ALTER TABLE settlement_batches DROP COLUMN IF EXISTS basis;
+DROP INDEX IF EXISTS idx_transactions_value_date;
+ALTER TABLE transactions DROP COLUMN IF EXISTS value_date;
+ALTER TABLE ledgers DROP COLUMN IF EXISTS weekend;
@@ -0,0 +1,14 @@
+-- Weekend days of a ledger's business calendar, as day-of-week numbers (0 = Sunday)
+ALTER TABLE ledgers ADD COLUMN IF NOT EXISTS weekend SMALLINT[] NOT NULL DEFAULT '{0,6}';
+
+-- The date a transaction takes effect for interest and settlement, which may differ from
+-- when it occurred (back- or forward-valued). Existing transactions are valued on the
+-- day they occurred.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS value_date DATE;
+UPDATE transactions SET value_date = (occurred_at AT TIME ZONE 'UTC')::date WHERE value_date IS NULL;
+ALTER TABLE transactions ALTER COLUMN value_date SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_value_date ON transactions (ledger_id, value_date);
+
+-- Whether a batch claimed transactions up to the cutoff by occurred_at or value_date
+ALTER TABLE settlement_batches
+    ADD COLUMN IF NOT EXISTS basis TEXT NOT NULL DEFAULT 'occurred_at' CHECK (basis IN ('occurred_at', 'value_date'));
@@ -0,0 +1,10 @@
+-- Operational alerts raised on a ledger and not yet resolved, so each incident is
+-- notified once. subject names what the alert is about, e.g. the lagging projector.
+CREATE TABLE IF NOT EXISTS ledger_alerts
+(
+    ledger_id UUID        NOT NULL REFERENCES ledgers (id) ON DELETE CASCADE,
+    kind      TEXT        NOT NULL,
+    subject   TEXT        NOT NULL,
+    raised_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, kind, subject)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE webhook_endpoints
+    DROP COLUMN IF EXISTS rate_limit_per_second,
+    DROP COLUMN IF EXISTS rate_limit_max_concurrency;
@@ -0,0 +1,4 @@
+-- Per-endpoint overrides of the worker's delivery limits; NULL keeps the default
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS rate_limit_max_concurrency INT CHECK (rate_limit_max_concurrency > 0),
+    ADD COLUMN IF NOT EXISTS rate_limit_per_second      INT CHECK (rate_limit_per_second > 0);
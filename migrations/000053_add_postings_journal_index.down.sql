@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_postings_ledger_created;
@@ -0,0 +1,2 @@
+-- GET /v1/postings pages through a ledger's postings newest first
+CREATE INDEX IF NOT EXISTS idx_postings_ledger_created ON postings (ledger_id, created_at DESC, id DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE webhook_endpoints
+    DROP CONSTRAINT IF EXISTS webhook_endpoints_client_key_check,
+    DROP COLUMN IF EXISTS client_key,
+    DROP COLUMN IF EXISTS client_certificate,
+    DROP COLUMN IF EXISTS custom_headers;
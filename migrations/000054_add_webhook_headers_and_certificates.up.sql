@@ -0,0 +1,7 @@
+-- Static headers added to every delivery, and a client certificate (PEM) presented for
+-- mutual TLS, for receivers behind gateways
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS custom_headers     JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS client_certificate TEXT,
+    ADD COLUMN IF NOT EXISTS client_key         TEXT,
+    ADD CONSTRAINT webhook_endpoints_client_key_check CHECK ((client_certificate IS NULL) = (client_key IS NULL));
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS webhook_batch_items;
+
+ALTER TABLE webhook_endpoints
+    DROP COLUMN IF EXISTS batch_due_at,
+    DROP COLUMN IF EXISTS batch_max_events,
+    DROP COLUMN IF EXISTS batch_window_seconds;
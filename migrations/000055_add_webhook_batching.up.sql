@@ -0,0 +1,19 @@
+-- Endpoints in batch mode receive the events arriving within batch_window_seconds of
+-- the first as one request; NULL delivers each event on its own
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS batch_window_seconds INT CHECK (batch_window_seconds > 0),
+    ADD COLUMN IF NOT EXISTS batch_max_events     INT CHECK (batch_max_events > 0),
+    -- When the pending batch job is due; NULL when none is
+    ADD COLUMN IF NOT EXISTS batch_due_at         TIMESTAMPTZ;
+
+-- Events waiting for an endpoint's next batch
+CREATE TABLE IF NOT EXISTS webhook_batch_items
+(
+    webhook_endpoint_id UUID        NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+    event_id            UUID        NOT NULL,
+    attempts            INT         NOT NULL DEFAULT 0,
+    queued_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (webhook_endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_batch_items_queued ON webhook_batch_items (webhook_endpoint_id, queued_at);
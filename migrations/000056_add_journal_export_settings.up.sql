@@ -0,0 +1,8 @@
+-- How a ledger's accounts and tax codes are named in the accounting system its journal
+-- is exported to; see journal.Mapping.
+CREATE TABLE IF NOT EXISTS journal_export_settings
+(
+    ledger_id  UUID PRIMARY KEY REFERENCES ledgers (id) ON DELETE CASCADE,
+    mapping    JSONB       NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
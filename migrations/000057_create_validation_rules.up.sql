@@ -0,0 +1,7 @@
+-- Per-ledger CEL rules transactions must satisfy to be posted (see package rules)
+CREATE TABLE IF NOT EXISTS validation_rules
+(
+    ledger_id  UUID PRIMARY KEY REFERENCES ledgers (id) ON DELETE CASCADE,
+    rules      JSONB       NOT NULL DEFAULT '{"rules": []}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
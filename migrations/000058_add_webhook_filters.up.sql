@@ -0,0 +1,4 @@
+-- A CEL expression events must satisfy to be delivered to the endpoint; NULL delivers
+-- every event
+ALTER TABLE webhook_endpoints
+    ADD COLUMN IF NOT EXISTS filter TEXT;
@@ -0,0 +1,2 @@
+-- The hashes stay in api_keys only; events are not given them back
+SELECT 1;
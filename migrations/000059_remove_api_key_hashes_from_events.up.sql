@@ -0,0 +1,24 @@
+-- API key hashes are written to api_keys along with the key's first event, not into the
+-- event, which the events API, the event stream and the outbox relay all expose. Keys
+-- whose first event is not projected yet get their row here, before the hashes already
+-- recorded are removed from the events.
+INSERT INTO api_keys (id, ledger_id, key_hash, prefix, description, is_active, status, created_by, created_at)
+SELECT e.aggregate_id,
+       e.ledger_id,
+       e.payload ->> 'key_hash',
+       e.payload ->> 'prefix',
+       NULLIF(e.payload ->> 'description', ''),
+       e.payload ->> 'status' = 'active',
+       e.payload ->> 'status',
+       NULLIF(e.payload ->> 'user_id', '')::uuid,
+       e.occurred_at
+FROM events e
+WHERE e.aggregate_type = 'api_key'
+  AND e.event_type IN ('APIKeyRequested', 'APIKeyCreated')
+  AND e.payload ? 'key_hash'
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE events
+SET payload = payload - 'key_hash'
+WHERE aggregate_type = 'api_key'
+  AND payload ? 'key_hash';
@@ -0,0 +1,42 @@
+#!/usr/bin/env bash
+# Generates the TypeScript and Python clients in sdks/ from the OpenAPI spec, then
+# builds and tests them. The spec, api/openapi.json, is first regenerated from the API's
+# Go types unless SPEC points elsewhere. Only sdks/typescript/src/api.ts and
+# sdks/python/ledger_client/api.py are generated (by cmd/sdkgen); the runtimes and tests
+# next to them are written by hand. With --check it fails instead when the committed
+# spec or clients are out of date (for CI).
+#
+# Requires go; building and testing the clients also requires node and python3.
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+check=false
+if [ "${1:-}" = "--check" ]; then
+	check=true
+fi
+
+if [ -z "${SPEC:-}" ]; then
+	SPEC="api/openapi.json"
+	if $check; then
+		go run ./cmd/openapi | diff - "$SPEC"
+	else
+		go run ./cmd/openapi -o "$SPEC"
+	fi
+fi
+
+if [ ! -f "$SPEC" ]; then
+	echo "no OpenAPI spec at $SPEC (set SPEC to override)" >&2
+	exit 1
+fi
+
+if $check; then
+	go run ./cmd/sdkgen -spec "$SPEC" -o sdks -check
+	echo "SDKs are up to date with $SPEC"
+	exit 0
+fi
+
+go run ./cmd/sdkgen -spec "$SPEC" -o sdks
+
+(cd sdks/typescript && npm install --no-audit --no-fund && npm test)
+(cd sdks/python && python3 -m unittest discover -s tests -t .)
@@ -0,0 +1,11 @@
+"""Python client of the ledger API.
+
+ledger_client.api is generated from the OpenAPI document; the runtime that sends its
+requests is in ledger_client._runtime.
+"""
+
+from ._runtime import LedgerApiError
+from .api import *  # noqa: F401,F403
+from .api import __all__ as _api_all
+
+__all__ = ["LedgerApiError", *_api_all]
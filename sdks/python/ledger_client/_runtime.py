@@ -0,0 +1,88 @@
+"""The hand-written half of the client: api.py is generated from the OpenAPI document
+and sends every operation through BaseClient._request."""
+
+from __future__ import annotations
+
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+from typing import Any, Dict, List, Mapping, Optional
+
+
+class LedgerApiError(Exception):
+    """An error response of the API, decoded from its {"error": {...}} envelope."""
+
+    def __init__(self, status: int, code: str, message: str, details: Optional[List[str]] = None):
+        super().__init__(message)
+        self.status = status
+        self.code = code
+        self.message = message
+        self.details = details or []
+
+
+def quote_path(value: Any) -> str:
+    """Escapes a path parameter, slashes included."""
+    return urllib.parse.quote(str(value), safe="")
+
+
+class BaseClient:
+    def __init__(
+        self,
+        base_url: str,
+        api_key: str,
+        *,
+        headers: Optional[Mapping[str, str]] = None,
+        timeout: float = 30.0,
+    ):
+        self._base_url = base_url.rstrip("/")
+        self._api_key = api_key
+        self._headers = dict(headers or {})
+        self._timeout = timeout
+
+    def _request(
+        self,
+        method: str,
+        path: str,
+        query: Optional[Dict[str, Any]] = None,
+        body: Any = None,
+    ) -> Any:
+        url = self._base_url + path
+        params = {k: _query_value(v) for k, v in (query or {}).items() if v is not None}
+        if params:
+            url += "?" + urllib.parse.urlencode(params)
+
+        headers = {
+            **self._headers,
+            "Accept": "application/json",
+            "Authorization": f"Bearer {self._api_key}",
+        }
+        data = None
+        if body is not None:
+            headers["Content-Type"] = "application/json"
+            data = json.dumps(body).encode()
+
+        request = urllib.request.Request(url, data=data, headers=headers, method=method)
+        try:
+            with urllib.request.urlopen(request, timeout=self._timeout) as resp:
+                text = resp.read().decode()
+        except urllib.error.HTTPError as err:
+            raise _decode_error(err.code, err.reason, err.read().decode()) from None
+        if not text:
+            return None
+        return json.loads(text)
+
+
+def _query_value(value: Any) -> str:
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    return str(value)
+
+
+def _decode_error(status: int, reason: str, text: str) -> LedgerApiError:
+    try:
+        error = json.loads(text)["error"]
+        return LedgerApiError(status, error.get("code", ""), error["message"], error.get("details"))
+    except (ValueError, KeyError, TypeError):
+        # not the error envelope, e.g. a proxy's page
+        return LedgerApiError(status, "", text.strip() or str(reason))
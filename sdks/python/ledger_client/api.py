@@ -0,0 +1,785 @@
+# Code generated by cmd/sdkgen from api/openapi.json. DO NOT EDIT.
+
+from __future__ import annotations
+
+from typing import Any, Dict, List, Literal, NotRequired, Optional, TypedDict
+
+from ._runtime import BaseClient, quote_path
+
+__all__ = [
+    "LedgerClient",
+    "AccountBalanceDiff",
+    "AccountBalanceHistoryResponse",
+    "AccountBatchResult",
+    "AccountConstraintsRequest",
+    "AccountConstraintsResponse",
+    "AccountResponse",
+    "AccountStatusResponse",
+    "BalanceAssertion",
+    "BalanceDiffResponse",
+    "BalanceHistoryPoint",
+    "BalanceRollupNode",
+    "BalanceRollupResponse",
+    "BalanceSnapshotResponse",
+    "BalanceSummaryResponse",
+    "CertificateInfo",
+    "ConvertedBalanceSummary",
+    "CreateAccountRequest",
+    "CreateAccountsRequest",
+    "CreateAccountsResponse",
+    "CreateBalanceSnapshotRequest",
+    "CreateWebhookEndpointRequest",
+    "CreateWebhookEndpointResponse",
+    "ErrorBody",
+    "ErrorResponse",
+    "EventDeliveriesResponse",
+    "EventResponse",
+    "ListEventsResponse",
+    "ListPostingsResponse",
+    "ListTransactionsResponse",
+    "Note",
+    "PaginationResponse",
+    "PostTransactionRequest",
+    "PostTransactionResponse",
+    "PostingDetail",
+    "PostingInput",
+    "PostingResponse",
+    "ReplayWebhookEventsRequest",
+    "ReplayWebhookEventsResponse",
+    "ReplayedDelivery",
+    "RetriedDelivery",
+    "RetryWebhookDeliveriesRequest",
+    "RetryWebhookDeliveriesResponse",
+    "RotateWebhookSecretRequest",
+    "RotateWebhookSecretResponse",
+    "TestWebhookEndpointResponse",
+    "TransactionResponse",
+    "UpdateAccountMetadataRequest",
+    "UpdateAccountMetadataResponse",
+    "UpdateWebhookEndpointRequest",
+    "WebhookBatch",
+    "WebhookClientCertificate",
+    "WebhookDeliveryResponse",
+    "WebhookEndpointResponse",
+    "WebhookRateLimit",
+    "WebhookRetryPolicy",
+]
+
+
+class AccountBalanceDiff(TypedDict):
+    account_code: str
+    account_name: str
+    account_type: str
+    balance_from: str
+    balance_to: str
+    delta: str
+    transaction_count: int
+
+
+class AccountBalanceHistoryResponse(TypedDict):
+    account_code: str
+    history: List[BalanceHistoryPoint]
+
+
+class AccountBatchResult(TypedDict):
+    code: str
+    error: NotRequired[str]
+    id: NotRequired[str]
+    index: int
+    status: str
+
+
+class AccountConstraintsRequest(TypedDict):
+    allow_negative_balance: bool
+    min_balance: NotRequired[str]
+
+
+class AccountConstraintsResponse(TypedDict):
+    allow_negative_balance: bool
+    code: str
+    min_balance: str
+
+
+class AccountResponse(TypedDict):
+    allow_negative_balance: bool
+    available_balance: str
+    balance: str
+    code: str
+    created_at: str
+    entity: NotRequired[str]
+    held_balance: str
+    id: str
+    metadata: Dict[str, Any]
+    min_balance: NotRequired[str]
+    name: str
+    notes: NotRequired[List[Note]]
+    status: str
+    tax_code: NotRequired[str]
+    type: str
+
+
+class AccountStatusResponse(TypedDict):
+    code: str
+    status: str
+
+
+class BalanceAssertion(TypedDict):
+    account: str
+    balance_eq: NotRequired[str]
+    balance_gte: NotRequired[str]
+    balance_lte: NotRequired[str]
+
+
+BalanceDiffResponse = TypedDict(
+    "BalanceDiffResponse",
+    {
+        "accounts": List[AccountBalanceDiff],
+        "from": str,
+        "to": str,
+    },
+)
+
+
+class BalanceHistoryPoint(TypedDict):
+    balance: str
+    date: str
+
+
+class BalanceRollupNode(TypedDict):
+    accounts: int
+    balance: str
+    code: str
+    held_balance: str
+
+
+class BalanceRollupResponse(TypedDict):
+    accounts: int
+    balance: str
+    children: List[BalanceRollupNode]
+    held_balance: str
+    parent: str
+
+
+class BalanceSnapshotResponse(TypedDict):
+    created_at: str
+    id: str
+    name: str
+    taken_at: str
+
+
+class BalanceSummaryResponse(TypedDict):
+    by_type: Dict[str, str]
+    converted: NotRequired[ConvertedBalanceSummary]
+    total_assets: str
+    total_equity: str
+    total_expenses: str
+    total_liabilities: str
+    total_revenue: str
+
+
+class CertificateInfo(TypedDict):
+    not_after: str
+    subject: str
+
+
+class ConvertedBalanceSummary(TypedDict):
+    by_type: Dict[str, str]
+    currency: str
+    missing_rates: NotRequired[List[str]]
+
+
+class CreateAccountRequest(TypedDict):
+    allow_negative_balance: NotRequired[Optional[bool]]
+    code: str
+    entity: NotRequired[str]
+    metadata: NotRequired[Dict[str, Any]]
+    min_balance: NotRequired[str]
+    name: str
+    tax_code: NotRequired[str]
+    type: str
+
+
+class CreateAccountsRequest(TypedDict):
+    accounts: List[CreateAccountRequest]
+
+
+class CreateAccountsResponse(TypedDict):
+    created: int
+    results: List[AccountBatchResult]
+
+
+class CreateBalanceSnapshotRequest(TypedDict):
+    at: NotRequired[Optional[str]]
+    name: str
+
+
+class CreateWebhookEndpointRequest(TypedDict):
+    batch: NotRequired[WebhookBatch]
+    client_certificate: NotRequired[WebhookClientCertificate]
+    filter: NotRequired[str]
+    headers: NotRequired[Dict[str, str]]
+    rate_limit: NotRequired[WebhookRateLimit]
+    retry_policy: NotRequired[WebhookRetryPolicy]
+    url: str
+
+
+class CreateWebhookEndpointResponse(TypedDict):
+    id: str
+    secret: str
+    url: str
+
+
+class ErrorBody(TypedDict):
+    code: str
+    details: NotRequired[List[str]]
+    message: str
+
+
+class ErrorResponse(TypedDict):
+    error: ErrorBody
+
+
+class EventDeliveriesResponse(TypedDict):
+    deliveries: List[WebhookDeliveryResponse]
+    event_fingerprint: str
+    event_id: str
+
+
+class EventResponse(TypedDict):
+    aggregate_id: str
+    aggregate_type: str
+    created_at: str
+    event_type: str
+    id: str
+    occurred_at: str
+    payload: Dict[str, Any]
+    sequence: int
+
+
+class ListEventsResponse(TypedDict):
+    events: List[EventResponse]
+    pagination: PaginationResponse
+
+
+class ListPostingsResponse(TypedDict):
+    pagination: PaginationResponse
+    postings: List[PostingResponse]
+
+
+class ListTransactionsResponse(TypedDict):
+    pagination: PaginationResponse
+    transactions: List[TransactionResponse]
+
+
+class Note(TypedDict):
+    author_email: str
+    body: str
+    created_at: str
+
+
+class PaginationResponse(TypedDict):
+    continuation_token: NotRequired[str]
+    count: int
+    has_more: bool
+
+
+class PostTransactionRequest(TypedDict):
+    assertions: NotRequired[List[BalanceAssertion]]
+    currency: str
+    description: NotRequired[str]
+    entity: NotRequired[str]
+    external_id: str
+    idempotency_key: str
+    metadata: NotRequired[Dict[str, Any]]
+    occurred_at: str
+    postings: List[PostingInput]
+    script: NotRequired[str]
+    value_date: NotRequired[str]
+    vars: NotRequired[Dict[str, str]]
+
+
+class PostTransactionResponse(TypedDict):
+    status: str
+    transaction_id: str
+
+
+class PostingDetail(TypedDict):
+    account_code: str
+    account_name: str
+    amount: str
+    currency: str
+    description: NotRequired[str]
+    direction: str
+    id: str
+    tax_code: NotRequired[str]
+
+
+class PostingInput(TypedDict):
+    account_code: str
+    amount: str
+    currency: NotRequired[str]
+    description: NotRequired[str]
+    direction: str
+    tax_code: NotRequired[str]
+
+
+class PostingResponse(TypedDict):
+    account_code: str
+    account_name: str
+    amount: str
+    created_at: str
+    currency: str
+    description: NotRequired[str]
+    direction: str
+    external_id: str
+    id: str
+    occurred_at: str
+    tax_code: NotRequired[str]
+    transaction_id: str
+    value_date: str
+
+
+class ReplayWebhookEventsRequest(TypedDict):
+    event_type: NotRequired[str]
+    from_sequence: int
+    limit: NotRequired[int]
+    secret: NotRequired[str]
+    to_sequence: NotRequired[int]
+    url: str
+
+
+class ReplayWebhookEventsResponse(TypedDict):
+    deliveries: List[ReplayedDelivery]
+    next_from_sequence: NotRequired[int]
+    secret: str
+
+
+class ReplayedDelivery(TypedDict):
+    error_message: NotRequired[str]
+    event_id: str
+    event_type: str
+    http_status: int
+    sequence: int
+    status: str
+
+
+class RetriedDelivery(TypedDict):
+    delivery_id: str
+    duplicate: NotRequired[bool]
+    event_id: str
+    webhook_endpoint_id: str
+
+
+RetryWebhookDeliveriesRequest = TypedDict(
+    "RetryWebhookDeliveriesRequest",
+    {
+        "endpoint_id": NotRequired[str],
+        "event_ids": NotRequired[List[str]],
+        "from": NotRequired[str],
+        "to": NotRequired[str],
+    },
+)
+
+
+class RetryWebhookDeliveriesResponse(TypedDict):
+    more: NotRequired[bool]
+    retries: List[RetriedDelivery]
+
+
+class RotateWebhookSecretRequest(TypedDict):
+    grace_period_hours: NotRequired[int]
+
+
+class RotateWebhookSecretResponse(TypedDict):
+    id: str
+    previous_secret_expires_at: str
+    secret: str
+    url: str
+
+
+class TestWebhookEndpointResponse(TypedDict):
+    error_message: NotRequired[str]
+    event_id: str
+    http_status: int
+    latency_ms: int
+    status: str
+
+
+class TransactionResponse(TypedDict):
+    amount: str
+    created_at: str
+    currency: str
+    description: NotRequired[str]
+    entity: NotRequired[str]
+    external_id: str
+    id: str
+    metadata: Dict[str, Any]
+    notes: NotRequired[List[Note]]
+    occurred_at: str
+    postings: List[PostingDetail]
+    value_date: str
+
+
+class UpdateAccountMetadataRequest(TypedDict):
+    metadata: Dict[str, Any]
+
+
+class UpdateAccountMetadataResponse(TypedDict):
+    code: str
+    id: str
+    status: str
+
+
+class UpdateWebhookEndpointRequest(TypedDict):
+    batch: NotRequired[WebhookBatch]
+    client_certificate: NotRequired[WebhookClientCertificate]
+    filter: NotRequired[Optional[str]]
+    headers: NotRequired[Dict[str, str]]
+    is_active: NotRequired[Optional[bool]]
+    rate_limit: NotRequired[WebhookRateLimit]
+    retry_policy: NotRequired[WebhookRetryPolicy]
+    url: NotRequired[Optional[str]]
+
+
+class WebhookBatch(TypedDict):
+    max_events: NotRequired[Optional[int]]
+    window_seconds: NotRequired[Optional[int]]
+
+
+class WebhookClientCertificate(TypedDict):
+    certificate: str
+    private_key: str
+
+
+class WebhookDeliveryResponse(TypedDict):
+    attempt: int
+    delivery_id: str
+    endpoint_url: str
+    error_message: NotRequired[str]
+    event_id: str
+    http_status: int
+    id: str
+    last_attempt_at: str
+    next_attempt_at: NotRequired[str]
+    status: str
+    webhook_endpoint_id: str
+
+
+class WebhookEndpointResponse(TypedDict):
+    batch: WebhookBatch
+    client_certificate: NotRequired[CertificateInfo]
+    consecutive_failures: int
+    created_at: str
+    disabled_at: NotRequired[str]
+    disabled_reason: NotRequired[str]
+    filter: NotRequired[str]
+    headers: List[str]
+    id: str
+    is_active: bool
+    rate_limit: WebhookRateLimit
+    retry_policy: WebhookRetryPolicy
+    url: str
+
+
+class WebhookRateLimit(TypedDict):
+    max_concurrency: NotRequired[Optional[int]]
+    per_second: NotRequired[Optional[int]]
+
+
+class WebhookRetryPolicy(TypedDict):
+    backoff: NotRequired[Optional[str]]
+    base_seconds: NotRequired[Optional[int]]
+    max_attempts: NotRequired[Optional[int]]
+
+
+class LedgerClient(BaseClient):
+    """A client of the ledger API, authenticated with an API key."""
+
+    def list_accounts(
+        self,
+        *,
+        code: Optional[str] = None,
+        status: Optional[Literal["active", "disabled", "all"]] = None,
+        entity: Optional[str] = None,
+        parent: Optional[str] = None,
+    ) -> List[AccountResponse]:
+        """List accounts, or get one by code
+
+        With code, replies with that AccountResponse, including its notes.
+        """
+        return self._request(
+            "GET",
+            "/v1/accounts",
+            query={"code": code, "status": status, "entity": entity, "parent": parent},
+        )
+
+    def create_account(self, body: CreateAccountRequest) -> Dict[str, Any]:
+        """Create an account"""
+        return self._request("POST", "/v1/accounts", body=body)
+
+    def update_account_metadata(
+        self,
+        body: UpdateAccountMetadataRequest,
+        *,
+        code: str,
+    ) -> UpdateAccountMetadataResponse:
+        """Update an account's metadata as a JSON merge patch; null removes a key"""
+        return self._request("PATCH", "/v1/accounts", query={"code": code}, body=body)
+
+    def get_account_balance_history(
+        self,
+        *,
+        code: str,
+        basis: Optional[Literal["occurred_at", "value_date"]] = None,
+    ) -> AccountBalanceHistoryResponse:
+        """Daily balance history of an account"""
+        return self._request(
+            "GET",
+            "/v1/accounts/balance-history",
+            query={"code": code, "basis": basis},
+        )
+
+    def create_accounts(self, body: CreateAccountsRequest) -> CreateAccountsResponse:
+        """Create many accounts at once
+
+        Either every new account is created or, when any item is invalid, none is and the reply is a 422 with the same body naming the invalid items.
+        """
+        return self._request("POST", "/v1/accounts/batch", body=body)
+
+    def set_account_constraints(
+        self,
+        body: AccountConstraintsRequest,
+        *,
+        code: str,
+    ) -> AccountConstraintsResponse:
+        """Set an account's overdraft protection"""
+        return self._request("PUT", "/v1/accounts/constraints", query={"code": code}, body=body)
+
+    def disable_account(self, *, code: str) -> AccountStatusResponse:
+        """Retire an account with a zero balance"""
+        return self._request("POST", "/v1/accounts/disable", query={"code": code})
+
+    def enable_account(self, *, code: str) -> AccountStatusResponse:
+        """Accept postings to a disabled account again"""
+        return self._request("POST", "/v1/accounts/enable", query={"code": code})
+
+    def get_balance_diff(
+        self,
+        *,
+        from_: Optional[str] = None,
+        to: Optional[str] = None,
+        from_snapshot: Optional[str] = None,
+        to_snapshot: Optional[str] = None,
+        changed_only: Optional[bool] = None,
+    ) -> BalanceDiffResponse:
+        """Per-account balance movement between two timestamps or snapshots"""
+        return self._request(
+            "GET",
+            "/v1/balance/diff",
+            query={
+                "from": from_,
+                "to": to,
+                "from_snapshot": from_snapshot,
+                "to_snapshot": to_snapshot,
+                "changed_only": changed_only,
+            },
+        )
+
+    def get_balance_rollup(
+        self,
+        *,
+        parent: Optional[str] = None,
+        depth: Optional[int] = None,
+    ) -> BalanceRollupResponse:
+        """Balances aggregated by account code segments"""
+        return self._request("GET", "/v1/balance/rollup", query={"parent": parent, "depth": depth})
+
+    def list_balance_snapshots(self) -> List[BalanceSnapshotResponse]:
+        """List balance snapshots"""
+        return self._request("GET", "/v1/balance/snapshots")
+
+    def create_balance_snapshot(
+        self,
+        body: CreateBalanceSnapshotRequest,
+    ) -> BalanceSnapshotResponse:
+        """Freeze every account balance as of a point in time"""
+        return self._request("POST", "/v1/balance/snapshots", body=body)
+
+    def get_balance_summary(self, *, currency: Optional[str] = None) -> BalanceSummaryResponse:
+        """Balances totaled by account type"""
+        return self._request("GET", "/v1/balance/summary", query={"currency": currency})
+
+    def list_events(
+        self,
+        *,
+        id: Optional[str] = None,
+        limit: Optional[int] = None,
+        continuation_token: Optional[str] = None,
+        event_type: Optional[str] = None,
+        aggregate_id: Optional[str] = None,
+    ) -> ListEventsResponse:
+        """List events in sequence order, or get one by id
+
+        With id, replies with that EventResponse. With Accept: application/x-ndjson every matching event is streamed, one per line.
+        """
+        return self._request(
+            "GET",
+            "/v1/events",
+            query={
+                "id": id,
+                "limit": limit,
+                "continuation_token": continuation_token,
+                "event_type": event_type,
+                "aggregate_id": aggregate_id,
+            },
+        )
+
+    def list_event_deliveries(self, id: str) -> EventDeliveriesResponse:
+        """Delivery attempts of one event, oldest first"""
+        return self._request("GET", f"/v1/events/{quote_path(id)}/deliveries")
+
+    def list_postings(
+        self,
+        *,
+        limit: Optional[int] = None,
+        continuation_token: Optional[str] = None,
+        account: Optional[str] = None,
+        direction: Optional[Literal["debit", "credit"]] = None,
+        currency: Optional[str] = None,
+        min_amount: Optional[str] = None,
+        max_amount: Optional[str] = None,
+        start_time: Optional[str] = None,
+        end_time: Optional[str] = None,
+    ) -> ListPostingsResponse:
+        """List postings across transactions, newest first"""
+        return self._request(
+            "GET",
+            "/v1/postings",
+            query={
+                "limit": limit,
+                "continuation_token": continuation_token,
+                "account": account,
+                "direction": direction,
+                "currency": currency,
+                "min_amount": min_amount,
+                "max_amount": max_amount,
+                "start_time": start_time,
+                "end_time": end_time,
+            },
+        )
+
+    def list_transactions(
+        self,
+        *,
+        id: Optional[str] = None,
+        limit: Optional[int] = None,
+        continuation_token: Optional[str] = None,
+        start_time: Optional[str] = None,
+        end_time: Optional[str] = None,
+        value_from: Optional[str] = None,
+        value_to: Optional[str] = None,
+        entity: Optional[str] = None,
+        confirm_expensive: Optional[bool] = None,
+    ) -> ListTransactionsResponse:
+        """List transactions, or get one by id
+
+        With id, replies with that TransactionResponse. With Accept: application/x-ndjson every matching transaction is streamed, one per line. metadata[key]=value filters match metadata values.
+        """
+        return self._request(
+            "GET",
+            "/v1/transactions",
+            query={
+                "id": id,
+                "limit": limit,
+                "continuation_token": continuation_token,
+                "start_time": start_time,
+                "end_time": end_time,
+                "value_from": value_from,
+                "value_to": value_to,
+                "entity": entity,
+                "confirm_expensive": confirm_expensive,
+            },
+        )
+
+    def post_transaction(
+        self,
+        body: PostTransactionRequest,
+        *,
+        dry_run: Optional[bool] = None,
+        explain: Optional[bool] = None,
+    ) -> PostTransactionResponse:
+        """Post a transaction
+
+        Postings must balance per currency. Retrying with the same idempotency_key returns the first transaction. A transaction held by screening is answered with 202 and its review id. Rules refusing a transaction (unknown or disabled accounts, overdraft, KYC, assertions, the ledger's validation rules, screening, posting hooks) reply 422 with a specific error code.
+        """
+        return self._request(
+            "POST",
+            "/v1/transactions",
+            query={"dry_run": dry_run, "explain": explain},
+            body=body,
+        )
+
+    def list_webhook_deliveries(
+        self,
+        *,
+        limit: Optional[int] = None,
+    ) -> List[WebhookDeliveryResponse]:
+        """List delivery attempts, newest first"""
+        return self._request("GET", "/v1/webhook-deliveries", query={"limit": limit})
+
+    def retry_webhook_deliveries(
+        self,
+        body: RetryWebhookDeliveriesRequest,
+    ) -> RetryWebhookDeliveriesResponse:
+        """Deliver failed events again in bulk"""
+        return self._request("POST", "/v1/webhook-deliveries/retry", body=body)
+
+    def retry_webhook_delivery(self, id: str) -> RetriedDelivery:
+        """Deliver a failed delivery's event again"""
+        return self._request("POST", f"/v1/webhook-deliveries/{quote_path(id)}/retry")
+
+    def list_webhook_endpoints(self) -> List[WebhookEndpointResponse]:
+        """List webhook endpoints"""
+        return self._request("GET", "/v1/webhook-endpoints")
+
+    def create_webhook_endpoint(
+        self,
+        body: CreateWebhookEndpointRequest,
+    ) -> CreateWebhookEndpointResponse:
+        """Register a webhook endpoint; the reply holds its signing secret"""
+        return self._request("POST", "/v1/webhook-endpoints", body=body)
+
+    def update_webhook_endpoint(
+        self,
+        id: str,
+        body: UpdateWebhookEndpointRequest,
+    ) -> WebhookEndpointResponse:
+        """Change an endpoint's URL, state, retry policy, rate limit, batch mode, headers, client certificate or filter"""
+        return self._request("PATCH", f"/v1/webhook-endpoints/{quote_path(id)}", body=body)
+
+    def delete_webhook_endpoint(self, id: str) -> None:
+        """Delete an endpoint along with its delivery history"""
+        return self._request("DELETE", f"/v1/webhook-endpoints/{quote_path(id)}")
+
+    def rotate_webhook_secret(
+        self,
+        id: str,
+        body: RotateWebhookSecretRequest,
+    ) -> RotateWebhookSecretResponse:
+        """Issue a new signing secret; the old one stays valid for the grace period"""
+        return self._request(
+            "POST",
+            f"/v1/webhook-endpoints/{quote_path(id)}/rotate-secret",
+            body=body,
+        )
+
+    def test_webhook_endpoint(self, id: str) -> TestWebhookEndpointResponse:
+        """Send a signed WebhookTest event to an endpoint"""
+        return self._request("POST", f"/v1/webhook-endpoints/{quote_path(id)}/test")
+
+    def replay_webhook_events(
+        self,
+        body: ReplayWebhookEventsRequest,
+    ) -> ReplayWebhookEventsResponse:
+        """Send a range of historical events to a staging URL"""
+        return self._request("POST", "/v1/webhook-replays", body=body)
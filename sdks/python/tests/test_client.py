@@ -0,0 +1,116 @@
+import json
+import threading
+import unittest
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+from ledger_client import LedgerApiError, LedgerClient
+
+
+class _Handler(BaseHTTPRequestHandler):
+    def _serve(self):
+        length = int(self.headers.get("Content-Length") or 0)
+        self.server.requests.append(
+            {
+                "method": self.command,
+                "path": self.path,
+                "headers": dict(self.headers),
+                "body": self.rfile.read(length).decode(),
+            }
+        )
+        status, body = self.server.reply
+        self.send_response(status)
+        if body is not None:
+            payload = json.dumps(body).encode()
+            self.send_header("Content-Type", "application/json")
+            self.send_header("Content-Length", str(len(payload)))
+            self.end_headers()
+            self.wfile.write(payload)
+        else:
+            self.end_headers()
+
+    do_GET = do_POST = do_PATCH = do_DELETE = _serve
+
+    def log_message(self, format, *args):
+        pass
+
+
+class LedgerClientTest(unittest.TestCase):
+    @classmethod
+    def setUpClass(cls):
+        cls.server = HTTPServer(("127.0.0.1", 0), _Handler)
+        threading.Thread(target=cls.server.serve_forever, daemon=True).start()
+        cls.client = LedgerClient(f"http://127.0.0.1:{cls.server.server_port}/", "secret-key")
+
+    @classmethod
+    def tearDownClass(cls):
+        cls.server.shutdown()
+        cls.server.server_close()
+
+    def respond(self, status, body):
+        self.server.requests = []
+        self.server.reply = (status, body)
+
+    def test_query_parameters_and_api_key(self):
+        self.respond(200, [{"code": "assets:cash", "name": "Cash", "balance": "10.00"}])
+
+        accounts = self.client.list_accounts(status="all", entity=None)
+
+        self.assertEqual(accounts[0]["code"], "assets:cash")
+        request = self.server.requests[0]
+        self.assertEqual(request["method"], "GET")
+        self.assertEqual(request["path"], "/v1/accounts?status=all")
+        self.assertEqual(request["headers"]["Authorization"], "Bearer secret-key")
+
+    def test_renamed_keyword_parameter(self):
+        self.respond(200, {"from": "2026-01-01", "to": "2026-02-01", "accounts": []})
+
+        diff = self.client.get_balance_diff(from_="2026-01-01", to="2026-02-01", changed_only=True)
+
+        self.assertEqual(diff["from"], "2026-01-01")
+        self.assertEqual(
+            self.server.requests[0]["path"],
+            "/v1/balance/diff?from=2026-01-01&to=2026-02-01&changed_only=true",
+        )
+
+    def test_path_parameter_and_body(self):
+        self.respond(200, {"id": "wh/1", "url": "https://example.com/hook"})
+
+        self.client.update_webhook_endpoint("wh/1", {"url": "https://example.com/hook"})
+
+        request = self.server.requests[0]
+        self.assertEqual(request["method"], "PATCH")
+        self.assertEqual(request["path"], "/v1/webhook-endpoints/wh%2F1")
+        self.assertEqual(request["headers"]["Content-Type"], "application/json")
+        self.assertEqual(json.loads(request["body"]), {"url": "https://example.com/hook"})
+
+    def test_no_content(self):
+        self.respond(204, None)
+
+        self.assertIsNone(self.client.delete_webhook_endpoint("wh_1"))
+        self.assertEqual(self.server.requests[0]["method"], "DELETE")
+
+    def test_error_envelope(self):
+        self.respond(
+            400,
+            {
+                "error": {
+                    "code": "VALIDATION_FAILED",
+                    "message": "2 errors",
+                    "details": ["amount is required", "currency is required"],
+                }
+            },
+        )
+        transaction = {"currency": "USD", "external_id": "ext-1", "idempotency_key": "key-1", "postings": []}
+
+        with self.assertRaises(LedgerApiError) as raised:
+            self.client.post_transaction(transaction, dry_run=True)
+
+        self.assertEqual(raised.exception.status, 400)
+        self.assertEqual(raised.exception.code, "VALIDATION_FAILED")
+        self.assertEqual(raised.exception.message, "2 errors")
+        self.assertEqual(raised.exception.details, ["amount is required", "currency is required"])
+        self.assertEqual(self.server.requests[0]["path"], "/v1/transactions?dry_run=true")
+
+
+if __name__ == "__main__":
+    unittest.main()
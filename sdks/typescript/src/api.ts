@@ -0,0 +1,751 @@
+// Code generated by cmd/sdkgen from api/openapi.json. DO NOT EDIT.
+
+import { BaseClient } from "./runtime.js";
+
+export interface AccountBalanceDiff {
+  account_code: string;
+  account_name: string;
+  account_type: string;
+  balance_from: string;
+  balance_to: string;
+  delta: string;
+  transaction_count: number;
+}
+
+export interface AccountBalanceHistoryResponse {
+  account_code: string;
+  history: BalanceHistoryPoint[];
+}
+
+export interface AccountBatchResult {
+  code: string;
+  error?: string;
+  id?: string;
+  index: number;
+  status: string;
+}
+
+export interface AccountConstraintsRequest {
+  allow_negative_balance: boolean;
+  min_balance?: string;
+}
+
+export interface AccountConstraintsResponse {
+  allow_negative_balance: boolean;
+  code: string;
+  min_balance: string;
+}
+
+export interface AccountResponse {
+  allow_negative_balance: boolean;
+  available_balance: string;
+  balance: string;
+  code: string;
+  created_at: string;
+  entity?: string;
+  held_balance: string;
+  id: string;
+  metadata: { [key: string]: unknown };
+  min_balance?: string;
+  name: string;
+  notes?: Note[];
+  status: string;
+  tax_code?: string;
+  type: string;
+}
+
+export interface AccountStatusResponse {
+  code: string;
+  status: string;
+}
+
+export interface BalanceAssertion {
+  account: string;
+  balance_eq?: string;
+  balance_gte?: string;
+  balance_lte?: string;
+}
+
+export interface BalanceDiffResponse {
+  accounts: AccountBalanceDiff[];
+  from: string;
+  to: string;
+}
+
+export interface BalanceHistoryPoint {
+  balance: string;
+  date: string;
+}
+
+export interface BalanceRollupNode {
+  accounts: number;
+  balance: string;
+  code: string;
+  held_balance: string;
+}
+
+export interface BalanceRollupResponse {
+  accounts: number;
+  balance: string;
+  children: BalanceRollupNode[];
+  held_balance: string;
+  parent: string;
+}
+
+export interface BalanceSnapshotResponse {
+  created_at: string;
+  id: string;
+  name: string;
+  taken_at: string;
+}
+
+export interface BalanceSummaryResponse {
+  by_type: { [key: string]: string };
+  converted?: ConvertedBalanceSummary;
+  total_assets: string;
+  total_equity: string;
+  total_expenses: string;
+  total_liabilities: string;
+  total_revenue: string;
+}
+
+export interface CertificateInfo {
+  not_after: string;
+  subject: string;
+}
+
+export interface ConvertedBalanceSummary {
+  by_type: { [key: string]: string };
+  currency: string;
+  missing_rates?: string[];
+}
+
+export interface CreateAccountRequest {
+  allow_negative_balance?: boolean | null;
+  code: string;
+  entity?: string;
+  metadata?: { [key: string]: unknown };
+  min_balance?: string;
+  name: string;
+  tax_code?: string;
+  type: string;
+}
+
+export interface CreateAccountsRequest {
+  accounts: CreateAccountRequest[];
+}
+
+export interface CreateAccountsResponse {
+  created: number;
+  results: AccountBatchResult[];
+}
+
+export interface CreateBalanceSnapshotRequest {
+  at?: string | null;
+  name: string;
+}
+
+export interface CreateWebhookEndpointRequest {
+  batch?: WebhookBatch;
+  client_certificate?: WebhookClientCertificate;
+  filter?: string;
+  headers?: { [key: string]: string };
+  rate_limit?: WebhookRateLimit;
+  retry_policy?: WebhookRetryPolicy;
+  url: string;
+}
+
+export interface CreateWebhookEndpointResponse {
+  id: string;
+  secret: string;
+  url: string;
+}
+
+export interface ErrorBody {
+  code: string;
+  details?: string[];
+  message: string;
+}
+
+export interface ErrorResponse {
+  error: ErrorBody;
+}
+
+export interface EventDeliveriesResponse {
+  deliveries: WebhookDeliveryResponse[];
+  event_fingerprint: string;
+  event_id: string;
+}
+
+export interface EventResponse {
+  aggregate_id: string;
+  aggregate_type: string;
+  created_at: string;
+  event_type: string;
+  id: string;
+  occurred_at: string;
+  payload: { [key: string]: unknown };
+  sequence: number;
+}
+
+export interface ListEventsResponse {
+  events: EventResponse[];
+  pagination: PaginationResponse;
+}
+
+export interface ListPostingsResponse {
+  pagination: PaginationResponse;
+  postings: PostingResponse[];
+}
+
+export interface ListTransactionsResponse {
+  pagination: PaginationResponse;
+  transactions: TransactionResponse[];
+}
+
+export interface Note {
+  author_email: string;
+  body: string;
+  created_at: string;
+}
+
+export interface PaginationResponse {
+  continuation_token?: string;
+  count: number;
+  has_more: boolean;
+}
+
+export interface PostTransactionRequest {
+  assertions?: BalanceAssertion[];
+  currency: string;
+  description?: string;
+  entity?: string;
+  external_id: string;
+  idempotency_key: string;
+  metadata?: { [key: string]: unknown };
+  occurred_at: string;
+  postings: PostingInput[];
+  script?: string;
+  value_date?: string;
+  vars?: { [key: string]: string };
+}
+
+export interface PostTransactionResponse {
+  status: string;
+  transaction_id: string;
+}
+
+export interface PostingDetail {
+  account_code: string;
+  account_name: string;
+  amount: string;
+  currency: string;
+  description?: string;
+  direction: string;
+  id: string;
+  tax_code?: string;
+}
+
+export interface PostingInput {
+  account_code: string;
+  amount: string;
+  currency?: string;
+  description?: string;
+  direction: string;
+  tax_code?: string;
+}
+
+export interface PostingResponse {
+  account_code: string;
+  account_name: string;
+  amount: string;
+  created_at: string;
+  currency: string;
+  description?: string;
+  direction: string;
+  external_id: string;
+  id: string;
+  occurred_at: string;
+  tax_code?: string;
+  transaction_id: string;
+  value_date: string;
+}
+
+export interface ReplayWebhookEventsRequest {
+  event_type?: string;
+  from_sequence: number;
+  limit?: number;
+  secret?: string;
+  to_sequence?: number;
+  url: string;
+}
+
+export interface ReplayWebhookEventsResponse {
+  deliveries: ReplayedDelivery[];
+  next_from_sequence?: number;
+  secret: string;
+}
+
+export interface ReplayedDelivery {
+  error_message?: string;
+  event_id: string;
+  event_type: string;
+  http_status: number;
+  sequence: number;
+  status: string;
+}
+
+export interface RetriedDelivery {
+  delivery_id: string;
+  duplicate?: boolean;
+  event_id: string;
+  webhook_endpoint_id: string;
+}
+
+export interface RetryWebhookDeliveriesRequest {
+  endpoint_id?: string;
+  event_ids?: string[];
+  from?: string;
+  to?: string;
+}
+
+export interface RetryWebhookDeliveriesResponse {
+  more?: boolean;
+  retries: RetriedDelivery[];
+}
+
+export interface RotateWebhookSecretRequest {
+  grace_period_hours?: number;
+}
+
+export interface RotateWebhookSecretResponse {
+  id: string;
+  previous_secret_expires_at: string;
+  secret: string;
+  url: string;
+}
+
+export interface TestWebhookEndpointResponse {
+  error_message?: string;
+  event_id: string;
+  http_status: number;
+  latency_ms: number;
+  status: string;
+}
+
+export interface TransactionResponse {
+  amount: string;
+  created_at: string;
+  currency: string;
+  description?: string;
+  entity?: string;
+  external_id: string;
+  id: string;
+  metadata: { [key: string]: unknown };
+  notes?: Note[];
+  occurred_at: string;
+  postings: PostingDetail[];
+  value_date: string;
+}
+
+export interface UpdateAccountMetadataRequest {
+  metadata: { [key: string]: unknown };
+}
+
+export interface UpdateAccountMetadataResponse {
+  code: string;
+  id: string;
+  status: string;
+}
+
+export interface UpdateWebhookEndpointRequest {
+  batch?: WebhookBatch;
+  client_certificate?: WebhookClientCertificate;
+  filter?: string | null;
+  headers?: { [key: string]: string };
+  is_active?: boolean | null;
+  rate_limit?: WebhookRateLimit;
+  retry_policy?: WebhookRetryPolicy;
+  url?: string | null;
+}
+
+export interface WebhookBatch {
+  max_events?: number | null;
+  window_seconds?: number | null;
+}
+
+export interface WebhookClientCertificate {
+  certificate: string;
+  private_key: string;
+}
+
+export interface WebhookDeliveryResponse {
+  attempt: number;
+  delivery_id: string;
+  endpoint_url: string;
+  error_message?: string;
+  event_id: string;
+  http_status: number;
+  id: string;
+  last_attempt_at: string;
+  next_attempt_at?: string;
+  status: string;
+  webhook_endpoint_id: string;
+}
+
+export interface WebhookEndpointResponse {
+  batch: WebhookBatch;
+  client_certificate?: CertificateInfo;
+  consecutive_failures: number;
+  created_at: string;
+  disabled_at?: string;
+  disabled_reason?: string;
+  filter?: string;
+  headers: string[];
+  id: string;
+  is_active: boolean;
+  rate_limit: WebhookRateLimit;
+  retry_policy: WebhookRetryPolicy;
+  url: string;
+}
+
+export interface WebhookRateLimit {
+  max_concurrency?: number | null;
+  per_second?: number | null;
+}
+
+export interface WebhookRetryPolicy {
+  backoff?: string | null;
+  base_seconds?: number | null;
+  max_attempts?: number | null;
+}
+
+/** Parameters of {@link LedgerClient.listAccounts}. */
+export interface ListAccountsParams {
+  /** Get this account instead of listing */
+  code?: string;
+  /** Default active */
+  status?: "active" | "disabled" | "all";
+  /** Entity code linked to the accounts */
+  entity?: string;
+  /** Only accounts whose code is parent or starts with parent: */
+  parent?: string;
+}
+
+/** Parameters of {@link LedgerClient.updateAccountMetadata}. */
+export interface UpdateAccountMetadataParams {
+  /** Account code */
+  code: string;
+}
+
+/** Parameters of {@link LedgerClient.getAccountBalanceHistory}. */
+export interface GetAccountBalanceHistoryParams {
+  /** Account code */
+  code: string;
+  /** Date transactions by the day they occurred (default) or by their value date */
+  basis?: "occurred_at" | "value_date";
+}
+
+/** Parameters of {@link LedgerClient.setAccountConstraints}. */
+export interface SetAccountConstraintsParams {
+  /** Account code */
+  code: string;
+}
+
+/** Parameters of {@link LedgerClient.disableAccount}. */
+export interface DisableAccountParams {
+  /** Account code */
+  code: string;
+}
+
+/** Parameters of {@link LedgerClient.enableAccount}. */
+export interface EnableAccountParams {
+  /** Account code */
+  code: string;
+}
+
+/** Parameters of {@link LedgerClient.getBalanceDiff}. */
+export interface GetBalanceDiffParams {
+  from?: string;
+  to?: string;
+  /** Snapshot id, instead of from */
+  from_snapshot?: string;
+  /** Snapshot id, instead of to */
+  to_snapshot?: string;
+  changed_only?: boolean;
+}
+
+/** Parameters of {@link LedgerClient.getBalanceRollup}. */
+export interface GetBalanceRollupParams {
+  /** Code whose children are totaled; the whole ledger when empty */
+  parent?: string;
+  /** Segments below parent to group by (default 1) */
+  depth?: number;
+}
+
+/** Parameters of {@link LedgerClient.getBalanceSummary}. */
+export interface GetBalanceSummaryParams {
+  /** Also convert every balance to this currency */
+  currency?: string;
+}
+
+/** Parameters of {@link LedgerClient.listEvents}. */
+export interface ListEventsParams {
+  /** Get this event instead of listing */
+  id?: string;
+  /** Page size, at most 1000 (default 100) */
+  limit?: number;
+  /** pagination.continuation_token of the previous page */
+  continuation_token?: string;
+  event_type?: string;
+  aggregate_id?: string;
+}
+
+/** Parameters of {@link LedgerClient.listEventDeliveries}. */
+export interface ListEventDeliveriesParams {
+  id: string;
+}
+
+/** Parameters of {@link LedgerClient.listPostings}. */
+export interface ListPostingsParams {
+  /** Page size, at most 1000 (default 100) */
+  limit?: number;
+  /** pagination.continuation_token of the previous page */
+  continuation_token?: string;
+  /** Account code */
+  account?: string;
+  direction?: "debit" | "credit";
+  currency?: string;
+  /** Inclusive */
+  min_amount?: string;
+  /** Inclusive */
+  max_amount?: string;
+  /** Transaction occurred_at lower bound, inclusive */
+  start_time?: string;
+  /** Transaction occurred_at upper bound, inclusive */
+  end_time?: string;
+}
+
+/** Parameters of {@link LedgerClient.listTransactions}. */
+export interface ListTransactionsParams {
+  /** Get this transaction instead of listing */
+  id?: string;
+  /** Page size, at most 1000 (default 100) */
+  limit?: number;
+  /** pagination.continuation_token of the previous page */
+  continuation_token?: string;
+  /** occurred_at lower bound, inclusive */
+  start_time?: string;
+  /** occurred_at upper bound, inclusive */
+  end_time?: string;
+  /** Value date lower bound, inclusive */
+  value_from?: string;
+  /** Value date upper bound, inclusive */
+  value_to?: string;
+  /** Entity code of the counterparty */
+  entity?: string;
+  /** Allow metadata filters without a bounded time range on large ledgers */
+  confirm_expensive?: boolean;
+}
+
+/** Parameters of {@link LedgerClient.postTransaction}. */
+export interface PostTransactionParams {
+  /** Validate and return the balance impact without posting; replies with a PreviewTransactionResponse */
+  dry_run?: boolean;
+  /** With dry_run, add the evaluation trace (accounts, lock order, checks, balance checks, screening); refusals reply with an ExplainedErrorResponse */
+  explain?: boolean;
+}
+
+/** Parameters of {@link LedgerClient.listWebhookDeliveries}. */
+export interface ListWebhookDeliveriesParams {
+  /** Page size, at most 1000 (default 100) */
+  limit?: number;
+}
+
+/** Parameters of {@link LedgerClient.retryWebhookDelivery}. */
+export interface RetryWebhookDeliveryParams {
+  id: string;
+}
+
+/** Parameters of {@link LedgerClient.updateWebhookEndpoint}. */
+export interface UpdateWebhookEndpointParams {
+  id: string;
+}
+
+/** Parameters of {@link LedgerClient.deleteWebhookEndpoint}. */
+export interface DeleteWebhookEndpointParams {
+  id: string;
+}
+
+/** Parameters of {@link LedgerClient.rotateWebhookSecret}. */
+export interface RotateWebhookSecretParams {
+  id: string;
+}
+
+/** Parameters of {@link LedgerClient.testWebhookEndpoint}. */
+export interface TestWebhookEndpointParams {
+  id: string;
+}
+
+/** A client of the ledger API, authenticated with an API key. */
+export class LedgerClient extends BaseClient {
+  /**
+   * List accounts, or get one by code
+   *
+   * With code, replies with that AccountResponse, including its notes.
+   */
+  listAccounts(params: ListAccountsParams = {}): Promise<AccountResponse[]> {
+    return this.request<AccountResponse[]>("GET", "/v1/accounts", { query: { code: params.code, status: params.status, entity: params.entity, parent: params.parent } });
+  }
+
+  /** Create an account */
+  createAccount(body: CreateAccountRequest): Promise<{ [key: string]: unknown }> {
+    return this.request<{ [key: string]: unknown }>("POST", "/v1/accounts", { body });
+  }
+
+  /** Update an account's metadata as a JSON merge patch; null removes a key */
+  updateAccountMetadata(params: UpdateAccountMetadataParams, body: UpdateAccountMetadataRequest): Promise<UpdateAccountMetadataResponse> {
+    return this.request<UpdateAccountMetadataResponse>("PATCH", "/v1/accounts", { query: { code: params.code }, body });
+  }
+
+  /** Daily balance history of an account */
+  getAccountBalanceHistory(params: GetAccountBalanceHistoryParams): Promise<AccountBalanceHistoryResponse> {
+    return this.request<AccountBalanceHistoryResponse>("GET", "/v1/accounts/balance-history", { query: { code: params.code, basis: params.basis } });
+  }
+
+  /**
+   * Create many accounts at once
+   *
+   * Either every new account is created or, when any item is invalid, none is and the reply is a 422 with the same body naming the invalid items.
+   */
+  createAccounts(body: CreateAccountsRequest): Promise<CreateAccountsResponse> {
+    return this.request<CreateAccountsResponse>("POST", "/v1/accounts/batch", { body });
+  }
+
+  /** Set an account's overdraft protection */
+  setAccountConstraints(params: SetAccountConstraintsParams, body: AccountConstraintsRequest): Promise<AccountConstraintsResponse> {
+    return this.request<AccountConstraintsResponse>("PUT", "/v1/accounts/constraints", { query: { code: params.code }, body });
+  }
+
+  /** Retire an account with a zero balance */
+  disableAccount(params: DisableAccountParams): Promise<AccountStatusResponse> {
+    return this.request<AccountStatusResponse>("POST", "/v1/accounts/disable", { query: { code: params.code } });
+  }
+
+  /** Accept postings to a disabled account again */
+  enableAccount(params: EnableAccountParams): Promise<AccountStatusResponse> {
+    return this.request<AccountStatusResponse>("POST", "/v1/accounts/enable", { query: { code: params.code } });
+  }
+
+  /** Per-account balance movement between two timestamps or snapshots */
+  getBalanceDiff(params: GetBalanceDiffParams = {}): Promise<BalanceDiffResponse> {
+    return this.request<BalanceDiffResponse>("GET", "/v1/balance/diff", { query: { from: params.from, to: params.to, from_snapshot: params.from_snapshot, to_snapshot: params.to_snapshot, changed_only: params.changed_only } });
+  }
+
+  /** Balances aggregated by account code segments */
+  getBalanceRollup(params: GetBalanceRollupParams = {}): Promise<BalanceRollupResponse> {
+    return this.request<BalanceRollupResponse>("GET", "/v1/balance/rollup", { query: { parent: params.parent, depth: params.depth } });
+  }
+
+  /** List balance snapshots */
+  listBalanceSnapshots(): Promise<BalanceSnapshotResponse[]> {
+    return this.request<BalanceSnapshotResponse[]>("GET", "/v1/balance/snapshots");
+  }
+
+  /** Freeze every account balance as of a point in time */
+  createBalanceSnapshot(body: CreateBalanceSnapshotRequest): Promise<BalanceSnapshotResponse> {
+    return this.request<BalanceSnapshotResponse>("POST", "/v1/balance/snapshots", { body });
+  }
+
+  /** Balances totaled by account type */
+  getBalanceSummary(params: GetBalanceSummaryParams = {}): Promise<BalanceSummaryResponse> {
+    return this.request<BalanceSummaryResponse>("GET", "/v1/balance/summary", { query: { currency: params.currency } });
+  }
+
+  /**
+   * List events in sequence order, or get one by id
+   *
+   * With id, replies with that EventResponse. With Accept: application/x-ndjson every matching event is streamed, one per line.
+   */
+  listEvents(params: ListEventsParams = {}): Promise<ListEventsResponse> {
+    return this.request<ListEventsResponse>("GET", "/v1/events", { query: { id: params.id, limit: params.limit, continuation_token: params.continuation_token, event_type: params.event_type, aggregate_id: params.aggregate_id } });
+  }
+
+  /** Delivery attempts of one event, oldest first */
+  listEventDeliveries(params: ListEventDeliveriesParams): Promise<EventDeliveriesResponse> {
+    return this.request<EventDeliveriesResponse>("GET", `/v1/events/${encodeURIComponent(params.id)}/deliveries`);
+  }
+
+  /** List postings across transactions, newest first */
+  listPostings(params: ListPostingsParams = {}): Promise<ListPostingsResponse> {
+    return this.request<ListPostingsResponse>("GET", "/v1/postings", { query: { limit: params.limit, continuation_token: params.continuation_token, account: params.account, direction: params.direction, currency: params.currency, min_amount: params.min_amount, max_amount: params.max_amount, start_time: params.start_time, end_time: params.end_time } });
+  }
+
+  /**
+   * List transactions, or get one by id
+   *
+   * With id, replies with that TransactionResponse. With Accept: application/x-ndjson every matching transaction is streamed, one per line. metadata[key]=value filters match metadata values.
+   */
+  listTransactions(params: ListTransactionsParams = {}): Promise<ListTransactionsResponse> {
+    return this.request<ListTransactionsResponse>("GET", "/v1/transactions", { query: { id: params.id, limit: params.limit, continuation_token: params.continuation_token, start_time: params.start_time, end_time: params.end_time, value_from: params.value_from, value_to: params.value_to, entity: params.entity, confirm_expensive: params.confirm_expensive } });
+  }
+
+  /**
+   * Post a transaction
+   *
+   * Postings must balance per currency. Retrying with the same idempotency_key returns the first transaction. A transaction held by screening is answered with 202 and its review id. Rules refusing a transaction (unknown or disabled accounts, overdraft, KYC, assertions, the ledger's validation rules, screening, posting hooks) reply 422 with a specific error code.
+   */
+  postTransaction(body: PostTransactionRequest, params: PostTransactionParams = {}): Promise<PostTransactionResponse> {
+    return this.request<PostTransactionResponse>("POST", "/v1/transactions", { query: { dry_run: params.dry_run, explain: params.explain }, body });
+  }
+
+  /** List delivery attempts, newest first */
+  listWebhookDeliveries(params: ListWebhookDeliveriesParams = {}): Promise<WebhookDeliveryResponse[]> {
+    return this.request<WebhookDeliveryResponse[]>("GET", "/v1/webhook-deliveries", { query: { limit: params.limit } });
+  }
+
+  /** Deliver failed events again in bulk */
+  retryWebhookDeliveries(body: RetryWebhookDeliveriesRequest): Promise<RetryWebhookDeliveriesResponse> {
+    return this.request<RetryWebhookDeliveriesResponse>("POST", "/v1/webhook-deliveries/retry", { body });
+  }
+
+  /** Deliver a failed delivery's event again */
+  retryWebhookDelivery(params: RetryWebhookDeliveryParams): Promise<RetriedDelivery> {
+    return this.request<RetriedDelivery>("POST", `/v1/webhook-deliveries/${encodeURIComponent(params.id)}/retry`);
+  }
+
+  /** List webhook endpoints */
+  listWebhookEndpoints(): Promise<WebhookEndpointResponse[]> {
+    return this.request<WebhookEndpointResponse[]>("GET", "/v1/webhook-endpoints");
+  }
+
+  /** Register a webhook endpoint; the reply holds its signing secret */
+  createWebhookEndpoint(body: CreateWebhookEndpointRequest): Promise<CreateWebhookEndpointResponse> {
+    return this.request<CreateWebhookEndpointResponse>("POST", "/v1/webhook-endpoints", { body });
+  }
+
+  /** Change an endpoint's URL, state, retry policy, rate limit, batch mode, headers, client certificate or filter */
+  updateWebhookEndpoint(params: UpdateWebhookEndpointParams, body: UpdateWebhookEndpointRequest): Promise<WebhookEndpointResponse> {
+    return this.request<WebhookEndpointResponse>("PATCH", `/v1/webhook-endpoints/${encodeURIComponent(params.id)}`, { body });
+  }
+
+  /** Delete an endpoint along with its delivery history */
+  deleteWebhookEndpoint(params: DeleteWebhookEndpointParams): Promise<void> {
+    return this.request<void>("DELETE", `/v1/webhook-endpoints/${encodeURIComponent(params.id)}`);
+  }
+
+  /** Issue a new signing secret; the old one stays valid for the grace period */
+  rotateWebhookSecret(params: RotateWebhookSecretParams, body: RotateWebhookSecretRequest): Promise<RotateWebhookSecretResponse> {
+    return this.request<RotateWebhookSecretResponse>("POST", `/v1/webhook-endpoints/${encodeURIComponent(params.id)}/rotate-secret`, { body });
+  }
+
+  /** Send a signed WebhookTest event to an endpoint */
+  testWebhookEndpoint(params: TestWebhookEndpointParams): Promise<TestWebhookEndpointResponse> {
+    return this.request<TestWebhookEndpointResponse>("POST", `/v1/webhook-endpoints/${encodeURIComponent(params.id)}/test`);
+  }
+
+  /** Send a range of historical events to a staging URL */
+  replayWebhookEvents(body: ReplayWebhookEventsRequest): Promise<ReplayWebhookEventsResponse> {
+    return this.request<ReplayWebhookEventsResponse>("POST", "/v1/webhook-replays", { body });
+  }
+}
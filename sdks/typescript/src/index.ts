@@ -0,0 +1,3 @@
+export * from "./api.js";
+export { LedgerApiError } from "./runtime.js";
+export type { ClientOptions } from "./runtime.js";
@@ -0,0 +1,81 @@
+// The hand-written half of the client: api.ts is generated from the OpenAPI document
+// and sends every operation through BaseClient.request.
+
+export interface ClientOptions {
+  /** The fetch implementation, the global one by default */
+  fetch?: typeof fetch;
+  /** Headers sent with every request */
+  headers?: Record<string, string>;
+}
+
+/** An error response of the API, decoded from its {"error": {...}} envelope. */
+export class LedgerApiError extends Error {
+  constructor(
+    readonly status: number,
+    readonly code: string,
+    message: string,
+    readonly details: string[] = [],
+  ) {
+    super(message);
+    this.name = "LedgerApiError";
+  }
+}
+
+type QueryValue = string | number | boolean | undefined;
+
+export class BaseClient {
+  private readonly baseUrl: string;
+  private readonly fetch: typeof fetch;
+  private readonly headers: Record<string, string>;
+
+  constructor(baseUrl: string, private readonly apiKey: string, options: ClientOptions = {}) {
+    this.baseUrl = baseUrl.replace(/\/+$/, "");
+    this.fetch = options.fetch ?? globalThis.fetch.bind(globalThis);
+    this.headers = options.headers ?? {};
+  }
+
+  protected async request<T>(
+    method: string,
+    path: string,
+    options: { query?: Record<string, QueryValue>; body?: unknown } = {},
+  ): Promise<T> {
+    const url = new URL(this.baseUrl + path);
+    for (const [key, value] of Object.entries(options.query ?? {})) {
+      if (value !== undefined) {
+        url.searchParams.set(key, String(value));
+      }
+    }
+    const headers: Record<string, string> = {
+      ...this.headers,
+      Accept: "application/json",
+      Authorization: `Bearer ${this.apiKey}`,
+    };
+    let body: string | undefined;
+    if (options.body !== undefined) {
+      headers["Content-Type"] = "application/json";
+      body = JSON.stringify(options.body);
+    }
+
+    const resp = await this.fetch(url, { method, headers, body });
+    const text = await resp.text();
+    if (!resp.ok) {
+      throw decodeError(resp.status, resp.statusText, text);
+    }
+    if (resp.status === 204 || text === "") {
+      return undefined as T;
+    }
+    return JSON.parse(text) as T;
+  }
+}
+
+function decodeError(status: number, statusText: string, text: string): LedgerApiError {
+  try {
+    const { error } = JSON.parse(text);
+    if (error && typeof error.message === "string") {
+      return new LedgerApiError(status, error.code ?? "", error.message, error.details ?? []);
+    }
+  } catch {
+    // not the error envelope, e.g. a proxy's page
+  }
+  return new LedgerApiError(status, "", text.trim() || statusText);
+}
@@ -0,0 +1,79 @@
+import assert from "node:assert/strict";
+import { createServer } from "node:http";
+import { after, before, test } from "node:test";
+
+import { LedgerApiError, LedgerClient } from "../dist/index.js";
+
+let server;
+let client;
+let requests = [];
+let reply = () => ({ status: 200, body: {} });
+
+before(async () => {
+  server = createServer((req, res) => {
+    let body = "";
+    req.on("data", (chunk) => (body += chunk));
+    req.on("end", () => {
+      requests.push({ method: req.method, url: req.url, headers: req.headers, body });
+      const { status, body: payload } = reply(req);
+      res.writeHead(status, payload === undefined ? {} : { "Content-Type": "application/json" });
+      res.end(payload === undefined ? undefined : JSON.stringify(payload));
+    });
+  });
+  await new Promise((resolve) => server.listen(0, "127.0.0.1", resolve));
+  client = new LedgerClient(`http://127.0.0.1:${server.address().port}/`, "secret-key");
+});
+
+after(() => server.close());
+
+function respond(status, body) {
+  requests = [];
+  reply = () => ({ status, body });
+}
+
+test("sends query parameters and the API key, and decodes the response", async () => {
+  respond(200, [{ code: "assets:cash", name: "Cash", balance: "10.00" }]);
+
+  const accounts = await client.listAccounts({ status: "all", entity: undefined });
+
+  assert.equal(accounts[0].code, "assets:cash");
+  assert.equal(requests.length, 1);
+  assert.equal(requests[0].method, "GET");
+  assert.equal(requests[0].url, "/v1/accounts?status=all");
+  assert.equal(requests[0].headers.authorization, "Bearer secret-key");
+});
+
+test("escapes path parameters and sends the body as JSON", async () => {
+  respond(200, { id: "wh/1", url: "https://example.com/hook" });
+
+  await client.updateWebhookEndpoint({ id: "wh/1" }, { url: "https://example.com/hook" });
+
+  assert.equal(requests[0].method, "PATCH");
+  assert.equal(requests[0].url, "/v1/webhook-endpoints/wh%2F1");
+  assert.equal(requests[0].headers["content-type"], "application/json");
+  assert.deepEqual(JSON.parse(requests[0].body), { url: "https://example.com/hook" });
+});
+
+test("resolves to undefined on 204", async () => {
+  respond(204, undefined);
+
+  assert.equal(await client.deleteWebhookEndpoint({ id: "wh_1" }), undefined);
+  assert.equal(requests[0].method, "DELETE");
+});
+
+test("rejects with the error envelope", async () => {
+  respond(400, {
+    error: { code: "VALIDATION_FAILED", message: "2 errors", details: ["amount is required", "currency is required"] },
+  });
+
+  const transaction = { currency: "USD", external_id: "ext-1", idempotency_key: "key-1", postings: [] };
+  await assert.rejects(client.postTransaction(transaction, { dry_run: true }), (err) => {
+    assert.ok(err instanceof LedgerApiError);
+    assert.equal(err.status, 400);
+    assert.equal(err.code, "VALIDATION_FAILED");
+    assert.equal(err.message, "2 errors");
+    assert.deepEqual(err.details, ["amount is required", "currency is required"]);
+    return true;
+  });
+  assert.equal(requests[0].url, "/v1/transactions?dry_run=true");
+});
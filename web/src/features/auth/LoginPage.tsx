@@ -33,11 +33,11 @@ export function LoginPage() {
             // Backend sets session cookie, redirect to dashboard
             navigate("/dashboard");
         } catch (err: unknown) {
-            const axiosError = err as { response?: { status?: number; data?: string } };
+            const axiosError = err as { response?: { status?: number; data?: { error?: { message?: string } } } };
             if (axiosError.response?.status === 401) {
                 setError("Invalid email or password. Please try again.");
-            } else if (axiosError.response?.data) {
-                setError(axiosError.response.data);
+            } else if (axiosError.response?.data?.error?.message) {
+                setError(axiosError.response.data.error.message);
             } else {
                 setError("Login failed. Please try again.");
             }